@@ -0,0 +1,357 @@
+// ccpathGen 根据ccmanifest.json为每个合约生成可部署的ccpath
+//
+// 公共代码(错误码、日志、密码学、账户系统base等)只在 智能合约/ccpkg 下保存一份，
+// 每个合约在ccmanifest.json中声明引用的包及版本，本工具把这些包(包括它们依赖的包)
+// 复制到合约目录的 vendor/loulan 下，替代原来的hardlinkMk/hardlinkChk。
+//
+// 用法(在 智能合约 目录下执行)：
+//
+//	go run ../tools/ccpathGen                 在各合约目录中生成vendor
+//	go run ../tools/ccpathGen -check          只检查各合约的vendor是否和ccpkg一致，不修改
+//	go run ../tools/ccpathGen -out ./deploy   在 ./deploy 下按原目录结构(如 ./deploy/Mogao/ccpath)组装完整的ccpath
+//	go run ../tools/ccpathGen -cc kd,mg       只处理指定的合约
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Manifest struct {
+	PkgRoot    string          `json:"pkgRoot"`   //公共包的根目录，每个子目录是一个包
+	PkgPrefix  string          `json:"pkgPrefix"` //公共包import路径的前缀
+	Chaincodes []ChaincodeDecl `json:"chaincodes"`
+}
+
+type ChaincodeDecl struct {
+	Name     string            `json:"name"`     //合约名，即ccpath/src下的目录名。不同ccpath中的合约可以同名
+	Src      string            `json:"src"`      //合约自身代码的目录
+	CcPath   string            `json:"ccpath"`   //合约所在的ccpath，不填时为src所在的ccpath。和src不一致时，会把src中的代码复制过去
+	Packages map[string]string `json:"packages"` //直接引用的公共包及版本，包的依赖不用填
+}
+
+type PkgInfo struct {
+	Name    string
+	Dir     string
+	Version string
+	Files   []string //不包括测试文件
+	Imports []string //引用的其它公共包
+}
+
+var (
+	manifestFile = flag.String("m", "./ccmanifest.json", "manifest file")
+	outDir       = flag.String("out", "", "assemble ccpath of each chaincode under this dir (keeping the relative path) instead of in place")
+	checkOnly    = flag.Bool("check", false, "only check whether the vendored packages are up to date")
+	ccFilter     = flag.String("cc", "", "comma separated chaincode names, default all")
+)
+
+func main() {
+	flag.Parse()
+
+	data, err := ioutil.ReadFile(*manifestFile)
+	if err != nil {
+		ErrorPrint("Read %s failed, err:%s.\n", *manifestFile, err)
+		os.Exit(1)
+	}
+
+	var mf Manifest
+	err = json.Unmarshal(data, &mf)
+	if err != nil {
+		ErrorPrint("Unmarshal %s failed, err:%s.\n", *manifestFile, err)
+		os.Exit(1)
+	}
+
+	pkgs, err := loadPackages(&mf)
+	if err != nil {
+		ErrorPrint("load packages failed, err:%s.\n", err)
+		os.Exit(1)
+	}
+
+	var names = make(map[string]bool)
+	for _, n := range strings.Split(*ccFilter, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			names[n] = true
+		}
+	}
+
+	var hasErr = false
+	for _, cc := range mf.Chaincodes {
+		if len(names) > 0 && !names[cc.Name] {
+			continue
+		}
+
+		NormalPrint("%s:\n", cc.Name)
+		problems, err := processChaincode(&mf, pkgs, &cc)
+		if err != nil {
+			ErrorPrint("    %s\n", err)
+			hasErr = true
+			continue
+		}
+		for _, p := range problems {
+			ErrorPrint("    %s\n", p)
+			hasErr = true
+		}
+	}
+
+	if hasErr {
+		ErrorPrint("Got some error, please check.\n")
+		os.Exit(1)
+	}
+	NormalPrint("All successful.\n")
+}
+
+//读取pkgRoot下所有的包
+func loadPackages(mf *Manifest) (map[string]*PkgInfo, error) {
+	dirs, err := ioutil.ReadDir(mf.PkgRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs = make(map[string]*PkgInfo)
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+
+		var pi = &PkgInfo{Name: d.Name(), Dir: filepath.Join(mf.PkgRoot, d.Name())}
+		var constName = strings.ToUpper(pi.Name) + "_VERSION"
+		files, imports, consts, err := parseGoDir(pi.Dir, mf.PkgPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("package %s: %s", pi.Name, err)
+		}
+		pi.Files = files
+		pi.Imports = imports
+		pi.Version = consts[constName]
+		if pi.Version == "" {
+			return nil, fmt.Errorf("package %s: const %s not found", pi.Name, constName)
+		}
+
+		pkgs[pi.Name] = pi
+	}
+
+	return pkgs, nil
+}
+
+//解析目录下的go文件(不包括测试文件)，返回文件列表、引用的公共包、字符串常量
+func parseGoDir(dir, pkgPrefix string) ([]string, []string, map[string]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var files []string
+	var importSet = make(map[string]bool)
+	var consts = make(map[string]string)
+	var fset = token.NewFileSet()
+	for _, f := range matches {
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		files = append(files, filepath.Base(f))
+
+		af, err := parser.ParseFile(fset, f, nil, 0)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for _, imp := range af.Imports {
+			p, _ := strconv.Unquote(imp.Path.Value)
+			if strings.HasPrefix(p, pkgPrefix+"/") {
+				importSet[strings.TrimPrefix(p, pkgPrefix+"/")] = true
+			}
+		}
+		for _, decl := range af.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, n := range vs.Names {
+					if i < len(vs.Values) {
+						if bl, ok := vs.Values[i].(*ast.BasicLit); ok && bl.Kind == token.STRING {
+							consts[n.Name], _ = strconv.Unquote(bl.Value)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	var imports []string
+	for p := range importSet {
+		imports = append(imports, p)
+	}
+	sort.Strings(imports)
+
+	return files, imports, consts, nil
+}
+
+//处理一个合约，返回检查出的问题
+func processChaincode(mf *Manifest, pkgs map[string]*PkgInfo, cc *ChaincodeDecl) ([]string, error) {
+	var problems []string
+
+	ownFiles, imports, _, err := parseGoDir(cc.Src, mf.PkgPrefix)
+	if err != nil {
+		return nil, err
+	}
+	if len(ownFiles) == 0 {
+		return nil, fmt.Errorf("no go files in '%s'", cc.Src)
+	}
+
+	//合约直接引用的包必须在manifest中声明，且版本一致
+	for _, imp := range imports {
+		if _, ok := cc.Packages[imp]; !ok {
+			problems = append(problems, fmt.Sprintf("package '%s' imported but not declared in manifest", imp))
+		}
+	}
+	for name, ver := range cc.Packages {
+		pi, ok := pkgs[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("package '%s' not exists in '%s'", name, mf.PkgRoot))
+		} else if pi.Version != ver {
+			problems = append(problems, fmt.Sprintf("package '%s' is version %s, but manifest requires %s", name, pi.Version, ver))
+		}
+	}
+	if len(problems) > 0 {
+		return problems, nil
+	}
+
+	//加上间接引用的包
+	var closure = make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if closure[name] {
+			return
+		}
+		closure[name] = true
+		for _, dep := range pkgs[name].Imports {
+			visit(dep)
+		}
+	}
+	for name := range cc.Packages {
+		visit(name)
+	}
+
+	//目标目录
+	var srcDir = filepath.Clean(cc.Src)
+	var ccPath = cc.CcPath
+	if ccPath == "" {
+		ccPath = filepath.Dir(filepath.Dir(srcDir))
+	}
+	var destDir = filepath.Join(ccPath, "src", cc.Name)
+	if *outDir != "" {
+		//不同项目中有同名的合约(如Mogao和Retail中的mogao)，所以保留ccpath的相对路径
+		destDir = filepath.Join(*outDir, ccPath, "src", cc.Name)
+	}
+
+	//需要生成的文件：目标目录   => 源文件
+	var want = make(map[string]string)
+	if destDir != srcDir {
+		for _, f := range ownFiles {
+			want[filepath.Join(destDir, f)] = filepath.Join(srcDir, f)
+		}
+	}
+	var vendorDir = filepath.Join(destDir, "vendor", mf.PkgPrefix)
+	for name := range closure {
+		for _, f := range pkgs[name].Files {
+			want[filepath.Join(vendorDir, name, f)] = filepath.Join(pkgs[name].Dir, f)
+		}
+	}
+
+	if *checkOnly {
+		return checkFiles(want, vendorDir)
+	}
+
+	//vendor整个重新生成，避免包中删除的文件残留
+	err = os.RemoveAll(vendorDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var dests []string
+	for dest := range want {
+		dests = append(dests, dest)
+	}
+	sort.Strings(dests)
+	for _, dest := range dests {
+		err = copyFile(want[dest], dest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var vers []string
+	for name := range closure {
+		vers = append(vers, name+"@"+pkgs[name].Version)
+	}
+	sort.Strings(vers)
+	NormalPrint("    => %s [%s]\n", destDir, strings.Join(vers, " "))
+
+	return nil, nil
+}
+
+func checkFiles(want map[string]string, vendorDir string) ([]string, error) {
+	var problems []string
+
+	for dest, src := range want {
+		srcData, err := ioutil.ReadFile(src)
+		if err != nil {
+			return nil, err
+		}
+		destData, err := ioutil.ReadFile(dest)
+		if os.IsNotExist(err) {
+			problems = append(problems, fmt.Sprintf("'%s' not exists", dest))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(srcData, destData) {
+			problems = append(problems, fmt.Sprintf("'%s' is different from '%s'", dest, src))
+		}
+	}
+
+	//vendor中多余的文件
+	filepath.Walk(vendorDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			if _, ok := want[path]; !ok {
+				problems = append(problems, fmt.Sprintf("'%s' is stale", path))
+			}
+		}
+		return nil
+	})
+
+	sort.Strings(problems)
+	return problems, nil
+}
+
+func copyFile(source, dest string) error {
+	data, err := ioutil.ReadFile(source)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(dest), 0755)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(dest, data, 0644)
+}
+
+func ErrorPrint(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "Error: "+format, args...)
+}
+func NormalPrint(format string, args ...interface{}) {
+	fmt.Printf(" Info: "+format, args...)
+}
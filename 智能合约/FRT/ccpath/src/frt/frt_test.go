@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	. "loulan/errcode"
+)
+
+func TestRackFinance(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, map[string]int64{"alice": 1000, "bob": 1100, "plat": 500})
+
+	//默认融资配置：利润率20%，90%的利润分给投资人，融资能力2000
+	c.run([]testStep{
+		{
+			name: "alice buy", user: "alice_user", function: "buyFinance",
+			args:  []string{"alice", "r1", "f1", "plat", "1000", "buy", "", "0"},
+			check: wantBalances(map[string]int64{"alice": 0, "plat": 1500}),
+		},
+		{
+			name: "bob buy", user: "bob_user", function: "buyFinance",
+			args:  []string{"bob", "r1", "f1", "plat", "800", "buy", "", "0"},
+			check: wantBalances(map[string]int64{"bob": 300, "plat": 2300}),
+		},
+		{
+			name: "over capacity", user: "bob_user", function: "buyFinance",
+			args:     []string{"bob", "r1", "f1", "plat", "300", "buy", "", "0"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+			check:    wantBalances(map[string]int64{"bob": 300, "plat": 2300}),
+		},
+		{
+			name: "issue finish by others", user: "alice_user", function: "financeIssueFinish",
+			args:     []string{"alice", "f1"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "issue finish f1", user: "cbuser", function: "financeIssueFinish",
+			args: []string{testCBAcc, "f1"},
+		},
+		{
+			name: "bonus by others", user: "alice_user", function: "financeBouns",
+			args:     []string{"alice", "f1", "r1:100000"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			//利润 100000*20%*92%*90%/100 = 165，按投资额占融资能力的比例分配
+			name: "bonus f1", user: "cbuser", function: "financeBouns",
+			args: []string{testCBAcc, "f1", "r1:100000"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				rfi := c.rackFinance("r1", "f1")
+				if rfi.UserProfitMap["alice"] != 82 || rfi.UserProfitMap["bob"] != 66 {
+					t.Errorf("profit: want alice 82 bob 66, got %v", rfi.UserProfitMap)
+				}
+			},
+		},
+		{
+			name: "bob profit", user: "bob_user", function: "getRackFinanceProfit",
+			args: []string{"bob", "r1"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				if string(payload) != "66" {
+					t.Errorf("getRackFinanceProfit: want 66, got %s", payload)
+				}
+			},
+		},
+		{
+			//赎回本金和收益
+			name: "pay alice", user: "plat_user", function: "payFinance",
+			args:  []string{"plat", "r1", "alice", "pay", "", "0"},
+			check: wantBalances(map[string]int64{"alice": 1082, "plat": 1218}),
+		},
+		{
+			name: "pay alice again", user: "plat_user", function: "payFinance",
+			args:  []string{"plat", "r1", "alice", "pay", "", "0"},
+			check: wantBalances(map[string]int64{"alice": 1082, "plat": 1218}),
+		},
+		{
+			//下一期发行结束时，上一期没有赎回的投资自动续期，不再转账
+			name: "issue finish f2", user: "cbuser", function: "financeIssueFinish",
+			args: []string{testCBAcc, "f2"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantBalances(map[string]int64{"alice": 1082, "bob": 300, "plat": 1218})(t, c, payload)
+				rfi := c.rackFinance("r1", "f2")
+				if rfi == nil || !reflect.DeepEqual(rfi.UserAmountMap, map[string]int64{"bob": 800}) {
+					t.Errorf("renewal: want bob 800 in f2, got %+v", rfi)
+				}
+			},
+		},
+	})
+}
+
+func TestAllocEarning(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, map[string]int64{"alice": 0, "bob": 0, "carol": 0, "dave": 0, "plat": 0})
+
+	c.run([]testStep{
+		{
+			name: "alloc by others", user: "alice_user", function: "allocEarning",
+			args:     []string{"alice", "r1", "alice", "bob:1;carol:1", "dave", "plat", "k1", "10000"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			//默认分成比例：经营者92%，场地3%，送货2%，平台3%。分成只记录，不转账
+			name: "alloc", user: "cbuser", function: "allocEarning",
+			args:  []string{testCBAcc, "r1", "alice", "bob:1;carol:1", "dave", "plat", "k1", "10000"},
+			check: wantBalances(map[string]int64{"alice": 0, "bob": 0, "plat": 0}),
+		},
+		{
+			name: "query by others", user: "alice_user", function: "queryRackAlloc",
+			args:     []string{"alice", "r1", "k1", "0", "0", "0", "0", ""},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "query", user: "cbuser", function: "queryRackAlloc",
+			args: []string{testCBAcc, "r1", "k1", "0", "0", "0", "0", ""},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				var txs []QueryEarningAllocTx
+				if err := json.Unmarshal(payload, &txs); err != nil {
+					t.Fatalf("Unmarshal QueryEarningAllocTx failed, err=%s", err)
+				}
+				var want = map[string]map[string]int64{
+					RACK_ROLE_SELLER:   {"alice": 9200},
+					RACK_ROLE_FIELDER:  {"bob": 150, "carol": 150},
+					RACK_ROLE_DELIVERY: {"dave": 200},
+					RACK_ROLE_PLATFORM: {"plat": 300},
+				}
+				if len(txs) != 1 || !reflect.DeepEqual(txs[0].AmountMap, want) {
+					t.Errorf("queryRackAlloc: want %v, got %s", want, payload)
+				}
+			},
+		},
+	})
+}
+
+func TestEncourageScore(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(200000, map[string]int64{"alice": 0, "bob": 0, "carol": 0, "dave": 0, "plat": 100000})
+
+	c.run([]testStep{
+		{
+			name: "new rack", user: "plat_user", function: "encourageScoreForNewRack",
+			args:  []string{"plat", "r1,alice,bob,carol,dave,1000", "score", "", "0"},
+			check: wantBalances(map[string]int64{"alice": 920, "bob": 30, "carol": 20, "dave": 30, "plat": 99000}),
+		},
+		{
+			//有一条记录出错时整个交易失败，已执行的转账也不生效
+			name: "sales with bad record", user: "plat_user", function: "encourageScoreForSales",
+			args:     []string{"plat", "r1,300000,alice,bob,carol,dave;r2,xx,alice,bob,carol,dave", "score", "", "0"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+			check:    wantBalances(map[string]int64{"alice": 920, "plat": 99000}),
+		},
+		{
+			//销售额3000元，在(2500,3000]区间，奖励170%即5100，经营者另外补偿销售额3000
+			name: "sales", user: "plat_user", function: "encourageScoreForSales",
+			args:  []string{"plat", "r1,300000,alice,bob,carol,dave", "score", "", "0"},
+			check: wantBalances(map[string]int64{"alice": 8612, "bob": 183, "carol": 122, "dave": 183, "plat": 90900}),
+		},
+		{
+			name: "set SES cfg by others", user: "alice_user", function: "setSESCfg",
+			args:     []string{"alice", "r1", "1000:100;*:200"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+	})
+}
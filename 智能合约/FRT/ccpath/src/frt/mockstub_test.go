@@ -0,0 +1,469 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"loulan/llcrypto"
+
+	. "loulan/acbase"
+	. "loulan/errcode"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//base的init中设置的货币发行总额
+const testIssueAmountTotal = 10000000000
+
+const testCBAcc = "cb"
+
+var testSecp256k1 = llcrypto.NewSecp256k1()
+
+//测试用户在各条链之间共用，央行账户的公钥hash和第一条链中开户时的一致
+var testUsers = make(map[string]*testUser)
+
+//base中缓存了央行账户名，同一进程中只能开一次央行账户。第一条链开户时保存央行账户的数据，之后的链直接写入
+var testCBState map[string][]byte
+
+//测试用户。secp256k1密钥用于交易签名，证书用于身份校验(GetCreator)
+type testUser struct {
+	Name       string
+	PubKey     []byte
+	SecKey     []byte
+	PubKeyHash string //base64
+	Cert       []byte //pem格式
+	CertHash   string //base64
+}
+
+func newTestUser(t *testing.T, name string) *testUser {
+	var u = &testUser{Name: name}
+	var err error
+
+	u.PubKey, u.SecKey, err = testSecp256k1.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("newTestUser(%s): GenerateKeyPair failed, err=%s", name, err)
+	}
+	hash, err := llcrypto.RipemdHash160(u.PubKey)
+	if err != nil {
+		t.Fatalf("newTestUser(%s): RipemdHash160(pubkey) failed, err=%s", name, err)
+	}
+	u.PubKeyHash = base64.StdEncoding.EncodeToString(hash)
+
+	//自签名证书，CommonName为用户名，base中校验身份时会用到
+	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("newTestUser(%s): GenerateKey failed, err=%s", name, err)
+	}
+	var tmpl = x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &certKey.PublicKey, certKey)
+	if err != nil {
+		t.Fatalf("newTestUser(%s): CreateCertificate failed, err=%s", name, err)
+	}
+	u.Cert = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	hash, err = llcrypto.RipemdHash160(u.Cert)
+	if err != nil {
+		t.Fatalf("newTestUser(%s): RipemdHash160(cert) failed, err=%s", name, err)
+	}
+	u.CertHash = base64.StdEncoding.EncodeToString(hash)
+
+	return u
+}
+
+//按客户端的方式签名：函数名和参数用","拼接后计算sha256，再用私钥签名
+func (u *testUser) sign(t *testing.T, function string, args []string) string {
+	var msg = util.ComputeSHA256([]byte(function + "," + strings.Join(args, ",")))
+	sig, err := testSecp256k1.Sign(msg, u.SecKey)
+	if err != nil {
+		t.Fatalf("sign(%s): Sign failed, err=%s", u.Name, err)
+	}
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+//MockStub的交易时间为当前时间，GetCreator返回nil，测试中需要可控的时间和身份，所以包装一下
+type testStub struct {
+	*shim.MockStub
+	args    [][]byte
+	creator []byte
+	now     time.Time
+}
+
+func (s *testStub) GetArgs() [][]byte {
+	return s.args
+}
+
+func (s *testStub) GetStringArgs() []string {
+	var strs = make([]string, 0, len(s.args))
+	for _, arg := range s.args {
+		strs = append(strs, string(arg))
+	}
+	return strs
+}
+
+func (s *testStub) GetFunctionAndParameters() (string, []string) {
+	var strs = s.GetStringArgs()
+	if len(strs) == 0 {
+		return "", []string{}
+	}
+	return strs[0], strs[1:]
+}
+
+func (s *testStub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+func (s *testStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return &timestamp.Timestamp{Seconds: s.now.Unix(), Nanos: int32(s.now.Nanosecond())}, nil
+}
+
+//当前交易时间，单位毫秒，和base中invokeTime的计算方式一致
+func (s *testStub) nowMs() int64 {
+	return s.now.Unix()*1000 + int64(s.now.Nanosecond()/1000000)
+}
+
+func (s *testStub) advance(d time.Duration) {
+	s.now = s.now.Add(d)
+}
+
+//模拟控制合约，返回配置的参数值
+type testCtrlCC struct {
+	paras map[string]string
+}
+
+func (c *testCtrlCC) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success(nil)
+}
+
+func (c *testCtrlCC) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	function, args := stub.GetFunctionAndParameters()
+	if function != CONTROL_CC_GETPARA_FUNC_NAME || len(args) < 1 {
+		return shim.Error(fmt.Sprintf("testCtrlCC: unknown function %s%v", function, args))
+	}
+	value, ok := c.paras[args[0]]
+	if !ok {
+		return shim.Error(fmt.Sprintf("testCtrlCC: parameter %s not exists", args[0]))
+	}
+	return shim.Success([]byte(value))
+}
+
+//一条测试链：frt合约 + 控制合约
+type testChain struct {
+	t     *testing.T
+	stub  *testStub
+	ctrl  *testCtrlCC
+	txSeq int
+	appid string
+}
+
+func newTestChain(t *testing.T) *testChain {
+	//包级别的缓存会跨测试保留，每条链重新开始
+	currentFidCache = ""
+
+	var c = &testChain{t: t}
+
+	c.ctrl = &testCtrlCC{paras: map[string]string{
+		CTRL_PARA_IS_TEST_CHAIN:       "false",
+		CTRL_PARA_NEED_CHECK_SIGN:     "true",
+		CTRL_PARA_NEED_CHECK_IDENTITY: "true",
+	}}
+
+	var mock = shim.NewMockStub(EXTEND_MODULE_NAME, &Base)
+	mock.ChannelID = "testchannel"
+	mock.MockPeerChaincode(CONTROL_CC_NAME, shim.NewMockStub(CONTROL_CC_NAME, c.ctrl))
+
+	c.stub = &testStub{MockStub: mock, now: time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)}
+
+	c.txSeq++
+	var txid = fmt.Sprintf("tx%d", c.txSeq)
+	c.stub.args = util.ToChaincodeArgs("init")
+	c.stub.MockTransactionStart(txid)
+	resp := Base.Init(c.stub)
+	c.stub.MockTransactionEnd(txid)
+	if resp.Status != shim.OK {
+		t.Fatalf("newTestChain: init failed, %s", resp.Message)
+	}
+
+	return c
+}
+
+func (c *testChain) user(name string) *testUser {
+	u, ok := testUsers[name]
+	if !ok {
+		u = newTestUser(c.t, name)
+		testUsers[name] = u
+	}
+	return u
+}
+
+//执行一个交易。args为用户名之后、签名之前的参数，第一个为账户名。失败时和fabric一样丢弃本交易的所有写操作
+func (c *testChain) invoke(user, function string, args ...string) pb.Response {
+	var u = c.user(user)
+	var allArgs = append([]string{u.Name}, args...)
+	allArgs = append(allArgs, u.sign(c.t, function, allArgs))
+
+	c.txSeq++
+	var txid = fmt.Sprintf("tx%d", c.txSeq)
+
+	var snapshot = make(map[string][]byte, len(c.stub.State))
+	for k, v := range c.stub.State {
+		snapshot[k] = v
+	}
+
+	c.stub.args = util.ToChaincodeArgs(append([]string{function}, allArgs...)...)
+	c.stub.creator = u.Cert
+	c.stub.MockTransactionStart(txid)
+
+	resp := Base.Invoke(c.stub)
+	if resp.Status != shim.OK {
+		c.rollback(snapshot)
+	}
+
+	c.stub.MockTransactionEnd(txid)
+
+	return resp
+}
+
+func (c *testChain) rollback(snapshot map[string][]byte) {
+	var keys []string
+	for k := range c.stub.State {
+		keys = append(keys, k)
+	}
+	for _, k := range keys {
+		old, ok := snapshot[k]
+		if !ok {
+			c.stub.MockStub.DelState(k)
+		} else if !bytes.Equal(old, c.stub.State[k]) {
+			c.stub.MockStub.PutState(k, old)
+		}
+	}
+	for k, v := range snapshot {
+		if _, ok := c.stub.State[k]; !ok {
+			c.stub.MockStub.PutState(k, v)
+		}
+	}
+}
+
+//开户。userIdentity由平台追加在签名之后，所以不能用invoke
+func (c *testChain) openAccount(user, acc string, isCB bool) {
+	var u = c.user(user)
+	var function = "account"
+	if isCB {
+		function = "accountCB"
+	}
+
+	var args = []string{u.Name, acc, u.PubKeyHash}
+	args = append(args, u.sign(c.t, function, args), u.CertHash)
+
+	c.txSeq++
+	var txid = fmt.Sprintf("tx%d", c.txSeq)
+	c.stub.args = util.ToChaincodeArgs(append([]string{function}, args...)...)
+	c.stub.creator = u.Cert
+	c.stub.MockTransactionStart(txid)
+	resp := Base.Invoke(c.stub)
+	c.stub.MockTransactionEnd(txid)
+
+	if resp.Status != shim.OK {
+		c.t.Fatalf("openAccount(%s,%s) failed, %s", user, acc, resp.Message)
+	}
+}
+
+//开央行账户，见testCBState
+func (c *testChain) openCBAccount() {
+	if testCBState != nil {
+		c.txSeq++
+		var txid = fmt.Sprintf("tx%d", c.txSeq)
+		c.stub.MockTransactionStart(txid)
+		for k, v := range testCBState {
+			c.stub.MockStub.PutState(k, v)
+		}
+		c.stub.MockTransactionEnd(txid)
+		return
+	}
+
+	var before = make(map[string][]byte, len(c.stub.State))
+	for k, v := range c.stub.State {
+		before[k] = v
+	}
+
+	c.openAccount("cbuser", testCBAcc, true)
+
+	testCBState = make(map[string][]byte)
+	for k, v := range c.stub.State {
+		if !bytes.Equal(before[k], v) {
+			testCBState[k] = v
+		}
+	}
+}
+
+//搭建一个常用的环境：央行发行货币，注册应用，给每个账户转入初始余额
+func (c *testChain) setup(issue int64, balances map[string]int64) {
+	c.openCBAccount()
+	c.mustOK(c.invoke("cbuser", "issue", testCBAcc, fmt.Sprintf("%d", issue)), "issue")
+
+	c.appid = "testapp"
+	c.mustOK(c.invoke("cbuser", "registerApp", testCBAcc, c.appid, "test app", "test corp"), "registerApp")
+
+	for acc, amt := range balances {
+		c.openAccount(acc+"_user", acc, false)
+		if amt > 0 {
+			c.mustOK(c.invoke("cbuser", "transefer", testCBAcc, acc, fmt.Sprintf("%d", amt), c.appid), "transefer to "+acc)
+		}
+	}
+	c.checkInvariants("setup")
+}
+
+func (c *testChain) mustOK(resp pb.Response, what string) []byte {
+	if resp.Status != shim.OK {
+		c.t.Fatalf("%s failed, %s", what, resp.Message)
+	}
+	return resp.Payload
+}
+
+//失败时返回的错误码。无法解析时返回-1
+func respErrCode(resp pb.Response) int32 {
+	if resp.Status == shim.OK {
+		return 0
+	}
+	errcm, err := NewErrorCodeMsgFromString(resp.Message)
+	if err != nil {
+		return -1
+	}
+	return errcm.Code
+}
+
+func (c *testChain) accountEntity(acc string) *AccountEntity {
+	entB := c.stub.State[ACC_ENTITY_PREFIX+acc]
+	if entB == nil {
+		return nil
+	}
+	var ent AccountEntity
+	if err := json.Unmarshal(entB, &ent); err != nil {
+		c.t.Fatalf("accountEntity(%s): Unmarshal failed, err=%s", acc, err)
+	}
+	return &ent
+}
+
+func (c *testChain) balance(acc string) int64 {
+	ent := c.accountEntity(acc)
+	if ent == nil {
+		c.t.Fatalf("balance: account %s not exists", acc)
+	}
+	return ent.RestAmount
+}
+
+func (c *testChain) rackFinance(rackid, fid string) *RackFinancInfo {
+	rfiB := c.stub.State[new(FRT).getRackFinacInfoKey(rackid, fid)]
+	if rfiB == nil {
+		return nil
+	}
+	var rfi RackFinancInfo
+	if err := json.Unmarshal(rfiB, &rfi); err != nil {
+		c.t.Fatalf("rackFinance(%s,%s): Unmarshal failed, err=%s", rackid, fid, err)
+	}
+	return &rfi
+}
+
+//每一步之后检查的不变量：
+//1. 所有账户（包括虚拟的发行账户）余额之和等于发行总额，账户余额不为负
+//2. 每期货架理财的投资总额等于各用户投资额之和，且不超过货架融资能力
+func (c *testChain) checkInvariants(step string) {
+	var sum int64
+	for k, v := range c.stub.State {
+		if !strings.HasPrefix(k, ACC_ENTITY_PREFIX) {
+			continue
+		}
+		var ent AccountEntity
+		if err := json.Unmarshal(v, &ent); err != nil {
+			c.t.Fatalf("[%s] invariant: Unmarshal %s failed, err=%s", step, k, err)
+		}
+		if ent.RestAmount < 0 {
+			c.t.Errorf("[%s] invariant: %s rest amount %d < 0", step, ent.EntID, ent.RestAmount)
+		}
+		sum += ent.RestAmount
+	}
+	if sum != testIssueAmountTotal {
+		c.t.Errorf("[%s] invariant: sum of balances %d != issue total %d", step, sum, testIssueAmountTotal)
+	}
+
+	for k, v := range c.stub.State {
+		if !strings.HasPrefix(k, RACKFINACINFO_PREFIX) {
+			continue
+		}
+		var rfi RackFinancInfo
+		if err := json.Unmarshal(v, &rfi); err != nil {
+			c.t.Fatalf("[%s] invariant: Unmarshal %s failed, err=%s", step, k, err)
+		}
+
+		var amtSum int64
+		for _, amt := range rfi.UserAmountMap {
+			amtSum += amt
+		}
+		if amtSum != rfi.AmountFinca {
+			c.t.Errorf("[%s] invariant: (%s,%s) AmountFinca %d != sum of users %d", step, rfi.RackID, rfi.FID, rfi.AmountFinca, amtSum)
+		}
+		if rfi.AmountFinca > rfi.RFCfg.InvestCapacity {
+			c.t.Errorf("[%s] invariant: (%s,%s) AmountFinca %d > capacity %d", step, rfi.RackID, rfi.FID, rfi.AmountFinca, rfi.RFCfg.InvestCapacity)
+		}
+	}
+}
+
+//表驱动测试的一步
+type testStep struct {
+	name     string
+	user     string        //发起交易并签名的用户
+	function string        //
+	args     []string      //用户名之后、签名之前的参数，第一个为账户名
+	advance  time.Duration //执行前推进的时间
+	wantCode int32         //0表示期望成功，否则为期望的错误码
+	check    func(t *testing.T, c *testChain, payload []byte)
+}
+
+func (c *testChain) run(steps []testStep) {
+	for _, st := range steps {
+		c.stub.advance(st.advance)
+
+		resp := c.invoke(st.user, st.function, st.args...)
+		c.stub.advance(time.Second)
+
+		if code := respErrCode(resp); code != st.wantCode {
+			c.t.Fatalf("[%s] want code %d, got %d, resp=%s", st.name, st.wantCode, code, resp.Message)
+		}
+
+		c.checkInvariants(st.name)
+
+		if st.check != nil {
+			st.check(c.t, c, resp.Payload)
+		}
+	}
+}
+
+//检查账户余额的check函数
+func wantBalances(want map[string]int64) func(t *testing.T, c *testChain, payload []byte) {
+	return func(t *testing.T, c *testChain, payload []byte) {
+		for acc, amt := range want {
+			if got := c.balance(acc); got != amt {
+				t.Errorf("balance of %s: want %d, got %d", acc, amt, got)
+			}
+		}
+	}
+}
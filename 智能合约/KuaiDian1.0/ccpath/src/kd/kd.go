@@ -12,11 +12,19 @@ import (
 	"strconv"
 	"strings"
 
+	"loulan/llcrypto"
+
+	. "loulan/acbase"
+	. "loulan/ccutil"
+	. "loulan/errcode"
+	. "loulan/mylog"
+
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	//"github.com/hyperledger/fabric/core/crypto/primitives"
 )
 
 const (
+	EXTEND_MODULE_NAME = "kd" //模块名，base中的key都以此为前缀
 
 	//销售分成相关
 	RACK_GLOBAL_ALLOCRATE_KEY = "!kd@globalAllocRate@!" //全局的收入分成比例
@@ -236,12 +244,18 @@ type RackFinanceRecd struct {
 }
 
 var kdlogger = NewMylogger("kd")
+var kdCrypto = llcrypto.MyCryptoNew()
 
 type KD struct {
 }
 
+//base中的数据和交易都在同一个stateCache中缓存
+var stateCache = &StateCache
+
 //包初始化函数
 func init() {
+	SetModuleName(EXTEND_MODULE_NAME)
+
 	var kd KD
 	//注册base中的hook函数
 	InitHook = kd.Init
@@ -250,7 +264,16 @@ func init() {
 	DateUpdateAfterLoadHook = kd.loadAfter
 }
 
-func (t *KD) Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]byte, error) {
+func (t *KD) Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]byte, *ErrorCodeMsg) {
+	payload, err := t.__Init(stub, ifas)
+	if err != nil {
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_INNER_ERROR, "%s", err)
+	}
+
+	return payload, nil
+}
+
+func (t *KD) __Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]byte, error) {
 	kdlogger.Debug("Enter Init")
 	function, args := stub.GetFunctionAndParameters()
 	//init函数属于非常规操作，记录日志
@@ -291,7 +314,7 @@ func (t *KD) Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]byte,
 			return nil, kdlogger.Errorf("Init Marshal error, err=%s.", err)
 		}
 
-		err = stateCache.putState_Ex(stub, t.getGlobalRackAllocRateKey(), eapJson)
+		err = stateCache.PutState_Ex(stub, t.getGlobalRackAllocRateKey(), eapJson)
 		if err != nil {
 			return nil, kdlogger.Errorf("Init PutState_Ex error, err=%s.", err)
 		}
@@ -315,7 +338,7 @@ func (t *KD) Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]byte,
 			return nil, kdlogger.Errorf("Init Marshal(serc) error, err=%s.", err)
 		}
 
-		err = stateCache.putState_Ex(stub, t.getGlobalRackEncourageScoreCfgKey(), sercJson)
+		err = stateCache.PutState_Ex(stub, t.getGlobalRackEncourageScoreCfgKey(), sercJson)
 		if err != nil {
 			return nil, kdlogger.Errorf("Init PutState_Ex(serc) error, err=%s.", err)
 		}
@@ -332,16 +355,12 @@ func (t *KD) Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]byte,
 			return nil, kdlogger.Errorf("Init Marshal(rfc) error, err=%s.", err)
 		}
 
-		err = stateCache.putState_Ex(stub, t.getGlobalRackFinancCfgKey(), rfcJson)
+		err = stateCache.PutState_Ex(stub, t.getGlobalRackFinancCfgKey(), rfcJson)
 		if err != nil {
 			return nil, kdlogger.Errorf("Init PutState_Ex(rfc) error, err=%s.", err)
 		}
 
-		//虚拟一个超级账户，设置货币发行总额，给央行发行货币。
-		err = Base.setIssueAmountTotal(stub, 10000000000, initTime)
-		if err != nil {
-			return nil, kdlogger.Errorf("Init setIssueAmountTotal error, err=%s.", err)
-		}
+		//货币发行总额在base的init中设置
 
 		return nil, nil
 	} else if function == "upgrade" {
@@ -353,8 +372,17 @@ func (t *KD) Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]byte,
 	}
 }
 
+func (t *KD) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]byte, *ErrorCodeMsg) {
+	payload, err := t.__Invoke(stub, ifas)
+	if err != nil {
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_INNER_ERROR, "%s", err)
+	}
+
+	return payload, nil
+}
+
 // Transaction makes payment of X units from A to B
-func (t *KD) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]byte, error) {
+func (t *KD) __Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]byte, error) {
 	kdlogger.Debug("Enter Invoke")
 	function, args := stub.GetFunctionAndParameters()
 	kdlogger.Debug("func =%s, args = %+v", function, args)
@@ -373,9 +401,10 @@ func (t *KD) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]b
 	var accountEnt = ifas.AccountEnt
 
 	if accountEnt == nil {
-		accountEnt, err = Base.getAccountEntity(stub, accName)
-		if err != nil {
-			return nil, kdlogger.Errorf("Invoke getAccountEntity failed. err=%s", err)
+		var errcm *ErrorCodeMsg
+		accountEnt, errcm = Base.GetAccountEntity(stub, accName)
+		if errcm != nil {
+			return nil, kdlogger.Errorf("Invoke getAccountEntity failed. err=%s", errcm)
 		}
 
 		ifas.AccountEnt = accountEnt
@@ -443,14 +472,14 @@ func (t *KD) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]b
 			}
 		}
 
-		_, err = Base.transferCoin(stub, accName, toAcc, transType, description, transAmount, invokeTime, sameEntSaveTransFlag)
+		_, err = t.transferCoin(stub, accName, toAcc, transType, description, transAmount, invokeTime, sameEntSaveTransFlag)
 		if err != nil {
 			return nil, kdlogger.Errorf("Invoke(transeferUsePwd) transferCoin2 failed. err=%s", err)
 		}
 		return nil, nil
 
 	} else if function == "setAllocCfg" {
-		if !Base.isAdmin(stub, accName) {
+		if !Base.IsAdmin(stub, accName) {
 			return nil, kdlogger.Errorf("Invoke(setAllocCfg) can't exec by %s.", accName)
 		}
 
@@ -502,7 +531,7 @@ func (t *KD) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]b
 			stateKey = t.getRackAllocRateKey(rackid)
 		}
 
-		err = stateCache.putState_Ex(stub, stateKey, eapJson)
+		err = stateCache.PutState_Ex(stub, stateKey, eapJson)
 		if err != nil {
 			return nil, kdlogger.Errorf("Invoke(setAllocCfg) PutState_Ex error, err=%s.", err)
 		}
@@ -510,7 +539,7 @@ func (t *KD) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]b
 		return nil, nil
 
 	} else if function == "allocEarning" {
-		if !Base.isAdmin(stub, accName) {
+		if !Base.IsAdmin(stub, accName) {
 			return nil, kdlogger.Errorf("Invoke(allocEarning) can't exec by %s.", accName)
 		}
 
@@ -534,14 +563,14 @@ func (t *KD) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]b
 
 		var eap EarningAllocRate
 
-		eapB, err := stateCache.getState_Ex(stub, t.getRackAllocRateKey(rackid))
+		eapB, err := stateCache.GetState_Ex(stub, t.getRackAllocRateKey(rackid))
 		if err != nil {
 			return nil, kdlogger.Errorf("Invoke(allocEarning) GetState(rackid=%s) failed. err=%s", rackid, err)
 		}
 		if eapB == nil {
 			kdlogger.Warn("Invoke(allocEarning) GetState(rackid=%s) nil, try to get global.", rackid)
 			//没有为该货架单独配置，返回global配置
-			eapB, err = stateCache.getState_Ex(stub, t.getGlobalRackAllocRateKey())
+			eapB, err = stateCache.GetState_Ex(stub, t.getGlobalRackAllocRateKey())
 			if err != nil {
 				return nil, kdlogger.Errorf("Invoke(allocEarning) GetState(global, rackid=%s) failed. err=%s", rackid, err)
 			}
@@ -568,7 +597,7 @@ func (t *KD) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]b
 		return nil, nil
 
 	} else if function == "setSESCfg" { //设置每个货架的销售额奖励区间比例
-		if !Base.isAdmin(stub, accName) {
+		if !Base.IsAdmin(stub, accName) {
 			return nil, kdlogger.Errorf("Invoke(setSESCfg) can't exec by %s.", accName)
 		}
 
@@ -631,7 +660,7 @@ func (t *KD) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]b
 		return nil, nil
 
 	} else if function == "setFinanceCfg" {
-		if !Base.isAdmin(stub, accName) {
+		if !Base.IsAdmin(stub, accName) {
 			return nil, kdlogger.Errorf("Invoke(setFinanceCfg) can't exec by %s.", accName)
 		}
 
@@ -680,7 +709,7 @@ func (t *KD) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]b
 			stateKey = t.getRackFinancCfgKey(rackid)
 		}
 
-		err = stateCache.putState_Ex(stub, stateKey, rfcJson)
+		err = stateCache.PutState_Ex(stub, stateKey, rfcJson)
 		if err != nil {
 			return nil, kdlogger.Errorf("IInvoke(setFinanceCfg) PutState_Ex(rfc) error, err=%s.", err)
 		}
@@ -724,7 +753,7 @@ func (t *KD) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]b
 		return nil, nil
 
 	} else if function == "financeIssueFinish" {
-		if !Base.isAdmin(stub, accName) {
+		if !Base.IsAdmin(stub, accName) {
 			return nil, kdlogger.Errorf("Invoke(financeIssueFinish) can't exec by %s.", accName)
 		}
 
@@ -772,7 +801,7 @@ func (t *KD) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]b
 		return nil, nil
 
 	} else if function == "financeBouns" {
-		if !Base.isAdmin(stub, accName) {
+		if !Base.IsAdmin(stub, accName) {
 			return nil, kdlogger.Errorf("Invoke(financeBouns) can't exec by %s.", accName)
 		}
 
@@ -927,7 +956,7 @@ func (t *KD) Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]by
 
 		if len(allocKey) > 0 {
 			//是否是管理员帐户，管理员用户才可以查
-			if !Base.isAdmin(stub, accName) {
+			if !Base.IsAdmin(stub, accName) {
 				return nil, kdlogger.Errorf("queryRackAlloc: %s can't query allocKey.", accName)
 			}
 
@@ -938,7 +967,7 @@ func (t *KD) Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]by
 			}
 			return retValue, nil
 		} else {
-			if Base.isAdmin(stub, accName) {
+			if Base.IsAdmin(stub, accName) {
 				if len(txAcc) > 0 {
 					//查询某一个账户的分配情况
 					retValue, err := t.getOneAccAllocTxRecds(stub, txAcc, begSeq, txCount, begTime, endTime)
@@ -967,7 +996,7 @@ func (t *KD) Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]by
 		return nil, nil
 
 	} else if function == "getRackAllocCfg" {
-		if !Base.isAdmin(stub, accName) {
+		if !Base.IsAdmin(stub, accName) {
 			return nil, kdlogger.Errorf("getRackAllocCfg: %s can't query.", accName)
 		}
 
@@ -986,7 +1015,7 @@ func (t *KD) Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]by
 		return eapB, nil
 
 	} else if function == "getSESCfg" {
-		if !Base.isAdmin(stub, accName) {
+		if !Base.IsAdmin(stub, accName) {
 			return nil, kdlogger.Errorf("getSESCfg: %s can't query.", accName)
 		}
 
@@ -1005,7 +1034,7 @@ func (t *KD) Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]by
 		return sercB, nil
 
 	} else if function == "getRackFinanceCfg" {
-		if !Base.isAdmin(stub, accName) {
+		if !Base.IsAdmin(stub, accName) {
 			return nil, kdlogger.Errorf("getRackFinanceCfg: %s can't query.", accName)
 		}
 
@@ -1040,7 +1069,7 @@ func (t *KD) Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]by
 		return []byte(strconv.FormatInt(profit, 10)), nil
 
 	} else if function == "getRackRestFinanceCapacity" {
-		if !Base.isAdmin(stub, accName) {
+		if !Base.IsAdmin(stub, accName) {
 			return nil, kdlogger.Errorf("getRackFinanceCapacity: %s can't query.", accName)
 		}
 
@@ -1098,9 +1127,9 @@ func (t *KD) Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]by
 			return []byte(strconv.FormatInt(ERRCODE_TRANS_AMOUNT_INVALID, 10)), nil
 		}
 		//看是否有锁定金额
-		lockAmt, _, err := Base.getAccountLockedAmount(stub, accName, queryTime)
-		if err != nil {
-			return nil, kdlogger.Errorf("transPreCheck: getAccountLockedAmount(id=%s) failed. err=%s", accName, err)
+		lockAmt, _, errcm := Base.GetAccountLockedAmount(stub, accName, queryTime)
+		if errcm != nil {
+			return nil, kdlogger.Errorf("transPreCheck: getAccountLockedAmount(id=%s) failed. err=%s", accName, errcm)
 		}
 
 		if transAmount > accountEnt.RestAmount {
@@ -1112,9 +1141,9 @@ func (t *KD) Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]by
 		}
 
 		//收款账户是否存在  这个检查放到最后执行
-		exists, err := Base.isEntityExists(stub, toAcc)
-		if err != nil {
-			return nil, kdlogger.Errorf("transPreCheck: isEntityExists(%s) failed. err=%s", toAcc, err)
+		exists, errcm := Base.IsAccEntityExists(stub, toAcc)
+		if errcm != nil {
+			return nil, kdlogger.Errorf("transPreCheck: isEntityExists(%s) failed. err=%s", toAcc, errcm)
 		}
 		if !exists {
 			return []byte(strconv.FormatInt(ERRCODE_TRANS_PAYEE_ACCOUNT_NOT_EXIST, 10)), nil
@@ -1179,7 +1208,7 @@ func (t *KD) setAllocEarnTx(stub shim.ChaincodeStubInterface, rackid, allocKey s
 	}
 
 	seqKey := t.getAllocTxSeqKey(stub, rackid)
-	seq, err := Base.getTransSeq(stub, seqKey)
+	seq, err := t.getTransSeq(stub, seqKey)
 	if err != nil {
 		return nil, kdlogger.Errorf("setAllocEarnTx  getTransSeq failed.err=%s", err)
 	}
@@ -1195,12 +1224,12 @@ func (t *KD) setAllocEarnTx(stub shim.ChaincodeStubInterface, rackid, allocKey s
 	kdlogger.Debug("setAllocEarnTx return %s.", string(eatJson))
 
 	var txKey = t.getAllocTxKey(stub, rackid, seq)
-	err = stateCache.putState_Ex(stub, txKey, eatJson)
+	err = stateCache.PutState_Ex(stub, txKey, eatJson)
 	if err != nil {
 		return nil, kdlogger.Errorf("setAllocEarnTx  PutState_Ex failed.err=%s", err)
 	}
 
-	err = Base.setTransSeq(stub, seqKey, seq)
+	err = t.setTransSeq(stub, seqKey, seq)
 	if err != nil {
 		return nil, kdlogger.Errorf("setAllocEarnTx  setTransSeq failed.err=%s", err)
 	}
@@ -1258,7 +1287,7 @@ func (t *KD) getRackRolesAllocAmt(eap *EarningAllocRate, totalAmt int64) *RolesA
 func (t *KD) setOneAccAllocEarnTx(stub shim.ChaincodeStubInterface, accName, txKey string) error {
 	var accTxKey = t.getOneAccAllocTxKey(accName)
 
-	txsB, err := stateCache.getState_Ex(stub, accTxKey)
+	txsB, err := stateCache.GetState_Ex(stub, accTxKey)
 	if err != nil {
 		return kdlogger.Errorf("setOneAccAllocEarnTx: GetState err = %s", err)
 	}
@@ -1271,7 +1300,7 @@ func (t *KD) setOneAccAllocEarnTx(stub shim.ChaincodeStubInterface, accName, txK
 		newTxsB = append(newTxsB, MULTI_STRING_DELIM)
 	}
 
-	err = stateCache.putState_Ex(stub, accTxKey, newTxsB)
+	err = stateCache.PutState_Ex(stub, accTxKey, newTxsB)
 	if err != nil {
 		kdlogger.Error("setOneAccAllocEarnTx PutState failed.err=%s", err)
 		return err
@@ -1361,7 +1390,7 @@ func (t *KD) getAllocTxRecdByKey(stub shim.ChaincodeStubInterface, rackid, alloc
 
 	//先判断是否存在交易序列号了，如果不存在，说明还没有交易发生。 这里做这个判断是因为在 getTransSeq 里如果没有设置过序列号的key会自动设置一次，但是在query中无法执行PutStat，会报错
 	var seqKey = t.getAllocTxSeqKey(stub, rackid)
-	test, err := stateCache.getState_Ex(stub, seqKey)
+	test, err := stateCache.GetState_Ex(stub, seqKey)
 	if err != nil {
 		return nil, kdlogger.Errorf("getOneAllocRecd GetState(seqKey) failed. err=%s", err)
 	}
@@ -1371,7 +1400,7 @@ func (t *KD) getAllocTxRecdByKey(stub shim.ChaincodeStubInterface, rackid, alloc
 	}
 
 	//先获取当前最大的序列号
-	maxSeq, err := Base.getTransSeq(stub, seqKey)
+	maxSeq, err := t.getTransSeq(stub, seqKey)
 	if err != nil {
 		return nil, kdlogger.Errorf("getOneAllocRecd getTransSeq failed. err=%s", err)
 	}
@@ -1381,7 +1410,7 @@ func (t *KD) getAllocTxRecdByKey(stub shim.ChaincodeStubInterface, rackid, alloc
 	//从最后往前找，因为查找最新的可能性比较大
 	for i := maxSeq; i > 0; i-- { //序列号生成器从1开始
 		txkey := t.getAllocTxKey(stub, rackid, i)
-		txB, err := stateCache.getState_Ex(stub, txkey)
+		txB, err := stateCache.GetState_Ex(stub, txkey)
 		if err != nil {
 			kdlogger.Errorf("getOneAllocRecd GetState(rackid=%s) failed. err=%s", rackid, err)
 			continue
@@ -1434,7 +1463,7 @@ func (t *KD) getAllocTxRecds(stub shim.ChaincodeStubInterface, rackid string, be
 
 	//先判断是否存在交易序列号了，如果不存在，说明还没有交易发生。 这里做这个判断是因为在 getTransSeq 里如果没有设置过序列号的key会自动设置一次，但是在query中无法执行PutStat，会报错
 	var seqKey = t.getAllocTxSeqKey(stub, rackid)
-	test, err := stateCache.getState_Ex(stub, seqKey)
+	test, err := stateCache.GetState_Ex(stub, seqKey)
 	if err != nil {
 		return nil, kdlogger.Errorf("getAllocTxRecds GetState(seqKey) failed. err=%s", err)
 	}
@@ -1444,7 +1473,7 @@ func (t *KD) getAllocTxRecds(stub shim.ChaincodeStubInterface, rackid string, be
 	}
 
 	//先获取当前最大的序列号
-	maxSeq, err = Base.getTransSeq(stub, seqKey)
+	maxSeq, err = t.getTransSeq(stub, seqKey)
 	if err != nil {
 		return nil, kdlogger.Errorf("getAllocTxRecds getTransSeq failed. err=%s", err)
 	}
@@ -1468,7 +1497,7 @@ func (t *KD) getAllocTxRecds(stub shim.ChaincodeStubInterface, rackid string, be
 		}
 
 		txkey := t.getAllocTxKey(stub, rackid, loop)
-		txB, err := stateCache.getState_Ex(stub, txkey)
+		txB, err := stateCache.GetState_Ex(stub, txkey)
 		if err != nil {
 			kdlogger.Errorf("getAllocTxRecds GetState(rackid=%s) failed. err=%s", rackid, err)
 			continue
@@ -1523,7 +1552,7 @@ func (t *KD) getOneAccAllocTxRecds(stub shim.ChaincodeStubInterface, accName str
 		count = math.MaxInt64
 	}
 
-	txsB, err := stateCache.getState_Ex(stub, accTxKey)
+	txsB, err := stateCache.GetState_Ex(stub, accTxKey)
 	if err != nil {
 		return nil, kdlogger.Errorf("getOneAccAllocTxRecds: GetState(accName=%s) err = %s", accName, err)
 	}
@@ -1601,7 +1630,7 @@ func (t *KD) getOneAccAllocTx(stub shim.ChaincodeStubInterface, txKey, accName s
 }
 
 func (t *KD) getAllocTxRecdEntity(stub shim.ChaincodeStubInterface, txKey string) (*EarningAllocTx, error) {
-	txB, err := stateCache.getState_Ex(stub, txKey)
+	txB, err := stateCache.GetState_Ex(stub, txKey)
 	if err != nil {
 		return nil, kdlogger.Errorf("getAllocTxRecdEntity GetState(txKey=%s) failed. err=%s", txKey, err)
 	}
@@ -1623,7 +1652,7 @@ func (t *KD) getRackAllocCfg(stub shim.ChaincodeStubInterface, rackid string, pe
 	var err error
 
 	if rackid != "*" {
-		eapB, err = stateCache.getState_Ex(stub, t.getRackAllocRateKey(rackid))
+		eapB, err = stateCache.GetState_Ex(stub, t.getRackAllocRateKey(rackid))
 		if err != nil {
 			return nil, kdlogger.Errorf("getRackAllocCfg GetState(rackid=%s) failed. err=%s", rackid, err)
 		}
@@ -1632,7 +1661,7 @@ func (t *KD) getRackAllocCfg(stub shim.ChaincodeStubInterface, rackid string, pe
 	if eapB == nil {
 		kdlogger.Warn("getRackAllocCfg GetState(rackid=%s) nil, try to get global.", rackid)
 		//没有为该货架单独配置，返回global配置
-		eapB, err = stateCache.getState_Ex(stub, t.getGlobalRackAllocRateKey())
+		eapB, err = stateCache.GetState_Ex(stub, t.getGlobalRackAllocRateKey())
 		if err != nil {
 			return nil, kdlogger.Errorf("getRackAllocCfg GetState(global, rackid=%s) failed. err=%s", rackid, err)
 		}
@@ -1742,7 +1771,7 @@ func (t *KD) setRackEncourageScoreCfg(stub shim.ChaincodeStubInterface, rackid,
 		stateKey = t.getRackEncourageScoreCfgKey(rackid)
 	}
 
-	err = stateCache.putState_Ex(stub, stateKey, sepcJson)
+	err = stateCache.PutState_Ex(stub, stateKey, sepcJson)
 	if err != nil {
 		return nil, kdlogger.Errorf("setRackEncourageScoreCfg PutState_Ex failed. err=%s", err)
 	}
@@ -1756,7 +1785,7 @@ func (t *KD) getRackEncourageScoreCfg(stub shim.ChaincodeStubInterface, rackid s
 	var err error
 
 	if rackid != "*" {
-		sepcB, err = stateCache.getState_Ex(stub, t.getRackEncourageScoreCfgKey(rackid))
+		sepcB, err = stateCache.GetState_Ex(stub, t.getRackEncourageScoreCfgKey(rackid))
 		if err != nil {
 			return nil, kdlogger.Errorf("getRackEncourageScoreCfg GetState failed.rackid=%s err=%s", rackid, err)
 		}
@@ -1764,7 +1793,7 @@ func (t *KD) getRackEncourageScoreCfg(stub shim.ChaincodeStubInterface, rackid s
 
 	if sepcB == nil {
 		kdlogger.Warn("getRackEncourageScoreCfg: can not find cfg for %s, will use golobal.", rackid)
-		sepcB, err = stateCache.getState_Ex(stub, t.getGlobalRackEncourageScoreCfgKey())
+		sepcB, err = stateCache.GetState_Ex(stub, t.getGlobalRackEncourageScoreCfgKey())
 		if err != nil {
 			return nil, kdlogger.Errorf("getRackEncourageScoreCfg GetState(global cfg) failed.rackid=%s err=%s", rackid, err)
 		}
@@ -1923,7 +1952,7 @@ func (t *KD) allocEncourageScore(stub shim.ChaincodeStubInterface, rrs *RackRole
 
 	rolesAllocScore := t.getRackRolesAllocAmt(&ear, rrs.Scores)
 
-	_, err = Base.transferCoin(stub, transFromAcc, rrs.SellerAcc, transType, transDesc,
+	_, err = t.transferCoin(stub, transFromAcc, rrs.SellerAcc, transType, transDesc,
 		rolesAllocScore.SellerAmount+sellerComps, invokeTime, sameEntSaveTx)
 	if err != nil {
 		kdlogger.Errorf("allocEncourageScore: transferCoin(SellerAcc=%s) failed, error=%s.", rrs.SellerAcc, err)
@@ -1931,7 +1960,7 @@ func (t *KD) allocEncourageScore(stub shim.ChaincodeStubInterface, rrs *RackRole
 		failedAccList = append(failedAccList, rrs.SellerAcc)
 	}
 
-	_, err = Base.transferCoin(stub, transFromAcc, rrs.FielderAcc, transType, transDesc,
+	_, err = t.transferCoin(stub, transFromAcc, rrs.FielderAcc, transType, transDesc,
 		rolesAllocScore.FielderAmount, invokeTime, sameEntSaveTx)
 	if err != nil {
 		kdlogger.Errorf("allocEncourageScore: transferCoin(FielderAcc=%s) failed, error=%s.", rrs.FielderAcc, err)
@@ -1939,7 +1968,7 @@ func (t *KD) allocEncourageScore(stub shim.ChaincodeStubInterface, rrs *RackRole
 		failedAccList = append(failedAccList, rrs.FielderAcc)
 	}
 
-	_, err = Base.transferCoin(stub, transFromAcc, rrs.DeliveryAcc, transType, transDesc,
+	_, err = t.transferCoin(stub, transFromAcc, rrs.DeliveryAcc, transType, transDesc,
 		rolesAllocScore.DeliveryAmount, invokeTime, sameEntSaveTx)
 	if err != nil {
 		kdlogger.Errorf("allocEncourageScore: transferCoin(DeliveryAcc=%s) failed, error=%s.", rrs.DeliveryAcc, err)
@@ -1947,7 +1976,7 @@ func (t *KD) allocEncourageScore(stub shim.ChaincodeStubInterface, rrs *RackRole
 		failedAccList = append(failedAccList, rrs.DeliveryAcc)
 	}
 
-	_, err = Base.transferCoin(stub, transFromAcc, rrs.PlatformAcc, transType, transDesc,
+	_, err = t.transferCoin(stub, transFromAcc, rrs.PlatformAcc, transType, transDesc,
 		rolesAllocScore.PlatformAmount, invokeTime, sameEntSaveTx)
 	if err != nil {
 		kdlogger.Errorf("allocEncourageScore: transferCoin(PlatformAcc=%s) failed, error=%s.", rrs.PlatformAcc, err)
@@ -2052,7 +2081,7 @@ func (t *KD) getRackFinancCfg(stub shim.ChaincodeStubInterface, rackid string, p
 	var err error
 
 	if rackid != "*" { // "*"表示查询全局配置
-		rfcB, err = stateCache.getState_Ex(stub, t.getRackFinancCfgKey(rackid))
+		rfcB, err = stateCache.GetState_Ex(stub, t.getRackFinancCfgKey(rackid))
 		if err != nil {
 			return nil, kdlogger.Errorf("getRackFinancCfg GetState failed.rackid=%s err=%s", rackid, err)
 		}
@@ -2060,7 +2089,7 @@ func (t *KD) getRackFinancCfg(stub shim.ChaincodeStubInterface, rackid string, p
 
 	if rfcB == nil {
 		kdlogger.Warn("getRackFinancCfg: can not find cfg for %s, will use golobal.", rackid)
-		rfcB, err = stateCache.getState_Ex(stub, t.getGlobalRackFinancCfgKey())
+		rfcB, err = stateCache.GetState_Ex(stub, t.getGlobalRackFinancCfgKey())
 		if err != nil {
 			return nil, kdlogger.Errorf("getRackFinancCfg GetState(global cfg) failed.rackid=%s err=%s", rackid, err)
 		}
@@ -2092,7 +2121,7 @@ func (t *KD) getRackFinacInfoKey(rackId, finacId string) string {
 //用户购买理财，包括自动续期
 func (t *KD) userBuyFinance(stub shim.ChaincodeStubInterface, accName, rackid, fid, payee, transType, desc string, amount, invokeTime int64, sameEntSaveTx, isRenewal bool) ([]byte, error) {
 	var fiacInfoKey = t.getFinacInfoKey(fid)
-	fiB, err := stateCache.getState_Ex(stub, fiacInfoKey)
+	fiB, err := stateCache.GetState_Ex(stub, fiacInfoKey)
 	if err != nil {
 		return nil, kdlogger.Errorf("userBuyFinance:  GetState(%s) failed. err=%s.", fiacInfoKey, err)
 	}
@@ -2112,7 +2141,7 @@ func (t *KD) userBuyFinance(stub shim.ChaincodeStubInterface, accName, rackid, f
 	}
 
 	var rackInfoKey = t.getRackInfoKey(rackid)
-	riB, err := stateCache.getState_Ex(stub, rackInfoKey)
+	riB, err := stateCache.GetState_Ex(stub, rackInfoKey)
 	if err != nil {
 		return nil, kdlogger.Errorf("userBuyFinance:  GetState(%s) failed. err=%s.", rackInfoKey, err)
 	}
@@ -2133,7 +2162,7 @@ func (t *KD) userBuyFinance(stub shim.ChaincodeStubInterface, accName, rackid, f
 
 	//写入货架融资信息
 	rackFinacInfoKey := t.getRackFinacInfoKey(rackid, fid)
-	rfiB, err := stateCache.getState_Ex(stub, rackFinacInfoKey)
+	rfiB, err := stateCache.GetState_Ex(stub, rackFinacInfoKey)
 	if err != nil {
 		return nil, kdlogger.Errorf("userBuyFinance:  GetState(%s) failed. err=%s.", rackFinacInfoKey, err)
 	}
@@ -2181,13 +2210,13 @@ func (t *KD) userBuyFinance(stub shim.ChaincodeStubInterface, accName, rackid, f
 		_, ok := rfi.UserAmountMap[accName]
 		if ok {
 			//如果用户已提取了，又来买，那么从新记录投资额，不能累计，否则会把前一次的累计进来。
-			if strSliceContains(rfi.PayFinanceUserList, accName) {
+			if StrSliceContains(rfi.PayFinanceUserList, accName) {
 				rfi.AmountFinca -= rfi.UserAmountMap[accName] //实际投资额度要减去上一次的
 				rfi.UserAmountMap[accName] = amount
 				if isRenewal {
 					rfi.UserRenewalMap[accName] = amount
 				}
-				rfi.PayFinanceUserList = strSliceDelete(rfi.PayFinanceUserList, accName)
+				rfi.PayFinanceUserList = StrSliceDelete(rfi.PayFinanceUserList, accName)
 			} else {
 				rfi.UserAmountMap[accName] += amount
 				if isRenewal {
@@ -2240,7 +2269,7 @@ func (t *KD) userBuyFinance(stub shim.ChaincodeStubInterface, accName, rackid, f
 
 	//用户给融资方转账
 	if !isRenewal {
-		_, err = Base.transferCoin(stub, accName, payee, transType, desc, amount, invokeTime, sameEntSaveTx)
+		_, err = t.transferCoin(stub, accName, payee, transType, desc, amount, invokeTime, sameEntSaveTx)
 		if err != nil {
 			return nil, kdlogger.Errorf("userBuyFinance: transferCoin failed. err=%s.", err)
 		}
@@ -2271,7 +2300,7 @@ func (t *KD) userBuyFinance(stub shim.ChaincodeStubInterface, accName, rackid, f
 	}
 	kdlogger.Debug("userBuyFinance: ent=%+v", arfi)
 
-	if !strSliceContains(fi.RackList, ri.RackID) {
+	if !StrSliceContains(fi.RackList, ri.RackID) {
 		fi.RackList = append(fi.RackList, ri.RackID)
 	}
 	fiJson, err := json.Marshal(fi)
@@ -2279,7 +2308,7 @@ func (t *KD) userBuyFinance(stub shim.ChaincodeStubInterface, accName, rackid, f
 		return nil, kdlogger.Errorf("userBuyFinance:  Marshal failed. err=%s.", err)
 	}
 
-	if !strSliceContains(ri.FinacList, fi.FID) {
+	if !StrSliceContains(ri.FinacList, fi.FID) {
 		ri.FinacList = append(ri.FinacList, fi.FID)
 	}
 
@@ -2292,17 +2321,17 @@ func (t *KD) userBuyFinance(stub shim.ChaincodeStubInterface, accName, rackid, f
 		return nil, kdlogger.Errorf("userBuyFinance:  Marshal failed. err=%s.", err)
 	}
 
-	err = stateCache.putState_Ex(stub, rackFinacInfoKey, rfiJson)
+	err = stateCache.PutState_Ex(stub, rackFinacInfoKey, rfiJson)
 	if err != nil {
 		return nil, kdlogger.Errorf("userBuyFinance:  PutState failed. err=%s.", err)
 	}
 
-	err = stateCache.putState_Ex(stub, rackInfoKey, riJson)
+	err = stateCache.PutState_Ex(stub, rackInfoKey, riJson)
 	if err != nil {
 		return nil, kdlogger.Errorf("userBuyFinance:  PutState failed. err=%s.", err)
 	}
 
-	err = stateCache.putState_Ex(stub, fiacInfoKey, fiJson)
+	err = stateCache.PutState_Ex(stub, fiacInfoKey, fiJson)
 	if err != nil {
 		return nil, kdlogger.Errorf("userBuyFinance:  PutState failed. err=%s.", err)
 	}
@@ -2373,7 +2402,7 @@ func (t *KD) financeBonus(stub shim.ChaincodeStubInterface, fid, rackales string
 func (t *KD) financeBonus4OneRack(stub shim.ChaincodeStubInterface, rackid, fid string, sales, invokeTime int64) error {
 	var rackFinacInfoKey = t.getRackFinacInfoKey(rackid, fid)
 
-	rfiB, err := stateCache.getState_Ex(stub, rackFinacInfoKey)
+	rfiB, err := stateCache.GetState_Ex(stub, rackFinacInfoKey)
 	if err != nil {
 		return kdlogger.Errorf("financeBonus4OneRack:  GetState(%s) failed. err=%s.", rackFinacInfoKey, err)
 	}
@@ -2434,7 +2463,7 @@ func (t *KD) financeBonus4OneRack(stub shim.ChaincodeStubInterface, rackid, fid
 		return kdlogger.Errorf("financeBonus4OneRack:  Marshal failed. err=%s.", err)
 	}
 
-	err = stateCache.putState_Ex(stub, rackFinacInfoKey, rfiJson)
+	err = stateCache.PutState_Ex(stub, rackFinacInfoKey, rfiJson)
 	if err != nil {
 		return kdlogger.Errorf("financeBonus4OneRack:  PutState failed. err=%s.", err)
 	}
@@ -2452,7 +2481,7 @@ func (t *KD) setCurrentFid(stub shim.ChaincodeStubInterface, currentFid string)
 		return nil
 	}
 
-	hisB, err := stateCache.getState_Ex(stub, RACKFINACHISTORY_KEY)
+	hisB, err := stateCache.GetState_Ex(stub, RACKFINACHISTORY_KEY)
 	if err != nil {
 		return kdlogger.Errorf("setCurrentFid: GetState failed. err=%s.", err)
 	}
@@ -2481,7 +2510,7 @@ func (t *KD) setCurrentFid(stub shim.ChaincodeStubInterface, currentFid string)
 		return kdlogger.Errorf("setCurrentFid: Marshal failed. err=%s.", err)
 	}
 
-	err = stateCache.putState_Ex(stub, RACKFINACHISTORY_KEY, hisB)
+	err = stateCache.PutState_Ex(stub, RACKFINACHISTORY_KEY, hisB)
 	if err != nil {
 		return kdlogger.Errorf("setCurrentFid: PutState_Ex failed. err=%s.", err)
 	}
@@ -2492,7 +2521,7 @@ func (t *KD) setCurrentFid(stub shim.ChaincodeStubInterface, currentFid string)
 }
 
 func (t *KD) getPrevAndCurrFids(stub shim.ChaincodeStubInterface) (*RackFinancHistory, error) {
-	hisB, err := stateCache.getState_Ex(stub, RACKFINACHISTORY_KEY)
+	hisB, err := stateCache.GetState_Ex(stub, RACKFINACHISTORY_KEY)
 	if err != nil {
 		return nil, kdlogger.Errorf("getPrevAndCurrFids: GetState failed. err=%s.", err)
 	}
@@ -2557,7 +2586,7 @@ func (t *KD) getUserInvestAmount(stub shim.ChaincodeStubInterface, accName, rack
 		return 0, nil
 	}
 
-	rfiB, err := stateCache.getState_Ex(stub, t.getRackFinacInfoKey(rackid, fid))
+	rfiB, err := stateCache.GetState_Ex(stub, t.getRackFinacInfoKey(rackid, fid))
 	if err != nil {
 		return 0, kdlogger.Errorf("getUserHistoryFinance:  GetState failed. err=%s.", err)
 	}
@@ -2588,7 +2617,7 @@ func (t *KD) getRackFinanceAmount(stub shim.ChaincodeStubInterface, rackid, fid
 	   }
 	*/
 
-	rfiB, err := stateCache.getState_Ex(stub, t.getRackFinacInfoKey(rackid, fid))
+	rfiB, err := stateCache.GetState_Ex(stub, t.getRackFinacInfoKey(rackid, fid))
 	if err != nil {
 		return 0, kdlogger.Errorf("getRackHistoryFinance:  GetState failed. err=%s.", err)
 	}
@@ -2603,7 +2632,7 @@ func (t *KD) getRackFinanceAmount(stub shim.ChaincodeStubInterface, rackid, fid
 	}
 	var totalAmt int64 = 0
 	for acc, amt := range rfi.UserAmountMap {
-		if !strSliceContains(rfi.PayFinanceUserList, acc) {
+		if !StrSliceContains(rfi.PayFinanceUserList, acc) {
 			totalAmt += amt
 		}
 	}
@@ -2613,12 +2642,12 @@ func (t *KD) getRackFinanceAmount(stub shim.ChaincodeStubInterface, rackid, fid
 
 func (t *KD) financeIssueFinishAfter(stub shim.ChaincodeStubInterface, currentFid string, invokeTime int64) error {
 	//看是否已经处理过
-	finishIdB, err := stateCache.getState_Ex(stub, RACKFINACISSUEFINISHID_KEY)
+	finishIdB, err := stateCache.GetState_Ex(stub, RACKFINACISSUEFINISHID_KEY)
 	if err != nil {
 		return kdlogger.Errorf("financeIssueFinishAfter: GetState(finishId) failed. err=%s.", err)
 	}
 	if finishIdB == nil {
-		err = stateCache.putState_Ex(stub, RACKFINACISSUEFINISHID_KEY, []byte(currentFid))
+		err = stateCache.PutState_Ex(stub, RACKFINACISSUEFINISHID_KEY, []byte(currentFid))
 		if err != nil {
 			return kdlogger.Errorf("financeIssueFinishAfter: PutState_Ex(finishId) failed. err=%s.", err)
 		}
@@ -2631,7 +2660,7 @@ func (t *KD) financeIssueFinishAfter(stub shim.ChaincodeStubInterface, currentFi
 	}
 
 	//给本期理财设置为"发行完毕"
-	fiB, err := stateCache.getState_Ex(stub, t.getFinacInfoKey(currentFid))
+	fiB, err := stateCache.GetState_Ex(stub, t.getFinacInfoKey(currentFid))
 	if err != nil {
 		return kdlogger.Errorf("financeIssueFinishAfter: GetState(fi=%s) failed. err=%s.", currentFid, err)
 	}
@@ -2645,7 +2674,7 @@ func (t *KD) financeIssueFinishAfter(stub shim.ChaincodeStubInterface, currentFi
 
 		for _, rackid := range fi.RackList {
 			var rfiKey = t.getRackFinacInfoKey(rackid, currentFid)
-			rfiB, err := stateCache.getState_Ex(stub, rfiKey)
+			rfiB, err := stateCache.GetState_Ex(stub, rfiKey)
 			if err != nil {
 				return kdlogger.Errorf("financeIssueFinishAfter: GetState(rfi=%s,%s) failed. err=%s.", rackid, currentFid, err)
 			}
@@ -2672,7 +2701,7 @@ func (t *KD) financeIssueFinishAfter(stub shim.ChaincodeStubInterface, currentFi
 				return kdlogger.Errorf("financeIssueFinishAfter: Marshal(rfi=%s,%s) failed. err=%s.", rackid, currentFid, err)
 			}
 
-			err = stateCache.putState_Ex(stub, rfiKey, rfiB)
+			err = stateCache.PutState_Ex(stub, rfiKey, rfiB)
 			if err != nil {
 				return kdlogger.Errorf("financeIssueFinishAfter: PutState_Ex(rfi=%s,%s) failed. err=%s.", rackid, currentFid, err)
 			}
@@ -2703,7 +2732,7 @@ func (t *KD) financeRenewalPreviousFinance(stub shim.ChaincodeStubInterface, cur
 		return kdlogger.Errorf("financeRenewal: preFid == currentFid, error.")
 	}
 
-	fiB, err := stateCache.getState_Ex(stub, t.getFinacInfoKey(preFid))
+	fiB, err := stateCache.GetState_Ex(stub, t.getFinacInfoKey(preFid))
 	if err != nil {
 		return kdlogger.Errorf("financeRenewal: GetState(fi=%s) failed. err=%s.", preFid, err)
 	}
@@ -2722,7 +2751,7 @@ func (t *KD) financeRenewalPreviousFinance(stub shim.ChaincodeStubInterface, cur
 
 	for _, rackid := range fi.RackList {
 		var rfiKey = t.getRackFinacInfoKey(rackid, preFid)
-		rfiB, err := stateCache.getState_Ex(stub, rfiKey)
+		rfiB, err := stateCache.GetState_Ex(stub, rfiKey)
 		if err != nil {
 			return kdlogger.Errorf("financeRenewal: GetState(rfi=%s,%s) failed. err=%s.", rackid, preFid, err)
 		}
@@ -2740,7 +2769,7 @@ func (t *KD) financeRenewalPreviousFinance(stub shim.ChaincodeStubInterface, cur
 
 		for acc, amt := range rfi.UserAmountMap {
 			//已赎回的用户不在续期
-			if strSliceContains(rfi.PayFinanceUserList, acc) {
+			if StrSliceContains(rfi.PayFinanceUserList, acc) {
 				continue
 			}
 
@@ -2790,7 +2819,7 @@ func (t *KD) payUserFinance(stub shim.ChaincodeStubInterface, accName, reacc, ra
 		}
 
 		var rfiKey = t.getRackFinacInfoKey(rackid, f)
-		rfiB, err := stateCache.getState_Ex(stub, rfiKey)
+		rfiB, err := stateCache.GetState_Ex(stub, rfiKey)
 		if err != nil {
 			return kdlogger.Errorf("payUserFinance:  GetState(%s,%s) failed. err=%s.", rackid, f, err)
 		}
@@ -2805,7 +2834,7 @@ func (t *KD) payUserFinance(stub shim.ChaincodeStubInterface, accName, reacc, ra
 		}
 
 		//如果已提取过，则不能再提取。这里不报错，不实际执行转账即可
-		if strSliceContains(rfi.PayFinanceUserList, reacc) {
+		if StrSliceContains(rfi.PayFinanceUserList, reacc) {
 			kdlogger.Warn("payUserFinance: %s has paid already, do nothing.", reacc)
 			continue
 		}
@@ -2820,7 +2849,7 @@ func (t *KD) payUserFinance(stub shim.ChaincodeStubInterface, accName, reacc, ra
 			return kdlogger.Errorf("payUserFinance:  Marshal(%s,%s) failed. err=%s.", rackid, f, err)
 		}
 
-		err = stateCache.putState_Ex(stub, rfiKey, rfiB)
+		err = stateCache.PutState_Ex(stub, rfiKey, rfiB)
 		if err != nil {
 			return kdlogger.Errorf("payUserFinance:  PutState_Ex(%s,%s) failed. err=%s.", rackid, f, err)
 		}
@@ -2836,14 +2865,14 @@ func (t *KD) payUserFinance(stub shim.ChaincodeStubInterface, accName, reacc, ra
 
 	kdlogger.Debug("payUserFinance: %s will pay %d to %s.", accName, totalAmt, reacc)
 
-	_, err = Base.transferCoin(stub, accName, reacc, transType, desc, totalAmt, invokeTime, sameEntSaveTx)
+	_, err = t.transferCoin(stub, accName, reacc, transType, desc, totalAmt, invokeTime, sameEntSaveTx)
 	if err != nil {
 		return kdlogger.Errorf("payUserFinance:  transferCoin(%s) failed. err=%s.", reacc, err)
 	}
 
 	//将赎回的理财期号写入已赎回列表
 	for _, fid := range paidFidList {
-		if !strSliceContains(reaccEnt.PaidFidList, fid) {
+		if !StrSliceContains(reaccEnt.PaidFidList, fid) {
 			reaccEnt.PaidFidList = append(reaccEnt.PaidFidList, fid)
 		}
 	}
@@ -2895,7 +2924,7 @@ func (t *KD) getUserFinanceProfit(stub shim.ChaincodeStubInterface, accName, rac
 		}
 
 		var rfiKey = t.getRackFinacInfoKey(rackid, f)
-		rfiB, err := stateCache.getState_Ex(stub, rfiKey)
+		rfiB, err := stateCache.GetState_Ex(stub, rfiKey)
 		if err != nil {
 			return profit, kdlogger.Errorf("getUserFinanceProfit:  GetState(%s,%s) failed. err=%s.", rackid, f, err)
 		}
@@ -2995,7 +3024,7 @@ func (t *KD) _getAccRackFinanceTx(stub shim.ChaincodeStubInterface, accName, rac
 		}
 
 		var rfiKey = t.getRackFinacInfoKey(rackid, f)
-		rfiB, err := stateCache.getState_Ex(stub,rfiKey)
+		rfiB, err := stateCache.GetState_Ex(stub,rfiKey)
 		if err != nil {
 			return mylog.Errorf("payUserFinance:  GetState(%s,%s) failed. err=%s.", rackid, f, err)
 		}
@@ -3134,7 +3163,7 @@ func (t *KD) getAccountRackInvestInfoKey(accName string) string {
 }
 
 func (t *KD) getAccountRackInvestInfo(stub shim.ChaincodeStubInterface, accName string) (*AccRackInvest, error) {
-	arfiB, err := stateCache.getState_Ex(stub, t.getAccountRackInvestInfoKey(accName))
+	arfiB, err := stateCache.GetState_Ex(stub, t.getAccountRackInvestInfoKey(accName))
 	if err != nil {
 		return nil, kdlogger.Errorf("getAccountRackInvestInfo: GetState failed.err=%s, acc=%s", err, accName)
 	}
@@ -3162,7 +3191,7 @@ func (t *KD) setAccountRackInvestInfo(stub shim.ChaincodeStubInterface, accRackI
 		return kdlogger.Errorf("setAccountRackInvestInfo: Marshal failed.err=%s, acc=%s", err, accName)
 	}
 
-	err = stateCache.putState_Ex(stub, t.getAccountRackInvestInfoKey(accName), ariB)
+	err = stateCache.PutState_Ex(stub, t.getAccountRackInvestInfoKey(accName), ariB)
 	if err != nil {
 		return kdlogger.Errorf("setAccountRackInvestInfo: putState_Ex failed.err=%s, acc=%s", err, accName)
 	}
@@ -3177,12 +3206,12 @@ func (t *KD) setAccountPasswd(stub shim.ChaincodeStubInterface, accName, pwd str
 
 	salt := md5.Sum([]byte(accName))
 
-	hash, err := baseCrypto.GenCipher(pwd, salt[:])
+	hash, err := kdCrypto.GenCipher(pwd, salt[:])
 	if err != nil {
 		return kdlogger.Errorf("setAccountPasswd: GenCipher failed.err=%s, acc=%s", err, accName)
 	}
 
-	err = stateCache.putState_Ex(stub, t.getUserCipherKey(accName), hash)
+	err = stateCache.PutState_Ex(stub, t.getUserCipherKey(accName), hash)
 	if err != nil {
 		return kdlogger.Errorf("setAccountPasswd: putState_Ex failed.err=%s, acc=%s", err, accName)
 	}
@@ -3192,7 +3221,7 @@ func (t *KD) setAccountPasswd(stub shim.ChaincodeStubInterface, accName, pwd str
 func (t *KD) authAccountPasswd(stub shim.ChaincodeStubInterface, accName, pwd string) (bool, error) {
 	var err error
 
-	cipher, err := stateCache.getState_Ex(stub, t.getUserCipherKey(accName))
+	cipher, err := stateCache.GetState_Ex(stub, t.getUserCipherKey(accName))
 	if err != nil {
 		return false, kdlogger.Errorf("AuthAccountPasswd: GetState failed.err=%s, acc=%s", err, accName)
 	}
@@ -3201,7 +3230,7 @@ func (t *KD) authAccountPasswd(stub shim.ChaincodeStubInterface, accName, pwd st
 		return false, kdlogger.Errorf("AuthAccountPasswd: Cipher is nil, acc=%s", accName)
 	}
 
-	ok, err := baseCrypto.AuthPass(cipher, pwd)
+	ok, err := kdCrypto.AuthPass(cipher, pwd)
 	if err != nil {
 		return false, kdlogger.Errorf("AuthAccountPasswd: AuthPass failed.err=%s, acc=%s", err, accName)
 	}
@@ -3215,7 +3244,7 @@ func (t *KD) authAccountPasswd(stub shim.ChaincodeStubInterface, accName, pwd st
 
 func (t *KD) isSetAccountPasswd(stub shim.ChaincodeStubInterface, accName string) (bool, error) {
 
-	cipher, err := stateCache.getState_Ex(stub, t.getUserCipherKey(accName))
+	cipher, err := stateCache.GetState_Ex(stub, t.getUserCipherKey(accName))
 	if err != nil {
 		return false, kdlogger.Errorf("isSetAccountPasswd: GetState failed.err=%s, acc=%s", err, accName)
 	}
@@ -3251,7 +3280,7 @@ func (t *KD) decodeAccountPasswd(pwdBase64 string) (string, error) {
 		return "", kdlogger.Errorf("decodeAccountPasswd: DecodeString failed. err=%s", err)
 	}
 
-	pwdB, err := baseCrypto.AESDecrypt(256, []byte(PWD_ENCRYPT_KEY), []byte(PWD_ENCRYPT_IV), pwdEncrypt)
+	pwdB, err := kdCrypto.AESDecrypt(256, []byte(PWD_ENCRYPT_KEY), []byte(PWD_ENCRYPT_IV), pwdEncrypt)
 	if err != nil {
 		return "", kdlogger.Errorf("decodeAccountPasswd: AESDecrypt failed. err=%s", err)
 	}
@@ -3259,7 +3288,16 @@ func (t *KD) decodeAccountPasswd(pwdBase64 string) (string, error) {
 	return string(pwdB), nil
 }
 
-func (t *KD) dateConvertWhenLoad(stub shim.ChaincodeStubInterface, srcCcid, key string, valueB []byte) (string, []byte, error) {
+func (t *KD) dateConvertWhenLoad(stub shim.ChaincodeStubInterface, srcCcid, key string, valueB []byte) (string, []byte, *ErrorCodeMsg) {
+	newKey, newValB, err := t.convertKd10State(stub, srcCcid, key, valueB)
+	if err != nil {
+		return "", nil, kdlogger.ErrorECM(ERRCODE_COMMON_INNER_ERROR, "dateConvertWhenLoad: convert key '%s' failed, err=%s", key, err)
+	}
+
+	return newKey, newValB, nil
+}
+
+func (t *KD) convertKd10State(stub shim.ChaincodeStubInterface, srcCcid, key string, valueB []byte) (string, []byte, error) {
 	var err error
 	var newKey = key
 	var newValB = valueB
@@ -3291,11 +3329,9 @@ func (t *KD) dateConvertWhenLoad(stub shim.ChaincodeStubInterface, srcCcid, key
 			}
 
 			var newEnt AccountEntity
-			existEnt, err := Base.getAccountEntity(stub, oldEnt.EntID)
-			if err != nil {
-				if err != ErrNilEntity {
-					return "", nil, kdlogger.Errorf("dateConvertWhenLoad: getAccountEntity failed, err=%s", err)
-				}
+			existEnt, errcm := Base.GetAccountEntity(stub, oldEnt.EntID)
+			if errcm != nil && errcm != ErrcmNilEntity {
+				return "", nil, kdlogger.Errorf("dateConvertWhenLoad: getAccountEntity failed, err=%s", errcm)
 			}
 
 			if existEnt == nil {
@@ -3317,7 +3353,7 @@ func (t *KD) dateConvertWhenLoad(stub shim.ChaincodeStubInterface, srcCcid, key
 				newEnt.Owner = oldEnt.Owner
 			}
 
-			err = stateCache.putState_Ex(stub, t.getUserCipherKey(oldEnt.EntID), oldEnt.Cipher)
+			err = stateCache.PutState_Ex(stub, t.getUserCipherKey(oldEnt.EntID), oldEnt.Cipher)
 			if err != nil {
 				return "", nil, kdlogger.Errorf("dateConvertWhenLoad: putState_Ex(newEnt) failed, err=%s", err)
 			}
@@ -3342,7 +3378,7 @@ func (t *KD) dateConvertWhenLoad(stub shim.ChaincodeStubInterface, srcCcid, key
 
 	return newKey, newValB, nil
 }
-func (t *KD) loadAfter(stub shim.ChaincodeStubInterface, srcCcid string) error {
+func (t *KD) loadAfter(stub shim.ChaincodeStubInterface, srcCcid string) *ErrorCodeMsg {
 
 	if srcCcid == "" {
 
@@ -3354,3 +3390,38 @@ func (t *KD) loadAfter(stub shim.ChaincodeStubInterface, srcCcid string) error {
 func (t *KD) getUserCipherKey(accName string) string {
 	return ACCOUT_CIPHER_PREFIX + accName
 }
+
+//base的转账函数，kd的交易都没有appid
+func (t *KD) transferCoin(stub shim.ChaincodeStubInterface, from, to, transType, description string, amount, transeTime int64, sameEntSaveTrans bool) ([]byte, error) {
+	retB, errcm := Base.TransferCoin(stub, from, to, transType, description, amount, transeTime, sameEntSaveTrans, "")
+	if errcm != nil {
+		return nil, kdlogger.Errorf("transferCoin failed. err=%s", errcm)
+	}
+
+	return retB, nil
+}
+
+func (t *KD) getTransSeq(stub shim.ChaincodeStubInterface, transSeqKey string) (int64, error) {
+	seq, errcm := Base.GetTransSeq(stub, transSeqKey)
+	if errcm != nil {
+		return -1, kdlogger.Errorf("getTransSeq failed. err=%s", errcm)
+	}
+
+	return seq, nil
+}
+
+func (t *KD) setTransSeq(stub shim.ChaincodeStubInterface, transSeqKey string, seq int64) error {
+	errcm := Base.SetTransSeq(stub, transSeqKey, seq)
+	if errcm != nil {
+		return kdlogger.Errorf("setTransSeq failed. err=%s", errcm)
+	}
+
+	return nil
+}
+
+func main() {
+	err := shim.Start(&Base)
+	if err != nil {
+		kdlogger.Error("Error starting kd chaincode: %s", err)
+	}
+}
@@ -604,7 +604,7 @@ function parseParams_Query(params) {
 
         args.push(reacc, pwd, amt)
 
-    } else if (func == "getInfoForWeb") {
+    } else if (func == "getStatisticInfo") {
         args.push("kdcoinpool") //Ŀǰͨҵ˻
     }
   
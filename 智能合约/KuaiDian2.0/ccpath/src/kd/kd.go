@@ -5,15 +5,20 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	//"errors"
-	//"fmt"
+	"fmt"
 	"io"
 	"math"
-	//"sort"
-	"crypto/md5"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 
+	"loulan/llcrypto"
+
+	. "loulan/ccutil"
+	. "loulan/errcode"
+	. "loulan/mylog"
+
 	//"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	//"github.com/hyperledger/fabric/core/crypto/primitives"
@@ -34,6 +39,14 @@ const (
 	RACK_SALE_ENC_SCORE_CFG_PREFIX = "!kd@rackSESCPre~" //货架销售奖励积分比例分配配置的key前缀 销售奖励积分，简称SES
 	RACK_NEWRACK_ENC_SCORE_DEFAULT = 5000               //新开货架默认奖励的金额
 
+	//积分奖励活动相关
+	RACK_SCORE_CAMPAIGN_PREFIX    = "!kd@scoreCampPre~"     //积分奖励活动
+	RACK_SCORE_CAMPAIGN_LIST_KEY  = "!kd@scoreCampList@!"   //所有积分奖励活动的id
+	RACK_SCORE_CAMP_USED_PREFIX   = "!kd@scoreCampUsedPre~" //活动每个周期已奖励的积分，用于计算上限
+	RACK_SCORE_GRANT_SEQ_PREFIX   = "!kd@scoreGrantSeqPre~" //账户活动奖励积分记录的序列号
+	RACK_SCORE_GRANT_PREFIX       = "!kd@scoreGrantPre~"    //账户活动奖励积分记录
+	RACK_SCORE_GRANT_SWEEP_PREFIX = "!kd@scoreGrantSwpPre~" //账户活动奖励积分记录中，该序列号及之前的记录都已做过期处理
+
 	//货架融资相关
 	RACK_FINANCE_CFG_PREFIX    = "!kd@rack_FinacCfgPre~"             //货架融资配置的key前缀
 	FINACINFO_PREFIX           = "!kd@rack_FinacInfoPre~"            //理财发行信息的key的前缀。使用的是worldState存储
@@ -42,9 +55,11 @@ const (
 	RACKFINACHISTORY_KEY       = "!kd@rack_RackFinacHistoryKey@!"    //货架融资发行的历史信息
 	RACKFINACISSUEFINISHID_KEY = "!kd@rack_RackFinacIssueFinIdKey@!" //货架融资发行完毕的期号
 	RACK_ACCINVESTINFO_PREFIX  = "!kd@rack_AccInvestInfoPre~"        //账户货架融资信息
+	RACK_ACCFINACPREF_PREFIX   = "!kd@rack_AccFinacPrefPre~"         //账户货架融资到期处理偏好（续期或赎回）
 
 	//临时用一下
-	ACCOUT_CIPHER_PREFIX = "!kd@accCip~" //每个货架的收入分成比例的key前缀
+	ACCOUT_CIPHER_PREFIX   = "!kd@accCip~"    //账户交易密码，已废弃，使用支付公钥代替
+	ACCOUT_SPENDKEY_PREFIX = "!kd@accSpdKey~" //账户注册的支付公钥
 
 	RACKFINAC_INVEST = 0 //融资明细中的投资
 	RACKFINAC_PROFIT = 1 //融资明细中的收益
@@ -72,6 +87,11 @@ const (
 	FINANC_STAGE_BONUS_FINISH //理财分红结束
 )
 
+const (
+	FINANC_PREF_AUTO_RENEW  = 0 //理财到期自动续期（默认）
+	FINANC_PREF_AUTO_REDEEM = 1 //理财到期自动赎回
+)
+
 type RolesRate struct {
 	SellerRate   int64 `json:"slr"` //经营者分成比例 因为要和int64参与运算，这里都定义为int64
 	FielderRate  int64 `json:"fld"` //场地提供者分成比例
@@ -142,6 +162,63 @@ type ScoreEncouragePercentCfg struct {
 	*/
 }
 
+//积分奖励活动。活动期间内，适用的货架使用活动的奖励比例
+type ScoreCampaign struct {
+	CampID         string   `json:"cid"`  //活动id
+	BeginTime      int64    `json:"btm"`  //开始时间，毫秒
+	EndTime        int64    `json:"etm"`  //结束时间，毫秒
+	RackList       []string `json:"rks"`  //适用的货架，为空表示所有货架
+	RangeList      []int64  `json:"rl"`   //区间list
+	PercentList    []int    `json:"pl"`   //比例list
+	NewRackScore   int64    `json:"nrs"`  //新货架奖励积分，0表示使用默认值
+	Period         int64    `json:"prd"`  //上限的统计周期，毫秒，0表示整个活动期间
+	RackCap        int64    `json:"rcap"` //每个货架每个周期奖励积分的上限，0表示不限
+	AccCap         int64    `json:"acap"` //每个账户每个周期奖励积分的上限，0表示不限
+	ExpireDuration int64    `json:"exp"`  //奖励积分的有效期，毫秒，0表示不过期
+	UpdateTime     int64    `json:"uptm"`
+}
+
+//账户通过活动获得的积分记录，用于有效期的计算
+type ScoreGrant struct {
+	Serial     int64  `json:"ser"`
+	AccName    string `json:"acc"`
+	CampID     string `json:"cid"`
+	Rackid     string `json:"rid"`
+	Amount     int64  `json:"amt"`
+	GrantTime  int64  `json:"gtm"`
+	ExpireTime int64  `json:"etm"`  //过期时间，0表示不过期
+	Expired    bool   `json:"exd"`  //查询时是否已过期，查询时计算
+	FromAcc    string `json:"from"` //发放积分的账户，过期扣回的积分退回该账户
+	Swept      bool   `json:"swp"`  //是否已做过期扣回
+	Deducted   int64  `json:"ded"`  //过期时应扣回的积分。账户系统按账户的可用余额扣回，余额不足时实际扣回的少于该值
+	SweepTime  int64  `json:"stm"`
+}
+
+//一个账户过期积分的扣回结果
+type ScoreExpireResult struct {
+	AccName  string `json:"acc"`
+	Expired  int64  `json:"expired"` //本次处理的过期积分
+	Deducted int64  `json:"ded"`     //应扣回的积分，实际扣回的积分不超过账户的可用余额
+}
+
+type QueryScoreGrant struct {
+	ValidAmount   int64        `json:"valid"`
+	ExpiredAmount int64        `json:"expired"`
+	NextSerial    int64        `json:"nextser"`
+	GrantList     []ScoreGrant `json:"grants"`
+}
+
+//积分奖励预览
+type ScorePreview struct {
+	Rackid      string           `json:"rid"`
+	Sales       int64            `json:"sale"`     //销售额，单位为分
+	CampID      string           `json:"cid"`      //使用的活动id，为空表示没有使用活动
+	Score       int64            `json:"score"`    //按比例计算的奖励积分
+	CappedScore int64            `json:"cscore"`   //货架上限限制后的奖励积分
+	RackCapRest int64            `json:"rcaprest"` //货架当前周期剩余的额度，-1表示不限
+	RolesAlloc  RolesAllocAmount `json:"roles"`    //各个角色分配的积分（不含账户上限）
+}
+
 type RackRolesSales struct {
 	Rackid string `json:"rid"`  //货架id
 	Sales  int64  `json:"sale"` //销售额
@@ -204,6 +281,8 @@ type RackFinancInfo struct {
 	UserAmountMap      map[string]int64  `json:"uamp"` //每个用户投资的金额（包括新买的和续期的）
 	UserProfitMap      map[string]int64  `json:"upmp"` //每个用户收益的金额
 	UserRenewalMap     map[string]int64  `json:"urmp"` //每个用户续期的金额
+	UserPaidProfitMap  map[string]int64  `json:"uppm"` //每个用户已提取的收益（部分赎回时只提取收益，不退出投资）
+	UserRedeemMap      map[string]int64  `json:"urdm"` //每个用户在本期赎回的本金
 	Stage              int               `json:"stg"`  //处于什么阶段
 	PayFinanceUserList []string          `json:"pful"` //退出投资的用户列表
 	/*
@@ -212,6 +291,51 @@ type RackFinancInfo struct {
 	*/
 }
 
+//投资者在某个货架某期理财中的投资情况
+type InvestorPeriodStmt struct {
+	Rackid     string `json:"rid"`
+	FID        string `json:"fid"`
+	Stage      int    `json:"stg"`
+	Principal  int64  `json:"prin"`  //本期的投资额（部分赎回后为剩余的投资额）
+	NewInvest  int64  `json:"new"`   //本期新购买的金额
+	Renewal    int64  `json:"renew"` //本期续期的金额
+	Redeemed   int64  `json:"rdm"`   //本期赎回的本金
+	Profit     int64  `json:"prof"`  //本期的收益，分红后才有值
+	PaidProfit int64  `json:"pprof"` //本期已提取的收益
+	Exited     bool   `json:"exit"`  //本期是否已全部赎回退出
+}
+
+//投资者对账单
+type InvestorStatement struct {
+	AccName        string               `json:"acc"`
+	LatestFid      string               `json:"lfid"`
+	Principal      int64                `json:"prin"`   //当前所有货架投资的本金
+	TotalProfit    int64                `json:"tprof"`  //累计收益（已分红各期的收益之和）
+	PaidProfit     int64                `json:"pprof"`  //已提取的收益
+	UnpaidProfit   int64                `json:"upprof"` //未提取的收益
+	TotalRedeemed  int64                `json:"trdm"`   //累计赎回的本金
+	RackPrincipals map[string]int64     `json:"rprin"`  //每个货架当前的本金
+	Periods        []InvestorPeriodStmt `json:"prds"`
+}
+
+//货架某期理财的损益。销售额只在分红时才上链，所以销售额及由它算出的利润在分红后才有值，分红前都为0
+type RackPnL struct {
+	Rackid         string           `json:"rid"`
+	FID            string           `json:"fid"`
+	Stage          int              `json:"stg"`
+	Bonused        bool             `json:"bns"`   //是否已分红
+	Sales          int64            `json:"sale"`  //销售额，单位为分
+	Cost           int64            `json:"cost"`  //成本，单位为分
+	RackProfit     int64            `json:"rprof"` //货架利润，单位为分
+	RolesShare     RolesAllocAmount `json:"roles"` //各个角色分得的利润，单位为分
+	InvestorProfit int64            `json:"iprof"` //投资者分得的利润，单位为积分
+	PaidOutProfit  int64            `json:"paid"`  //已分配给投资者的利润，单位为积分
+	AmountFinca    int64            `json:"amtf"`  //本期投资额
+	InvestorCount  int              `json:"icnt"`  //本期投资人数（不含已退出的）
+	InvestCapacity int64            `json:"ivc"`   //货架投资容量
+	RestCapacity   int64            `json:"rcap"`  //本期剩余可投资额度（投资容量-本期投资额）
+}
+
 type RackFinancHistory struct {
 	PreCurrFID [2]string `json:"pcfid"` //前一次和本次的融资id  第一个位置为前一期融资id，第二个位置为本期融资id
 }
@@ -224,6 +348,13 @@ type AccRackInvest struct {
 	PaidFidList []string       `json:"pfl"`  //用户已经赎回的理财期号。
 }
 
+//账户理财到期处理偏好
+type AccFinancePref struct {
+	EntID       string         `json:"id"`
+	DefaultPref int            `json:"dp"`  //默认偏好，没有单独设置的货架使用此偏好
+	RackPrefMap map[string]int `json:"rpm"` //每个货架单独设置的偏好
+}
+
 type QueryFinac struct {
 	FinancialInfo
 	RFInfoList []RackFinancInfo `json:"rfList"`
@@ -248,6 +379,135 @@ type RackFinanceRecd struct {
 	Type    string `json:"type"` //投资、收益
 }
 
+//货架销售额，分红时使用
+type RackSales struct {
+	Rackid string `json:"rid"`  //货架id
+	Sales  int64  `json:"sale"` //销售额，单位为分
+}
+
+const (
+	BATCH_ENTRY_OK      = "ok"      //处理成功
+	BATCH_ENTRY_FAILED  = "failed"  //校验失败
+	BATCH_ENTRY_SKIPPED = "skipped" //校验通过，但是因为其它记录校验失败或者没有需要处理的内容（如销售额为0），未执行
+)
+
+//批量操作中每条记录的处理结果
+type BatchEntryResult struct {
+	Index   int    `json:"idx"`  //在输入中的序号，从0开始
+	Rackid  string `json:"rid"`  //货架id
+	Status  string `json:"stat"` //处理结果
+	Code    int32  `json:"code"` //失败时的错误码
+	Message string `json:"msg"`  //失败时的错误信息，或者跳过的原因
+}
+
+//批量操作的处理结果。先校验所有记录，有任何一条校验失败时，所有记录都不执行，并返回错误（错误码为第一条失败记录的错误码），
+//错误信息中依次列出每条失败记录的序号、货架id、错误码和错误信息。成功返回时Executed总为true
+type BatchResult struct {
+	Total    int                `json:"total"`
+	Succeed  int                `json:"succ"`
+	Failed   int                `json:"fail"`
+	Skipped  int                `json:"skip"`
+	Executed bool               `json:"exec"` //是否已执行
+	Results  []BatchEntryResult `json:"rslt"`
+}
+
+func NewBatchResult(count int) *BatchResult {
+	var br BatchResult
+	br.Total = count
+	br.Results = make([]BatchEntryResult, count)
+	for i := 0; i < count; i++ {
+		br.Results[i].Index = i
+	}
+	return &br
+}
+
+func (br *BatchResult) setRackid(idx int, rackid string) {
+	br.Results[idx].Rackid = rackid
+}
+
+func (br *BatchResult) setFailed(idx int, errcm *ErrorCodeMsg) {
+	br.Results[idx].Status = BATCH_ENTRY_FAILED
+	br.Results[idx].Code = errcm.Code
+	br.Results[idx].Message = errcm.Message
+}
+
+//校验通过但是不需要执行的记录，执行时调用
+func (br *BatchResult) setSkipped(idx int, reason string) {
+	br.Results[idx].Status = BATCH_ENTRY_SKIPPED
+	br.Results[idx].Message = reason
+}
+
+func (br *BatchResult) isFailed(idx int) bool {
+	return br.Results[idx].Status == BATCH_ENTRY_FAILED
+}
+
+func (br *BatchResult) hasFailed() bool {
+	for i := range br.Results {
+		if br.isFailed(i) {
+			return true
+		}
+	}
+	return false
+}
+
+//executed表示校验通过的记录是否已执行。未执行时返回错误，见BatchResult
+func (br *BatchResult) finish(executed bool) ([]byte, *ErrorCodeMsg) {
+	br.Executed = executed
+	br.Succeed = 0
+	br.Failed = 0
+	br.Skipped = 0
+	for i := range br.Results {
+		if br.isFailed(i) {
+			br.Failed++
+			continue
+		}
+		if executed && br.Results[i].Status != BATCH_ENTRY_SKIPPED {
+			br.Results[i].Status = BATCH_ENTRY_OK
+			br.Succeed++
+		} else {
+			br.Results[i].Status = BATCH_ENTRY_SKIPPED
+			br.Skipped++
+		}
+	}
+
+	if !executed {
+		return nil, br.failedError()
+	}
+
+	brB, err := json.Marshal(br)
+	if err != nil {
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "BatchResult: Marshal failed. error=(%s).", err)
+	}
+
+	return brB, nil
+}
+
+//校验失败时返回的错误。错误信息会被拼到json中返回给前端，所以去掉其中的双引号
+func (br *BatchResult) failedError() *ErrorCodeMsg {
+	var code int32 = ERRCODE_COMMON_PARAM_INVALID
+	var report []string
+	for i := range br.Results {
+		if !br.isFailed(i) {
+			continue
+		}
+		if len(report) == 0 {
+			code = br.Results[i].Code
+		}
+		var r = &br.Results[i]
+		report = append(report, fmt.Sprintf("idx=%d rid=%s code=%d msg=(%s)", r.Index, r.Rackid, r.Code, strings.Replace(r.Message, "\"", "'", -1)))
+	}
+
+	return kdlogger.ErrorECM(code, "BatchResult: %d of %d entries failed, nothing executed. failed=[%s]", br.Failed, br.Total, strings.Join(report, "; "))
+}
+
+//账户注册的支付公钥。transefer2转账时，除了账户本身的签名，还需要支付公钥对应私钥的签名
+type AccSpendKey struct {
+	EntID      string `json:"id"`
+	PubKeyHash string `json:"pkh"`   //支付公钥的Hash160，base64编码
+	Nonce      int64  `json:"nonce"` //每使用一次支付签名加1，防止签名被重复使用
+	UpdateTime int64  `json:"uptm"`
+}
+
 type InvokeArgs struct {
 	FixedArgCount int
 	UserName      string
@@ -256,7 +516,8 @@ type InvokeArgs struct {
 }
 
 var kdlogger = NewMylogger("kd")
-var kdCrypto = MyCryptoNew()
+var kdCrypto = llcrypto.MyCryptoNew()
+var kdSecp256k1 = llcrypto.NewSecp256k1()
 
 var stateCache StateWorldCache
 
@@ -286,7 +547,7 @@ func (t *KD) Init(stub shim.ChaincodeStubInterface) (pbResponse pb.Response) {
 
 	retBytes, errcm := t.__Init(stub)
 	if errcm != nil {
-		return shim.Error(errcm.toJson())
+		return shim.Error(errcm.ToJson())
 	}
 
 	return shim.Success(retBytes)
@@ -427,7 +688,7 @@ func (t *KD) Invoke(stub shim.ChaincodeStubInterface) (pbResponse pb.Response) {
 
 	payload, errcm := t.__Invoke(stub)
 	if errcm != nil {
-		return shim.Error(errcm.toJson())
+		return shim.Error(errcm.ToJson())
 	}
 
 	if CROSSCHAINCODE_CALL_THIS {
@@ -498,10 +759,10 @@ func (t *KD) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg)
 		}
 
 		return nil, nil
-	} else if function == "transefer2" { //带交易密码功能
+	} else if function == "transefer2" { //需要支付签名的转账
 		var argCount = fixedArgCount + 4
 		if len(args) < argCount {
-			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(transeferUsePwd) miss arg, got %d, at least need %d.", len(args), argCount)
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(transefer2) miss arg, got %d, at least need %d.", len(args), argCount)
 		}
 
 		var toAcc = args[fixedArgCount]
@@ -509,16 +770,12 @@ func (t *KD) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg)
 		var transAmount int64
 		transAmount, err = strconv.ParseInt(args[fixedArgCount+1], 0, 64)
 		if err != nil {
-			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "Invoke(transeferUsePwd): convert issueAmount(%s) failed. error=(%s)", args[fixedArgCount+1], err)
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "Invoke(transefer2): convert issueAmount(%s) failed. error=(%s)", args[fixedArgCount+1], err)
 		}
 		kdlogger.Debug("transAmount= %+v", transAmount)
 
-		var pwdBase64 = args[fixedArgCount+2]
-		pwd, errcm := t.decodeAccountPasswd(pwdBase64)
-		if errcm != nil {
-			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(resetAccPwd): decodeAccountPasswd (%s) failed. error=(%s)", pwdBase64, errcm)
-		}
-		kdlogger.Debug("Invoke(transeferUsePwd): pwd=%s", pwd)
+		//支付公钥对应私钥的签名，签名的内容见getSpendSignMsg
+		var spendSign = args[fixedArgCount+2]
 
 		//var appid = args[fixedArgCount+3]
 
@@ -540,31 +797,35 @@ func (t *KD) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg)
 			}
 		}
 
-		//验证密码
-		setPwd, errcm := t.isSetAccountPasswd(stub, accName)
+		//验证支付签名。必须先通过管理员注册支付公钥，不能用本次签名的公钥直接注册，否则只持有账户签名私钥就能转账
+		ask, errcm := t.getAccSpendKey(stub, accName)
 		if errcm != nil {
-			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(transeferUsePwd): IsSetAccountPasswd failed. error=(%s), acc=%s", errcm, accName)
+			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(transefer2): getAccSpendKey failed. error=(%s), acc=%s", errcm, accName)
+		}
+		if ask == nil || len(ask.PubKeyHash) == 0 {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "Invoke(transefer2): spend key of %s not registered.", accName)
 		}
-		kdlogger.Debug("Invoke(transeferUsePwd): setPwd=%v", setPwd)
-		if setPwd {
-			ok, errcm := t.authAccountPasswd(stub, accName, pwd)
-			if errcm != nil {
-				return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(transeferUsePwd): AuthAccountPasswd failed. error=(%s)", errcm)
-			}
-			if !ok {
-				return nil, kdlogger.ErrorECM(ERRCODE_COMMON_IDENTITY_VERIFY_FAILED, "Invoke(transeferUsePwd): passwd invalid.")
-			}
-		} else {
 
-			errcm := t.setAccountPasswd(stub, accName, pwd)
-			if errcm != nil {
-				return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(transeferUsePwd): setAccountPasswd failed. error=(%s)", errcm)
-			}
+		var signMsg = t.getSpendSignMsg(function, accName, toAcc, args[fixedArgCount+1], ask.Nonce)
+		pubKeyHash, errcm := t.recoverSpendKeyHash(spendSign, signMsg)
+		if errcm != nil {
+			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(transefer2): recoverSpendKeyHash failed. error=(%s)", errcm)
+		}
+
+		if ask.PubKeyHash != pubKeyHash {
+			return nil, kdlogger.ErrorECM(ERRCODE_TRANS_PASSWD_INVALID, "Invoke(transefer2): spend sign invalid.")
+		}
+
+		ask.Nonce++
+		ask.UpdateTime = invokeTime
+		errcm = t.setAccSpendKey(stub, ask)
+		if errcm != nil {
+			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(transefer2): setAccSpendKey failed. error=(%s)", errcm)
 		}
 
 		_, errcm = t.transferCoin(stub, accName, toAcc, transType, description, transAmount, invokeTime, sameEntSaveTransFlag)
 		if errcm != nil {
-			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(transeferUsePwd) transferCoin2 failed. error=(%s)", errcm)
+			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(transefer2) transferCoin2 failed. error=(%s)", errcm)
 		}
 		return nil, nil
 
@@ -705,6 +966,91 @@ func (t *KD) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg)
 		}
 		return nil, nil
 
+	} else if function == "setScoreCampaign" { //设置积分奖励活动
+		if !t.isAdmin(stub, accName) {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "Invoke(setScoreCampaign) can't exec by %s.", accName)
+		}
+
+		var argCount = fixedArgCount + 9
+		if len(args) < argCount {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(setScoreCampaign) miss arg, got %d, at least need %d.", len(args), argCount)
+		}
+
+		var sc ScoreCampaign
+		sc.CampID = args[fixedArgCount]
+		var rangeCfg = args[fixedArgCount+3]
+		var rackListStr = args[fixedArgCount+4]
+
+		//数值参数依次为 开始时间,结束时间,(跳过2个),统计周期,货架上限,账户上限,有效期[,新货架奖励积分]
+		var numArgIdx = []int{fixedArgCount + 1, fixedArgCount + 2, fixedArgCount + 5, fixedArgCount + 6, fixedArgCount + 7, fixedArgCount + 8}
+		var numPtrs = []*int64{&sc.BeginTime, &sc.EndTime, &sc.Period, &sc.RackCap, &sc.AccCap, &sc.ExpireDuration}
+		if len(args) > argCount {
+			numArgIdx = append(numArgIdx, argCount)
+			numPtrs = append(numPtrs, &sc.NewRackScore)
+		}
+		for i, idx := range numArgIdx {
+			v, err := strconv.ParseInt(args[idx], 0, 64)
+			if err != nil {
+				return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(setScoreCampaign) convert arg(%s) failed. error=(%s)", args[idx], err)
+			}
+			*numPtrs[i] = v
+		}
+
+		//货架列表格式 "货架1,货架2"，"*"表示所有货架
+		rackListStr = strings.Trim(strings.TrimSpace(rackListStr), ",")
+		if rackListStr != "*" && len(rackListStr) > 0 {
+			sc.RackList = strings.Split(rackListStr, ",")
+		}
+
+		var errcm *ErrorCodeMsg
+		sc.RangeList, sc.PercentList, errcm = t.parseScoreRangeCfg(rangeCfg)
+		if errcm != nil {
+			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(setScoreCampaign) parseScoreRangeCfg failed. error=(%s)", errcm)
+		}
+		sc.UpdateTime = invokeTime
+
+		errcm = t.setScoreCampaign(stub, &sc)
+		if errcm != nil {
+			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(setScoreCampaign) setScoreCampaign failed. error=(%s)", errcm)
+		}
+		return nil, nil
+
+	} else if function == "delScoreCampaign" { //删除积分奖励活动
+		if !t.isAdmin(stub, accName) {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "Invoke(delScoreCampaign) can't exec by %s.", accName)
+		}
+
+		var argCount = fixedArgCount + 1
+		if len(args) < argCount {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(delScoreCampaign) miss arg, got %d, at least need %d.", len(args), argCount)
+		}
+
+		errcm := t.delScoreCampaign(stub, args[fixedArgCount])
+		if errcm != nil {
+			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(delScoreCampaign) delScoreCampaign failed. error=(%s)", errcm)
+		}
+		return nil, nil
+
+	} else if function == "expireScores" { //扣回已过期的活动奖励积分
+		if !t.isAdmin(stub, accName) {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "Invoke(expireScores) can't exec by %s.", accName)
+		}
+
+		var argCount = fixedArgCount + 3
+		if len(args) < argCount {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(expireScores) miss arg, got %d, at least need %d.", len(args), argCount)
+		}
+
+		var paraStr = args[fixedArgCount]
+		var transType = args[fixedArgCount+1]
+		var transDesc = args[fixedArgCount+2]
+
+		rsltB, errcm := t.expireScores(stub, paraStr, accName, transType, transDesc, invokeTime)
+		if errcm != nil {
+			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(expireScores) expireScores failed. error=(%s)", errcm)
+		}
+		return rsltB, nil
+
 	} else if function == "encourageScoreForSales" { //根据销售额奖励积分
 		var argCount = fixedArgCount + 4
 		if len(args) < argCount {
@@ -721,11 +1067,11 @@ func (t *KD) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg)
 		}
 
 		//使用登录的账户进行转账
-		_, errcm := t.allocEncourageScoreForSales(stub, paraStr, accName, transType, transDesc, invokeTime, sameEntSaveTransFlag)
+		rsltB, errcm := t.allocEncourageScoreForSales(stub, paraStr, accName, transType, transDesc, invokeTime, sameEntSaveTransFlag)
 		if errcm != nil {
 			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(encourageScoreForSales) allocEncourageScoreForSales failed. error=(%s)", errcm)
 		}
-		return nil, nil
+		return rsltB, nil
 
 	} else if function == "encourageScoreForNewRack" { //新开货架奖励积分
 		var argCount = fixedArgCount + 4
@@ -743,11 +1089,11 @@ func (t *KD) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg)
 		}
 
 		//使用登录的账户进行转账
-		_, errcm := t.allocEncourageScoreForNewRack(stub, paraStr, accName, transType, transDesc, invokeTime, sameEntSaveTransFlag)
+		rsltB, errcm := t.allocEncourageScoreForNewRack(stub, paraStr, accName, transType, transDesc, invokeTime, sameEntSaveTransFlag)
 		if errcm != nil {
 			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(encourageScoreForNewRack) allocEncourageScoreForNewRack failed. error=(%s)", errcm)
 		}
-		return nil, nil
+		return rsltB, nil
 
 	} else if function == "setFinanceCfg" {
 		if !t.isAdmin(stub, accName) {
@@ -854,13 +1200,22 @@ func (t *KD) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg)
 
 		var financid = args[fixedArgCount]
 
+		//可选参数，上期理财中设置了自动赎回的用户，由当前账户转账赎回时使用
+		var transType, transDesc string
+		if len(args) > argCount {
+			transType = args[argCount]
+		}
+		if len(args) > argCount+1 {
+			transDesc = args[argCount+1]
+		}
+
 		//理财结束时，肯定是最新一期的理财，设置为当前的fid
 		errcm := t.setCurrentFid(stub, financid)
 		if errcm != nil {
 			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(financeIssueFinish) setCurrentFid failed, error=(%s).", errcm)
 		}
 
-		errcm = t.financeIssueFinishAfter(stub, financid, invokeTime)
+		errcm = t.financeIssueFinishAfter(stub, accName, financid, invokeTime, transType, transDesc)
 		if errcm != nil {
 			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(financeIssueFinishAfter) financeRenewal failed, error=(%s).", errcm)
 		}
@@ -883,13 +1238,49 @@ func (t *KD) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg)
 			sameEntSaveTransFlag = false
 		}
 
-		errcm := t.payUserFinance(stub, accName, reacc, rackid, invokeTime, transType, transDesc, sameEntSaveTransFlag)
+		//可选参数，赎回的本金金额。不传或者为0时全部赎回
+		var redeemAmt int64 = 0
+		if len(args) > argCount {
+			var err error
+			redeemAmt, err = strconv.ParseInt(args[argCount], 0, 64)
+			if err != nil {
+				return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(payFinance) convert redeemAmt(%s) failed. error=(%s)", args[argCount], err)
+			}
+			if redeemAmt < 0 {
+				return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(payFinance) redeemAmt(%d) invalid.", redeemAmt)
+			}
+		}
+
+		errcm := t.payUserFinance(stub, accName, reacc, rackid, redeemAmt, invokeTime, transType, transDesc, sameEntSaveTransFlag)
 		if errcm != nil {
 			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(payFinance) payUserFinance failed, error=(%s).", errcm)
 		}
 
 		return nil, nil
 
+	} else if function == "setFinancePref" { //设置理财到期后的处理方式（自动续期或自动赎回）
+		var argCount = fixedArgCount + 2
+		if len(args) < argCount {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(setFinancePref) miss arg, got %d, need %d.", len(args), argCount)
+		}
+
+		var rackid = args[fixedArgCount]
+		pref, err := strconv.Atoi(args[fixedArgCount+1])
+		if err != nil {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(setFinancePref) convert pref(%s) failed. error=(%s)", args[fixedArgCount+1], err)
+		}
+		if pref != FINANC_PREF_AUTO_RENEW && pref != FINANC_PREF_AUTO_REDEEM {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(setFinancePref) pref(%d) invalid.", pref)
+		}
+
+		//rackid为*时，设置该账户的默认偏好
+		errcm := t.setAccRackFinancePref(stub, accName, rackid, pref)
+		if errcm != nil {
+			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(setFinancePref) setAccRackFinancePref failed, error=(%s).", errcm)
+		}
+
+		return nil, nil
+
 	} else if function == "financeBouns" {
 		if !t.isAdmin(stub, accName) {
 			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "Invoke(financeBouns) can't exec by %s.", accName)
@@ -902,87 +1293,71 @@ func (t *KD) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg)
 
 		var fid = args[fixedArgCount]
 		var rackSalesCfg = args[fixedArgCount+1]
-		_, errcm := t.financeBonus(stub, fid, rackSalesCfg, invokeTime)
+		rsltB, errcm := t.financeBonus(stub, fid, rackSalesCfg, invokeTime)
 		if errcm != nil {
 			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(financeBouns) financeBonus failed. error=(%s)", errcm)
 		}
-		return nil, nil
+		return rsltB, nil
 
-	} else if function == "setAccCfg1" { //设置交易密码
-		var argCount = fixedArgCount + 1
+	} else if function == "setSpendKey" { //更换支付公钥
+		var argCount = fixedArgCount + 2
 		if len(args) < argCount {
-			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(setAccPwd) miss arg, got %d, need %d.", len(args), argCount)
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(setSpendKey) miss arg, got %d, need %d.", len(args), argCount)
 		}
 
-		setPwd, errcm := t.isSetAccountPasswd(stub, accName)
-		if errcm != nil {
-			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(setAccPwd): IsSetAccountPasswd failed. error=(%s), acc=%s", errcm, accName)
-		}
-		//如果已设置，则报错
-		if setPwd {
-			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "Invoke(setAccPwd): pwd is setted, do nothing, acc=%s", accName)
-		}
+		//新支付私钥的签名，证明持有该私钥
+		var newSign = args[fixedArgCount]
+		//旧支付私钥对包含新公钥Hash160的消息的签名，见registerAccSpendKey。没有注册过支付公钥时，只能由管理员通过resetSpendKey注册
+		var oldSign = args[fixedArgCount+1]
 
-		var pwdBase64 = args[fixedArgCount]
-		pwd, errcm := t.decodeAccountPasswd(pwdBase64)
+		errcm := t.registerAccSpendKey(stub, accName, newSign, oldSign, false, invokeTime)
 		if errcm != nil {
-			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(setAccPwd): decodeAccountPasswd (%s) failed. error=(%s)", pwdBase64, errcm)
+			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(setSpendKey) registerAccSpendKey failed. error=(%s)", errcm)
 		}
-		kdlogger.Debug("Invoke(setAccPwd): pwd=%s", pwd)
+		return nil, nil
 
-		errcm = t.setAccountPasswd(stub, accName, pwd)
-		if errcm != nil {
-			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(setAccPwd) setAccountPasswd failed. error=(%s)", errcm)
+	} else if function == "resetSpendKey" { //管理员为账户注册或重置支付公钥
+		if !t.isAdmin(stub, accName) {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "Invoke(resetSpendKey) can't exec by %s.", accName)
 		}
-		return nil, nil
 
-	} else if function == "setAccCfg2" { //重置交易密码
-		var argCount = fixedArgCount + 1
+		var argCount = fixedArgCount + 2
 		if len(args) < argCount {
-			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(resetAccPwd) miss arg, got %d, need %d.", len(args), argCount)
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(resetSpendKey) miss arg, got %d, need %d.", len(args), argCount)
 		}
 
-		var pwdBase64 = args[fixedArgCount]
-		pwd, errcm := t.decodeAccountPasswd(pwdBase64)
-		if errcm != nil {
-			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(resetAccPwd): decodeAccountPasswd (%s) failed. error=(%s)", pwdBase64, errcm)
-		}
-		kdlogger.Debug("Invoke(resetAccPwd): pwd=%s", pwd)
+		var targetAcc = args[fixedArgCount]
+		//用户用新支付私钥对setSpendKey消息的签名，由管理员核实身份后提交
+		var newSign = args[fixedArgCount+1]
 
-		errcm = t.setAccountPasswd(stub, accName, pwd)
+		errcm := t.registerAccSpendKey(stub, targetAcc, newSign, "", true, invokeTime)
 		if errcm != nil {
-			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(resetAccPwd) setAccountPasswd failed. error=(%s)", errcm)
+			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(resetSpendKey) registerAccSpendKey failed. error=(%s)", errcm)
 		}
 		return nil, nil
 
-	} else if function == "setAccCfg3" { //修改交易密码
-		var argCount = fixedArgCount + 2
-		if len(args) < argCount {
-			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(chgAccPwd) miss arg, got %d, need %d.", len(args), argCount)
-		}
-
-		var errcm *ErrorCodeMsg
-		var oldpwd, newpwd string
-		var oldpwdBase64 = args[fixedArgCount]
-		var newpwdBase64 = args[fixedArgCount+1]
+	} else if function == "setAccCfg1" || function == "setAccCfg2" || function == "setAccCfg3" {
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(%s): transaction password is retired, use setSpendKey instead.", function)
 
-		oldpwd, errcm = t.decodeAccountPasswd(oldpwdBase64)
-		if errcm != nil {
-			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(chgAccPwd): decodeAccountPasswd o(%s) failed. error=(%s)", oldpwdBase64, errcm)
-		}
-		newpwd, errcm = t.decodeAccountPasswd(newpwdBase64)
-		if errcm != nil {
-			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(chgAccPwd): decodeAccountPasswd n(%s) failed. error=(%s)", newpwdBase64, errcm)
+	} else if function == "retireAccPwd" { //删除已保存的交易密码
+		if !t.isAdmin(stub, accName) {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "Invoke(retireAccPwd) can't exec by %s.", accName)
 		}
 
-		kdlogger.Debug("Invoke(chgAccPwd): opwd=%s", oldpwd)
-		kdlogger.Debug("Invoke(chgAccPwd): npwd=%s", newpwd)
+		//可选参数，本次最多删除的个数，数据较多时可以分多次执行
+		var maxCount int64 = 0
+		if len(args) > fixedArgCount {
+			maxCount, err = strconv.ParseInt(args[fixedArgCount], 0, 64)
+			if err != nil {
+				return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(retireAccPwd) convert count(%s) failed. error=(%s)", args[fixedArgCount], err)
+			}
+		}
 
-		errcm = t.changeAccountPasswd(stub, accName, oldpwd, newpwd)
+		delCount, errcm := t.retireAccountPasswd(stub, maxCount)
 		if errcm != nil {
-			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(chgAccPwd) changeAccountPasswd failed. error=(%s)", errcm)
+			return nil, kdlogger.ErrorECM(errcm.Code, "Invoke(retireAccPwd) retireAccountPasswd failed. error=(%s)", errcm)
 		}
-		return nil, nil
+		return []byte(strconv.FormatInt(delCount, 10)), nil
 
 	} else if function == "updateEnv" {
 		var argCount = fixedArgCount + 2
@@ -1017,7 +1392,6 @@ func (t *KD) __Query(stub shim.ChaincodeStubInterface, ifas *InvokeArgs) ([]byte
 	function, args := stub.GetFunctionAndParameters()
 	kdlogger.Debug("func =%s, args = %+v", function, args)
 
-
 	var fixedArgCount = ifas.FixedArgCount
 	if len(args) < fixedArgCount {
 		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Query miss arg, got %d, at least need %d.", len(args), fixedArgCount)
@@ -1025,7 +1399,7 @@ func (t *KD) __Query(stub shim.ChaincodeStubInterface, ifas *InvokeArgs) ([]byte
 
 	//var userName = ifas.UserName
 	var accName = ifas.AccountName
-	//var queryTime int64 = ifas.InvokeTime
+	var queryTime int64 = ifas.InvokeTime
 
 	if function == "queryRackAlloc" {
 
@@ -1041,7 +1415,7 @@ func (t *KD) __Query(stub shim.ChaincodeStubInterface, ifas *InvokeArgs) ([]byte
 		var begTime int64
 		var endTime int64
 		var txAcc string
-        var err error
+		var err error
 
 		rackid = args[fixedArgCount]
 		allocKey = args[fixedArgCount+1]
@@ -1144,6 +1518,107 @@ func (t *KD) __Query(stub shim.ChaincodeStubInterface, ifas *InvokeArgs) ([]byte
 
 		return sercB, nil
 
+	} else if function == "getScoreCampaign" {
+		var argCount = fixedArgCount + 1
+		if len(args) < argCount {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getScoreCampaign miss arg, got %d, need %d.", len(args), argCount)
+		}
+
+		var campid = args[fixedArgCount]
+
+		//"*"表示查询所有活动
+		if campid == "*" {
+			campList, errcm := t.getScoreCampaignList(stub)
+			if errcm != nil {
+				return nil, kdlogger.ErrorECM(errcm.Code, "getScoreCampaign getScoreCampaignList failed. error=(%s)", errcm)
+			}
+			var scList = []ScoreCampaign{}
+			for _, cid := range campList {
+				sc, errcm := t.getScoreCampaign(stub, cid)
+				if errcm != nil {
+					return nil, kdlogger.ErrorECM(errcm.Code, "getScoreCampaign getScoreCampaign(%s) failed. error=(%s)", cid, errcm)
+				}
+				if sc != nil {
+					scList = append(scList, *sc)
+				}
+			}
+			scListB, err := json.Marshal(scList)
+			if err != nil {
+				return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getScoreCampaign Marshal failed. error=(%s)", err)
+			}
+			return scListB, nil
+		}
+
+		sc, errcm := t.getScoreCampaign(stub, campid)
+		if errcm != nil {
+			return nil, kdlogger.ErrorECM(errcm.Code, "getScoreCampaign getScoreCampaign(%s) failed. error=(%s)", campid, errcm)
+		}
+		if sc == nil {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getScoreCampaign campaign(%s) not exists.", campid)
+		}
+		scB, err := json.Marshal(sc)
+		if err != nil {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getScoreCampaign Marshal failed. error=(%s)", err)
+		}
+		return scB, nil
+
+	} else if function == "previewEncourageScore" { //预览某个销售额能获得的奖励积分
+		var argCount = fixedArgCount + 2
+		if len(args) < argCount {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "previewEncourageScore miss arg, got %d, need %d.", len(args), argCount)
+		}
+
+		var rackid = args[fixedArgCount]
+		sales, err := strconv.ParseInt(args[fixedArgCount+1], 0, 64)
+		if err != nil {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "previewEncourageScore convert sales(%s) failed. error=(%s)", args[fixedArgCount+1], err)
+		}
+		if sales < 0 {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "previewEncourageScore sales(%d) invalid.", sales)
+		}
+
+		//可选参数，预览的时间点，不传时为当前时间
+		var previewTime = queryTime
+		if len(args) > argCount {
+			previewTime, err = strconv.ParseInt(args[argCount], 0, 64)
+			if err != nil {
+				return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "previewEncourageScore convert time(%s) failed. error=(%s)", args[argCount], err)
+			}
+		}
+
+		spB, errcm := t.previewEncourageScore(stub, rackid, sales, previewTime)
+		if errcm != nil {
+			return nil, kdlogger.ErrorECM(errcm.Code, "previewEncourageScore failed. error=(%s)", errcm)
+		}
+		return spB, nil
+
+	} else if function == "getAccScoreGrants" { //查询账户通过活动获得的积分及有效期
+		var argCount = fixedArgCount + 2
+		if len(args) < argCount {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getAccScoreGrants miss arg, got %d, need %d.", len(args), argCount)
+		}
+
+		begSeq, err := strconv.ParseInt(args[fixedArgCount], 0, 64)
+		if err != nil {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getAccScoreGrants convert begSeq(%s) failed. error=(%s)", args[fixedArgCount], err)
+		}
+		count, err := strconv.ParseInt(args[fixedArgCount+1], 0, 64)
+		if err != nil {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getAccScoreGrants convert count(%s) failed. error=(%s)", args[fixedArgCount+1], err)
+		}
+
+		var qAcc = accName
+		//管理员可以查询其它账户
+		if len(args) > argCount && t.isAdmin(stub, accName) {
+			qAcc = args[argCount]
+		}
+
+		qsgB, errcm := t.queryAccScoreGrants(stub, qAcc, begSeq, count, queryTime)
+		if errcm != nil {
+			return nil, kdlogger.ErrorECM(errcm.Code, "getAccScoreGrants failed. error=(%s)", errcm)
+		}
+		return qsgB, nil
+
 	} else if function == "getRackFinanceCfg" {
 		if !t.isAdmin(stub, accName) {
 			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "getRackFinanceCfg: %s can't query.", accName)
@@ -1178,14 +1653,64 @@ func (t *KD) __Query(stub shim.ChaincodeStubInterface, ifas *InvokeArgs) ([]byte
 
 		return []byte(strconv.FormatInt(profit, 10)), nil
 
-	} else if function == "getRackRestFinanceCapacity" {
+	} else if function == "getInvestorStatement" { //投资者对账单
+		var qAcc = accName
+		//管理员可以查询其它账户
+		if len(args) > fixedArgCount && len(args[fixedArgCount]) > 0 {
+			if !t.isAdmin(stub, accName) {
+				return nil, kdlogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "getInvestorStatement: %s can't query other account.", accName)
+			}
+			qAcc = args[fixedArgCount]
+		}
+
+		stmtB, errcm := t.getInvestorStatement(stub, qAcc)
+		if errcm != nil {
+			return nil, kdlogger.ErrorECM(errcm.Code, "getInvestorStatement(acc=%s) failed. error=(%s)", qAcc, errcm)
+		}
+		return stmtB, nil
+
+	} else if function == "getRackPnL" { //货架理财损益
 		if !t.isAdmin(stub, accName) {
-			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "getRackFinanceCapacity: %s can't query.", accName)
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "getRackPnL: %s can't query.", accName)
 		}
 
 		var argCount = fixedArgCount + 2
 		if len(args) < argCount {
-			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getRackFinanceCapacity miss arg, got %d, need %d.", len(args), argCount)
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getRackPnL miss arg, got %d, need %d.", len(args), argCount)
+		}
+
+		var rackid = args[fixedArgCount]
+		var fid = args[fixedArgCount+1] //"*"表示查询该货架所有期的理财
+
+		pnlB, errcm := t.getRackPnL(stub, rackid, fid)
+		if errcm != nil {
+			return nil, kdlogger.ErrorECM(errcm.Code, "getRackPnL(rackid=%s, fid=%s) failed. error=(%s)", rackid, fid, errcm)
+		}
+		return pnlB, nil
+
+	} else if function == "getFinancePref" {
+		var argCount = fixedArgCount + 1
+		if len(args) < argCount {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getFinancePref miss arg, got %d, need %d.", len(args), argCount)
+		}
+
+		var rackid = args[fixedArgCount]
+
+		pref, errcm := t.getAccRackFinancePref(stub, accName, rackid)
+		if errcm != nil {
+			return nil, kdlogger.ErrorECM(errcm.Code, "getFinancePref getAccRackFinancePref(rackid=%s) failed. error=(%s)", rackid, errcm)
+		}
+
+		return []byte(strconv.Itoa(pref)), nil
+
+	} else if function == "getRackRestFinanceCapacity" {
+		if !t.isAdmin(stub, accName) {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "getRackFinanceCapacity: %s can't query.", accName)
+		}
+
+		var argCount = fixedArgCount + 2
+		if len(args) < argCount {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getRackFinanceCapacity miss arg, got %d, need %d.", len(args), argCount)
 		}
 
 		var rackid = args[fixedArgCount]
@@ -1206,50 +1731,60 @@ func (t *KD) __Query(stub shim.ChaincodeStubInterface, ifas *InvokeArgs) ([]byte
 			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "transPreCheck miss arg, got %d, need %d.", len(args), argCount)
 		}
 
-		//toAcc := args[fixedArgCount]
-		pwd := args[fixedArgCount+1]
-		/*
-			transAmount, err := strconv.ParseInt(args[fixedArgCount+2], 0, 64)
-			if err != nil {
-				return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "transPreCheck: convert transAmount(%s) failed. error=(%s)", args[fixedArgCount+2], err)
-			}
-		*/
+		toAcc := args[fixedArgCount]
+		spendSign := args[fixedArgCount+1]
+		amountStr := args[fixedArgCount+2]
 
-		//先看密码是否正确
-		if len(pwd) > 0 {
-			setPwd, errcm := t.isSetAccountPasswd(stub, accName)
+		//先看支付签名是否正确，签名内容和transefer2一致
+		if len(spendSign) > 0 {
+			ask, errcm := t.getAccSpendKey(stub, accName)
 			if errcm != nil {
-				return nil, kdlogger.ErrorECM(errcm.Code, "transPreCheck: isSetAccountPasswd(%s) failed. error=(%s)", accName, errcm)
+				return nil, kdlogger.ErrorECM(errcm.Code, "transPreCheck: getAccSpendKey(%s) failed. error=(%s)", accName, errcm)
 			}
 
-			if setPwd {
-				ok, errcm := t.authAccountPasswd(stub, accName, pwd)
-				if errcm != nil {
-					return nil, kdlogger.ErrorECM(errcm.Code, "transPreCheck: AuthAccountPasswd(%s) failed. error=(%s)", accName, errcm)
-				}
-				if !ok {
-					return []byte(strconv.FormatInt(ERRCODE_TRANS_PASSWD_INVALID, 10)), nil
-				}
+			//没有注册支付公钥时transefer2会失败
+			if ask == nil || len(ask.PubKeyHash) == 0 {
+				return []byte(strconv.FormatInt(ERRCODE_TRANS_PASSWD_INVALID, 10)), nil
+			}
+			pubKeyHash, errcm := t.recoverSpendKeyHash(spendSign, t.getSpendSignMsg("transefer2", accName, toAcc, amountStr, ask.Nonce))
+			if errcm != nil || pubKeyHash != ask.PubKeyHash {
+				return []byte(strconv.FormatInt(ERRCODE_TRANS_PASSWD_INVALID, 10)), nil
 			}
 		}
 
 		//通过返回0，表示检查通过
 		return []byte(strconv.FormatInt(0, 10)), nil
 
-	} else if function == "isAccSetPwd" { //账户是否设置密码
-		setPwd, errcm := t.isSetAccountPasswd(stub, accName)
+	} else if function == "isAccSetPwd" { //账户是否注册了支付公钥，保留原来的函数名兼容客户端
+		ask, errcm := t.getAccSpendKey(stub, accName)
 		if errcm != nil {
-			return nil, kdlogger.ErrorECM(errcm.Code, "Query(isAccSetPwd): IsSetAccountPasswd failed. error=(%s), acc=%s", errcm, accName)
+			return nil, kdlogger.ErrorECM(errcm.Code, "Query(isAccSetPwd): getAccSpendKey failed. error=(%s), acc=%s", errcm, accName)
 		}
 
 		var retValues []byte
-		if setPwd {
+		if ask != nil && len(ask.PubKeyHash) > 0 {
 			retValues = []byte("1")
 		} else {
 			retValues = []byte("0")
 		}
 
 		return retValues, nil
+
+	} else if function == "getSpendKey" { //查询支付公钥信息，客户端签名时需要nonce
+		ask, errcm := t.getAccSpendKey(stub, accName)
+		if errcm != nil {
+			return nil, kdlogger.ErrorECM(errcm.Code, "Query(getSpendKey): getAccSpendKey failed. error=(%s), acc=%s", errcm, accName)
+		}
+		if ask == nil {
+			ask = &AccSpendKey{EntID: accName}
+		}
+
+		askB, err := json.Marshal(ask)
+		if err != nil {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "Query(getSpendKey): Marshal failed. error=(%s)", err)
+		}
+		return askB, nil
+
 	} else {
 
 		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "unknown function: %s.", function)
@@ -1757,7 +2292,7 @@ func (t *KD) getRackAllocCfg(stub shim.ChaincodeStubInterface, rackid string, pe
 	if peap != nil {
 		err = json.Unmarshal(eapB, peap)
 		if err != nil {
-			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getRackAllocCfg Unmarshal failed. error=(%s)", rackid, err)
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getRackAllocCfg Unmarshal(rackid=%s) failed. error=(%s)", rackid, err)
 		}
 	}
 
@@ -1772,17 +2307,11 @@ func (t *KD) getRackEncourageScoreCfgKey(rackid string) string {
 	return RACK_SALE_ENC_SCORE_CFG_PREFIX + "rack_" + rackid
 }
 
-func (t *KD) setRackEncourageScoreCfg(stub shim.ChaincodeStubInterface, rackid, cfgStr string, invokeTime int64) ([]byte, *ErrorCodeMsg) {
+//解析销售额区间奖励比例配置，返回按区间升序排列的区间和比例
+func (t *KD) parseScoreRangeCfg(cfgStr string) ([]int64, []int, *ErrorCodeMsg) {
 	//配置格式如下 "2000:150;3000:170..."，防止输入错误，先去除两边的空格，然后再去除两边的';'（防止split出来空字符串）
 	var newCfg = strings.Trim(strings.TrimSpace(cfgStr), ";")
 
-	var sepc ScoreEncouragePercentCfg
-	sepc.Rackid = rackid
-	if rackid == "*" {
-		sepc.Rackid = RACK_GLOBAL_CFG_RACK_ID
-	}
-	sepc.UpdateTime = invokeTime
-
 	var rangeRatArr []string
 
 	var err error
@@ -1800,11 +2329,11 @@ func (t *KD) setRackEncourageScoreCfg(stub shim.ChaincodeStubInterface, rackid,
 	var rangePercentMap = make(map[int64]int)
 	for _, rangeRate := range rangeRatArr {
 		if !strings.Contains(rangeRate, ":") {
-			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "setRackEncourageScoreCfg  rangeRate parse error, '%s' has no ':'.", rangeRate)
+			return nil, nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "parseScoreRangeCfg  rangeRate parse error, '%s' has no ':'.", rangeRate)
 		}
 		var pair = strings.Split(rangeRate, ":")
 		if len(pair) != 2 {
-			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "setRackEncourageScoreCfg  rangeRate parse error, '%s' format error 1.", rangeRate)
+			return nil, nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "parseScoreRangeCfg  rangeRate parse error, '%s' format error 1.", rangeRate)
 		}
 		//"-"表示正无穷
 		if pair[0] == "-" {
@@ -1812,35 +2341,54 @@ func (t *KD) setRackEncourageScoreCfg(stub shim.ChaincodeStubInterface, rackid,
 		} else {
 			rang, err = strconv.ParseInt(pair[0], 0, 64)
 			if err != nil {
-				return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setRackEncourageScoreCfg  rangeRate parse error, '%s' format error 2.", rangeRate)
+				return nil, nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "parseScoreRangeCfg  rangeRate parse error, '%s' format error 2.", rangeRate)
 			}
 		}
 		percent, err = strconv.Atoi(pair[1])
 		if err != nil {
-			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setRackEncourageScoreCfg  rangeRate parse error, '%s' format error 3.", rangeRate)
+			return nil, nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "parseScoreRangeCfg  rangeRate parse error, '%s' format error 3.", rangeRate)
 		}
 
-		//sepc.RangePercentMap[rang] = percent
 		rangePercentMap[rang] = percent
 	}
 
-	//注意，这里如果下面没有排序sepc.RangeList， 则不能使用 rangePercentMap 来临时存储数据，会导致各个节点上sepc.RangeList数据顺序不一致
+	var rangeList []int64
+	var percentList []int
+
+	//注意，这里如果下面没有排序rangeList， 则不能使用 rangePercentMap 来临时存储数据，会导致各个节点上rangeList数据顺序不一致
 	for rang, _ := range rangePercentMap {
-		sepc.RangeList = append(sepc.RangeList, rang)
+		rangeList = append(rangeList, rang)
 	}
 
 	//升序排序
-	var cnt = len(sepc.RangeList)
+	var cnt = len(rangeList)
 	for i := 0; i < cnt; i++ {
 		for j := i + 1; j < cnt; j++ {
-			if sepc.RangeList[i] > sepc.RangeList[j] {
-				sepc.RangeList[j], sepc.RangeList[i] = sepc.RangeList[i], sepc.RangeList[j]
+			if rangeList[i] > rangeList[j] {
+				rangeList[j], rangeList[i] = rangeList[i], rangeList[j]
 			}
 		}
 	}
 
 	for i := 0; i < cnt; i++ {
-		sepc.PercentList = append(sepc.PercentList, rangePercentMap[sepc.RangeList[i]])
+		percentList = append(percentList, rangePercentMap[rangeList[i]])
+	}
+
+	return rangeList, percentList, nil
+}
+
+func (t *KD) setRackEncourageScoreCfg(stub shim.ChaincodeStubInterface, rackid, cfgStr string, invokeTime int64) ([]byte, *ErrorCodeMsg) {
+	var sepc ScoreEncouragePercentCfg
+	sepc.Rackid = rackid
+	if rackid == "*" {
+		sepc.Rackid = RACK_GLOBAL_CFG_RACK_ID
+	}
+	sepc.UpdateTime = invokeTime
+
+	var errcm *ErrorCodeMsg
+	sepc.RangeList, sepc.PercentList, errcm = t.parseScoreRangeCfg(cfgStr)
+	if errcm != nil {
+		return nil, kdlogger.ErrorECM(errcm.Code, "setRackEncourageScoreCfg parseScoreRangeCfg failed. error=(%s)", errcm)
 	}
 
 	sepcJson, err := json.Marshal(sepc)
@@ -1896,93 +2444,130 @@ func (t *KD) getRackEncourageScoreCfg(stub shim.ChaincodeStubInterface, rackid s
 	return sepcB, nil
 }
 
-func (t *KD) allocEncourageScoreForSales(stub shim.ChaincodeStubInterface, paraStr string, transFromAcc, transType, transDesc string, invokeTime int64, sameEntSaveTx bool) ([]byte, *ErrorCodeMsg) {
-	//配置格式如下 "货架id1,销售额,货架经营者账户,场地提供者账户,送货人账户,平台账户;货架id2,销售额,货架经营者账户,场地提供者账户,送货人账户,平台账户;...."，
+//批量参数是否为json数组格式
+func (t *KD) isJsonArrayPara(paraStr string) bool {
+	return strings.HasPrefix(strings.TrimSpace(paraStr), "[")
+}
+
+//拆分旧格式的批量参数 "记录1;记录2;..."
+func (t *KD) splitBatchPara(paraStr string) []string {
 	//防止输入错误，先去除两边的空格，然后再去除两边的';'（防止split出来空字符串）
 	var newStr = strings.Trim(strings.TrimSpace(paraStr), ";")
+	if len(newStr) == 0 {
+		return []string{}
+	}
+	return strings.Split(newStr, ";")
+}
 
-	var rrsList []RackRolesSales
-
-	var rackRolesSalesArr []string
+//校验货架和四个角色账户
+func (t *KD) checkRackAllocAccs(rackid string, accs *AllocAccs) *ErrorCodeMsg {
+	if len(rackid) == 0 {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "checkRackAllocAccs: rackid is empty.")
+	}
+	if len(accs.SellerAcc) == 0 || len(accs.FielderAcc) == 0 || len(accs.DeliveryAcc) == 0 || len(accs.PlatformAcc) == 0 {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "checkRackAllocAccs: rack(%s) has empty account(%+v).", rackid, *accs)
+	}
+	return nil
+}
 
-	var err error
+func (t *KD) allocEncourageScoreForSales(stub shim.ChaincodeStubInterface, paraStr string, transFromAcc, transType, transDesc string, invokeTime int64, sameEntSaveTx bool) ([]byte, *ErrorCodeMsg) {
+	//支持两种格式
+	//json数组 [{"rid":"货架id","sale":销售额,"slracc":"货架经营者账户","fldacc":"场地提供者账户","dvyacc":"送货人账户","pfmacc":"平台账户"},...]
+	//旧格式 "货架id1,销售额,货架经营者账户,场地提供者账户,送货人账户,平台账户;货架id2,销售额,货架经营者账户,场地提供者账户,送货人账户,平台账户;...."
+	//销售额的单位都为分
+	var rrsList []RackRolesSales
+	var br *BatchResult
 
-	//含有";"，表示有多条配置，没有则说明只有一条配置
-	if strings.Contains(newStr, ";") {
-		rackRolesSalesArr = strings.Split(newStr, ";")
+	if t.isJsonArrayPara(paraStr) {
+		err := json.Unmarshal([]byte(paraStr), &rrsList)
+		if err != nil {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "encourageScoreBySales: Unmarshal para failed. error=(%s).", err)
+		}
+		br = NewBatchResult(len(rrsList))
 	} else {
-		rackRolesSalesArr = append(rackRolesSalesArr, newStr)
-	}
+		var rackRolesSalesArr = t.splitBatchPara(paraStr)
+		rrsList = make([]RackRolesSales, len(rackRolesSalesArr))
+		br = NewBatchResult(len(rackRolesSalesArr))
+
+		var eleDelim = ","
+		for i, v := range rackRolesSalesArr {
+			var rackRolesSales = strings.Trim(strings.TrimSpace(v), eleDelim)
+			var eles = strings.Split(rackRolesSales, eleDelim)
+			if len(eles) != 6 {
+				br.setFailed(i, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "encourageScoreBySales: rackRolesSales parse error, '%s' format error 1.", rackRolesSales))
+				continue
+			}
 
-	var eleDelim = ","
-	var rackRolesSales string
-	var errList []string
-	for _, v := range rackRolesSalesArr {
-		rackRolesSales = strings.Trim(strings.TrimSpace(v), eleDelim)
-		if !strings.Contains(rackRolesSales, eleDelim) {
-			kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "encourageScoreBySales  rackRolesSales parse error, '%s' has no '%s'.", rackRolesSales, eleDelim)
-			errList = append(errList, rackRolesSales)
-			continue
+			var rrs = &rrsList[i]
+			var err error
+			rrs.Rackid = eles[0]
+			rrs.Sales, err = strconv.ParseInt(eles[1], 0, 64)
+			if err != nil {
+				br.setFailed(i, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "encourageScoreBySales: rackRolesSales parse error, '%s' format error 2.", rackRolesSales))
+				continue
+			}
+			rrs.AllocAccs.SellerAcc = eles[2]
+			rrs.AllocAccs.FielderAcc = eles[3]
+			rrs.AllocAccs.DeliveryAcc = eles[4]
+			rrs.AllocAccs.PlatformAcc = eles[5]
 		}
-		var eles = strings.Split(rackRolesSales, eleDelim)
-		if len(eles) != 6 {
-			kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "encourageScoreBySales  rackRolesSales parse error, '%s' format error 1.", rackRolesSales)
-			errList = append(errList, rackRolesSales)
+	}
+
+	//先校验所有记录，并计算每条记录的奖励积分
+	var scoreList = make([]int64, len(rrsList))
+	var campList = make([]*ScoreCampaign, len(rrsList))
+	for i := range rrsList {
+		var rrs = &rrsList[i]
+		br.setRackid(i, rrs.Rackid)
+		if br.isFailed(i) {
 			continue
 		}
 
-		var rrs RackRolesSales
-
-		rrs.Rackid = eles[0]
-		rrs.Sales, err = strconv.ParseInt(eles[1], 0, 64)
-		if err != nil {
-			kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "encourageScoreBySales  rackRolesSales parse error, '%s' format error 2.", rackRolesSales)
-			errList = append(errList, rrs.Rackid)
+		errcm := t.checkRackAllocAccs(rrs.Rackid, &rrs.AllocAccs)
+		if errcm != nil {
+			br.setFailed(i, errcm)
 			continue
 		}
-
-		if rrs.Sales <= 0 {
-			kdlogger.Info("encourageScoreBySales sales is 0(rack=%s), do nothing.", rrs.Rackid)
+		if rrs.Sales < 0 {
+			br.setFailed(i, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "encourageScoreBySales: sales(%d) of rack(%s) invalid.", rrs.Sales, rrs.Rackid))
 			continue
 		}
 
 		rrs.Sales = rrs.Sales / 100 //输入的单位为分，这里计算以元为单位
 
-		rrs.AllocAccs.SellerAcc = eles[2]
-		rrs.AllocAccs.FielderAcc = eles[3]
-		rrs.AllocAccs.DeliveryAcc = eles[4]
-		rrs.AllocAccs.PlatformAcc = eles[5]
+		scoreList[i], campList[i], errcm = t.getEncourageScoreBySales(stub, rrs.Rackid, rrs.Sales, invokeTime)
+		if errcm != nil {
+			br.setFailed(i, errcm)
+			continue
+		}
+	}
 
-		rrsList = append(rrsList, rrs)
+	if br.hasFailed() {
+		kdlogger.Error("encourageScoreBySales: para check failed, do nothing.")
+		return br.finish(false)
 	}
 
-	for _, rrs := range rrsList {
-		encourageScore, errcm := t.getRackEncourgeScoreBySales(stub, rrs.Rackid, rrs.Sales)
-		if errcm != nil {
-			kdlogger.ErrorECM(errcm.Code, "encourageScoreBySales  getRackEncourgePercentBySales failed, error=%s.", errcm)
-			errList = append(errList, rrs.Rackid)
+	for i := range rrsList {
+		var rrs = &rrsList[i]
+		if rrs.Sales <= 0 {
+			kdlogger.Info("encourageScoreBySales sales is 0(rack=%s), do nothing.", rrs.Rackid)
+			br.setSkipped(i, "sales is less than 1 yuan")
 			continue
 		}
 
 		var rres RackRolesEncourageScores
 		rres.Rackid = rrs.Rackid
-		rres.Scores = encourageScore
+		rres.Scores = scoreList[i]
 		rres.AllocAccs = rrs.AllocAccs
 
 		//销售奖励积分时，货架经营者要补偿销售额同等的积分
-		errcm = t.allocEncourageScore(stub, &rres, transFromAcc, transType, transDesc, invokeTime, sameEntSaveTx, rrs.Sales)
+		errcm := t.allocEncourageScore(stub, &rres, transFromAcc, transType, transDesc, invokeTime, sameEntSaveTx, rrs.Sales, campList[i])
 		if errcm != nil {
-			kdlogger.ErrorECM(errcm.Code, "encourageScoreBySales allocEncourageScore failed, error=%s.", errcm)
-			errList = append(errList, rrs.Rackid)
-			continue
+			return nil, kdlogger.ErrorECM(errcm.Code, "encourageScoreBySales: allocEncourageScore(idx=%d, rack=%s) failed, error=%s.", i, rrs.Rackid, errcm)
 		}
 	}
 
-	if len(errList) > 0 {
-		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_INNER_ERROR, "encourageScoreBySales: has some error:(%s).", strings.Join(errList, ";"))
-	}
-
-	return nil, nil
+	return br.finish(true)
 }
 
 func (t *KD) getRackEncourgeScoreBySales(stub shim.ChaincodeStubInterface, rackid string, sales int64) (int64, *ErrorCodeMsg) {
@@ -2014,17 +2599,39 @@ func (t *KD) getRackEncourgeScoreBySales(stub shim.ChaincodeStubInterface, racki
 	       }
 	   }
 	*/
-	for i, v := range sepc.RangeList {
+	return t.getScoreByRange(sepc.RangeList, sepc.PercentList, sales), nil
+}
+
+func (t *KD) getScoreByRange(rangeList []int64, percentList []int, sales int64) int64 {
+	for i, v := range rangeList {
 		if sales <= v {
-			return int64(sepc.PercentList[i]) * sales / 100, nil //营业额乘以百分比
+			return int64(percentList[i]) * sales / 100 //营业额乘以百分比
 		}
 	}
 
-	return sales, nil
+	return sales
 }
 
+//计算销售额奖励的积分。invokeTime时有适用于该货架的活动时，使用活动的比例，并返回该活动
+func (t *KD) getEncourageScoreBySales(stub shim.ChaincodeStubInterface, rackid string, sales, invokeTime int64) (int64, *ScoreCampaign, *ErrorCodeMsg) {
+	sc, errcm := t.getActiveScoreCampaign(stub, rackid, invokeTime)
+	if errcm != nil {
+		return 0, nil, kdlogger.ErrorECM(errcm.Code, "getEncourageScoreBySales getActiveScoreCampaign failed.rackid=%s error=(%s)", rackid, errcm)
+	}
+	if sc != nil {
+		return t.getScoreByRange(sc.RangeList, sc.PercentList, sales), sc, nil
+	}
+
+	score, errcm := t.getRackEncourgeScoreBySales(stub, rackid, sales)
+	if errcm != nil {
+		return 0, nil, kdlogger.ErrorECM(errcm.Code, "getEncourageScoreBySales getRackEncourgeScoreBySales failed.rackid=%s error=(%s)", rackid, errcm)
+	}
+	return score, nil, nil
+}
+
+//sc不为空时，奖励的积分受活动的上限限制，并记录有效期
 func (t *KD) allocEncourageScore(stub shim.ChaincodeStubInterface, rrs *RackRolesEncourageScores, transFromAcc, transType, transDesc string,
-	invokeTime int64, sameEntSaveTx bool, sellerComps int64) *ErrorCodeMsg {
+	invokeTime int64, sameEntSaveTx bool, sellerComps int64, sc *ScoreCampaign) *ErrorCodeMsg {
 	var ear EarningAllocRate
 	_, errcm := t.getRackAllocCfg(stub, rrs.Rackid, &ear)
 	if errcm != nil {
@@ -2036,6 +2643,15 @@ func (t *KD) allocEncourageScore(stub shim.ChaincodeStubInterface, rrs *RackRole
 
 	rolesAllocScore := t.getRackRolesAllocAmt(&ear, rrs.Scores)
 
+	if sc != nil {
+		var roleAccs = []string{rrs.SellerAcc, rrs.FielderAcc, rrs.DeliveryAcc, rrs.PlatformAcc}
+		var roleAmts = []*int64{&rolesAllocScore.SellerAmount, &rolesAllocScore.FielderAmount, &rolesAllocScore.DeliveryAmount, &rolesAllocScore.PlatformAmount}
+		errcm = t.useScoreCampaignCaps(stub, sc, rrs.Rackid, roleAccs, roleAmts, transFromAcc, invokeTime)
+		if errcm != nil {
+			return kdlogger.ErrorECM(errcm.Code, "allocEncourageScore useScoreCampaignCaps failed,Rackid=%s,  error=%s.", rrs.Rackid, errcm)
+		}
+	}
+
 	_, errcm = t.transferCoin(stub, transFromAcc, rrs.SellerAcc, transType, transDesc,
 		rolesAllocScore.SellerAmount+sellerComps, invokeTime, sameEntSaveTx)
 	if errcm != nil {
@@ -2076,77 +2692,636 @@ func (t *KD) allocEncourageScore(stub shim.ChaincodeStubInterface, rrs *RackRole
 }
 
 func (t *KD) allocEncourageScoreForNewRack(stub shim.ChaincodeStubInterface, paraStr string, transFromAcc, transType, transDesc string, invokeTime int64, sameEntSaveTx bool) ([]byte, *ErrorCodeMsg) {
-	//配置格式如下 "货架1,货架经营者账户,场地提供者账户,送货人账户,平台账户,奖励金额(可省略);货架2,货架经营者账户,场地提供者账户,送货人账户,平台账户,奖励金额(可省略);...."，
-	//防止输入错误，先去除两边的空格，然后再去除两边的';'（防止split出来空字符串）
-	var newStr = strings.Trim(strings.TrimSpace(paraStr), ";")
-
+	//支持两种格式
+	//json数组 [{"rid":"货架id","score":奖励积分(可省略),"slracc":"货架经营者账户","fldacc":"场地提供者账户","dvyacc":"送货人账户","pfmacc":"平台账户"},...]
+	//旧格式 "货架1,货架经营者账户,场地提供者账户,送货人账户,平台账户,奖励金额(可省略);货架2,货架经营者账户,场地提供者账户,送货人账户,平台账户,奖励金额(可省略);...."
+	//奖励积分省略或者为0时，使用默认值
 	var rresList []RackRolesEncourageScores
+	var br *BatchResult
+
+	if t.isJsonArrayPara(paraStr) {
+		err := json.Unmarshal([]byte(paraStr), &rresList)
+		if err != nil {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "allocEncourageScoreForNewRack: Unmarshal para failed. error=(%s).", err)
+		}
+		br = NewBatchResult(len(rresList))
+	} else {
+		var rackRolesScoreArr = t.splitBatchPara(paraStr)
+		rresList = make([]RackRolesEncourageScores, len(rackRolesScoreArr))
+		br = NewBatchResult(len(rackRolesScoreArr))
+
+		var eleDelim = ","
+		for i, v := range rackRolesScoreArr {
+			var rackRolesScore = strings.Trim(strings.TrimSpace(v), eleDelim)
+			var eles = strings.Split(rackRolesScore, eleDelim)
+			//至少包含货架id，四个角色
+			if len(eles) < 5 {
+				br.setFailed(i, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "allocEncourageScoreForNewRack: rackRolesScore parse error, '%s' format error 1.", rackRolesScore))
+				continue
+			}
 
-	var rackRolesScoreArr []string
+			var rres = &rresList[i]
+			rres.Rackid = eles[0]
+			rres.AllocAccs.SellerAcc = eles[1]
+			rres.AllocAccs.FielderAcc = eles[2]
+			rres.AllocAccs.DeliveryAcc = eles[3]
+			rres.AllocAccs.PlatformAcc = eles[4]
+			if len(eles) >= 6 {
+				var err error
+				rres.Scores, err = strconv.ParseInt(eles[5], 0, 64)
+				if err != nil {
+					br.setFailed(i, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "allocEncourageScoreForNewRack: rackRolesScore parse error, '%s' format error 2.", rackRolesScore))
+					continue
+				}
+			}
+		}
+	}
 
-	var err error
+	//先校验所有记录
+	var campList = make([]*ScoreCampaign, len(rresList))
+	for i := range rresList {
+		var rres = &rresList[i]
+		br.setRackid(i, rres.Rackid)
+		if br.isFailed(i) {
+			continue
+		}
 
-	//含有";"，表示有多条配置，没有则说明只有一条配置
-	if strings.Contains(newStr, ";") {
-		rackRolesScoreArr = strings.Split(newStr, ";")
+		errcm := t.checkRackAllocAccs(rres.Rackid, &rres.AllocAccs)
+		if errcm != nil {
+			br.setFailed(i, errcm)
+			continue
+		}
+		if rres.Scores < 0 {
+			br.setFailed(i, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "allocEncourageScoreForNewRack: score(%d) of rack(%s) invalid.", rres.Scores, rres.Rackid))
+			continue
+		}
 
-	} else {
-		rackRolesScoreArr = append(rackRolesScoreArr, newStr)
+		campList[i], errcm = t.getActiveScoreCampaign(stub, rres.Rackid, invokeTime)
+		if errcm != nil {
+			br.setFailed(i, errcm)
+			continue
+		}
+		if rres.Scores == 0 {
+			//有活动时，使用活动设置的新货架奖励积分
+			if campList[i] != nil && campList[i].NewRackScore > 0 {
+				rres.Scores = campList[i].NewRackScore
+			} else {
+				rres.Scores = RACK_NEWRACK_ENC_SCORE_DEFAULT
+			}
+		}
+	}
+
+	if br.hasFailed() {
+		kdlogger.Error("allocEncourageScoreForNewRack: para check failed, do nothing.")
+		return br.finish(false)
+	}
+
+	for i := range rresList {
+		errcm := t.allocEncourageScore(stub, &rresList[i], transFromAcc, transType, transDesc, invokeTime, sameEntSaveTx, 0, campList[i])
+		if errcm != nil {
+			return nil, kdlogger.ErrorECM(errcm.Code, "allocEncourageScoreForNewRack: allocEncourageScore(idx=%d, rack=%s) failed, error=%s.", i, rresList[i].Rackid, errcm)
+		}
+	}
+
+	return br.finish(true)
+}
+
+func (t *KD) getScoreCampaignKey(campid string) string {
+	return RACK_SCORE_CAMPAIGN_PREFIX + campid
+}
+
+func (t *KD) getScoreCampaignList(stub shim.ChaincodeStubInterface) ([]string, *ErrorCodeMsg) {
+	var campList = []string{}
+	clB, err := stateCache.GetState_Ex(stub, RACK_SCORE_CAMPAIGN_LIST_KEY)
+	if err != nil {
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getScoreCampaignList GetState failed. error=(%s)", err)
+	}
+	if clB == nil {
+		return campList, nil
+	}
+	err = json.Unmarshal(clB, &campList)
+	if err != nil {
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getScoreCampaignList Unmarshal failed. error=(%s)", err)
+	}
+	return campList, nil
+}
+
+func (t *KD) setScoreCampaignList(stub shim.ChaincodeStubInterface, campList []string) *ErrorCodeMsg {
+	clB, err := json.Marshal(campList)
+	if err != nil {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setScoreCampaignList Marshal failed. error=(%s)", err)
+	}
+	err = stateCache.PutState_Ex(stub, RACK_SCORE_CAMPAIGN_LIST_KEY, clB)
+	if err != nil {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setScoreCampaignList PutState_Ex failed. error=(%s)", err)
+	}
+	return nil
+}
+
+func (t *KD) getScoreCampaign(stub shim.ChaincodeStubInterface, campid string) (*ScoreCampaign, *ErrorCodeMsg) {
+	scB, err := stateCache.GetState_Ex(stub, t.getScoreCampaignKey(campid))
+	if err != nil {
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getScoreCampaign GetState(%s) failed. error=(%s)", campid, err)
+	}
+	if scB == nil {
+		return nil, nil
+	}
+	var sc ScoreCampaign
+	err = json.Unmarshal(scB, &sc)
+	if err != nil {
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getScoreCampaign Unmarshal(%s) failed. error=(%s)", campid, err)
+	}
+	return &sc, nil
+}
+
+func (t *KD) setScoreCampaign(stub shim.ChaincodeStubInterface, sc *ScoreCampaign) *ErrorCodeMsg {
+	if len(sc.CampID) == 0 {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "setScoreCampaign campid is empty.")
+	}
+	if sc.BeginTime >= sc.EndTime {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "setScoreCampaign time range(%d,%d) invalid.", sc.BeginTime, sc.EndTime)
+	}
+	if len(sc.RangeList) == 0 || len(sc.RangeList) != len(sc.PercentList) {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "setScoreCampaign range cfg invalid.")
+	}
+	if sc.Period < 0 || sc.RackCap < 0 || sc.AccCap < 0 || sc.ExpireDuration < 0 || sc.NewRackScore < 0 {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "setScoreCampaign cap cfg invalid(%d,%d,%d,%d,%d).", sc.Period, sc.RackCap, sc.AccCap, sc.ExpireDuration, sc.NewRackScore)
+	}
+
+	scB, err := json.Marshal(sc)
+	if err != nil {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setScoreCampaign Marshal failed. error=(%s)", err)
+	}
+	err = stateCache.PutState_Ex(stub, t.getScoreCampaignKey(sc.CampID), scB)
+	if err != nil {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setScoreCampaign PutState_Ex failed. error=(%s)", err)
+	}
+
+	campList, errcm := t.getScoreCampaignList(stub)
+	if errcm != nil {
+		return kdlogger.ErrorECM(errcm.Code, "setScoreCampaign getScoreCampaignList failed. error=(%s)", errcm)
+	}
+	if !StrSliceContains(campList, sc.CampID) {
+		campList = append(campList, sc.CampID)
+		errcm = t.setScoreCampaignList(stub, campList)
+		if errcm != nil {
+			return kdlogger.ErrorECM(errcm.Code, "setScoreCampaign setScoreCampaignList failed. error=(%s)", errcm)
+		}
+	}
+
+	kdlogger.Info("setScoreCampaign: campaign=%+v", *sc)
+
+	return nil
+}
+
+func (t *KD) delScoreCampaign(stub shim.ChaincodeStubInterface, campid string) *ErrorCodeMsg {
+	campList, errcm := t.getScoreCampaignList(stub)
+	if errcm != nil {
+		return kdlogger.ErrorECM(errcm.Code, "delScoreCampaign getScoreCampaignList failed. error=(%s)", errcm)
+	}
+	if !StrSliceContains(campList, campid) {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "delScoreCampaign campaign(%s) not exists.", campid)
+	}
+
+	errcm = t.setScoreCampaignList(stub, StrSliceDelete(campList, campid))
+	if errcm != nil {
+		return kdlogger.ErrorECM(errcm.Code, "delScoreCampaign setScoreCampaignList failed. error=(%s)", errcm)
+	}
+
+	err := stub.DelState(t.getScoreCampaignKey(campid))
+	if err != nil {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "delScoreCampaign DelState failed. error=(%s)", err)
+	}
+
+	return nil
+}
+
+//获取invokeTime时适用于该货架的活动。指定了该货架的活动优先于适用所有货架的活动，同类活动中开始时间晚的优先
+func (t *KD) getActiveScoreCampaign(stub shim.ChaincodeStubInterface, rackid string, invokeTime int64) (*ScoreCampaign, *ErrorCodeMsg) {
+	campList, errcm := t.getScoreCampaignList(stub)
+	if errcm != nil {
+		return nil, kdlogger.ErrorECM(errcm.Code, "getActiveScoreCampaign getScoreCampaignList failed. error=(%s)", errcm)
 	}
 
-	var eleDelim = ","
-	var rackRolesScore string
-	var errList []string
-	for _, v := range rackRolesScoreArr {
-		rackRolesScore = strings.Trim(strings.TrimSpace(v), eleDelim)
-		if !strings.Contains(rackRolesScore, eleDelim) {
-			kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "allocEncourageScoreForNewRack  rackRolesSales parse error, '%s' has no '%s'.", rackRolesScore, eleDelim)
-			errList = append(errList, rackRolesScore)
+	var active *ScoreCampaign = nil
+	var activeForRack = false
+	for _, cid := range campList {
+		sc, errcm := t.getScoreCampaign(stub, cid)
+		if errcm != nil {
+			return nil, kdlogger.ErrorECM(errcm.Code, "getActiveScoreCampaign getScoreCampaign(%s) failed. error=(%s)", cid, errcm)
+		}
+		if sc == nil || invokeTime < sc.BeginTime || invokeTime >= sc.EndTime {
 			continue
 		}
-		var eles = strings.Split(rackRolesScore, eleDelim)
-		//至少包含货架id，四个角色
-		if len(eles) < 5 {
-			kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "allocEncourageScoreForNewRack  rackRolesSales parse error, '%s' format error 1.", rackRolesScore)
-			errList = append(errList, rackRolesScore)
+
+		var forRack = StrSliceContains(sc.RackList, rackid)
+		if !forRack && len(sc.RackList) > 0 {
 			continue
 		}
 
-		var rres RackRolesEncourageScores
+		if active == nil || (forRack && !activeForRack) ||
+			(forRack == activeForRack && sc.BeginTime > active.BeginTime) {
+			active = sc
+			activeForRack = forRack
+		}
+	}
 
-		rres.Rackid = eles[0]
-		rres.AllocAccs.SellerAcc = eles[1]
-		rres.AllocAccs.FielderAcc = eles[2]
-		rres.AllocAccs.DeliveryAcc = eles[3]
-		rres.AllocAccs.PlatformAcc = eles[4]
-		if len(eles) >= 6 {
-			rres.Scores, err = strconv.ParseInt(eles[5], 0, 64)
-			if err != nil {
-				kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "allocEncourageScoreForNewRack  rackRolesSales parse error, '%s' format error 2.", rackRolesScore)
-				errList = append(errList, rres.Rackid)
-				continue
+	return active, nil
+}
+
+//活动上限使用情况的key，按周期统计。typ为 r(货架) 或 a(账户)
+func (t *KD) getScoreCampUsedKey(sc *ScoreCampaign, typ, id string, invokeTime int64) string {
+	var period int64 = 0
+	if sc.Period > 0 {
+		period = (invokeTime - sc.BeginTime) / sc.Period
+	}
+	return RACK_SCORE_CAMP_USED_PREFIX + sc.CampID + "_" + strconv.FormatInt(period, 10) + "_" + typ + "_" + id
+}
+
+func (t *KD) getScoreCampUsed(stub shim.ChaincodeStubInterface, usedKey string) (int64, *ErrorCodeMsg) {
+	usedB, err := stateCache.GetState_Ex(stub, usedKey)
+	if err != nil {
+		return 0, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getScoreCampUsed GetState(%s) failed. error=(%s)", usedKey, err)
+	}
+	if usedB == nil {
+		return 0, nil
+	}
+	used, err := strconv.ParseInt(string(usedB), 10, 64)
+	if err != nil {
+		return 0, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getScoreCampUsed ParseInt(%s) failed. error=(%s)", string(usedB), err)
+	}
+	return used, nil
+}
+
+//活动上限剩余的额度。cap为0表示不限，返回-1
+func (t *KD) getScoreCampCapRest(stub shim.ChaincodeStubInterface, usedKey string, cap int64) (int64, *ErrorCodeMsg) {
+	if cap <= 0 {
+		return -1, nil
+	}
+
+	used, errcm := t.getScoreCampUsed(stub, usedKey)
+	if errcm != nil {
+		return 0, kdlogger.ErrorECM(errcm.Code, "getScoreCampCapRest getScoreCampUsed failed. error=(%s)", errcm)
+	}
+	if used >= cap {
+		return 0, nil
+	}
+	return cap - used, nil
+}
+
+//累加已使用的额度
+func (t *KD) addScoreCampUsed(stub shim.ChaincodeStubInterface, usedKey string, amount int64) *ErrorCodeMsg {
+	if amount == 0 {
+		return nil
+	}
+
+	used, errcm := t.getScoreCampUsed(stub, usedKey)
+	if errcm != nil {
+		return kdlogger.ErrorECM(errcm.Code, "addScoreCampUsed getScoreCampUsed failed. error=(%s)", errcm)
+	}
+
+	err := stateCache.PutState_Ex(stub, usedKey, []byte(strconv.FormatInt(used+amount, 10)))
+	if err != nil {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "addScoreCampUsed PutState_Ex(%s) failed. error=(%s)", usedKey, err)
+	}
+	return nil
+}
+
+//按活动的上限计算各角色实际奖励的积分，结果直接写回amts。先按账户上限限制，再按货架上限限制奖励的总数（不够时各角色按比例减少），
+//最后按实际奖励的积分累加货架和账户已使用的额度，这样被账户上限扣掉的积分不会占用货架的额度。
+//活动设置了有效期时，记录各账户获得的积分。fromAcc为发放积分的账户
+func (t *KD) useScoreCampaignCaps(stub shim.ChaincodeStubInterface, sc *ScoreCampaign, rackid string, accs []string, amts []*int64, fromAcc string, invokeTime int64) *ErrorCodeMsg {
+	//同一个账户可能担任多个角色，共用一个账户上限
+	var accRest = make(map[string]int64)
+	var total int64
+	for i, acc := range accs {
+		rest, ok := accRest[acc]
+		if !ok {
+			var errcm *ErrorCodeMsg
+			rest, errcm = t.getScoreCampCapRest(stub, t.getScoreCampUsedKey(sc, "a", acc, invokeTime), sc.AccCap)
+			if errcm != nil {
+				return kdlogger.ErrorECM(errcm.Code, "useScoreCampaignCaps getScoreCampCapRest(acc=%s) failed. error=(%s)", acc, errcm)
 			}
-		} else {
-			rres.Scores = RACK_NEWRACK_ENC_SCORE_DEFAULT
 		}
+		if rest >= 0 {
+			if *amts[i] > rest {
+				kdlogger.Info("useScoreCampaignCaps: acc %s reach cap of campaign %s, %d -> %d.", acc, sc.CampID, *amts[i], rest)
+				*amts[i] = rest
+			}
+			rest -= *amts[i]
+		}
+		accRest[acc] = rest
+		total += *amts[i]
+	}
 
-		rresList = append(rresList, rres)
+	var rackUsedKey = t.getScoreCampUsedKey(sc, "r", rackid, invokeTime)
+	rackRest, errcm := t.getScoreCampCapRest(stub, rackUsedKey, sc.RackCap)
+	if errcm != nil {
+		return kdlogger.ErrorECM(errcm.Code, "useScoreCampaignCaps getScoreCampCapRest(rack=%s) failed. error=(%s)", rackid, errcm)
+	}
+	if rackRest >= 0 && total > rackRest {
+		kdlogger.Info("useScoreCampaignCaps: rack %s reach cap of campaign %s, %d -> %d.", rackid, sc.CampID, total, rackRest)
+		var capped int64
+		for _, amt := range amts {
+			*amt = *amt * rackRest / total
+			capped += *amt
+		}
+		total = capped
+	}
+
+	errcm = t.addScoreCampUsed(stub, rackUsedKey, total)
+	if errcm != nil {
+		return kdlogger.ErrorECM(errcm.Code, "useScoreCampaignCaps addScoreCampUsed(rack=%s) failed. error=(%s)", rackid, errcm)
 	}
 
-	for _, rres := range rresList {
-		errcm := t.allocEncourageScore(stub, &rres, transFromAcc, transType, transDesc, invokeTime, sameEntSaveTx, 0)
+	for i, acc := range accs {
+		errcm = t.addScoreCampUsed(stub, t.getScoreCampUsedKey(sc, "a", acc, invokeTime), *amts[i])
 		if errcm != nil {
-			kdlogger.ErrorECM(errcm.Code, "allocEncourageScoreForNewRack allocEncourageScore failed, error=%s.", errcm)
-			errList = append(errList, rres.Rackid)
+			return kdlogger.ErrorECM(errcm.Code, "useScoreCampaignCaps addScoreCampUsed(acc=%s) failed. error=(%s)", acc, errcm)
+		}
+
+		if *amts[i] > 0 && sc.ExpireDuration > 0 {
+			var sg ScoreGrant
+			sg.AccName = acc
+			sg.CampID = sc.CampID
+			sg.Rackid = rackid
+			sg.Amount = *amts[i]
+			sg.GrantTime = invokeTime
+			sg.ExpireTime = invokeTime + sc.ExpireDuration
+			sg.FromAcc = fromAcc
+
+			errcm = t.setScoreGrant(stub, &sg)
+			if errcm != nil {
+				return kdlogger.ErrorECM(errcm.Code, "useScoreCampaignCaps setScoreGrant failed. error=(%s)", errcm)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (t *KD) getScoreGrantSeqKey(accName string) string {
+	return RACK_SCORE_GRANT_SEQ_PREFIX + accName
+}
+func (t *KD) getScoreGrantKey(accName string, seq int64) string {
+	return RACK_SCORE_GRANT_PREFIX + accName + "_" + strconv.FormatInt(seq, 10)
+}
+
+func (t *KD) setScoreGrant(stub shim.ChaincodeStubInterface, sg *ScoreGrant) *ErrorCodeMsg {
+	var seqKey = t.getScoreGrantSeqKey(sg.AccName)
+	seq, errcm := t.getTransSeq(stub, seqKey)
+	if errcm != nil {
+		return kdlogger.ErrorECM(errcm.Code, "setScoreGrant getTransSeq failed. error=(%s)", errcm)
+	}
+	seq++
+
+	sg.Serial = seq
+	sgB, err := json.Marshal(sg)
+	if err != nil {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setScoreGrant Marshal failed. error=(%s)", err)
+	}
+	err = stateCache.PutState_Ex(stub, t.getScoreGrantKey(sg.AccName, seq), sgB)
+	if err != nil {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setScoreGrant PutState_Ex failed. error=(%s)", err)
+	}
+
+	errcm = t.setTransSeq(stub, seqKey, seq)
+	if errcm != nil {
+		return kdlogger.ErrorECM(errcm.Code, "setScoreGrant setTransSeq failed. error=(%s)", errcm)
+	}
+	return nil
+}
+
+//查询账户通过活动获得的积分，queryTime时已过期的积分单独统计
+func (t *KD) queryAccScoreGrants(stub shim.ChaincodeStubInterface, accName string, begSeq, count, queryTime int64) ([]byte, *ErrorCodeMsg) {
+	maxSeq, errcm := t.getTransSeq(stub, t.getScoreGrantSeqKey(accName))
+	if errcm != nil {
+		return nil, kdlogger.ErrorECM(errcm.Code, "queryAccScoreGrants getTransSeq failed. error=(%s)", errcm)
+	}
+
+	if begSeq <= 0 {
+		begSeq = 1
+	}
+	if count <= 0 {
+		count = math.MaxInt64 - begSeq
+	}
+
+	var qsg QueryScoreGrant
+	qsg.GrantList = []ScoreGrant{}
+	qsg.NextSerial = -1
+
+	for seq := begSeq; seq <= maxSeq; seq++ {
+		if int64(len(qsg.GrantList)) >= count {
+			qsg.NextSerial = seq
+			break
+		}
+
+		sgB, err := stateCache.GetState_Ex(stub, t.getScoreGrantKey(accName, seq))
+		if err != nil {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "queryAccScoreGrants GetState failed. error=(%s)", err)
+		}
+		if sgB == nil {
 			continue
 		}
+		var sg ScoreGrant
+		err = json.Unmarshal(sgB, &sg)
+		if err != nil {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "queryAccScoreGrants Unmarshal failed. error=(%s)", err)
+		}
+
+		sg.Expired = sg.ExpireTime > 0 && sg.ExpireTime <= queryTime
+		if sg.Expired {
+			qsg.ExpiredAmount += sg.Amount
+		} else {
+			qsg.ValidAmount += sg.Amount
+		}
+		qsg.GrantList = append(qsg.GrantList, sg)
 	}
 
-	if len(errList) > 0 {
-		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_INNER_ERROR, "allocEncourageScoreForNewRack: some err,[%s].", strings.Join(errList, ";"))
+	qsgB, err := json.Marshal(qsg)
+	if err != nil {
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "queryAccScoreGrants Marshal failed. error=(%s)", err)
 	}
+	return qsgB, nil
+}
 
-	return nil, nil
+func (t *KD) getScoreGrantSweepKey(accName string) string {
+	return RACK_SCORE_GRANT_SWEEP_PREFIX + accName
+}
+
+//处理账户在invokeTime已过期且未处理的活动奖励积分。
+//返回处理结果，以及每个发放账户应退回的积分。没有记录发放账户的积分退回defaultFrom
+func (t *KD) sweepExpiredScoreGrants(stub shim.ChaincodeStubInterface, accName, defaultFrom string, invokeTime int64) (*ScoreExpireResult, map[string]int64, *ErrorCodeMsg) {
+	maxSeq, errcm := t.getTransSeq(stub, t.getScoreGrantSeqKey(accName))
+	if errcm != nil {
+		return nil, nil, kdlogger.ErrorECM(errcm.Code, "sweepExpiredScoreGrants getTransSeq failed. error=(%s)", errcm)
+	}
+	var sweepKey = t.getScoreGrantSweepKey(accName)
+	sweptSeq, errcm := t.getTransSeq(stub, sweepKey)
+	if errcm != nil {
+		return nil, nil, kdlogger.ErrorECM(errcm.Code, "sweepExpiredScoreGrants getTransSeq(sweep) failed. error=(%s)", errcm)
+	}
+
+	var ser = &ScoreExpireResult{AccName: accName}
+	var refundMap = make(map[string]int64)
+	//有效期不同的活动，积分不一定按发放顺序过期。只有之前的记录都已处理时，才推进已处理的序列号
+	var contiguous = true
+	for seq := sweptSeq + 1; seq <= maxSeq; seq++ {
+		var sgKey = t.getScoreGrantKey(accName, seq)
+		sgB, err := stateCache.GetState_Ex(stub, sgKey)
+		if err != nil {
+			return nil, nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "sweepExpiredScoreGrants GetState failed. error=(%s)", err)
+		}
+		if sgB == nil {
+			if contiguous {
+				sweptSeq = seq
+			}
+			continue
+		}
+		var sg ScoreGrant
+		err = json.Unmarshal(sgB, &sg)
+		if err != nil {
+			return nil, nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "sweepExpiredScoreGrants Unmarshal failed. error=(%s)", err)
+		}
+
+		if !sg.Swept {
+			if sg.ExpireTime <= 0 || sg.ExpireTime > invokeTime {
+				contiguous = false
+				continue
+			}
+
+			var from = sg.FromAcc
+			if len(from) == 0 {
+				from = defaultFrom
+			}
+			var deduct = sg.Amount
+			//自己发放给自己的积分不用扣回
+			if from == accName {
+				deduct = 0
+			}
+
+			sg.Swept = true
+			sg.Deducted = deduct
+			sg.SweepTime = invokeTime
+			sgB, err = json.Marshal(sg)
+			if err != nil {
+				return nil, nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "sweepExpiredScoreGrants Marshal failed. error=(%s)", err)
+			}
+			err = stateCache.PutState_Ex(stub, sgKey, sgB)
+			if err != nil {
+				return nil, nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "sweepExpiredScoreGrants PutState_Ex failed. error=(%s)", err)
+			}
+
+			ser.Expired += sg.Amount
+			ser.Deducted += deduct
+			if deduct > 0 {
+				refundMap[from] += deduct
+			}
+		}
+
+		if contiguous {
+			sweptSeq = seq
+		}
+	}
+
+	errcm = t.setTransSeq(stub, sweepKey, sweptSeq)
+	if errcm != nil {
+		return nil, nil, kdlogger.ErrorECM(errcm.Code, "sweepExpiredScoreGrants setTransSeq failed. error=(%s)", errcm)
+	}
+
+	return ser, refundMap, nil
+}
+
+//扣回已过期的活动奖励积分，退回发放积分的账户
+//参数格式 "账户1;账户2;..."。应扣回的积分由kd记录的发放记录算出；kd看不到账户余额，所以扣回的转账设置了UpToBalance，
+//由账户系统按账户当时的可用余额扣回，已经用掉的积分不再扣回
+func (t *KD) expireScores(stub shim.ChaincodeStubInterface, paraStr string, transFromAcc, transType, transDesc string, invokeTime int64) ([]byte, *ErrorCodeMsg) {
+	var accArr = t.splitBatchPara(paraStr)
+	if len(accArr) == 0 {
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "expireScores: account list is empty.")
+	}
+
+	var serList = []ScoreExpireResult{}
+	for _, v := range accArr {
+		var acc = strings.TrimSpace(v)
+		//账户名中不能有','，以前的格式中','后面是调用方传入的余额，现在不再接受
+		if len(acc) == 0 || strings.Contains(acc, ",") {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "expireScores: account '%s' invalid.", acc)
+		}
+
+		ser, refundMap, errcm := t.sweepExpiredScoreGrants(stub, acc, transFromAcc, invokeTime)
+		if errcm != nil {
+			return nil, kdlogger.ErrorECM(errcm.Code, "expireScores: sweepExpiredScoreGrants(%s) failed. error=(%s)", acc, errcm)
+		}
+
+		//map遍历顺序不固定，排序后转账
+		var fromList []string
+		for from := range refundMap {
+			fromList = append(fromList, from)
+		}
+		sort.Strings(fromList)
+
+		for _, from := range fromList {
+			_, errcm = t.transferCoinUpToBalance(stub, acc, from, transType, transDesc, refundMap[from], invokeTime)
+			if errcm != nil {
+				return nil, kdlogger.ErrorECM(errcm.Code, "expireScores: transferCoin(%s->%s) failed. error=(%s)", acc, from, errcm)
+			}
+		}
+
+		kdlogger.Info("expireScores: %+v, refund=%v", *ser, refundMap)
+		serList = append(serList, *ser)
+	}
+
+	serB, err := json.Marshal(serList)
+	if err != nil {
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "expireScores: Marshal failed. error=(%s)", err)
+	}
+	return serB, nil
+}
+
+//预览销售额能获得的奖励积分，不修改状态
+func (t *KD) previewEncourageScore(stub shim.ChaincodeStubInterface, rackid string, sales, previewTime int64) ([]byte, *ErrorCodeMsg) {
+	var sp ScorePreview
+	sp.Rackid = rackid
+	sp.Sales = sales
+	sp.RackCapRest = -1
+
+	score, sc, errcm := t.getEncourageScoreBySales(stub, rackid, sales/100, previewTime) //输入的单位为分，计算以元为单位
+	if errcm != nil {
+		return nil, kdlogger.ErrorECM(errcm.Code, "previewEncourageScore getEncourageScoreBySales failed. error=(%s)", errcm)
+	}
+	sp.Score = score
+	sp.CappedScore = score
+
+	if sc != nil {
+		sp.CampID = sc.CampID
+		if sc.RackCap > 0 {
+			used, errcm := t.getScoreCampUsed(stub, t.getScoreCampUsedKey(sc, "r", rackid, previewTime))
+			if errcm != nil {
+				return nil, kdlogger.ErrorECM(errcm.Code, "previewEncourageScore getScoreCampUsed failed. error=(%s)", errcm)
+			}
+			sp.RackCapRest = sc.RackCap - used
+			if sp.RackCapRest < 0 {
+				sp.RackCapRest = 0
+			}
+			if sp.CappedScore > sp.RackCapRest {
+				sp.CappedScore = sp.RackCapRest
+			}
+		}
+	}
+
+	var ear EarningAllocRate
+	_, errcm = t.getRackAllocCfg(stub, rackid, &ear)
+	if errcm != nil {
+		return nil, kdlogger.ErrorECM(errcm.Code, "previewEncourageScore getRackAllocCfg failed. error=(%s)", errcm)
+	}
+	sp.RolesAlloc = *t.getRackRolesAllocAmt(&ear, sp.CappedScore)
+
+	spB, err := json.Marshal(sp)
+	if err != nil {
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "previewEncourageScore Marshal failed. error=(%s)", err)
+	}
+	return spB, nil
 }
 
 /* ----------------------- 积分奖励相关 ----------------------- */
@@ -2294,13 +3469,13 @@ func (t *KD) userBuyFinance(stub shim.ChaincodeStubInterface, accName, rackid, f
 		_, ok := rfi.UserAmountMap[accName]
 		if ok {
 			//如果用户已提取了，又来买，那么从新记录投资额，不能累计，否则会把前一次的累计进来。
-			if strSliceContains(rfi.PayFinanceUserList, accName) {
+			if StrSliceContains(rfi.PayFinanceUserList, accName) {
 				rfi.AmountFinca -= rfi.UserAmountMap[accName] //实际投资额度要减去上一次的
 				rfi.UserAmountMap[accName] = amount
 				if isRenewal {
 					rfi.UserRenewalMap[accName] = amount
 				}
-				rfi.PayFinanceUserList = strSliceDelete(rfi.PayFinanceUserList, accName)
+				rfi.PayFinanceUserList = StrSliceDelete(rfi.PayFinanceUserList, accName)
 			} else {
 				rfi.UserAmountMap[accName] += amount
 				if isRenewal {
@@ -2384,7 +3559,7 @@ func (t *KD) userBuyFinance(stub shim.ChaincodeStubInterface, accName, rackid, f
 	}
 	kdlogger.Debug("userBuyFinance: ent=%+v", arfi)
 
-	if !strSliceContains(fi.RackList, ri.RackID) {
+	if !StrSliceContains(fi.RackList, ri.RackID) {
 		fi.RackList = append(fi.RackList, ri.RackID)
 	}
 	fiJson, err := json.Marshal(fi)
@@ -2392,7 +3567,7 @@ func (t *KD) userBuyFinance(stub shim.ChaincodeStubInterface, accName, rackid, f
 		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "userBuyFinance:  Marshal failed. error=(%s).", err)
 	}
 
-	if !strSliceContains(ri.FinacList, fi.FID) {
+	if !StrSliceContains(ri.FinacList, fi.FID) {
 		ri.FinacList = append(ri.FinacList, fi.FID)
 	}
 
@@ -2426,61 +3601,109 @@ func (t *KD) userBuyFinance(stub shim.ChaincodeStubInterface, accName, rackid, f
 	return nil, nil
 }
 
-func (t *KD) financeBonus(stub shim.ChaincodeStubInterface, fid, rackales string, invokeTime int64) ([]byte, *ErrorCodeMsg) {
-	//配置格式如下 "货架1:销售额;货架2:销售额"，
-	//防止输入错误，先去除两边的空格，然后再去除两边的';'（防止split出来空字符串）
-	var newStr = strings.Trim(strings.TrimSpace(rackales), ";")
-
-	var rackSalesArr []string
-
-	var err error
+func (t *KD) financeBonus(stub shim.ChaincodeStubInterface, fid, rackSales string, invokeTime int64) ([]byte, *ErrorCodeMsg) {
+	//支持两种格式
+	//json数组 [{"rid":"货架1","sale":销售额},{"rid":"货架2","sale":销售额}]
+	//旧格式 "货架1:销售额;货架2:销售额"
+	var rsList []RackSales
+	var br *BatchResult
 
-	//含有";"，表示有多条配置，没有则说明只有一条配置
-	if strings.Contains(newStr, ";") {
-		rackSalesArr = strings.Split(newStr, ";")
+	if t.isJsonArrayPara(rackSales) {
+		err := json.Unmarshal([]byte(rackSales), &rsList)
+		if err != nil {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "financeBonus: Unmarshal para failed. error=(%s).", err)
+		}
+		br = NewBatchResult(len(rsList))
 	} else {
-		rackSalesArr = append(rackSalesArr, newStr)
+		var rackSalesArr = t.splitBatchPara(rackSales)
+		rsList = make([]RackSales, len(rackSalesArr))
+		br = NewBatchResult(len(rackSalesArr))
+
+		var eleDelim = ":"
+		for i, v := range rackSalesArr {
+			var rs = strings.Trim(strings.TrimSpace(v), eleDelim)
+			var eles = strings.Split(rs, eleDelim)
+			if len(eles) < 2 {
+				br.setFailed(i, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "financeBonus: rackSales parse error, '%s' format error 1.", rs))
+				continue
+			}
+
+			var err error
+			rsList[i].Rackid = eles[0]
+			rsList[i].Sales, err = strconv.ParseInt(eles[1], 0, 64)
+			if err != nil {
+				br.setFailed(i, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "financeBonus: sales parse error, '%s' format error 2.", rs))
+				continue
+			}
+		}
 	}
 
-	var eleDelim = ":"
-	var rackSales string
-	var errRackList []string
-	for _, v := range rackSalesArr {
-		rackSales = strings.Trim(strings.TrimSpace(v), eleDelim)
-		if !strings.Contains(rackSales, eleDelim) {
-			kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "financeBonus: rackSales parse error, '%s' has no '%s'.", rackSales, eleDelim)
-			errRackList = append(errRackList, rackSales)
+	//先校验所有记录
+	var rackSet = make(map[string]int)
+	for i, rs := range rsList {
+		br.setRackid(i, rs.Rackid)
+		if br.isFailed(i) {
 			continue
 		}
-		var eles = strings.Split(rackSales, eleDelim)
-		if len(eles) < 2 {
-			kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "financeBonus: rackSales parse error, '%s' format error 1.", rackSales)
-			errRackList = append(errRackList, rackSales)
+
+		if len(rs.Rackid) == 0 {
+			br.setFailed(i, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "financeBonus: rackid is empty."))
 			continue
 		}
-
-		var rackid = eles[0]
-		var sales int64
-		sales, err = strconv.ParseInt(eles[1], 0, 64)
-		if err != nil {
-			kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "financeBonus: sales parse error, '%s' format error 2.", rackSales)
-			errRackList = append(errRackList, rackid)
+		if rs.Sales < 0 {
+			br.setFailed(i, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "financeBonus: sales(%d) of rack(%s) invalid.", rs.Sales, rs.Rackid))
 			continue
 		}
+		if idx, ok := rackSet[rs.Rackid]; ok {
+			br.setFailed(i, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "financeBonus: rack(%s) duplicated with index %d.", rs.Rackid, idx))
+			continue
+		}
+		rackSet[rs.Rackid] = i
 
-		errcm := t.financeBonus4OneRack(stub, rackid, fid, sales, invokeTime)
+		errcm := t.financeBonusPreCheck(stub, rs.Rackid, fid)
 		if errcm != nil {
-			kdlogger.ErrorECM(errcm.Code, "financeBonus: financeBonus4OneRack failed, error=(%s)", errcm)
-			errRackList = append(errRackList, rackid)
+			br.setFailed(i, errcm)
 			continue
 		}
 	}
 
-	if len(errRackList) > 0 {
-		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_INNER_ERROR, "financeBonus: has some error:(%s)", strings.Join(errRackList, ";"))
+	if br.hasFailed() {
+		kdlogger.Error("financeBonus: para check failed, do nothing.")
+		return br.finish(false)
 	}
 
-	return nil, nil
+	for i, rs := range rsList {
+		errcm := t.financeBonus4OneRack(stub, rs.Rackid, fid, rs.Sales, invokeTime)
+		if errcm != nil {
+			return nil, kdlogger.ErrorECM(errcm.Code, "financeBonus: financeBonus4OneRack(idx=%d, rack=%s) failed, error=(%s)", i, rs.Rackid, errcm)
+		}
+	}
+
+	return br.finish(true)
+}
+
+//分红前检查货架理财信息是否存在，是否已分红
+func (t *KD) financeBonusPreCheck(stub shim.ChaincodeStubInterface, rackid, fid string) *ErrorCodeMsg {
+	var rackFinacInfoKey = t.getRackFinacInfoKey(rackid, fid)
+
+	rfiB, err := stateCache.GetState_Ex(stub, rackFinacInfoKey)
+	if err != nil {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "financeBonusPreCheck:  GetState(%s) failed. error=(%s).", rackFinacInfoKey, err)
+	}
+	if rfiB == nil {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "financeBonusPreCheck:  rackFinacInfo not exists(%s,%s).", rackid, fid)
+	}
+	var rfi RackFinancInfo
+	err = json.Unmarshal(rfiB, &rfi)
+	if err != nil {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "financeBonusPreCheck:  Unmarshal failed. error=(%s).", err)
+	}
+
+	if rfi.Stage >= FINANC_STAGE_BONUS_FINISH {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "financeBonusPreCheck: rack(rid=%s fid=%s) has bonus already.", rackid, fid)
+	}
+
+	return nil
 }
 
 func (t *KD) financeBonus4OneRack(stub shim.ChaincodeStubInterface, rackid, fid string, sales, invokeTime int64) *ErrorCodeMsg {
@@ -2506,14 +3729,7 @@ func (t *KD) financeBonus4OneRack(stub shim.ChaincodeStubInterface, rackid, fid
 
 	rfi.CEInfo.WareSales = sales
 
-	//货架利润
-	var rackProfit = rfi.CEInfo.WareSales * int64(rfi.RFCfg.ProfitsPercent) / 100
-	//经营者获取的利润
-	var sellerProfit = rackProfit * rfi.RolesAllocRate.SellerRate / (rfi.RolesAllocRate.SellerRate + rfi.RolesAllocRate.FielderRate + rfi.RolesAllocRate.DeliveryRate + rfi.RolesAllocRate.PlatformRate)
-	//分给投资者的利润
-	var profit = sellerProfit * int64(rfi.RFCfg.InvestProfitsPercent) / 100
-
-	profit = profit / 100 //利润的单位为分，一块钱兑一积分
+	rackProfit, sellerProfit, profit := t.calcRackFinanceProfit(&rfi)
 
 	kdlogger.Debug("financeBonus4OneRack: rfi.RFCfg=%+v, rfi.RolesAllocRate=%+v", rfi.RFCfg, rfi.RolesAllocRate)
 
@@ -2557,6 +3773,24 @@ func (t *KD) financeBonus4OneRack(stub shim.ChaincodeStubInterface, rackid, fid
 	return nil
 }
 
+//根据销售额计算货架利润、经营者利润和分给投资者的利润（积分）
+func (t *KD) calcRackFinanceProfit(rfi *RackFinancInfo) (int64, int64, int64) {
+	//货架利润
+	var rackProfit = rfi.CEInfo.WareSales * int64(rfi.RFCfg.ProfitsPercent) / 100
+	//经营者获取的利润
+	var rateSum = rfi.RolesAllocRate.SellerRate + rfi.RolesAllocRate.FielderRate + rfi.RolesAllocRate.DeliveryRate + rfi.RolesAllocRate.PlatformRate
+	var sellerProfit int64 = 0
+	if rateSum > 0 {
+		sellerProfit = rackProfit * rfi.RolesAllocRate.SellerRate / rateSum
+	}
+	//分给投资者的利润
+	var profit = sellerProfit * int64(rfi.RFCfg.InvestProfitsPercent) / 100
+
+	profit = profit / 100 //利润的单位为分，一块钱兑一积分
+
+	return rackProfit, sellerProfit, profit
+}
+
 var currentFidCache string
 
 func (t *KD) setCurrentFid(stub shim.ChaincodeStubInterface, currentFid string) *ErrorCodeMsg {
@@ -2716,7 +3950,7 @@ func (t *KD) getRackFinanceAmount(stub shim.ChaincodeStubInterface, rackid, fid
 	}
 	var totalAmt int64 = 0
 	for acc, amt := range rfi.UserAmountMap {
-		if !strSliceContains(rfi.PayFinanceUserList, acc) {
+		if !StrSliceContains(rfi.PayFinanceUserList, acc) {
 			totalAmt += amt
 		}
 	}
@@ -2724,7 +3958,7 @@ func (t *KD) getRackFinanceAmount(stub shim.ChaincodeStubInterface, rackid, fid
 	return totalAmt, nil
 }
 
-func (t *KD) financeIssueFinishAfter(stub shim.ChaincodeStubInterface, currentFid string, invokeTime int64) *ErrorCodeMsg {
+func (t *KD) financeIssueFinishAfter(stub shim.ChaincodeStubInterface, accName, currentFid string, invokeTime int64, transType, desc string) *ErrorCodeMsg {
 	//看是否已经处理过
 	finishIdB, err := stateCache.GetState_Ex(stub, RACKFINACISSUEFINISHID_KEY)
 	if err != nil {
@@ -2793,10 +4027,11 @@ func (t *KD) financeIssueFinishAfter(stub shim.ChaincodeStubInterface, currentFi
 	}
 
 	//为上一期理财续期
-	return t.financeRenewalPreviousFinance(stub, currentFid, invokeTime)
+	return t.financeRenewalPreviousFinance(stub, accName, currentFid, invokeTime, transType, desc)
 }
 
-func (t *KD) financeRenewalPreviousFinance(stub shim.ChaincodeStubInterface, currentFid string, invokeTime int64) *ErrorCodeMsg {
+//accName为自动赎回时的付款账户
+func (t *KD) financeRenewalPreviousFinance(stub shim.ChaincodeStubInterface, accName, currentFid string, invokeTime int64, transType, desc string) *ErrorCodeMsg {
 	//看上期的理财中，哪些没有提取的自动续期
 	//调用理财续期的接口时，已经将最新的理财期号设置了（调用setCurrentFid），所以这里取前一期的期号
 	preFid, errcm := t.getPreviousFid(stub)
@@ -2851,9 +4086,43 @@ func (t *KD) financeRenewalPreviousFinance(stub shim.ChaincodeStubInterface, cur
 
 		kdlogger.Debug("financeRenewal: rfi=%+v", rfi)
 
-		for acc, amt := range rfi.UserAmountMap {
+		//map遍历顺序不固定，排序后处理，保证各节点的转账顺序一致
+		var accList []string
+		for acc, _ := range rfi.UserAmountMap {
+			accList = append(accList, acc)
+		}
+		sort.Strings(accList)
+
+		for _, acc := range accList {
+			var amt = rfi.UserAmountMap[acc]
+
 			//已赎回的用户不在续期
-			if strSliceContains(rfi.PayFinanceUserList, acc) {
+			if StrSliceContains(rfi.PayFinanceUserList, acc) {
+				continue
+			}
+
+			pref, errcm := t.getAccRackFinancePref(stub, acc, rackid)
+			if errcm != nil {
+				return kdlogger.ErrorECM(errcm.Code, "financeRenewal: getAccRackFinancePref(%s,%s) failed. error=(%s).", acc, rackid, errcm)
+			}
+
+			//设置了自动赎回的用户，赎回上期的本金和收益，不再续期
+			if pref == FINANC_PREF_AUTO_REDEEM {
+				kdlogger.Info("financeRenewal: auto redeem for %s,%s,%s", acc, rackid, preFid)
+
+				accEnt, errcm := t.getAccountRackInvestInfo(stub, acc)
+				if errcm != nil {
+					return kdlogger.ErrorECM(errcm.Code, "financeRenewal: getAccountRackInvestInfo(%s) failed. error=(%s).", acc, errcm)
+				}
+				if accEnt == nil {
+					return kdlogger.ErrorECM(ERRCODE_COMMON_INNER_ERROR, "financeRenewal: AccRackInvest(%s) not exists.", acc)
+				}
+
+				//本期新购买的理财不赎回
+				errcm = t.redeemUserFinance(stub, accName, accEnt, rackid, preFid, currentFid, 0, invokeTime, transType, desc, true)
+				if errcm != nil {
+					return kdlogger.ErrorECM(errcm.Code, "financeRenewal: redeemUserFinance(%s,%s,%s) failed. error=(%s).", rackid, preFid, acc, errcm)
+				}
 				continue
 			}
 
@@ -2861,7 +4130,7 @@ func (t *KD) financeRenewalPreviousFinance(stub shim.ChaincodeStubInterface, cur
 			kdlogger.Info("financeRenewal: renewal for %s,%s", rackid, currentFid)
 
 			//续期，即内部给这些用户买新一期的理财
-			_, errcm := t.userBuyFinance(stub, acc, rackid, currentFid, "", "", "", amt, invokeTime, true, true)
+			_, errcm = t.userBuyFinance(stub, acc, rackid, currentFid, "", "", "", amt, invokeTime, true, true)
 			if errcm != nil {
 				return kdlogger.ErrorECM(errcm.Code, "financeRenewal: userBuyFinance(rfi=%s,%s,%s) failed. error=(%s).", rackid, preFid, acc, errcm)
 			}
@@ -2871,7 +4140,8 @@ func (t *KD) financeRenewalPreviousFinance(stub shim.ChaincodeStubInterface, cur
 	return nil
 }
 
-func (t *KD) payUserFinance(stub shim.ChaincodeStubInterface, accName, reacc, rackid string, invokeTime int64, transType, desc string, sameEntSaveTx bool) *ErrorCodeMsg {
+//amount为赎回的本金，小于等于0或者等于全部本金时全部赎回，大于全部本金时报错
+func (t *KD) payUserFinance(stub shim.ChaincodeStubInterface, accName, reacc, rackid string, amount, invokeTime int64, transType, desc string, sameEntSaveTx bool) *ErrorCodeMsg {
 	reaccEnt, errcm := t.getAccountRackInvestInfo(stub, reacc)
 	if errcm != nil {
 		return kdlogger.ErrorECM(errcm.Code, "payUserFinance: getAccountEntity(acc=%s) failed. error=(%s).", reacc, errcm)
@@ -2883,80 +4153,131 @@ func (t *KD) payUserFinance(stub shim.ChaincodeStubInterface, accName, reacc, ra
 		return nil
 	}
 
-	//获取用户投资的本金  最近一期投资的额度为本金，因为投资会自动续期
-	var investAmt int64 = 0
-	investAmt, errcm = t.getUserInvestAmount(stub, reacc, rackid, reaccEnt.LatestFid)
+	//最近一期投资的额度为本金，因为投资会自动续期
+	return t.redeemUserFinance(stub, accName, reaccEnt, rackid, reaccEnt.LatestFid, "", amount, invokeTime, transType, desc, sameEntSaveTx)
+}
+
+//赎回用户在某个货架上的理财。principalFid为计算本金的理财期号，skipFid为不赎回的理财期号（为空表示都赎回）
+//全部赎回时，提取本金和所有收益，并退出投资；部分赎回时，提取部分本金和所有未提取的收益，剩余的本金继续投资
+func (t *KD) redeemUserFinance(stub shim.ChaincodeStubInterface, accName string, reaccEnt *AccRackInvest, rackid, principalFid, skipFid string,
+	amount, invokeTime int64, transType, desc string, sameEntSaveTx bool) *ErrorCodeMsg {
+	var reacc = reaccEnt.EntID
+
+	//获取用户投资的本金
+	investAmt, errcm := t.getUserInvestAmount(stub, reacc, rackid, principalFid)
 	if errcm != nil {
-		return kdlogger.ErrorECM(errcm.Code, "payUserFinance: getUserInvestAmount failed. error=(%s).", errcm)
+		return kdlogger.ErrorECM(errcm.Code, "redeemUserFinance: getUserInvestAmount failed. error=(%s).", errcm)
+	}
+
+	kdlogger.Debug("redeemUserFinance: acc=%s investAmt=%d (%s,%s)", reacc, investAmt, rackid, principalFid)
+
+	if amount > investAmt {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "redeemUserFinance: redeem amount(%d) is more than invest amount(%d).", amount, investAmt)
 	}
 
-	kdlogger.Debug("payUserFinance: acc=%s investAmt=%d (%s,%s)", reacc, investAmt, rackid, reaccEnt.LatestFid)
+	var isPartial = amount > 0 && amount < investAmt
+	var redeemAmt = investAmt
+	if isPartial {
+		redeemAmt = amount
+	}
 
 	var profit int64 = 0
 	var delKeyList []string
 	var paidFidList []string
 
+	//map遍历顺序不固定，排序后处理
+	var rfkeyList []string
 	for rfkey, _ := range reaccEnt.RFInfoMap {
+		rfkeyList = append(rfkeyList, rfkey)
+	}
+	sort.Strings(rfkeyList)
+
+	for _, rfkey := range rfkeyList {
 		r, f := t.getRackFinanceFromMapKey(rfkey)
-		if r != rackid {
+		if r != rackid || f == skipFid {
 			continue
 		}
 
 		var rfiKey = t.getRackFinacInfoKey(rackid, f)
 		rfiB, err := stateCache.GetState_Ex(stub, rfiKey)
 		if err != nil {
-			return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "payUserFinance:  GetState(%s,%s) failed. error=(%s).", rackid, f, err)
+			return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "redeemUserFinance:  GetState(%s,%s) failed. error=(%s).", rackid, f, err)
 		}
 		//ent中记录了该条记录，肯定是有的，没有则报错
 		if rfiB == nil {
-			return kdlogger.ErrorECM(ERRCODE_COMMON_INNER_ERROR, "payUserFinance:  FinancialInfo(%s,%s) not exists.", rackid, f)
+			return kdlogger.ErrorECM(ERRCODE_COMMON_INNER_ERROR, "redeemUserFinance:  FinancialInfo(%s,%s) not exists.", rackid, f)
 		}
 		var rfi RackFinancInfo
 		err = json.Unmarshal(rfiB, &rfi)
 		if err != nil {
-			return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "payUserFinance:  Unmarshal(%s,%s) failed. error=(%s).", rackid, f, err)
+			return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "redeemUserFinance:  Unmarshal(%s,%s) failed. error=(%s).", rackid, f, err)
 		}
 
 		//如果已提取过，则不能再提取。这里不报错，不实际执行转账即可
-		if strSliceContains(rfi.PayFinanceUserList, reacc) {
-			kdlogger.Warn("payUserFinance: %s has paid already, do nothing.", reacc)
+		if StrSliceContains(rfi.PayFinanceUserList, reacc) {
+			kdlogger.Warn("redeemUserFinance: %s has paid already, do nothing.", reacc)
 			continue
 		}
 
+		profit += t.getUnpaidProfit(&rfi, reacc)
 		if rfi.UserProfitMap != nil {
-			profit += rfi.UserProfitMap[reacc]
+			if rfi.UserPaidProfitMap == nil {
+				rfi.UserPaidProfitMap = make(map[string]int64)
+			}
+			rfi.UserPaidProfitMap[reacc] = rfi.UserProfitMap[reacc]
+		}
+
+		//本金所在的那期理财，记录赎回金额
+		if f == principalFid && redeemAmt > 0 {
+			if rfi.UserRedeemMap == nil {
+				rfi.UserRedeemMap = make(map[string]int64)
+			}
+			rfi.UserRedeemMap[reacc] += redeemAmt
+
+			//部分赎回时，扣减投资额，续期金额不能超过剩余的投资额
+			if isPartial {
+				rfi.UserAmountMap[reacc] -= redeemAmt
+				rfi.AmountFinca -= redeemAmt
+				if rfi.UserRenewalMap != nil {
+					if renewal, ok := rfi.UserRenewalMap[reacc]; ok && renewal > rfi.UserAmountMap[reacc] {
+						rfi.UserRenewalMap[reacc] = rfi.UserAmountMap[reacc]
+					}
+				}
+			}
+		}
+
+		//全部赎回时才退出投资
+		if !isPartial {
+			rfi.PayFinanceUserList = append(rfi.PayFinanceUserList, reacc)
+			delKeyList = append(delKeyList, rfkey)
+			paidFidList = append(paidFidList, f)
 		}
 
-		rfi.PayFinanceUserList = append(rfi.PayFinanceUserList, reacc)
 		rfiB, err = json.Marshal(rfi)
 		if err != nil {
-			return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "payUserFinance:  Marshal(%s,%s) failed. error=(%s).", rackid, f, err)
+			return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "redeemUserFinance:  Marshal(%s,%s) failed. error=(%s).", rackid, f, err)
 		}
 
 		err = stateCache.PutState_Ex(stub, rfiKey, rfiB)
 		if err != nil {
-			return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "payUserFinance:  PutState_Ex(%s,%s) failed. error=(%s).", rackid, f, err)
+			return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "redeemUserFinance:  PutState_Ex(%s,%s) failed. error=(%s).", rackid, f, err)
 		}
 
-		kdlogger.Debug("payUserFinance: acc=%s rfi=%+v", reacc, rfi)
-
-		//delete(reaccEnt.RFInfoMap, rfkey)
-		delKeyList = append(delKeyList, rfkey)
-		paidFidList = append(paidFidList, f)
+		kdlogger.Debug("redeemUserFinance: acc=%s rfi=%+v", reacc, rfi)
 	}
 
-	var totalAmt = investAmt + profit
+	var totalAmt = redeemAmt + profit
 
-	kdlogger.Debug("payUserFinance: %s will pay %d to %s.", accName, totalAmt, reacc)
+	kdlogger.Debug("redeemUserFinance: %s will pay %d to %s.", accName, totalAmt, reacc)
 
 	_, errcm = t.transferCoin(stub, accName, reacc, transType, desc, totalAmt, invokeTime, sameEntSaveTx)
 	if errcm != nil {
-		return kdlogger.ErrorECM(errcm.Code, "payUserFinance:  transferCoin(%s) failed. error=(%s).", reacc, errcm)
+		return kdlogger.ErrorECM(errcm.Code, "redeemUserFinance:  transferCoin(%s) failed. error=(%s).", reacc, errcm)
 	}
 
 	//将赎回的理财期号写入已赎回列表
 	for _, fid := range paidFidList {
-		if !strSliceContains(reaccEnt.PaidFidList, fid) {
+		if !StrSliceContains(reaccEnt.PaidFidList, fid) {
 			reaccEnt.PaidFidList = append(reaccEnt.PaidFidList, fid)
 		}
 	}
@@ -2968,15 +4289,27 @@ func (t *KD) payUserFinance(stub shim.ChaincodeStubInterface, accName, reacc, ra
 
 	errcm = t.setAccountRackInvestInfo(stub, reaccEnt)
 	if errcm != nil {
-		return kdlogger.ErrorECM(errcm.Code, "payUserFinance:  setAccountRackInvestInfo(%s) failed. error=(%s).", reacc, errcm)
+		return kdlogger.ErrorECM(errcm.Code, "redeemUserFinance:  setAccountRackInvestInfo(%s) failed. error=(%s).", reacc, errcm)
 	}
 
-	kdlogger.Debug("payUserFinance: after reaccEnt = %+v", *reaccEnt)
-	kdlogger.Info("payUserFinance: %s pay %v,%v for %s,  rf=%+v", accName, investAmt, profit, reacc, reaccEnt)
+	kdlogger.Debug("redeemUserFinance: after reaccEnt = %+v", *reaccEnt)
+	kdlogger.Info("redeemUserFinance: %s pay %v,%v for %s, partial=%v, rf=%+v", accName, redeemAmt, profit, reacc, isPartial, reaccEnt)
 
 	return nil
 }
 
+//用户在某期理财中还未提取的收益
+func (t *KD) getUnpaidProfit(rfi *RackFinancInfo, accName string) int64 {
+	if rfi.UserProfitMap == nil {
+		return 0
+	}
+	var profit = rfi.UserProfitMap[accName]
+	if rfi.UserPaidProfitMap != nil {
+		profit -= rfi.UserPaidProfitMap[accName]
+	}
+	return profit
+}
+
 const rackFinanceKeyDelim = "_@!&!@_"
 
 func (t *KD) getMapKey4RackFinance(rackid, fid string) string {
@@ -3022,14 +4355,211 @@ func (t *KD) getUserFinanceProfit(stub shim.ChaincodeStubInterface, accName, rac
 			return profit, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getUserFinanceProfit:  Unmarshal(%s,%s) failed. error=(%s).", rackid, f, err)
 		}
 
-		if rfi.UserProfitMap != nil {
-			profit += rfi.UserProfitMap[accName]
-		}
+		profit += t.getUnpaidProfit(&rfi, accName)
 	}
 
 	return profit, nil
 }
 
+func (t *KD) getRackFinancInfo(stub shim.ChaincodeStubInterface, rackid, fid string) (*RackFinancInfo, *ErrorCodeMsg) {
+	rfiB, err := stateCache.GetState_Ex(stub, t.getRackFinacInfoKey(rackid, fid))
+	if err != nil {
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getRackFinancInfo: GetState(%s,%s) failed. error=(%s).", rackid, fid, err)
+	}
+	if rfiB == nil {
+		return nil, nil
+	}
+	var rfi RackFinancInfo
+	err = json.Unmarshal(rfiB, &rfi)
+	if err != nil {
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getRackFinancInfo: Unmarshal(%s,%s) failed. error=(%s).", rackid, fid, err)
+	}
+	return &rfi, nil
+}
+
+//投资者对账单。包括当前持有的投资，以及已赎回的理财期中该账户参与的投资
+func (t *KD) getInvestorStatement(stub shim.ChaincodeStubInterface, accName string) ([]byte, *ErrorCodeMsg) {
+	var stmt InvestorStatement
+	stmt.AccName = accName
+	stmt.RackPrincipals = make(map[string]int64)
+	stmt.Periods = []InvestorPeriodStmt{}
+
+	ari, errcm := t.getAccountRackInvestInfo(stub, accName)
+	if errcm != nil {
+		return nil, kdlogger.ErrorECM(errcm.Code, "getInvestorStatement: getAccountRackInvestInfo(%s) failed. error=(%s).", accName, errcm)
+	}
+
+	if ari != nil {
+		stmt.LatestFid = ari.LatestFid
+
+		//当前持有的投资
+		var rfkeySet = make(map[string]int)
+		for rfkey, _ := range ari.RFInfoMap {
+			rfkeySet[rfkey] = 0
+		}
+
+		//已赎回的理财期，从理财信息中找到该账户参与的货架
+		for _, fid := range ari.PaidFidList {
+			fiB, err := stateCache.GetState_Ex(stub, t.getFinacInfoKey(fid))
+			if err != nil {
+				return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getInvestorStatement: GetState(fi=%s) failed. error=(%s).", fid, err)
+			}
+			if fiB == nil {
+				continue
+			}
+			var fi FinancialInfo
+			err = json.Unmarshal(fiB, &fi)
+			if err != nil {
+				return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getInvestorStatement: Unmarshal(fi=%s) failed. error=(%s).", fid, err)
+			}
+			for _, rackid := range fi.RackList {
+				rfkeySet[t.getMapKey4RackFinance(rackid, fid)] = 0
+			}
+		}
+
+		var rfkeyList []string
+		for rfkey, _ := range rfkeySet {
+			rfkeyList = append(rfkeyList, rfkey)
+		}
+		sort.Strings(rfkeyList)
+
+		for _, rfkey := range rfkeyList {
+			rackid, fid := t.getRackFinanceFromMapKey(rfkey)
+			rfi, errcm := t.getRackFinancInfo(stub, rackid, fid)
+			if errcm != nil {
+				return nil, kdlogger.ErrorECM(errcm.Code, "getInvestorStatement: getRackFinancInfo failed. error=(%s).", errcm)
+			}
+			if rfi == nil {
+				continue
+			}
+			principal, ok := rfi.UserAmountMap[accName]
+			if !ok {
+				continue
+			}
+
+			var ps InvestorPeriodStmt
+			ps.Rackid = rackid
+			ps.FID = fid
+			ps.Stage = rfi.Stage
+			ps.Principal = principal
+			ps.Renewal = rfi.UserRenewalMap[accName]
+			ps.Redeemed = rfi.UserRedeemMap[accName]
+			ps.Exited = StrSliceContains(rfi.PayFinanceUserList, accName)
+			if ps.Exited {
+				//全部赎回时，本期的投资额没有扣减
+				ps.NewInvest = ps.Principal - ps.Renewal
+			} else {
+				ps.NewInvest = ps.Principal + ps.Redeemed - ps.Renewal
+			}
+			if rfi.UserProfitMap != nil {
+				ps.Profit = rfi.UserProfitMap[accName]
+			}
+			//全部赎回时收益已全部提取
+			if ps.Exited {
+				ps.PaidProfit = ps.Profit
+			} else {
+				ps.PaidProfit = ps.Profit - t.getUnpaidProfit(rfi, accName)
+			}
+
+			stmt.TotalProfit += ps.Profit
+			stmt.PaidProfit += ps.PaidProfit
+			stmt.TotalRedeemed += ps.Redeemed
+
+			//最新一期的投资额为当前的本金
+			if fid == ari.LatestFid && !ps.Exited {
+				stmt.RackPrincipals[rackid] = ps.Principal
+				stmt.Principal += ps.Principal
+			}
+
+			stmt.Periods = append(stmt.Periods, ps)
+		}
+	}
+
+	stmt.UnpaidProfit = stmt.TotalProfit - stmt.PaidProfit
+
+	stmtB, err := json.Marshal(stmt)
+	if err != nil {
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getInvestorStatement: Marshal failed. error=(%s).", err)
+	}
+	return stmtB, nil
+}
+
+//货架理财损益。fid为"*"时返回该货架参与过的所有理财期
+func (t *KD) getRackPnL(stub shim.ChaincodeStubInterface, rackid, fid string) ([]byte, *ErrorCodeMsg) {
+	var fidList []string
+	if fid == "*" {
+		riB, err := stateCache.GetState_Ex(stub, t.getRackInfoKey(rackid))
+		if err != nil {
+			return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getRackPnL: GetState(ri=%s) failed. error=(%s).", rackid, err)
+		}
+		if riB != nil {
+			var ri RackInfo
+			err = json.Unmarshal(riB, &ri)
+			if err != nil {
+				return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getRackPnL: Unmarshal(ri=%s) failed. error=(%s).", rackid, err)
+			}
+			fidList = ri.FinacList
+		}
+	} else {
+		fidList = append(fidList, fid)
+	}
+
+	var pnlList = []RackPnL{}
+	for _, f := range fidList {
+		rfi, errcm := t.getRackFinancInfo(stub, rackid, f)
+		if errcm != nil {
+			return nil, kdlogger.ErrorECM(errcm.Code, "getRackPnL: getRackFinancInfo failed. error=(%s).", errcm)
+		}
+		if rfi == nil {
+			if fid != "*" {
+				return nil, kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getRackPnL: rack finance(%s,%s) not exists.", rackid, f)
+			}
+			continue
+		}
+
+		var pnl RackPnL
+		pnl.Rackid = rackid
+		pnl.FID = f
+		pnl.Stage = rfi.Stage
+		pnl.Bonused = rfi.Stage >= FINANC_STAGE_BONUS_FINISH
+		if pnl.Bonused {
+			pnl.Sales = rfi.CEInfo.WareSales
+			pnl.Cost = rfi.CEInfo.WareSales * int64(100-rfi.RFCfg.ProfitsPercent) / 100
+			pnl.RackProfit, _, pnl.InvestorProfit = t.calcRackFinanceProfit(rfi)
+
+			var ear EarningAllocRate
+			ear.RolesRate = rfi.RolesAllocRate
+			if ear.SellerRate+ear.FielderRate+ear.DeliveryRate+ear.PlatformRate > 0 {
+				pnl.RolesShare = *t.getRackRolesAllocAmt(&ear, pnl.RackProfit)
+			}
+		}
+
+		for acc, amt := range rfi.UserAmountMap {
+			if !StrSliceContains(rfi.PayFinanceUserList, acc) && amt > 0 {
+				pnl.InvestorCount++
+			}
+		}
+		for _, p := range rfi.UserProfitMap {
+			pnl.PaidOutProfit += p
+		}
+
+		pnl.AmountFinca = rfi.AmountFinca
+		pnl.InvestCapacity = rfi.RFCfg.InvestCapacity
+		pnl.RestCapacity = rfi.RFCfg.InvestCapacity - rfi.AmountFinca
+		if pnl.RestCapacity < 0 {
+			pnl.RestCapacity = 0
+		}
+
+		pnlList = append(pnlList, pnl)
+	}
+
+	pnlB, err := json.Marshal(pnlList)
+	if err != nil {
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getRackPnL: Marshal failed. error=(%s).", err)
+	}
+	return pnlB, nil
+}
+
 func (t *KD) getRestFinanceCapacityForRack(stub shim.ChaincodeStubInterface, rackid, fid string) (int64, *ErrorCodeMsg) {
 	var rfc RackFinanceCfg
 	_, errcm := t.getRackFinancCfg(stub, rackid, &rfc)
@@ -3283,89 +4813,237 @@ func (t *KD) setAccountRackInvestInfo(stub shim.ChaincodeStubInterface, accRackI
 	return nil
 }
 
-/* ----------------------- 货架融资相关 end ----------------------- */
+func (t *KD) getAccFinancePrefKey(accName string) string {
+	return RACK_ACCFINACPREF_PREFIX + accName
+}
+
+func (t *KD) getAccFinancePref(stub shim.ChaincodeStubInterface, accName string) (*AccFinancePref, *ErrorCodeMsg) {
+	prefB, err := stateCache.GetState_Ex(stub, t.getAccFinancePrefKey(accName))
+	if err != nil {
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getAccFinancePref: GetState(%s) failed. error=(%s).", accName, err)
+	}
+	if prefB == nil {
+		return nil, nil
+	}
+
+	var afp AccFinancePref
+	err = json.Unmarshal(prefB, &afp)
+	if err != nil {
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getAccFinancePref: Unmarshal(%s) failed. error=(%s).", accName, err)
+	}
+
+	return &afp, nil
+}
+
+//获取账户在某个货架上的理财偏好，没有单独设置时使用默认偏好
+func (t *KD) getAccRackFinancePref(stub shim.ChaincodeStubInterface, accName, rackid string) (int, *ErrorCodeMsg) {
+	afp, errcm := t.getAccFinancePref(stub, accName)
+	if errcm != nil {
+		return FINANC_PREF_AUTO_RENEW, kdlogger.ErrorECM(errcm.Code, "getAccRackFinancePref: getAccFinancePref(%s) failed. error=(%s).", accName, errcm)
+	}
+	if afp == nil {
+		return FINANC_PREF_AUTO_RENEW, nil
+	}
+
+	if rackid != "*" && afp.RackPrefMap != nil {
+		if pref, ok := afp.RackPrefMap[rackid]; ok {
+			return pref, nil
+		}
+	}
 
-func (t *KD) setAccountPasswd(stub shim.ChaincodeStubInterface, accName, pwd string) *ErrorCodeMsg {
-	salt := md5.Sum([]byte(accName))
+	return afp.DefaultPref, nil
+}
+
+//rackid为*时设置默认偏好
+func (t *KD) setAccRackFinancePref(stub shim.ChaincodeStubInterface, accName, rackid string, pref int) *ErrorCodeMsg {
+	afp, errcm := t.getAccFinancePref(stub, accName)
+	if errcm != nil {
+		return kdlogger.ErrorECM(errcm.Code, "setAccRackFinancePref: getAccFinancePref(%s) failed. error=(%s).", accName, errcm)
+	}
+	if afp == nil {
+		afp = &AccFinancePref{EntID: accName, DefaultPref: FINANC_PREF_AUTO_RENEW}
+	}
+	if afp.RackPrefMap == nil {
+		afp.RackPrefMap = make(map[string]int)
+	}
+
+	if rackid == "*" {
+		afp.DefaultPref = pref
+	} else {
+		afp.RackPrefMap[rackid] = pref
+	}
 
-	hash, err := kdCrypto.GenCipher(pwd, salt[:])
+	prefB, err := json.Marshal(afp)
 	if err != nil {
-		return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setAccountPasswd: GenCipher failed.error=(%s), acc=%s", err, accName)
+		return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setAccRackFinancePref: Marshal(%s) failed. error=(%s).", accName, err)
 	}
 
-	err = stateCache.PutState_Ex(stub, t.getUserCipherKey(accName), hash)
+	err = stateCache.PutState_Ex(stub, t.getAccFinancePrefKey(accName), prefB)
 	if err != nil {
-		return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setAccountPasswd: putState_Ex failed.error=(%s), acc=%s", err, accName)
+		return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setAccRackFinancePref: PutState_Ex(%s) failed. error=(%s).", accName, err)
 	}
 
+	kdlogger.Info("setAccRackFinancePref: %s set pref %d for %s.", accName, pref, rackid)
+
 	return nil
 }
-func (t *KD) authAccountPasswd(stub shim.ChaincodeStubInterface, accName, pwd string) (bool, *ErrorCodeMsg) {
-	cipher, err := stateCache.GetState_Ex(stub, t.getUserCipherKey(accName))
+
+/* ----------------------- 货架融资相关 end ----------------------- */
+
+func (t *KD) getAccSpendKeyKey(accName string) string {
+	return ACCOUT_SPENDKEY_PREFIX + accName
+}
+
+func (t *KD) getAccSpendKey(stub shim.ChaincodeStubInterface, accName string) (*AccSpendKey, *ErrorCodeMsg) {
+	askB, err := stateCache.GetState_Ex(stub, t.getAccSpendKeyKey(accName))
 	if err != nil {
-		return false, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "AuthAccountPasswd: GetState failed.error=(%s), acc=%s", err, accName)
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getAccSpendKey: GetState failed.error=(%s), acc=%s", err, accName)
 	}
+	if askB == nil {
+		return nil, nil
+	}
+
+	var ask AccSpendKey
+	err = json.Unmarshal(askB, &ask)
+	if err != nil {
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getAccSpendKey: Unmarshal failed.error=(%s), acc=%s", err, accName)
+	}
+	return &ask, nil
+}
 
-	if cipher == nil || len(cipher) == 0 {
-		return false, kdlogger.ErrorECM(ERRCODE_COMMON_INNER_ERROR, "AuthAccountPasswd: Cipher is nil, acc=%s", accName)
+func (t *KD) setAccSpendKey(stub shim.ChaincodeStubInterface, ask *AccSpendKey) *ErrorCodeMsg {
+	askB, err := json.Marshal(ask)
+	if err != nil {
+		return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setAccSpendKey: Marshal failed.error=(%s), acc=%s", err, ask.EntID)
 	}
 
-	ok, err := kdCrypto.AuthPass(cipher, pwd)
+	err = stateCache.PutState_Ex(stub, t.getAccSpendKeyKey(ask.EntID), askB)
 	if err != nil {
-		return false, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "AuthAccountPasswd: AuthPass failed.error=(%s), acc=%s", err, accName)
+		return kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setAccSpendKey: PutState_Ex failed.error=(%s), acc=%s", err, ask.EntID)
 	}
+	return nil
+}
 
-	if ok {
-		return true, nil
+//支付签名的内容：把函数名、账户、转入账户、金额、nonce用","拼接为字符串，然后计算其Sha256。
+//注册支付公钥时，新私钥签名的消息没有转入账户和金额；旧私钥签名的消息中转入账户为新公钥的Hash160，金额为空
+func (t *KD) getSpendSignMsg(function, accName, toAcc, amount string, nonce int64) []byte {
+	var msgStr = function + "," + accName
+	if len(toAcc) > 0 || len(amount) > 0 {
+		msgStr += "," + toAcc + "," + amount
 	}
+	msgStr += "," + strconv.FormatInt(nonce, 10)
+
+	kdlogger.Debug("getSpendSignMsg: msgStr=%s", msgStr)
 
-	return false, nil
+	msg, _ := kdCrypto.Sha256([]byte(msgStr))
+	return msg
 }
 
-func (t *KD) isSetAccountPasswd(stub shim.ChaincodeStubInterface, accName string) (bool, *ErrorCodeMsg) {
+//从签名中恢复公钥，返回公钥的Hash160（base64编码）
+func (t *KD) recoverSpendKeyHash(signBase64 string, signMsg []byte) (string, *ErrorCodeMsg) {
+	sign, err := base64.StdEncoding.DecodeString(signBase64)
+	if err != nil {
+		return "", kdlogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "recoverSpendKeyHash: convert sign(%s) failed. error=(%s)", signBase64, err)
+	}
+
+	if code := kdSecp256k1.VerifySignatureValidity(sign); code != 1 {
+		return "", kdlogger.ErrorECM(ERRCODE_COMMON_IDENTITY_VERIFY_FAILED, "recoverSpendKeyHash: sign invalid, code=%v.", code)
+	}
 
-	cipher, err := stateCache.GetState_Ex(stub, t.getUserCipherKey(accName))
+	pubKey, err := kdSecp256k1.RecoverPubkey(signMsg, sign)
 	if err != nil {
-		return false, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "isSetAccountPasswd: GetState failed.error=(%s), acc=%s", err, accName)
+		return "", kdlogger.ErrorECM(ERRCODE_COMMON_IDENTITY_VERIFY_FAILED, "recoverSpendKeyHash: RecoverPubkey failed,error=(%s)", err)
 	}
 
-	if cipher == nil || len(cipher) == 0 {
-		return false, nil
+	hash, err := llcrypto.RipemdHash160(pubKey)
+	if err != nil {
+		return "", kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "recoverSpendKeyHash: Hash160 error, error=(%s).", err)
 	}
 
-	return true, nil
+	return base64.StdEncoding.EncodeToString(hash), nil
 }
 
-func (t *KD) changeAccountPasswd(stub shim.ChaincodeStubInterface, accName, oldpwd, newpwd string) *ErrorCodeMsg {
-	ok, errcm := t.authAccountPasswd(stub, accName, oldpwd)
+//注册支付公钥。newSign为新私钥对注册消息（见getSpendSignMsg，函数名为setSpendKey）的签名。
+//byAdmin为false时只能更换已注册的支付公钥，oldSign为旧私钥对包含新公钥Hash160的消息的签名，这样旧签名不能被用来注册别的公钥；
+//为true时由管理员注册或重置，不需要旧签名
+func (t *KD) registerAccSpendKey(stub shim.ChaincodeStubInterface, accName, newSign, oldSign string, byAdmin bool, invokeTime int64) *ErrorCodeMsg {
+	ask, errcm := t.getAccSpendKey(stub, accName)
+	if errcm != nil {
+		return kdlogger.ErrorECM(errcm.Code, "registerAccSpendKey: getAccSpendKey failed. error=(%s), acc=%s", errcm, accName)
+	}
+	if ask == nil {
+		ask = &AccSpendKey{EntID: accName}
+	}
+
+	newHash, errcm := t.recoverSpendKeyHash(newSign, t.getSpendSignMsg("setSpendKey", accName, "", "", ask.Nonce))
 	if errcm != nil {
-		return kdlogger.ErrorECM(errcm.Code, "changeAccountPasswd: authAccountPasswd failed.error=(%s), acc=%s", errcm, accName)
+		return kdlogger.ErrorECM(errcm.Code, "registerAccSpendKey: recover new key failed. error=(%s)", errcm)
 	}
-	if !ok {
-		return kdlogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "changeAccountPasswd: authAccountPasswd not pass.")
+
+	if !byAdmin {
+		if len(ask.PubKeyHash) == 0 {
+			return kdlogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "registerAccSpendKey: spend key of %s not registered, need admin to register.", accName)
+		}
+		if len(oldSign) == 0 {
+			return kdlogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "registerAccSpendKey: spend key is set, need old sign, acc=%s", accName)
+		}
+		oldHash, errcm := t.recoverSpendKeyHash(oldSign, t.getSpendSignMsg("setSpendKey", accName, newHash, "", ask.Nonce))
+		if errcm != nil {
+			return kdlogger.ErrorECM(errcm.Code, "registerAccSpendKey: recover old key failed. error=(%s)", errcm)
+		}
+		if oldHash != ask.PubKeyHash {
+			return kdlogger.ErrorECM(ERRCODE_TRANS_PASSWD_INVALID, "registerAccSpendKey: old sign invalid, acc=%s", accName)
+		}
 	}
 
-	errcm = t.setAccountPasswd(stub, accName, newpwd)
+	ask.PubKeyHash = newHash
+	ask.Nonce++
+	ask.UpdateTime = invokeTime
+
+	errcm = t.setAccSpendKey(stub, ask)
 	if errcm != nil {
-		return kdlogger.ErrorECM(errcm.Code, "changeAccountPasswd: setAccountPasswd failed.error=(%s), acc=%s", errcm, accName)
+		return kdlogger.ErrorECM(errcm.Code, "registerAccSpendKey: setAccSpendKey failed. error=(%s)", errcm)
 	}
 
+	kdlogger.Info("registerAccSpendKey: %s register spend key %s.", accName, newHash)
+
 	return nil
 }
 
-func (t *KD) decodeAccountPasswd(pwdBase64 string) (string, *ErrorCodeMsg) {
+//删除已保存的交易密码。maxCount<=0时全部删除，返回删除的个数
+func (t *KD) retireAccountPasswd(stub shim.ChaincodeStubInterface, maxCount int64) (int64, *ErrorCodeMsg) {
+	//前缀最后一个字符加1作为结束key，即可遍历该前缀的所有key
+	var begKey = ACCOUT_CIPHER_PREFIX
+	var endKey = begKey[:len(begKey)-1] + string(begKey[len(begKey)-1]+1)
 
-	pwdEncrypt, err := base64.StdEncoding.DecodeString(pwdBase64)
+	keysIter, err := stub.GetStateByRange(begKey, endKey)
 	if err != nil {
-		return "", kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "decodeAccountPasswd: DecodeString failed. error=(%s)", err)
+		return 0, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "retireAccountPasswd: GetStateByRange failed. error=(%s)", err)
 	}
+	defer keysIter.Close()
 
-	pwdB, err := kdCrypto.AESDecrypt(256, []byte(PWD_ENCRYPT_KEY), []byte(PWD_ENCRYPT_IV), pwdEncrypt)
-	if err != nil {
-		return "", kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "decodeAccountPasswd: AESDecrypt failed. error=(%s)", err)
+	var delKeys []string
+	for keysIter.HasNext() {
+		if maxCount > 0 && int64(len(delKeys)) >= maxCount {
+			break
+		}
+		kv, iterErr := keysIter.Next()
+		if iterErr != nil {
+			return 0, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "retireAccountPasswd: getNext failed. error=(%s)", iterErr)
+		}
+		delKeys = append(delKeys, kv.GetKey())
+	}
+
+	for _, key := range delKeys {
+		err = stub.DelState(key)
+		if err != nil {
+			return 0, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "retireAccountPasswd: DelState(%s) failed. error=(%s)", key, err)
+		}
 	}
 
-	return string(pwdB), nil
+	kdlogger.Info("retireAccountPasswd: delete %d ciphers.", len(delKeys))
+
+	return int64(len(delKeys)), nil
 }
 
 func (t *KD) dateConvertWhenLoad(stub shim.ChaincodeStubInterface, srcCcid, key string, valueB []byte) (string, []byte, *ErrorCodeMsg) {
@@ -3468,13 +5146,23 @@ func (t *KD) isAdmin(stub shim.ChaincodeStubInterface, accName string) bool {
 }
 
 func (t *KD) transferCoin(stub shim.ChaincodeStubInterface, from, to, transType, description string, amount, transeTime int64, sameEntSaveTrans bool) ([]byte, *ErrorCodeMsg) {
+	return t.addTransferInfo(stub, from, to, transType, description, amount, transeTime, false)
+}
+
+//和transferCoin一样，但付款账户的可用余额不足时，账户系统只转出可用余额，不会失败
+func (t *KD) transferCoinUpToBalance(stub shim.ChaincodeStubInterface, from, to, transType, description string, amount, transeTime int64) ([]byte, *ErrorCodeMsg) {
+	return t.addTransferInfo(stub, from, to, transType, description, amount, transeTime, true)
+}
+
+//kd不直接修改账户余额，转账记录在transInfoCache中，随返回值交给账户系统执行
+func (t *KD) addTransferInfo(stub shim.ChaincodeStubInterface, from, to, transType, description string, amount, transeTime int64, upToBalance bool) ([]byte, *ErrorCodeMsg) {
 
 	appidB, err := stateCache.GetState_Ex(stub, APPID_KEY)
 	if err != nil {
-		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "transferCoin: get appid failed, error=(%s).", err)
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "addTransferInfo: get appid failed, error=(%s).", err)
 	}
 	if appidB == nil {
-		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_INNER_ERROR, "transferCoin: appid not register.")
+		return nil, kdlogger.ErrorECM(ERRCODE_COMMON_INNER_ERROR, "addTransferInfo: appid not register.")
 	}
 	var appid = string(appidB)
 
@@ -3487,6 +5175,7 @@ func (t *KD) transferCoin(stub shim.ChaincodeStubInterface, from, to, transType,
 	txInfo.TransType = transType
 	txInfo.Amount = amount
 	txInfo.Time = transeTime
+	txInfo.UpToBalance = upToBalance
 
 	transInfoCache.Add(stub, &txInfo)
 
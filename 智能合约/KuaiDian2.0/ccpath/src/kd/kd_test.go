@@ -0,0 +1,493 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	. "loulan/ccutil"
+	. "loulan/errcode"
+	"loulan/llcrypto"
+)
+
+func TestRackFinance(t *testing.T) {
+	var c = newTestChain(t, map[string]int64{"alice": 1000, "bob": 1100, "carol": 500, "kdplat": 0})
+
+	//默认融资配置：利润率20%，90%的利润分给投资人，融资能力2000
+	var wantBatch = func(executed bool, succ, fail int) func(t *testing.T, c *testChain, payload []byte) {
+		return func(t *testing.T, c *testChain, payload []byte) {
+			var br BatchResult
+			if err := json.Unmarshal(payload, &br); err != nil {
+				t.Fatalf("Unmarshal BatchResult failed, err=%s", err)
+			}
+			if br.Executed != executed || br.Succeed != succ || br.Failed != fail {
+				t.Errorf("batch result: want (%v,%d,%d), got (%v,%d,%d)", executed, succ, fail, br.Executed, br.Succeed, br.Failed)
+			}
+		}
+	}
+
+	c.run([]testStep{
+		{
+			name: "alice buy", acc: "alice", function: "buyFinance",
+			args:  []string{"r1", "f1", "kdplat", "1000", "buy", "", "0"},
+			check: wantBalances(map[string]int64{"alice": 0, "kdplat": 1000}),
+		},
+		{
+			name: "bob buy", acc: "bob", function: "buyFinance",
+			args:  []string{"r1", "f1", "kdplat", "800", "buy", "", "0"},
+			check: wantBalances(map[string]int64{"bob": 300, "kdplat": 1800}),
+		},
+		{
+			name: "over capacity", acc: "bob", function: "buyFinance",
+			args:     []string{"r1", "f1", "kdplat", "300", "buy", "", "0"},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+			check:    wantBalances(map[string]int64{"bob": 300, "kdplat": 1800}),
+		},
+		{
+			name: "issue finish f1", acc: "kdplat", function: "financeIssueFinish",
+			args: []string{"f1"},
+		},
+		{
+			name: "issue finish f1 again", acc: "kdplat", function: "financeIssueFinish",
+			args:     []string{"f1"},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "buy after issue finish", acc: "alice", function: "buyFinance",
+			args:     []string{"r1", "f1", "kdplat", "100", "buy", "", "0"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			//利润 100000*20%*92%*90%/100 = 165，按投资额占融资能力的比例分配
+			name: "bonus f1", acc: "kdplat", function: "financeBouns",
+			args: []string{"f1", `[{"rid":"r1","sale":100000}]`},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantBatch(true, 1, 0)(t, c, payload)
+				rfi := c.rackFinance("r1", "f1")
+				if rfi.UserProfitMap["alice"] != 82 || rfi.UserProfitMap["bob"] != 66 {
+					t.Errorf("profit: want alice 82 bob 66, got %v", rfi.UserProfitMap)
+				}
+			},
+		},
+		{
+			//有记录校验失败时整批不执行，返回错误
+			name: "bonus f1 again", acc: "kdplat", function: "financeBouns",
+			args:     []string{"f1", "r1:100000"},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+			check:    wantBalances(map[string]int64{"alice": 0, "bob": 300, "kdplat": 1800}),
+		},
+		{
+			name: "alice auto redeem", acc: "alice", function: "setFinancePref",
+			args: []string{"r1", "1"},
+		},
+		{
+			name: "carol buy f2", acc: "carol", function: "buyFinance",
+			args:  []string{"r1", "f2", "kdplat", "200", "buy", "", "0"},
+			check: wantBalances(map[string]int64{"carol": 300, "kdplat": 2000}),
+		},
+		{
+			//上期的投资会自动续期，计入融资额度
+			name: "carol over capacity with history", acc: "carol", function: "buyFinance",
+			args:     []string{"r1", "f2", "kdplat", "1", "buy", "", "0"},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "issue finish f2", acc: "kdplat", function: "financeIssueFinish",
+			args: []string{"f2", "redeem", "auto redeem"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				//alice自动赎回本金和收益，bob自动续期
+				wantBalances(map[string]int64{"alice": 1082, "kdplat": 918})(t, c, payload)
+				if rfi := c.rackFinance("r1", "f1"); !StrSliceContains(rfi.PayFinanceUserList, "alice") {
+					t.Errorf("alice not in PayFinanceUserList of f1: %v", rfi.PayFinanceUserList)
+				}
+				rfi := c.rackFinance("r1", "f2")
+				if _, ok := rfi.UserAmountMap["alice"]; ok {
+					t.Errorf("alice should not renew: %v", rfi.UserAmountMap)
+				}
+				if rfi.UserAmountMap["bob"] != 800 || rfi.UserRenewalMap["bob"] != 800 {
+					t.Errorf("bob renewal: want 800, got %d,%d", rfi.UserAmountMap["bob"], rfi.UserRenewalMap["bob"])
+				}
+			},
+		},
+		{
+			name: "bob partial redeem", acc: "kdplat", function: "payFinance",
+			args: []string{"r1", "bob", "redeem", "", "0", "300"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				//赎回300本金和f1中未提取的收益66
+				wantBalances(map[string]int64{"bob": 666, "kdplat": 552})(t, c, payload)
+				rfi := c.rackFinance("r1", "f2")
+				if rfi.UserAmountMap["bob"] != 500 || rfi.UserRenewalMap["bob"] != 500 || rfi.UserRedeemMap["bob"] != 300 {
+					t.Errorf("bob after partial redeem: got amount %d renewal %d redeem %d",
+						rfi.UserAmountMap["bob"], rfi.UserRenewalMap["bob"], rfi.UserRedeemMap["bob"])
+				}
+			},
+		},
+		{
+			name: "bob redeem more than principal", acc: "kdplat", function: "payFinance",
+			args:     []string{"r1", "bob", "redeem", "", "0", "501"},
+			wantCode: ERRCODE_COMMON_PARAM_INVALID,
+			check:    wantBalances(map[string]int64{"bob": 666, "kdplat": 552}),
+		},
+		{
+			name: "bob full redeem", acc: "kdplat", function: "payFinance",
+			args:  []string{"r1", "bob", "redeem", "", "0", "0"},
+			check: wantBalances(map[string]int64{"bob": 1166, "kdplat": 52}),
+		},
+		{
+			name: "bob redeem nothing", acc: "kdplat", function: "payFinance",
+			args:  []string{"r1", "bob", "redeem", "", "0", "0"},
+			check: wantBalances(map[string]int64{"bob": 1166, "kdplat": 52}),
+		},
+	})
+
+	//批量校验失败时，错误信息中带有每条失败记录的结果
+	resp := c.invoke("kdplat", "financeBouns", "f1", "r1:100000")
+	if !strings.Contains(resp.Message, "idx=0 rid=r1 code=90005") {
+		t.Fatalf("batch report missing in error: %s", resp.Message)
+	}
+}
+
+func TestInvestorStatementAndRackPnL(t *testing.T) {
+	var c = newTestChain(t, map[string]int64{"alice": 1000, "bob": 1100, "kdplat": 0})
+
+	var wantPnL = func(want RackPnL) func(t *testing.T, c *testChain, payload []byte) {
+		return func(t *testing.T, c *testChain, payload []byte) {
+			var got []RackPnL
+			if err := json.Unmarshal(payload, &got); err != nil {
+				t.Fatalf("Unmarshal RackPnL failed, err=%s", err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("pnl: want 1 period, got %+v", got)
+			}
+			got[0].RolesShare = want.RolesShare
+			if got[0] != want {
+				t.Errorf("pnl: want %+v, got %+v", want, got[0])
+			}
+		}
+	}
+	var wantStmt = func(principal, total, paid int64, periods int) func(t *testing.T, c *testChain, payload []byte) {
+		return func(t *testing.T, c *testChain, payload []byte) {
+			var got InvestorStatement
+			if err := json.Unmarshal(payload, &got); err != nil {
+				t.Fatalf("Unmarshal InvestorStatement failed, err=%s", err)
+			}
+			if got.Principal != principal || got.TotalProfit != total || got.PaidProfit != paid ||
+				got.UnpaidProfit != total-paid || len(got.Periods) != periods {
+				t.Errorf("statement: want (%d,%d,%d,%d), got %+v", principal, total, paid, periods, got)
+			}
+		}
+	}
+
+	c.run([]testStep{
+		{
+			name: "alice buy", acc: "alice", function: "buyFinance",
+			args: []string{"r1", "f1", "kdplat", "1000", "buy", "", "0"},
+		},
+		{
+			name: "bob buy", acc: "bob", function: "buyFinance",
+			args: []string{"r1", "f1", "kdplat", "800", "buy", "", "0"},
+		},
+		{
+			//分红前销售额没有上链，不估算利润
+			name: "pnl before bonus", acc: "kdplat", function: "getRackPnL",
+			args: []string{"r1", "f1"},
+			check: wantPnL(RackPnL{Rackid: "r1", FID: "f1", Stage: FINANC_STAGE_ISSUE_BEGING, AmountFinca: 1800,
+				InvestorCount: 2, InvestCapacity: 2000, RestCapacity: 200}),
+		},
+		{
+			name: "alice statement before bonus", acc: "alice", function: "getInvestorStatement",
+			args:  []string{""},
+			check: wantStmt(1000, 0, 0, 1),
+		},
+		{
+			name: "issue finish f1", acc: "kdplat", function: "financeIssueFinish",
+			args: []string{"f1"},
+		},
+		{
+			name: "bonus f1", acc: "kdplat", function: "financeBouns",
+			args: []string{"f1", "r1:100000"},
+		},
+		{
+			//利润 100000*20%*92%*90%/100 = 165，alice 82 bob 66
+			name: "pnl after bonus", acc: "kdplat", function: "getRackPnL",
+			args: []string{"r1", "*"},
+			check: wantPnL(RackPnL{Rackid: "r1", FID: "f1", Stage: FINANC_STAGE_BONUS_FINISH, Bonused: true,
+				Sales: 100000, Cost: 80000, RackProfit: 20000, InvestorProfit: 165, PaidOutProfit: 148,
+				AmountFinca: 1800, InvestorCount: 2, InvestCapacity: 2000, RestCapacity: 200}),
+		},
+		{
+			name: "kdplat query bob statement", acc: "kdplat", function: "getInvestorStatement",
+			args:  []string{"bob"},
+			check: wantStmt(800, 66, 0, 1),
+		},
+		{
+			name: "alice auto redeem", acc: "alice", function: "setFinancePref",
+			args: []string{"r1", "1"},
+		},
+		{
+			name: "issue finish f2", acc: "kdplat", function: "financeIssueFinish",
+			args:  []string{"f2", "redeem", "auto redeem"},
+			check: wantBalances(map[string]int64{"alice": 1082}),
+		},
+		{
+			//alice已全部赎回，本金为0，收益已提取
+			name: "alice statement after redeem", acc: "alice", function: "getInvestorStatement",
+			args:  []string{""},
+			check: wantStmt(0, 82, 82, 1),
+		},
+		{
+			//bob续期到f2，本金计入最新一期
+			name: "bob statement after renewal", acc: "bob", function: "getInvestorStatement",
+			args:  []string{""},
+			check: wantStmt(800, 66, 0, 2),
+		},
+	})
+}
+
+func TestEncourageScoreForSales(t *testing.T) {
+	var c = newTestChain(t, map[string]int64{"kdplat": 10000, "seller": 0, "fielder": 0, "delivery": 0})
+
+	var para = `[{"rid":"r1","sale":20000,"slracc":"seller","fldacc":"fielder","dvyacc":"delivery","pfmacc":"kdplat"},` +
+		`{"rid":"r2","sale":99,"slracc":"seller","fldacc":"fielder","dvyacc":"delivery","pfmacc":"kdplat"}]`
+
+	c.run([]testStep{
+		{
+			//r2的销售额不足1元，不奖励积分，结果中报告为跳过
+			name: "sales less than 1 yuan skipped", acc: "kdplat", function: "encourageScoreForSales",
+			args: []string{para, "encourage", "", "0"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				var br BatchResult
+				if err := json.Unmarshal(payload, &br); err != nil {
+					t.Fatalf("Unmarshal BatchResult failed, err=%s", err)
+				}
+				if !br.Executed || br.Succeed != 1 || br.Failed != 0 || br.Skipped != 1 {
+					t.Errorf("batch result: want (true,1,0,1), got (%v,%d,%d,%d)", br.Executed, br.Succeed, br.Failed, br.Skipped)
+				}
+				if br.Results[0].Status != BATCH_ENTRY_OK || br.Results[1].Status != BATCH_ENTRY_SKIPPED {
+					t.Errorf("entry status: want (ok,skipped), got (%s,%s)", br.Results[0].Status, br.Results[1].Status)
+				}
+				if c.ledger["seller"] <= 0 {
+					t.Errorf("seller of r1 got no score")
+				}
+			},
+		},
+	})
+}
+
+func TestExpireScores(t *testing.T) {
+	var c = newTestChain(t, map[string]int64{"kdplat": 10000, "seller": 0, "fielder": 0, "delivery": 0})
+
+	var para = `[{"rid":"r1","sale":20000,"slracc":"seller","fldacc":"fielder","dvyacc":"delivery","pfmacc":"kdplat"}]`
+	var grants = make(map[string]int64)
+
+	var wantExpire = func(want []ScoreExpireResult) func(t *testing.T, c *testChain, payload []byte) {
+		return func(t *testing.T, c *testChain, payload []byte) {
+			var got []ScoreExpireResult
+			if err := json.Unmarshal(payload, &got); err != nil {
+				t.Fatalf("Unmarshal ScoreExpireResult failed, err=%s", err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("expire result: want %+v, got %+v", want, got)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("expire result %d: want %+v, got %+v", i, want[i], got[i])
+				}
+			}
+		}
+	}
+
+	c.run([]testStep{
+		{
+			//活动奖励销售额(元)的100%，积分1小时后过期
+			name: "set campaign", acc: "kdplat", function: "setScoreCampaign",
+			args: []string{"c1", "0", "9999999999999", "99999999:100", "*", "0", "0", "0", "3600000", "0"},
+		},
+		{
+			name: "encourage", acc: "kdplat", function: "encourageScoreForSales",
+			args: []string{para, "encourage", "", "0"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				for _, acc := range []string{"fielder", "delivery"} {
+					resp := c.invoke("kdplat", "getAccScoreGrants", "0", "0", acc)
+					var qsg QueryScoreGrant
+					if err := json.Unmarshal(c.mustOK(resp, "getAccScoreGrants"), &qsg); err != nil {
+						t.Fatalf("Unmarshal QueryScoreGrant failed, err=%s", err)
+					}
+					if qsg.ValidAmount <= 1 || qsg.ValidAmount != c.ledger[acc] {
+						t.Fatalf("grant of %s: valid %d, balance %d", acc, qsg.ValidAmount, c.ledger[acc])
+					}
+					grants[acc] = qsg.ValidAmount
+				}
+			},
+		},
+		{
+			name: "nothing expired yet", acc: "kdplat", function: "expireScores",
+			args: []string{"fielder;delivery", "expire", ""},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantExpire([]ScoreExpireResult{{AccName: "fielder"}, {AccName: "delivery"}})(t, c, payload)
+				wantBalances(grants)(t, c, payload)
+
+				//delivery在账户系统中用掉了部分积分，只剩1
+				c.ledger["delivery"] = 1
+				c.ledger["shop"] = grants["delivery"] - 1
+			},
+		},
+		{
+			//扣回的积分由发放记录算出，账户系统按余额扣回
+			name: "expire", acc: "kdplat", function: "expireScores",
+			args:    []string{"fielder;delivery", "expire", ""},
+			advance: 2 * time.Hour,
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantExpire([]ScoreExpireResult{
+					{AccName: "fielder", Expired: grants["fielder"], Deducted: grants["fielder"]},
+					{AccName: "delivery", Expired: grants["delivery"], Deducted: grants["delivery"]},
+				})(t, c, payload)
+				wantBalances(map[string]int64{"fielder": 0, "delivery": 0, "shop": grants["delivery"] - 1})(t, c, payload)
+			},
+		},
+		{
+			name: "expire again", acc: "kdplat", function: "expireScores",
+			args: []string{"fielder;delivery", "expire", ""},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantExpire([]ScoreExpireResult{{AccName: "fielder"}, {AccName: "delivery"}})(t, c, payload)
+				wantBalances(map[string]int64{"fielder": 0, "delivery": 0})(t, c, payload)
+			},
+		},
+		{
+			//不再接受调用方传入的余额
+			name: "caller supplied balance", acc: "kdplat", function: "expireScores",
+			args:     []string{"fielder,100", "expire", ""},
+			wantCode: ERRCODE_COMMON_PARAM_INVALID,
+		},
+	})
+}
+
+//账户上限扣掉的积分不占用货架上限
+func TestScoreCampaignCaps(t *testing.T) {
+	var c = newTestChain(t, map[string]int64{"kdplat": 10000, "s1": 0, "f1": 0, "d1": 0, "s2": 0, "f2": 0, "d2": 0})
+
+	var para = func(s, f, d string) string {
+		return `[{"rid":"r1","sale":10000,"slracc":"` + s + `","fldacc":"` + f + `","dvyacc":"` + d + `","pfmacc":"kdplat"}]`
+	}
+	var rackCapRest = func() int64 {
+		var sp ScorePreview
+		if err := json.Unmarshal(c.mustOK(c.invoke("kdplat", "previewEncourageScore", "r1", "0", fmt.Sprintf("%d", c.stub.now.UnixNano()/1e6)), "previewEncourageScore"), &sp); err != nil {
+			t.Fatalf("Unmarshal ScorePreview failed, err=%s", err)
+		}
+		return sp.RackCapRest
+	}
+
+	c.run([]testStep{
+		{
+			//奖励销售额(元)的100%，每个货架上限100，每个账户上限10
+			name: "set campaign", acc: "kdplat", function: "setScoreCampaign",
+			args: []string{"c1", "0", "9999999999999", "99999999:100", "*", "0", "100", "10", "0", "0"},
+		},
+		{
+			name: "encourage", acc: "kdplat", function: "encourageScoreForSales",
+			args: []string{para("s1", "f1", "d1"), "encourage", "", "0"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				for _, acc := range []string{"f1", "d1"} {
+					if c.ledger[acc] <= 0 || c.ledger[acc] > 10 {
+						t.Errorf("score of %s: want (0,10], got %d", acc, c.ledger[acc])
+					}
+				}
+				//四个角色最多各得10，货架只用掉实际奖励的积分
+				if rest := rackCapRest(); rest < 60 {
+					t.Errorf("rack cap rest: want >= 60, got %d", rest)
+				}
+			},
+		},
+		{
+			name: "encourage other accounts", acc: "kdplat", function: "encourageScoreForSales",
+			args: []string{para("s2", "f2", "d2"), "encourage", "", "0"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantBalances(map[string]int64{"f2": c.ledger["f1"], "d2": c.ledger["d1"]})(t, c, payload)
+			},
+		},
+	})
+}
+
+func TestSpendKey(t *testing.T) {
+	var c = newTestChain(t, map[string]int64{"alice": 1000, "bob": 0})
+
+	var secp = llcrypto.NewSecp256k1()
+	_, key1, _ := secp.GenerateKeyPair()
+	_, key2, _ := secp.GenerateKeyPair()
+
+	var sign = func(seckey []byte, function, toAcc, amount string, nonce int64) string {
+		sig, err := secp.Sign(c.kd.getSpendSignMsg(function, "alice", toAcc, amount, nonce), seckey)
+		if err != nil {
+			t.Fatalf("Sign failed, err=%s", err)
+		}
+		return base64.StdEncoding.EncodeToString(sig)
+	}
+	//旧私钥更换支付公钥时签名的消息包含新公钥的Hash160
+	var keyHash = func(seckey []byte) string {
+		var msg = c.kd.getSpendSignMsg("setSpendKey", "alice", "", "", 0)
+		hash, errcm := c.kd.recoverSpendKeyHash(sign(seckey, "setSpendKey", "", "", 0), msg)
+		if errcm != nil {
+			t.Fatalf("recoverSpendKeyHash failed, err=%s", errcm)
+		}
+		return hash
+	}
+	_, key3, _ := secp.GenerateKeyPair()
+
+	c.run([]testStep{
+		{
+			//没有注册支付公钥时，不能用转账的签名直接注册
+			name: "transfer without spend key", acc: "alice", function: "transefer2",
+			args:     []string{"bob", "100", sign(key1, "transefer2", "bob", "100", 0), "kdapp", "", "", "0"},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+			check:    wantBalances(map[string]int64{"alice": 1000, "bob": 0}),
+		},
+		{
+			name: "self register without spend key", acc: "alice", function: "setSpendKey",
+			args:     []string{sign(key1, "setSpendKey", "", "", 0), sign(key1, "setSpendKey", "", "", 0)},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "admin register", acc: "kdplat", function: "resetSpendKey",
+			args: []string{"alice", sign(key1, "setSpendKey", "", "", 0)},
+		},
+		{
+			name: "transfer with wrong key", acc: "alice", function: "transefer2",
+			args:     []string{"bob", "100", sign(key2, "transefer2", "bob", "100", 1), "kdapp", "", "", "0"},
+			wantCode: ERRCODE_TRANS_PASSWD_INVALID,
+		},
+		{
+			name: "transfer", acc: "alice", function: "transefer2",
+			args:  []string{"bob", "100", sign(key1, "transefer2", "bob", "100", 1), "kdapp", "", "", "0"},
+			check: wantBalances(map[string]int64{"alice": 900, "bob": 100}),
+		},
+		{
+			//签名不能重复使用
+			name: "replay", acc: "alice", function: "transefer2",
+			args:     []string{"bob", "100", sign(key1, "transefer2", "bob", "100", 1), "kdapp", "", "", "0"},
+			wantCode: ERRCODE_TRANS_PASSWD_INVALID,
+		},
+		{
+			name: "old sign without new key", acc: "alice", function: "setSpendKey",
+			args:     []string{sign(key2, "setSpendKey", "", "", 2), sign(key1, "setSpendKey", "", "", 2)},
+			wantCode: ERRCODE_TRANS_PASSWD_INVALID,
+		},
+		{
+			//旧私钥的签名是为key3做的，不能拿来注册key2
+			name: "old sign for other new key", acc: "alice", function: "setSpendKey",
+			args:     []string{sign(key2, "setSpendKey", "", "", 2), sign(key1, "setSpendKey", keyHash(key3), "", 2)},
+			wantCode: ERRCODE_TRANS_PASSWD_INVALID,
+		},
+		{
+			name: "change key", acc: "alice", function: "setSpendKey",
+			args: []string{sign(key2, "setSpendKey", "", "", 2), sign(key1, "setSpendKey", keyHash(key2), "", 2)},
+		},
+		{
+			name: "transfer with new key", acc: "alice", function: "transefer2",
+			args:  []string{"bob", "100", sign(key2, "transefer2", "bob", "100", 3), "kdapp", "", "", "0"},
+			check: wantBalances(map[string]int64{"alice": 800, "bob": 200}),
+		},
+	})
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	. "loulan/ccutil"
+	. "loulan/errcode"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//kd由账户系统跨合约调用，签名和身份在账户系统中校验，这里只需要在最后带一个占位的签名参数
+const testSignPlaceholder = "-"
+
+//MockStub的交易时间为当前时间，测试中需要可控的时间，所以包装一下
+type testStub struct {
+	*shim.MockStub
+	args [][]byte
+	now  time.Time
+}
+
+func (s *testStub) GetArgs() [][]byte {
+	return s.args
+}
+
+func (s *testStub) GetStringArgs() []string {
+	var strs = make([]string, 0, len(s.args))
+	for _, arg := range s.args {
+		strs = append(strs, string(arg))
+	}
+	return strs
+}
+
+func (s *testStub) GetFunctionAndParameters() (string, []string) {
+	var strs = s.GetStringArgs()
+	if len(strs) == 0 {
+		return "", []string{}
+	}
+	return strs[0], strs[1:]
+}
+
+func (s *testStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return &timestamp.Timestamp{Seconds: s.now.Unix(), Nanos: int32(s.now.Nanosecond())}, nil
+}
+
+func (s *testStub) advance(d time.Duration) {
+	s.now = s.now.Add(d)
+}
+
+//一条测试链：kd合约 + 模拟的账户余额。kd返回的转账按账户系统的方式在ledger上执行
+type testChain struct {
+	t      *testing.T
+	stub   *testStub
+	kd     *KD
+	txSeq  int
+	appid  string
+	ledger map[string]int64
+	total  int64
+}
+
+func newTestChain(t *testing.T, balances map[string]int64) *testChain {
+	//包级别的缓存会跨测试保留，每条链重新开始
+	currentFidCache = ""
+
+	var c = &testChain{t: t, kd: new(KD), appid: "kdapp", ledger: make(map[string]int64)}
+	for acc, amt := range balances {
+		c.ledger[acc] = amt
+		c.total += amt
+	}
+
+	c.stub = &testStub{MockStub: shim.NewMockStub("kd", c.kd), now: time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)}
+
+	c.txSeq++
+	var txid = fmt.Sprintf("tx%d", c.txSeq)
+	c.stub.args = util.ToChaincodeArgs("init")
+	c.stub.MockTransactionStart(txid)
+	resp := c.kd.Init(c.stub)
+	c.stub.MockTransactionEnd(txid)
+	if resp.Status != shim.OK {
+		t.Fatalf("newTestChain: init failed, %s", resp.Message)
+	}
+
+	c.mustOK(c.invoke("admin", "saveAppid", c.appid), "saveAppid")
+
+	return c
+}
+
+//执行一个交易，args为账户名之后、签名之前的参数。失败时和fabric一样丢弃本交易的所有写操作，
+//成功时把返回的转账记到ledger上
+func (c *testChain) invoke(acc, function string, args ...string) pb.Response {
+	var allArgs = append([]string{function, acc + "_user", acc}, args...)
+	allArgs = append(allArgs, testSignPlaceholder)
+
+	c.txSeq++
+	var txid = fmt.Sprintf("tx%d", c.txSeq)
+
+	var snapshot = make(map[string][]byte, len(c.stub.State))
+	for k, v := range c.stub.State {
+		snapshot[k] = v
+	}
+
+	c.stub.args = util.ToChaincodeArgs(allArgs...)
+	c.stub.MockTransactionStart(txid)
+	resp := c.kd.Invoke(c.stub)
+	if resp.Status != shim.OK {
+		c.rollback(snapshot)
+	}
+	c.stub.MockTransactionEnd(txid)
+
+	if resp.Status != shim.OK {
+		return resp
+	}
+
+	var invokeRslt InvokeResult
+	if err := json.Unmarshal(resp.Payload, &invokeRslt); err != nil {
+		c.t.Fatalf("%s: Unmarshal InvokeResult failed, err=%s", function, err)
+	}
+	for _, tx := range invokeRslt.TransInfos {
+		if tx.AppID != c.appid {
+			c.t.Errorf("%s: transfer appid want %s, got %s", function, c.appid, tx.AppID)
+		}
+		if tx.Amount < 0 {
+			c.t.Errorf("%s: transfer %s->%s amount %d < 0", function, tx.FromID, tx.ToID, tx.Amount)
+		}
+		//和账户系统一样，UpToBalance的转账最多转出余额
+		var amount = tx.Amount
+		if tx.UpToBalance && amount > c.ledger[tx.FromID] {
+			amount = c.ledger[tx.FromID]
+		}
+		c.ledger[tx.FromID] -= amount
+		c.ledger[tx.ToID] += amount
+	}
+
+	//把payload还原为kd本身的返回值，方便检查
+	resp.Payload = invokeRslt.Payload
+	return resp
+}
+
+func (c *testChain) rollback(snapshot map[string][]byte) {
+	var keys []string
+	for k := range c.stub.State {
+		keys = append(keys, k)
+	}
+	for _, k := range keys {
+		old, ok := snapshot[k]
+		if !ok {
+			c.stub.MockStub.DelState(k)
+		} else if !bytes.Equal(old, c.stub.State[k]) {
+			c.stub.MockStub.PutState(k, old)
+		}
+	}
+	for k, v := range snapshot {
+		if _, ok := c.stub.State[k]; !ok {
+			c.stub.MockStub.PutState(k, v)
+		}
+	}
+}
+
+func (c *testChain) mustOK(resp pb.Response, what string) []byte {
+	if resp.Status != shim.OK {
+		c.t.Fatalf("%s failed, %s", what, resp.Message)
+	}
+	return resp.Payload
+}
+
+//失败时返回的错误码。无法解析时返回-1
+func respErrCode(resp pb.Response) int32 {
+	if resp.Status == shim.OK {
+		return 0
+	}
+	errcm, err := NewErrorCodeMsgFromString(resp.Message)
+	if err != nil {
+		return -1
+	}
+	return errcm.Code
+}
+
+func (c *testChain) rackFinance(rackid, fid string) *RackFinancInfo {
+	rfiB := c.stub.State[c.kd.getRackFinacInfoKey(rackid, fid)]
+	if rfiB == nil {
+		return nil
+	}
+	var rfi RackFinancInfo
+	if err := json.Unmarshal(rfiB, &rfi); err != nil {
+		c.t.Fatalf("rackFinance(%s,%s): Unmarshal failed, err=%s", rackid, fid, err)
+	}
+	return &rfi
+}
+
+//每一步之后检查的不变量：
+//1. kd发起的转账不会凭空产生或销毁积分，且不会让账户余额为负
+//2. 每期货架理财的投资总额等于各用户投资额之和，且仍在投资的金额不超过货架融资能力
+//3. 每个用户已提取的收益不超过其收益
+func (c *testChain) checkInvariants(step string) {
+	var sum int64
+	var accs []string
+	for acc := range c.ledger {
+		accs = append(accs, acc)
+	}
+	sort.Strings(accs)
+	for _, acc := range accs {
+		if c.ledger[acc] < 0 {
+			c.t.Errorf("[%s] invariant: balance of %s %d < 0", step, acc, c.ledger[acc])
+		}
+		sum += c.ledger[acc]
+	}
+	if sum != c.total {
+		c.t.Errorf("[%s] invariant: sum of balances %d != %d", step, sum, c.total)
+	}
+
+	for k, v := range c.stub.State {
+		if !strings.HasPrefix(k, RACKFINACINFO_PREFIX) {
+			continue
+		}
+		var rfi RackFinancInfo
+		if err := json.Unmarshal(v, &rfi); err != nil {
+			c.t.Fatalf("[%s] invariant: Unmarshal %s failed, err=%s", step, k, err)
+		}
+
+		var amtSum, investing int64
+		for acc, amt := range rfi.UserAmountMap {
+			amtSum += amt
+			if !StrSliceContains(rfi.PayFinanceUserList, acc) {
+				investing += amt
+			}
+		}
+		if amtSum != rfi.AmountFinca {
+			c.t.Errorf("[%s] invariant: (%s,%s) AmountFinca %d != sum of users %d", step, rfi.RackID, rfi.FID, rfi.AmountFinca, amtSum)
+		}
+		if investing > rfi.RFCfg.InvestCapacity {
+			c.t.Errorf("[%s] invariant: (%s,%s) investing %d > capacity %d", step, rfi.RackID, rfi.FID, investing, rfi.RFCfg.InvestCapacity)
+		}
+		for acc, paid := range rfi.UserPaidProfitMap {
+			if paid > rfi.UserProfitMap[acc] {
+				c.t.Errorf("[%s] invariant: (%s,%s) %s paid profit %d > profit %d", step, rfi.RackID, rfi.FID, acc, paid, rfi.UserProfitMap[acc])
+			}
+		}
+	}
+}
+
+//表驱动测试的一步
+type testStep struct {
+	name     string
+	acc      string        //发起交易的账户
+	function string        //
+	args     []string      //账户名之后、签名之前的参数
+	advance  time.Duration //执行前推进的时间
+	wantCode int32         //0表示期望成功，否则为期望的错误码
+	check    func(t *testing.T, c *testChain, payload []byte)
+}
+
+func (c *testChain) run(steps []testStep) {
+	for _, st := range steps {
+		c.stub.advance(st.advance)
+
+		resp := c.invoke(st.acc, st.function, st.args...)
+		c.stub.advance(time.Second)
+
+		if code := respErrCode(resp); code != st.wantCode {
+			c.t.Fatalf("[%s] want code %d, got %d, resp=%s", st.name, st.wantCode, code, resp.Message)
+		}
+
+		c.checkInvariants(st.name)
+
+		if st.check != nil {
+			st.check(c.t, c, resp.Payload)
+		}
+	}
+}
+
+//检查账户余额的check函数
+func wantBalances(want map[string]int64) func(t *testing.T, c *testChain, payload []byte) {
+	return func(t *testing.T, c *testChain, payload []byte) {
+		for acc, amt := range want {
+			if got := c.ledger[acc]; got != amt {
+				t.Errorf("balance of %s: want %d, got %d", acc, amt, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	. "loulan/acbase"
+	. "loulan/mylog"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//账户系统的实现都在acbase包中，这里只负责启动合约。acbase的版本见ccmanifest.json
+var acsyslogger = NewMylogger("acsys")
+
+func main() {
+	acsyslogger.Info("acbase version %s", ACBASE_VERSION)
+
+	err := shim.Start(&Base)
+	if err != nil {
+		acsyslogger.Error("Error starting accountsys chaincode: %s", err)
+	}
+}
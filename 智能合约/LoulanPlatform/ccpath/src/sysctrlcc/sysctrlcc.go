@@ -0,0 +1,508 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"loulan/llcrypto"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	PARA_PREFIX         = "!ctrl@paraPre~"      //参数当前值的key的前缀
+	PARA_HISTORY_PREFIX = "!ctrl@paraHisPre~"   //参数修改记录的key的前缀，后面为 参数名_版本号
+	ALL_PARA_KEY        = "!ctrl@allParaKey@!"  //所有参数名
+	ADMIN_PUBKEY_KEY    = "!ctrl@adminPubKey@!" //管理员公钥的hash
+
+	SIGN_MAX_VALID_PERIOD = 24 * 3600 * 1000 //签名的过期时间最多在交易时间之后24小时，单位毫秒
+
+	PARA_TYPE_BOOL   = "bool"
+	PARA_TYPE_INT    = "int"
+	PARA_TYPE_STRING = "string"
+)
+
+//系统控制参数
+type SysParameter struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`  //bool int string
+	Value      string `json:"value"` //统一以字符串保存，bool类型保存为true/false
+	Version    int64  `json:"ver"`   //每修改一次加1，从1开始
+	UpdateTime int64  `json:"time"`  //修改时间
+	TxID       string `json:"txid"`  //修改的交易id
+}
+
+//参数默认值，init时写入。升级时，新增加的参数也会写入
+var defaultParameters = []SysParameter{
+	{Name: "isTestChain", Type: PARA_TYPE_BOOL, Value: "false"},       //是否是测试链
+	{Name: "needCheckSign", Type: PARA_TYPE_BOOL, Value: "true"},      //是否检查签名
+	{Name: "needCheckIndentity", Type: PARA_TYPE_BOOL, Value: "true"}, //是否检查用户身份
+	{Name: "balanceCkptInterval", Type: PARA_TYPE_INT, Value: "100"},  //每隔多少条交易记录保存一次账户余额快照，0表示不保存
+}
+
+type SysCtrl struct {
+}
+
+var ctrlogger = shim.NewLogger("sysctrlcc")
+
+var secp256k1 = llcrypto.NewSecp256k1()
+
+func (b *SysCtrl) Init(stub shim.ChaincodeStubInterface) (pbResponse pb.Response) {
+	ctrlogger.Debug("Enter Init")
+	function, args := stub.GetFunctionAndParameters()
+	ctrlogger.Infof("func =%s, args = %+v", function, args)
+
+	defer func() {
+		if excption := recover(); excption != nil {
+			pbResponse = shim.Error(fmt.Sprintf("Init(%s) got excption:%s", function, excption))
+		}
+	}()
+
+	//合约实例化时，默认会执行init函数，除非在调用合约实例化接口时指定了其它的函数
+	//注意，只有在第一次部署时才能执行init函数，后续升级时如果执行了init函数，所有数据将会被清空
+	if function == "init" {
+		//args: [adminPubKeyHash, name1, type1, value1, name2, type2, value2, ...]  后面的参数用来覆盖默认值，如测试链可以设置isTestChain为true
+		if len(args) < 1 || len(args[0]) == 0 {
+			return shim.Error("Init miss arg, need adminPubKeyHash.")
+		}
+		if (len(args)-1)%3 != 0 {
+			return shim.Error("Init args invalid, parameters must be in (name, type, value) format.")
+		}
+
+		err := stub.PutState(ADMIN_PUBKEY_KEY, []byte(args[0]))
+		if err != nil {
+			return shim.Error(fmt.Sprintf("Init PutState(admin) failed, err=%s", err))
+		}
+
+		var overrides = make(map[string]SysParameter)
+		for i := 1; i < len(args); i += 3 {
+			overrides[args[i]] = SysParameter{Name: args[i], Type: args[i+1], Value: args[i+2]}
+		}
+
+		for _, para := range defaultParameters {
+			if p, ok := overrides[para.Name]; ok {
+				para = p
+				delete(overrides, para.Name)
+			}
+			err = b.setParameter(stub, &para)
+			if err != nil {
+				return shim.Error(fmt.Sprintf("Init setParameter(%s) failed, err=%s", para.Name, err))
+			}
+		}
+		//按参数的顺序写入，保证每个节点执行结果一致
+		for i := 1; i < len(args); i += 3 {
+			if para, ok := overrides[args[i]]; ok {
+				err = b.setParameter(stub, &para)
+				if err != nil {
+					return shim.Error(fmt.Sprintf("Init setParameter(%s) failed, err=%s", para.Name, err))
+				}
+			}
+		}
+
+		return shim.Success(nil)
+
+	} else if function == "upgrade" { //升级时默认会执行upgrade函数，除非在调用合约升级接口时指定了其它的函数
+		//升级需要通道管理员的背书，所以可以在升级时重新指定管理员
+		if len(args) > 0 && len(args[0]) > 0 {
+			err := stub.PutState(ADMIN_PUBKEY_KEY, []byte(args[0]))
+			if err != nil {
+				return shim.Error(fmt.Sprintf("upgrade PutState(admin) failed, err=%s", err))
+			}
+		}
+
+		//新版本中增加的参数写入默认值，已有的参数保留原值
+		for _, para := range defaultParameters {
+			curr, err := b.getParameter(stub, para.Name)
+			if err != nil {
+				return shim.Error(fmt.Sprintf("upgrade getParameter(%s) failed, err=%s", para.Name, err))
+			}
+			if curr != nil {
+				continue
+			}
+			err = b.setParameter(stub, &para)
+			if err != nil {
+				return shim.Error(fmt.Sprintf("upgrade setParameter(%s) failed, err=%s", para.Name, err))
+			}
+		}
+
+		return shim.Success(nil)
+
+	} else {
+
+		return shim.Error(fmt.Sprintf("unknown function: %s", function))
+	}
+}
+
+func (b *SysCtrl) Invoke(stub shim.ChaincodeStubInterface) (pbResponse pb.Response) {
+
+	ctrlogger.Debug("Enter Invoke")
+	function, args := stub.GetFunctionAndParameters()
+	ctrlogger.Debug("func =%s, args = %+v", function, args)
+
+	defer func() {
+		if excption := recover(); excption != nil {
+			pbResponse = shim.Error(fmt.Sprintf("Invoke(%s) got excption:%s", function, excption))
+		}
+	}()
+
+	if function == "getParameter" { //只返回参数值，供其它合约调用
+		if len(args) < 1 {
+			return shim.Error(fmt.Sprintf("Invoke(%s) miss args.", function))
+		}
+
+		var paraName = args[0]
+
+		para, err := b.getParameter(stub, paraName)
+		if err != nil {
+			return shim.Error(fmt.Sprintf("Invoke(%s) getParameter failed, err=%s", function, err))
+		}
+		if para == nil {
+			return shim.Error(fmt.Sprintf("Invoke(%s), can not find parameter name '%s'.", function, paraName))
+		}
+
+		return shim.Success([]byte(para.Value))
+
+	} else if function == "setParameter" {
+		//args: [name, type, version, value, sign]  version为修改后的版本号，即当前版本号加1，新增参数为1。用来防止签名过的交易被重放
+		//sign必须是v1格式（见llcrypto/signmsg.go），签名中包含链id、合约名和过期时间，不能拿到其它链或合约上使用
+		if len(args) < 5 {
+			return shim.Error(fmt.Sprintf("Invoke(%s) miss args.", function))
+		}
+
+		err := b.verifyAdminSign(stub, function, args)
+		if err != nil {
+			return shim.Error(fmt.Sprintf("Invoke(%s) verify signature failed, err=%s", function, err))
+		}
+
+		var para SysParameter
+		para.Name = args[0]
+		para.Type = args[1]
+		para.Value = args[3]
+		version, err := strconv.ParseInt(args[2], 0, 64)
+		if err != nil {
+			return shim.Error(fmt.Sprintf("Invoke(%s) convert version(%s) failed, err=%s", function, args[2], err))
+		}
+
+		curr, err := b.getParameter(stub, para.Name)
+		if err != nil {
+			return shim.Error(fmt.Sprintf("Invoke(%s) getParameter failed, err=%s", function, err))
+		}
+		var currVersion int64 = 0
+		if curr != nil {
+			currVersion = curr.Version
+			if curr.Type != para.Type {
+				return shim.Error(fmt.Sprintf("Invoke(%s) parameter '%s' type is '%s', can not change to '%s'.", function, para.Name, curr.Type, para.Type))
+			}
+		}
+		if version != currVersion+1 {
+			return shim.Error(fmt.Sprintf("Invoke(%s) version mismatch, current is %d, got %d.", function, currVersion, version))
+		}
+
+		err = b.setParameter(stub, &para)
+		if err != nil {
+			return shim.Error(fmt.Sprintf("Invoke(%s) setParameter failed, err=%s", function, err))
+		}
+
+		ctrlogger.Infof("setParameter: %+v", para)
+
+		paraB, err := json.Marshal(para)
+		if err != nil {
+			return shim.Error(fmt.Sprintf("Invoke(%s) Marshal failed, err=%s", function, err))
+		}
+
+		return shim.Success(paraB)
+
+	} else if function == "listParameters" {
+		names, err := b.getAllParameterNames(stub)
+		if err != nil {
+			return shim.Error(fmt.Sprintf("Invoke(%s) getAllParameterNames failed, err=%s", function, err))
+		}
+
+		var paras = []SysParameter{}
+		for _, name := range names {
+			para, err := b.getParameter(stub, name)
+			if err != nil {
+				return shim.Error(fmt.Sprintf("Invoke(%s) getParameter(%s) failed, err=%s", function, name, err))
+			}
+			if para != nil {
+				paras = append(paras, *para)
+			}
+		}
+
+		parasB, err := json.Marshal(paras)
+		if err != nil {
+			return shim.Error(fmt.Sprintf("Invoke(%s) Marshal failed, err=%s", function, err))
+		}
+
+		return shim.Success(parasB)
+
+	} else if function == "getParameterHistory" {
+		if len(args) < 1 {
+			return shim.Error(fmt.Sprintf("Invoke(%s) miss args.", function))
+		}
+
+		var paraName = args[0]
+
+		curr, err := b.getParameter(stub, paraName)
+		if err != nil {
+			return shim.Error(fmt.Sprintf("Invoke(%s) getParameter failed, err=%s", function, err))
+		}
+		if curr == nil {
+			return shim.Error(fmt.Sprintf("Invoke(%s), can not find parameter name '%s'.", function, paraName))
+		}
+
+		var history = []SysParameter{}
+		for ver := int64(1); ver <= curr.Version; ver++ {
+			hisB, err := stub.GetState(b.getParameterHistoryKey(paraName, ver))
+			if err != nil {
+				return shim.Error(fmt.Sprintf("Invoke(%s) GetState failed, err=%s", function, err))
+			}
+			if hisB == nil {
+				continue
+			}
+			var his SysParameter
+			err = json.Unmarshal(hisB, &his)
+			if err != nil {
+				return shim.Error(fmt.Sprintf("Invoke(%s) Unmarshal failed, err=%s", function, err))
+			}
+			history = append(history, his)
+		}
+
+		historyB, err := json.Marshal(history)
+		if err != nil {
+			return shim.Error(fmt.Sprintf("Invoke(%s) Marshal failed, err=%s", function, err))
+		}
+
+		return shim.Success(historyB)
+
+	} else {
+
+		return shim.Error(fmt.Sprintf("unknown function: %s", function))
+	}
+}
+
+func (b *SysCtrl) getParameterKey(name string) string {
+	return PARA_PREFIX + name
+}
+
+func (b *SysCtrl) getParameterHistoryKey(name string, version int64) string {
+	return PARA_HISTORY_PREFIX + name + "_" + strconv.FormatInt(version, 10)
+}
+
+//参数不存在时返回nil
+func (b *SysCtrl) getParameter(stub shim.ChaincodeStubInterface, name string) (*SysParameter, error) {
+	paraB, err := stub.GetState(b.getParameterKey(name))
+	if err != nil {
+		return nil, err
+	}
+	if paraB == nil {
+		return nil, nil
+	}
+
+	var para SysParameter
+	err = json.Unmarshal(paraB, &para)
+	if err != nil {
+		return nil, err
+	}
+
+	return &para, nil
+}
+
+//检查参数值，更新版本号后保存当前值和修改记录
+func (b *SysCtrl) setParameter(stub shim.ChaincodeStubInterface, para *SysParameter) error {
+	if len(para.Name) == 0 {
+		return fmt.Errorf("parameter name is empty")
+	}
+
+	value, err := b.normalizeValue(para.Type, para.Value)
+	if err != nil {
+		return err
+	}
+	para.Value = value
+
+	curr, err := b.getParameter(stub, para.Name)
+	if err != nil {
+		return err
+	}
+	if curr != nil {
+		para.Version = curr.Version + 1
+	} else {
+		para.Version = 1
+		names, err := b.getAllParameterNames(stub)
+		if err != nil {
+			return err
+		}
+		namesB, err := json.Marshal(append(names, para.Name))
+		if err != nil {
+			return err
+		}
+		err = stub.PutState(ALL_PARA_KEY, namesB)
+		if err != nil {
+			return err
+		}
+	}
+
+	timestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	para.UpdateTime = timestamp.Seconds*1000 + int64(timestamp.Nanos/1000000) //精确到毫秒
+	para.TxID = stub.GetTxID()
+
+	paraB, err := json.Marshal(para)
+	if err != nil {
+		return err
+	}
+
+	err = stub.PutState(b.getParameterKey(para.Name), paraB)
+	if err != nil {
+		return err
+	}
+
+	return stub.PutState(b.getParameterHistoryKey(para.Name, para.Version), paraB)
+}
+
+//按类型检查参数值，并转为统一的格式
+func (b *SysCtrl) normalizeValue(paraType, value string) (string, error) {
+	switch paraType {
+	case PARA_TYPE_BOOL:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("value '%s' is not bool", value)
+		}
+		return strconv.FormatBool(v), nil
+	case PARA_TYPE_INT:
+		v, err := strconv.ParseInt(value, 0, 64)
+		if err != nil {
+			return "", fmt.Errorf("value '%s' is not int", value)
+		}
+		return strconv.FormatInt(v, 10), nil
+	case PARA_TYPE_STRING:
+		return value, nil
+	default:
+		return "", fmt.Errorf("unknown parameter type '%s'", paraType)
+	}
+}
+
+func (b *SysCtrl) getAllParameterNames(stub shim.ChaincodeStubInterface) ([]string, error) {
+	namesB, err := stub.GetState(ALL_PARA_KEY)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	if namesB == nil {
+		return names, nil
+	}
+
+	err = json.Unmarshal(namesB, &names)
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+//签名为最后一个参数，只接受v1格式：msg为llcrypto.CanonicalSignMsg(链id, 合约名, 函数名, 签名前的参数, 过期时间)，然后用私钥对msg做签名
+func (b *SysCtrl) verifyAdminSign(stub shim.ChaincodeStubInterface, function string, args []string) error {
+	adminB, err := stub.GetState(ADMIN_PUBKEY_KEY)
+	if err != nil {
+		return err
+	}
+	if len(adminB) == 0 {
+		return fmt.Errorf("admin not set")
+	}
+
+	var signIdx = len(args) - 1
+	isV1, expiry, signBase64, err := llcrypto.ParseSignEnvelope(args[signIdx])
+	if err != nil {
+		return fmt.Errorf("parse sign failed, err=%s", err)
+	}
+	if !isV1 {
+		return fmt.Errorf("sign must be in v1 format")
+	}
+	sign, err := base64.StdEncoding.DecodeString(signBase64)
+	if err != nil {
+		return fmt.Errorf("convert sign failed, err=%s", err)
+	}
+
+	timestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("GetTxTimestamp failed, err=%s", err)
+	}
+	var invokeTime = timestamp.Seconds*1000 + int64(timestamp.Nanos/1000000)
+	if expiry <= invokeTime {
+		return fmt.Errorf("sign expired(%d,%d)", expiry, invokeTime)
+	}
+	if expiry > invokeTime+SIGN_MAX_VALID_PERIOD {
+		return fmt.Errorf("sign expiry too far(%d,%d)", expiry, invokeTime)
+	}
+
+	ccName, err := b.getInvokedChaincodeName(stub)
+	if err != nil {
+		return err
+	}
+
+	msg := llcrypto.CanonicalSignMsg(stub.GetChannelID(), ccName, function, args[:signIdx], expiry)
+
+	if code := secp256k1.VerifySignatureValidity(sign); code != 1 {
+		return fmt.Errorf("sign invalid, code=%v", code)
+	}
+
+	pubKey, err := secp256k1.RecoverPubkey(msg, sign)
+	if err != nil {
+		return fmt.Errorf("RecoverPubkey failed, err=%s", err)
+	}
+
+	hash, err := llcrypto.RipemdHash160(pubKey)
+	if err != nil {
+		return fmt.Errorf("Hash160 failed, err=%s", err)
+	}
+
+	if base64.StdEncoding.EncodeToString(hash) != string(adminB) {
+		return fmt.Errorf("not signed by admin")
+	}
+
+	return nil
+}
+
+//客户端提交交易时调用的合约名，从交易提案中解析
+func (b *SysCtrl) getInvokedChaincodeName(stub shim.ChaincodeStubInterface) (string, error) {
+	sp, err := stub.GetSignedProposal()
+	if err != nil || sp == nil {
+		return "", fmt.Errorf("GetSignedProposal failed, err=%v", err)
+	}
+
+	var prop pb.Proposal
+	if err = proto.Unmarshal(sp.ProposalBytes, &prop); err != nil {
+		return "", fmt.Errorf("Unmarshal proposal failed, err=%s", err)
+	}
+
+	var cpp pb.ChaincodeProposalPayload
+	if err = proto.Unmarshal(prop.Payload, &cpp); err != nil {
+		return "", fmt.Errorf("Unmarshal payload failed, err=%s", err)
+	}
+
+	var cis pb.ChaincodeInvocationSpec
+	if err = proto.Unmarshal(cpp.Input, &cis); err != nil {
+		return "", fmt.Errorf("Unmarshal invocation spec failed, err=%s", err)
+	}
+
+	if cis.ChaincodeSpec == nil || cis.ChaincodeSpec.ChaincodeId == nil {
+		return "", fmt.Errorf("no chaincode id in proposal")
+	}
+
+	return cis.ChaincodeSpec.ChaincodeId.Name, nil
+}
+
+func main() {
+
+	err := shim.Start(new(SysCtrl))
+	if err != nil {
+		ctrlogger.Error("Error starting  chaincode: %s", err)
+	}
+}
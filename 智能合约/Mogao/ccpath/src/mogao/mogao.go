@@ -10,12 +10,16 @@ import (
 	//"encoding/json"
 	//"fmt"
 	//"io"
-	//"math"
+	"math"
 	//"os"
 	//"time"
-	//"sort"
+	"sort"
 	"strconv"
-	//"strings"
+	"strings"
+
+	. "loulan/acbase"
+	. "loulan/errcode"
+	. "loulan/mylog"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 )
@@ -23,14 +27,27 @@ import (
 const (
 	MODULE_NAME                    = "mg"
 	MOVIE_INFO_PREFIX              = "!mg@mvIfPre~"       //影片信息key前缀
-	MOVIE_COMMENT_INFO_PREFIX      = "!mg@mvCmtIfPre~"    //影片评论信息key前缀
+	MOVIE_COMMENT_INFO_PREFIX      = "!mg@mvCmtIfPre~"    //影片评论信息key前缀（旧的存储方式，所有评论存在一个key中，只读）
+	MOVIE_COMMENT_PREFIX           = "!mg@mvCmtPre~"      //每条影片评论的key前缀，后面为 影片id~评论id
+	MOVIE_COMMENT_LIKE_PREFIX      = "!mg@mvCmtLikePre~"  //评论点赞的key前缀，后面为 评论id~点赞账户
 	MOVIE_INCOME_ALLOC_RATE_PREFIX = "!mg@mvInAlocRtPre~" //影片收入分配比例
 	MOVIE_GLOBAL_CFG_KEY           = "!mg@mvGlbCfg@!"     //
 	MOVIE_ALLOCTX_SEQ_PREFIX       = "!mg@alocTxSeqPre~"  //每部影片的分成记录的序列号的key前缀
 	MOVIE_ALLOCTX_PREFIX           = "!mg@alocTxPre~"     //每部影片收入分成记录
+	MOVIE_CMTR_ALLOCTX_SEQ_PREFIX  = "!mg@cmtrTxSeqPre~"  //每个评论人的分成记录的序列号的key前缀
+	MOVIE_CMTR_ALLOCTX_PREFIX      = "!mg@cmtrTxPre~"     //每个评论人的分成记录，值为影片收入分成记录的key
 
 	MOVIE_GLOBAL_ID = "__global@movie_id__" //影片一些全局配置的影片id
 
+	MOVIE_INCOME_SETTLE_TRANSTYPE = "mvIncSettle" //影片收入结算的交易类型，后面加上影片id和分成记录的序列号
+
+	RATE_BASE_BP = 10000 //分成比例的基数，比例以万分比表示，各部分之和必须等于该值
+
+	MOVIE_KEY_DELIM     = "~"    //影片id和评论id、评论id和点赞账户之间的分隔符
+	MOVIE_KEY_DELIM_END = "\x7f" //范围查询的结束字符，比分隔符大1
+
+	OLD_VERSION_CCID          = "ef38784bad472d640839c1782232aac63985489ee624b67a2d2b23448b03ebfb" //老版本合约的ccid，load该版本导出的worldState时需要转换数据
+	OLD_VERSION_ISSUED_AMOUNT = 1000000000                                                         //老版本中已给央行发行的货币
 )
 
 /***********************************************************/
@@ -54,6 +71,15 @@ type CommentatorCommentInfo struct {
 	Comments    []CommentInfo `json:"cmts"` //
 }
 
+//单条影片评论，每条评论单独存储
+type MovieComment struct {
+	CommentId   string `json:"cid"`  //评论id，使用评论时的交易id。旧数据中的评论为空
+	MovieId     string `json:"mid"`  //
+	Commentator string `json:"cmtr"` //
+	LikeCnt     int64  `json:"lkc"`  //点赞数，读取时统计，FavourateCnt为初始点赞数+点赞数
+	CommentInfo
+}
+
 //影片评论信息
 type MovieCommentInfo struct {
 	MovieId string                   `json:"mid"` //
@@ -77,6 +103,7 @@ type cmtorIncomeAllocRate struct {
 	//这两个rate计算方式  FixedRate/(FixedRate+FavourateRate)
 	FixedRate     int   `json:"fxr"`  //分给评论人的的分成部分， 按此固定部分的比例平分给各评论人
 	FavourateRate int   `json:"frr"`  //分给评论人的的分成部分， 按此好评比例，再按照每个评论的点赞数分成
+	RateBase      int   `json:"rb"`   //比例基数，为0时（旧数据）以FixedRate+FavourateRate为基数
 	UpdateTime    int64 `json:"uptm"` // 记录此条记录的时间
 }
 type movieIncomePlatformRate struct {
@@ -97,6 +124,7 @@ type MovieIncomeAllocRateCfg struct {
 	UploaderRate     int    `json:"uplr"`  //
 	CommentatorsRate int    `json:"cmtrr"` //
 	PlatformRate     int    `json:"pltr"`  // 这个从全局中计算得出？ 前两个rate应该由上传者指定，这个rate应该由平台指定
+	RateBase         int    `json:"rb"`    //比例基数，为0时（旧数据）以三个比例之和为基数
 	UpdateTime       int64  `json:"uptm"`  // 记录此条记录的时间
 }
 
@@ -115,6 +143,65 @@ type MovieIncomeTx struct {
 	CommentatorFixedRate     int              `json:"fxr"`   //
 	CommentatorFavourateRate int              `json:"frr"`   //
 	GlobalSerial             int64            `json:"gser"`
+	RateBase                 int              `json:"rb"`    //UploaderRate等三个比例的基数
+	CmtrRateBase             int              `json:"crb"`   //CommentatorFixedRate等两个比例的基数
+	NoCommentator            bool             `json:"nocmt"` //没有评论人，评论人的分成归平台
+	NoFavourate              bool             `json:"nofav"` //所有评论都没有点赞，按点赞分成的部分平分给各评论人
+	Settled                  bool             `json:"stl"`   //是否已结算（已实际转账）
+	SettleTime               int64            `json:"stm"`   //结算时间
+	PayerAcc                 string           `json:"pyacc"` //结算时的付款账户
+	UploaderAcc              string           `json:"upacc"` //结算时的上传人账户
+	PlatformAcc              string           `json:"pfacc"` //结算时的平台账户
+	SettleTransType          string           `json:"stt"`   //结算转账的交易类型
+}
+
+//影片评论查询结果
+type QueryCommentRecd struct {
+	Idx int64 `json:"idx"` //评论序号，从1开始
+	MovieComment
+}
+type QueryMovieComments struct {
+	MovieId  string             `json:"mid"`
+	Total    int64              `json:"total"`   //符合条件的评论总数
+	NextIdx  int64              `json:"nextidx"` //下次要请求的序号，-1表示没有更多
+	Comments []QueryCommentRecd `json:"cmts"`
+}
+
+//影片生效的分成比例配置查询结果
+type QueryMovieAllocRate struct {
+	MovieId   string                  `json:"mid"`
+	UseGlobal bool                    `json:"glb"` //该影片没有单独配置，使用全局配置
+	AllocRate MovieIncomeAllocRateCfg `json:"miar"`
+	GlobalCfg MovieGlobalCfg          `json:"mgc"`
+}
+
+//影片收入分成记录查询结果
+type QueryMovieIncomeResult struct {
+	NextSerial int64           `json:"nextser"` //因为是批量返回结果，表示下次要请求的序列号
+	MaxSerial  int64           `json:"maxser"`
+	Records    []MovieIncomeTx `json:"records"`
+}
+
+//评论人的一条分成记录
+type CmtrIncomeRecd struct {
+	Serial      int64  `json:"ser"`  //评论人分成记录的序列号
+	MovieId     string `json:"mid"`  //
+	AllocSerial int64  `json:"aser"` //影片收入分成记录的序列号
+	DateTime    int64  `json:"dt"`   //
+	Income      int64  `json:"inc"`  //
+	Favourate   int64  `json:"fav"`  //
+	Settled     bool   `json:"stl"`  //
+}
+
+//评论人在所有影片中的收入查询结果（统计范围为本次返回的记录）
+type QueryCmtrEarnings struct {
+	Commentator   string           `json:"cmtr"`
+	TotalIncome   int64            `json:"tinc"`
+	SettledIncome int64            `json:"sinc"`
+	MovieIncome   map[string]int64 `json:"minc"` //每部影片的收入
+	NextSerial    int64            `json:"nextser"`
+	MaxSerial     int64            `json:"maxser"`
+	Records       []CmtrIncomeRecd `json:"records"`
 }
 
 type MOGAO struct {
@@ -124,14 +211,30 @@ var mogao MOGAO
 
 var mglogger = NewMylogger("mg")
 
+//base中的数据和交易都在同一个stateCache中缓存
+var stateCache = &StateCache
+
 //包初始化函数
 func init() {
+	SetModuleName(MODULE_NAME)
+
 	//注册base中的hook函数
 	InitHook = mogao.Init
 	InvokeHook = mogao.Invoke
+	DateConvertWhenLoadHook = mogao.dateConvertWhenLoad
+	DateUpdateAfterLoadHook = mogao.loadAfter
+}
+
+func (m *MOGAO) Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]byte, *ErrorCodeMsg) {
+	payload, err := m.__Init(stub, ifas)
+	if err != nil {
+		return nil, mglogger.ErrorECM(ERRCODE_COMMON_INNER_ERROR, "%s", err)
+	}
+
+	return payload, nil
 }
 
-func (m *MOGAO) Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]byte, error) {
+func (m *MOGAO) __Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]byte, error) {
 	mglogger.Debug("Enter Init")
 	function, args := stub.GetFunctionAndParameters()
 	mglogger.Debug("func =%s, args = %+v", function, args)
@@ -145,31 +248,33 @@ func (m *MOGAO) Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]by
 
 	if function == "init" { //合约实例化时，默认会执行init函数，除非在调用合约实例化接口时指定了其它的函数
 
-		err := Base.setIssueAmountTotal(stub, 100000000, initTime)
-		if err != nil {
-			return nil, mglogger.Errorf("Init setIssueAmountTotal error, err=%s.", err)
+		errcm := Base.SetIssueAmountTotal(stub, 100000000, initTime)
+		if errcm != nil {
+			return nil, mglogger.Errorf("Init SetIssueAmountTotal error, err=%s.", errcm)
 		}
 
 		var miar MovieIncomeAllocRateCfg
 		miar.MovieId = MOVIE_GLOBAL_ID
 		miar.UpdateTime = initTime
-		miar.UploaderRate = 85
-		miar.CommentatorsRate = 10
-		miar.PlatformRate = 5
+		miar.UploaderRate = 8500
+		miar.CommentatorsRate = 1000
+		miar.PlatformRate = 500
+		miar.RateBase = RATE_BASE_BP
 
 		miarJson, err := json.Marshal(miar)
 		if err != nil {
 			return nil, mglogger.Errorf("Init(init) Marshal(miar) failed, err=%s", err)
 		}
-		err = Base.putState_Ex(stub, m.getMovieIncomeAllocRateKey(MOVIE_GLOBAL_ID), miarJson)
+		err = stateCache.PutState_Ex(stub, m.getMovieIncomeAllocRateKey(MOVIE_GLOBAL_ID), miarJson)
 		if err != nil {
 			return nil, mglogger.Errorf("Init(init) putState_Ex(miar) failed, err=%s", err)
 		}
 
 		var mgc MovieGlobalCfg
 		mgc.CIAR.UpdateTime = initTime
-		mgc.CIAR.FixedRate = 7
-		mgc.CIAR.FavourateRate = 3
+		mgc.CIAR.FixedRate = 7000
+		mgc.CIAR.FavourateRate = 3000
+		mgc.CIAR.RateBase = RATE_BASE_BP
 
 		mgc.MIPR.UpdateTime = initTime
 		mgc.MIPR.PlatformRate = 50
@@ -179,7 +284,7 @@ func (m *MOGAO) Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]by
 		if err != nil {
 			return nil, mglogger.Errorf("Init(init) Marshal(mgc) failed, err=%s", err)
 		}
-		err = Base.putState_Ex(stub, MOVIE_GLOBAL_CFG_KEY, mgcJson)
+		err = stateCache.PutState_Ex(stub, MOVIE_GLOBAL_CFG_KEY, mgcJson)
 		if err != nil {
 			return nil, mglogger.Errorf("Init(init) putState_Ex(mgc) failed, err=%s", err)
 		}
@@ -196,8 +301,17 @@ func (m *MOGAO) Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]by
 
 }
 
+func (m *MOGAO) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]byte, *ErrorCodeMsg) {
+	payload, err := m.__Invoke(stub, ifas)
+	if err != nil {
+		return nil, mglogger.ErrorECM(ERRCODE_COMMON_INNER_ERROR, "%s", err)
+	}
+
+	return payload, nil
+}
+
 // Transaction makes payment of X units from A to B
-func (m *MOGAO) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]byte, error) {
+func (m *MOGAO) __Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]byte, error) {
 	mglogger.Debug("Enter Invoke")
 	function, args := stub.GetFunctionAndParameters()
 	mglogger.Debug("func =%s, args = %+v", function, args)
@@ -229,7 +343,7 @@ func (m *MOGAO) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) (
 			return nil, mglogger.Errorf("Invoke(setMovieInfo) miss arg, got %d, at least need %d.", len(args), argCount)
 		}
 
-		if !Base.isAdmin(stub, accName) {
+		if !Base.IsAdmin(stub, accName) {
 			return nil, mglogger.Errorf("Invoke can't exec setMovieInfo by %s.", accName)
 		}
 
@@ -245,7 +359,7 @@ func (m *MOGAO) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) (
 		}
 
 		//TODO: 要不要检测数据是否已存在?
-		err = Base.putState_Ex(stub, m.getMovieInfoKey(mi.MovieId), miJson)
+		err = stateCache.PutState_Ex(stub, m.getMovieInfoKey(mi.MovieId), miJson)
 		if err != nil {
 			return nil, mglogger.Errorf("Invoke(setMovieInfo) putState_Ex failed, err=%s.", err)
 		}
@@ -253,21 +367,33 @@ func (m *MOGAO) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) (
 		return nil, nil
 
 	} else if function == "setMovieComment" {
-		var argCount = fixedArgCount + 4
+		var argCount = fixedArgCount + 3
 		if len(args) < argCount {
 			return nil, mglogger.Errorf("Invoke(setMovieComment) miss arg, got %d, at least need %d.", len(args), argCount)
 		}
 
-		if !Base.isAdmin(stub, accName) {
-			return nil, mglogger.Errorf("Invoke can't exec setMovieComment by %s.", accName)
-		}
 		var movieId = args[fixedArgCount]
 		var commentator = args[fixedArgCount+1]
 		var comment = args[fixedArgCount+2]
-		favourateCnt, err := strconv.ParseInt(args[fixedArgCount+3], 0, 64)
-		if err != nil {
-			return nil, mglogger.Errorf("Invoke(setMovieComment) convert favourateCnt(%s) failed. err=%s", args[fixedArgCount+3], err)
+
+		//管理员或评论人自己可以发表评论
+		if commentator != accName && !Base.IsAdmin(stub, accName) {
+			return nil, mglogger.Errorf("Invoke can't exec setMovieComment by %s.", accName)
+		}
+
+		//初始点赞数，用于导入链外已有的评论，只有管理员可以设置。最后一个参数为签名
+		var favourateCnt int64 = 0
+		if len(args) > argCount+1 && len(args[argCount]) > 0 {
+			if !Base.IsAdmin(stub, accName) {
+				return nil, mglogger.Errorf("Invoke(setMovieComment) %s can't set favourateCnt.", accName)
+			}
+			var err error
+			favourateCnt, err = strconv.ParseInt(args[argCount], 0, 64)
+			if err != nil {
+				return nil, mglogger.Errorf("Invoke(setMovieComment) convert favourateCnt(%s) failed. err=%s", args[argCount], err)
+			}
 		}
+
 		//看这个电影是否存在
 		pmi, err := m.getMovieInfo(stub, movieId)
 		if err != nil {
@@ -277,206 +403,274 @@ func (m *MOGAO) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) (
 			return nil, mglogger.Errorf("Invoke(setMovieComment)  movie not registe. id=%s", movieId)
 		}
 
-		pmci, err := m.getMovieCommentInfo(stub, movieId)
-		if err != nil {
-			return nil, mglogger.Errorf("Invoke(setMovieComment) getMovieCommentInfo failed. id=%s, err=%s", movieId, err)
-		}
-		var mci MovieCommentInfo
-		if pmci == nil {
-			mci.MovieId = movieId
-			mci.CCI = append(mci.CCI, CommentatorCommentInfo{
-				Commentator: commentator,
-				Comments:    append([]CommentInfo{}, CommentInfo{Comment: comment, FavourateCnt: favourateCnt, UpdateTime: ifas.InvokeTime})})
-		} else {
-			mci = *pmci
-			var matched = false
-			for _, cci := range mci.CCI {
-				if cci.Commentator == commentator {
-					matched = true
-					cci.Comments = append(cci.Comments, CommentInfo{Comment: comment, FavourateCnt: favourateCnt, UpdateTime: ifas.InvokeTime})
-					break
-				}
-			}
-			if !matched {
-				mci.CCI = append(mci.CCI, CommentatorCommentInfo{
-					Commentator: commentator,
-					Comments:    append([]CommentInfo{}, CommentInfo{Comment: comment, FavourateCnt: favourateCnt, UpdateTime: ifas.InvokeTime})})
-			}
-		}
-
-		mciJson, err := json.Marshal(mci)
+		//每条评论单独存储，避免同一影片的评论并发写同一个key
+		var mc MovieComment
+		mc.CommentId = stub.GetTxID()
+		mc.MovieId = movieId
+		mc.Commentator = commentator
+		mc.Comment = comment
+		mc.CmtTime = ifas.InvokeTime
+		mc.FavourateCnt = favourateCnt
+		mc.UpdateTime = ifas.InvokeTime
+
+		mcJson, err := json.Marshal(mc)
 		if err != nil {
 			return nil, mglogger.Errorf("Invoke(setMovieComment) Marshal failed. id=%s, err=%s", movieId, err)
 		}
 
-		err = Base.putState_Ex(stub, m.getMovieCommentInfoKey(movieId), mciJson)
+		err = stateCache.PutState_Ex(stub, m.getMovieCommentKey(movieId, mc.CommentId), mcJson)
 		if err != nil {
 			return nil, mglogger.Errorf("Invoke(setMovieComment) putState_Ex failed, err=%s.", err)
 		}
 
-		return nil, nil
+		return mcJson, nil
 
-	} else if function == "setMovieIncomeAllocRate" {
-		var argCount = fixedArgCount + 5
+	} else if function == "likeComment" { //给评论点赞，每个账户对同一条评论只能点赞一次
+		var argCount = fixedArgCount + 2
 		if len(args) < argCount {
-			return nil, mglogger.Errorf("Invoke(setMovieIncomeAllocRate) miss arg, got %d, at least need %d.", len(args), argCount)
+			return nil, mglogger.Errorf("Invoke(likeComment) miss arg, got %d, at least need %d.", len(args), argCount)
 		}
 
 		var movieId = args[fixedArgCount]
-		uploaderRate, err := strconv.Atoi(args[fixedArgCount+1])
+		var commentId = args[fixedArgCount+1]
+
+		pmc, err := m.getMovieComment(stub, movieId, commentId)
 		if err != nil {
-			return nil, mglogger.Errorf("Invoke(setMovieIncomeAllocRate) convert uploaderRate(%s) failed, err=%s.", args[fixedArgCount+1], err)
+			return nil, mglogger.Errorf("Invoke(likeComment) getMovieComment failed. id=%s, cid=%s, err=%s", movieId, commentId, err)
+		}
+		if pmc == nil {
+			return nil, mglogger.Errorf("Invoke(likeComment) comment not exists. id=%s, cid=%s", movieId, commentId)
 		}
-		commentatorRate, err := strconv.Atoi(args[fixedArgCount+2])
+		if pmc.Commentator == accName {
+			return nil, mglogger.Errorf("Invoke(likeComment) can't like own comment. id=%s, cid=%s", movieId, commentId)
+		}
+
+		var likeKey = m.getCommentLikeKey(commentId, accName)
+		likeB, err := stateCache.GetState_Ex(stub, likeKey)
 		if err != nil {
-			return nil, mglogger.Errorf("Invoke(setMovieIncomeAllocRate) convert commentatorRate(%s) failed, err=%s.", args[fixedArgCount+2], err)
+			return nil, mglogger.Errorf("Invoke(likeComment) GetState failed. cid=%s, err=%s", commentId, err)
 		}
-		platformRate, err := strconv.Atoi(args[fixedArgCount+3])
+		if likeB != nil {
+			return nil, mglogger.Errorf("Invoke(likeComment) %s already liked comment %s.", accName, commentId)
+		}
+
+		err = stateCache.PutState_Ex(stub, likeKey, []byte(strconv.FormatInt(ifas.InvokeTime, 10)))
 		if err != nil {
-			return nil, mglogger.Errorf("Invoke(setMovieIncomeAllocRate) convert platformRate(%s) failed, err=%s.", args[fixedArgCount+3], err)
+			return nil, mglogger.Errorf("Invoke(likeComment) putState_Ex failed. cid=%s, err=%s", commentId, err)
+		}
+
+		return nil, nil
+
+	} else if function == "setMovieIncomeAllocRate" {
+		//该接口的globalFlag会把配置写到全局，容易误用，已由 setMovieAllocRate 和 setGlobalAllocRate 代替
+		return nil, mglogger.Errorf("Invoke(setMovieIncomeAllocRate) is retired, use setMovieAllocRate or setGlobalAllocRate.")
+
+	} else if function == "setMovieAllocRate" || function == "setGlobalAllocRate" { //设置单部影片或全局的收入分成比例，万分比，三者之和必须为10000
+		var movieId = MOVIE_GLOBAL_ID
+		var rateIdx = fixedArgCount
+		if function == "setMovieAllocRate" {
+			rateIdx++
+		}
+		var argCount = rateIdx + 3
+		if len(args) < argCount {
+			return nil, mglogger.Errorf("Invoke(%s) miss arg, got %d, at least need %d.", function, len(args), argCount)
+		}
+
+		if !Base.IsAdmin(stub, accName) {
+			return nil, mglogger.Errorf("Invoke can't exec %s by %s.", function, accName)
+		}
+
+		if function == "setMovieAllocRate" {
+			movieId = args[fixedArgCount]
+			if movieId == MOVIE_GLOBAL_ID {
+				return nil, mglogger.Errorf("Invoke(setMovieAllocRate) invalid movie id %s.", movieId)
+			}
+			pmi, err := m.getMovieInfo(stub, movieId)
+			if err != nil {
+				return nil, mglogger.Errorf("Invoke(setMovieAllocRate) getMovieInfo failed. id=%s, err=%s", movieId, err)
+			}
+			if pmi == nil {
+				return nil, mglogger.Errorf("Invoke(setMovieAllocRate) movie not registe. id=%s", movieId)
+			}
 		}
 
-		var globalFlag = args[fixedArgCount+4]
-		if len(globalFlag) > 0 {
-			movieId = MOVIE_GLOBAL_ID
+		rates, err := m.parseBpRates(args[rateIdx : rateIdx+3])
+		if err != nil {
+			return nil, mglogger.Errorf("Invoke(%s) parseBpRates failed, err=%s.", function, err)
 		}
 
 		var miar MovieIncomeAllocRateCfg
 		miar.MovieId = movieId
 		miar.UpdateTime = ifas.InvokeTime
-		miar.UploaderRate = uploaderRate
-		miar.CommentatorsRate = commentatorRate
-		miar.PlatformRate = platformRate
+		miar.UploaderRate = rates[0]
+		miar.CommentatorsRate = rates[1]
+		miar.PlatformRate = rates[2]
+		miar.RateBase = RATE_BASE_BP
 
 		miarJson, err := json.Marshal(miar)
 		if err != nil {
-			return nil, mglogger.Errorf("Invoke(setMovieIncomeAllocRate) Marshal failed. id=%s, err=%s", movieId, err)
+			return nil, mglogger.Errorf("Invoke(%s) Marshal failed. id=%s, err=%s", function, movieId, err)
 		}
 
-		err = Base.putState_Ex(stub, m.getMovieIncomeAllocRateKey(movieId), miarJson)
+		err = stateCache.PutState_Ex(stub, m.getMovieIncomeAllocRateKey(movieId), miarJson)
 		if err != nil {
-			return nil, mglogger.Errorf("Invoke(setMovieIncomeAllocRate) putState_Ex failed, err=%s.", err)
+			return nil, mglogger.Errorf("Invoke(%s) putState_Ex failed, err=%s.", function, err)
 		}
 
-		return nil, nil
+		return miarJson, nil
+
+	} else if function == "delMovieAllocRate" { //删除单部影片的分成比例，之后使用全局配置
+		var argCount = fixedArgCount + 1
+		if len(args) < argCount {
+			return nil, mglogger.Errorf("Invoke(delMovieAllocRate) miss arg, got %d, at least need %d.", len(args), argCount)
+		}
+
+		if !Base.IsAdmin(stub, accName) {
+			return nil, mglogger.Errorf("Invoke can't exec delMovieAllocRate by %s.", accName)
+		}
 
-	} else if function == "computeMovieIncome" {
 		var movieId = args[fixedArgCount]
-		income, err := strconv.ParseInt(args[fixedArgCount+1], 0, 64)
-		if err != nil {
-			return nil, mglogger.Errorf("Invoke(computeMovieIncome) convert income(%s) failed, err=%s.", args[fixedArgCount+1], err)
+		if movieId == MOVIE_GLOBAL_ID {
+			return nil, mglogger.Errorf("Invoke(delMovieAllocRate) can't delete global config.")
 		}
 
-		pmci, err := m.getMovieCommentInfo(stub, movieId)
+		err := stub.DelState(m.getMovieIncomeAllocRateKey(movieId))
 		if err != nil {
-			return nil, mglogger.Errorf("Invoke(computeMovieIncome) getMovieCommentInfo failed. id=%s, err=%s", movieId, err)
+			return nil, mglogger.Errorf("Invoke(delMovieAllocRate) DelState failed. id=%s, err=%s", movieId, err)
+		}
+
+		return nil, nil
+
+	} else if function == "setCmtrAllocRate" { //设置评论人分成中固定部分和点赞部分的比例，万分比，二者之和必须为10000
+		var argCount = fixedArgCount + 2
+		if len(args) < argCount {
+			return nil, mglogger.Errorf("Invoke(setCmtrAllocRate) miss arg, got %d, at least need %d.", len(args), argCount)
+		}
+
+		if !Base.IsAdmin(stub, accName) {
+			return nil, mglogger.Errorf("Invoke can't exec setCmtrAllocRate by %s.", accName)
 		}
-		if pmci == nil {
-			return nil, mglogger.Errorf("Invoke(computeMovieIncome) getMovieCommentInfo nil. id=%s", movieId)
+
+		rates, err := m.parseBpRates(args[fixedArgCount : fixedArgCount+2])
+		if err != nil {
+			return nil, mglogger.Errorf("Invoke(setCmtrAllocRate) parseBpRates failed, err=%s.", err)
 		}
 
 		mgc, err := m.getMovieGlobalCfg(stub)
 		if err != nil {
-			return nil, mglogger.Errorf("Invoke(computeMovieIncome) getMovieGlobalCfg failed. id=%s, err=%s", movieId, err)
+			return nil, mglogger.Errorf("Invoke(setCmtrAllocRate) getMovieGlobalCfg failed. err=%s", err)
 		}
 		if mgc == nil {
-			return nil, mglogger.Errorf("Invoke(computeMovieIncome) getMovieGlobalCfg nil. id=%s", movieId)
+			mgc = &MovieGlobalCfg{}
 		}
 
-		pmiar, err := m.getMovieIncomeAllocRate(stub, movieId)
+		mgc.CIAR.FixedRate = rates[0]
+		mgc.CIAR.FavourateRate = rates[1]
+		mgc.CIAR.RateBase = RATE_BASE_BP
+		mgc.CIAR.UpdateTime = ifas.InvokeTime
+
+		mgcJson, err := json.Marshal(mgc)
 		if err != nil {
-			return nil, mglogger.Errorf("Invoke(computeMovieIncome) getMovieIncomeAllocRate failed, id=%s, err=%s.", movieId, err)
+			return nil, mglogger.Errorf("Invoke(setCmtrAllocRate) Marshal failed. err=%s", err)
 		}
-		if pmiar == nil {
-			mglogger.Info("Invoke(computeMovieIncome) getMovieIncomeAllocRate nil, try to get global.")
-			//do not use ":=" here
-			pmiar, err = m.getMovieIncomeAllocRate(stub, MOVIE_GLOBAL_ID)
-			if err != nil {
-				return nil, mglogger.Errorf("Invoke(computeMovieIncome) getMovieIncomeAllocRate global failed, id=%s, err=%s.", movieId, err)
-			}
-			if pmiar == nil {
-				return nil, mglogger.Errorf("Invoke(computeMovieIncome) getMovieIncomeAllocRate global nil, id=%s, err=%s.", movieId, err)
-			}
+		err = stateCache.PutState_Ex(stub, MOVIE_GLOBAL_CFG_KEY, mgcJson)
+		if err != nil {
+			return nil, mglogger.Errorf("Invoke(setCmtrAllocRate) putState_Ex failed. err=%s", err)
 		}
 
-		var base = int64(pmiar.PlatformRate + pmiar.UploaderRate + pmiar.CommentatorsRate)
-		var uploaderIncome = income * int64(pmiar.UploaderRate) / base
-		var commentatorsIncome = income * int64(pmiar.CommentatorsRate) / base
-		var platformIncome = income - uploaderIncome - commentatorsIncome
+		return mgcJson, nil
 
-		var CommentatorCmtFavourateMap = make(map[string]int64)
-		var CommentatorIncomeMap = make(map[string]int64)
-		var totalFavourate int64 = 0
-		var totalCommentator int = 0
-		for _, cci := range pmci.CCI {
-			CommentatorCmtFavourateMap[cci.Commentator] = 0
-			totalCommentator++
-			for _, cmt := range cci.Comments {
-				CommentatorCmtFavourateMap[cci.Commentator] += cmt.FavourateCnt
-				totalFavourate += cmt.FavourateCnt
-			}
+	} else if function == "computeMovieIncome" {
+		var argCount = fixedArgCount + 2
+		if len(args) < argCount {
+			return nil, mglogger.Errorf("Invoke(computeMovieIncome) miss arg, got %d, at least need %d.", len(args), argCount)
 		}
 
-		var cmtrTotalIncome int64 = 0
-		var fixedIncomeTotal = commentatorsIncome * int64(mgc.CIAR.FixedRate) / int64(mgc.CIAR.FixedRate+mgc.CIAR.FavourateRate)
-		var favourateIncomeTotal = commentatorsIncome - fixedIncomeTotal
-		var fixedIncome = fixedIncomeTotal / int64(totalCommentator)
+		var movieId = args[fixedArgCount]
+		income, err := strconv.ParseInt(args[fixedArgCount+1], 0, 64)
+		if err != nil {
+			return nil, mglogger.Errorf("Invoke(computeMovieIncome) convert income(%s) failed, err=%s.", args[fixedArgCount+1], err)
+		}
 
-		for cmtr, fav := range CommentatorCmtFavourateMap {
-			var cmtrIncome = fixedIncome + fav*favourateIncomeTotal/totalFavourate
-			CommentatorIncomeMap[cmtr] = cmtrIncome
-			cmtrTotalIncome += cmtrIncome
+		//结算模式：指定了付款账户和平台账户时，计算完后直接从付款账户转账给各方。付款账户只能是调用者自己的账户
+		var settle = false
+		var payerAcc string
+		var platformAcc string
+		//最后一个参数为签名
+		if len(args) > argCount+2 && len(args[argCount]) > 0 {
+			if len(args[argCount+1]) == 0 {
+				return nil, mglogger.Errorf("Invoke(computeMovieIncome) settle mode need platform account.")
+			}
+			settle = true
+			payerAcc = args[argCount]
+			platformAcc = args[argCount+1]
 		}
 
-		if cmtrTotalIncome > commentatorsIncome {
-			return nil, mglogger.Errorf("Invoke(computeMovieIncome) something wrong?(%d, %d). id=%s", cmtrTotalIncome, commentatorsIncome, movieId)
-		} else {
-			platformIncome += commentatorsIncome - cmtrTotalIncome
+		if settle && !Base.IsAdmin(stub, accName) {
+			return nil, mglogger.Errorf("Invoke can't exec computeMovieIncome(settle) by %s.", accName)
 		}
 
-		if uploaderIncome+cmtrTotalIncome+platformIncome != income {
-			return nil, mglogger.Errorf("Invoke(computeMovieIncome) something wrong2?(%d, %d, %d). id=%s", uploaderIncome, cmtrTotalIncome, platformIncome, movieId)
+		if income < 0 {
+			return nil, mglogger.Errorf("Invoke(computeMovieIncome) invalid income(%d).", income)
 		}
 
-		var mit MovieIncomeTx
-		mit.MovieId = movieId
-		mit.DateTime = ifas.InvokeTime
-		mit.TotalIncome = income
-		mit.UploaderIncome = uploaderIncome
-		mit.CommentatorsIncome = CommentatorIncomeMap
-		mit.PlatformIncome = platformIncome
-		mit.UploaderRate = pmiar.UploaderRate
-		mit.CommentatorsRate = pmiar.CommentatorsRate
-		mit.PlatformRate = pmiar.PlatformRate
-		mit.CommentatorFavourate = CommentatorCmtFavourateMap
-		mit.CommentatorFixedRate = mgc.CIAR.FixedRate
-		mit.CommentatorFavourateRate = mgc.CIAR.FavourateRate
+		mit, err := m.computeMovieIncome(stub, movieId, income, ifas.InvokeTime)
+		if err != nil {
+			return nil, mglogger.Errorf("Invoke(computeMovieIncome) computeMovieIncome failed. id=%s, err=%s", movieId, err)
+		}
+
+		if settle {
+			err = m.settleMovieIncome(stub, mit, accName, payerAcc, platformAcc, ifas.InvokeTime)
+			if err != nil {
+				return nil, mglogger.Errorf("Invoke(computeMovieIncome) settleMovieIncome failed. id=%s, err=%s", movieId, err)
+			}
+		}
 
-		seqKey := m.getAllocTxSeqKey(movieId)
-		seq, err := Base.getTransSeq(stub, seqKey)
+		mitJson, err := m.setMovieIncomeTx(stub, mit)
 		if err != nil {
-			return nil, mglogger.Errorf("Invoke(computeMovieIncome) getTransSeq failed. id=%s, err=%s", movieId, err)
+			return nil, mglogger.Errorf("Invoke(computeMovieIncome) setMovieIncomeTx failed. id=%s, err=%s", movieId, err)
+		}
+
+		return mitJson, nil
+
+	} else if function == "settleMovieIncome" { //结算一条已计算的影片收入分成记录
+		var argCount = fixedArgCount + 4
+		if len(args) < argCount {
+			return nil, mglogger.Errorf("Invoke(settleMovieIncome) miss arg, got %d, at least need %d.", len(args), argCount)
 		}
-		seq++
-		err = Base.setTransSeq(stub, seqKey, seq)
+
+		if !Base.IsAdmin(stub, accName) {
+			return nil, mglogger.Errorf("Invoke can't exec settleMovieIncome by %s.", accName)
+		}
+
+		var movieId = args[fixedArgCount]
+		seq, err := strconv.ParseInt(args[fixedArgCount+1], 0, 64)
 		if err != nil {
-			return nil, mglogger.Errorf("Invoke(computeMovieIncome) setTransSeq failed. id=%s, err=%s", movieId, err)
+			return nil, mglogger.Errorf("Invoke(settleMovieIncome) convert serial(%s) failed, err=%s.", args[fixedArgCount+1], err)
 		}
+		var payerAcc = args[fixedArgCount+2] //只能是调用者自己的账户
+		var platformAcc = args[fixedArgCount+3]
 
-		mit.GlobalSerial = seq
+		mit, err := m.getMovieIncomeTx(stub, movieId, seq)
+		if err != nil {
+			return nil, mglogger.Errorf("Invoke(settleMovieIncome) getMovieIncomeTx failed. id=%s, seq=%d, err=%s", movieId, seq, err)
+		}
+		if mit == nil {
+			return nil, mglogger.Errorf("Invoke(settleMovieIncome) income record not exists. id=%s, seq=%d", movieId, seq)
+		}
 
-		mitJson, err := json.Marshal(mit)
+		err = m.settleMovieIncome(stub, mit, accName, payerAcc, platformAcc, ifas.InvokeTime)
 		if err != nil {
-			return nil, mglogger.Errorf("Invoke(computeMovieIncome) Marshal failed. id=%s, err=%s", movieId, err)
+			return nil, mglogger.Errorf("Invoke(settleMovieIncome) settleMovieIncome failed. id=%s, seq=%d, err=%s", movieId, seq, err)
 		}
 
-		var txKey = m.getAllocTxKey(movieId, seq)
-		err = Base.putState_Ex(stub, txKey, mitJson)
+		mitJson, err := m.setMovieIncomeTx(stub, mit)
 		if err != nil {
-			return nil, mglogger.Errorf("Invoke(computeMovieIncome) putState_Ex failed. id=%s, err=%s", movieId, err)
+			return nil, mglogger.Errorf("Invoke(settleMovieIncome) setMovieIncomeTx failed. id=%s, seq=%d, err=%s", movieId, seq, err)
 		}
 
 		return mitJson, nil
+
 	} else {
 
 		//其它函数看是否是query函数
@@ -492,10 +686,10 @@ func (m *MOGAO) Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([
 
 	//var err error
 
-	//var fixedArgCount = ifas.argCount
+	var fixedArgCount = ifas.FixedArgCount
 
 	//var userName = args[0]
-	//var accName = args[1]
+	var accName = ifas.AccountName
 	/*
 			//	var queryTime int64 = 0
 
@@ -507,8 +701,100 @@ func (m *MOGAO) Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([
 		var accountEnt *AccountEntity = nil
 	*/
 
-	if function == "XXXX" {
-		return nil, nil
+	if function == "getMovieInfo" { //查询影片信息
+		var argCount = fixedArgCount + 1
+		if len(args) < argCount {
+			return nil, mglogger.Errorf("Query(getMovieInfo) miss arg, got %d, at least need %d.", len(args), argCount)
+		}
+		var movieId = args[fixedArgCount]
+
+		pmi, err := m.getMovieInfo(stub, movieId)
+		if err != nil {
+			return nil, mglogger.Errorf("Query(getMovieInfo) getMovieInfo failed. id=%s, err=%s", movieId, err)
+		}
+		if pmi == nil {
+			return nil, mglogger.Errorf("Query(getMovieInfo) movie not registe. id=%s", movieId)
+		}
+
+		miJson, err := json.Marshal(pmi)
+		if err != nil {
+			return nil, mglogger.Errorf("Query(getMovieInfo) Marshal failed. id=%s, err=%s", movieId, err)
+		}
+		return miJson, nil
+
+	} else if function == "getMovieComments" { //分页查询影片的评论
+		var argCount = fixedArgCount + 4
+		if len(args) < argCount {
+			return nil, mglogger.Errorf("Query(getMovieComments) miss arg, got %d, at least need %d.", len(args), argCount)
+		}
+		var movieId = args[fixedArgCount]
+		var commentator = args[fixedArgCount+1] //为空或"*"时查询所有评论人的评论
+		begIdx, err := strconv.ParseInt(args[fixedArgCount+2], 0, 64)
+		if err != nil {
+			return nil, mglogger.Errorf("Query(getMovieComments) convert begIdx(%s) failed. err=%s", args[fixedArgCount+2], err)
+		}
+		count, err := strconv.ParseInt(args[fixedArgCount+3], 0, 64)
+		if err != nil {
+			return nil, mglogger.Errorf("Query(getMovieComments) convert count(%s) failed. err=%s", args[fixedArgCount+3], err)
+		}
+		if commentator == "*" {
+			commentator = ""
+		}
+
+		return m.queryMovieComments(stub, movieId, commentator, begIdx, count)
+
+	} else if function == "getMovieAllocRate" { //查询影片生效的分成比例配置
+		var argCount = fixedArgCount + 1
+		if len(args) < argCount {
+			return nil, mglogger.Errorf("Query(getMovieAllocRate) miss arg, got %d, at least need %d.", len(args), argCount)
+		}
+		var movieId = args[fixedArgCount]
+
+		return m.queryMovieAllocRate(stub, movieId)
+
+	} else if function == "getMovieIncomeTxs" { //按序列号和时间范围查询影片的收入分成记录
+		var argCount = fixedArgCount + 6
+		if len(args) < argCount {
+			return nil, mglogger.Errorf("Query(getMovieIncomeTxs) miss arg, got %d, at least need %d.", len(args), argCount)
+		}
+		var movieId = args[fixedArgCount]
+		begSeq, count, begTime, endTime, err := m.parseSeqTimeRangeArgs(args[fixedArgCount+1 : fixedArgCount+5])
+		if err != nil {
+			return nil, mglogger.Errorf("Query(getMovieIncomeTxs) parse args failed. err=%s", err)
+		}
+		var isAsc = args[fixedArgCount+5] == "asc"
+
+		//只有管理员和影片上传人可以查询
+		if !Base.IsAdmin(stub, accName) {
+			pmi, err := m.getMovieInfo(stub, movieId)
+			if err != nil {
+				return nil, mglogger.Errorf("Query(getMovieIncomeTxs) getMovieInfo failed. id=%s, err=%s", movieId, err)
+			}
+			if pmi == nil || pmi.Uploader != accName {
+				return nil, mglogger.Errorf("Query(getMovieIncomeTxs) %s can't query movie %s.", accName, movieId)
+			}
+		}
+
+		return m.queryMovieIncomeTxs(stub, movieId, begSeq, count, begTime, endTime, isAsc)
+
+	} else if function == "getCmtrEarnings" { //查询评论人在所有影片中的收入
+		var argCount = fixedArgCount + 5
+		if len(args) < argCount {
+			return nil, mglogger.Errorf("Query(getCmtrEarnings) miss arg, got %d, at least need %d.", len(args), argCount)
+		}
+		var commentator = args[fixedArgCount] //为空时查询自己
+		begSeq, count, begTime, endTime, err := m.parseSeqTimeRangeArgs(args[fixedArgCount+1 : fixedArgCount+5])
+		if err != nil {
+			return nil, mglogger.Errorf("Query(getCmtrEarnings) parse args failed. err=%s", err)
+		}
+
+		if len(commentator) == 0 {
+			commentator = accName
+		} else if commentator != accName && !Base.IsAdmin(stub, accName) {
+			return nil, mglogger.Errorf("Query(getCmtrEarnings) %s can't query %s.", accName, commentator)
+		}
+
+		return m.queryCmtrEarnings(stub, commentator, begSeq, count, begTime, endTime)
 
 	} else {
 		return nil, mglogger.Errorf("unknown function:%s.", function)
@@ -523,12 +809,20 @@ func (m *MOGAO) getMovieCommentInfoKey(movieId string) string {
 	return MOVIE_COMMENT_INFO_PREFIX + movieId
 }
 
+func (m *MOGAO) getMovieCommentKey(movieId, commentId string) string {
+	return MOVIE_COMMENT_PREFIX + movieId + MOVIE_KEY_DELIM + commentId
+}
+
+func (m *MOGAO) getCommentLikeKey(commentId, accName string) string {
+	return MOVIE_COMMENT_LIKE_PREFIX + commentId + MOVIE_KEY_DELIM + accName
+}
+
 func (m *MOGAO) getMovieIncomeAllocRateKey(movieId string) string {
 	return MOVIE_INCOME_ALLOC_RATE_PREFIX + movieId
 }
 
 func (m *MOGAO) getMovieInfo(stub shim.ChaincodeStubInterface, movieId string) (*MovieInfo, error) {
-	movieBytes, err := stub.GetState(m.getMovieInfoKey(movieId))
+	movieBytes, err := stateCache.GetState_Ex(stub, m.getMovieInfoKey(movieId))
 	if err != nil {
 		return nil, mglogger.Errorf("getMovieInfo: GetState failed, id=%s err=%s.", movieId, err)
 	}
@@ -545,7 +839,7 @@ func (m *MOGAO) getMovieInfo(stub shim.ChaincodeStubInterface, movieId string) (
 	return &mi, nil
 }
 func (m *MOGAO) getMovieCommentInfo(stub shim.ChaincodeStubInterface, movieId string) (*MovieCommentInfo, error) {
-	movieCmtBytes, err := stub.GetState(m.getMovieCommentInfoKey(movieId))
+	movieCmtBytes, err := stateCache.GetState_Ex(stub, m.getMovieCommentInfoKey(movieId))
 	if err != nil {
 		return nil, mglogger.Errorf("getMovieCommentInfo: GetState failed, id=%s err=%s.", movieId, err)
 	}
@@ -562,7 +856,7 @@ func (m *MOGAO) getMovieCommentInfo(stub shim.ChaincodeStubInterface, movieId st
 	return &mci, nil
 }
 func (m *MOGAO) getMovieIncomeAllocRate(stub shim.ChaincodeStubInterface, movieId string) (*MovieIncomeAllocRateCfg, error) {
-	movieIARBytes, err := stub.GetState(m.getMovieIncomeAllocRateKey(movieId))
+	movieIARBytes, err := stateCache.GetState_Ex(stub, m.getMovieIncomeAllocRateKey(movieId))
 	if err != nil {
 		return nil, mglogger.Errorf("getMovieIncomeAllocRate: GetState failed, id=%s err=%s.", movieId, err)
 	}
@@ -580,7 +874,7 @@ func (m *MOGAO) getMovieIncomeAllocRate(stub shim.ChaincodeStubInterface, movieI
 }
 
 func (m *MOGAO) getMovieGlobalCfg(stub shim.ChaincodeStubInterface) (*MovieGlobalCfg, error) {
-	movieGlbBytes, err := stub.GetState(MOVIE_GLOBAL_CFG_KEY)
+	movieGlbBytes, err := stateCache.GetState_Ex(stub, MOVIE_GLOBAL_CFG_KEY)
 	if err != nil {
 		return nil, mglogger.Errorf("getMovieGlobalCfg: GetState failed, err=%s.", err)
 	}
@@ -609,3 +903,767 @@ func (m *MOGAO) getAllocTxKey(movieid string, seq int64) string {
 	buf.WriteString(strconv.FormatInt(seq, 10))
 	return buf.String()
 }
+
+//计算影片收入分成，生成分成记录（不保存）
+func (m *MOGAO) computeMovieIncome(stub shim.ChaincodeStubInterface, movieId string, income, invokeTime int64) (*MovieIncomeTx, error) {
+	mcList, err := m.getMovieComments(stub, movieId)
+	if err != nil {
+		return nil, mglogger.Errorf("computeMovieIncome: getMovieComments failed. id=%s, err=%s", movieId, err)
+	}
+
+	mgc, err := m.getMovieGlobalCfg(stub)
+	if err != nil {
+		return nil, mglogger.Errorf("computeMovieIncome: getMovieGlobalCfg failed. id=%s, err=%s", movieId, err)
+	}
+	if mgc == nil {
+		return nil, mglogger.Errorf("computeMovieIncome: getMovieGlobalCfg nil. id=%s", movieId)
+	}
+
+	pmiar, err := m.getMovieIncomeAllocRate(stub, movieId)
+	if err != nil {
+		return nil, mglogger.Errorf("computeMovieIncome: getMovieIncomeAllocRate failed, id=%s, err=%s.", movieId, err)
+	}
+	if pmiar == nil {
+		mglogger.Info("computeMovieIncome: getMovieIncomeAllocRate nil, try to get global.")
+		//do not use ":=" here
+		pmiar, err = m.getMovieIncomeAllocRate(stub, MOVIE_GLOBAL_ID)
+		if err != nil {
+			return nil, mglogger.Errorf("computeMovieIncome: getMovieIncomeAllocRate global failed, id=%s, err=%s.", movieId, err)
+		}
+		if pmiar == nil {
+			return nil, mglogger.Errorf("computeMovieIncome: getMovieIncomeAllocRate global nil, id=%s, err=%s.", movieId, err)
+		}
+	}
+
+	var base = int64(pmiar.RateBase)
+	if base <= 0 {
+		base = int64(pmiar.PlatformRate + pmiar.UploaderRate + pmiar.CommentatorsRate)
+	}
+	if base <= 0 {
+		return nil, mglogger.Errorf("computeMovieIncome: invalid alloc rate(%+v). id=%s", *pmiar, movieId)
+	}
+	var cmtrBase = int64(mgc.CIAR.RateBase)
+	if cmtrBase <= 0 {
+		cmtrBase = int64(mgc.CIAR.FixedRate + mgc.CIAR.FavourateRate)
+	}
+	if cmtrBase <= 0 {
+		return nil, mglogger.Errorf("computeMovieIncome: invalid commentator alloc rate(%+v). id=%s", mgc.CIAR, movieId)
+	}
+
+	var uploaderIncome = income * int64(pmiar.UploaderRate) / base
+	var commentatorsIncome = income * int64(pmiar.CommentatorsRate) / base
+	var platformIncome = income - uploaderIncome - commentatorsIncome
+
+	var CommentatorCmtFavourateMap = make(map[string]int64)
+	var CommentatorIncomeMap = make(map[string]int64)
+	var totalFavourate int64 = 0
+	var totalCommentator int = 0
+	for _, mc := range mcList {
+		if _, ok := CommentatorCmtFavourateMap[mc.Commentator]; !ok {
+			CommentatorCmtFavourateMap[mc.Commentator] = 0
+			totalCommentator++
+		}
+		CommentatorCmtFavourateMap[mc.Commentator] += mc.FavourateCnt
+		totalFavourate += mc.FavourateCnt
+	}
+
+	var cmtrTotalIncome int64 = 0
+	//没有评论人时，评论人的分成全部归平台（在下面的剩余部分中加给平台）
+	if totalCommentator > 0 {
+		var fixedIncomeTotal = commentatorsIncome * int64(mgc.CIAR.FixedRate) / cmtrBase
+		var favourateIncomeTotal = commentatorsIncome - fixedIncomeTotal
+
+		//所有评论都没有点赞时，按点赞分成的部分也平分给各评论人
+		if totalFavourate <= 0 {
+			fixedIncomeTotal = commentatorsIncome
+			favourateIncomeTotal = 0
+		}
+		var fixedIncome = fixedIncomeTotal / int64(totalCommentator)
+
+		for cmtr, fav := range CommentatorCmtFavourateMap {
+			var cmtrIncome = fixedIncome
+			if totalFavourate > 0 {
+				cmtrIncome += fav * favourateIncomeTotal / totalFavourate
+			}
+			CommentatorIncomeMap[cmtr] = cmtrIncome
+			cmtrTotalIncome += cmtrIncome
+		}
+	}
+
+	//除不尽的部分归平台
+	if cmtrTotalIncome > commentatorsIncome {
+		return nil, mglogger.Errorf("computeMovieIncome: something wrong?(%d, %d). id=%s", cmtrTotalIncome, commentatorsIncome, movieId)
+	} else {
+		platformIncome += commentatorsIncome - cmtrTotalIncome
+	}
+
+	if uploaderIncome+cmtrTotalIncome+platformIncome != income {
+		return nil, mglogger.Errorf("computeMovieIncome: something wrong2?(%d, %d, %d). id=%s", uploaderIncome, cmtrTotalIncome, platformIncome, movieId)
+	}
+
+	var mit MovieIncomeTx
+	mit.MovieId = movieId
+	mit.DateTime = invokeTime
+	mit.TotalIncome = income
+	mit.UploaderIncome = uploaderIncome
+	mit.CommentatorsIncome = CommentatorIncomeMap
+	mit.PlatformIncome = platformIncome
+	mit.UploaderRate = pmiar.UploaderRate
+	mit.CommentatorsRate = pmiar.CommentatorsRate
+	mit.PlatformRate = pmiar.PlatformRate
+	mit.CommentatorFavourate = CommentatorCmtFavourateMap
+	mit.CommentatorFixedRate = mgc.CIAR.FixedRate
+	mit.CommentatorFavourateRate = mgc.CIAR.FavourateRate
+	mit.RateBase = int(base)
+	mit.CmtrRateBase = int(cmtrBase)
+	mit.NoCommentator = totalCommentator == 0
+	mit.NoFavourate = totalCommentator > 0 && totalFavourate <= 0
+
+	seqKey := m.getAllocTxSeqKey(movieId)
+	seq, err := m.getTransSeq(stub, seqKey)
+	if err != nil {
+		return nil, mglogger.Errorf("computeMovieIncome: getTransSeq failed. id=%s, err=%s", movieId, err)
+	}
+	seq++
+	err = m.setTransSeq(stub, seqKey, seq)
+	if err != nil {
+		return nil, mglogger.Errorf("computeMovieIncome: setTransSeq failed. id=%s, err=%s", movieId, err)
+	}
+
+	mit.GlobalSerial = seq
+
+	//记录每个评论人的分成记录索引，供按评论人查询
+	var cmtrList []string
+	for cmtr, _ := range CommentatorIncomeMap {
+		cmtrList = append(cmtrList, cmtr)
+	}
+	sort.Strings(cmtrList)
+	for _, cmtr := range cmtrList {
+		err = m.setCmtrAllocTxIndex(stub, cmtr, m.getAllocTxKey(movieId, seq))
+		if err != nil {
+			return nil, mglogger.Errorf("computeMovieIncome: setCmtrAllocTxIndex failed. id=%s, cmtr=%s, err=%s", movieId, cmtr, err)
+		}
+	}
+
+	return &mit, nil
+}
+
+//结算影片收入分成，从付款账户转账给上传人、各评论人和平台。同一条分成记录只能结算一次
+//付款账户必须是操作者（已验证签名的调用账户）自己的账户，管理员也不能从别人的账户扣款
+func (m *MOGAO) settleMovieIncome(stub shim.ChaincodeStubInterface, mit *MovieIncomeTx, operator, payerAcc, platformAcc string, settleTime int64) error {
+	if payerAcc != operator {
+		return mglogger.Errorf("settleMovieIncome: payer(%s) is not the caller's own account(%s).", payerAcc, operator)
+	}
+	if mit.Settled {
+		return mglogger.Errorf("settleMovieIncome: income record already settled. id=%s, seq=%d", mit.MovieId, mit.GlobalSerial)
+	}
+
+	pmi, err := m.getMovieInfo(stub, mit.MovieId)
+	if err != nil {
+		return mglogger.Errorf("settleMovieIncome: getMovieInfo failed. id=%s, err=%s", mit.MovieId, err)
+	}
+	if pmi == nil {
+		return mglogger.Errorf("settleMovieIncome: movie not registe. id=%s", mit.MovieId)
+	}
+
+	var transType = m.getIncomeSettleTransType(mit.MovieId, mit.GlobalSerial)
+	var desc = "影片收入分成:" + mit.MovieId
+
+	_, err = m.transferCoin(stub, payerAcc, pmi.Uploader, transType, desc, mit.UploaderIncome, settleTime, false)
+	if err != nil {
+		return mglogger.Errorf("settleMovieIncome: transferCoin to uploader(%s) failed. err=%s", pmi.Uploader, err)
+	}
+
+	//排序，保证每个节点的转账顺序一致
+	var cmtrList []string
+	for cmtr, _ := range mit.CommentatorsIncome {
+		cmtrList = append(cmtrList, cmtr)
+	}
+	sort.Strings(cmtrList)
+
+	for _, cmtr := range cmtrList {
+		_, err = m.transferCoin(stub, payerAcc, cmtr, transType, desc, mit.CommentatorsIncome[cmtr], settleTime, false)
+		if err != nil {
+			return mglogger.Errorf("settleMovieIncome: transferCoin to commentator(%s) failed. err=%s", cmtr, err)
+		}
+	}
+
+	_, err = m.transferCoin(stub, payerAcc, platformAcc, transType, desc, mit.PlatformIncome, settleTime, false)
+	if err != nil {
+		return mglogger.Errorf("settleMovieIncome: transferCoin to platform(%s) failed. err=%s", platformAcc, err)
+	}
+
+	mit.Settled = true
+	mit.SettleTime = settleTime
+	mit.PayerAcc = payerAcc
+	mit.UploaderAcc = pmi.Uploader
+	mit.PlatformAcc = platformAcc
+	mit.SettleTransType = transType
+
+	return nil
+}
+
+func (m *MOGAO) getIncomeSettleTransType(movieId string, seq int64) string {
+	var buf = bytes.NewBufferString(MOVIE_INCOME_SETTLE_TRANSTYPE)
+	buf.WriteByte(MULTI_STRING_DELIM)
+	buf.WriteString(movieId)
+	buf.WriteByte(MULTI_STRING_DELIM)
+	buf.WriteString(strconv.FormatInt(seq, 10))
+	return buf.String()
+}
+
+func (m *MOGAO) setMovieIncomeTx(stub shim.ChaincodeStubInterface, mit *MovieIncomeTx) ([]byte, error) {
+	mitJson, err := json.Marshal(mit)
+	if err != nil {
+		return nil, mglogger.Errorf("setMovieIncomeTx: Marshal failed. id=%s, err=%s", mit.MovieId, err)
+	}
+
+	err = stateCache.PutState_Ex(stub, m.getAllocTxKey(mit.MovieId, mit.GlobalSerial), mitJson)
+	if err != nil {
+		return nil, mglogger.Errorf("setMovieIncomeTx: putState_Ex failed. id=%s, err=%s", mit.MovieId, err)
+	}
+
+	return mitJson, nil
+}
+
+func (m *MOGAO) getMovieIncomeTx(stub shim.ChaincodeStubInterface, movieId string, seq int64) (*MovieIncomeTx, error) {
+	mitBytes, err := stateCache.GetState_Ex(stub, m.getAllocTxKey(movieId, seq))
+	if err != nil {
+		return nil, mglogger.Errorf("getMovieIncomeTx: GetState failed, id=%s seq=%d err=%s.", movieId, seq, err)
+	}
+	if mitBytes == nil {
+		return nil, nil
+	}
+
+	var mit MovieIncomeTx
+	err = json.Unmarshal(mitBytes, &mit)
+	if err != nil {
+		return nil, mglogger.Errorf("getMovieIncomeTx: Unmarshal failed, id=%s seq=%d err=%s.", movieId, seq, err)
+	}
+
+	return &mit, nil
+}
+
+func (m *MOGAO) getCmtrAllocTxSeqKey(cmtr string) string {
+	return MOVIE_CMTR_ALLOCTX_SEQ_PREFIX + cmtr
+}
+
+func (m *MOGAO) getCmtrAllocTxKey(cmtr string, seq int64) string {
+	var buf = bytes.NewBufferString(MOVIE_CMTR_ALLOCTX_PREFIX)
+	buf.WriteString(cmtr)
+	buf.WriteString("_")
+	buf.WriteString(strconv.FormatInt(seq, 10))
+	return buf.String()
+}
+
+func (m *MOGAO) setCmtrAllocTxIndex(stub shim.ChaincodeStubInterface, cmtr, allocTxKey string) error {
+	seqKey := m.getCmtrAllocTxSeqKey(cmtr)
+	seq, err := m.getTransSeq(stub, seqKey)
+	if err != nil {
+		return mglogger.Errorf("setCmtrAllocTxIndex: getTransSeq failed. cmtr=%s, err=%s", cmtr, err)
+	}
+	seq++
+
+	err = stateCache.PutState_Ex(stub, m.getCmtrAllocTxKey(cmtr, seq), []byte(allocTxKey))
+	if err != nil {
+		return mglogger.Errorf("setCmtrAllocTxIndex: putState_Ex failed. cmtr=%s, err=%s", cmtr, err)
+	}
+
+	err = m.setTransSeq(stub, seqKey, seq)
+	if err != nil {
+		return mglogger.Errorf("setCmtrAllocTxIndex: setTransSeq failed. cmtr=%s, err=%s", cmtr, err)
+	}
+
+	return nil
+}
+
+//查询时获取序列号。getTransSeq在序列号不存在时会写入，query中不能写入，所以这里单独处理
+func (m *MOGAO) getSeqForQuery(stub shim.ChaincodeStubInterface, seqKey string) (int64, error) {
+	seqB, err := stateCache.GetState_Ex(stub, seqKey)
+	if err != nil {
+		return -1, mglogger.Errorf("getSeqForQuery: GetState failed. key=%s, err=%s", seqKey, err)
+	}
+	if seqB == nil {
+		return 0, nil
+	}
+
+	seq, err := strconv.ParseInt(string(seqB), 10, 64)
+	if err != nil {
+		return -1, mglogger.Errorf("getSeqForQuery: ParseInt failed. key=%s, err=%s", seqKey, err)
+	}
+	return seq, nil
+}
+
+//解析万分比的分成比例，每个比例不能为负数，且总和必须为RATE_BASE_BP
+func (m *MOGAO) parseBpRates(args []string) ([]int, error) {
+	var rates []int
+	var total = 0
+	for _, arg := range args {
+		rate, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, mglogger.Errorf("parseBpRates: convert rate(%s) failed, err=%s.", arg, err)
+		}
+		if rate < 0 || rate > RATE_BASE_BP {
+			return nil, mglogger.Errorf("parseBpRates: rate(%d) out of range [0,%d].", rate, RATE_BASE_BP)
+		}
+		total += rate
+		rates = append(rates, rate)
+	}
+
+	if total != RATE_BASE_BP {
+		return nil, mglogger.Errorf("parseBpRates: rates(%v) sum to %d, need %d.", rates, total, RATE_BASE_BP)
+	}
+
+	return rates, nil
+}
+
+//解析 begSeq, count, begTime, endTime 四个查询参数
+func (m *MOGAO) parseSeqTimeRangeArgs(args []string) (int64, int64, int64, int64, error) {
+	var vals [4]int64
+	var names = [4]string{"begSeq", "count", "begTime", "endTime"}
+	for i := 0; i < 4; i++ {
+		v, err := strconv.ParseInt(args[i], 0, 64)
+		if err != nil {
+			return 0, 0, 0, 0, mglogger.Errorf("parseSeqTimeRangeArgs: convert %s(%s) failed. err=%s", names[i], args[i], err)
+		}
+		vals[i] = v
+	}
+
+	//begSeq统一从1开始，防止调用者有的以0开始，有的以1开始
+	if vals[0] < 1 {
+		vals[0] = 1
+	}
+	//endTime为负数，查询到最新时间
+	if vals[3] < 0 {
+		vals[3] = math.MaxInt64
+	}
+
+	return vals[0], vals[1], vals[2], vals[3], nil
+}
+
+func (m *MOGAO) queryMovieComments(stub shim.ChaincodeStubInterface, movieId, commentator string, begIdx, count int64) ([]byte, error) {
+	var qmc QueryMovieComments
+	qmc.MovieId = movieId
+	qmc.NextIdx = -1
+	qmc.Comments = []QueryCommentRecd{} //初始化为空，即使没查到数据也会返回'[]'
+
+	//begIdx统一从1开始
+	if begIdx < 1 {
+		begIdx = 1
+	}
+
+	mcList, err := m.getMovieComments(stub, movieId)
+	if err != nil {
+		return nil, mglogger.Errorf("queryMovieComments: getMovieComments failed. id=%s, err=%s", movieId, err)
+	}
+
+	var idx int64 = 0
+	for _, mc := range mcList {
+		if len(commentator) > 0 && mc.Commentator != commentator {
+			continue
+		}
+		idx++
+		if idx < begIdx {
+			continue
+		}
+		//count为负数时返回所有
+		if count >= 0 && int64(len(qmc.Comments)) >= count {
+			if qmc.NextIdx < 0 {
+				qmc.NextIdx = idx
+			}
+			continue
+		}
+		qmc.Comments = append(qmc.Comments, QueryCommentRecd{Idx: idx, MovieComment: mc})
+	}
+	qmc.Total = idx
+
+	qmcB, err := json.Marshal(qmc)
+	if err != nil {
+		return nil, mglogger.Errorf("queryMovieComments: Marshal failed. id=%s, err=%s", movieId, err)
+	}
+	return qmcB, nil
+}
+
+func (m *MOGAO) queryMovieAllocRate(stub shim.ChaincodeStubInterface, movieId string) ([]byte, error) {
+	var qmar QueryMovieAllocRate
+	qmar.MovieId = movieId
+
+	pmiar, err := m.getMovieIncomeAllocRate(stub, movieId)
+	if err != nil {
+		return nil, mglogger.Errorf("queryMovieAllocRate: getMovieIncomeAllocRate failed, id=%s, err=%s.", movieId, err)
+	}
+	if pmiar == nil {
+		qmar.UseGlobal = true
+		pmiar, err = m.getMovieIncomeAllocRate(stub, MOVIE_GLOBAL_ID)
+		if err != nil {
+			return nil, mglogger.Errorf("queryMovieAllocRate: getMovieIncomeAllocRate global failed, id=%s, err=%s.", movieId, err)
+		}
+	}
+	if pmiar != nil {
+		qmar.AllocRate = *pmiar
+	}
+
+	mgc, err := m.getMovieGlobalCfg(stub)
+	if err != nil {
+		return nil, mglogger.Errorf("queryMovieAllocRate: getMovieGlobalCfg failed. err=%s", err)
+	}
+	if mgc != nil {
+		qmar.GlobalCfg = *mgc
+	}
+
+	qmarB, err := json.Marshal(qmar)
+	if err != nil {
+		return nil, mglogger.Errorf("queryMovieAllocRate: Marshal failed. id=%s, err=%s", movieId, err)
+	}
+	return qmarB, nil
+}
+
+func (m *MOGAO) queryMovieIncomeTxs(stub shim.ChaincodeStubInterface, movieId string, begSeq, count, begTime, endTime int64, isAsc bool) ([]byte, error) {
+	var qmir QueryMovieIncomeResult
+	qmir.NextSerial = -1
+	qmir.MaxSerial = -1
+	qmir.Records = []MovieIncomeTx{} //初始化为空，即使没查到数据也会返回'[]'
+
+	maxSeq, err := m.getSeqForQuery(stub, m.getAllocTxSeqKey(movieId))
+	if err != nil {
+		return nil, mglogger.Errorf("queryMovieIncomeTxs: getSeqForQuery failed. id=%s, err=%s", movieId, err)
+	}
+	qmir.MaxSerial = maxSeq
+
+	//count为负数时返回所有
+	if count < 0 {
+		count = maxSeq
+	}
+
+	var loopCnt int64 = 0
+	for i := begSeq; i <= maxSeq; i++ {
+		if loopCnt >= count {
+			qmir.NextSerial = i
+			break
+		}
+
+		//降序时，第一条为最大序列号
+		var seq = i
+		if !isAsc {
+			seq = maxSeq - i + 1
+		}
+
+		mit, err := m.getMovieIncomeTx(stub, movieId, seq)
+		if err != nil {
+			mglogger.Error("queryMovieIncomeTxs: getMovieIncomeTx(id=%s, seq=%d) failed. err=%s", movieId, seq, err)
+			continue
+		}
+		if mit == nil {
+			continue
+		}
+		if mit.DateTime >= begTime && mit.DateTime <= endTime {
+			qmir.Records = append(qmir.Records, *mit)
+			loopCnt++
+		}
+	}
+
+	qmirB, err := json.Marshal(qmir)
+	if err != nil {
+		return nil, mglogger.Errorf("queryMovieIncomeTxs: Marshal failed. id=%s, err=%s", movieId, err)
+	}
+	return qmirB, nil
+}
+
+func (m *MOGAO) queryCmtrEarnings(stub shim.ChaincodeStubInterface, cmtr string, begSeq, count, begTime, endTime int64) ([]byte, error) {
+	var qce QueryCmtrEarnings
+	qce.Commentator = cmtr
+	qce.MovieIncome = make(map[string]int64)
+	qce.NextSerial = -1
+	qce.Records = []CmtrIncomeRecd{} //初始化为空，即使没查到数据也会返回'[]'
+
+	maxSeq, err := m.getSeqForQuery(stub, m.getCmtrAllocTxSeqKey(cmtr))
+	if err != nil {
+		return nil, mglogger.Errorf("queryCmtrEarnings: getSeqForQuery failed. cmtr=%s, err=%s", cmtr, err)
+	}
+	qce.MaxSerial = maxSeq
+
+	//count为负数时返回所有
+	if count < 0 {
+		count = maxSeq
+	}
+
+	var loopCnt int64 = 0
+	for seq := begSeq; seq <= maxSeq; seq++ {
+		if loopCnt >= count {
+			qce.NextSerial = seq
+			break
+		}
+
+		txKeyB, err := stateCache.GetState_Ex(stub, m.getCmtrAllocTxKey(cmtr, seq))
+		if err != nil {
+			mglogger.Error("queryCmtrEarnings: GetState(cmtr=%s, seq=%d) failed. err=%s", cmtr, seq, err)
+			continue
+		}
+		if txKeyB == nil {
+			continue
+		}
+		mitB, err := stateCache.GetState_Ex(stub, string(txKeyB))
+		if err != nil {
+			mglogger.Error("queryCmtrEarnings: GetState(%s) failed. err=%s", string(txKeyB), err)
+			continue
+		}
+		if mitB == nil {
+			continue
+		}
+		var mit MovieIncomeTx
+		err = json.Unmarshal(mitB, &mit)
+		if err != nil {
+			mglogger.Error("queryCmtrEarnings: Unmarshal(%s) failed. err=%s", string(txKeyB), err)
+			continue
+		}
+
+		if mit.DateTime < begTime || mit.DateTime > endTime {
+			continue
+		}
+
+		var cir CmtrIncomeRecd
+		cir.Serial = seq
+		cir.MovieId = mit.MovieId
+		cir.AllocSerial = mit.GlobalSerial
+		cir.DateTime = mit.DateTime
+		cir.Income = mit.CommentatorsIncome[cmtr]
+		cir.Favourate = mit.CommentatorFavourate[cmtr]
+		cir.Settled = mit.Settled
+
+		qce.TotalIncome += cir.Income
+		if cir.Settled {
+			qce.SettledIncome += cir.Income
+		}
+		qce.MovieIncome[cir.MovieId] += cir.Income
+		qce.Records = append(qce.Records, cir)
+		loopCnt++
+	}
+
+	qceB, err := json.Marshal(qce)
+	if err != nil {
+		return nil, mglogger.Errorf("queryCmtrEarnings: Marshal failed. cmtr=%s, err=%s", cmtr, err)
+	}
+	return qceB, nil
+}
+
+func (m *MOGAO) getMovieComment(stub shim.ChaincodeStubInterface, movieId, commentId string) (*MovieComment, error) {
+	mcBytes, err := stateCache.GetState_Ex(stub, m.getMovieCommentKey(movieId, commentId))
+	if err != nil {
+		return nil, mglogger.Errorf("getMovieComment: GetState failed, id=%s cid=%s err=%s.", movieId, commentId, err)
+	}
+	if mcBytes == nil {
+		return nil, nil
+	}
+
+	var mc MovieComment
+	err = json.Unmarshal(mcBytes, &mc)
+	if err != nil {
+		return nil, mglogger.Errorf("getMovieComment: Unmarshal failed, id=%s cid=%s err=%s.", movieId, commentId, err)
+	}
+
+	return &mc, nil
+}
+
+//统计评论的点赞数
+func (m *MOGAO) getCommentLikeCount(stub shim.ChaincodeStubInterface, commentId string) (int64, error) {
+	var begKey = MOVIE_COMMENT_LIKE_PREFIX + commentId + MOVIE_KEY_DELIM
+	var endKey = MOVIE_COMMENT_LIKE_PREFIX + commentId + MOVIE_KEY_DELIM_END
+
+	keysIter, err := stub.GetStateByRange(begKey, endKey)
+	if err != nil {
+		return 0, mglogger.Errorf("getCommentLikeCount: GetStateByRange failed. cid=%s, err=%s", commentId, err)
+	}
+	defer keysIter.Close()
+
+	var cnt int64 = 0
+	for keysIter.HasNext() {
+		_, iterErr := keysIter.Next()
+		if iterErr != nil {
+			return 0, mglogger.Errorf("getCommentLikeCount: Next failed. cid=%s, err=%s", commentId, iterErr)
+		}
+		cnt++
+	}
+
+	return cnt, nil
+}
+
+//获取影片的所有评论，包括旧的整体存储的评论和单独存储的评论。单独存储的评论按评论时间排序，FavourateCnt为初始点赞数加上点赞数
+func (m *MOGAO) getMovieComments(stub shim.ChaincodeStubInterface, movieId string) ([]MovieComment, error) {
+	var mcList []MovieComment
+
+	pmci, err := m.getMovieCommentInfo(stub, movieId)
+	if err != nil {
+		return nil, mglogger.Errorf("getMovieComments: getMovieCommentInfo failed. id=%s, err=%s", movieId, err)
+	}
+	if pmci != nil {
+		for _, cci := range pmci.CCI {
+			for _, cmt := range cci.Comments {
+				mcList = append(mcList, MovieComment{MovieId: movieId, Commentator: cci.Commentator, CommentInfo: cmt})
+			}
+		}
+	}
+
+	var prefix = MOVIE_COMMENT_PREFIX + movieId + MOVIE_KEY_DELIM
+	keysIter, err := stub.GetStateByRange(prefix, MOVIE_COMMENT_PREFIX+movieId+MOVIE_KEY_DELIM_END)
+	if err != nil {
+		return nil, mglogger.Errorf("getMovieComments: GetStateByRange failed. id=%s, err=%s", movieId, err)
+	}
+	defer keysIter.Close()
+
+	var sortKeyList []string
+	var sortMap = make(map[string]MovieComment)
+	for keysIter.HasNext() {
+		kv, iterErr := keysIter.Next()
+		if iterErr != nil {
+			return nil, mglogger.Errorf("getMovieComments: Next failed. id=%s, err=%s", movieId, iterErr)
+		}
+		//影片id中可能包含分隔符，如 影片"a"的范围中会包含影片"a~b"的评论，评论id中不含分隔符，以此过滤
+		if strings.Contains(kv.GetKey()[len(prefix):], MOVIE_KEY_DELIM) {
+			continue
+		}
+
+		var mc MovieComment
+		err = json.Unmarshal(kv.GetValue(), &mc)
+		if err != nil {
+			return nil, mglogger.Errorf("getMovieComments: Unmarshal failed. key=%s, err=%s", kv.GetKey(), err)
+		}
+
+		mc.LikeCnt, err = m.getCommentLikeCount(stub, mc.CommentId)
+		if err != nil {
+			return nil, mglogger.Errorf("getMovieComments: getCommentLikeCount failed. cid=%s, err=%s", mc.CommentId, err)
+		}
+		mc.FavourateCnt += mc.LikeCnt
+
+		var timeStr = strconv.FormatInt(mc.CmtTime, 10)
+		if len(timeStr) < 20 {
+			timeStr = strings.Repeat("0", 20-len(timeStr)) + timeStr
+		}
+		var sortKey = timeStr + mc.CommentId
+		sortKeyList = append(sortKeyList, sortKey)
+		sortMap[sortKey] = mc
+	}
+
+	sort.Strings(sortKeyList)
+	for _, sortKey := range sortKeyList {
+		mcList = append(mcList, sortMap[sortKey])
+	}
+
+	return mcList, nil
+}
+
+//base的转账函数，mogao的交易都没有appid
+func (m *MOGAO) transferCoin(stub shim.ChaincodeStubInterface, from, to, transType, description string, amount, transeTime int64, sameEntSaveTrans bool) ([]byte, error) {
+	retB, errcm := Base.TransferCoin(stub, from, to, transType, description, amount, transeTime, sameEntSaveTrans, "")
+	if errcm != nil {
+		return nil, mglogger.Errorf("transferCoin failed. err=%s", errcm)
+	}
+
+	return retB, nil
+}
+
+func (m *MOGAO) getTransSeq(stub shim.ChaincodeStubInterface, transSeqKey string) (int64, error) {
+	seq, errcm := Base.GetTransSeq(stub, transSeqKey)
+	if errcm != nil {
+		return -1, mglogger.Errorf("getTransSeq failed. err=%s", errcm)
+	}
+
+	return seq, nil
+}
+
+func (m *MOGAO) setTransSeq(stub shim.ChaincodeStubInterface, transSeqKey string, seq int64) error {
+	errcm := Base.SetTransSeq(stub, transSeqKey, seq)
+	if errcm != nil {
+		return mglogger.Errorf("setTransSeq failed. err=%s", errcm)
+	}
+
+	return nil
+}
+
+//load老版本合约导出的worldState时，把数据转换为base的格式
+func (m *MOGAO) dateConvertWhenLoad(stub shim.ChaincodeStubInterface, srcCcid, key string, valueB []byte) (string, []byte, *ErrorCodeMsg) {
+	if srcCcid != OLD_VERSION_CCID {
+		return key, valueB, nil
+	}
+
+	//账户的key变化  因为老的账户的key没有前缀，所以根据json里的字段名来区分
+	if bytes.Contains(valueB, []byte("\"ocert\"")) && bytes.Contains(valueB, []byte("\"aucm\"")) && bytes.Contains(valueB, []byte("\"id\"")) {
+		mglogger.Debug("dateConvertWhenLoad: got acc key:%s", key)
+		if !strings.Contains(key, ACC_ENTITY_PREFIX) {
+			return Base.GetAccountEntityKey(key), valueB, nil
+		}
+		return key, valueB, nil
+	}
+
+	//第一条交易记录的收款方改为发行货币的账户
+	if key == TRANSINFO_PREFIX+"1" {
+		mglogger.Debug("dateConvertWhenLoad: got trans 1 key:%s", key)
+
+		var trans Transaction
+		err := json.Unmarshal(valueB, &trans)
+		if err != nil {
+			return "", nil, mglogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "dateConvertWhenLoad: Unmarshal(trans) failed. err=%s", err)
+		}
+
+		trans.ToID = COIN_ISSUE_ACC_ENTID
+		newValB, err := json.Marshal(trans)
+		if err != nil {
+			return "", nil, mglogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "dateConvertWhenLoad: Marshal(trans) failed. err=%s", err)
+		}
+
+		return key, newValB, nil
+	}
+
+	return key, valueB, nil
+}
+
+func (m *MOGAO) loadAfter(stub shim.ChaincodeStubInterface, srcCcid string) *ErrorCodeMsg {
+	if srcCcid != OLD_VERSION_CCID {
+		return nil
+	}
+
+	//老版本没有账户统计信息，根据账户列表重新统计
+	accsB, err := stateCache.GetState_Ex(stub, ALL_ACC_INFO_KEY)
+	if err != nil {
+		return mglogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "loadAfter: GetState(allAcc) failed, err=%s", err)
+	}
+
+	var asi AccountStatisticInfo
+	asi.AccountCount = int64(bytes.Count(accsB, []byte{MULTI_STRING_DELIM}))
+
+	asiB, err := json.Marshal(asi)
+	if err != nil {
+		return mglogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "loadAfter: Marshal(asi) failed, err=%s", err)
+	}
+
+	err = stateCache.PutState_Ex(stub, ACC_STATIC_INFO_KEY, asiB)
+	if err != nil {
+		return mglogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "loadAfter: PutState(asi) failed, err=%s", err)
+	}
+
+	//发行货币的账户是init时新建的，扣除老版本中已给央行发行的部分
+	issueEntity, errcm := Base.GetAccountEntity(stub, COIN_ISSUE_ACC_ENTID)
+	if errcm != nil {
+		return mglogger.ErrorECM(errcm.Code, "loadAfter: get issue entity failed, err=%s", errcm)
+	}
+
+	issueEntity.RestAmount = issueEntity.RestAmount - OLD_VERSION_ISSUED_AMOUNT
+
+	issueB, err := json.Marshal(issueEntity)
+	if err != nil {
+		return mglogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "loadAfter: Marshal(issueEntity) failed, err=%s", err)
+	}
+
+	err = stateCache.PutState_Ex(stub, Base.GetAccountEntityKey(issueEntity.EntID), issueB)
+	if err != nil {
+		return mglogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "loadAfter: PutState(issueEntity) failed, err=%s", err)
+	}
+
+	return nil
+}
+
+func main() {
+	err := shim.Start(&Base)
+	if err != nil {
+		mglogger.Error("Error starting mogao chaincode: %s", err)
+	}
+}
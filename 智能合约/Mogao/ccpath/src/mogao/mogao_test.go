@@ -0,0 +1,323 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	. "loulan/errcode"
+)
+
+//登记影片m1（上传人up），alice和bob各发表一条评论，返回两条评论的id
+func (c *testChain) setupMovie() (string, string) {
+	var cids []string
+	var saveCid = func(t *testing.T, c *testChain, payload []byte) {
+		var mc MovieComment
+		if err := json.Unmarshal(payload, &mc); err != nil {
+			t.Fatalf("Unmarshal MovieComment failed, err=%s", err)
+		}
+		if mc.CommentId == "" || mc.Commentator == "" {
+			t.Fatalf("setMovieComment: bad comment %s", payload)
+		}
+		cids = append(cids, mc.CommentId)
+	}
+
+	c.run([]testStep{
+		{
+			name: "set movie by others", user: "alice_user", function: "setMovieInfo",
+			args:     []string{"alice", "m1", "movie one", "up"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "set movie", user: "cbuser", function: "setMovieInfo",
+			args: []string{testCBAcc, "m1", "movie one", "up"},
+		},
+		{
+			name: "comment on unknown movie", user: "alice_user", function: "setMovieComment",
+			args:     []string{"alice", "m9", "alice", "good"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "comment for others", user: "alice_user", function: "setMovieComment",
+			args:     []string{"alice", "m1", "bob", "good"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			//只有管理员可以设置初始点赞数
+			name: "comment with favourate", user: "alice_user", function: "setMovieComment",
+			args:     []string{"alice", "m1", "alice", "good", "5"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "alice comment", user: "alice_user", function: "setMovieComment",
+			args:  []string{"alice", "m1", "alice", "good"},
+			check: saveCid,
+		},
+		{
+			name: "bob comment", user: "bob_user", function: "setMovieComment",
+			args:  []string{"bob", "m1", "bob", "not bad"},
+			check: saveCid,
+		},
+	})
+
+	return cids[0], cids[1]
+}
+
+func TestMovieComment(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, []string{"up", "alice", "bob", "carol"})
+	cid1, cid2 := c.setupMovie()
+
+	c.run([]testStep{
+		{
+			name: "like own comment", user: "alice_user", function: "likeComment",
+			args:     []string{"alice", "m1", cid1},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "bob like", user: "bob_user", function: "likeComment",
+			args: []string{"bob", "m1", cid1},
+		},
+		{
+			name: "carol like", user: "carol_user", function: "likeComment",
+			args: []string{"carol", "m1", cid1},
+		},
+		{
+			name: "bob like again", user: "bob_user", function: "likeComment",
+			args:     []string{"bob", "m1", cid1},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "alice like", user: "alice_user", function: "likeComment",
+			args: []string{"alice", "m1", cid2},
+		},
+		{
+			name: "like unknown comment", user: "alice_user", function: "likeComment",
+			args:     []string{"alice", "m1", "nosuchcid"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "query comments", user: "carol_user", function: "getMovieComments",
+			args: []string{"carol", "m1", "*", "1", "10"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				var qmc QueryMovieComments
+				if err := json.Unmarshal(payload, &qmc); err != nil {
+					t.Fatalf("Unmarshal QueryMovieComments failed, err=%s", err)
+				}
+				var fav = make(map[string]int64)
+				for _, cmt := range qmc.Comments {
+					fav[cmt.CommentId] = cmt.FavourateCnt
+				}
+				var want = map[string]int64{cid1: 2, cid2: 1}
+				if qmc.Total != 2 || !reflect.DeepEqual(fav, want) {
+					t.Errorf("getMovieComments: want %v, got %s", want, payload)
+				}
+			},
+		},
+		{
+			name: "query bob comments", user: "carol_user", function: "getMovieComments",
+			args: []string{"carol", "m1", "bob", "1", "10"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				var qmc QueryMovieComments
+				if err := json.Unmarshal(payload, &qmc); err != nil {
+					t.Fatalf("Unmarshal QueryMovieComments failed, err=%s", err)
+				}
+				if qmc.Total != 1 || len(qmc.Comments) != 1 || qmc.Comments[0].CommentId != cid2 {
+					t.Errorf("getMovieComments(bob): want %s, got %s", cid2, payload)
+				}
+			},
+		},
+	})
+}
+
+//检查影片收入分成记录的check函数
+func wantMovieIncome(uploader, platform int64, cmtrs map[string]int64) func(t *testing.T, c *testChain, payload []byte) {
+	return func(t *testing.T, c *testChain, payload []byte) {
+		var mit MovieIncomeTx
+		if err := json.Unmarshal(payload, &mit); err != nil {
+			t.Fatalf("Unmarshal MovieIncomeTx failed, err=%s", err)
+		}
+		if len(cmtrs) == 0 {
+			cmtrs = map[string]int64{}
+		}
+		if mit.UploaderIncome != uploader || mit.PlatformIncome != platform || !reflect.DeepEqual(mit.CommentatorsIncome, cmtrs) {
+			t.Errorf("income: want uploader %d platform %d commentators %v, got %s", uploader, platform, cmtrs, payload)
+		}
+	}
+}
+
+func TestMovieIncome(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, []string{"up", "alice", "bob", "carol", "plat"})
+	cid1, cid2 := c.setupMovie()
+
+	//alice的评论2个赞，bob的评论1个赞
+	c.mustOK(c.invoke("bob_user", "likeComment", "bob", "m1", cid1), "bob like")
+	c.mustOK(c.invoke("carol_user", "likeComment", "carol", "m1", cid1), "carol like")
+	c.mustOK(c.invoke("alice_user", "likeComment", "alice", "m1", cid2), "alice like")
+
+	c.run([]testStep{
+		{
+			//默认比例：上传人85%，评论人10%，平台5%；评论人的分成70%平分，30%按点赞数分
+			//评论人 1000：alice 350+200，bob 350+100
+			name: "compute", user: "cbuser", function: "computeMovieIncome",
+			args: []string{testCBAcc, "m1", "10000"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantMovieIncome(8500, 500, map[string]int64{"alice": 550, "bob": 450})(t, c, payload)
+				//只计算，不转账
+				wantBalances(map[string]int64{testCBAcc: 100000, "up": 0, "alice": 0})(t, c, payload)
+			},
+		},
+		{
+			name: "settle by others", user: "alice_user", function: "settleMovieIncome",
+			args:     []string{"alice", "m1", "1", "alice", "plat"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			//管理员也不能从别人的账户付款
+			name: "settle from others", user: "cbuser", function: "settleMovieIncome",
+			args:     []string{testCBAcc, "m1", "1", "plat", "plat"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+			check:    wantBalances(map[string]int64{testCBAcc: 100000, "plat": 0}),
+		},
+		{
+			name: "settle", user: "cbuser", function: "settleMovieIncome",
+			args:  []string{testCBAcc, "m1", "1", testCBAcc, "plat"},
+			check: wantBalances(map[string]int64{testCBAcc: 90000, "up": 8500, "alice": 550, "bob": 450, "plat": 500}),
+		},
+		{
+			name: "settle again", user: "cbuser", function: "settleMovieIncome",
+			args:     []string{testCBAcc, "m1", "1", testCBAcc, "plat"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+			check:    wantBalances(map[string]int64{testCBAcc: 90000, "up": 8500}),
+		},
+		{
+			name: "compute and settle by others", user: "alice_user", function: "computeMovieIncome",
+			args:     []string{"alice", "m1", "1000", "alice", "plat"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "set alloc rate by others", user: "alice_user", function: "setMovieAllocRate",
+			args:     []string{"alice", "m1", "8000", "1000", "1000"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "alloc rate sum not 10000", user: "cbuser", function: "setMovieAllocRate",
+			args:     []string{testCBAcc, "m1", "8000", "1000", "500"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "set alloc rate", user: "cbuser", function: "setMovieAllocRate",
+			args: []string{testCBAcc, "m1", "8000", "1000", "1000"},
+		},
+		{
+			name: "query alloc rate", user: "alice_user", function: "getMovieAllocRate",
+			args: []string{"alice", "m1"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				var qmar QueryMovieAllocRate
+				if err := json.Unmarshal(payload, &qmar); err != nil {
+					t.Fatalf("Unmarshal QueryMovieAllocRate failed, err=%s", err)
+				}
+				if qmar.UseGlobal || qmar.AllocRate.UploaderRate != 8000 || qmar.AllocRate.PlatformRate != 1000 {
+					t.Errorf("getMovieAllocRate: want movie rate 8000/1000/1000, got %s", payload)
+				}
+			},
+		},
+		{
+			name: "compute and settle from others", user: "cbuser", function: "computeMovieIncome",
+			args:     []string{testCBAcc, "m1", "1000", "plat", "plat"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+			check:    wantBalances(map[string]int64{testCBAcc: 90000, "plat": 500}),
+		},
+		{
+			//评论人 100：alice 35+20，bob 35+10
+			name: "compute and settle", user: "cbuser", function: "computeMovieIncome",
+			args: []string{testCBAcc, "m1", "1000", testCBAcc, "plat"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantMovieIncome(800, 100, map[string]int64{"alice": 55, "bob": 45})(t, c, payload)
+				wantBalances(map[string]int64{testCBAcc: 89000, "up": 9300, "alice": 605, "bob": 495, "plat": 600})(t, c, payload)
+			},
+		},
+		{
+			name: "query income by others", user: "alice_user", function: "getMovieIncomeTxs",
+			args:     []string{"alice", "m1", "1", "10", "0", "-1", "asc"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			//上传人可以查询自己影片的分成记录
+			name: "query income", user: "up_user", function: "getMovieIncomeTxs",
+			args: []string{"up", "m1", "1", "10", "0", "-1", "asc"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				var qmir QueryMovieIncomeResult
+				if err := json.Unmarshal(payload, &qmir); err != nil {
+					t.Fatalf("Unmarshal QueryMovieIncomeResult failed, err=%s", err)
+				}
+				if len(qmir.Records) != 2 || !qmir.Records[0].Settled || !qmir.Records[1].Settled ||
+					qmir.Records[0].TotalIncome != 10000 || qmir.Records[1].TotalIncome != 1000 {
+					t.Errorf("getMovieIncomeTxs: want 2 settled records, got %s", payload)
+				}
+			},
+		},
+		{
+			name: "query earnings of others", user: "bob_user", function: "getCmtrEarnings",
+			args:     []string{"bob", "alice", "1", "10", "0", "-1"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "query earnings", user: "alice_user", function: "getCmtrEarnings",
+			args: []string{"alice", "", "1", "10", "0", "-1"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				var qce QueryCmtrEarnings
+				if err := json.Unmarshal(payload, &qce); err != nil {
+					t.Fatalf("Unmarshal QueryCmtrEarnings failed, err=%s", err)
+				}
+				if qce.Commentator != "alice" || qce.TotalIncome != 605 || qce.SettledIncome != 605 || len(qce.Records) != 2 {
+					t.Errorf("getCmtrEarnings: want alice 605 in 2 records, got %s", payload)
+				}
+			},
+		},
+		{
+			name: "del alloc rate", user: "cbuser", function: "delMovieAllocRate",
+			args: []string{testCBAcc, "m1"},
+		},
+		{
+			name: "query alloc rate after del", user: "alice_user", function: "getMovieAllocRate",
+			args: []string{"alice", "m1"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				var qmar QueryMovieAllocRate
+				if err := json.Unmarshal(payload, &qmar); err != nil {
+					t.Fatalf("Unmarshal QueryMovieAllocRate failed, err=%s", err)
+				}
+				if !qmar.UseGlobal || qmar.AllocRate.UploaderRate != 8500 {
+					t.Errorf("getMovieAllocRate: want global rate, got %s", payload)
+				}
+			},
+		},
+		{
+			name: "retired set income alloc rate", user: "cbuser", function: "setMovieIncomeAllocRate",
+			args:     []string{testCBAcc, "m1", "8000", "1000", "1000"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+	})
+}
+
+func TestMovieIncomeNoCommentator(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, []string{"up", "plat"})
+
+	c.run([]testStep{
+		{
+			name: "set movie", user: "cbuser", function: "setMovieInfo",
+			args: []string{testCBAcc, "m2", "movie two", "up"},
+		},
+		{
+			//没有评论人时，评论人的分成归平台
+			name: "compute and settle", user: "cbuser", function: "computeMovieIncome",
+			args: []string{testCBAcc, "m2", "1000", testCBAcc, "plat"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantMovieIncome(850, 150, nil)(t, c, payload)
+				wantBalances(map[string]int64{testCBAcc: 99000, "up": 850, "plat": 150})(t, c, payload)
+			},
+		},
+	})
+}
@@ -15,7 +15,11 @@ import (
 	//"time"
 	//"sort"
 	"strconv"
-	//"strings"
+	"strings"
+
+	. "loulan/acbase"
+	. "loulan/errcode"
+	. "loulan/mylog"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 )
@@ -31,6 +35,10 @@ const (
 
 	MOVIE_GLOBAL_ID = "__global@movie_id__" //影片一些全局配置的影片id
 
+	OLD_VERSION_CCID          = "ef38784bad472d640839c1782232aac63985489ee624b67a2d2b23448b03ebfb" //老版本合约的ccid，load该版本导出的worldState时需要转换数据
+	OLD_VERSION_ISSUED_AMOUNT = 1000000000                                                         //老版本中已给央行发行的货币
+
+	ISSUE_ACC_ENTID = "VirtualAccount" //链上数据中发行货币的账户id，和base中的默认值不同
 )
 
 /***********************************************************/
@@ -124,14 +132,31 @@ var mogao MOGAO
 
 var mglogger = NewMylogger("mg")
 
+//base中的数据和交易都在同一个stateCache中缓存
+var stateCache = &StateCache
+
 //包初始化函数
 func init() {
+	SetModuleName(MODULE_NAME)
+	SetIssueAccountId(ISSUE_ACC_ENTID)
+
 	//注册base中的hook函数
 	InitHook = mogao.Init
 	InvokeHook = mogao.Invoke
+	DateConvertWhenLoadHook = mogao.dateConvertWhenLoad
+	DateUpdateAfterLoadHook = mogao.loadAfter
+}
+
+func (m *MOGAO) Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]byte, *ErrorCodeMsg) {
+	payload, err := m.__Init(stub, ifas)
+	if err != nil {
+		return nil, mglogger.ErrorECM(ERRCODE_COMMON_INNER_ERROR, "%s", err)
+	}
+
+	return payload, nil
 }
 
-func (m *MOGAO) Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]byte, error) {
+func (m *MOGAO) __Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]byte, error) {
 	mglogger.Debug("Enter Init")
 	function, args := stub.GetFunctionAndParameters()
 	mglogger.Debug("func =%s, args = %+v", function, args)
@@ -145,9 +170,9 @@ func (m *MOGAO) Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]by
 
 	if function == "init" { //合约实例化时，默认会执行init函数，除非在调用合约实例化接口时指定了其它的函数
 
-		err := Base.setIssueAmountTotal(stub, 100000000, initTime)
-		if err != nil {
-			return nil, mglogger.Errorf("Init setIssueAmountTotal error, err=%s.", err)
+		errcm := Base.SetIssueAmountTotal(stub, 100000000, initTime)
+		if errcm != nil {
+			return nil, mglogger.Errorf("Init SetIssueAmountTotal error, err=%s.", errcm)
 		}
 
 		var miar MovieIncomeAllocRateCfg
@@ -161,7 +186,7 @@ func (m *MOGAO) Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]by
 		if err != nil {
 			return nil, mglogger.Errorf("Init(init) Marshal(miar) failed, err=%s", err)
 		}
-		err = Base.putState_Ex(stub, m.getMovieIncomeAllocRateKey(MOVIE_GLOBAL_ID), miarJson)
+		err = stateCache.PutState_Ex(stub, m.getMovieIncomeAllocRateKey(MOVIE_GLOBAL_ID), miarJson)
 		if err != nil {
 			return nil, mglogger.Errorf("Init(init) putState_Ex(miar) failed, err=%s", err)
 		}
@@ -179,7 +204,7 @@ func (m *MOGAO) Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]by
 		if err != nil {
 			return nil, mglogger.Errorf("Init(init) Marshal(mgc) failed, err=%s", err)
 		}
-		err = Base.putState_Ex(stub, MOVIE_GLOBAL_CFG_KEY, mgcJson)
+		err = stateCache.PutState_Ex(stub, MOVIE_GLOBAL_CFG_KEY, mgcJson)
 		if err != nil {
 			return nil, mglogger.Errorf("Init(init) putState_Ex(mgc) failed, err=%s", err)
 		}
@@ -196,8 +221,17 @@ func (m *MOGAO) Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]by
 
 }
 
+func (m *MOGAO) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]byte, *ErrorCodeMsg) {
+	payload, err := m.__Invoke(stub, ifas)
+	if err != nil {
+		return nil, mglogger.ErrorECM(ERRCODE_COMMON_INNER_ERROR, "%s", err)
+	}
+
+	return payload, nil
+}
+
 // Transaction makes payment of X units from A to B
-func (m *MOGAO) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]byte, error) {
+func (m *MOGAO) __Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]byte, error) {
 	mglogger.Debug("Enter Invoke")
 	function, args := stub.GetFunctionAndParameters()
 	mglogger.Debug("func =%s, args = %+v", function, args)
@@ -229,7 +263,7 @@ func (m *MOGAO) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) (
 			return nil, mglogger.Errorf("Invoke(setMovieInfo) miss arg, got %d, at least need %d.", len(args), argCount)
 		}
 
-		if !Base.isAdmin(stub, accName) {
+		if !Base.IsAdmin(stub, accName) {
 			return nil, mglogger.Errorf("Invoke can't exec setMovieInfo by %s.", accName)
 		}
 
@@ -245,7 +279,7 @@ func (m *MOGAO) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) (
 		}
 
 		//TODO: 要不要检测数据是否已存在?
-		err = Base.putState_Ex(stub, m.getMovieInfoKey(mi.MovieId), miJson)
+		err = stateCache.PutState_Ex(stub, m.getMovieInfoKey(mi.MovieId), miJson)
 		if err != nil {
 			return nil, mglogger.Errorf("Invoke(setMovieInfo) putState_Ex failed, err=%s.", err)
 		}
@@ -258,7 +292,7 @@ func (m *MOGAO) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) (
 			return nil, mglogger.Errorf("Invoke(setMovieComment) miss arg, got %d, at least need %d.", len(args), argCount)
 		}
 
-		if !Base.isAdmin(stub, accName) {
+		if !Base.IsAdmin(stub, accName) {
 			return nil, mglogger.Errorf("Invoke can't exec setMovieComment by %s.", accName)
 		}
 		var movieId = args[fixedArgCount]
@@ -309,7 +343,7 @@ func (m *MOGAO) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) (
 			return nil, mglogger.Errorf("Invoke(setMovieComment) Marshal failed. id=%s, err=%s", movieId, err)
 		}
 
-		err = Base.putState_Ex(stub, m.getMovieCommentInfoKey(movieId), mciJson)
+		err = stateCache.PutState_Ex(stub, m.getMovieCommentInfoKey(movieId), mciJson)
 		if err != nil {
 			return nil, mglogger.Errorf("Invoke(setMovieComment) putState_Ex failed, err=%s.", err)
 		}
@@ -353,7 +387,7 @@ func (m *MOGAO) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) (
 			return nil, mglogger.Errorf("Invoke(setMovieIncomeAllocRate) Marshal failed. id=%s, err=%s", movieId, err)
 		}
 
-		err = Base.putState_Ex(stub, m.getMovieIncomeAllocRateKey(movieId), miarJson)
+		err = stateCache.PutState_Ex(stub, m.getMovieIncomeAllocRateKey(movieId), miarJson)
 		if err != nil {
 			return nil, mglogger.Errorf("Invoke(setMovieIncomeAllocRate) putState_Ex failed, err=%s.", err)
 		}
@@ -453,12 +487,12 @@ func (m *MOGAO) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) (
 		mit.CommentatorFavourateRate = mgc.CIAR.FavourateRate
 
 		seqKey := m.getAllocTxSeqKey(movieId)
-		seq, err := Base.getTransSeq(stub, seqKey)
+		seq, err := m.getTransSeq(stub, seqKey)
 		if err != nil {
 			return nil, mglogger.Errorf("Invoke(computeMovieIncome) getTransSeq failed. id=%s, err=%s", movieId, err)
 		}
 		seq++
-		err = Base.setTransSeq(stub, seqKey, seq)
+		err = m.setTransSeq(stub, seqKey, seq)
 		if err != nil {
 			return nil, mglogger.Errorf("Invoke(computeMovieIncome) setTransSeq failed. id=%s, err=%s", movieId, err)
 		}
@@ -471,7 +505,7 @@ func (m *MOGAO) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) (
 		}
 
 		var txKey = m.getAllocTxKey(movieId, seq)
-		err = Base.putState_Ex(stub, txKey, mitJson)
+		err = stateCache.PutState_Ex(stub, txKey, mitJson)
 		if err != nil {
 			return nil, mglogger.Errorf("Invoke(computeMovieIncome) putState_Ex failed. id=%s, err=%s", movieId, err)
 		}
@@ -528,7 +562,7 @@ func (m *MOGAO) getMovieIncomeAllocRateKey(movieId string) string {
 }
 
 func (m *MOGAO) getMovieInfo(stub shim.ChaincodeStubInterface, movieId string) (*MovieInfo, error) {
-	movieBytes, err := stub.GetState(m.getMovieInfoKey(movieId))
+	movieBytes, err := stateCache.GetState_Ex(stub, m.getMovieInfoKey(movieId))
 	if err != nil {
 		return nil, mglogger.Errorf("getMovieInfo: GetState failed, id=%s err=%s.", movieId, err)
 	}
@@ -545,7 +579,7 @@ func (m *MOGAO) getMovieInfo(stub shim.ChaincodeStubInterface, movieId string) (
 	return &mi, nil
 }
 func (m *MOGAO) getMovieCommentInfo(stub shim.ChaincodeStubInterface, movieId string) (*MovieCommentInfo, error) {
-	movieCmtBytes, err := stub.GetState(m.getMovieCommentInfoKey(movieId))
+	movieCmtBytes, err := stateCache.GetState_Ex(stub, m.getMovieCommentInfoKey(movieId))
 	if err != nil {
 		return nil, mglogger.Errorf("getMovieCommentInfo: GetState failed, id=%s err=%s.", movieId, err)
 	}
@@ -562,7 +596,7 @@ func (m *MOGAO) getMovieCommentInfo(stub shim.ChaincodeStubInterface, movieId st
 	return &mci, nil
 }
 func (m *MOGAO) getMovieIncomeAllocRate(stub shim.ChaincodeStubInterface, movieId string) (*MovieIncomeAllocRateCfg, error) {
-	movieIARBytes, err := stub.GetState(m.getMovieIncomeAllocRateKey(movieId))
+	movieIARBytes, err := stateCache.GetState_Ex(stub, m.getMovieIncomeAllocRateKey(movieId))
 	if err != nil {
 		return nil, mglogger.Errorf("getMovieIncomeAllocRate: GetState failed, id=%s err=%s.", movieId, err)
 	}
@@ -580,7 +614,7 @@ func (m *MOGAO) getMovieIncomeAllocRate(stub shim.ChaincodeStubInterface, movieI
 }
 
 func (m *MOGAO) getMovieGlobalCfg(stub shim.ChaincodeStubInterface) (*MovieGlobalCfg, error) {
-	movieGlbBytes, err := stub.GetState(MOVIE_GLOBAL_CFG_KEY)
+	movieGlbBytes, err := stateCache.GetState_Ex(stub, MOVIE_GLOBAL_CFG_KEY)
 	if err != nil {
 		return nil, mglogger.Errorf("getMovieGlobalCfg: GetState failed, err=%s.", err)
 	}
@@ -609,3 +643,110 @@ func (m *MOGAO) getAllocTxKey(movieid string, seq int64) string {
 	buf.WriteString(strconv.FormatInt(seq, 10))
 	return buf.String()
 }
+
+func (m *MOGAO) getTransSeq(stub shim.ChaincodeStubInterface, transSeqKey string) (int64, error) {
+	seq, errcm := Base.GetTransSeq(stub, transSeqKey)
+	if errcm != nil {
+		return -1, mglogger.Errorf("getTransSeq failed. err=%s", errcm)
+	}
+
+	return seq, nil
+}
+
+func (m *MOGAO) setTransSeq(stub shim.ChaincodeStubInterface, transSeqKey string, seq int64) error {
+	errcm := Base.SetTransSeq(stub, transSeqKey, seq)
+	if errcm != nil {
+		return mglogger.Errorf("setTransSeq failed. err=%s", errcm)
+	}
+
+	return nil
+}
+
+//load老版本合约导出的worldState时，把数据转换为base的格式
+func (m *MOGAO) dateConvertWhenLoad(stub shim.ChaincodeStubInterface, srcCcid, key string, valueB []byte) (string, []byte, *ErrorCodeMsg) {
+	if srcCcid != OLD_VERSION_CCID {
+		return key, valueB, nil
+	}
+
+	//账户的key变化  因为老的账户的key没有前缀，所以根据json里的字段名来区分
+	if bytes.Contains(valueB, []byte("\"ocert\"")) && bytes.Contains(valueB, []byte("\"aucm\"")) && bytes.Contains(valueB, []byte("\"id\"")) {
+		mglogger.Debug("dateConvertWhenLoad: got acc key:%s", key)
+		if !strings.Contains(key, ACC_ENTITY_PREFIX) {
+			return Base.GetAccountEntityKey(key), valueB, nil
+		}
+		return key, valueB, nil
+	}
+
+	//第一条交易记录的收款方改为发行货币的账户
+	if key == TRANSINFO_PREFIX+"1" {
+		mglogger.Debug("dateConvertWhenLoad: got trans 1 key:%s", key)
+
+		var trans Transaction
+		err := json.Unmarshal(valueB, &trans)
+		if err != nil {
+			return "", nil, mglogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "dateConvertWhenLoad: Unmarshal(trans) failed. err=%s", err)
+		}
+
+		trans.ToID = COIN_ISSUE_ACC_ENTID
+		newValB, err := json.Marshal(trans)
+		if err != nil {
+			return "", nil, mglogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "dateConvertWhenLoad: Marshal(trans) failed. err=%s", err)
+		}
+
+		return key, newValB, nil
+	}
+
+	return key, valueB, nil
+}
+
+func (m *MOGAO) loadAfter(stub shim.ChaincodeStubInterface, srcCcid string) *ErrorCodeMsg {
+	if srcCcid != OLD_VERSION_CCID {
+		return nil
+	}
+
+	//老版本没有账户统计信息，根据账户列表重新统计
+	accsB, err := stateCache.GetState_Ex(stub, ALL_ACC_INFO_KEY)
+	if err != nil {
+		return mglogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "loadAfter: GetState(allAcc) failed, err=%s", err)
+	}
+
+	var asi AccountStatisticInfo
+	asi.AccountCount = int64(bytes.Count(accsB, []byte{MULTI_STRING_DELIM}))
+
+	asiB, err := json.Marshal(asi)
+	if err != nil {
+		return mglogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "loadAfter: Marshal(asi) failed, err=%s", err)
+	}
+
+	err = stateCache.PutState_Ex(stub, ACC_STATIC_INFO_KEY, asiB)
+	if err != nil {
+		return mglogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "loadAfter: PutState(asi) failed, err=%s", err)
+	}
+
+	//发行货币的账户是init时新建的，扣除老版本中已给央行发行的部分
+	issueEntity, errcm := Base.GetAccountEntity(stub, COIN_ISSUE_ACC_ENTID)
+	if errcm != nil {
+		return mglogger.ErrorECM(errcm.Code, "loadAfter: get issue entity failed, err=%s", errcm)
+	}
+
+	issueEntity.RestAmount = issueEntity.RestAmount - OLD_VERSION_ISSUED_AMOUNT
+
+	issueB, err := json.Marshal(issueEntity)
+	if err != nil {
+		return mglogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "loadAfter: Marshal(issueEntity) failed, err=%s", err)
+	}
+
+	err = stateCache.PutState_Ex(stub, Base.GetAccountEntityKey(issueEntity.EntID), issueB)
+	if err != nil {
+		return mglogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "loadAfter: PutState(issueEntity) failed, err=%s", err)
+	}
+
+	return nil
+}
+
+func main() {
+	err := shim.Start(&Base)
+	if err != nil {
+		mglogger.Error("Error starting mogao chaincode: %s", err)
+	}
+}
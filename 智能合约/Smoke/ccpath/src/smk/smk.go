@@ -0,0 +1,1862 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	. "loulan/acbase"
+	. "loulan/ccutil"
+	. "loulan/errcode"
+	. "loulan/mylog"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	EXTEND_MODULE_NAME = "smk" //模块名，base中的key都以此为前缀
+
+	DFID_PREFIX         = "!smk@dfIdPre~"       //欠款融资id的key的前缀
+	DFID_TX_PREFIX      = "!smk@dfIdTxPre~"     //欠款融资id交易信息的key的前缀
+	ACC_DFID_PREFIX     = "!smk@accDfIdPre~"    //账户的dfid子账户信息的key的前缀
+	TRACE_WARE_PREFIX   = "!smk@traceWarePre~"  //商品溯源信息的key的前缀
+	TRACE_EVENT_PREFIX  = "!smk@traceEvtPre~"   //商品溯源事件的key的前缀，后面为 商品id_序列号
+	TRACE_BATCH_PREFIX  = "!smk@traceBatchPre~" //批次信息的key的前缀
+	TRACE_CHILD_PREFIX  = "!smk@traceChildPre~" //子商品id的key的前缀，后面为 父商品id_序列号
+	TRACE_BWARE_PREFIX  = "!smk@traceBWarePre~" //批次中商品id的key的前缀，后面为 批次id_序列号
+	TRACE_LEGACY_PREFIX = "!smk@traceOldPre~"   //0.6版本以商品id为key保存的物流信息，load时转到此前缀下
+	RECV_PREFIX         = "!smk@recvPre~"       //应收账款融资信息的key的前缀，后面为DfId
+
+	//0.6版本的账户系统数据，load时转到以下前缀下，只保留用于查询，不再修改
+	LEGACY_TX_PREFIX      = "!smk@oldTxPre~"     //0.6版本的交易记录
+	LEGACY_DFID_TX_PREFIX = "!smk@oldDfIdTxPre~" //0.6版本的欠款融资id交易列表，列表中的key已转换为LEGACY_TX_PREFIX前缀
+	LEGACY_STATE_PREFIX   = "!smk@oldStatePre~"  //0.6版本的其它数据，如序列号、央行账户
+
+	//0.6版本的key前缀
+	SMK06_TRANSSEQ_PREFIX    = "__~!@#@!~_smk_transSeqPre__"
+	SMK06_TRANSINFO_PREFIX   = "__~!@#@!~_smk_transInfoPre__"
+	SMK06_DFID_PREFIX        = "__~!@#@!~_smk_dfIdPre__"
+	SMK06_DFID_TX_PREFIX     = "__~!@#@!~_smk_dfIdTxPre__"
+	SMK06_CENTERBANK_ACC_KEY = "__~!@#@!~_smk_centerBankAccKey__#$%"
+	SMK06_ALL_ACC_KEY        = "__~!@#@!~_smk_allAccInfo__#$%"
+	SMK06_TRACE_WARE_PREFIX  = "__~!@#@!~_smk_traceWarePre__"
+	SMK06_TRACE_EVENT_PREFIX = "__~!@#@!~_smk_traceEvtPre__"
+	SMK06_TRACE_BATCH_PREFIX = "__~!@#@!~_smk_traceBatchPre__"
+	SMK06_TRACE_CHILD_PREFIX = "__~!@#@!~_smk_traceChildPre__"
+	SMK06_TRACE_BWARE_PREFIX = "__~!@#@!~_smk_traceBWarePre__"
+	SMK06_RECV_PREFIX        = "__~!@#@!~_smk_recvPre__"
+
+	SMK06_CCID = "smk0.6" //load 0.6版本导出的worldState时，srcCcid填这个值
+
+	//溯源事件类型
+	TRACE_EVT_PRODUCED  = "produced"  //生产
+	TRACE_EVT_SHIPPED   = "shipped"   //发货
+	TRACE_EVT_RECEIVED  = "received"  //收货
+	TRACE_EVT_INSPECTED = "inspected" //检验
+	TRACE_EVT_NOTE      = "note"      //旧的trace接口记录的物流信息，没有类型
+
+	//应收账款融资状态
+	RECV_STAT_REGISTERED = 1 //供应商已登记应收账款
+	RECV_STAT_CONFIRMED  = 2 //核心企业已确认
+	RECV_STAT_FINANCED   = 3 //金融机构已放款
+	RECV_STAT_SETTLED    = 4 //核心企业已还清
+	RECV_STAT_OVERDUE    = 5 //已逾期未还清。不保存，查询时根据时间计算
+
+	RECV_TRANS_DISBURSE = "recvDisburse" //应收账款融资放款的交易类型
+	RECV_TRANS_REPAY    = "recvRepay"    //应收账款还款的交易类型
+
+	RECV_MAX_REPAYMENTS = 100 //一笔应收账款最多还款的次数，最后一次必须还清
+)
+
+//0.6版本的账户信息，以账户名为key保存，load时转换为base中的AccountEntity和AccountDfId
+type Smk06Entity struct {
+	EntID       string `json:"id"`          //银行/企业/项目/个人ID
+	EntDesc     string `json:"desc"`        //描述信息
+	EntType     int    `json:"entType"`     //类型 中央银行:1, 企业:2, 项目:3, 个人:4
+	TotalAmount int64  `json:"totalAmount"` //货币总数额(发行或接收)
+	RestAmount  int64  `json:"restAmount"`  //账户余额
+	User        string `json:"user"`        //该实例所属的用户
+	Time        int64  `json:"time"`        //创建时间
+
+	DFIdMap map[string]int64 `json:"dfIdMap"` //贷款融资id的map，DFId作为子账户
+}
+
+//0.6版本的交易记录，只解析查询dfid交易时需要的字段
+type Smk06Transaction struct {
+	FromID string `json:"fromid"` //发送方ID
+	ToID   string `json:"toid"`   //接收方ID
+	Amount int64  `json:"amount"` //交易数额
+	Time   int64  `json:"time"`   //交易时间
+	DfId   string `json:"dfid"`   //欠款融资id
+}
+
+//账户的dfid子账户。余额在base的AccountEntity中，这里只记录按dfid划分的部分
+type AccountDfId struct {
+	EntID   string           `json:"id"`
+	DFIdMap map[string]int64 `json:"dfIdMap"` //贷款融资id的map，DFId作为子账户
+}
+
+//查询账户信息
+type QueryEntity struct {
+	EntID       string           `json:"id"`      //银行/企业/项目/个人ID
+	TotalAmount int64            `json:"totAmt"`  //注意，这里指的是账户所有余额，即AccountEntity中RestAmount
+	DFIdMap     map[string]int64 `json:"dfIdMap"` //贷款融资id的map，DFId作为子账户
+}
+
+type TraceInfo struct {
+	Time     int64  `json:"time"`     //物流时间
+	TraceMsg string `json:"tracemsg"` //物流信息
+}
+
+//商品溯源信息
+type TraceWare struct {
+	WareId     string `json:"wareId"`
+	BatchId    string `json:"batchId"`    //批次id
+	ParentId   string `json:"parentId"`   //父商品id，如整箱商品拆分为单件时，单件的父商品为整箱商品
+	ChildCount int64  `json:"childCount"` //子商品数量，子商品id按序号单独保存，见getTraceChildKey
+	Creator    string `json:"creator"`    //登记人账户
+	CreateTime int64  `json:"createTime"` //登记时间
+	EventSeq   int64  `json:"eventSeq"`   //最新的溯源事件序列号
+}
+
+//溯源事件
+type TraceEvent struct {
+	Seq        int64  `json:"seq"`        //事件序列号，每个商品从1开始
+	WareId     string `json:"wareId"`     //
+	EventType  string `json:"eventType"`  //事件类型 produced/shipped/received/inspected
+	Actor      string `json:"actor"`      //记录事件的账户
+	Location   string `json:"location"`   //事件发生地点
+	AttachHash string `json:"attachHash"` //附件（检验报告、单据等）的hash
+	TxID       string `json:"txid"`       //
+	TraceInfo
+}
+
+//批次信息
+type TraceBatch struct {
+	BatchId   string `json:"batchId"`
+	Creator   string `json:"creator"`   //创建批次的账户，即在批次中登记第一个商品的账户。只有创建人和管理员可以往批次中登记商品
+	WareCount int64  `json:"wareCount"` //批次中的商品数量，商品id按序号单独保存，见getTraceBatchWareKey
+}
+
+//子商品或批次中商品的分页查询结果
+type QueryTraceIdList struct {
+	Total   int64    `json:"total"`   //
+	NextSeq int64    `json:"nextSeq"` //下次要请求的序列号，-1表示没有更多
+	IdList  []string `json:"idList"`  //
+}
+
+//溯源查询结果
+type QueryTraceResult struct {
+	Ware         *TraceWare   `json:"ware"`         //商品信息，旧数据没有登记商品时为null
+	NextSeq      int64        `json:"nextSeq"`      //下次要请求的序列号，-1表示没有更多
+	MaxSeq       int64        `json:"maxSeq"`       //
+	Events       []TraceEvent `json:"events"`       //
+	LegacyTraces []TraceInfo  `json:"legacyTraces"` //旧接口以商品id为key保存的物流信息
+}
+
+type DebtFinance struct {
+	DFID              string `json:"DFId"`        //欠款融资id
+	DebtInfo          string `json:"debtInfo"`    //欠款信息
+	DebtAmount        string `json:"debtAmt"`     //欠款金额。 不用运算，只是保存，所以用字符串类型
+	DebtTime          int64  `json:"debtTime"`    //欠款时间
+	DebtContractNo    string `json:"debtContNo"`  //欠款合同编号
+	FinanceInfo       string `json:"finacInfo"`   //融资信息
+	FinanceAmount     string `json:"finacAmt"`    //融资总额
+	FinanceTime       int64  `json:"finacTime"`   //融资时间
+	FinanceContractNo string `json:"finacContNo"` //融资合同编号
+}
+
+//应收账款还款记录
+type RecvRepayment struct {
+	Payer  string `json:"payer"`
+	Payee  string `json:"payee"`
+	Amount int64  `json:"amount"`
+	Time   int64  `json:"time"`
+	TxID   string `json:"txid"`
+}
+
+//应收账款融资。流程：供应商登记 -> 核心企业确认 -> 金融机构放款 -> 核心企业还款
+//放款前供应商要指定金融机构（确认前后都可以），只有指定的金融机构可以放款
+type ReceivableFinance struct {
+	DFID          string          `json:"DFId"`        //欠款融资id
+	Supplier      string          `json:"supplier"`    //供应商账户（债权人）
+	CoreEnt       string          `json:"coreEnt"`     //核心企业账户（债务人）
+	Financier     string          `json:"financier"`   //金融机构账户，由供应商指定
+	Amount        int64           `json:"amount"`      //应收账款金额
+	DueTime       int64           `json:"dueTime"`     //到期时间
+	ContractNo    string          `json:"contNo"`      //合同编号
+	Info          string          `json:"info"`        //应收账款信息
+	FinanceAmount int64           `json:"finacAmt"`    //放款金额
+	RepaidAmount  int64           `json:"repaidAmt"`   //已还款金额
+	Status        int             `json:"status"`      //状态
+	Overdue       bool            `json:"overdue"`     //是否发生过逾期还款
+	RegTime       int64           `json:"regTime"`     //登记时间
+	ConfirmTime   int64           `json:"confirmTime"` //确认时间
+	FinanceTime   int64           `json:"finacTime"`   //放款时间
+	SettleTime    int64           `json:"settleTime"`  //还清时间
+	Repayments    []RecvRepayment `json:"repayments"`  //还款记录，最多RECV_MAX_REPAYMENTS条
+}
+
+type QueryReceivable struct {
+	ReceivableFinance
+	TransInfoList []DfidTransInfo `json:"transInfoList"`
+}
+
+type DfidTransInfo struct {
+	AccName string `json:"accName"`
+	Amount  int64  `json:"amount"`
+	Time    int64  `json:"time"`
+}
+
+type QueryDfidTransInfo struct {
+	DebtFinance
+	TransInfoList []DfidTransInfo `json:"transInfoList"`
+}
+
+var smklogger = NewMylogger("smk")
+
+type SMK struct {
+}
+
+//base中的数据和交易都在同一个stateCache中缓存
+var stateCache = &StateCache
+
+//包初始化函数
+func init() {
+	SetModuleName(EXTEND_MODULE_NAME)
+
+	var smk SMK
+	//注册base中的hook函数
+	InitHook = smk.Init
+	InvokeHook = smk.Invoke
+	InvokeFilterHook = smk.invokeFilter
+	DateConvertWhenLoadHook = smk.dateConvertWhenLoad
+	DateUpdateAfterLoadHook = smk.loadAfter
+}
+
+func (t *SMK) Init(stub shim.ChaincodeStubInterface, ifas *BaseInitArgs) ([]byte, *ErrorCodeMsg) {
+	smklogger.Debug("Enter Init")
+	function, args := stub.GetFunctionAndParameters()
+	smklogger.Info("func =%s, args = %+v", function, args)
+
+	//央行账户、发行等都由base处理，smk没有需要初始化的数据
+	return nil, nil
+}
+
+func (t *SMK) Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]byte, *ErrorCodeMsg) {
+	payload, err := t.__Invoke(stub, ifas)
+	if err != nil {
+		return nil, smklogger.ErrorECM(ERRCODE_COMMON_INNER_ERROR, "%s", err)
+	}
+
+	return payload, nil
+}
+
+//base中的这些函数直接修改账户余额，不会同步修改dfid子账户，会使子账户之和与余额不一致，所以在smk中禁用。转账使用transeferDfid
+var smkDisabledBaseFunc = []string{"transefer", "transefer3", "transferFrom", "burn", "payInvoice", "lockHtlc", "claimHtlc", "refund", "recharge"}
+
+func (t *SMK) invokeFilter(stub shim.ChaincodeStubInterface, function string, ifas *BaseInvokeArgs) *ErrorCodeMsg {
+	if StrSliceContains(smkDisabledBaseFunc, function) {
+		return smklogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "%s is disabled in smk, use transeferDfid instead.", function)
+	}
+
+	return nil
+}
+
+func (t *SMK) __Invoke(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]byte, error) {
+	smklogger.Debug("Enter Invoke")
+	function, args := stub.GetFunctionAndParameters()
+	smklogger.Debug("func =%s, args = %+v", function, args)
+	var err error
+
+	//args:[usrname, accname, ..., signature]  用户和签名已在base中校验过
+	var fixedArgCount = ifas.FixedArgCount
+	if len(args) < fixedArgCount {
+		smklogger.Error("Invoke miss arg, got %d, at least need %d.", len(args), fixedArgCount)
+		return nil, errors.New("Invoke miss arg.")
+	}
+
+	var accName = ifas.AccountName
+	var times = ifas.InvokeTime
+
+	//发行、开户等由base处理。这里的转账需要指定dfid子账户，base的transefer等在invokeFilter中已禁用
+	if function == "transeferDfid" {
+		var argCount = fixedArgCount + 4
+		if len(args) < argCount {
+			smklogger.Error("Invoke(transeferDfid) miss arg, got %d, at least need %d.", len(args), argCount)
+			return nil, errors.New("Invoke(transeferDfid) miss arg.")
+		}
+
+		var toAcc = args[fixedArgCount]
+		var transType = args[fixedArgCount+1]
+		var dfId = args[fixedArgCount+2]
+
+		var transAmount int64
+		transAmount, err = strconv.ParseInt(args[fixedArgCount+3], 0, 64)
+		if err != nil {
+			smklogger.Error("convert transAmount(%s) failed. err=%s", args[fixedArgCount+3], err)
+			return nil, errors.New("convert transAmount failed.")
+		}
+		smklogger.Debug("transAmount= %v", transAmount)
+
+		return t.transferCoin(stub, accName, toAcc, dfId, transType, transAmount, times)
+
+	} else if function == "trace" {
+		var argCount = fixedArgCount + 2
+		if len(args) < argCount {
+			smklogger.Error("Invoke(transefer) miss arg, got %d, at least need %d.", len(args), argCount)
+			return nil, errors.New("Invoke(transefer) miss arg.")
+		}
+
+		var wareId = args[fixedArgCount]
+		var msg = args[fixedArgCount+1]
+
+		//旧接口，作为无类型的事件记录，商品没有登记时自动登记
+		ware, err := t.getTraceWare(stub, wareId)
+		if err != nil {
+			smklogger.Error("Invoke(trace) getTraceWare failed. err=%s", err)
+			return nil, errors.New("Invoke(trace) getTraceWare failed.")
+		}
+		if ware == nil {
+			ware, err = t.registerTraceWare(stub, wareId, "", "", accName, times)
+			if err != nil {
+				smklogger.Error("Invoke(trace) registerTraceWare failed. err=%s", err)
+				return nil, errors.New("Invoke(trace) registerTraceWare failed.")
+			}
+		} else if !t.canUpdateTraceWare(stub, ware, accName) {
+			smklogger.Error("Invoke(trace) %s can't trace ware(%s) of %s.", accName, wareId, ware.Creator)
+			return nil, errors.New("Invoke(trace) can't trace ware of others.")
+		}
+
+		return t.recordTraceEvent(stub, ware, TRACE_EVT_NOTE, accName, "", "", msg, times)
+
+	} else if function == "registerWare" { //登记商品，指定批次和父商品
+		var argCount = fixedArgCount + 3
+		if len(args) < argCount {
+			smklogger.Error("Invoke(registerWare) miss arg, got %d, at least need %d.", len(args), argCount)
+			return nil, errors.New("Invoke(registerWare) miss arg.")
+		}
+
+		var wareId = args[fixedArgCount]
+		var batchId = args[fixedArgCount+1]
+		var parentId = args[fixedArgCount+2]
+
+		ware, err := t.getTraceWare(stub, wareId)
+		if err != nil {
+			smklogger.Error("Invoke(registerWare) getTraceWare failed. err=%s", err)
+			return nil, errors.New("Invoke(registerWare) getTraceWare failed.")
+		}
+		if ware != nil {
+			smklogger.Error("Invoke(registerWare) ware(%s) exists already.", wareId)
+			return nil, errors.New("Invoke(registerWare) ware exists already.")
+		}
+
+		ware, err = t.registerTraceWare(stub, wareId, batchId, parentId, accName, times)
+		if err != nil {
+			smklogger.Error("Invoke(registerWare) registerTraceWare failed. err=%s", err)
+			return nil, err
+		}
+
+		return json.Marshal(ware)
+
+	} else if function == "traceEvent" { //记录溯源事件
+		var argCount = fixedArgCount + 5
+		if len(args) < argCount {
+			smklogger.Error("Invoke(traceEvent) miss arg, got %d, at least need %d.", len(args), argCount)
+			return nil, errors.New("Invoke(traceEvent) miss arg.")
+		}
+
+		var wareId = args[fixedArgCount]
+		var eventType = args[fixedArgCount+1]
+		var location = args[fixedArgCount+2]
+		var attachHash = args[fixedArgCount+3]
+		var msg = args[fixedArgCount+4]
+
+		if !t.isTraceEventTypeValid(eventType) {
+			smklogger.Error("Invoke(traceEvent) invalid eventType '%s'.", eventType)
+			return nil, errors.New("Invoke(traceEvent) invalid eventType.")
+		}
+
+		ware, err := t.getTraceWare(stub, wareId)
+		if err != nil {
+			smklogger.Error("Invoke(traceEvent) getTraceWare failed. err=%s", err)
+			return nil, errors.New("Invoke(traceEvent) getTraceWare failed.")
+		}
+		if ware == nil {
+			smklogger.Error("Invoke(traceEvent) ware(%s) not registered.", wareId)
+			return nil, errors.New("Invoke(traceEvent) ware not registered.")
+		}
+		if !t.canUpdateTraceWare(stub, ware, accName) {
+			smklogger.Error("Invoke(traceEvent) %s can't trace ware(%s) of %s.", accName, wareId, ware.Creator)
+			return nil, errors.New("Invoke(traceEvent) can't trace ware of others.")
+		}
+
+		return t.recordTraceEvent(stub, ware, eventType, accName, location, attachHash, msg, times)
+	} else if function == "debt" {
+		var argCount = fixedArgCount + 4
+		if len(args) < argCount {
+			smklogger.Error("Invoke(debt) miss arg, got %d, at least need %d.", len(args), argCount)
+			return nil, errors.New("Invoke(debt) miss arg.")
+		}
+
+		var dfId = args[fixedArgCount]
+		var debtInfo = args[fixedArgCount+1]
+		var contractNo = args[fixedArgCount+2]
+		var debtAmout = args[fixedArgCount+3]
+
+		var dfInfoKey = t.getDfInfoKey(dfId)
+		var dfB, dfJson []byte
+		var df DebtFinance
+
+		dfB, err = stateCache.GetState_Ex(stub, dfInfoKey)
+		if err != nil {
+			smklogger.Error("Invoke(debt) GetState(%s) failed. err=%s.", dfInfoKey, err)
+			return nil, errors.New("Invoke(debt) GetState failed.")
+		}
+		if dfB != nil {
+			smklogger.Error("Invoke(debt) DebtFinance exists already.")
+			return nil, errors.New("Invoke(debt) DebtFinance exists already.")
+		}
+
+		df.DFID = dfId
+		df.DebtInfo = debtInfo
+		df.DebtContractNo = contractNo
+		df.DebtTime = times
+		df.DebtAmount = debtAmout
+
+		dfJson, err = json.Marshal(df)
+		if err != nil {
+			smklogger.Error("Invoke(debt) Marshal failed. err=%s.", err)
+			return nil, errors.New("Invoke(debt) Marshal failed.")
+		}
+
+		err = stateCache.PutState_Ex(stub, dfInfoKey, dfJson)
+		if err != nil {
+			smklogger.Error("Invoke(debt) PutState failed. err=%s.", err)
+			return nil, errors.New("Invoke(debt) PutState failed.")
+		}
+
+		return nil, nil
+	} else if function == "finance" {
+		var argCount = fixedArgCount + 4
+		if len(args) < argCount {
+			smklogger.Error("Invoke(finance) miss arg, got %d, at least need %d.", len(args), argCount)
+			return nil, errors.New("Invoke(finance) miss arg.")
+		}
+
+		var dfId = args[fixedArgCount]
+		var finacInfo = args[fixedArgCount+1]
+		var contractNo = args[fixedArgCount+2]
+		var finacAmout = args[fixedArgCount+3]
+
+		var dfInfoKey = t.getDfInfoKey(dfId)
+		var dfB, dfJson []byte
+		var df DebtFinance
+
+		dfB, err = stateCache.GetState_Ex(stub, dfInfoKey)
+		if err != nil {
+			smklogger.Error("Invoke(finance) GetState(%s) failed. err=%s.", dfInfoKey, err)
+			return nil, errors.New("Invoke(finance) GetState failed.")
+		}
+		//如果没有供应商记入的欠款信息，则不能融资
+		if dfB == nil {
+			smklogger.Error("Invoke(finance) DebtInfo not exists.")
+			return nil, errors.New("Invoke(finance) DebtInfo not exists.")
+		}
+
+		err = json.Unmarshal(dfB, &df)
+		if err != nil {
+			smklogger.Error("Invoke(finance) Unmarshal failed. err=%s.", err)
+			return nil, errors.New("Invoke(finance) Unmarshal failed.")
+		}
+
+		//如果已发起过一次融资，则不能再次发起
+		if df.FinanceTime > 0 {
+			smklogger.Error("Invoke(finance) finance already.")
+			return nil, errors.New("Invoke(finance) finance already.")
+		}
+
+		df.FinanceInfo = finacInfo
+		df.FinanceTime = times
+		df.FinanceContractNo = contractNo
+		df.FinanceAmount = finacAmout
+
+		dfJson, err = json.Marshal(df)
+		if err != nil {
+			smklogger.Error("Invoke(finance) Marshal failed. err=%s.", err)
+			return nil, errors.New("Invoke(finance) Marshal failed.")
+		}
+
+		err = stateCache.PutState_Ex(stub, dfInfoKey, dfJson)
+		if err != nil {
+			smklogger.Error("Invoke(finance) PutState failed. err=%s.", err)
+			return nil, errors.New("Invoke(finance) PutState failed.")
+		}
+
+		return nil, nil
+	} else if function == "regReceivable" { //供应商登记应收账款
+		var argCount = fixedArgCount + 6
+		if len(args) < argCount {
+			smklogger.Error("Invoke(regReceivable) miss arg, got %d, at least need %d.", len(args), argCount)
+			return nil, errors.New("Invoke(regReceivable) miss arg.")
+		}
+
+		var dfId = args[fixedArgCount]
+		var coreEnt = args[fixedArgCount+1]
+		amount, err := strconv.ParseInt(args[fixedArgCount+2], 0, 64)
+		if err != nil || amount <= 0 {
+			smklogger.Error("Invoke(regReceivable) invalid amount(%s). err=%v", args[fixedArgCount+2], err)
+			return nil, errors.New("Invoke(regReceivable) invalid amount.")
+		}
+		dueTime, err := strconv.ParseInt(args[fixedArgCount+3], 0, 64)
+		if err != nil || dueTime <= times {
+			smklogger.Error("Invoke(regReceivable) invalid dueTime(%s). err=%v", args[fixedArgCount+3], err)
+			return nil, errors.New("Invoke(regReceivable) invalid dueTime.")
+		}
+		var contractNo = args[fixedArgCount+4]
+		var info = args[fixedArgCount+5]
+
+		if coreEnt == accName {
+			smklogger.Error("Invoke(regReceivable) coreEnt can't be supplier itself.")
+			return nil, errors.New("Invoke(regReceivable) coreEnt can't be supplier itself.")
+		}
+		exists, errcm := Base.IsAccEntityExists(stub, coreEnt)
+		if errcm != nil || !exists {
+			smklogger.Error("Invoke(regReceivable) coreEnt(%s) not exists. err=%v", coreEnt, errcm)
+			return nil, errors.New("Invoke(regReceivable) coreEnt not exists.")
+		}
+
+		//和旧的欠款融资共用DfId，不能重复
+		dfB, err := stateCache.GetState_Ex(stub, t.getDfInfoKey(dfId))
+		if err != nil {
+			smklogger.Error("Invoke(regReceivable) GetState(df) failed. err=%s.", err)
+			return nil, errors.New("Invoke(regReceivable) GetState failed.")
+		}
+		rf, err := t.getReceivable(stub, dfId)
+		if err != nil {
+			return nil, errors.New("Invoke(regReceivable) getReceivable failed.")
+		}
+		if dfB != nil || rf != nil {
+			smklogger.Error("Invoke(regReceivable) DfId(%s) exists already.", dfId)
+			return nil, errors.New("Invoke(regReceivable) DfId exists already.")
+		}
+
+		rf = &ReceivableFinance{}
+		rf.DFID = dfId
+		rf.Supplier = accName
+		rf.CoreEnt = coreEnt
+		rf.Amount = amount
+		rf.DueTime = dueTime
+		rf.ContractNo = contractNo
+		rf.Info = info
+		rf.Status = RECV_STAT_REGISTERED
+		rf.RegTime = times
+		rf.Repayments = []RecvRepayment{}
+
+		return t.setReceivable(stub, rf)
+
+	} else if function == "confirmReceivable" { //核心企业确认应收账款
+		var argCount = fixedArgCount + 1
+		if len(args) < argCount {
+			smklogger.Error("Invoke(confirmReceivable) miss arg, got %d, at least need %d.", len(args), argCount)
+			return nil, errors.New("Invoke(confirmReceivable) miss arg.")
+		}
+
+		rf, err := t.getReceivable(stub, args[fixedArgCount])
+		if err != nil {
+			return nil, errors.New("Invoke(confirmReceivable) getReceivable failed.")
+		}
+		if rf == nil {
+			smklogger.Error("Invoke(confirmReceivable) receivable(%s) not exists.", args[fixedArgCount])
+			return nil, errors.New("Invoke(confirmReceivable) receivable not exists.")
+		}
+		if rf.CoreEnt != accName {
+			smklogger.Error("Invoke(confirmReceivable) %s is not the coreEnt of %s.", accName, rf.DFID)
+			return nil, errors.New("Invoke(confirmReceivable) only coreEnt can confirm.")
+		}
+		if rf.Status != RECV_STAT_REGISTERED {
+			smklogger.Error("Invoke(confirmReceivable) invalid status(%d).", rf.Status)
+			return nil, errors.New("Invoke(confirmReceivable) invalid status.")
+		}
+
+		rf.Status = RECV_STAT_CONFIRMED
+		rf.ConfirmTime = times
+
+		return t.setReceivable(stub, rf)
+
+	} else if function == "approveRecvFinancier" { //供应商指定放款的金融机构
+		var argCount = fixedArgCount + 2
+		if len(args) < argCount {
+			smklogger.Error("Invoke(approveRecvFinancier) miss arg, got %d, at least need %d.", len(args), argCount)
+			return nil, errors.New("Invoke(approveRecvFinancier) miss arg.")
+		}
+
+		var dfId = args[fixedArgCount]
+		var financier = args[fixedArgCount+1]
+
+		rf, err := t.getReceivable(stub, dfId)
+		if err != nil {
+			return nil, errors.New("Invoke(approveRecvFinancier) getReceivable failed.")
+		}
+		if rf == nil {
+			smklogger.Error("Invoke(approveRecvFinancier) receivable(%s) not exists.", dfId)
+			return nil, errors.New("Invoke(approveRecvFinancier) receivable not exists.")
+		}
+		if rf.Supplier != accName {
+			smklogger.Error("Invoke(approveRecvFinancier) %s is not the supplier of %s.", accName, dfId)
+			return nil, errors.New("Invoke(approveRecvFinancier) only supplier can approve financier.")
+		}
+		if rf.Status != RECV_STAT_REGISTERED && rf.Status != RECV_STAT_CONFIRMED {
+			smklogger.Error("Invoke(approveRecvFinancier) invalid status(%d).", rf.Status)
+			return nil, errors.New("Invoke(approveRecvFinancier) invalid status.")
+		}
+		if financier == rf.Supplier || financier == rf.CoreEnt {
+			smklogger.Error("Invoke(approveRecvFinancier) %s can't be financier.", financier)
+			return nil, errors.New("Invoke(approveRecvFinancier) invalid financier.")
+		}
+		exists, errcm := Base.IsAccEntityExists(stub, financier)
+		if errcm != nil || !exists {
+			smklogger.Error("Invoke(approveRecvFinancier) financier(%s) not exists. err=%v", financier, errcm)
+			return nil, errors.New("Invoke(approveRecvFinancier) financier not exists.")
+		}
+
+		rf.Financier = financier
+
+		return t.setReceivable(stub, rf)
+
+	} else if function == "disburseReceivable" { //金融机构放款给供应商
+		var argCount = fixedArgCount + 3
+		if len(args) < argCount {
+			smklogger.Error("Invoke(disburseReceivable) miss arg, got %d, at least need %d.", len(args), argCount)
+			return nil, errors.New("Invoke(disburseReceivable) miss arg.")
+		}
+
+		var dfId = args[fixedArgCount]
+		amount, err := strconv.ParseInt(args[fixedArgCount+1], 0, 64)
+		if err != nil {
+			smklogger.Error("Invoke(disburseReceivable) convert amount(%s) failed. err=%s", args[fixedArgCount+1], err)
+			return nil, errors.New("Invoke(disburseReceivable) convert amount failed.")
+		}
+		var srcDfId = args[fixedArgCount+2] //放款资金所在的子账户
+
+		rf, err := t.getReceivable(stub, dfId)
+		if err != nil {
+			return nil, errors.New("Invoke(disburseReceivable) getReceivable failed.")
+		}
+		if rf == nil {
+			smklogger.Error("Invoke(disburseReceivable) receivable(%s) not exists.", dfId)
+			return nil, errors.New("Invoke(disburseReceivable) receivable not exists.")
+		}
+		if rf.Status != RECV_STAT_CONFIRMED {
+			smklogger.Error("Invoke(disburseReceivable) invalid status(%d).", rf.Status)
+			return nil, errors.New("Invoke(disburseReceivable) invalid status.")
+		}
+		if times > rf.DueTime {
+			smklogger.Error("Invoke(disburseReceivable) receivable(%s) is overdue.", dfId)
+			return nil, errors.New("Invoke(disburseReceivable) receivable is overdue.")
+		}
+		if amount <= 0 || amount > rf.Amount-rf.RepaidAmount {
+			smklogger.Error("Invoke(disburseReceivable) invalid amount(%d), rest receivable is %d.", amount, rf.Amount-rf.RepaidAmount)
+			return nil, errors.New("Invoke(disburseReceivable) invalid amount.")
+		}
+		if len(rf.Financier) == 0 || accName != rf.Financier {
+			smklogger.Error("Invoke(disburseReceivable) %s is not the financier(%s) approved by supplier.", accName, rf.Financier)
+			return nil, errors.New("Invoke(disburseReceivable) invalid financier.")
+		}
+
+		_, err = t.transferCoinEx(stub, accName, rf.Supplier, srcDfId, dfId, RECV_TRANS_DISBURSE, amount, times, true)
+		if err != nil {
+			smklogger.Error("Invoke(disburseReceivable) transferCoinEx failed. err=%s", err)
+			return nil, err
+		}
+
+		rf.FinanceAmount = amount
+		rf.FinanceTime = times
+		rf.Status = RECV_STAT_FINANCED
+
+		return t.setReceivable(stub, rf)
+
+	} else if function == "repayReceivable" { //核心企业还款。放款后还给金融机构，未放款时还给供应商
+		var argCount = fixedArgCount + 3
+		if len(args) < argCount {
+			smklogger.Error("Invoke(repayReceivable) miss arg, got %d, at least need %d.", len(args), argCount)
+			return nil, errors.New("Invoke(repayReceivable) miss arg.")
+		}
+
+		var dfId = args[fixedArgCount]
+		amount, err := strconv.ParseInt(args[fixedArgCount+1], 0, 64)
+		if err != nil {
+			smklogger.Error("Invoke(repayReceivable) convert amount(%s) failed. err=%s", args[fixedArgCount+1], err)
+			return nil, errors.New("Invoke(repayReceivable) convert amount failed.")
+		}
+		var srcDfId = args[fixedArgCount+2] //还款资金所在的子账户
+
+		rf, err := t.getReceivable(stub, dfId)
+		if err != nil {
+			return nil, errors.New("Invoke(repayReceivable) getReceivable failed.")
+		}
+		if rf == nil {
+			smklogger.Error("Invoke(repayReceivable) receivable(%s) not exists.", dfId)
+			return nil, errors.New("Invoke(repayReceivable) receivable not exists.")
+		}
+		if rf.CoreEnt != accName {
+			smklogger.Error("Invoke(repayReceivable) %s is not the coreEnt of %s.", accName, dfId)
+			return nil, errors.New("Invoke(repayReceivable) only coreEnt can repay.")
+		}
+		if rf.Status != RECV_STAT_CONFIRMED && rf.Status != RECV_STAT_FINANCED {
+			smklogger.Error("Invoke(repayReceivable) invalid status(%d).", rf.Status)
+			return nil, errors.New("Invoke(repayReceivable) invalid status.")
+		}
+		if amount <= 0 || amount > rf.Amount-rf.RepaidAmount {
+			smklogger.Error("Invoke(repayReceivable) invalid amount(%d), rest is %d.", amount, rf.Amount-rf.RepaidAmount)
+			return nil, errors.New("Invoke(repayReceivable) invalid amount.")
+		}
+		//限制还款记录的条数，最后一次还款必须还清
+		if len(rf.Repayments) >= RECV_MAX_REPAYMENTS-1 && amount < rf.Amount-rf.RepaidAmount {
+			smklogger.Error("Invoke(repayReceivable) repaid %d times, must repay all the rest(%d).", len(rf.Repayments), rf.Amount-rf.RepaidAmount)
+			return nil, errors.New("Invoke(repayReceivable) must repay all the rest.")
+		}
+
+		var payee = rf.Supplier
+		if rf.Status == RECV_STAT_FINANCED {
+			payee = rf.Financier
+		}
+
+		_, err = t.transferCoinEx(stub, accName, payee, srcDfId, dfId, RECV_TRANS_REPAY, amount, times, true)
+		if err != nil {
+			smklogger.Error("Invoke(repayReceivable) transferCoinEx failed. err=%s", err)
+			return nil, err
+		}
+
+		rf.RepaidAmount += amount
+		rf.Repayments = append(rf.Repayments, RecvRepayment{Payer: accName, Payee: payee, Amount: amount, Time: times, TxID: stub.GetTxID()})
+		if times > rf.DueTime {
+			rf.Overdue = true
+		}
+		if rf.RepaidAmount >= rf.Amount {
+			rf.Status = RECV_STAT_SETTLED
+			rf.SettleTime = times
+		}
+
+		return t.setReceivable(stub, rf)
+	} else {
+		//base中没有处理的函数，也走Invoke的hook，这里再交给Query处理
+		return t.Query(stub, ifas)
+	}
+}
+
+func (t *SMK) Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs) ([]byte, error) {
+	smklogger.Debug("Enter Query")
+	function, args := stub.GetFunctionAndParameters()
+	smklogger.Debug("func =%s, args = %+v", function, args)
+
+	var fixedArgCount = ifas.FixedArgCount
+	var accName = ifas.AccountName
+
+	//余额、交易记录、账户是否存在等由base的getBalance、getTransInfo、isAccExists处理
+	if function == "query" { //查询本账户的余额及dfid子账户
+		accDfId, err := t.getAccountDfId(stub, accName)
+		if err != nil {
+			smklogger.Error("query getAccountDfId(%s) failed. err=%s", accName, err)
+			return nil, errors.New("query getAccountDfId failed.")
+		}
+
+		var qEnt QueryEntity
+		qEnt.EntID = ifas.AccountEnt.EntID
+		qEnt.TotalAmount = ifas.AccountEnt.RestAmount
+		qEnt.DFIdMap = accDfId.DFIdMap
+
+		retValue, err := json.Marshal(qEnt)
+		if err != nil {
+			smklogger.Error("query Marshal failed. err=%s", err)
+			return nil, errors.New("query Marshal failed.")
+		}
+
+		return retValue, nil
+	} else if function == "queryAllAcc" { //查询有dfid子账户的所有账户，只有管理员可以查询
+		var argCount = fixedArgCount + 2
+		if len(args) < argCount {
+			smklogger.Error("queryAllAcc miss arg, got %d, need %d.", len(args), argCount)
+			return nil, errors.New("queryAllAcc miss arg.")
+		}
+
+		if !Base.IsAdmin(stub, accName) {
+			smklogger.Error("queryAllAcc can't exec by %s.", accName)
+			return nil, errors.New("queryAllAcc no permission.")
+		}
+
+		begIdx, err := strconv.ParseInt(args[fixedArgCount], 0, 64)
+		if err != nil {
+			smklogger.Error("queryAllAcc ParseInt(%s) failed.", args[fixedArgCount])
+			return nil, errors.New("queryAllAcc ParseInt failed.")
+		}
+
+		endIdx, err := strconv.ParseInt(args[fixedArgCount+1], 0, 64)
+		if err != nil {
+			smklogger.Error("queryAllAcc ParseInt(%s) failed.", args[fixedArgCount+1])
+			return nil, errors.New("queryAllAcc ParseInt failed.")
+		}
+
+		//begIdx从1开始
+		if begIdx <= 0 {
+			begIdx = 1
+		}
+		if endIdx < 0 {
+			endIdx = math.MaxInt64
+		}
+		return t.queryAllAcc(stub, begIdx, endIdx, false)
+
+	} else if function == "queryTrace" {
+		var argCount = fixedArgCount + 1
+		if len(args) < argCount {
+			smklogger.Error("queryTrace miss arg, got %d, need %d.", len(args), argCount)
+			return nil, errors.New("queryTrace miss arg.")
+		}
+		wareId := args[fixedArgCount]
+
+		//参数: wareId, begSeq, count, eventType, actor, begTime, endTime。 只传wareId时按旧格式返回所有事件的数组
+		var legacyFmt = true
+		var begSeq int64 = 1
+		var count int64 = -1
+		var eventType string
+		var actor string
+		var begTime int64 = 0
+		var endTime int64 = math.MaxInt64
+		if len(args) > fixedArgCount+6 { //最后一个参数为签名
+			var err error
+			var vals [4]int64
+			var idxs = [4]int{fixedArgCount + 1, fixedArgCount + 2, fixedArgCount + 5, fixedArgCount + 6}
+			for i, idx := range idxs {
+				vals[i], err = strconv.ParseInt(args[idx], 0, 64)
+				if err != nil {
+					smklogger.Error("queryTrace ParseInt(%s) failed. err=%s", args[idx], err)
+					return nil, errors.New("queryTrace ParseInt failed.")
+				}
+			}
+			begSeq = vals[0]
+			count = vals[1]
+			begTime = vals[2]
+			endTime = vals[3]
+			eventType = args[fixedArgCount+3]
+			actor = args[fixedArgCount+4]
+			legacyFmt = false
+
+			if begSeq <= 0 {
+				begSeq = 1
+			}
+			if endTime < 0 {
+				endTime = math.MaxInt64
+			}
+		}
+
+		return t.queryTraceEvents(stub, wareId, begSeq, count, eventType, actor, begTime, endTime, legacyFmt)
+
+	} else if function == "queryWare" {
+		var argCount = fixedArgCount + 1
+		if len(args) < argCount {
+			smklogger.Error("queryWare miss arg, got %d, need %d.", len(args), argCount)
+			return nil, errors.New("queryWare miss arg.")
+		}
+
+		ware, err := t.getTraceWare(stub, args[fixedArgCount])
+		if err != nil {
+			smklogger.Error("queryWare getTraceWare failed. err=%s", err)
+			return nil, errors.New("queryWare getTraceWare failed.")
+		}
+		if ware == nil {
+			return []byte("{}"), nil
+		}
+
+		return json.Marshal(ware)
+
+	} else if function == "queryWareChildren" || function == "queryBatch" { //分页查询子商品id或批次中的商品id
+		var argCount = fixedArgCount + 1
+		if len(args) < argCount {
+			smklogger.Error("%s miss arg, got %d, need %d.", function, len(args), argCount)
+			return nil, errors.New(function + " miss arg.")
+		}
+		var id = args[fixedArgCount]
+
+		//参数: 商品id或批次id, begSeq, count。只传id时返回全部
+		var begSeq int64 = 1
+		var count int64 = -1
+		if len(args) > fixedArgCount+3 { //最后一个参数为签名
+			var err error
+			begSeq, err = strconv.ParseInt(args[fixedArgCount+1], 0, 64)
+			if err != nil {
+				smklogger.Error("%s ParseInt(%s) failed. err=%s", function, args[fixedArgCount+1], err)
+				return nil, errors.New(function + " ParseInt failed.")
+			}
+			count, err = strconv.ParseInt(args[fixedArgCount+2], 0, 64)
+			if err != nil {
+				smklogger.Error("%s ParseInt(%s) failed. err=%s", function, args[fixedArgCount+2], err)
+				return nil, errors.New(function + " ParseInt failed.")
+			}
+		}
+
+		var total int64
+		var getKey func(seq int64) string
+		if function == "queryWareChildren" {
+			ware, err := t.getTraceWare(stub, id)
+			if err != nil {
+				smklogger.Error("queryWareChildren getTraceWare failed. err=%s", err)
+				return nil, errors.New("queryWareChildren getTraceWare failed.")
+			}
+			if ware != nil {
+				total = ware.ChildCount
+			}
+			getKey = func(seq int64) string { return t.getTraceChildKey(id, seq) }
+		} else {
+			batch, err := t.getTraceBatch(stub, id)
+			if err != nil {
+				smklogger.Error("queryBatch getTraceBatch failed. err=%s", err)
+				return nil, errors.New("queryBatch getTraceBatch failed.")
+			}
+			if batch != nil {
+				total = batch.WareCount
+			}
+			getKey = func(seq int64) string { return t.getTraceBatchWareKey(id, seq) }
+		}
+
+		return t.queryTraceIdList(stub, getKey, total, begSeq, count)
+
+	} else if function == "queryDfid" {
+		var argCount = fixedArgCount + 1
+		if len(args) < argCount {
+			smklogger.Error("queryDfid miss arg, got %d, need %d.", len(args), argCount)
+			return nil, errors.New("queryDfid miss arg.")
+		}
+		dfId := args[fixedArgCount]
+
+		//先获取DebtFinance的信息
+		var qdf QueryDfidTransInfo
+		dfB, err := stateCache.GetState_Ex(stub, t.getDfInfoKey(dfId))
+		if err != nil {
+			smklogger.Error("queryDfid GetState(DfInfo) failed. err=%s", err)
+			return nil, errors.New("queryDfid GetState failed.")
+		}
+		if dfB == nil {
+			smklogger.Info("queryDfid no dfInfo for such id '%s'.", dfId)
+			//不存在则返回空结构
+			return []byte("{}"), nil
+		}
+
+		err = json.Unmarshal(dfB, &qdf.DebtFinance)
+		if err != nil {
+			smklogger.Error("queryDfid Unmarshal(DebtFinance) failed. err=%s", err)
+			return nil, errors.New("queryDfid Unmarshal failed.")
+		}
+
+		qdf.TransInfoList, err = t.getDfIdTransInfoList(stub, dfId)
+		if err != nil {
+			return nil, errors.New("queryDfid getDfIdTransInfoList failed.")
+		}
+
+		retValues, err := json.Marshal(qdf)
+		if err != nil {
+			smklogger.Error("queryDfid Marshal(qdf) failed. err=%s", err)
+			return nil, errors.New("queryDfid Marshal(qdf) failed.")
+		}
+
+		return retValues, nil
+	} else if function == "queryReceivable" {
+		var argCount = fixedArgCount + 1
+		if len(args) < argCount {
+			smklogger.Error("queryReceivable miss arg, got %d, need %d.", len(args), argCount)
+			return nil, errors.New("queryReceivable miss arg.")
+		}
+		dfId := args[fixedArgCount]
+
+		//用调用时间判断是否逾期
+		var queryTime = ifas.InvokeTime
+
+		rf, err := t.getReceivable(stub, dfId)
+		if err != nil {
+			return nil, errors.New("queryReceivable getReceivable failed.")
+		}
+		if rf == nil {
+			return []byte("{}"), nil
+		}
+
+		var qr QueryReceivable
+		qr.ReceivableFinance = *rf
+		if qr.Status != RECV_STAT_SETTLED && queryTime > qr.DueTime {
+			qr.Status = RECV_STAT_OVERDUE
+		}
+
+		qr.TransInfoList, err = t.getDfIdTransInfoList(stub, dfId)
+		if err != nil {
+			return nil, errors.New("queryReceivable getDfIdTransInfoList failed.")
+		}
+
+		retValues, err := json.Marshal(qr)
+		if err != nil {
+			smklogger.Error("queryReceivable Marshal failed. err=%s", err)
+			return nil, errors.New("queryReceivable Marshal failed.")
+		}
+
+		return retValues, nil
+	}
+
+	smklogger.Error("unknown function:%s.", function)
+	return nil, errors.New("unknown function.")
+}
+
+//begIdx从1开始
+func (t *SMK) queryAllAcc(stub shim.ChaincodeStubInterface, begIdx, endIdx int64, all bool) ([]byte, error) {
+	if endIdx < begIdx {
+		smklogger.Warn("queryAllAcc nothing to do(%d,%d).", begIdx, endIdx)
+		return []byte("[]"), nil
+	}
+
+	allAccs, err := t.getAllAccountNames(stub)
+	if err != nil {
+		smklogger.Error("queryAllAcc getAllAccountNames failed. err=%s", err)
+		return nil, errors.New("queryAllAcc getAllAccountNames failed.")
+	}
+	allAccCnt := int64(len(allAccs))
+	if endIdx > allAccCnt {
+		endIdx = allAccCnt
+		if endIdx < begIdx {
+			smklogger.Warn("queryAllAcc nothing to do2(%d,%d).", begIdx, endIdx)
+			return []byte("[]"), nil
+		}
+	}
+
+	//begIdx从1开始，自然计数法，所以这里要减1
+	allAccs = allAccs[begIdx-1 : endIdx]
+
+	var accInfo []QueryEntity = []QueryEntity{}
+
+	for _, acc := range allAccs {
+		ent, errcm := Base.GetAccountEntity(stub, acc)
+		if errcm != nil {
+			smklogger.Error("queryAllAcc getAccountEntity(%s) failed. err=%s", acc, errcm)
+			continue
+		}
+		accDfId, err := t.getAccountDfId(stub, acc)
+		if err != nil {
+			smklogger.Error("queryAllAcc getAccountDfId(%s) failed. err=%s", acc, err)
+			continue
+		}
+		if !all && len(accDfId.DFIdMap) == 0 {
+			continue
+		}
+
+		accInfo = append(accInfo, QueryEntity{EntID: ent.EntID, TotalAmount: ent.TotalAmount, DFIdMap: accDfId.DFIdMap})
+	}
+
+	retValue, err := json.Marshal(accInfo)
+	if err != nil {
+		smklogger.Error("queryAllAcc Marshal failed. err=%s", err)
+		return nil, errors.New("queryAllAcc Marshal failed.")
+	}
+
+	return retValue, nil
+}
+
+//base中保存的所有账户名，央行账户不在其中
+func (t *SMK) getAllAccountNames(stub shim.ChaincodeStubInterface) ([]string, error) {
+
+	accB, err := stateCache.GetState_Ex(stub, ALL_ACC_INFO_KEY)
+	if err != nil {
+		smklogger.Error("getAllAccountNames GetState failed.err=%s", err)
+		return nil, errors.New("getAllAccountNames GetState failed.")
+	}
+
+	var result []string
+	if accB == nil {
+		return result, nil
+	}
+
+	var allAccs = bytes.NewBuffer(accB)
+	var acc []byte
+	for {
+		acc, err = allAccs.ReadBytes(MULTI_STRING_DELIM)
+		if err != nil {
+			if err == io.EOF {
+				break
+			} else {
+				smklogger.Error("getAllAccountNames ReadBytes failed. err=%s", err)
+				continue
+			}
+		}
+		acc = acc[:len(acc)-1] //去掉末尾的分隔符
+		result = append(result, string(acc))
+	}
+
+	return result, nil
+}
+
+func (t *SMK) getAccountDfIdKey(accName string) string {
+	return ACC_DFID_PREFIX + accName
+}
+
+//没有保存过dfid子账户的账户（如base中新开的账户），返回空的子账户
+func (t *SMK) getAccountDfId(stub shim.ChaincodeStubInterface, accName string) (*AccountDfId, error) {
+	var accDfId AccountDfId
+
+	adB, err := stateCache.GetState_Ex(stub, t.getAccountDfIdKey(accName))
+	if err != nil {
+		smklogger.Error("getAccountDfId GetState failed. err=%s", err)
+		return nil, err
+	}
+	if adB != nil {
+		err = json.Unmarshal(adB, &accDfId)
+		if err != nil {
+			smklogger.Error("getAccountDfId Unmarshal failed. err=%s", err)
+			return nil, err
+		}
+	}
+
+	accDfId.EntID = accName
+	if accDfId.DFIdMap == nil {
+		accDfId.DFIdMap = make(map[string]int64)
+	}
+
+	return &accDfId, nil
+}
+
+func (t *SMK) setAccountDfId(stub shim.ChaincodeStubInterface, accDfId *AccountDfId) error {
+	adJson, err := json.Marshal(accDfId)
+	if err != nil {
+		smklogger.Error("setAccountDfId Marshal failed. err=%s", err)
+		return err
+	}
+
+	err = stateCache.PutState_Ex(stub, t.getAccountDfIdKey(accDfId.EntID), adJson)
+	if err != nil {
+		smklogger.Error("setAccountDfId PutState failed. err=%s", err)
+		return err
+	}
+	return nil
+}
+
+//转账
+func (t *SMK) transferCoin(stub shim.ChaincodeStubInterface, from, to, dfId, transType string, amount, times int64) ([]byte, error) {
+	return t.transferCoinEx(stub, from, to, dfId, dfId, transType, amount, times, false)
+}
+
+//转账。从转出账户的srcDfId子账户转到转入账户的dstDfId子账户；forceRecordDfid为true时，该交易总是记录到dstDfId的交易记录里
+//余额的变动和交易记录由base的transferCoin处理，这里只处理dfid子账户
+func (t *SMK) transferCoinEx(stub shim.ChaincodeStubInterface, from, to, srcDfId, dstDfId, transType string, amount, times int64, forceRecordDfid bool) ([]byte, error) {
+	smklogger.Debug("Enter transferCoin")
+
+	if amount <= 0 {
+		smklogger.Error("transferCoin failed. invalid amount(%d)", amount)
+		return nil, errors.New("transferCoin failed. invalid amount.")
+	}
+	if from == to {
+		smklogger.Error("transferCoin from equals to.")
+		return nil, errors.New("transferCoin from equals to.")
+	}
+
+	cbAccB, errcm := Base.GetCenterBankAcc(stub)
+	if errcm != nil {
+		smklogger.Error("transferCoin getCenterBankAcc failed. err=%s", errcm)
+		return nil, errors.New("transferCoin getCenterBankAcc failed.")
+	}
+	var cbAcc = string(cbAccB)
+
+	var recordDfid string = ""
+
+	//如果转出账户是央行账户，那么直接转出，无需处理子账户。因为央行账户没有以融资id的子账户
+	if from != cbAcc {
+		fromDfId, err := t.getAccountDfId(stub, from)
+		if err != nil {
+			return nil, errors.New("transferCoin getAccountDfId(from) failed.")
+		}
+		v, ok := fromDfId.DFIdMap[srcDfId]
+		if !ok {
+			smklogger.Error("transferCoin: fromEntity(id=%s) has no such dfid '%s'.", from, srcDfId)
+			return nil, errors.New("fromEntity has no such dfid.")
+		}
+		if v < amount {
+			smklogger.Error("transferCoin: fromEntity(id=%s) sub acc(id=%s) restAmount not enough.", from, srcDfId)
+			return nil, errors.New("fromEntity sub acc restAmount not enough.")
+		}
+		fromDfId.DFIdMap[srcDfId] -= amount
+
+		err = t.setAccountDfId(stub, fromDfId)
+		if err != nil {
+			return nil, errors.New("transferCoin setAccountDfId(from) failed.")
+		}
+
+		//只有转出账户有dfid子账户时，才需要记录该交易到以dfid为key的交易记录里
+		recordDfid = dstDfId
+	}
+	if forceRecordDfid {
+		recordDfid = dstDfId
+	}
+	if to != cbAcc {
+		toDfId, err := t.getAccountDfId(stub, to)
+		if err != nil {
+			return nil, errors.New("transferCoin getAccountDfId(to) failed.")
+		}
+		toDfId.DFIdMap[dstDfId] += amount
+
+		err = t.setAccountDfId(stub, toDfId)
+		if err != nil {
+			return nil, errors.New("transferCoin setAccountDfId(to) failed.")
+		}
+	}
+
+	//交易描述中记录dfid
+	_, errcm = Base.TransferCoin(stub, from, to, transType, dstDfId, amount, times, false, "")
+	if errcm != nil {
+		smklogger.Error("transferCoin: Base.transferCoin failed. err=%s", errcm)
+		return nil, errors.New("transferCoin failed.")
+	}
+
+	if len(recordDfid) > 0 {
+		err := t.recordDfIdTrans(stub, recordDfid, &DfidTransInfo{AccName: to, Amount: amount, Time: times})
+		if err != nil {
+			smklogger.Error("transferCoin recordDfIdTrans failed. err=%s", err)
+			return nil, errors.New("transferCoin recordDfIdTrans failed.")
+		}
+	}
+
+	return nil, nil
+}
+
+//获取某个dfid下的交易，0.6版本的交易在前
+func (t *SMK) getDfIdTransInfoList(stub shim.ChaincodeStubInterface, dfId string) ([]DfidTransInfo, error) {
+	var transInfoList = []DfidTransInfo{}
+
+	oldB, err := stateCache.GetState_Ex(stub, t.getLegacyDfIdTransKey(dfId))
+	if err != nil {
+		smklogger.Error("getDfIdTransInfoList GetState(legacy) failed. err=%s", err)
+		return nil, errors.New("getDfIdTransInfoList GetState failed.")
+	}
+	if oldB != nil {
+		var transkList []string
+		err = json.Unmarshal(oldB, &transkList)
+		if err != nil {
+			smklogger.Error("getDfIdTransInfoList Unmarshal(legacy) failed. err=%s", err)
+			return nil, errors.New("getDfIdTransInfoList Unmarshal failed.")
+		}
+		var transB []byte
+		for _, k := range transkList {
+			transB, err = stateCache.GetState_Ex(stub, k)
+			if err != nil {
+				smklogger.Error("getDfIdTransInfoList GetState(TranseInfo) failed. err=%s", err)
+				continue
+			}
+			if transB == nil {
+				smklogger.Error("getDfIdTransInfoList GetState(TranseInfo) for '%s' is nil.", k)
+				continue
+			}
+			var transInfo Smk06Transaction
+			err = json.Unmarshal(transB, &transInfo)
+			if err != nil {
+				smklogger.Error("getDfIdTransInfoList Unmarshal(TranseInfo) failed. err=%s", err)
+				continue
+			}
+			transInfoList = append(transInfoList, DfidTransInfo{AccName: transInfo.ToID, Amount: transInfo.Amount, Time: transInfo.Time})
+		}
+	}
+
+	dftB, err := stateCache.GetState_Ex(stub, t.getDfIdTransKey(dfId))
+	if err != nil {
+		smklogger.Error("getDfIdTransInfoList GetState(DfTranseInfo) failed. err=%s", err)
+		return nil, errors.New("getDfIdTransInfoList GetState failed.")
+	}
+	if dftB != nil {
+		var newList []DfidTransInfo
+		err = json.Unmarshal(dftB, &newList)
+		if err != nil {
+			smklogger.Error("getDfIdTransInfoList Unmarshal(DfidTransInfo) failed. err=%s", err)
+			return nil, errors.New("getDfIdTransInfoList Unmarshal failed.")
+		}
+		transInfoList = append(transInfoList, newList...)
+	}
+
+	return transInfoList, nil
+}
+
+func (t *SMK) getDfIdTransKey(dfId string) string {
+	return DFID_TX_PREFIX + dfId
+}
+func (t *SMK) getLegacyDfIdTransKey(dfId string) string {
+	return LEGACY_DFID_TX_PREFIX + dfId
+}
+
+//记录某个dfid下的交易。交易本身由base记录，这里直接保存dfid查询需要的内容
+func (t *SMK) recordDfIdTrans(stub shim.ChaincodeStubInterface, dfId string, dti *DfidTransInfo) error {
+
+	recdB, err := stateCache.GetState_Ex(stub, t.getDfIdTransKey(dfId))
+	if err != nil {
+		smklogger.Error("recordDfIdTrans GetState failed. err=%s", err)
+		return errors.New("recordDfIdTrans GetState failed.")
+	}
+
+	var transList []DfidTransInfo
+	if recdB != nil {
+		err = json.Unmarshal(recdB, &transList)
+		if err != nil {
+			smklogger.Error("recordDfIdTrans Unmarshal failed. err=%s", err)
+			return errors.New("recordDfIdTrans Unmarshal failed.")
+		}
+	}
+	transList = append(transList, *dti)
+
+	recdJson, err := json.Marshal(transList)
+	if err != nil {
+		smklogger.Error("recordDfIdTrans Marshal failed. err=%s", err)
+		return errors.New("recordDfIdTrans Marshal failed.")
+	}
+
+	err = stateCache.PutState_Ex(stub, t.getDfIdTransKey(dfId), recdJson)
+	if err != nil {
+		smklogger.Error("recordDfIdTrans PutState failed. err=%s", err)
+		return errors.New("recordDfIdTrans PutState failed.")
+	}
+
+	return nil
+}
+
+func (t *SMK) getTraceWareKey(wareId string) string {
+	return TRACE_WARE_PREFIX + wareId
+}
+func (t *SMK) getLegacyTraceKey(wareId string) string {
+	return TRACE_LEGACY_PREFIX + wareId
+}
+func (t *SMK) getTraceEventKey(wareId string, seq int64) string {
+	var buf = bytes.NewBufferString(TRACE_EVENT_PREFIX)
+	buf.WriteString(wareId)
+	buf.WriteString("_")
+	buf.WriteString(strconv.FormatInt(seq, 10))
+	return buf.String()
+}
+func (t *SMK) getTraceBatchKey(batchId string) string {
+	return TRACE_BATCH_PREFIX + batchId
+}
+func (t *SMK) getTraceChildKey(parentId string, seq int64) string {
+	return TRACE_CHILD_PREFIX + parentId + "_" + strconv.FormatInt(seq, 10)
+}
+func (t *SMK) getTraceBatchWareKey(batchId string, seq int64) string {
+	return TRACE_BWARE_PREFIX + batchId + "_" + strconv.FormatInt(seq, 10)
+}
+
+//只有登记人和管理员可以记录商品的溯源事件、在商品下登记子商品
+func (t *SMK) canUpdateTraceWare(stub shim.ChaincodeStubInterface, ware *TraceWare, accName string) bool {
+	return ware.Creator == accName || Base.IsAdmin(stub, accName)
+}
+
+func (t *SMK) isTraceEventTypeValid(eventType string) bool {
+	switch eventType {
+	case TRACE_EVT_PRODUCED, TRACE_EVT_SHIPPED, TRACE_EVT_RECEIVED, TRACE_EVT_INSPECTED:
+		return true
+	}
+	return false
+}
+
+func (t *SMK) getTraceWare(stub shim.ChaincodeStubInterface, wareId string) (*TraceWare, error) {
+	wareB, err := stateCache.GetState_Ex(stub, t.getTraceWareKey(wareId))
+	if err != nil {
+		smklogger.Error("getTraceWare GetState failed. err=%s", err)
+		return nil, err
+	}
+	if wareB == nil {
+		return nil, nil
+	}
+
+	var ware TraceWare
+	err = json.Unmarshal(wareB, &ware)
+	if err != nil {
+		smklogger.Error("getTraceWare Unmarshal failed. err=%s", err)
+		return nil, err
+	}
+
+	return &ware, nil
+}
+func (t *SMK) setTraceWare(stub shim.ChaincodeStubInterface, ware *TraceWare) error {
+	wareJson, err := json.Marshal(ware)
+	if err != nil {
+		smklogger.Error("setTraceWare Marshal failed. err=%s", err)
+		return err
+	}
+
+	err = stateCache.PutState_Ex(stub, t.getTraceWareKey(ware.WareId), wareJson)
+	if err != nil {
+		smklogger.Error("setTraceWare PutState failed. err=%s", err)
+		return err
+	}
+	return nil
+}
+
+func (t *SMK) getTraceBatch(stub shim.ChaincodeStubInterface, batchId string) (*TraceBatch, error) {
+	batchB, err := stateCache.GetState_Ex(stub, t.getTraceBatchKey(batchId))
+	if err != nil {
+		smklogger.Error("getTraceBatch GetState failed. err=%s", err)
+		return nil, err
+	}
+	if batchB == nil {
+		return nil, nil
+	}
+
+	var batch TraceBatch
+	err = json.Unmarshal(batchB, &batch)
+	if err != nil {
+		smklogger.Error("getTraceBatch Unmarshal failed. err=%s", err)
+		return nil, err
+	}
+
+	return &batch, nil
+}
+
+//登记商品，同时加入批次并建立和父商品的关系。子商品和批次中的商品都按序号单独保存，父商品和批次中只记录数量
+func (t *SMK) registerTraceWare(stub shim.ChaincodeStubInterface, wareId, batchId, parentId, creator string, times int64) (*TraceWare, error) {
+	if wareId == parentId {
+		smklogger.Error("registerTraceWare ware(%s) can't be parent of itself.", wareId)
+		return nil, errors.New("registerTraceWare ware can't be parent of itself.")
+	}
+
+	var ware TraceWare
+	ware.WareId = wareId
+	ware.BatchId = batchId
+	ware.ParentId = parentId
+	ware.Creator = creator
+	ware.CreateTime = times
+	ware.EventSeq = 0
+
+	if len(parentId) > 0 {
+		parent, err := t.getTraceWare(stub, parentId)
+		if err != nil {
+			smklogger.Error("registerTraceWare getTraceWare(parent=%s) failed. err=%s", parentId, err)
+			return nil, errors.New("registerTraceWare getTraceWare(parent) failed.")
+		}
+		if parent == nil {
+			smklogger.Error("registerTraceWare parent(%s) not registered.", parentId)
+			return nil, errors.New("registerTraceWare parent not registered.")
+		}
+		if !t.canUpdateTraceWare(stub, parent, creator) {
+			smklogger.Error("registerTraceWare %s can't add child to parent(%s) of %s.", creator, parentId, parent.Creator)
+			return nil, errors.New("registerTraceWare can't add child to ware of others.")
+		}
+
+		parent.ChildCount++
+		err = stateCache.PutState_Ex(stub, t.getTraceChildKey(parentId, parent.ChildCount), []byte(wareId))
+		if err != nil {
+			smklogger.Error("registerTraceWare PutState(child) failed. err=%s", err)
+			return nil, errors.New("registerTraceWare PutState(child) failed.")
+		}
+		err = t.setTraceWare(stub, parent)
+		if err != nil {
+			return nil, errors.New("registerTraceWare setTraceWare(parent) failed.")
+		}
+	}
+
+	if len(batchId) > 0 {
+		batch, err := t.getTraceBatch(stub, batchId)
+		if err != nil {
+			return nil, errors.New("registerTraceWare getTraceBatch failed.")
+		}
+		if batch == nil {
+			batch = &TraceBatch{BatchId: batchId, Creator: creator}
+		} else if batch.Creator != creator && !Base.IsAdmin(stub, creator) {
+			smklogger.Error("registerTraceWare %s can't add ware to batch(%s) of %s.", creator, batchId, batch.Creator)
+			return nil, errors.New("registerTraceWare can't add ware to batch of others.")
+		}
+		batch.WareCount++
+		err = stateCache.PutState_Ex(stub, t.getTraceBatchWareKey(batchId, batch.WareCount), []byte(wareId))
+		if err != nil {
+			smklogger.Error("registerTraceWare PutState(batch ware) failed. err=%s", err)
+			return nil, errors.New("registerTraceWare PutState(batch ware) failed.")
+		}
+
+		batchJson, err := json.Marshal(batch)
+		if err != nil {
+			smklogger.Error("registerTraceWare Marshal(batch) failed. err=%s", err)
+			return nil, errors.New("registerTraceWare Marshal(batch) failed.")
+		}
+		err = stateCache.PutState_Ex(stub, t.getTraceBatchKey(batchId), batchJson)
+		if err != nil {
+			smklogger.Error("registerTraceWare PutState(batch) failed. err=%s", err)
+			return nil, errors.New("registerTraceWare PutState(batch) failed.")
+		}
+	}
+
+	err := t.setTraceWare(stub, &ware)
+	if err != nil {
+		return nil, errors.New("registerTraceWare setTraceWare failed.")
+	}
+
+	return &ware, nil
+}
+
+//记录溯源事件，每个事件单独存储
+func (t *SMK) recordTraceEvent(stub shim.ChaincodeStubInterface, ware *TraceWare, eventType, actor, location, attachHash, msg string, times int64) ([]byte, error) {
+	ware.EventSeq++
+
+	var evt TraceEvent
+	evt.Seq = ware.EventSeq
+	evt.WareId = ware.WareId
+	evt.EventType = eventType
+	evt.Actor = actor
+	evt.Location = location
+	evt.AttachHash = attachHash
+	evt.TxID = stub.GetTxID()
+	evt.Time = times
+	evt.TraceMsg = msg
+
+	evtJson, err := json.Marshal(evt)
+	if err != nil {
+		smklogger.Error("recordTraceEvent Marshal failed. err=%s", err)
+		return nil, errors.New("recordTraceEvent Marshal failed.")
+	}
+
+	err = stateCache.PutState_Ex(stub, t.getTraceEventKey(ware.WareId, evt.Seq), evtJson)
+	if err != nil {
+		smklogger.Error("recordTraceEvent PutState failed. err=%s", err)
+		return nil, errors.New("recordTraceEvent PutState failed.")
+	}
+
+	err = t.setTraceWare(stub, ware)
+	if err != nil {
+		return nil, errors.New("recordTraceEvent setTraceWare failed.")
+	}
+
+	return evtJson, nil
+}
+
+//查询溯源事件。eventType和actor为空时不过滤
+//legacyFmt为true时，和旧接口一样返回数组，旧数据作为note类型的事件放在前面
+func (t *SMK) queryTraceEvents(stub shim.ChaincodeStubInterface, wareId string, begSeq, count int64, eventType, actor string, begTime, endTime int64, legacyFmt bool) ([]byte, error) {
+	var qtr QueryTraceResult
+	qtr.NextSeq = -1
+	qtr.Events = []TraceEvent{}
+	qtr.LegacyTraces = []TraceInfo{}
+
+	//0.6版本旧接口以商品id作为key，保存的是用','拼接的TraceInfo，load时转到了TRACE_LEGACY_PREFIX下
+	legacyB, err := stateCache.GetState_Ex(stub, t.getLegacyTraceKey(wareId))
+	if err != nil {
+		smklogger.Error("queryTraceEvents GetState(legacy) failed. err=%s", err)
+		return nil, errors.New("queryTraceEvents GetState failed.")
+	}
+	if legacyB != nil {
+		legacyB = append([]byte("["), legacyB...)
+		legacyB = append(legacyB, ']')
+		err = json.Unmarshal(legacyB, &qtr.LegacyTraces)
+		if err != nil {
+			smklogger.Error("queryTraceEvents Unmarshal(legacy) failed. err=%s", err)
+		}
+	}
+
+	qtr.Ware, err = t.getTraceWare(stub, wareId)
+	if err != nil {
+		return nil, errors.New("queryTraceEvents getTraceWare failed.")
+	}
+	if qtr.Ware != nil {
+		qtr.MaxSeq = qtr.Ware.EventSeq
+		if count < 0 {
+			count = qtr.MaxSeq
+		}
+
+		var loopCnt int64 = 0
+		for seq := begSeq; seq <= qtr.MaxSeq; seq++ {
+			if loopCnt >= count {
+				qtr.NextSeq = seq
+				break
+			}
+
+			evtB, err := stateCache.GetState_Ex(stub, t.getTraceEventKey(wareId, seq))
+			if err != nil {
+				smklogger.Error("queryTraceEvents GetState(seq=%d) failed. err=%s", seq, err)
+				continue
+			}
+			if evtB == nil {
+				continue
+			}
+			var evt TraceEvent
+			err = json.Unmarshal(evtB, &evt)
+			if err != nil {
+				smklogger.Error("queryTraceEvents Unmarshal(seq=%d) failed. err=%s", seq, err)
+				continue
+			}
+
+			if len(eventType) > 0 && evt.EventType != eventType {
+				continue
+			}
+			if len(actor) > 0 && evt.Actor != actor {
+				continue
+			}
+			if evt.Time < begTime || evt.Time > endTime {
+				continue
+			}
+
+			qtr.Events = append(qtr.Events, evt)
+			loopCnt++
+		}
+	}
+
+	if legacyFmt {
+		var evtList = []TraceEvent{}
+		for _, ti := range qtr.LegacyTraces {
+			evtList = append(evtList, TraceEvent{WareId: wareId, EventType: TRACE_EVT_NOTE, TraceInfo: ti})
+		}
+		evtList = append(evtList, qtr.Events...)
+
+		retValue, err := json.Marshal(evtList)
+		if err != nil {
+			smklogger.Error("queryTraceEvents Marshal(legacy) failed. err=%s", err)
+			return nil, errors.New("queryTraceEvents Marshal failed.")
+		}
+		return retValue, nil
+	}
+
+	retValue, err := json.Marshal(qtr)
+	if err != nil {
+		smklogger.Error("queryTraceEvents Marshal failed. err=%s", err)
+		return nil, errors.New("queryTraceEvents Marshal failed.")
+	}
+
+	return retValue, nil
+}
+
+//分页查询子商品或批次中的商品id。getKey为按序号获取key的函数，total为总数，count小于0时查询全部
+func (t *SMK) queryTraceIdList(stub shim.ChaincodeStubInterface, getKey func(seq int64) string, total, begSeq, count int64) ([]byte, error) {
+	var qil QueryTraceIdList
+	qil.Total = total
+	qil.NextSeq = -1
+	qil.IdList = []string{}
+
+	if begSeq <= 0 {
+		begSeq = 1
+	}
+	if count < 0 {
+		count = total
+	}
+
+	for seq := begSeq; seq <= total; seq++ {
+		if int64(len(qil.IdList)) >= count {
+			qil.NextSeq = seq
+			break
+		}
+
+		idB, err := stateCache.GetState_Ex(stub, getKey(seq))
+		if err != nil {
+			smklogger.Error("queryTraceIdList GetState(seq=%d) failed. err=%s", seq, err)
+			return nil, errors.New("queryTraceIdList GetState failed.")
+		}
+		if idB == nil {
+			continue
+		}
+		qil.IdList = append(qil.IdList, string(idB))
+	}
+
+	retValue, err := json.Marshal(qil)
+	if err != nil {
+		smklogger.Error("queryTraceIdList Marshal failed. err=%s", err)
+		return nil, errors.New("queryTraceIdList Marshal failed.")
+	}
+
+	return retValue, nil
+}
+
+func (t *SMK) getDfInfoKey(dfId string) string {
+	return DFID_PREFIX + dfId
+}
+
+func (t *SMK) getReceivableKey(dfId string) string {
+	return RECV_PREFIX + dfId
+}
+
+func (t *SMK) getReceivable(stub shim.ChaincodeStubInterface, dfId string) (*ReceivableFinance, error) {
+	rfB, err := stateCache.GetState_Ex(stub, t.getReceivableKey(dfId))
+	if err != nil {
+		smklogger.Error("getReceivable GetState failed. err=%s", err)
+		return nil, err
+	}
+	if rfB == nil {
+		return nil, nil
+	}
+
+	var rf ReceivableFinance
+	err = json.Unmarshal(rfB, &rf)
+	if err != nil {
+		smklogger.Error("getReceivable Unmarshal failed. err=%s", err)
+		return nil, err
+	}
+
+	return &rf, nil
+}
+
+func (t *SMK) setReceivable(stub shim.ChaincodeStubInterface, rf *ReceivableFinance) ([]byte, error) {
+	rfJson, err := json.Marshal(rf)
+	if err != nil {
+		smklogger.Error("setReceivable Marshal failed. err=%s", err)
+		return nil, errors.New("setReceivable Marshal failed.")
+	}
+
+	err = stateCache.PutState_Ex(stub, t.getReceivableKey(rf.DFID), rfJson)
+	if err != nil {
+		smklogger.Error("setReceivable PutState failed. err=%s", err)
+		return nil, errors.New("setReceivable PutState failed.")
+	}
+
+	return rfJson, nil
+}
+
+//load 0.6版本导出的worldState时，把数据转换为base及本模块的格式
+func (t *SMK) dateConvertWhenLoad(stub shim.ChaincodeStubInterface, srcCcid, key string, valueB []byte) (string, []byte, *ErrorCodeMsg) {
+	if srcCcid != SMK06_CCID {
+		return key, valueB, nil
+	}
+
+	newKey, newValB, err := t.convertSmk06State(stub, key, valueB)
+	if err != nil {
+		return "", nil, smklogger.ErrorECM(ERRCODE_COMMON_INNER_ERROR, "dateConvertWhenLoad: convert key '%s' failed, err=%s", key, err)
+	}
+
+	return newKey, newValB, nil
+}
+
+func (t *SMK) convertSmk06State(stub shim.ChaincodeStubInterface, key string, valueB []byte) (string, []byte, error) {
+	//业务数据只换key的前缀
+	var prefixMap = map[string]string{
+		SMK06_DFID_PREFIX:        DFID_PREFIX,
+		SMK06_TRACE_WARE_PREFIX:  TRACE_WARE_PREFIX,
+		SMK06_TRACE_EVENT_PREFIX: TRACE_EVENT_PREFIX,
+		SMK06_TRACE_BATCH_PREFIX: TRACE_BATCH_PREFIX,
+		SMK06_TRACE_CHILD_PREFIX: TRACE_CHILD_PREFIX,
+		SMK06_TRACE_BWARE_PREFIX: TRACE_BWARE_PREFIX,
+		SMK06_RECV_PREFIX:        RECV_PREFIX,
+		SMK06_TRANSINFO_PREFIX:   LEGACY_TX_PREFIX,
+	}
+
+	if key == SMK06_ALL_ACC_KEY || key == SMK06_CENTERBANK_ACC_KEY || strings.HasPrefix(key, SMK06_TRANSSEQ_PREFIX) {
+		//账户列表在转换账户时已加入base，央行账户以base中的为准，序列号由base重新生成，这些只保留不再使用
+		return LEGACY_STATE_PREFIX + key, valueB, nil
+
+	} else if strings.HasPrefix(key, SMK06_DFID_TX_PREFIX) {
+		//列表中保存的是0.6版本交易记录的key，交易记录的key也一起转换
+		var transkList []string
+		err := json.Unmarshal(valueB, &transkList)
+		if err != nil {
+			return "", nil, smklogger.Errorf("convertSmk06State: Unmarshal(dfIdTx) failed, err=%s", err)
+		}
+		for i, k := range transkList {
+			if strings.HasPrefix(k, SMK06_TRANSINFO_PREFIX) {
+				transkList[i] = LEGACY_TX_PREFIX + k[len(SMK06_TRANSINFO_PREFIX):]
+			}
+		}
+		newValB, err := json.Marshal(transkList)
+		if err != nil {
+			return "", nil, smklogger.Errorf("convertSmk06State: Marshal(dfIdTx) failed, err=%s", err)
+		}
+
+		return t.getLegacyDfIdTransKey(key[len(SMK06_DFID_TX_PREFIX):]), newValB, nil
+	}
+
+	//map遍历顺序不固定，排序后再匹配，保证每个节点执行结果一致
+	var oldPrefixs []string
+	for oldPrefix := range prefixMap {
+		oldPrefixs = append(oldPrefixs, oldPrefix)
+	}
+	sort.Strings(oldPrefixs)
+	for _, oldPrefix := range oldPrefixs {
+		if strings.HasPrefix(key, oldPrefix) {
+			return prefixMap[oldPrefix] + key[len(oldPrefix):], valueB, nil
+		}
+	}
+
+	//其它的key直接以账户名或者商品id为key
+	var oldEnt Smk06Entity
+	if json.Unmarshal(valueB, &oldEnt) == nil && len(oldEnt.EntID) > 0 && oldEnt.EntID == key {
+		return t.convertSmk06Account(stub, &oldEnt)
+	}
+
+	//旧的trace接口以商品id为key，保存的是用','拼接的TraceInfo
+	return TRACE_LEGACY_PREFIX + key, valueB, nil
+}
+
+//账户余额转为base中的账户，dfid子账户单独保存。转换后的账户没有公钥和身份的hash，需要账户所有者用account重新绑定
+func (t *SMK) convertSmk06Account(stub shim.ChaincodeStubInterface, oldEnt *Smk06Entity) (string, []byte, error) {
+	var newEnt AccountEntity
+	existEnt, errcm := Base.GetAccountEntity(stub, oldEnt.EntID)
+	if errcm != nil && errcm != ErrcmNilEntity {
+		return "", nil, smklogger.Errorf("convertSmk06Account: getAccountEntity failed, err=%s", errcm)
+	}
+
+	if existEnt != nil {
+		newEnt = *existEnt
+	} else {
+		newEnt.Owner = oldEnt.User
+		newEnt.OwnerPubKeyHash = ""
+		newEnt.OwnerIdentityHash = ""
+	}
+	newEnt.EntID = oldEnt.EntID
+	newEnt.EntType = oldEnt.EntType
+	newEnt.TotalAmount = oldEnt.TotalAmount
+	newEnt.RestAmount = oldEnt.RestAmount
+	newEnt.Time = oldEnt.Time
+
+	newValB, err := json.Marshal(newEnt)
+	if err != nil {
+		return "", nil, smklogger.Errorf("convertSmk06Account: Marshal(newEnt) failed, err=%s", err)
+	}
+
+	if oldEnt.DFIdMap != nil {
+		err = t.setAccountDfId(stub, &AccountDfId{EntID: oldEnt.EntID, DFIdMap: oldEnt.DFIdMap})
+		if err != nil {
+			return "", nil, smklogger.Errorf("convertSmk06Account: setAccountDfId failed, err=%s", err)
+		}
+	}
+
+	//base中的央行账户不在账户列表中
+	if !Base.IsAdmin(stub, oldEnt.EntID) {
+		allAccs, err := t.getAllAccountNames(stub)
+		if err != nil {
+			return "", nil, smklogger.Errorf("convertSmk06Account: getAllAccountNames failed, err=%s", err)
+		}
+		if !StrSliceContains(allAccs, oldEnt.EntID) {
+			accs := append([]byte(strings.Join(append(allAccs, oldEnt.EntID), string(MULTI_STRING_DELIM))), MULTI_STRING_DELIM)
+			err = stateCache.PutState_Ex(stub, ALL_ACC_INFO_KEY, accs)
+			if err != nil {
+				return "", nil, smklogger.Errorf("convertSmk06Account: PutState(allAcc) failed, err=%s", err)
+			}
+		}
+	}
+
+	return Base.GetAccountEntityKey(oldEnt.EntID), newValB, nil
+}
+
+func (t *SMK) loadAfter(stub shim.ChaincodeStubInterface, srcCcid string) *ErrorCodeMsg {
+	if srcCcid != SMK06_CCID {
+		return nil
+	}
+
+	//转换时有账户加入了base的账户列表，重新统计账户数
+	allAccs, err := t.getAllAccountNames(stub)
+	if err != nil {
+		return smklogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "loadAfter: getAllAccountNames failed, err=%s", err)
+	}
+
+	var asi AccountStatisticInfo
+	asi.AccountCount = int64(len(allAccs))
+
+	asiB, err := json.Marshal(asi)
+	if err != nil {
+		return smklogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "loadAfter: Marshal failed, err=%s", err)
+	}
+
+	err = stateCache.PutState_Ex(stub, ACC_STATIC_INFO_KEY, asiB)
+	if err != nil {
+		return smklogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "loadAfter: PutState failed, err=%s", err)
+	}
+
+	return nil
+}
+
+func main() {
+	err := shim.Start(&Base)
+	if err != nil {
+		smklogger.Error("Error starting smk chaincode: %s", err)
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	. "loulan/errcode"
+)
+
+func TestTransferDfid(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, "d1", map[string]int64{"alice": 1000, "bob": 0})
+
+	c.run([]testStep{
+		{
+			name: "alice to bob", user: "alice_user", function: "transeferDfid",
+			args:  []string{"alice", "bob", "pay", "d1", "300"},
+			check: wantBalances(map[string]int64{"alice": 700, "bob": 300}, map[string]map[string]int64{"alice": {"d1": 700}, "bob": {"d1": 300}}),
+		},
+		{
+			name: "no such dfid", user: "alice_user", function: "transeferDfid",
+			args:     []string{"alice", "bob", "pay", "d2", "100"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "dfid not enough", user: "alice_user", function: "transeferDfid",
+			args:     []string{"alice", "bob", "pay", "d1", "701"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "zero amount", user: "bob_user", function: "transeferDfid",
+			args:     []string{"bob", "alice", "pay", "d1", "0"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "bob back to alice", user: "bob_user", function: "transeferDfid",
+			args:  []string{"bob", "alice", "pay", "d1", "300"},
+			check: wantBalances(map[string]int64{"alice": 1000, "bob": 0}, map[string]map[string]int64{"alice": {"d1": 1000}, "bob": {"d1": 0}}),
+		},
+		{
+			name: "query", user: "alice_user", function: "query",
+			args: []string{"alice"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				var qe QueryEntity
+				if err := json.Unmarshal(payload, &qe); err != nil {
+					t.Fatalf("Unmarshal QueryEntity failed, err=%s", err)
+				}
+				if qe.TotalAmount != 1000 || qe.DFIdMap["d1"] != 1000 {
+					t.Errorf("query: want 1000 in d1, got %+v", qe)
+				}
+			},
+		},
+	})
+}
+
+//base中直接修改余额的函数在smk中禁用，否则dfid子账户之和与余额会不一致
+func TestDisabledBaseFunc(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, "d1", map[string]int64{"alice": 1000, "bob": 0})
+
+	var steps []testStep
+	for _, fn := range smkDisabledBaseFunc {
+		steps = append(steps, testStep{
+			name: fn, user: "alice_user", function: fn,
+			args:     []string{"alice", "bob", "100", "testapp"},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+			check:    wantBalances(map[string]int64{"alice": 1000, "bob": 0}, nil),
+		})
+	}
+	c.run(steps)
+}
+
+func TestTraceBatch(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, "d1", map[string]int64{"alice": 0, "bob": 0})
+
+	var wantIdList = func(total int64, ids ...string) func(t *testing.T, c *testChain, payload []byte) {
+		return func(t *testing.T, c *testChain, payload []byte) {
+			var qil QueryTraceIdList
+			if err := json.Unmarshal(payload, &qil); err != nil {
+				t.Fatalf("Unmarshal QueryTraceIdList failed, err=%s", err)
+			}
+			if qil.Total != total || !reflect.DeepEqual(qil.IdList, ids) {
+				t.Errorf("id list: want %d %v, got %d %v", total, ids, qil.Total, qil.IdList)
+			}
+		}
+	}
+
+	c.run([]testStep{
+		{
+			name: "alice create b1", user: "alice_user", function: "registerWare",
+			args: []string{"alice", "w1", "b1", ""},
+		},
+		{
+			name: "alice add to b1", user: "alice_user", function: "registerWare",
+			args: []string{"alice", "w2", "b1", "w1"},
+		},
+		{
+			//批次只有创建人和管理员可以追加商品
+			name: "bob add to b1", user: "bob_user", function: "registerWare",
+			args:     []string{"bob", "w3", "b1", ""},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "bob add child of w1", user: "bob_user", function: "registerWare",
+			args:     []string{"bob", "w3", "b2", "w1"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "bob create b2", user: "bob_user", function: "registerWare",
+			args: []string{"bob", "w3", "b2", ""},
+		},
+		{
+			name: "admin add to b1", user: "cbuser", function: "registerWare",
+			args: []string{testCBAcc, "w4", "b1", ""},
+		},
+		{
+			name: "register w1 again", user: "alice_user", function: "registerWare",
+			args:     []string{"alice", "w1", "b1", ""},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "query b1", user: "bob_user", function: "queryBatch",
+			args:  []string{"bob", "b1"},
+			check: wantIdList(3, "w1", "w2", "w4"),
+		},
+		{
+			name: "query b1 page", user: "bob_user", function: "queryBatch",
+			args:  []string{"bob", "b1", "2", "1"},
+			check: wantIdList(3, "w2"),
+		},
+		{
+			name: "query w1 children", user: "bob_user", function: "queryWareChildren",
+			args:  []string{"bob", "w1"},
+			check: wantIdList(1, "w2"),
+		},
+		{
+			name: "alice traceEvent w1", user: "alice_user", function: "traceEvent",
+			args: []string{"alice", "w1", TRACE_EVT_SHIPPED, "shanghai", "hash1", "shipped"},
+		},
+		{
+			name: "bob traceEvent w1", user: "bob_user", function: "traceEvent",
+			args:     []string{"bob", "w1", TRACE_EVT_RECEIVED, "beijing", "", "received"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "invalid event type", user: "alice_user", function: "traceEvent",
+			args:     []string{"alice", "w1", TRACE_EVT_NOTE, "", "", "note"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "bob trace w1", user: "bob_user", function: "trace",
+			args:     []string{"bob", "w1", "note"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "query w1 events", user: "bob_user", function: "queryTrace",
+			args: []string{"bob", "w1", "1", "10", "", "", "0", "-1"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				var qtr QueryTraceResult
+				if err := json.Unmarshal(payload, &qtr); err != nil {
+					t.Fatalf("Unmarshal QueryTraceResult failed, err=%s", err)
+				}
+				if qtr.Ware == nil || qtr.Ware.Creator != "alice" || len(qtr.Events) != 1 || qtr.Events[0].Actor != "alice" {
+					t.Errorf("queryTrace: unexpected result %s", payload)
+				}
+			},
+		},
+	})
+}
+
+func TestReceivable(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, "fund", map[string]int64{"supplier": 0, "core": 5000, "bank": 8000, "other": 8000})
+
+	var dueTime = fmt.Sprintf("%d", c.stub.nowMs()+int64(30*24*time.Hour/time.Millisecond))
+
+	var wantRecv = func(status int, financier string, repaid int64) func(t *testing.T, c *testChain, payload []byte) {
+		return func(t *testing.T, c *testChain, payload []byte) {
+			var rf ReceivableFinance
+			if err := json.Unmarshal(c.stub.State[RECV_PREFIX+"r1"], &rf); err != nil {
+				t.Fatalf("Unmarshal ReceivableFinance failed, err=%s", err)
+			}
+			if rf.Status != status || rf.Financier != financier || rf.RepaidAmount != repaid {
+				t.Errorf("receivable: want (%d,%s,%d), got (%d,%s,%d)", status, financier, repaid, rf.Status, rf.Financier, rf.RepaidAmount)
+			}
+		}
+	}
+
+	c.run([]testStep{
+		{
+			name: "register", user: "supplier_user", function: "regReceivable",
+			args:  []string{"supplier", "r1", "core", "3000", dueTime, "c1", "goods"},
+			check: wantRecv(RECV_STAT_REGISTERED, "", 0),
+		},
+		{
+			name: "register again", user: "other_user", function: "regReceivable",
+			args:     []string{"other", "r1", "core", "3000", dueTime, "c1", "goods"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "disburse before confirm", user: "bank_user", function: "disburseReceivable",
+			args:     []string{"bank", "r1", "1000", "fund"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "confirm by supplier", user: "supplier_user", function: "confirmReceivable",
+			args:     []string{"supplier", "r1"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "confirm", user: "core_user", function: "confirmReceivable",
+			args:  []string{"core", "r1"},
+			check: wantRecv(RECV_STAT_CONFIRMED, "", 0),
+		},
+		{
+			name: "disburse before approve", user: "bank_user", function: "disburseReceivable",
+			args:     []string{"bank", "r1", "1000", "fund"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "approve by others", user: "other_user", function: "approveRecvFinancier",
+			args:     []string{"other", "r1", "other"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "approve coreEnt", user: "supplier_user", function: "approveRecvFinancier",
+			args:     []string{"supplier", "r1", "core"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "approve", user: "supplier_user", function: "approveRecvFinancier",
+			args:  []string{"supplier", "r1", "bank"},
+			check: wantRecv(RECV_STAT_CONFIRMED, "bank", 0),
+		},
+		{
+			//只有供应商指定的金融机构可以放款
+			name: "disburse by others", user: "other_user", function: "disburseReceivable",
+			args:     []string{"other", "r1", "2500", "fund"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+			check:    wantBalances(map[string]int64{"other": 8000, "supplier": 0}, nil),
+		},
+		{
+			name: "disburse too much", user: "bank_user", function: "disburseReceivable",
+			args:     []string{"bank", "r1", "3001", "fund"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "disburse", user: "bank_user", function: "disburseReceivable",
+			args: []string{"bank", "r1", "2500", "fund"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantRecv(RECV_STAT_FINANCED, "bank", 0)(t, c, payload)
+				wantBalances(map[string]int64{"bank": 5500, "supplier": 2500}, map[string]map[string]int64{"bank": {"fund": 5500}, "supplier": {"r1": 2500}})(t, c, payload)
+			},
+		},
+		{
+			name: "repay by others", user: "bank_user", function: "repayReceivable",
+			args:     []string{"bank", "r1", "1000", "fund"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			//放款后还款给金融机构
+			name: "repay", user: "core_user", function: "repayReceivable",
+			args: []string{"core", "r1", "1000", "fund"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantRecv(RECV_STAT_FINANCED, "bank", 1000)(t, c, payload)
+				wantBalances(map[string]int64{"core": 4000, "bank": 6500}, map[string]map[string]int64{"core": {"fund": 4000}, "bank": {"r1": 1000}})(t, c, payload)
+			},
+		},
+		{
+			name: "repay too much", user: "core_user", function: "repayReceivable",
+			args:     []string{"core", "r1", "2001", "fund"},
+			wantCode: ERRCODE_COMMON_INNER_ERROR,
+		},
+		{
+			name: "repay rest overdue", user: "core_user", function: "repayReceivable",
+			args:    []string{"core", "r1", "2000", "fund"},
+			advance: 31 * 24 * time.Hour,
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantRecv(RECV_STAT_SETTLED, "bank", 3000)(t, c, payload)
+				wantBalances(map[string]int64{"core": 2000, "bank": 8500}, map[string]map[string]int64{"bank": {"r1": 3000}})(t, c, payload)
+			},
+		},
+		{
+			name: "query", user: "other_user", function: "queryReceivable",
+			args: []string{"other", "r1"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				var qr QueryReceivable
+				if err := json.Unmarshal(payload, &qr); err != nil {
+					t.Fatalf("Unmarshal QueryReceivable failed, err=%s", err)
+				}
+				if qr.Status != RECV_STAT_SETTLED || !qr.Overdue || len(qr.Repayments) != 2 || len(qr.TransInfoList) != 3 {
+					t.Errorf("queryReceivable: unexpected result %s", payload)
+				}
+			},
+		},
+	})
+}
@@ -0,0 +1,255 @@
+package acbase
+
+import (
+	"encoding/json"
+
+	. "loulan/ccutil"
+	. "loulan/errcode"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//授权额度。账户所有者授权其它账户（spender）在额度内从自己的账户转出，和authAccountManager不同，spender只能转账，且不能超过额度
+type Allowance struct {
+	Spender string `json:"spd"`            //被授权的账户
+	AssetID string `json:"asid,omitempty"` //授权的资产，默认资产为空
+	Amount  int64  `json:"amt"`            //剩余额度
+	Expiry  int64  `json:"exp"`            //过期时间，单位毫秒，0表示不过期
+	AppID   string `json:"app"`            //使用授权转账时，交易记录在该应用下
+	Time    int64  `json:"time"`           //授权时间
+}
+
+//一个账户授权出去的所有额度。每个spender的每种资产只有一条记录
+type AccountAllowanceInfo struct {
+	AccName string      `json:"acc"`
+	List    []Allowance `json:"al"`
+}
+
+//查询的授权额度
+type QueryAllowance struct {
+	Owner string `json:"owner"`
+	Allowance
+}
+
+func (b *BASE) getAllowanceKey(owner string) string {
+	return ALLOWANCE_PREFIX + owner
+}
+
+//spender被哪些账户授权了，查询用
+func (b *BASE) getSpenderOwnersKey(spender string) string {
+	return ALLOWANCE_SPENDER_PREFIX + spender
+}
+
+func (b *BASE) getAllowanceInfo(stub shim.ChaincodeStubInterface, owner string) (*AccountAllowanceInfo, *ErrorCodeMsg) {
+	var aai AccountAllowanceInfo
+	aai.AccName = owner
+
+	aaiB, err := StateCache.GetState_Ex(stub, b.getAllowanceKey(owner))
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getAllowanceInfo: GetState failed. error=(%s)", err)
+	}
+	if aaiB == nil {
+		return &aai, nil
+	}
+
+	err = json.Unmarshal(aaiB, &aai)
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getAllowanceInfo: Unmarshal failed. error=(%s)", err)
+	}
+
+	return &aai, nil
+}
+
+func (b *BASE) setAllowanceInfo(stub shim.ChaincodeStubInterface, aai *AccountAllowanceInfo) *ErrorCodeMsg {
+	aaiB, err := json.Marshal(aai)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setAllowanceInfo: Marshal failed. error=(%s)", err)
+	}
+
+	err = StateCache.PutState_Ex(stub, b.getAllowanceKey(aai.AccName), aaiB)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setAllowanceInfo: PutState failed. error=(%s)", err)
+	}
+
+	return nil
+}
+
+func (b *BASE) getSpenderOwners(stub shim.ChaincodeStubInterface, spender string) ([]string, *ErrorCodeMsg) {
+	var owners []string
+
+	ownersB, err := StateCache.GetState_Ex(stub, b.getSpenderOwnersKey(spender))
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getSpenderOwners: GetState failed. error=(%s)", err)
+	}
+	if ownersB == nil {
+		return owners, nil
+	}
+
+	err = json.Unmarshal(ownersB, &owners)
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getSpenderOwners: Unmarshal failed. error=(%s)", err)
+	}
+
+	return owners, nil
+}
+
+//更新spender的授权账户列表。hasAllowance表示owner是否还有授权给spender的额度
+func (b *BASE) updateSpenderOwners(stub shim.ChaincodeStubInterface, spender, owner string, hasAllowance bool) *ErrorCodeMsg {
+	owners, errcm := b.getSpenderOwners(stub, spender)
+	if errcm != nil {
+		return errcm
+	}
+
+	var contains = StrSliceContains(owners, owner)
+	if hasAllowance == contains {
+		return nil
+	}
+
+	if hasAllowance {
+		owners = append(owners, owner)
+	} else {
+		owners = StrSliceDelete(owners, owner)
+	}
+
+	ownersB, err := json.Marshal(owners)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "updateSpenderOwners: Marshal failed. error=(%s)", err)
+	}
+
+	err = StateCache.PutState_Ex(stub, b.getSpenderOwnersKey(spender), ownersB)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "updateSpenderOwners: PutState failed. error=(%s)", err)
+	}
+
+	return nil
+}
+
+//设置授权额度，amount为0时删除授权。同一spender同一资产的授权会覆盖之前的授权；已过期的授权顺便清理掉
+func (b *BASE) setAllowance(stub shim.ChaincodeStubInterface, owner string, allow *Allowance, currTime int64) *ErrorCodeMsg {
+	aai, errcm := b.getAllowanceInfo(stub, owner)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "setAllowance: getAllowanceInfo failed. error=(%s)", errcm)
+	}
+
+	var newList []Allowance
+	var spenders = make(map[string]bool)
+	for _, al := range aai.List {
+		if al.Spender == allow.Spender && b.isSameAsset(al.AssetID, allow.AssetID) {
+			continue
+		}
+		if al.Expiry > 0 && al.Expiry <= currTime {
+			continue
+		}
+		newList = append(newList, al)
+		spenders[al.Spender] = true
+	}
+	if allow.Amount > 0 {
+		newList = append(newList, *allow)
+		spenders[allow.Spender] = true
+	}
+
+	//被清理掉的spender也要更新列表
+	for _, al := range aai.List {
+		if !spenders[al.Spender] {
+			errcm = b.updateSpenderOwners(stub, al.Spender, owner, false)
+			if errcm != nil {
+				return baselogger.ErrorECM(errcm.Code, "setAllowance: updateSpenderOwners failed. error=(%s)", errcm)
+			}
+		}
+	}
+	errcm = b.updateSpenderOwners(stub, allow.Spender, owner, spenders[allow.Spender])
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "setAllowance: updateSpenderOwners failed. error=(%s)", errcm)
+	}
+
+	aai.List = newList
+
+	errcm = b.setAllowanceInfo(stub, aai)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "setAllowance: setAllowanceInfo failed. error=(%s)", errcm)
+	}
+
+	return nil
+}
+
+//使用授权额度从owner账户转账，由spender发起
+func (b *BASE) transferFrom(stub shim.ChaincodeStubInterface, spender, owner, to, assetId, transType, description, encMemo string, amount, transTime int64) *ErrorCodeMsg {
+	if amount <= 0 {
+		return baselogger.ErrorECM(ERRCODE_TRANS_AMOUNT_INVALID, "transferFrom: invalid amount(%d).", amount)
+	}
+
+	aai, errcm := b.getAllowanceInfo(stub, owner)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "transferFrom: getAllowanceInfo failed. error=(%s)", errcm)
+	}
+
+	var allow *Allowance
+	for i := range aai.List {
+		if aai.List[i].Spender == spender && b.isSameAsset(aai.List[i].AssetID, assetId) {
+			allow = &aai.List[i]
+			break
+		}
+	}
+
+	if allow == nil {
+		return baselogger.ErrorECM(ERRCODE_TRANS_ALLOWANCE_NOT_ENOUGH, "transferFrom: %s has no allowance from %s.", spender, owner)
+	}
+	if allow.Expiry > 0 && allow.Expiry <= transTime {
+		return baselogger.ErrorECM(ERRCODE_TRANS_ALLOWANCE_NOT_ENOUGH, "transferFrom: allowance from %s to %s expired at %d.", owner, spender, allow.Expiry)
+	}
+	if allow.Amount < amount {
+		return baselogger.ErrorECM(ERRCODE_TRANS_ALLOWANCE_NOT_ENOUGH, "transferFrom: allowance not enough(%d,%d).", allow.Amount, amount)
+	}
+
+	var appid = allow.AppID
+
+	allow.Amount -= amount
+	errcm = b.setAllowance(stub, owner, allow, transTime)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "transferFrom: setAllowance failed. error=(%s)", errcm)
+	}
+
+	if len(description) == 0 {
+		description = "transferFrom by " + spender
+	}
+
+	_, errcm = b.TransferAsset(stub, assetId, owner, to, transType, description, encMemo, amount, transTime, true, appid)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "transferFrom: transferCoin failed. error=(%s)", errcm)
+	}
+
+	return nil
+}
+
+//查询未过期的授权。isSpender为false时查询accName授权出去的额度，为true时查询accName被授权的额度
+func (b *BASE) queryAllowances(stub shim.ChaincodeStubInterface, accName string, isSpender bool, currTime int64) ([]QueryAllowance, *ErrorCodeMsg) {
+	var result = []QueryAllowance{}
+
+	var owners = []string{accName}
+	if isSpender {
+		var errcm *ErrorCodeMsg
+		owners, errcm = b.getSpenderOwners(stub, accName)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "queryAllowances: getSpenderOwners failed. error=(%s)", errcm)
+		}
+	}
+
+	for _, owner := range owners {
+		aai, errcm := b.getAllowanceInfo(stub, owner)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "queryAllowances: getAllowanceInfo(%s) failed. error=(%s)", owner, errcm)
+		}
+
+		for _, al := range aai.List {
+			if isSpender && al.Spender != accName {
+				continue
+			}
+			if al.Expiry > 0 && al.Expiry <= currTime {
+				continue
+			}
+			result = append(result, QueryAllowance{Owner: owner, Allowance: al})
+		}
+	}
+
+	return result, nil
+}
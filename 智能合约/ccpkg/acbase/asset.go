@@ -0,0 +1,288 @@
+package acbase
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	. "loulan/errcode"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	//默认资产，即引入多资产之前链上唯一的货币。默认资产的余额仍然保存在AccountEntity的TotalAmount、RestAmount中，
+	//发行量由虚拟发行账户COIN_ISSUE_ACC_ENTID记录，不需要注册
+	DEFAULT_ASSET_ID = "default"
+
+	ASSET_MAX_DECIMALS = 18
+)
+
+//资产定义
+type AssetInfo struct {
+	AssetID      string `json:"id"`   //资产id
+	Symbol       string `json:"sym"`  //资产符号
+	Issuer       string `json:"isr"`  //发行账户，只有该账户可以发行此资产
+	SupplyCap    int64  `json:"cap"`  //发行上限
+	Decimals     int    `json:"dec"`  //小数位数，链上都以最小单位记账，只供前端显示用
+	IssuedAmount int64  `json:"isd"`  //已发行数量
+	Creater      string `json:"crtr"` //注册资产的账户
+	Time         int64  `json:"time"` //注册时间
+}
+
+//账户中某种资产的数额
+type AssetAmount struct {
+	TotalAmount int64 `json:"tamt"` //累计收入
+	RestAmount  int64 `json:"ramt"` //余额
+}
+
+//空字符串表示默认资产，便于兼容老接口
+func IsDefaultAsset(assetId string) bool {
+	return len(assetId) == 0 || assetId == DEFAULT_ASSET_ID
+}
+
+func (b *BASE) isSameAsset(assetId1, assetId2 string) bool {
+	if IsDefaultAsset(assetId1) {
+		return IsDefaultAsset(assetId2)
+	}
+	return assetId1 == assetId2
+}
+
+//返回账户某种资产的累计收入和余额
+func (ent *AccountEntity) GetAssetAmount(assetId string) (int64, int64) {
+	if IsDefaultAsset(assetId) {
+		return ent.TotalAmount, ent.RestAmount
+	}
+
+	if aa, ok := ent.AssetAmounts[assetId]; ok {
+		return aa.TotalAmount, aa.RestAmount
+	}
+
+	return 0, 0
+}
+
+//收入或支出某种资产。amount为正数时为收入，累计收入同时增加；为负数时为支出
+func (ent *AccountEntity) AddAssetAmount(assetId string, amount int64) {
+	if IsDefaultAsset(assetId) {
+		ent.RestAmount += amount
+		if amount > 0 {
+			ent.TotalAmount += amount
+		}
+		return
+	}
+
+	if ent.AssetAmounts == nil {
+		ent.AssetAmounts = make(map[string]*AssetAmount)
+	}
+	aa, ok := ent.AssetAmounts[assetId]
+	if !ok {
+		aa = &AssetAmount{}
+		ent.AssetAmounts[assetId] = aa
+	}
+	aa.RestAmount += amount
+	if amount > 0 {
+		aa.TotalAmount += amount
+	}
+}
+
+func (b *BASE) getAssetInfoKey(assetId string) string {
+	return ASSET_INFO_PREFIX + assetId
+}
+
+func (b *BASE) checkAssetId(assetId string) *ErrorCodeMsg {
+	if len(strings.TrimSpace(assetId)) == 0 || IsDefaultAsset(assetId) {
+		return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "assetId '%s' is invalid.", assetId)
+	}
+	if strings.ContainsAny(assetId, ACC_INVALID_CHAR_SET) {
+		return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "assetId '%s' can not contains any of '%s'.", assetId, ACC_INVALID_CHAR_SET)
+	}
+	return nil
+}
+
+func (b *BASE) GetAssetInfo(stub shim.ChaincodeStubInterface, assetId string) (*AssetInfo, *ErrorCodeMsg) {
+	var ai AssetInfo
+
+	aiB, err := StateCache.GetState_Ex(stub, b.getAssetInfoKey(assetId))
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getAssetInfo: GetState_Ex failed, error=(%s).", err)
+	}
+
+	if aiB == nil {
+		return nil, ErrcmNilEntity
+	}
+
+	if err = json.Unmarshal(aiB, &ai); err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getAssetInfo: Unmarshal failed, error=(%s).", err)
+	}
+
+	return &ai, nil
+}
+
+func (b *BASE) setAssetInfo(stub shim.ChaincodeStubInterface, ai *AssetInfo) *ErrorCodeMsg {
+	aiB, err := json.Marshal(ai)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setAssetInfo: Marshal failed. error=(%s)", err)
+	}
+
+	err = StateCache.PutState_Ex(stub, b.getAssetInfoKey(ai.AssetID), aiB)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setAssetInfo: PutState failed. error=(%s)", err)
+	}
+
+	return nil
+}
+
+//非默认资产必须已注册
+func (b *BASE) checkAssetExists(stub shim.ChaincodeStubInterface, assetId string) *ErrorCodeMsg {
+	if IsDefaultAsset(assetId) {
+		return nil
+	}
+
+	_, errcm := b.GetAssetInfo(stub, assetId)
+	if errcm != nil {
+		if errcm == ErrcmNilEntity {
+			return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "asset(%s) not exists.", assetId)
+		}
+		return baselogger.ErrorECM(errcm.Code, "getAssetInfo(%s) failed. error=(%s)", assetId, errcm)
+	}
+
+	return nil
+}
+
+//注册资产
+func (b *BASE) registerAsset(stub shim.ChaincodeStubInterface, ai *AssetInfo) *ErrorCodeMsg {
+	if errcm := b.checkAssetId(ai.AssetID); errcm != nil {
+		return errcm
+	}
+	if ai.SupplyCap <= 0 {
+		return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "registerAsset: supply cap(%d) must > 0.", ai.SupplyCap)
+	}
+	if ai.Decimals < 0 || ai.Decimals > ASSET_MAX_DECIMALS {
+		return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "registerAsset: decimals(%d) must in [0,%d].", ai.Decimals, ASSET_MAX_DECIMALS)
+	}
+
+	_, errcm := b.GetAssetInfo(stub, ai.AssetID)
+	if errcm == nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "registerAsset: asset(%s) exists.", ai.AssetID)
+	} else if errcm != ErrcmNilEntity {
+		return baselogger.ErrorECM(errcm.Code, "registerAsset: getAssetInfo failed. error=(%s)", errcm)
+	}
+
+	exists, errcm := b.IsAccEntityExists(stub, ai.Issuer)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "registerAsset: isEntityExists(%s) failed. error=(%s)", ai.Issuer, errcm)
+	}
+	if !exists {
+		return baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "registerAsset: issuer account(%s) not exists.", ai.Issuer)
+	}
+
+	ai.IssuedAmount = 0
+
+	errcm = b.setAssetInfo(stub, ai)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "registerAsset: setAssetInfo failed. error=(%s)", errcm)
+	}
+
+	//记录所有的资产id，供查询
+	listB, err := StateCache.GetState_Ex(stub, ALL_ASSET_LIST_KEY)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "registerAsset: GetState failed. error=(%s)", err)
+	}
+	listB = append(listB, []byte(ai.AssetID)...)
+	listB = append(listB, MULTI_STRING_DELIM)
+
+	err = StateCache.PutState_Ex(stub, ALL_ASSET_LIST_KEY, listB)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "registerAsset: PutState failed. error=(%s)", err)
+	}
+
+	return nil
+}
+
+//查询所有注册的资产，不包括默认资产
+func (b *BASE) getAllAssetInfo(stub shim.ChaincodeStubInterface) ([]AssetInfo, *ErrorCodeMsg) {
+	var assets = []AssetInfo{}
+
+	listB, err := StateCache.GetState_Ex(stub, ALL_ASSET_LIST_KEY)
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getAllAssetInfo: GetState failed. error=(%s)", err)
+	}
+
+	var buf = bytes.NewBuffer(listB)
+	for {
+		idB, err := buf.ReadBytes(MULTI_STRING_DELIM)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getAllAssetInfo: ReadBytes failed. error=(%s)", err)
+		}
+
+		ai, errcm := b.GetAssetInfo(stub, string(idB[:len(idB)-1]))
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "getAllAssetInfo: getAssetInfo(%s) failed. error=(%s)", string(idB), errcm)
+		}
+		assets = append(assets, *ai)
+	}
+
+	return assets, nil
+}
+
+//发行资产。默认资产由央行发行，其它资产由注册时指定的发行账户发行，发行总量不能超过上限
+func (b *BASE) issueAsset(stub shim.ChaincodeStubInterface, assetId, issuer string, issueAmount, issueTime int64) *ErrorCodeMsg {
+	if IsDefaultAsset(assetId) {
+		_, errcm := b.issueCoin(stub, issuer, issueAmount, issueTime)
+		return errcm
+	}
+
+	if issueAmount < 0 {
+		return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "issueAsset issueAmount < 0.")
+	}
+	if issueAmount == 0 {
+		return nil
+	}
+
+	ai, errcm := b.GetAssetInfo(stub, assetId)
+	if errcm != nil {
+		if errcm == ErrcmNilEntity {
+			return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "issueAsset: asset(%s) not exists.", assetId)
+		}
+		return baselogger.ErrorECM(errcm.Code, "issueAsset: getAssetInfo failed. error=(%s)", errcm)
+	}
+
+	if ai.Issuer != issuer {
+		return baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "issueAsset: issuer of asset(%s) is %s, can't issue by %s.", assetId, ai.Issuer, issuer)
+	}
+
+	if issueAmount > ai.SupplyCap-ai.IssuedAmount {
+		return baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "issueAsset: issue amount not enougth(%v,%v), reject.", ai.SupplyCap-ai.IssuedAmount, issueAmount)
+	}
+
+	issuerEnt, errcm := b.GetAccountEntity(stub, issuer)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "issueAsset: getAccountEntity(%s) failed. error=(%s)", issuer, errcm)
+	}
+
+	ai.IssuedAmount += issueAmount
+	issuerEnt.AddAssetAmount(assetId, issueAmount)
+
+	errcm = b.setAssetInfo(stub, ai)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "issueAsset: setAssetInfo failed. error=(%s)", errcm)
+	}
+
+	errcm = b.setAccountEntity(stub, issuerEnt)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "issueAsset: setAccountEntity failed. error=(%s)", errcm)
+	}
+
+	//和默认资产一样，只记录发行账户的收入
+	var virtualEnt = AccountEntity{EntID: COIN_ISSUE_ACC_ENTID, EntType: -1}
+	_, errcm = b.recordTranse(stub, issuerEnt, &virtualEnt, TRANS_INCOME, "issue", "issue asset "+assetId+".", "", issueAmount, issueTime, "", assetId, 0, 0)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "issueAsset: recordTranse failed. error=(%s)", errcm)
+	}
+
+	return nil
+}
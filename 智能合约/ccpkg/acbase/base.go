@@ -1,4 +1,4 @@
-package main
+package acbase
 
 import (
 	"bufio"
@@ -17,8 +17,16 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"loulan/llcrypto"
+
+	. "loulan/ccutil"
+	. "loulan/errcode"
+	. "loulan/mylog"
+
+	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	pb "github.com/hyperledger/fabric/protos/peer"
@@ -32,39 +40,100 @@ const (
 	ATTR_USRNAME = "usrname"
 	ATTR_USRTYPE = "usrtype"
 
-	/*********************************************/
-	/****** 适配新模块请修改此常量，一般用模块名命名******/
-	/*********************************************/
-	EXTEND_MODULE_NAME = "acsys"
-
-	//每个key都加上前缀，便于区分，也便于以后在线升级时处理方便
-	TRANSSEQ_PREFIX      = "!" + EXTEND_MODULE_NAME + "@txSeqPre~"          //序列号生成器的key的前缀。使用的是worldState存储
-	TRANSINFO_PREFIX     = "!" + EXTEND_MODULE_NAME + "@txInfoPre~"         //全局交易信息的key的前缀。使用的是worldState存储
-	ONE_ACC_TRANS_PREFIX = "!" + EXTEND_MODULE_NAME + "@oneAccTxPre~"       //存储单个账户的交易的key前缀
-	USR_ENTITY_PREFIX    = "!" + EXTEND_MODULE_NAME + "@usrEntPre~"         //存储某个用户的用户信息的key前缀。
-	ACC_ENTITY_PREFIX    = "!" + EXTEND_MODULE_NAME + "@accEntPre~"         //存储某个账户的账户信息的key前缀。
-	USR_INFOS_PREFIX     = "!" + EXTEND_MODULE_NAME + "@usrInfoPre~"        //存储某个用户的信息的key前缀。
-	CENTERBANK_ACC_KEY   = "!" + EXTEND_MODULE_NAME + "@centerBankAccKey@!" //央行账户的key。使用的是worldState存储
-	ALL_ACC_INFO_KEY     = "!" + EXTEND_MODULE_NAME + "@allAccInfoKey@!"    //存储所有账户名的key。使用的是worldState存储
-	ACC_STATIC_INFO_KEY  = "!" + EXTEND_MODULE_NAME + "@accStatcInfoKey@!"  //存储所有账户统计信息的key。
-	ACC_AMTLOCK_PREFIX   = "!" + EXTEND_MODULE_NAME + "@accAmtLockPre~"     //账户金额锁定key前缀
-	APP_INFO_PREFIX      = "!" + EXTEND_MODULE_NAME + "@appInfoKeyPre~"     //应用信息
-
-	WORLDSTATE_FILE_PREFIX = "/home/" + EXTEND_MODULE_NAME + "_worldstate_"
-
 	MULTI_STRING_DELIM        = ':' //多个string的分隔符
 	INVALID_MD5_VALUE         = "-"
 	INVALID_PUBKEY_HASH_VALUE = "-"
 
-	ACC_INVALID_CHAR_SET = ",;:/\\"                  //账户中不能包含的字符
-	COIN_ISSUE_ACC_ENTID = "issueCoinVirtualAccount" //发行货币的账户id
+	ACC_INVALID_CHAR_SET = ",;:/\\" //账户中不能包含的字符
 
 	CONTROL_CC_NAME              = "sysctrlcc"
 	CONTROL_CC_GETPARA_FUNC_NAME = "getParameter"
 
+	//控制合约中的参数名
+	CTRL_PARA_IS_TEST_CHAIN         = "isTestChain"
+	CTRL_PARA_NEED_CHECK_SIGN       = "needCheckSign"
+	CTRL_PARA_NEED_CHECK_IDENTITY   = "needCheckIndentity"
+	CTRL_PARA_BALANCE_CKPT_INTERVAL = "balanceCkptInterval"
+
 	CROSSCCCALL_PREFIX = "^_^~"
+
+	ENC_MEMO_MAX_LEN = 4096 //加密备注的最大长度
+
+	//账户要求的签名格式
+	SIGN_SCHEME_LEGACY       = 0                //旧格式，兼容老客户端，同时也接受v1格式的签名
+	SIGN_SCHEME_CANONICAL_V1 = 1                //只接受v1格式的签名，见llcrypto/signmsg.go
+	SIGN_MAX_VALID_PERIOD    = 24 * 3600 * 1000 //v1签名的过期时间最多在交易时间之后24小时，单位毫秒
+)
+
+var (
+	//每个key都加上前缀，便于区分，也便于以后在线升级时处理方便
+	TRANSSEQ_PREFIX          string //序列号生成器的key的前缀。使用的是worldState存储
+	TRANSINFO_PREFIX         string //全局交易信息的key的前缀。使用的是worldState存储
+	ONE_ACC_TRANS_PREFIX     string //存储单个账户的交易的key前缀
+	USR_ENTITY_PREFIX        string //存储某个用户的用户信息的key前缀。
+	ACC_ENTITY_PREFIX        string //存储某个账户的账户信息的key前缀。
+	USR_INFOS_PREFIX         string //存储某个用户的信息的key前缀。
+	CENTERBANK_ACC_KEY       string //央行账户的key。使用的是worldState存储
+	ALL_ACC_INFO_KEY         string //存储所有账户名的key。使用的是worldState存储
+	ACC_STATIC_INFO_KEY      string //存储所有账户统计信息的key。
+	ACC_AMTLOCK_PREFIX       string //账户金额锁定key前缀
+	APP_INFO_PREFIX          string //应用信息
+	ASSET_INFO_PREFIX        string //资产定义
+	ALL_ASSET_LIST_KEY       string //存储所有资产id的key
+	ALLOWANCE_PREFIX         string //账户授权出去的额度
+	ALLOWANCE_SPENDER_PREFIX string //账户被哪些账户授权了额度
+	FEE_SCHEDULE_PREFIX      string //应用的手续费规则
+	REFUND_INFO_PREFIX       string //交易的退款信息
+	TXID_SERIAL_PREFIX       string //交易id到转账记录序列号的索引
+	BALANCE_CKPT_PREFIX      string //账户余额快照，见history.go
+	SUPPLY_INFO_KEY          string //货币销毁和发行上限修改记录，见supply.go
+	INVOICE_PREFIX           string //收款账单
+	INVOICE_PAYEE_PREFIX     string //收款方的账单id列表
+	HTLC_PREFIX              string //哈希时间锁
+	HTLC_PAYER_PREFIX        string //付款方锁定中的哈希时间锁id列表
+
+	WORLDSTATE_FILE_PREFIX string
+
+	COIN_ISSUE_ACC_ENTID = "issueCoinVirtualAccount" //发行货币的账户id
 )
 
+//设置模块名，并据此生成各个key的前缀，所有key的前缀中都带有模块名，便于区分不同合约的数据。扩展合约需要在自己的init函数中调用，使用自己的模块名
+func SetModuleName(name string) {
+	baselogger.SetModule(name + "base")
+
+	TRANSSEQ_PREFIX = "!" + name + "@txSeqPre~"
+	TRANSINFO_PREFIX = "!" + name + "@txInfoPre~"
+	ONE_ACC_TRANS_PREFIX = "!" + name + "@oneAccTxPre~"
+	USR_ENTITY_PREFIX = "!" + name + "@usrEntPre~"
+	ACC_ENTITY_PREFIX = "!" + name + "@accEntPre~"
+	USR_INFOS_PREFIX = "!" + name + "@usrInfoPre~"
+	CENTERBANK_ACC_KEY = "!" + name + "@centerBankAccKey@!"
+	ALL_ACC_INFO_KEY = "!" + name + "@allAccInfoKey@!"
+	ACC_STATIC_INFO_KEY = "!" + name + "@accStatcInfoKey@!"
+	ACC_AMTLOCK_PREFIX = "!" + name + "@accAmtLockPre~"
+	APP_INFO_PREFIX = "!" + name + "@appInfoKeyPre~"
+	ASSET_INFO_PREFIX = "!" + name + "@assetInfoPre~"
+	ALL_ASSET_LIST_KEY = "!" + name + "@allAssetListKey@!"
+	ALLOWANCE_PREFIX = "!" + name + "@allowancePre~"
+	ALLOWANCE_SPENDER_PREFIX = "!" + name + "@allowanceSpdPre~"
+	FEE_SCHEDULE_PREFIX = "!" + name + "@feeSchedulePre~"
+	REFUND_INFO_PREFIX = "!" + name + "@refundInfoPre~"
+	TXID_SERIAL_PREFIX = "!" + name + "@txidSerialPre~"
+	BALANCE_CKPT_PREFIX = "!" + name + "@balCkptPre~"
+	SUPPLY_INFO_KEY = "!" + name + "@supplyInfoKey@!"
+	INVOICE_PREFIX = "!" + name + "@invoicePre~"
+	INVOICE_PAYEE_PREFIX = "!" + name + "@invoicePayeePre~"
+	HTLC_PREFIX = "!" + name + "@htlcPre~"
+	HTLC_PAYER_PREFIX = "!" + name + "@htlcPayerPre~"
+
+	WORLDSTATE_FILE_PREFIX = "/home/" + name + "_worldstate_"
+}
+
+//设置发行货币的虚拟账户id。Retail等老合约的链上数据使用的是"VirtualAccount"，移植过来的合约需要在自己的init函数中调用
+func SetIssueAccountId(entId string) {
+	COIN_ISSUE_ACC_ENTID = entId
+}
+
 type UserEntity struct {
 	EntID       string   `json:"id"`  //ID
 	AuthAccList []string `json:"aal"` //此user被授权给了哪些账户
@@ -74,15 +143,18 @@ type UserEntity struct {
 //账户信息Entity
 // 一系列ID（或账户）都定义为字符串类型。因为putStat函数的第一个参数为字符串类型，这些ID（或账户）都作为putStat的第一个参数；另外从SDK传过来的参数也都是字符串类型。
 type AccountEntity struct {
-	EntID             string              `json:"id"`   //银行/企业/项目/个人ID
-	EntType           int                 `json:"etp"`  //类型 中央银行:1, 企业:2, 项目:3, 个人:4
-	TotalAmount       int64               `json:"tamt"` //货币总数额(发行或接收)
-	RestAmount        int64               `json:"ramt"` //账户余额
-	Time              int64               `json:"time"` //开户时间
-	Owner             string              `json:"own"`  //该实例所属的用户
-	OwnerPubKeyHash   string              `json:"opbk"` //公钥hash
-	OwnerIdentityHash string              `json:"oidt"` //身份hash
-	AuthUserHashMap   map[string][]string `json:"auhm"` //授权用户的pubkey和indentity的hash
+	EntID             string                  `json:"id"`             //银行/企业/项目/个人ID
+	EntType           int                     `json:"etp"`            //类型 中央银行:1, 企业:2, 项目:3, 个人:4
+	TotalAmount       int64                   `json:"tamt"`           //货币总数额(发行或接收)
+	RestAmount        int64                   `json:"ramt"`           //账户余额
+	Time              int64                   `json:"time"`           //开户时间
+	Owner             string                  `json:"own"`            //该实例所属的用户
+	OwnerPubKeyHash   string                  `json:"opbk"`           //公钥hash
+	OwnerIdentityHash string                  `json:"oidt"`           //身份hash
+	OwnerPubKey       string                  `json:"opk,omitempty"`  //公钥，base64，用户自己登记，用于加密转账备注
+	AuthUserHashMap   map[string][]string     `json:"auhm"`           //授权用户的pubkey和indentity的hash
+	AssetAmounts      map[string]*AssetAmount `json:"asam,omitempty"` //默认资产以外的其它资产的数额，见asset.go
+	SignScheme        int                     `json:"ssch,omitempty"` //要求的签名格式，见SIGN_SCHEME_*
 }
 
 type UserInfo struct {
@@ -118,16 +190,20 @@ type AppInfo struct {
 }
 
 type PubTrans struct {
-	FromID       string `json:"fid"`  //发送方ID
-	TransFlag    int    `json:"tsf"`  //交易标志，收入还是支出
-	Amount       int64  `json:"amt"`  //交易数额
-	ToID         string `json:"tid"`  //接收方ID
-	TransType    string `json:"tstp"` //交易类型，前端传入，透传
-	Description  string `json:"desc"` //交易描述
-	TxID         string `json:"txid"` //交易ID
-	Time         int64  `json:"time"` //交易时间
-	GlobalSerial int64  `json:"gser"` //全局交易序列号
-	AppID        string `json:"app"`  //应用ID  目前一条链一个账户体系，但是可能会有多种应用，所以交易信息记录一下应用id，可以按应用来过滤交易信息
+	FromID       string `json:"fid"`            //发送方ID
+	TransFlag    int    `json:"tsf"`            //交易标志，收入还是支出
+	Amount       int64  `json:"amt"`            //交易数额
+	ToID         string `json:"tid"`            //接收方ID
+	TransType    string `json:"tstp"`           //交易类型，前端传入，透传
+	Description  string `json:"desc"`           //交易描述
+	TxID         string `json:"txid"`           //交易ID
+	Time         int64  `json:"time"`           //交易时间
+	GlobalSerial int64  `json:"gser"`           //全局交易序列号
+	AppID        string `json:"app"`            //应用ID  目前一条链一个账户体系，但是可能会有多种应用，所以交易信息记录一下应用id，可以按应用来过滤交易信息
+	AssetID      string `json:"asid,omitempty"` //资产id，默认资产为空
+	EncMemo      string `json:"emo,omitempty"`  //加密的备注，客户端用付款方和收款方的公钥加密，合约不解析，见llcrypto.EncryptMemo
+	Fee          int64  `json:"fee,omitempty"`  //付款方在转账金额之外另付的手续费，手续费本身另有交易记录，见fee.go
+	RefundOf     int64  `json:"rfof,omitempty"` //退款交易对应的原交易付款记录的全局序列号，见refund.go
 }
 
 //交易内容  注意，Transaction中的字段名（包括json字段名）不能和PubTrans中的字段名重复，否则解析会出问题
@@ -143,6 +219,7 @@ type QueryBalance struct {
 	IssueAmount  int64  `json:"issueAmount"`  //市面上发行货币的总量
 	AccCount     int64  `json:"accCount"`     //所有账户的总量
 	AccSumAmount int64  `json:"accSumAmount"` //所有账户的货币的总量
+	BurnedAmount int64  `json:"burnedAmount"` //累计销毁的货币
 	Message      string `json:"message"`      //对账附件信息
 }
 
@@ -161,9 +238,11 @@ type AccountCoinLockInfo struct {
 }
 
 type QueryBalanceAndLocked struct {
+	AssetID      string        `json:"asset"`
 	Balance      int64         `json:"balance"`
 	LockedAmount int64         `json:"lockedamt"`
 	LockCfg      []CoinLockCfg `json:"lockcfg"`
+	HtlcAmount   int64         `json:"htlcamt"` //哈希时间锁锁定的金额，已包含在LockedAmount中
 }
 
 type TopNData struct {
@@ -196,16 +275,20 @@ type BaseInvokeArgs struct {
 //扩展包的回调函数
 var InitHook func(shim.ChaincodeStubInterface, *BaseInitArgs) ([]byte, *ErrorCodeMsg)
 var InvokeHook func(shim.ChaincodeStubInterface, *BaseInvokeArgs) ([]byte, *ErrorCodeMsg)
+
+//base处理交易之前调用，返回错误时拒绝该交易。扩展包用来禁用base中和其自身数据不兼容的函数
+var InvokeFilterHook func(stub shim.ChaincodeStubInterface, function string, ifas *BaseInvokeArgs) *ErrorCodeMsg
 var DateConvertWhenLoadHook func(stub shim.ChaincodeStubInterface, srcCcid, key string, valueB []byte) (string, []byte, *ErrorCodeMsg)
 var DateUpdateAfterLoadHook func(stub shim.ChaincodeStubInterface, srcCcid string) *ErrorCodeMsg
 
-var baselogger = NewMylogger(EXTEND_MODULE_NAME + "base")
-var baseCrypto = MyCryptoNew()
+var baselogger = NewMylogger("acsysbase")
+var baseCrypto = llcrypto.MyCryptoNew()
 
 var ErrcmNilEntity = NewErrorCodeMsg(ERRCODE_COMMON_INNER_ERROR, "nil entity.")
 var ErrcmUnregistedFun = NewErrorCodeMsg(ERRCODE_COMMON_INNER_ERROR, "unregisted function.")
 
-var stateCache StateWorldCache
+var StateCache StateWorldCache
+var ctrlParaCache CtrlParameterCache
 
 type BASE struct {
 }
@@ -214,6 +297,7 @@ var Base BASE
 
 //包初始化函数
 func init() {
+	SetModuleName("acsys")
 	baselogger.SetDefaultLvl(shim.LogDebug)
 }
 
@@ -228,7 +312,7 @@ func (b *BASE) Init(stub shim.ChaincodeStubInterface) (pbResponse pb.Response) {
 
 	payload, errcm := b.__Init(stub)
 	if errcm != nil {
-		return shim.Error(errcm.toJson())
+		return shim.Error(errcm.ToJson())
 	}
 
 	return shim.Success(payload)
@@ -239,9 +323,11 @@ func (b *BASE) __Init(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg)
 	function, args := stub.GetFunctionAndParameters()
 	baselogger.Info("func =%s, args = %+v", function, args)
 
-	stateCache.Create(stub)
+	StateCache.Create(stub)
+	ctrlParaCache.Create(stub)
 	defer func() {
-		stateCache.Destroy(stub)
+		StateCache.Destroy(stub)
+		ctrlParaCache.Destroy(stub)
 	}()
 
 	LogCtrlParameter(baselogger)
@@ -269,9 +355,9 @@ func (b *BASE) __Init(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg)
 		//do someting
 
 		//虚拟一个超级账户，设置货币发行总额，给央行发行货币。
-		errcm := b.setIssueAmountTotal(stub, 10000000000, initTime)
+		errcm := b.SetIssueAmountTotal(stub, 10000000000, initTime)
 		if errcm != nil {
-			return nil, baselogger.ErrorECM(errcm.Code, "Init setIssueAmountTotal error, error=(%s).", errcm)
+			return nil, baselogger.ErrorECM(errcm.Code, "Init SetIssueAmountTotal error, error=(%s).", errcm)
 		}
 
 		if InitHook == nil {
@@ -300,7 +386,11 @@ func (b *BASE) __Init(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg)
 }
 
 var sysFunc = []string{"account", "transefer", "transefer3", "registerApp", "updateUserInfo", "recharge",
-	"getBalance", "getBalanceAndLocked", "getTransInfo", "isAccExists", "getAppInfo", "getStatisticInfo", "getRankingAndTopN", "getUserInfo"}
+	"getBalance", "getBalanceAndLocked", "getTransInfo", "isAccExists", "getAppInfo", "getStatisticInfo", "getRankingAndTopN", "getUserInfo",
+	"registerAsset", "getAssetInfo", "approve", "revokeAllowance", "transferFrom", "getAllowance", "setPubKey", "getPubKey", "setSignScheme",
+	"setFeeRule", "getFeeSchedule", "refund", "getRefundInfo", "getHistoryBalance", "getStatement",
+	"burn", "setIssueCap", "getSupplyInfo", "createInvoice", "cancelInvoice", "payInvoice", "getInvoice", "getInvoicesByPayee",
+	"lockHtlc", "claimHtlc", "refundHtlc", "getHtlc"}
 
 // Transaction makes payment of X units from A to B
 func (b *BASE) Invoke(stub shim.ChaincodeStubInterface) (pbResponse pb.Response) {
@@ -314,7 +404,7 @@ func (b *BASE) Invoke(stub shim.ChaincodeStubInterface) (pbResponse pb.Response)
 
 	payload, errcm := b.__Invoke(stub)
 	if errcm != nil {
-		return shim.Error(errcm.toJson())
+		return shim.Error(errcm.ToJson())
 	}
 
 	return shim.Success(payload)
@@ -326,9 +416,11 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 	baselogger.Debug("Enter Invoke")
 	baselogger.Debug("func =%s, args = %+v", function, args)
 
-	stateCache.Create(stub)
+	StateCache.Create(stub)
+	ctrlParaCache.Create(stub)
 	defer func() {
-		stateCache.Destroy(stub)
+		StateCache.Destroy(stub)
+		ctrlParaCache.Destroy(stub)
 	}()
 
 	var err error
@@ -367,11 +459,12 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 	var accountEnt *AccountEntity = nil
 
 	var sign, signMsg []byte
+	var signIsV1 bool
 
 	//开户时验证在客户函数中做
 	if function != "account" && function != "accountCB" {
 
-		accountEnt, errcm = b.getAccountEntity(stub, accName)
+		accountEnt, errcm = b.GetAccountEntity(stub, accName)
 		if errcm != nil {
 			//查询函数和invoke函数走的一个入口， 所以查询接口的几个特殊处理放在这里
 			if errcm == ErrcmNilEntity {
@@ -383,17 +476,17 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 					return []byte(strconv.FormatInt(ERRCODE_TRANS_PAY_ACCOUNT_NOT_EXIST, 10)), nil
 				}
 			}
-			return nil, baselogger.ErrorECM(errcm.Code, "Invoke getAccountEntity(%s) failed. error=(%s)", accName, errcm)
+			return nil, baselogger.ErrorECM(errcm.Code, "Invoke GetAccountEntity(%s) failed. error=(%s)", accName, errcm)
 		}
 
 		//非account时签名为最后一个参数
-		sign, signMsg, errcm = b.getSignAndMsg(function, args, len(args)-1)
+		sign, signMsg, signIsV1, errcm = b.getSignAndMsg(stub, function, args, len(args)-1, invokeTime)
 		if errcm != nil {
 			return nil, baselogger.ErrorECM(errcm.Code, "Invoke: getSignAndMsg(%s) failed, error=(%s).", accName, errcm)
 		}
 
 		//校验修改Entity的用户身份，只有Entity的所有者才能修改自己的Entity
-		if errcm = b.verifyIdentity(stub, userName, sign, signMsg, accountEnt, "", ""); errcm != nil {
+		if errcm = b.verifyIdentity(stub, userName, sign, signMsg, signIsV1, accountEnt, "", ""); errcm != nil {
 			return nil, baselogger.ErrorECM(ERRCODE_COMMON_IDENTITY_VERIFY_FAILED, "Invoke: verifyIdentity(%s) failed.", accName)
 		}
 
@@ -403,10 +496,16 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 
 	invokeFixArgs.AccountEnt = accountEnt
 
+	if InvokeFilterHook != nil {
+		if errcm = InvokeFilterHook(stub, function, &invokeFixArgs); errcm != nil {
+			return nil, errcm
+		}
+	}
+
 	if len(crossCallChaincodeName) > 0 && !b.isAccountSysFunc(function) {
 		var calledArgs = stub.GetArgs()
 		//这里获取的是原始参数，所以要去掉后两个参数，最后一个参数是自动添加用来区分是不是跨合约调用的，倒数第二个为签名
-		payload, errcm := b.corssChaincodeCall(stub, calledArgs[:len(calledArgs)-2], crossCallChaincodeName, userName, accName, sign, signMsg)
+		payload, errcm := b.corssChaincodeCall(stub, calledArgs[:len(calledArgs)-2], crossCallChaincodeName, userName, accName, sign, signMsg, signIsV1)
 		if errcm != nil {
 			baselogger.Error("Invoke: invoke chaincode '%s' failed, error=(%s)", crossCallChaincodeName, errcm)
 			return nil, errcm //直接返回被调用者的错误信息
@@ -431,7 +530,7 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 		var userIdHash = args[len(args)-1] //base64
 
 		//签名为倒数第二个参数
-		sig, msg, errcm := b.getSignAndMsg(function, args, len(args)-2)
+		sig, msg, sigIsV1, errcm := b.getSignAndMsg(stub, function, args, len(args)-2, invokeTime)
 		if errcm != nil {
 			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(account): getSignAndMsg(%s) failed, error=(%s).", accName, errcm)
 		}
@@ -443,7 +542,7 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 		var userPubKeyHash = args[fixedArgCount] //base64
 
 		//校验修改Entity的用户身份，只有Entity的所有者才能修改自己的Entity
-		if errcm = b.verifyIdentity(stub, userName, sig, msg, nil, userPubKeyHash, userIdHash); errcm != nil {
+		if errcm = b.verifyIdentity(stub, userName, sig, msg, sigIsV1, nil, userPubKeyHash, userIdHash); errcm != nil {
 			return nil, baselogger.ErrorECM(ERRCODE_COMMON_IDENTITY_VERIFY_FAILED, "Invoke(account) verifyIdentity(%s) failed.", accName)
 		}
 
@@ -487,17 +586,17 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 		var userIdHash = args[len(args)-1]       //base64
 
 		//签名为倒数第二个参数
-		sig, msg, errcm := b.getSignAndMsg(function, args, len(args)-2)
+		sig, msg, sigIsV1, errcm := b.getSignAndMsg(stub, function, args, len(args)-2, invokeTime)
 		if errcm != nil {
 			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(account): getSignAndMsg(%s) failed, error=(%s).", accName, errcm)
 		}
 
 		//校验修改Entity的用户身份，只有Entity的所有者才能修改自己的Entity
-		if errcm = b.verifyIdentity(stub, userName, sig, msg, nil, userPubKeyHash, userIdHash); errcm != nil {
+		if errcm = b.verifyIdentity(stub, userName, sig, msg, sigIsV1, nil, userPubKeyHash, userIdHash); errcm != nil {
 			return nil, baselogger.ErrorECM(ERRCODE_COMMON_IDENTITY_VERIFY_FAILED, "Invoke(accountCB) verifyIdentity(%s) failed.", accName)
 		}
 
-		tmpByte, errcm := b.getCenterBankAcc(stub)
+		tmpByte, errcm := b.GetCenterBankAcc(stub)
 		if errcm != nil {
 			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(accountCB) getCenterBankAcc failed. error=(%s)", errcm)
 		}
@@ -534,7 +633,22 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 		}
 		baselogger.Debug("issueAmount= %+v", issueAmount)
 
-		tmpByte, errcm := b.getCenterBankAcc(stub)
+		//可选参数，发行的资产，不传时为默认资产
+		var assetId string
+		if len(args) > argCount {
+			assetId = args[argCount]
+		}
+
+		//默认资产以外的资产，由资产的发行账户发行
+		if !IsDefaultAsset(assetId) {
+			errcm = b.issueAsset(stub, assetId, accName, issueAmount, invokeTime)
+			if errcm != nil {
+				return nil, baselogger.ErrorECM(errcm.Code, "Invoke(issue) issueAsset failed. error=(%s)", errcm)
+			}
+			return nil, nil
+		}
+
+		tmpByte, errcm := b.GetCenterBankAcc(stub)
 		if errcm != nil {
 			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(issue) getCenterBankAcc failed. error=(%s)", errcm)
 		}
@@ -587,9 +701,17 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 				sameEntSaveTransFlag = false
 			}
 		}
+		var assetId string
+		if len(args) > argCount+3 {
+			assetId = args[argCount+3]
+		}
+		var encMemo string //加密的备注
+		if len(args) > argCount+4 {
+			encMemo = args[argCount+4]
+		}
 
 		//测试链不检查 appid 是否存在
-		if !Ctrl_isTestChain {
+		if !b.isTestChain(stub) {
 			idExist, errcm := b.isAppExists(stub, appid)
 			if errcm != nil {
 				return nil, baselogger.ErrorECM(errcm.Code, "Invoke(transefer) failed, error=(%s).", errcm)
@@ -599,7 +721,7 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 			}
 		}
 
-		_, errcm = b.transferCoin(stub, accName, toAcc, transType, description, transAmount, invokeTime, sameEntSaveTransFlag, appid)
+		_, errcm = b.TransferAsset(stub, assetId, accName, toAcc, transType, description, encMemo, transAmount, invokeTime, sameEntSaveTransFlag, appid)
 		if errcm != nil {
 			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(transefer) transferCoin failed. error=(%s)", errcm)
 		}
@@ -639,9 +761,17 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 				sameEntSaveTransFlag = false
 			}
 		}
+		var assetId string
+		if len(args) > argCount+3 {
+			assetId = args[argCount+3]
+		}
+		var encMemo string //加密的备注
+		if len(args) > argCount+4 {
+			encMemo = args[argCount+4]
+		}
 
 		var lockedThistime int64 = 0
-		lockedThistime, _, errcm = b.setAccountLockAmountCfg(stub, toAcc, lockCfgs, false)
+		lockedThistime, _, errcm = b.setAccountLockAmountCfg(stub, toAcc, assetId, lockCfgs, false)
 		if errcm != nil {
 			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(transeferLockAmt): setAccountLockAmountCfg failed. error=(%s)", errcm)
 		}
@@ -651,7 +781,7 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 		}
 
 		//测试链不检查 appid 是否存在
-		if !Ctrl_isTestChain {
+		if !b.isTestChain(stub) {
 			idExist, errcm := b.isAppExists(stub, appid)
 			if errcm != nil {
 				return nil, baselogger.ErrorECM(errcm.Code, "Invoke(transeferLockAmt) failed, error=(%s).", errcm)
@@ -661,15 +791,449 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 			}
 		}
 
-		_, errcm = b.transferCoin(stub, accName, toAcc, transType, description, transAmount, invokeTime, sameEntSaveTransFlag, appid)
+		_, errcm = b.TransferAsset(stub, assetId, accName, toAcc, transType, description, encMemo, transAmount, invokeTime, sameEntSaveTransFlag, appid)
 		if errcm != nil {
 			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(transeferLockAmt) transferCoin3 failed. error=(%s)", errcm)
 		}
 		return nil, nil
 
+	} else if function == "approve" { //授权其它账户在额度内从本账户转账
+		var argCount = fixedArgCount + 4
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(approve) miss arg, got %d, at least need %d.", len(args), argCount)
+		}
+
+		var allow Allowance
+		allow.Spender = args[fixedArgCount]
+		allow.Amount, err = strconv.ParseInt(args[fixedArgCount+1], 0, 64)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(approve): convert amount(%s) failed. error=(%s)", args[fixedArgCount+1], err)
+		}
+		allow.Expiry, err = strconv.ParseInt(args[fixedArgCount+2], 0, 64)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(approve): convert expiry(%s) failed. error=(%s)", args[fixedArgCount+2], err)
+		}
+		allow.AppID = args[fixedArgCount+3]
+		allow.Time = invokeTime
+
+		//可选参数，授权的资产
+		if len(args) > argCount && !IsDefaultAsset(args[argCount]) {
+			allow.AssetID = args[argCount]
+		}
+
+		if allow.Amount < 0 {
+			return nil, baselogger.ErrorECM(ERRCODE_TRANS_AMOUNT_INVALID, "Invoke(approve): invalid amount(%d).", allow.Amount)
+		}
+		if allow.Expiry != 0 && allow.Expiry <= invokeTime {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(approve): expiry(%d) is earlier than now(%d).", allow.Expiry, invokeTime)
+		}
+		if allow.Spender == accName {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(approve): can not approve to self.")
+		}
+
+		exists, errcm := b.IsAccEntityExists(stub, allow.Spender)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(approve): isEntityExists(%s) failed. error=(%s)", allow.Spender, errcm)
+		}
+		if !exists {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "Invoke(approve): spender(%s) not exists.", allow.Spender)
+		}
+
+		errcm = b.checkAssetExists(stub, allow.AssetID)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(approve): checkAssetExists failed. error=(%s)", errcm)
+		}
+
+		//测试链不检查 appid 是否存在
+		if !b.isTestChain(stub) {
+			idExist, errcm := b.isAppExists(stub, allow.AppID)
+			if errcm != nil {
+				return nil, baselogger.ErrorECM(errcm.Code, "Invoke(approve) failed, error=(%s).", errcm)
+			}
+			if !idExist {
+				return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(approve) appid(%s) not exist, please register it first.", allow.AppID)
+			}
+		}
+
+		errcm = b.setAllowance(stub, accName, &allow, invokeTime)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(approve): setAllowance failed. error=(%s)", errcm)
+		}
+
+		return nil, nil
+
+	} else if function == "revokeAllowance" {
+		var argCount = fixedArgCount + 1
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(revokeAllowance) miss arg, got %d, at least need %d.", len(args), argCount)
+		}
+
+		var allow Allowance
+		allow.Spender = args[fixedArgCount]
+		if len(args) > argCount && !IsDefaultAsset(args[argCount]) {
+			allow.AssetID = args[argCount]
+		}
+		allow.Amount = 0
+
+		errcm = b.setAllowance(stub, accName, &allow, invokeTime)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(revokeAllowance): setAllowance failed. error=(%s)", errcm)
+		}
+
+		return nil, nil
+
+	} else if function == "transferFrom" { //使用授权额度转账，accName为被授权的账户
+		var argCount = fixedArgCount + 3
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(transferFrom) miss arg, got %d, at least need %d.", len(args), argCount)
+		}
+
+		var owner = args[fixedArgCount]
+		var toAcc = args[fixedArgCount+1]
+
+		var transAmount int64
+		transAmount, err = strconv.ParseInt(args[fixedArgCount+2], 0, 64)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "Invoke(transferFrom): convert amount(%s) failed. error=(%s)", args[fixedArgCount+2], err)
+		}
+
+		//以下为可选参数
+		var description string
+		var transType string
+		var assetId string
+		if len(args) > argCount {
+			description = args[argCount]
+		}
+		if len(args) > argCount+1 {
+			transType = args[argCount+1]
+		}
+		if len(args) > argCount+2 {
+			assetId = args[argCount+2]
+		}
+		var encMemo string
+		if len(args) > argCount+3 {
+			encMemo = args[argCount+3]
+		}
+
+		errcm = b.transferFrom(stub, accName, owner, toAcc, assetId, transType, description, encMemo, transAmount, invokeTime)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(transferFrom) transferFrom failed. error=(%s)", errcm)
+		}
+		return nil, nil
+
+	} else if function == "setPubKey" { //登记公钥，用于加密转账备注。公钥的hash必须和开户时的OwnerPubKeyHash一致
+		var argCount = fixedArgCount + 1
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(setPubKey) miss arg, got %d, at least need %d.", len(args), argCount)
+		}
+
+		errcm = b.setAccountPubKey(stub, accountEnt, args[fixedArgCount])
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(setPubKey) setAccountPubKey failed. error=(%s)", errcm)
+		}
+
+		return nil, nil
+
+	} else if function == "setSignScheme" { //设置账户要求的签名格式。设为v1后不再接受旧格式的签名，所以从v1改回旧格式也必须用v1格式签名
+		var argCount = fixedArgCount + 1
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(setSignScheme) miss arg, got %d, at least need %d.", len(args), argCount)
+		}
+
+		//只有账户所有者能修改，被授权的用户不行
+		if accountEnt.Owner != userName {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "Invoke(setSignScheme) only owner can set sign scheme of %s.", accName)
+		}
+
+		scheme, err := strconv.Atoi(args[fixedArgCount])
+		if err != nil || (scheme != SIGN_SCHEME_LEGACY && scheme != SIGN_SCHEME_CANONICAL_V1) {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(setSignScheme) invalid scheme(%s).", args[fixedArgCount])
+		}
+
+		accountEnt.SignScheme = scheme
+		errcm = b.setAccountEntity(stub, accountEnt)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(setSignScheme) setAccountEntity failed. error=(%s)", errcm)
+		}
+
+		return nil, nil
+
+	} else if function == "setFeeRule" { //设置应用的手续费规则，手续费账户为空时删除规则
+		if !b.IsAdmin(stub, accName) {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "Invoke(setFeeRule) can't exec by %s.", accName)
+		}
+
+		//args: appid, transType, feeAccount, flatFee, rateBP, minFee, maxFee, freeAmount
+		var argCount = fixedArgCount + 8
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(setFeeRule) miss arg, got %d, need %d.", len(args), argCount)
+		}
+
+		var appid = args[fixedArgCount]
+		ok, errcm := b.isAppExists(stub, appid)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(setFeeRule) isAppExists error=(%s).", errcm)
+		}
+		if !ok {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(setFeeRule) appid(%s) not exists.", appid)
+		}
+
+		var rule FeeRule
+		rule.TransType = args[fixedArgCount+1]
+		rule.FeeAccount = args[fixedArgCount+2]
+		rule.Time = invokeTime
+
+		var values = []*int64{&rule.FlatFee, &rule.RateBP, &rule.MinFee, &rule.MaxFee, &rule.FreeAmount}
+		for i, v := range values {
+			*v, err = strconv.ParseInt(args[fixedArgCount+3+i], 0, 64)
+			if err != nil {
+				return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(setFeeRule) convert arg(%s) failed. error=(%s)", args[fixedArgCount+3+i], err)
+			}
+		}
+
+		errcm = b.setFeeRule(stub, appid, &rule)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(setFeeRule) setFeeRule failed. error=(%s)", errcm)
+		}
+
+		return nil, nil
+
+	} else if function == "burn" { //销毁本账户的货币
+		//args: amount, reason
+		var argCount = fixedArgCount + 2
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(burn) miss arg, got %d, need %d.", len(args), argCount)
+		}
+
+		amount, err := strconv.ParseInt(args[fixedArgCount], 0, 64)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(burn) convert amount(%s) failed. error=(%s)", args[fixedArgCount], err)
+		}
+
+		errcm = b.burnCoin(stub, accName, args[fixedArgCount+1], amount, invokeTime)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(burn) burnCoin failed. error=(%s)", errcm)
+		}
+
+		return nil, nil
+
+	} else if function == "setIssueCap" { //修改货币发行上限
+		if !b.IsAdmin(stub, accName) {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "Invoke(setIssueCap) can't exec by %s.", accName)
+		}
+
+		//args: newCap, reason
+		var argCount = fixedArgCount + 2
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(setIssueCap) miss arg, got %d, need %d.", len(args), argCount)
+		}
+
+		newCap, err := strconv.ParseInt(args[fixedArgCount], 0, 64)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(setIssueCap) convert cap(%s) failed. error=(%s)", args[fixedArgCount], err)
+		}
+
+		errcm = b.setIssueCap(stub, accName, args[fixedArgCount+1], newCap, invokeTime)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(setIssueCap) setIssueCap failed. error=(%s)", errcm)
+		}
+
+		return nil, nil
+
+	} else if function == "createInvoice" { //创建收款账单，本账户为收款方
+		//args: invoiceId, amount, expiry, appid, memoHash
+		var argCount = fixedArgCount + 5
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(createInvoice) miss arg, got %d, need %d.", len(args), argCount)
+		}
+
+		var inv Invoice
+		inv.InvoiceID = args[fixedArgCount]
+		inv.Payee = accName
+		inv.Amount, err = strconv.ParseInt(args[fixedArgCount+1], 0, 64)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(createInvoice): convert amount(%s) failed. error=(%s)", args[fixedArgCount+1], err)
+		}
+		inv.Expiry, err = strconv.ParseInt(args[fixedArgCount+2], 0, 64)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(createInvoice): convert expiry(%s) failed. error=(%s)", args[fixedArgCount+2], err)
+		}
+		inv.AppID = args[fixedArgCount+3]
+		inv.MemoHash = args[fixedArgCount+4]
+		inv.CreateTime = invokeTime
+
+		//测试链不检查 appid 是否存在
+		if !b.isTestChain(stub) {
+			idExist, errcm := b.isAppExists(stub, inv.AppID)
+			if errcm != nil {
+				return nil, baselogger.ErrorECM(errcm.Code, "Invoke(createInvoice) failed, error=(%s).", errcm)
+			}
+			if !idExist {
+				return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(createInvoice) appid(%s) not exist, please register it first.", inv.AppID)
+			}
+		}
+
+		errcm = b.createInvoice(stub, &inv)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(createInvoice): createInvoice failed. error=(%s)", errcm)
+		}
+
+		return nil, nil
+
+	} else if function == "cancelInvoice" { //取消账单，收款方或管理员可以取消
+		//args: invoiceId, [payee]  不传payee时为本账户的账单
+		var argCount = fixedArgCount + 1
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(cancelInvoice) miss arg, got %d, need %d.", len(args), argCount)
+		}
+
+		var payee = accName
+		if len(args) > argCount && len(args[argCount]) > 0 {
+			payee = args[argCount]
+		}
+
+		errcm = b.cancelInvoice(stub, accName, payee, args[fixedArgCount], invokeTime)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(cancelInvoice): cancelInvoice failed. error=(%s)", errcm)
+		}
+
+		return nil, nil
+
+	} else if function == "payInvoice" { //支付账单，本账户为付款方
+		//args: payee, invoiceId, amount  收款方和金额为付款方确认过的值，和账单不一致时不支付
+		var argCount = fixedArgCount + 3
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(payInvoice) miss arg, got %d, need %d.", len(args), argCount)
+		}
+
+		amount, err := strconv.ParseInt(args[fixedArgCount+2], 0, 64)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(payInvoice): convert amount(%s) failed. error=(%s)", args[fixedArgCount+2], err)
+		}
+
+		inv, errcm := b.payInvoice(stub, accName, args[fixedArgCount], args[fixedArgCount+1], amount, invokeTime)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(payInvoice): payInvoice failed. error=(%s)", errcm)
+		}
+
+		retValue, err := json.Marshal(inv)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "Invoke(payInvoice): Marshal failed. error=(%s)", err)
+		}
+
+		return retValue, nil
+
+	} else if function == "lockHtlc" { //创建哈希时间锁，本账户为付款方
+		//args: payee, amount, hashlock, timeout, appid, [assetId]
+		var argCount = fixedArgCount + 5
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(lockHtlc) miss arg, got %d, need %d.", len(args), argCount)
+		}
+
+		var lock HtlcLock
+		lock.Payer = accName
+		lock.Payee = args[fixedArgCount]
+		lock.Amount, err = strconv.ParseInt(args[fixedArgCount+1], 0, 64)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(lockHtlc): convert amount(%s) failed. error=(%s)", args[fixedArgCount+1], err)
+		}
+		lock.HashLock = args[fixedArgCount+2]
+		lock.Timeout, err = strconv.ParseInt(args[fixedArgCount+3], 0, 64)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(lockHtlc): convert timeout(%s) failed. error=(%s)", args[fixedArgCount+3], err)
+		}
+		lock.AppID = args[fixedArgCount+4]
+		lock.CreateTime = invokeTime
+
+		if len(args) > argCount {
+			lock.AssetID = args[argCount]
+		}
+
+		//测试链不检查 appid 是否存在
+		if !b.isTestChain(stub) {
+			idExist, errcm := b.isAppExists(stub, lock.AppID)
+			if errcm != nil {
+				return nil, baselogger.ErrorECM(errcm.Code, "Invoke(lockHtlc) failed, error=(%s).", errcm)
+			}
+			if !idExist {
+				return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(lockHtlc) appid(%s) not exist, please register it first.", lock.AppID)
+			}
+		}
+
+		errcm = b.createHtlc(stub, &lock)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(lockHtlc): createHtlc failed. error=(%s)", errcm)
+		}
+
+		retValue, err := json.Marshal(lock)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "Invoke(lockHtlc): Marshal failed. error=(%s)", err)
+		}
+
+		return retValue, nil
+
+	} else if function == "claimHtlc" || function == "refundHtlc" { //收款方出示原像领取，或超时后付款方取回
+		var isClaim = function == "claimHtlc"
+
+		//args: lockId, [preimage]
+		var argCount = fixedArgCount + 1
+		if isClaim {
+			argCount = fixedArgCount + 2
+		}
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(%s) miss arg, got %d, need %d.", function, len(args), argCount)
+		}
+
+		var lock *HtlcLock
+		if isClaim {
+			lock, errcm = b.claimHtlc(stub, accName, args[fixedArgCount], args[fixedArgCount+1], invokeTime)
+		} else {
+			lock, errcm = b.refundHtlc(stub, accName, args[fixedArgCount], invokeTime)
+		}
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(%s) failed. error=(%s)", function, errcm)
+		}
+
+		retValue, err := json.Marshal(lock)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "Invoke(%s): Marshal failed. error=(%s)", function, err)
+		}
+
+		return retValue, nil
+
+	} else if function == "refund" { //退款，由原交易的收款方或管理员发起
+		//args: origTxId或原交易的全局序列号, amount, [description]
+		var argCount = fixedArgCount + 2
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(refund) miss arg, got %d, need %d.", len(args), argCount)
+		}
+
+		var origRef = args[fixedArgCount]
+		amount, err := strconv.ParseInt(args[fixedArgCount+1], 0, 64)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(refund) convert amount(%s) failed. error=(%s)", args[fixedArgCount+1], err)
+		}
+
+		var description string
+		if len(args) > argCount {
+			description = args[argCount]
+		}
+
+		ri, errcm := b.refund(stub, accName, origRef, description, amount, invokeTime)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(refund) refund failed. error=(%s)", errcm)
+		}
+
+		riB, err := json.Marshal(ri)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "Invoke(refund) Marshal failed. error=(%s)", err)
+		}
+
+		return riB, nil
+
 	} else if function == "updateEnv" {
 		//更新环境变量
-		if !b.isAdmin(stub, accName) {
+		if !b.IsAdmin(stub, accName) {
 			return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "Invoke(updateEnv) can't exec updateEnv by %s.", accName)
 		}
 
@@ -694,7 +1258,7 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 		return nil, nil
 
 	} else if function == "updateState" {
-		if !b.isAdmin(stub, accName) {
+		if !b.IsAdmin(stub, accName) {
 			return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "Invoke(setWorldState) can't exec by %s.", accName)
 		}
 
@@ -722,7 +1286,7 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 		return nil, nil
 
 	} else if function == "lockAccAmt" {
-		if !b.isAdmin(stub, accName) {
+		if !b.IsAdmin(stub, accName) {
 			return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "Invoke(lockAccAmt) can't exec by %s.", accName)
 		}
 
@@ -744,18 +1308,60 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 			canLockMoreThanRest = true
 		}
 
-		lockEnt, errcm := b.getAccountEntity(stub, lockedAccName)
+		var assetId string //可选参数，锁定的资产
+		if len(args) > argCount {
+			assetId = args[argCount]
+		}
+		errcm = b.checkAssetExists(stub, assetId)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(lockAccAmt): checkAssetExists failed. error=(%s)", errcm)
+		}
+
+		lockEnt, errcm := b.GetAccountEntity(stub, lockedAccName)
 		if errcm != nil {
 			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(lockAccAmt): getAccountEntity failed. error=(%s)", errcm)
 		}
 
-		_, lockedTotal, errcm := b.setAccountLockAmountCfg(stub, lockedAccName, lockCfgs, overwriteOld)
+		_, lockedTotal, errcm := b.setAccountLockAmountCfg(stub, lockedAccName, assetId, lockCfgs, overwriteOld)
 		if errcm != nil {
 			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(lockAccAmt): setAccountLockAmountCfg failed. error=(%s)", errcm)
 		}
 
-		if !canLockMoreThanRest && lockedTotal > lockEnt.RestAmount {
-			return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "Invoke(lockAccAmt): lock amount > account rest(%d,%d).", lockedTotal, lockEnt.RestAmount)
+		_, lockRest := lockEnt.GetAssetAmount(assetId)
+		if !canLockMoreThanRest && lockedTotal > lockRest {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "Invoke(lockAccAmt): lock amount > account rest(%d,%d).", lockedTotal, lockRest)
+		}
+
+		return nil, nil
+
+	} else if function == "registerAsset" {
+		if !b.IsAdmin(stub, accName) {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "Invoke(registerAsset) can't exec by %s.", accName)
+		}
+
+		var argCount = fixedArgCount + 5
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(registerAsset) miss arg, got %d, need %d.", len(args), argCount)
+		}
+
+		var ai AssetInfo
+		ai.AssetID = args[fixedArgCount]
+		ai.Symbol = args[fixedArgCount+1]
+		ai.Issuer = args[fixedArgCount+2]
+		ai.SupplyCap, err = strconv.ParseInt(args[fixedArgCount+3], 0, 64)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(registerAsset) convert supplyCap(%s) failed. error=(%s)", args[fixedArgCount+3], err)
+		}
+		ai.Decimals, err = strconv.Atoi(args[fixedArgCount+4])
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(registerAsset) convert decimals(%s) failed. error=(%s)", args[fixedArgCount+4], err)
+		}
+		ai.Creater = accName
+		ai.Time = invokeTime
+
+		errcm = b.registerAsset(stub, &ai)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "Invoke(registerAsset) registerAsset failed. error=(%s)", errcm)
 		}
 
 		return nil, nil
@@ -792,7 +1398,7 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 		return nil, nil
 
 	} else if function == "recharge" { //测试链才有
-		if Ctrl_isTestChain {
+		if b.isTestChain(stub) {
 			var argCount = fixedArgCount + 1
 			if len(args) < argCount {
 				return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(lockAccAmt) miss arg, got %d, need %d.", len(args), argCount)
@@ -847,7 +1453,7 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 
 			//获取manager 的hash值 （从账户中取）
 			var mngAccName = managerEnt.AccList[0]
-			mngAccEnt, errcm := b.getAccountEntity(stub, mngAccName)
+			mngAccEnt, errcm := b.GetAccountEntity(stub, mngAccName)
 			if errcm != nil {
 				return nil, baselogger.ErrorECM(errcm.Code, "Invoke(authAccountManager) getAccountEntity failed. error=(%s), entname=%s", errcm, mngAccName)
 			}
@@ -859,7 +1465,7 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 			//第一个元素为身份hash，第二个为pubkey的hash
 			accountEnt.AuthUserHashMap[manager] = []string{mngAccEnt.OwnerIdentityHash, mngAccEnt.OwnerPubKeyHash}
 
-			if !strSliceContains(managerEnt.AuthAccList, accName) {
+			if !StrSliceContains(managerEnt.AuthAccList, accName) {
 				managerEnt.AuthAccList = append(managerEnt.AuthAccList, accName)
 			}
 
@@ -869,7 +1475,7 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 			if managerEnt == nil {
 				baselogger.Warn("manager(%s) not exists.", manager)
 			} else {
-				managerEnt.AuthAccList = strSliceDelete(managerEnt.AuthAccList, accName)
+				managerEnt.AuthAccList = StrSliceDelete(managerEnt.AuthAccList, accName)
 			}
 		}
 
@@ -907,7 +1513,7 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 
 		return nil, nil
 	} else if function == "deleteState" {
-		if Ctrl_isTestChain {
+		if b.isTestChain(stub) {
 			var argCount = fixedArgCount + 1
 			if len(args) < argCount {
 				return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "Invoke(deleteState) miss arg, got %d, at least need %d.", len(args), argCount)
@@ -937,7 +1543,7 @@ func (b *BASE) __Invoke(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg
 				}
 			} else {
 				if keyType == "account" {
-					key = b.getAccountEntityKey(key)
+					key = b.GetAccountEntityKey(key)
 				}
 				err = stub.DelState(key)
 				if err != nil {
@@ -992,7 +1598,7 @@ func (b *BASE) __Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs, f
 	var accountEnt = ifas.AccountEnt
 
 	if accountEnt == nil {
-		accountEnt, errcm = b.getAccountEntity(stub, accName)
+		accountEnt, errcm = b.GetAccountEntity(stub, accName)
 		if errcm != nil {
 			return nil, baselogger.ErrorECM(errcm.Code, "Query getAccountEntity failed. error=(%s)", errcm)
 		}
@@ -1011,7 +1617,14 @@ func (b *BASE) __Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs, f
 
 		baselogger.Debug("queryEntity=%+v", queryEntity)
 
-		retValue := []byte(strconv.FormatInt(queryEntity.RestAmount, 10))
+		var assetId string //可选参数，查询的资产
+		if len(args) > fixedArgCount {
+			assetId = args[fixedArgCount]
+		}
+
+		_, restAmount := queryEntity.GetAssetAmount(assetId)
+
+		retValue := []byte(strconv.FormatInt(restAmount, 10))
 
 		return (retValue), nil
 
@@ -1021,9 +1634,18 @@ func (b *BASE) __Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs, f
 		var queryEntity *AccountEntity = accountEnt
 		baselogger.Debug("queryEntity=%+v", queryEntity)
 
-		qbal.Balance = queryEntity.RestAmount
+		var assetId string //可选参数，查询的资产
+		if len(args) > fixedArgCount {
+			assetId = args[fixedArgCount]
+		}
+
+		qbal.AssetID = assetId
+		if IsDefaultAsset(assetId) {
+			qbal.AssetID = DEFAULT_ASSET_ID
+		}
+		_, qbal.Balance = queryEntity.GetAssetAmount(assetId)
 
-		qbal.LockedAmount, qbal.LockCfg, errcm = b.getAccountLockedAmount(stub, accName, queryTime)
+		qbal.LockedAmount, qbal.LockCfg, errcm = b.getAccountLockedAmount(stub, accName, assetId, queryTime)
 		if errcm != nil {
 			return nil, baselogger.ErrorECM(errcm.Code, "getBalanceAndLocked: getAccountLockedAmount(id=%s) failed. error=(%s)", accName, errcm)
 		}
@@ -1032,6 +1654,11 @@ func (b *BASE) __Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs, f
 			qbal.LockCfg = []CoinLockCfg{} //初始化为空，即使没查到数据也会返回'[]'
 		}
 
+		qbal.HtlcAmount, errcm = b.getHtlcLockedAmount(stub, accName, assetId)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "getBalanceAndLocked: getHtlcLockedAmount(id=%s) failed. error=(%s)", accName, errcm)
+		}
+
 		qbalB, err := json.Marshal(qbal)
 		if err != nil {
 			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getBalanceAndLocked: Marshal(id=%s) failed. error=(%s)", accName, err)
@@ -1101,23 +1728,27 @@ func (b *BASE) __Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs, f
 				return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "queryTx ParseInt for queryMaxSeq(%s) failed. error=(%s)", args[argCount+5], err)
 			}
 		}
+		var assetId string //只查询指定资产的交易，为空时查询所有资产
+		if len(args) > argCount+6 {
+			assetId = args[argCount+6]
+		}
 
 		var isAsc = false
 		if queryOrder == "asc" {
 			isAsc = true
 		}
 
-		if b.isAdmin(stub, accName) {
+		if b.IsAdmin(stub, accName) {
 			//管理员账户时，如果不传入txAcc，则查询所有交易记录；否则查询指定账户交易记录
 			//transLvl 只能管理员有权设置
 			if len(txAcc) == 0 {
-				retValue, errcm := b.queryTransInfos(stub, transLvl, begSeq, txCount, begTime, endTime, queryMaxSeq, isAsc, appid)
+				retValue, errcm := b.queryTransInfos(stub, transLvl, begSeq, txCount, begTime, endTime, queryMaxSeq, isAsc, appid, assetId)
 				if errcm != nil {
 					return nil, baselogger.ErrorECM(errcm.Code, "queryTx queryTransInfos failed. error=(%s)", errcm)
 				}
 				return (retValue), nil
 			} else {
-				retValue, errcm := b.queryAccTransInfos(stub, txAcc, begSeq, txCount, begTime, endTime, queryMaxSeq, isAsc, appid)
+				retValue, errcm := b.queryAccTransInfos(stub, txAcc, begSeq, txCount, begTime, endTime, queryMaxSeq, isAsc, appid, assetId)
 				if errcm != nil {
 					return nil, baselogger.ErrorECM(errcm.Code, "queryTx queryAccTransInfos failed. error=(%s)", errcm)
 				}
@@ -1125,7 +1756,7 @@ func (b *BASE) __Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs, f
 			}
 		} else {
 			//非管理员账户，只能查询自己的交易记录，忽略txAcc参数
-			retValue, errcm := b.queryAccTransInfos(stub, accName, begSeq, txCount, begTime, endTime, queryMaxSeq, isAsc, appid)
+			retValue, errcm := b.queryAccTransInfos(stub, accName, begSeq, txCount, begTime, endTime, queryMaxSeq, isAsc, appid, assetId)
 			if errcm != nil {
 				return nil, baselogger.ErrorECM(errcm.Code, "queryTx queryAccTransInfos2 failed. error=(%s)", errcm)
 			}
@@ -1136,7 +1767,7 @@ func (b *BASE) __Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs, f
 
 	} else if function == "getAllAccAmt" { //所有账户中钱是否正确
 		//是否是管理员帐户，管理员用户才可以查
-		if !b.isAdmin(stub, accName) {
+		if !b.IsAdmin(stub, accName) {
 			return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "%s can't query balance.", accName)
 		}
 
@@ -1148,7 +1779,7 @@ func (b *BASE) __Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs, f
 
 	} else if function == "queryState" { //某个state的值
 		//是否是管理员帐户，管理员用户才可以查
-		if !b.isAdmin(stub, accName) {
+		if !b.IsAdmin(stub, accName) {
 			return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "%s can't query state.", accName)
 		}
 
@@ -1159,7 +1790,7 @@ func (b *BASE) __Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs, f
 
 		key := args[fixedArgCount]
 
-		retValue, err := stateCache.GetState_Ex(stub, key)
+		retValue, err := StateCache.GetState_Ex(stub, key)
 		if err != nil {
 			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "queryState GetState failed. error=(%s)", err)
 		}
@@ -1167,7 +1798,7 @@ func (b *BASE) __Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs, f
 		return (retValue), nil
 
 	} else if function == "isAccExists" { //账户是否存在
-		accExist, errcm := b.isAccEntityExists(stub, accName)
+		accExist, errcm := b.IsAccEntityExists(stub, accName)
 		if errcm != nil {
 			return nil, baselogger.ErrorECM(errcm.Code, "accExists: isEntityExists (id=%s) failed. error=(%s)", accName, errcm)
 		}
@@ -1182,7 +1813,7 @@ func (b *BASE) __Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs, f
 		return (retValue), nil
 
 	} else if function == "getDataState" {
-		if !b.isAdmin(stub, accName) {
+		if !b.IsAdmin(stub, accName) {
 			return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "getWorldState: %s can't query.", accName)
 		}
 
@@ -1215,7 +1846,7 @@ func (b *BASE) __Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs, f
 
 	} else if function == "getStatisticInfo" {
 		//是否是管理员帐户，管理员用户才可以查
-		if !b.isAdmin(stub, accName) {
+		if !b.IsAdmin(stub, accName) {
 			return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "%s can't query InfoForWeb.", accName)
 		}
 
@@ -1239,44 +1870,241 @@ func (b *BASE) __Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs, f
 			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "transPreCheck miss arg, got %d, need %d.", len(args), argCount)
 		}
 
-		toAcc := args[fixedArgCount]
-		//pwd := args[fixedArgCount+1]
-		transAmount, err := strconv.ParseInt(args[fixedArgCount+2], 0, 64)
+		toAcc := args[fixedArgCount]
+		//pwd := args[fixedArgCount+1]
+		transAmount, err := strconv.ParseInt(args[fixedArgCount+2], 0, 64)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "transPreCheck: convert transAmount(%s) failed. error=(%s)", args[fixedArgCount+2], err)
+		}
+
+		baselogger.Debug("transPreCheck: accountEnt=%+v", accountEnt)
+
+		//可选参数，转账的资产、应用id和交易类型
+		var assetId, appid, transType string
+		if len(args) > argCount {
+			assetId = args[argCount]
+		}
+		if len(args) > argCount+1 {
+			appid = args[argCount+1]
+		}
+		if len(args) > argCount+2 {
+			transType = args[argCount+2]
+		}
+
+		code, fee, errcm := b.transPreCheck(stub, accountEnt, toAcc, assetId, appid, transType, transAmount, queryTime)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "transPreCheck: check failed. error=(%s)", errcm)
+		}
+
+		//不传应用id时和以前一样只返回错误码；传了应用id时返回错误码和手续费
+		if len(appid) == 0 {
+			return []byte(strconv.FormatInt(int64(code), 10)), nil
+		}
+
+		retValue, err := json.Marshal(TransPreCheckResult{Code: code, Fee: fee})
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "transPreCheck: Marshal failed. error=(%s)", err)
+		}
+
+		return retValue, nil
+
+	} else if function == "getHistoryBalance" || function == "getStatement" { //查询某一时刻的余额，或者一段时间的对账单
+		var isStatement = function == "getStatement"
+
+		var argCount = fixedArgCount + 1
+		if isStatement {
+			argCount = fixedArgCount + 2
+		}
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "%s miss arg, got %d, need %d.", function, len(args), argCount)
+		}
+
+		begTime, err := strconv.ParseInt(args[fixedArgCount], 0, 64)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "%s: convert time(%s) failed. error=(%s)", function, args[fixedArgCount], err)
+		}
+		var endTime = begTime
+		if isStatement {
+			endTime, err = strconv.ParseInt(args[fixedArgCount+1], 0, 64)
+			if err != nil {
+				return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "%s: convert endTime(%s) failed. error=(%s)", function, args[fixedArgCount+1], err)
+			}
+			if endTime < begTime {
+				return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "%s: endTime(%d) before begTime(%d).", function, endTime, begTime)
+			}
+		}
+
+		//可选参数，查询的资产和账户。查询其它账户需要管理员权限
+		var assetId string
+		var queryAcc = accName
+		if len(args) > argCount {
+			assetId = args[argCount]
+		}
+		if len(args) > argCount+1 && len(args[argCount+1]) > 0 && args[argCount+1] != accName {
+			if !b.IsAdmin(stub, accName) {
+				return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "%s: %s can't query other account.", function, accName)
+			}
+			queryAcc = args[argCount+1]
+
+			exists, errcm := b.IsAccEntityExists(stub, queryAcc)
+			if errcm != nil {
+				return nil, baselogger.ErrorECM(errcm.Code, "%s: IsAccEntityExists failed. error=(%s)", function, errcm)
+			}
+			if !exists {
+				return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "%s: account(%s) not exists.", function, queryAcc)
+			}
+		}
+
+		if !isStatement {
+			balance, errcm := b.getHistoryBalance(stub, queryAcc, assetId, begTime)
+			if errcm != nil {
+				return nil, baselogger.ErrorECM(errcm.Code, "getHistoryBalance: getHistoryBalance failed. error=(%s)", errcm)
+			}
+			return []byte(strconv.FormatInt(balance, 10)), nil
+		}
+
+		st, errcm := b.getAccountStatement(stub, queryAcc, assetId, begTime, endTime)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "getStatement: getAccountStatement failed. error=(%s)", errcm)
+		}
+
+		retValue, err := json.Marshal(st)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getStatement: Marshal failed. error=(%s)", err)
+		}
+
+		return retValue, nil
+
+	} else if function == "getSupplyInfo" { //查询发行上限、已发行和销毁的数量，以及发行上限的修改记录
+		if !b.IsAdmin(stub, accName) {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "%s can't query supply info.", accName)
+		}
+
+		si, errcm := b.getSupplyInfo(stub)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "getSupplyInfo: getSupplyInfo failed. error=(%s)", errcm)
+		}
+
+		retValue, err := json.Marshal(si)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getSupplyInfo: Marshal failed. error=(%s)", err)
+		}
+
+		return retValue, nil
+
+	} else if function == "getInvoice" { //查询账单，付款方支付前需要查询，所以不限制查询的账户
+		//args: payee, invoiceId
+		var argCount = fixedArgCount + 2
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getInvoice miss arg, got %d, need %d.", len(args), argCount)
+		}
+
+		inv, errcm := b.getInvoice(stub, args[fixedArgCount], args[fixedArgCount+1])
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "getInvoice: getInvoice failed. error=(%s)", errcm)
+		}
+		if inv == nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getInvoice: invoice(%s,%s) not exists.", args[fixedArgCount], args[fixedArgCount+1])
+		}
+		inv.Status = inv.currentStatus(queryTime)
+
+		retValue, err := json.Marshal(inv)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getInvoice: Marshal failed. error=(%s)", err)
+		}
+
+		return retValue, nil
+
+	} else if function == "getInvoicesByPayee" { //查询收款方的账单
+		//可选参数，账单状态（为空时查询所有状态）和收款账户。查询其它账户需要管理员权限
+		var status string
+		var payee = accName
+		if len(args) > fixedArgCount {
+			status = args[fixedArgCount]
+		}
+		if len(args) > fixedArgCount+1 && len(args[fixedArgCount+1]) > 0 && args[fixedArgCount+1] != accName {
+			if !b.IsAdmin(stub, accName) {
+				return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "getInvoicesByPayee: %s can't query other account.", accName)
+			}
+			payee = args[fixedArgCount+1]
+		}
+
+		invList, errcm := b.queryPayeeInvoices(stub, payee, status, queryTime)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "getInvoicesByPayee: queryPayeeInvoices failed. error=(%s)", errcm)
+		}
+
+		retValue, err := json.Marshal(invList)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getInvoicesByPayee: Marshal failed. error=(%s)", err)
+		}
+
+		return retValue, nil
+
+	} else if function == "getHtlc" { //查询哈希时间锁，另一条链上的对手方需要查询领取时出示的原像，所以不限制查询的账户
+		var argCount = fixedArgCount + 1
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getHtlc miss arg, got %d, need %d.", len(args), argCount)
+		}
+
+		lock, errcm := b.getHtlc(stub, args[fixedArgCount])
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "getHtlc: getHtlc failed. error=(%s)", errcm)
+		}
+		if lock == nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getHtlc: lock(%s) not exists.", args[fixedArgCount])
+		}
+
+		retValue, err := json.Marshal(lock)
 		if err != nil {
-			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "transPreCheck: convert transAmount(%s) failed. error=(%s)", args[fixedArgCount+2], err)
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getHtlc: Marshal failed. error=(%s)", err)
 		}
 
-		baselogger.Debug("transPreCheck: accountEnt=%+v", accountEnt)
+		return retValue, nil
 
-		//余额是否足够
-		if transAmount < 0 { //如果是内部接口调用，可能会转账金额为0， 这里不检查0
-			return []byte(strconv.FormatInt(ERRCODE_TRANS_AMOUNT_INVALID, 10)), nil
+	} else if function == "getRefundInfo" { //查询交易的退款信息，原交易的付款方、收款方和管理员可以查询
+		var argCount = fixedArgCount + 1
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getRefundInfo miss arg, got %d, need %d.", len(args), argCount)
+		}
+
+		orig, errcm := b.getRefundableTrans(stub, args[fixedArgCount])
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "getRefundInfo: getRefundableTrans failed. error=(%s)", errcm)
+		}
+		if accName != orig.FromID && accName != orig.ToID && !b.IsAdmin(stub, accName) {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "getRefundInfo: %s can't query trans(%d).", accName, orig.GlobalSerial)
 		}
-		//看是否有锁定金额
-		lockAmt, _, errcm := Base.getAccountLockedAmount(stub, accName, queryTime)
+
+		ri, errcm := b.getRefundInfo(stub, orig)
 		if errcm != nil {
-			return nil, baselogger.ErrorECM(errcm.Code, "transPreCheck: getAccountLockedAmount(id=%s) failed. error=(%s)", accName, errcm)
+			return nil, baselogger.ErrorECM(errcm.Code, "getRefundInfo: getRefundInfo failed. error=(%s)", errcm)
 		}
 
-		if transAmount > accountEnt.RestAmount {
-			return []byte(strconv.FormatInt(ERRCODE_TRANS_BALANCE_NOT_ENOUGH, 10)), nil
+		retValue, err := json.Marshal(ri)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getRefundInfo: Marshal failed. error=(%s)", err)
 		}
-		//错误码丰富一点，这里再判断是否是因为锁定导致余额不足
-		if lockAmt > 0 && transAmount > accountEnt.RestAmount-lockAmt {
-			return []byte(strconv.FormatInt(ERRCODE_TRANS_BALANCE_NOT_ENOUGH_BYLOCK, 10)), nil
+
+		return retValue, nil
+
+	} else if function == "getFeeSchedule" {
+		var argCount = fixedArgCount + 1
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getFeeSchedule miss arg, got %d, need %d.", len(args), argCount)
 		}
 
-		//收款账户是否存在  这个检查放到最后执行
-		exists, errcm := Base.isAccEntityExists(stub, toAcc)
+		fs, errcm := b.getFeeSchedule(stub, args[fixedArgCount])
 		if errcm != nil {
-			return nil, baselogger.ErrorECM(errcm.Code, "transPreCheck: isEntityExists(%s) failed. error=(%s)", toAcc, errcm)
+			return nil, baselogger.ErrorECM(errcm.Code, "getFeeSchedule: getFeeSchedule failed. error=(%s)", errcm)
 		}
-		if !exists {
-			return []byte(strconv.FormatInt(ERRCODE_TRANS_PAYEE_ACCOUNT_NOT_EXIST, 10)), nil
+
+		retValue, err := json.Marshal(fs)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getFeeSchedule: Marshal failed. error=(%s)", err)
 		}
 
-		//通过返回0，表示检查通过
-		return []byte(strconv.FormatInt(0, 10)), nil
+		return retValue, nil
 
 	} else if function == "getAppInfo" {
 
@@ -1306,6 +2134,72 @@ func (b *BASE) __Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs, f
 
 		return returnValue, nil
 
+	} else if function == "getAssetInfo" {
+		var assetList []AssetInfo = []AssetInfo{}
+
+		//assetId为空，则查询所有的
+		var assetId string
+		if len(args) > fixedArgCount {
+			assetId = args[fixedArgCount]
+		}
+
+		if len(assetId) != 0 {
+			ai, errcm := b.GetAssetInfo(stub, assetId)
+			if errcm != nil {
+				return nil, baselogger.ErrorECM(errcm.Code, "getAssetInfo: getAssetInfo(%s) failed. error=(%s)", assetId, errcm)
+			}
+
+			assetList = append(assetList, *ai)
+		} else {
+			assetList, errcm = b.getAllAssetInfo(stub)
+			if errcm != nil {
+				return nil, baselogger.ErrorECM(errcm.Code, "getAssetInfo: getAllAssetInfo failed. error=(%s)", errcm)
+			}
+		}
+
+		returnValue, err := json.Marshal(assetList)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getAssetInfo: Marshal failed. error=(%s)", err)
+		}
+
+		return returnValue, nil
+
+	} else if function == "getAllowance" {
+		//可选参数，"owner"查询本账户授权出去的额度，"spender"查询本账户被授权的额度，默认为"owner"
+		var role = "owner"
+		if len(args) > fixedArgCount {
+			role = args[fixedArgCount]
+		}
+		if role != "owner" && role != "spender" {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getAllowance: role must be 'owner' or 'spender'.")
+		}
+
+		allowList, errcm := b.queryAllowances(stub, accName, role == "spender", queryTime)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "getAllowance: queryAllowances failed. error=(%s)", errcm)
+		}
+
+		returnValue, err := json.Marshal(allowList)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getAllowance: Marshal failed. error=(%s)", err)
+		}
+
+		return returnValue, nil
+
+	} else if function == "getPubKey" { //查询账户登记的公钥，加密备注时使用
+		var argCount = fixedArgCount + 1
+		if len(args) < argCount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getPubKey miss arg, got %d, need %d.", len(args), argCount)
+		}
+
+		var queryAcc = args[fixedArgCount]
+		queryEnt, errcm := b.GetAccountEntity(stub, queryAcc)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "getPubKey: getAccountEntity(%s) failed. error=(%s)", queryAcc, errcm)
+		}
+
+		return []byte(queryEnt.OwnerPubKey), nil
+
 	} else if function == "getRankingAndTopN" {
 
 		var argCount = fixedArgCount + 3
@@ -1360,7 +2254,54 @@ func (b *BASE) __Query(stub shim.ChaincodeStubInterface, ifas *BaseInvokeArgs, f
 	}
 }
 
-func (b *BASE) queryTransInfos(stub shim.ChaincodeStubInterface, transLvl uint64, begIdx, count, begTime, endTime, queryMaxSeq int64, isAsc bool, appid string) ([]byte, *ErrorCodeMsg) {
+//转账预检查，返回错误码(0表示通过)和需要另付的手续费
+func (b *BASE) transPreCheck(stub shim.ChaincodeStubInterface, accountEnt *AccountEntity, toAcc, assetId, appid, transType string, transAmount, queryTime int64) (int32, int64, *ErrorCodeMsg) {
+	errcm := b.checkAssetExists(stub, assetId)
+	if errcm != nil {
+		return errcm.Code, 0, nil
+	}
+
+	//余额是否足够
+	if transAmount < 0 { //如果是内部接口调用，可能会转账金额为0， 这里不检查0
+		return ERRCODE_TRANS_AMOUNT_INVALID, 0, nil
+	}
+
+	var fee int64
+	if accountEnt.EntID != toAcc && transAmount > 0 {
+		fee, _, errcm = b.calcTransferFee(stub, appid, transType, assetId, accountEnt.EntID, transAmount)
+		if errcm != nil {
+			return 0, 0, baselogger.ErrorECM(errcm.Code, "transPreCheck: calcTransferFee failed. error=(%s)", errcm)
+		}
+	}
+
+	//看是否有锁定金额
+	lockAmt, _, errcm := b.getAccountLockedAmount(stub, accountEnt.EntID, assetId, queryTime)
+	if errcm != nil {
+		return 0, 0, baselogger.ErrorECM(errcm.Code, "transPreCheck: getAccountLockedAmount(id=%s) failed. error=(%s)", accountEnt.EntID, errcm)
+	}
+
+	_, restAmount := accountEnt.GetAssetAmount(assetId)
+	if transAmount+fee > restAmount {
+		return ERRCODE_TRANS_BALANCE_NOT_ENOUGH, fee, nil
+	}
+	//错误码丰富一点，这里再判断是否是因为锁定导致余额不足
+	if lockAmt > 0 && transAmount+fee > restAmount-lockAmt {
+		return ERRCODE_TRANS_BALANCE_NOT_ENOUGH_BYLOCK, fee, nil
+	}
+
+	//收款账户是否存在  这个检查放到最后执行
+	exists, errcm := b.IsAccEntityExists(stub, toAcc)
+	if errcm != nil {
+		return 0, 0, baselogger.ErrorECM(errcm.Code, "transPreCheck: isEntityExists(%s) failed. error=(%s)", toAcc, errcm)
+	}
+	if !exists {
+		return ERRCODE_TRANS_PAYEE_ACCOUNT_NOT_EXIST, fee, nil
+	}
+
+	return 0, fee, nil
+}
+
+func (b *BASE) queryTransInfos(stub shim.ChaincodeStubInterface, transLvl uint64, begIdx, count, begTime, endTime, queryMaxSeq int64, isAsc bool, appid, assetId string) ([]byte, *ErrorCodeMsg) {
 	var maxSeq int64
 	var err error
 	var errcm *ErrorCodeMsg
@@ -1392,7 +2333,7 @@ func (b *BASE) queryTransInfos(stub shim.ChaincodeStubInterface, transLvl uint64
 
 	//先判断是否存在交易序列号了，如果不存在，说明还没有交易发生。 这里做这个判断是因为在 getTransSeq 里如果没有设置过序列号的key会自动设置一次，但是在query中无法执行PutStat，会报错
 	var seqKey = b.getGlobalTransSeqKey(stub)
-	test, err := stateCache.GetState_Ex(stub, seqKey)
+	test, err := StateCache.GetState_Ex(stub, seqKey)
 	if err != nil {
 		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "queryTransInfos GetState failed. error=(%s)", err)
 	}
@@ -1405,7 +2346,7 @@ func (b *BASE) queryTransInfos(stub shim.ChaincodeStubInterface, transLvl uint64
 	if queryMaxSeq != -1 {
 		maxSeq = queryMaxSeq
 	} else {
-		maxSeq, errcm = b.getTransSeq(stub, seqKey)
+		maxSeq, errcm = b.GetTransSeq(stub, seqKey)
 		if errcm != nil {
 			return nil, baselogger.ErrorECM(errcm.Code, "queryTransInfos getTransSeq failed. error=(%s)", errcm)
 		}
@@ -1429,7 +2370,7 @@ func (b *BASE) queryTransInfos(stub shim.ChaincodeStubInterface, transLvl uint64
 		   for i := begIdx; i <= endIdx; i++ {
 		       key, _ := b.getTransInfoKey(stub, i)
 
-		       tmpState, err := stateCache.GetState_Ex(stub,key)
+		       tmpState, err := StateCache.GetState_Ex(stub,key)
 		       if err != nil {
 		           base_logger.Error("getTransInfo GetState(idx=%d) failed.error=(%s)", i, err)
 		           //return nil, err
@@ -1463,6 +2404,9 @@ func (b *BASE) queryTransInfos(stub shim.ChaincodeStubInterface, transLvl uint64
 				if len(appid) > 0 && appid != trans.AppID {
 					continue
 				}
+				if len(assetId) > 0 && !b.isSameAsset(assetId, trans.AssetID) {
+					continue
+				}
 				qTrans.Serial = trans.GlobalSerial
 				qTrans.PubTrans = trans.PubTrans
 				queryResult.TransRecords = append(queryResult.TransRecords, qTrans)
@@ -1489,6 +2433,9 @@ func (b *BASE) queryTransInfos(stub shim.ChaincodeStubInterface, transLvl uint64
 				if len(appid) > 0 && appid != trans.AppID {
 					continue
 				}
+				if len(assetId) > 0 && !b.isSameAsset(assetId, trans.AssetID) {
+					continue
+				}
 				qTrans.Serial = maxSeq - trans.GlobalSerial + 1
 				qTrans.PubTrans = trans.PubTrans
 				queryResult.TransRecords = append(queryResult.TransRecords, qTrans)
@@ -1507,7 +2454,7 @@ func (b *BASE) queryTransInfos(stub shim.ChaincodeStubInterface, transLvl uint64
 	return retTransInfo, nil
 }
 
-func (b *BASE) queryAccTransInfos(stub shim.ChaincodeStubInterface, accName string, begIdx, count, begTime, endTime, queryMaxSeq int64, isAsc bool, appid string) ([]byte, *ErrorCodeMsg) {
+func (b *BASE) queryAccTransInfos(stub shim.ChaincodeStubInterface, accName string, begIdx, count, begTime, endTime, queryMaxSeq int64, isAsc bool, appid, assetId string) ([]byte, *ErrorCodeMsg) {
 	var maxSeq int64
 	var err error
 	var errcm *ErrorCodeMsg
@@ -1539,7 +2486,7 @@ func (b *BASE) queryAccTransInfos(stub shim.ChaincodeStubInterface, accName stri
 
 	//先判断是否存在交易序列号了，如果不存在，说明还没有交易发生。 这里做这个判断是因为在 getTransSeq 里如果没有设置过序列号的key会自动设置一次，但是在query中无法执行PutStat，会报错
 	var seqKey = b.getAccTransSeqKey(accName)
-	test, err := stateCache.GetState_Ex(stub, seqKey)
+	test, err := StateCache.GetState_Ex(stub, seqKey)
 	if err != nil {
 		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "queryAccTransInfos GetState failed. error=(%s)", err)
 	}
@@ -1552,7 +2499,7 @@ func (b *BASE) queryAccTransInfos(stub shim.ChaincodeStubInterface, accName stri
 	if queryMaxSeq != -1 {
 		maxSeq = queryMaxSeq
 	} else {
-		maxSeq, errcm = b.getTransSeq(stub, seqKey)
+		maxSeq, errcm = b.GetTransSeq(stub, seqKey)
 		if errcm != nil {
 			return nil, baselogger.ErrorECM(errcm.Code, "queryAccTransInfos getTransSeq failed. error=(%s)", errcm)
 		}
@@ -1567,7 +2514,7 @@ func (b *BASE) queryAccTransInfos(stub shim.ChaincodeStubInterface, accName stri
 		count = maxSeq - begIdx + 1
 	}
 	/*
-	   infoB, err := stateCache.GetState_Ex(stub,t.getOneAccTransKey(accName))
+	   infoB, err := StateCache.GetState_Ex(stub,t.getOneAccTransKey(accName))
 	   if err != nil {
 	       return nil, base_logger.Errorf("queryAccTransInfos(%s) GetState failed.error=(%s)", accName, err)
 	   }
@@ -1626,7 +2573,7 @@ func (b *BASE) queryAccTransInfos(stub shim.ChaincodeStubInterface, accName stri
 				break
 			}
 
-			globTxKeyB, err = stateCache.GetState_Ex(stub, b.getOneAccTransInfoKey(accName, loop))
+			globTxKeyB, err = StateCache.GetState_Ex(stub, b.getOneAccTransInfoKey(accName, loop))
 			if err != nil {
 				baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "queryAccTransInfos GetState(globTxKeyB,acc=%s,idx=%d) failed.error=(%s)", accName, loop, err)
 				continue
@@ -1649,6 +2596,9 @@ func (b *BASE) queryAccTransInfos(stub shim.ChaincodeStubInterface, accName stri
 				if len(appid) > 0 && appid != trans.AppID {
 					continue
 				}
+				if len(assetId) > 0 && !b.isSameAsset(assetId, trans.AssetID) {
+					continue
+				}
 				qTrans.Serial = loop
 				qTrans.PubTrans = trans.PubTrans
 				queryResult.TransRecords = append(queryResult.TransRecords, qTrans)
@@ -1664,7 +2614,7 @@ func (b *BASE) queryAccTransInfos(stub shim.ChaincodeStubInterface, accName stri
 				break
 			}
 
-			globTxKeyB, err = stateCache.GetState_Ex(stub, b.getOneAccTransInfoKey(accName, loop))
+			globTxKeyB, err = StateCache.GetState_Ex(stub, b.getOneAccTransInfoKey(accName, loop))
 			if err != nil {
 				baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "queryAccTransInfos GetState(globTxKeyB,acc=%s,idx=%d) failed.error=(%s)", accName, loop, err)
 				continue
@@ -1687,6 +2637,9 @@ func (b *BASE) queryAccTransInfos(stub shim.ChaincodeStubInterface, accName stri
 				if len(appid) > 0 && appid != trans.AppID {
 					continue
 				}
+				if len(assetId) > 0 && !b.isSameAsset(assetId, trans.AssetID) {
+					continue
+				}
 				qTrans.Serial = maxSeq - loop + 1
 				qTrans.PubTrans = trans.PubTrans
 				queryResult.TransRecords = append(queryResult.TransRecords, qTrans)
@@ -1710,25 +2663,33 @@ func (b *BASE) getAllAccAmt(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCod
 	qb.AccSumAmount = 0
 	qb.AccCount = 0
 
-	accsB, err := stateCache.GetState_Ex(stub, ALL_ACC_INFO_KEY)
+	accsB, err := StateCache.GetState_Ex(stub, ALL_ACC_INFO_KEY)
 	if err != nil {
 		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getAllAccAmt GetState failed. error=(%s)", err)
 	}
 	if accsB != nil {
-		cbAccB, errcm := b.getCenterBankAcc(stub)
+		cbAccB, errcm := b.GetCenterBankAcc(stub)
 		if errcm != nil {
 			return nil, baselogger.ErrorECM(errcm.Code, "getAllAccAmt getCenterBankAcc failed. error=(%s)", errcm)
 		}
 		if cbAccB == nil {
 			qb.Message += "none centerBank;"
 		} else {
-			cbEnt, errcm := b.getAccountEntity(stub, string(cbAccB))
+			cbEnt, errcm := b.GetAccountEntity(stub, string(cbAccB))
 			if errcm != nil {
 				return nil, baselogger.ErrorECM(errcm.Code, "getAllAccAmt getCenterBankAcc failed. error=(%s)", errcm)
 			}
 			qb.IssueAmount = cbEnt.TotalAmount - cbEnt.RestAmount
 		}
 
+		//销毁的货币不在任何账户中，从央行发出的数量中减去
+		si, errcm := b.getSupplyInfo(stub)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "getAllAccAmt getSupplyInfo failed. error=(%s)", errcm)
+		}
+		qb.BurnedAmount = si.BurnedAmount
+		qb.IssueAmount -= si.BurnedAmount
+
 		var allAccs = bytes.NewBuffer(accsB)
 		var acc []byte
 		var ent *AccountEntity
@@ -1745,9 +2706,9 @@ func (b *BASE) getAllAccAmt(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCod
 			qb.AccCount++
 			acc = acc[:len(acc)-1] //去掉末尾的分隔符
 
-			ent, errcm = b.getAccountEntity(stub, string(acc))
+			ent, errcm = b.GetAccountEntity(stub, string(acc))
 			if errcm != nil {
-				baselogger.Error("getAllAccAmt getAccountEntity(%s) failed. error=(%s)", string(acc), errcm)
+				baselogger.Error("getAllAccAmt GetAccountEntity(%s) failed. error=(%s)", string(acc), errcm)
 				qb.Message += fmt.Sprintf("get account(%s) info failed;", string(acc))
 				continue
 			}
@@ -1770,6 +2731,7 @@ func (b *BASE) getSysStatisticInfo(stub shim.ChaincodeStubInterface, circulateAm
 		IssueTotalAmount int64 `json:"issuetotalamt"` //预计发行总量
 		IssueAmount      int64 `json:"issueamt"`      //已发行数量
 		CirculateAmount  int64 `json:"circulateamt"`  //流通数量
+		BurnedAmount     int64 `json:"burnedamt"`     //累计销毁数量
 	}
 
 	var qwi SysStatisticInfo
@@ -1778,15 +2740,21 @@ func (b *BASE) getSysStatisticInfo(stub shim.ChaincodeStubInterface, circulateAm
 	qwi.IssueAmount = 0
 	qwi.CirculateAmount = 0
 
-	issueEntity, errcm := b.getAccountEntity(stub, COIN_ISSUE_ACC_ENTID)
+	issueEntity, errcm := b.GetAccountEntity(stub, COIN_ISSUE_ACC_ENTID)
 	if errcm != nil {
 		return nil, baselogger.ErrorECM(errcm.Code, "getInfo4Web: getIssueEntity failed. error=(%s)", errcm)
 	}
 	qwi.IssueTotalAmount = issueEntity.TotalAmount
-	qwi.IssueAmount = issueEntity.TotalAmount - issueEntity.RestAmount
+	qwi.IssueAmount = issueEntity.TotalAmount - issueEntity.RestAmount //销毁的货币已回到虚拟账户，不计入已发行数量
+
+	si, errcm := b.getSupplyInfo(stub)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "getInfo4Web: getSupplyInfo failed. error=(%s)", errcm)
+	}
+	qwi.BurnedAmount = si.BurnedAmount
 
 	var asi AccountStatisticInfo
-	asiB, err := stateCache.GetState_Ex(stub, ACC_STATIC_INFO_KEY)
+	asiB, err := StateCache.GetState_Ex(stub, ACC_STATIC_INFO_KEY)
 	if err != nil {
 		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getInfo4Web: GetState failed. error=(%s)", err)
 	}
@@ -1800,22 +2768,23 @@ func (b *BASE) getSysStatisticInfo(stub shim.ChaincodeStubInterface, circulateAm
 
 	//如果传入了计算流通货币量的账户，用此账户；否则用央行账户
 	if len(circulateAmtAccName) > 0 {
-		ent, errcm := b.getAccountEntity(stub, circulateAmtAccName)
+		ent, errcm := b.GetAccountEntity(stub, circulateAmtAccName)
 		if errcm != nil {
 			return nil, baselogger.ErrorECM(errcm.Code, "getInfo4Web: getAccountEntity failed. error=(%s)", errcm)
 		}
 		qwi.CirculateAmount = ent.TotalAmount - ent.RestAmount
 	} else {
-		cbAccB, errcm := b.getCenterBankAcc(stub)
+		cbAccB, errcm := b.GetCenterBankAcc(stub)
 		if errcm != nil {
 			return nil, baselogger.ErrorECM(errcm.Code, "getInfo4Web: getCenterBankAcc failed. error=(%s)", errcm)
 		}
 		if cbAccB != nil {
-			cbEnt, errcm := b.getAccountEntity(stub, string(cbAccB))
+			cbEnt, errcm := b.GetAccountEntity(stub, string(cbAccB))
 			if errcm != nil {
 				return nil, baselogger.ErrorECM(errcm.Code, "getInfo4Web getAccountEntity failed. error=(%s)", errcm)
 			}
-			qwi.CirculateAmount = cbEnt.TotalAmount - cbEnt.RestAmount
+			//央行发出的货币中被销毁的部分不再流通
+			qwi.CirculateAmount = cbEnt.TotalAmount - cbEnt.RestAmount - si.BurnedAmount
 		}
 	}
 
@@ -1827,29 +2796,110 @@ func (b *BASE) getSysStatisticInfo(stub shim.ChaincodeStubInterface, circulateAm
 	return retValue, nil
 }
 
-func (b *BASE) needCheckSign(stub shim.ChaincodeStubInterface) bool {
-	/*
-		//默认返回true，除非读取到指定参数
+//控制参数的缓存。每个交易只调用一次控制合约，交易结束时删除
+type CtrlParameterCache struct {
+	paraCache map[string]map[string]string
+	lock      sync.RWMutex //这里的map类似于全局变量，访问需要加锁
+}
+
+func (t *CtrlParameterCache) Create(stub shim.ChaincodeStubInterface) {
+	t.lock.Lock()
+
+	if t.paraCache == nil {
+		t.paraCache = make(map[string]map[string]string)
+	}
+	t.paraCache[stub.GetTxID()] = make(map[string]string)
+
+	t.lock.Unlock()
+}
+
+func (t *CtrlParameterCache) Destroy(stub shim.ChaincodeStubInterface) {
+	t.lock.Lock()
+	delete(t.paraCache, stub.GetTxID())
+	t.lock.Unlock()
+}
+
+func (t *CtrlParameterCache) Get(stub shim.ChaincodeStubInterface, name string) (string, bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	value, ok := t.paraCache[stub.GetTxID()][name]
+	return value, ok
+}
+
+func (t *CtrlParameterCache) Set(stub shim.ChaincodeStubInterface, name, value string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	//没有Create的交易不缓存
+	if cache, ok := t.paraCache[stub.GetTxID()]; ok {
+		cache[name] = value
+	}
+}
 
-		var args = util.ToChaincodeArgs(CONTROL_CC_GETPARA_FUNC_NAME, "checkSiagnature")
+//从控制合约读取参数的原始字符串。控制合约未部署或者没有该参数时，使用defValue
+func (b *BASE) getCtrlParameter(stub shim.ChaincodeStubInterface, name string, defValue string) string {
+	paraValue, ok := ctrlParaCache.Get(stub, name)
+	if !ok {
+		var args = util.ToChaincodeArgs(CONTROL_CC_GETPARA_FUNC_NAME, name)
 
 		response := stub.InvokeChaincode(CONTROL_CC_NAME, args, "")
 		if response.Status != shim.OK {
-			baselogger.ErrorECM(ERRCODE_COMMON_INNER_ERROR,"needCheckSign: InvokeChaincode failed, response=%+v.", response)
-			return true
+			baselogger.Warn("getCtrlParameter: get '%s' from %s failed, use default value %v. response=%+v.", name, CONTROL_CC_NAME, defValue, response)
+			paraValue = defValue
+		} else {
+			paraValue = string(response.Payload)
 		}
 
-		paraValue := string(response.Payload)
-		if paraValue == "0" {
-			return false
-		}
+		ctrlParaCache.Set(stub, name, paraValue)
+	}
 
-		return true
-	*/
-	return Ctrl_needCheckSign
+	return paraValue
+}
+
+//从控制合约读取bool类型的参数。控制合约未部署或者没有该参数时，使用control.go中的默认值
+func (b *BASE) getCtrlBoolParameter(stub shim.ChaincodeStubInterface, name string, defValue bool) bool {
+	paraValue := b.getCtrlParameter(stub, name, strconv.FormatBool(defValue))
+
+	value, err := strconv.ParseBool(paraValue)
+	if err != nil {
+		baselogger.Warn("getCtrlBoolParameter: '%s' value '%s' is not bool, use default value %v.", name, paraValue, defValue)
+		return defValue
+	}
+
+	return value
+}
+
+//从控制合约读取整数类型的参数，同getCtrlBoolParameter
+func (b *BASE) getCtrlIntParameter(stub shim.ChaincodeStubInterface, name string, defValue int64) int64 {
+	paraValue := b.getCtrlParameter(stub, name, strconv.FormatInt(defValue, 10))
+
+	value, err := strconv.ParseInt(paraValue, 0, 64)
+	if err != nil {
+		baselogger.Warn("getCtrlIntParameter: '%s' value '%s' is not int, use default value %v.", name, paraValue, defValue)
+		return defValue
+	}
+
+	return value
 }
 
-var secp256k1 = NewSecp256k1()
+func (b *BASE) isTestChain(stub shim.ChaincodeStubInterface) bool {
+	return b.getCtrlBoolParameter(stub, CTRL_PARA_IS_TEST_CHAIN, Ctrl_isTestChain)
+}
+
+func (b *BASE) needCheckSign(stub shim.ChaincodeStubInterface) bool {
+	return b.getCtrlBoolParameter(stub, CTRL_PARA_NEED_CHECK_SIGN, Ctrl_needCheckSign)
+}
+
+func (b *BASE) needCheckIndentity(stub shim.ChaincodeStubInterface) bool {
+	return b.getCtrlBoolParameter(stub, CTRL_PARA_NEED_CHECK_IDENTITY, Ctrl_needCheckIndentity)
+}
+
+func (b *BASE) balanceCkptInterval(stub shim.ChaincodeStubInterface) int64 {
+	return b.getCtrlIntParameter(stub, CTRL_PARA_BALANCE_CKPT_INTERVAL, Ctrl_balanceCkptInterval)
+}
+
+var secp256k1 = llcrypto.NewSecp256k1()
 
 func (b *BASE) verifySign(stub shim.ChaincodeStubInterface, ownerPubKeyHash string, sign, signMsg []byte) *ErrorCodeMsg {
 
@@ -1877,7 +2927,7 @@ func (b *BASE) verifySign(stub shim.ChaincodeStubInterface, ownerPubKeyHash stri
 	}
 	baselogger.Debug("verifySign: pubKey = %v", pubKey)
 
-	hash, err := RipemdHash160(pubKey)
+	hash, err := llcrypto.RipemdHash160(pubKey)
 	if err != nil {
 		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "verifySign: Hash160 error, error=(%s).", err)
 	}
@@ -1892,7 +2942,7 @@ func (b *BASE) verifySign(stub shim.ChaincodeStubInterface, ownerPubKeyHash stri
 	return nil
 }
 
-func (b *BASE) verifyIdentity(stub shim.ChaincodeStubInterface, userName string, sign, signMsg []byte, accountEnt *AccountEntity, ownerPubKeyHash, ownerIdentityHash string) *ErrorCodeMsg {
+func (b *BASE) verifyIdentity(stub shim.ChaincodeStubInterface, userName string, sign, signMsg []byte, signIsV1 bool, accountEnt *AccountEntity, ownerPubKeyHash, ownerIdentityHash string) *ErrorCodeMsg {
 
 	var comparedPubKeyHash = ownerPubKeyHash
 	var comparedIndentityHash = ownerIdentityHash
@@ -1904,7 +2954,7 @@ func (b *BASE) verifyIdentity(stub shim.ChaincodeStubInterface, userName string,
 		comparedIndentityHash = accountEnt.OwnerIdentityHash
 	}
 
-	if Ctrl_needCheckIndentity {
+	if b.needCheckIndentity(stub) {
 		if accountEnt != nil && accountEnt.Owner != userName {
 			if _, ok := accountEnt.AuthUserHashMap[userName]; !ok {
 				return baselogger.ErrorECM(ERRCODE_COMMON_IDENTITY_VERIFY_FAILED, "verifyIdentity: username not match, user=%s", userName)
@@ -1951,7 +3001,7 @@ func (b *BASE) verifyIdentity(stub shim.ChaincodeStubInterface, userName string,
 		}
 
 		var userId = string(certText)
-		hash, err := RipemdHash160(certText)
+		hash, err := llcrypto.RipemdHash160(certText)
 		if err != nil {
 			return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "verifyIdentity: Hash160 error, user=%s error=(%s).", userName, err)
 		}
@@ -1966,23 +3016,32 @@ func (b *BASE) verifyIdentity(stub shim.ChaincodeStubInterface, userName string,
 		}
 	}
 
+	//账户要求v1格式签名时，旧格式的签名一律拒绝，防止降级
+	if accountEnt != nil && accountEnt.SignScheme == SIGN_SCHEME_CANONICAL_V1 && !signIsV1 && b.needCheckSign(stub) {
+		return baselogger.ErrorECM(ERRCODE_COMMON_IDENTITY_VERIFY_FAILED, "verifyIdentity: account %s requires v1 sign.", accountEnt.EntID)
+	}
+
 	return b.verifySign(stub, comparedPubKeyHash, sign, signMsg)
 }
 
-func (b *BASE) getAccountEntityKey(accName string) string {
+func (b *BASE) GetAccountEntityKey(accName string) string {
 	return ACC_ENTITY_PREFIX + accName
 }
 
-func (b *BASE) getAccountLockInfoKey(accName string) string {
-	return ACC_AMTLOCK_PREFIX + accName
+//默认资产的key保持不变，其它资产在账户名后加上资产id，用账户名中不能出现的':'分隔
+func (b *BASE) getAccountLockInfoKey(accName, assetId string) string {
+	if IsDefaultAsset(assetId) {
+		return ACC_AMTLOCK_PREFIX + accName
+	}
+	return ACC_AMTLOCK_PREFIX + accName + ":" + assetId
 }
 
-func (b *BASE) getAccountEntity(stub shim.ChaincodeStubInterface, entName string) (*AccountEntity, *ErrorCodeMsg) {
+func (b *BASE) GetAccountEntity(stub shim.ChaincodeStubInterface, entName string) (*AccountEntity, *ErrorCodeMsg) {
 	var entB []byte
 	var cb AccountEntity
 	var err error
 
-	entB, err = stateCache.GetState_Ex(stub, b.getAccountEntityKey(entName))
+	entB, err = StateCache.GetState_Ex(stub, b.GetAccountEntityKey(entName))
 	if err != nil {
 		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getAccountEntity: GetState_Ex failed, error=(%s).", err)
 	}
@@ -1998,11 +3057,16 @@ func (b *BASE) getAccountEntity(stub shim.ChaincodeStubInterface, entName string
 	return &cb, nil
 }
 
-func (b *BASE) getAccountLockedAmount(stub shim.ChaincodeStubInterface, accName string, currTime int64) (int64, []CoinLockCfg, *ErrorCodeMsg) {
+//查询账户默认资产的锁定金额
+func (b *BASE) GetAccountLockedAmount(stub shim.ChaincodeStubInterface, accName string, currTime int64) (int64, []CoinLockCfg, *ErrorCodeMsg) {
+	return b.getAccountLockedAmount(stub, accName, DEFAULT_ASSET_ID, currTime)
+}
+
+func (b *BASE) getAccountLockedAmount(stub shim.ChaincodeStubInterface, accName, assetId string, currTime int64) (int64, []CoinLockCfg, *ErrorCodeMsg) {
 	var acli AccountCoinLockInfo
 
-	var lockinfoKey = b.getAccountLockInfoKey(accName)
-	acliB, err := stateCache.GetState_Ex(stub, lockinfoKey)
+	var lockinfoKey = b.getAccountLockInfoKey(accName, assetId)
+	acliB, err := StateCache.GetState_Ex(stub, lockinfoKey)
 	if err != nil {
 		return math.MaxInt64, nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getAccountLockedAmount: GetState  failed. error=(%s)", err)
 	}
@@ -2024,17 +3088,24 @@ func (b *BASE) getAccountLockedAmount(stub shim.ChaincodeStubInterface, accName
 		}
 	}
 
-	baselogger.Debug("getAccountLockedAmount: amount is %d for %s", lockAmt, accName)
+	//哈希时间锁锁定的金额，见htlc.go
+	htlcAmt, errcm := b.getHtlcLockedAmount(stub, accName, assetId)
+	if errcm != nil {
+		return math.MaxInt64, nil, baselogger.ErrorECM(errcm.Code, "getAccountLockedAmount: getHtlcLockedAmount failed. error=(%s)", errcm)
+	}
+	lockAmt += htlcAmt
+
+	baselogger.Debug("getAccountLockedAmount: amount is %d for %s(%s)", lockAmt, accName, assetId)
 
 	return lockAmt, acli.LockList, nil
 
 }
 
-func (b *BASE) isAccEntityExists(stub shim.ChaincodeStubInterface, entName string) (bool, *ErrorCodeMsg) {
+func (b *BASE) IsAccEntityExists(stub shim.ChaincodeStubInterface, entName string) (bool, *ErrorCodeMsg) {
 	var entB []byte
 	var err error
 
-	entB, err = stateCache.GetState_Ex(stub, b.getAccountEntityKey(entName))
+	entB, err = StateCache.GetState_Ex(stub, b.GetAccountEntityKey(entName))
 	if err != nil {
 		return false, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "marshal cb failed. error=(%s)", err)
 	}
@@ -2046,6 +3117,30 @@ func (b *BASE) isAccEntityExists(stub shim.ChaincodeStubInterface, entName strin
 	return true, nil
 }
 
+func (b *BASE) setAccountPubKey(stub shim.ChaincodeStubInterface, accEnt *AccountEntity, pubKeyBase64 string) *ErrorCodeMsg {
+	pubKey, err := base64.StdEncoding.DecodeString(pubKeyBase64)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "setAccountPubKey: decode pubkey failed. error=(%s)", err)
+	}
+
+	//ECDH只支持压缩格式的公钥
+	if len(pubKey) != 33 || secp256k1.VerifyPubkey(pubKey) != 1 {
+		return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "setAccountPubKey: pubkey invalid.")
+	}
+
+	hash, err := llcrypto.RipemdHash160(pubKey)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setAccountPubKey: Hash160 error, error=(%s).", err)
+	}
+	if base64.StdEncoding.EncodeToString(hash) != accEnt.OwnerPubKeyHash {
+		return baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "setAccountPubKey: pubkey not match the account(%s).", accEnt.EntID)
+	}
+
+	accEnt.OwnerPubKey = pubKeyBase64
+
+	return b.setAccountEntity(stub, accEnt)
+}
+
 //央行数据写入
 func (b *BASE) setAccountEntity(stub shim.ChaincodeStubInterface, cb *AccountEntity) *ErrorCodeMsg {
 
@@ -2055,7 +3150,7 @@ func (b *BASE) setAccountEntity(stub shim.ChaincodeStubInterface, cb *AccountEnt
 		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "marshal cb failed. error=(%s)", err)
 	}
 
-	err = stateCache.PutState_Ex(stub, b.getAccountEntityKey(cb.EntID), jsons)
+	err = StateCache.PutState_Ex(stub, b.GetAccountEntityKey(cb.EntID), jsons)
 
 	if err != nil {
 		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "PutState cb failed. error=(%s)", err)
@@ -2077,12 +3172,12 @@ func (b *BASE) issueCoin(stub shim.ChaincodeStubInterface, cbID string, issueAmo
 	}
 
 	var cb *AccountEntity
-	cb, errcm = b.getAccountEntity(stub, cbID)
+	cb, errcm = b.GetAccountEntity(stub, cbID)
 	if errcm != nil {
 		return nil, baselogger.ErrorECM(errcm.Code, "getCenterBank failed. error=(%s)", errcm)
 	}
 
-	issueEntity, errcm := b.getAccountEntity(stub, COIN_ISSUE_ACC_ENTID)
+	issueEntity, errcm := b.GetAccountEntity(stub, COIN_ISSUE_ACC_ENTID)
 	if errcm != nil {
 		return nil, baselogger.ErrorECM(errcm.Code, "issue: getIssueEntity failed. error=(%s)", errcm)
 	}
@@ -2110,7 +3205,7 @@ func (b *BASE) issueCoin(stub shim.ChaincodeStubInterface, cbID string, issueAmo
 	baselogger.Debug("issue after:cb=%+v, issue=%+v", cb, issueEntity)
 
 	//这里只记录一下央行的收入，不记录支出
-	errcm = b.recordTranse(stub, cb, issueEntity, TRANS_INCOME, "issue", "center bank issue coin.", issueAmount, issueTime, "")
+	_, errcm = b.recordTranse(stub, cb, issueEntity, TRANS_INCOME, "issue", "center bank issue coin.", "", issueAmount, issueTime, "", DEFAULT_ASSET_ID, 0, 0)
 	if errcm != nil {
 		return nil, baselogger.ErrorECM(errcm.Code, "issue: recordTranse failed. error=(%s)", errcm)
 	}
@@ -2118,7 +3213,8 @@ func (b *BASE) issueCoin(stub shim.ChaincodeStubInterface, cbID string, issueAmo
 	return nil, nil
 }
 
-func (b *BASE) setIssueAmountTotal(stub shim.ChaincodeStubInterface, issueAmt, initTime int64) *ErrorCodeMsg {
+//设置货币发行总额。base的init中默认为10000000000，扩展合约可以在InitHook中重新设置
+func (b *BASE) SetIssueAmountTotal(stub shim.ChaincodeStubInterface, issueAmt, initTime int64) *ErrorCodeMsg {
 
 	//虚拟一个超级账户，设置货币发行总额，给央行发行货币。
 	var issueEntity AccountEntity
@@ -2137,29 +3233,50 @@ func (b *BASE) setIssueAmountTotal(stub shim.ChaincodeStubInterface, issueAmt, i
 	return nil
 }
 
-//转账
-func (b *BASE) transferCoin(stub shim.ChaincodeStubInterface, from, to, transType, description string, amount, transeTime int64, sameEntSaveTrans bool, appid string) ([]byte, *ErrorCodeMsg) {
+//转账（默认资产）
+func (b *BASE) TransferCoin(stub shim.ChaincodeStubInterface, from, to, transType, description string, amount, transeTime int64, sameEntSaveTrans bool, appid string) ([]byte, *ErrorCodeMsg) {
+	return b.TransferAsset(stub, DEFAULT_ASSET_ID, from, to, transType, description, "", amount, transeTime, sameEntSaveTrans, appid)
+}
+
+//转账指定的资产
+func (b *BASE) TransferAsset(stub shim.ChaincodeStubInterface, assetId, from, to, transType, description, encMemo string, amount, transeTime int64, sameEntSaveTrans bool, appid string) ([]byte, *ErrorCodeMsg) {
+	_, errcm := b.transferAsset(stub, assetId, from, to, transType, description, encMemo, amount, transeTime, sameEntSaveTrans, appid, 0, -1)
+	return nil, errcm
+}
+
+//refundOf不为0时表示是退款，见refund.go。maxFee不小于0时收取的手续费不超过maxFee，见htlc.go。返回付款记录的全局序列号，没有记录交易时为0
+func (b *BASE) transferAsset(stub shim.ChaincodeStubInterface, assetId, from, to, transType, description, encMemo string, amount, transeTime int64, sameEntSaveTrans bool, appid string, refundOf, maxFee int64) (int64, *ErrorCodeMsg) {
 	baselogger.Debug("Enter transferCoin")
 
 	var errcm *ErrorCodeMsg
+	var paySerial int64
 
 	if amount < 0 {
-		return nil, baselogger.ErrorECM(ERRCODE_TRANS_AMOUNT_INVALID, "transferCoin failed. invalid amount(%d)", amount)
+		return 0, baselogger.ErrorECM(ERRCODE_TRANS_AMOUNT_INVALID, "transferCoin failed. invalid amount(%d)", amount)
 	}
 
 	//有时前端后台调用这个接口时，可能会传0
 	if amount == 0 {
-		return nil, nil
+		return 0, nil
+	}
+
+	if len(encMemo) > ENC_MEMO_MAX_LEN {
+		return 0, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "transferCoin failed. encrypted memo too long(%d)", len(encMemo))
 	}
 
 	//如果账户相同，并且账户相同时不需要记录交易，直接返回
 	if from == to && !sameEntSaveTrans {
 		baselogger.Warn("transferCoin from equals to.")
-		return nil, nil
+		return 0, nil
+	}
+
+	errcm = b.checkAssetExists(stub, assetId)
+	if errcm != nil {
+		return 0, baselogger.ErrorECM(errcm.Code, "transferCoin: checkAssetExists failed. error=(%s)", errcm)
 	}
 
 	var fromEntity, toEntity *AccountEntity
-	fromEntity, errcm = b.getAccountEntity(stub, from)
+	fromEntity, errcm = b.GetAccountEntity(stub, from)
 	if errcm != nil {
 		var errCode int32
 		if errcm == ErrcmNilEntity {
@@ -2167,9 +3284,9 @@ func (b *BASE) transferCoin(stub shim.ChaincodeStubInterface, from, to, transTyp
 		} else {
 			errCode = errcm.Code
 		}
-		return nil, baselogger.ErrorECM(errCode, "transferCoin: getAccountEntity(id=%s) failed. error=(%s)", from, errcm)
+		return 0, baselogger.ErrorECM(errCode, "transferCoin: GetAccountEntity(id=%s) failed. error=(%s)", from, errcm)
 	}
-	toEntity, errcm = b.getAccountEntity(stub, to)
+	toEntity, errcm = b.GetAccountEntity(stub, to)
 	if errcm != nil {
 		var errCode int32
 		if errcm == ErrcmNilEntity {
@@ -2177,37 +3294,56 @@ func (b *BASE) transferCoin(stub shim.ChaincodeStubInterface, from, to, transTyp
 		} else {
 			errCode = errcm.Code
 		}
-		return nil, baselogger.ErrorECM(errCode, "transferCoin: getAccountEntity(id=%s) failed. error=(%s)", to, errcm)
+		return 0, baselogger.ErrorECM(errCode, "transferCoin: GetAccountEntity(id=%s) failed. error=(%s)", to, errcm)
 	}
 
 	//判断是否有锁定金额
-	lockAmt, _, errcm := b.getAccountLockedAmount(stub, from, transeTime)
+	lockAmt, _, errcm := b.getAccountLockedAmount(stub, from, assetId, transeTime)
 	if errcm != nil {
-		return nil, baselogger.ErrorECM(errcm.Code, "transferCoin: getAccountLockedAmount(id=%s) failed. error=(%s)", from, errcm)
+		return 0, baselogger.ErrorECM(errcm.Code, "transferCoin: getAccountLockedAmount(id=%s) failed. error=(%s)", from, errcm)
+	}
+
+	//手续费由付款方在转账金额之外另付，账户相同时不收
+	var fee int64
+	var feeAcc string
+	if from != to {
+		fee, feeAcc, errcm = b.calcTransferFee(stub, appid, transType, assetId, from, amount)
+		if errcm != nil {
+			return 0, baselogger.ErrorECM(errcm.Code, "transferCoin: calcTransferFee failed. error=(%s)", errcm)
+		}
+		if maxFee >= 0 && fee > maxFee {
+			fee = maxFee
+		}
 	}
 
-	if fromEntity.RestAmount-lockAmt < amount {
+	_, fromRestAmount := fromEntity.GetAssetAmount(assetId)
+	if fromRestAmount-lockAmt < amount+fee {
 		var errCode int32
 		if lockAmt > 0 {
 			errCode = ERRCODE_TRANS_BALANCE_NOT_ENOUGH_BYLOCK
 		} else {
 			errCode = ERRCODE_TRANS_BALANCE_NOT_ENOUGH
 		}
-		return nil, baselogger.ErrorECM(errCode, "transferCoin: fromEntity(id=%s) restAmount not enough(%d,%d,%d).", from, fromEntity.RestAmount, lockAmt, amount)
+		return 0, baselogger.ErrorECM(errCode, "transferCoin: fromEntity(id=%s) restAmount not enough(%d,%d,%d,%d).", from, fromRestAmount, lockAmt, amount, fee)
 	}
 
 	//如果账户相同，并且账户相同时需要记录交易，记录并返回
 	if from == to && sameEntSaveTrans {
-		errcm = b.recordTranse(stub, fromEntity, toEntity, TRANS_PAY, transType, description, amount, transeTime, appid)
+		paySerial, errcm = b.recordTranse(stub, fromEntity, toEntity, TRANS_PAY, transType, description, encMemo, amount, transeTime, appid, assetId, 0, refundOf)
 		if errcm != nil {
-			return nil, baselogger.ErrorECM(errcm.Code, "transferCoin: setAccountEntity recordTranse fromEntity(id=%s) failed. error=(%s)", from, errcm)
+			return 0, baselogger.ErrorECM(errcm.Code, "transferCoin: setAccountEntity recordTranse fromEntity(id=%s) failed. error=(%s)", from, errcm)
 		}
 
-		errcm = b.recordTranse(stub, toEntity, fromEntity, TRANS_INCOME, transType, description, amount, transeTime, appid)
+		_, errcm = b.recordTranse(stub, toEntity, fromEntity, TRANS_INCOME, transType, description, encMemo, amount, transeTime, appid, assetId, 0, refundOf)
 		if errcm != nil {
-			return nil, baselogger.ErrorECM(errcm.Code, "transferCoin: setAccountEntity recordTranse fromEntity(id=%s) failed. error=(%s)", from, errcm)
+			return 0, baselogger.ErrorECM(errcm.Code, "transferCoin: setAccountEntity recordTranse fromEntity(id=%s) failed. error=(%s)", from, errcm)
 		}
-		return nil, nil
+
+		errcm = b.updateBalanceCheckpoints(stub, transeTime, from)
+		if errcm != nil {
+			return 0, baselogger.ErrorECM(errcm.Code, "transferCoin: updateBalanceCheckpoints failed. error=(%s)", errcm)
+		}
+		return paySerial, nil
 	}
 
 	//账户相同时为什么单独处理？  因为如果走了下面的流程，setAccountEntity两次同一个账户，会导致账户余额变化。 除非在计算并设置完fromEntity之后，再获取一下toEntity，再计算toEntity，这样感觉太呆了
@@ -2215,36 +3351,46 @@ func (b *BASE) transferCoin(stub shim.ChaincodeStubInterface, from, to, transTyp
 	baselogger.Debug("transferCoin: fromEntity before= %+v", fromEntity)
 	baselogger.Debug("transferCoin: toEntity before= %+v", toEntity)
 
-	fromEntity.RestAmount -= amount
-
-	toEntity.RestAmount += amount
-	toEntity.TotalAmount += amount
+	fromEntity.AddAssetAmount(assetId, -amount-fee)
+	toEntity.AddAssetAmount(assetId, amount)
 
 	baselogger.Debug("transferCoin: fromEntity after= %+v", fromEntity)
 	baselogger.Debug("transferCoin: toEntity after= %+v", toEntity)
 
 	errcm = b.setAccountEntity(stub, fromEntity)
 	if errcm != nil {
-		return nil, baselogger.ErrorECM(errcm.Code, "transferCoin: setAccountEntity of fromEntity(id=%s) failed. error=(%s)", from, errcm)
+		return 0, baselogger.ErrorECM(errcm.Code, "transferCoin: setAccountEntity of fromEntity(id=%s) failed. error=(%s)", from, errcm)
 	}
 
-	errcm = b.recordTranse(stub, fromEntity, toEntity, TRANS_PAY, transType, description, amount, transeTime, appid)
+	paySerial, errcm = b.recordTranse(stub, fromEntity, toEntity, TRANS_PAY, transType, description, encMemo, amount, transeTime, appid, assetId, fee, refundOf)
 	if errcm != nil {
-		return nil, baselogger.ErrorECM(errcm.Code, "transferCoin: setAccountEntity recordTranse fromEntity(id=%s) failed. error=(%s)", from, errcm)
+		return 0, baselogger.ErrorECM(errcm.Code, "transferCoin: setAccountEntity recordTranse fromEntity(id=%s) failed. error=(%s)", from, errcm)
 	}
 
 	errcm = b.setAccountEntity(stub, toEntity)
 	if errcm != nil {
-		return nil, baselogger.ErrorECM(errcm.Code, "transferCoin: setAccountEntity of toEntity(id=%s) failed. error=(%s)", to, errcm)
+		return 0, baselogger.ErrorECM(errcm.Code, "transferCoin: setAccountEntity of toEntity(id=%s) failed. error=(%s)", to, errcm)
 	}
 
 	//两个账户的收入支出都记录交易
-	errcm = b.recordTranse(stub, toEntity, fromEntity, TRANS_INCOME, transType, description, amount, transeTime, appid)
+	_, errcm = b.recordTranse(stub, toEntity, fromEntity, TRANS_INCOME, transType, description, encMemo, amount, transeTime, appid, assetId, fee, refundOf)
 	if errcm != nil {
-		return nil, baselogger.ErrorECM(errcm.Code, "transferCoin: setAccountEntity recordTranse fromEntity(id=%s) failed. error=(%s)", from, errcm)
+		return 0, baselogger.ErrorECM(errcm.Code, "transferCoin: setAccountEntity recordTranse fromEntity(id=%s) failed. error=(%s)", from, errcm)
 	}
 
-	return nil, errcm
+	if fee > 0 {
+		errcm = b.chargeTransferFee(stub, fromEntity, feeAcc, assetId, to, fee, transeTime, appid)
+		if errcm != nil {
+			return 0, baselogger.ErrorECM(errcm.Code, "transferCoin: chargeTransferFee(id=%s) failed. error=(%s)", feeAcc, errcm)
+		}
+	}
+
+	errcm = b.updateBalanceCheckpoints(stub, transeTime, from, to, feeAcc)
+	if errcm != nil {
+		return 0, baselogger.ErrorECM(errcm.Code, "transferCoin: updateBalanceCheckpoints failed. error=(%s)", errcm)
+	}
+
+	return paySerial, nil
 }
 
 const (
@@ -2252,12 +3398,15 @@ const (
 	TRANS_LVL_COMM = 2
 )
 
-//记录交易。目前交易分为两种：一种是和央行打交道的，包括央行发行货币、央行给项目或企业转帐，此类交易普通用户不能查询；另一种是项目、企业、个人间互相转账，此类交易普通用户能查询
-func (b *BASE) recordTranse(stub shim.ChaincodeStubInterface, fromEnt, toEnt *AccountEntity, incomePayFlag int, transType, description string, amount, times int64, appid string) *ErrorCodeMsg {
+//记录交易，返回交易记录的全局序列号。目前交易分为两种：一种是和央行打交道的，包括央行发行货币、央行给项目或企业转帐，此类交易普通用户不能查询；另一种是项目、企业、个人间互相转账，此类交易普通用户能查询
+func (b *BASE) recordTranse(stub shim.ChaincodeStubInterface, fromEnt, toEnt *AccountEntity, incomePayFlag int, transType, description, encMemo string, amount, times int64, appid, assetId string, fee, refundOf int64) (int64, *ErrorCodeMsg) {
 	var transInfo Transaction
 	//var now = time.Now()
 
 	transInfo.AppID = appid
+	if !IsDefaultAsset(assetId) {
+		transInfo.AssetID = assetId
+	}
 	transInfo.FromID = fromEnt.EntID
 	transInfo.FromType = fromEnt.EntType
 	transInfo.ToID = toEnt.EntID
@@ -2269,11 +3418,14 @@ func (b *BASE) recordTranse(stub shim.ChaincodeStubInterface, fromEnt, toEnt *Ac
 	transInfo.TxID = stub.GetTxID()
 	transInfo.TransType = transType
 	transInfo.Description = description
+	transInfo.EncMemo = encMemo
+	transInfo.Fee = fee
+	transInfo.RefundOf = refundOf
 
 	var transLevel uint64 = TRANS_LVL_COMM
-	accCB, errcm := b.getCenterBankAcc(stub)
+	accCB, errcm := b.GetCenterBankAcc(stub)
 	if errcm != nil {
-		return baselogger.ErrorECM(errcm.Code, "recordTranse call getCenterBankAcc failed. error=(%s)", errcm)
+		return 0, baselogger.ErrorECM(errcm.Code, "recordTranse call getCenterBankAcc failed. error=(%s)", errcm)
 	}
 	if (accCB != nil) && (string(accCB) == transInfo.FromID || string(accCB) == transInfo.ToID) {
 		transLevel = TRANS_LVL_CB
@@ -2283,10 +3435,10 @@ func (b *BASE) recordTranse(stub shim.ChaincodeStubInterface, fromEnt, toEnt *Ac
 
 	errcm = b.setTransInfo(stub, &transInfo)
 	if errcm != nil {
-		return baselogger.ErrorECM(errcm.Code, "recordTranse call setTransInfo failed. error=(%s)", errcm)
+		return 0, baselogger.ErrorECM(errcm.Code, "recordTranse call setTransInfo failed. error=(%s)", errcm)
 	}
 
-	return nil
+	return transInfo.GlobalSerial, nil
 }
 
 func (b *BASE) checkAccountName(accName string) *ErrorCodeMsg {
@@ -2297,7 +3449,7 @@ func (b *BASE) checkAccountName(accName string) *ErrorCodeMsg {
 }
 
 func (b *BASE) saveAccountName(stub shim.ChaincodeStubInterface, accName string) *ErrorCodeMsg {
-	accB, err := stateCache.GetState_Ex(stub, ALL_ACC_INFO_KEY)
+	accB, err := StateCache.GetState_Ex(stub, ALL_ACC_INFO_KEY)
 	if err != nil {
 		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "saveAccountName GetState failed.error=(%s)", err)
 	}
@@ -2310,13 +3462,13 @@ func (b *BASE) saveAccountName(stub shim.ChaincodeStubInterface, accName string)
 		accs = append(accs, MULTI_STRING_DELIM)
 	}
 
-	err = stateCache.PutState_Ex(stub, ALL_ACC_INFO_KEY, accs)
+	err = StateCache.PutState_Ex(stub, ALL_ACC_INFO_KEY, accs)
 	if err != nil {
 		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "saveAccountName PutState(accs) failed.error=(%s)", err)
 	}
 
 	var asi AccountStatisticInfo
-	asiB, err := stateCache.GetState_Ex(stub, ACC_STATIC_INFO_KEY)
+	asiB, err := StateCache.GetState_Ex(stub, ACC_STATIC_INFO_KEY)
 	if asiB == nil {
 		asi.AccountCount = 1
 	} else {
@@ -2332,7 +3484,7 @@ func (b *BASE) saveAccountName(stub shim.ChaincodeStubInterface, accName string)
 		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "saveAccountName Marshal failed.error=(%s)", err)
 	}
 
-	err = stateCache.PutState_Ex(stub, ACC_STATIC_INFO_KEY, asiB)
+	err = StateCache.PutState_Ex(stub, ACC_STATIC_INFO_KEY, asiB)
 	if err != nil {
 		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "saveAccountName PutState(asiB) failed.error=(%s)", err)
 	}
@@ -2350,31 +3502,45 @@ func (b *BASE) newAccount(stub shim.ChaincodeStubInterface, accName string, accT
 		return nil, errcm
 	}
 
-	accExist, errcm = b.isAccEntityExists(stub, accName)
+	accExist, errcm = b.IsAccEntityExists(stub, accName)
 	if errcm != nil {
 		return nil, baselogger.ErrorECM(errcm.Code, "isEntityExists (id=%s) failed. error=(%s)", accName, errcm)
 	}
 
 	if accExist {
-		/*
-			//兼容kd老版本，没有userIdHash和userPubKeyHash的情况，如果这两个字段为空，只写这两个字段，后续可以删除
-			accEnt, errcm := b.getAccountEntity(stub, accName)
+		//兼容从老版本迁移过来的账户（如kd1.0、smk0.6、frt0.6），没有userIdHash和userPubKeyHash，如果这两个字段为空，由账户所有者补写这两个字段
+		accEnt, errcm := b.GetAccountEntity(stub, accName)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "getAccountEntity (id=%s) failed. error=(%s)", accName, errcm)
+		}
+		if accEnt.Owner == userName && len(accEnt.OwnerIdentityHash) == 0 && len(accEnt.OwnerPubKeyHash) == 0 &&
+			len(userIdHash) > 0 && len(userPubKeyHash) > 0 {
+			accEnt.OwnerIdentityHash = userIdHash
+			accEnt.OwnerPubKeyHash = userPubKeyHash
+
+			errcm = b.setAccountEntity(stub, accEnt)
 			if errcm != nil {
-				return nil, baselogger.ErrorECM(errcm.Code,"getAccountEntity (id=%s) failed. error=(%s)", accName, errcm)
+				return nil, baselogger.ErrorECM(errcm.Code, "setAccountEntity (id=%s) failed. error=(%s)", accName, errcm)
 			}
-			if (len(accEnt.OwnerIdentityHash) == 0 && len(userIdHash) > 0) ||
-				(len(accEnt.OwnerPubKeyHash) == 0 && len(userPubKeyHash) > 0) {
-				accEnt.OwnerIdentityHash = userIdHash
-				accEnt.OwnerPubKeyHash = userPubKeyHash
 
-				errcm = b.setAccountEntity(stub, accEnt)
+			userEnt, errcm := b.getUserEntity(stub, userName)
+			if errcm != nil && errcm != ErrcmNilEntity {
+				return nil, baselogger.ErrorECM(errcm.Code, "getUserEntity (id=%s) failed. error=(%s)", userName, errcm)
+			}
+			if userEnt == nil {
+				userEnt = &UserEntity{EntID: userName}
+			}
+			if !StrSliceContains(userEnt.AccList, accName) {
+				userEnt.AccList = append(userEnt.AccList, accName)
+				errcm = b.setUserEntity(stub, userEnt)
 				if errcm != nil {
-					return nil, baselogger.ErrorECM(errcm.Code,"setAccountEntity (id=%s) failed. error=(%s)", accName, errcm)
+					return nil, baselogger.ErrorECM(errcm.Code, "setUserEntity (id=%s) failed. error=(%s)", userName, errcm)
 				}
-				return nil, nil
 			}
-			//兼容kd老版本，没有userIdHash和userPubKeyHash的情况，如果这两个字段为空，只写这两个字段，后续可以删除
-		*/
+
+			baselogger.Info("account (id=%s) bind owner hash success.", accName)
+			return nil, nil
+		}
 
 		return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "account (id=%s) failed, already exists.", accName)
 	}
@@ -2433,7 +3599,7 @@ func (b *BASE) newAccount(stub shim.ChaincodeStubInterface, accName string, accT
 var centerBankAccCache []byte = nil
 
 func (b *BASE) setCenterBankAcc(stub shim.ChaincodeStubInterface, acc string) *ErrorCodeMsg {
-	err := stateCache.PutState_Ex(stub, CENTERBANK_ACC_KEY, []byte(acc))
+	err := StateCache.PutState_Ex(stub, CENTERBANK_ACC_KEY, []byte(acc))
 	if err != nil {
 		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setCenterBankAcc PutState failed.error=(%s)", err)
 	}
@@ -2442,12 +3608,12 @@ func (b *BASE) setCenterBankAcc(stub shim.ChaincodeStubInterface, acc string) *E
 
 	return nil
 }
-func (b *BASE) getCenterBankAcc(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg) {
+func (b *BASE) GetCenterBankAcc(stub shim.ChaincodeStubInterface) ([]byte, *ErrorCodeMsg) {
 	if centerBankAccCache != nil {
 		return centerBankAccCache, nil
 	}
 
-	bankB, err := stateCache.GetState_Ex(stub, CENTERBANK_ACC_KEY)
+	bankB, err := StateCache.GetState_Ex(stub, CENTERBANK_ACC_KEY)
 	if err != nil {
 		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getCenterBankAcc GetState failed.error=(%s)", err)
 	}
@@ -2457,14 +3623,14 @@ func (b *BASE) getCenterBankAcc(stub shim.ChaincodeStubInterface) ([]byte, *Erro
 	return bankB, nil
 }
 
-func (b *BASE) getTransSeq(stub shim.ChaincodeStubInterface, transSeqKey string) (int64, *ErrorCodeMsg) {
-	seqB, err := stateCache.GetState_Ex(stub, transSeqKey)
+func (b *BASE) GetTransSeq(stub shim.ChaincodeStubInterface, transSeqKey string) (int64, *ErrorCodeMsg) {
+	seqB, err := StateCache.GetState_Ex(stub, transSeqKey)
 	if err != nil {
 		return -1, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getTransSeq GetState failed.error=(%s)", err)
 	}
 	//如果不存在则创建
 	if seqB == nil {
-		err = stateCache.PutState_Ex(stub, transSeqKey, []byte("0"))
+		err = StateCache.PutState_Ex(stub, transSeqKey, []byte("0"))
 		if err != nil {
 			return -1, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "initTransSeq PutState failed.error=(%s)", err)
 		}
@@ -2478,8 +3644,8 @@ func (b *BASE) getTransSeq(stub shim.ChaincodeStubInterface, transSeqKey string)
 
 	return seq, nil
 }
-func (b *BASE) setTransSeq(stub shim.ChaincodeStubInterface, transSeqKey string, seq int64) *ErrorCodeMsg {
-	err := stateCache.PutState_Ex(stub, transSeqKey, []byte(strconv.FormatInt(seq, 10)))
+func (b *BASE) SetTransSeq(stub shim.ChaincodeStubInterface, transSeqKey string, seq int64) *ErrorCodeMsg {
+	err := StateCache.PutState_Ex(stub, transSeqKey, []byte(strconv.FormatInt(seq, 10)))
 	if err != nil {
 		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setTransSeq PutState failed.error=(%s)", err)
 	}
@@ -2504,7 +3670,7 @@ func (b *BASE) getAccTransSeqKey(accName string) string {
 
 func (b *BASE) setTransInfo(stub shim.ChaincodeStubInterface, info *Transaction) *ErrorCodeMsg {
 	//先获取全局seq
-	seqGlob, errcm := b.getTransSeq(stub, b.getGlobalTransSeqKey(stub))
+	seqGlob, errcm := b.GetTransSeq(stub, b.getGlobalTransSeqKey(stub))
 	if errcm != nil {
 		baselogger.Error("setTransInfo getTransSeq failed.error=(%s)", errcm)
 		return errcm
@@ -2513,7 +3679,7 @@ func (b *BASE) setTransInfo(stub shim.ChaincodeStubInterface, info *Transaction)
 
 	/*
 	   //再获取当前交易级别的seq
-	   seqLvl, err := b.getTransSeq(stub, b.getTransSeqKey(stub, info.TransLvl))
+	   seqLvl, err := b.GetTransSeq(stub, b.getTransSeqKey(stub, info.TransLvl))
 	   if err != nil {
 	       base_logger.Error("setTransInfo getTransSeq failed.error=(%s)", err)
 	       return err
@@ -2529,7 +3695,7 @@ func (b *BASE) setTransInfo(stub shim.ChaincodeStubInterface, info *Transaction)
 	}
 
 	putKey := b.getTransInfoKey(stub, seqGlob)
-	err = stateCache.PutState_Ex(stub, putKey, transJson)
+	err = StateCache.PutState_Ex(stub, putKey, transJson)
 	if err != nil {
 		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setTransInfo PutState failed. error=(%s)", err)
 	}
@@ -2551,13 +3717,21 @@ func (b *BASE) setTransInfo(stub shim.ChaincodeStubInterface, info *Transaction)
 		return baselogger.ErrorECM(errcm.Code, "setTransInfo setOneAccTransInfo(%s) failed. error=(%s)", info.FromID, errcm)
 	}
 
+	//转账的付款记录按交易id建索引，退款时可以用交易id找到原交易
+	if info.TransFlag == TRANS_PAY && info.TransType != FEE_TRANS_TYPE {
+		errcm = b.addTxSerial(stub, info.TxID, seqGlob)
+		if errcm != nil {
+			return baselogger.ErrorECM(errcm.Code, "setTransInfo addTxSerial failed. error=(%s)", errcm)
+		}
+	}
+
 	//交易信息设置成功后，保存序列号
-	errcm = b.setTransSeq(stub, b.getGlobalTransSeqKey(stub), seqGlob)
+	errcm = b.SetTransSeq(stub, b.getGlobalTransSeqKey(stub), seqGlob)
 	if errcm != nil {
 		return baselogger.ErrorECM(errcm.Code, "setTransInfo setTransSeq failed. error=(%s)", errcm)
 	}
 	/*
-	   err = b.setTransSeq(stub, b.getTransSeqKey(stub, info.TransLvl), seqLvl)
+	   err = b.SetTransSeq(stub, b.getTransSeqKey(stub, info.TransLvl), seqLvl)
 	   if err != nil {
 	       base_logger.Error("setTransInfo setTransSeq failed. error=(%s)", err)
 	       return errors.New("setTransInfo setTransSeq failed.")
@@ -2575,14 +3749,14 @@ func (b *BASE) getOneAccTransInfoKey(accName string, seq int64) string {
 
 func (b *BASE) setOneAccTransInfo(stub shim.ChaincodeStubInterface, accName, GlobalTransKey string) *ErrorCodeMsg {
 
-	seq, errcm := b.getTransSeq(stub, b.getAccTransSeqKey(accName))
+	seq, errcm := b.GetTransSeq(stub, b.getAccTransSeqKey(accName))
 	if errcm != nil {
 		return baselogger.ErrorECM(errcm.Code, "setOneAccTransInfo getTransSeq failed.error=(%s)", errcm)
 	}
 	seq++
 
 	var key = b.getOneAccTransInfoKey(accName, seq)
-	err := stateCache.PutState_Ex(stub, key, []byte(GlobalTransKey))
+	err := StateCache.PutState_Ex(stub, key, []byte(GlobalTransKey))
 	if err != nil {
 		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setOneAccTransInfo PutState failed. error=(%s)", err)
 	}
@@ -2590,7 +3764,7 @@ func (b *BASE) setOneAccTransInfo(stub shim.ChaincodeStubInterface, accName, Glo
 	baselogger.Debug("setOneAccTransInfo key=%s, v=%s", key, GlobalTransKey)
 
 	//交易信息设置成功后，保存序列号
-	errcm = b.setTransSeq(stub, b.getAccTransSeqKey(accName), seq)
+	errcm = b.SetTransSeq(stub, b.getAccTransSeqKey(accName), seq)
 	if errcm != nil {
 		return baselogger.ErrorECM(errcm.Code, "setOneAccTransInfo setTransSeq failed. error=(%s)", errcm)
 	}
@@ -2602,7 +3776,7 @@ func (b *BASE) getOnceTransInfo(stub shim.ChaincodeStubInterface, key string) (*
 	var err error
 	var trans Transaction
 
-	tmpState, err := stateCache.GetState_Ex(stub, key)
+	tmpState, err := StateCache.GetState_Ex(stub, key)
 	if err != nil {
 		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getTransInfo GetState failed.error=(%s)", err)
 	}
@@ -2623,7 +3797,7 @@ func (b *BASE) getQueryTransInfo(stub shim.ChaincodeStubInterface, key string) (
 	var err error
 	var trans QueryTransRecd
 
-	tmpState, err := stateCache.GetState_Ex(stub, key)
+	tmpState, err := StateCache.GetState_Ex(stub, key)
 	if err != nil {
 		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getQueryTransInfo GetState failed.error=(%s)", err)
 	}
@@ -2799,7 +3973,7 @@ func (b *BASE) loadWorldState(stub shim.ChaincodeStubInterface, fileName string,
 		var value = oneRecd[1]
 
 		if !sameKeyOverwrite {
-			testB, err := stateCache.GetState_Ex(stub, key)
+			testB, err := StateCache.GetState_Ex(stub, key)
 			if err != nil {
 				return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setWorldState: GetState failed. key=%s error=(%s)", key, err)
 			}
@@ -2841,7 +4015,7 @@ func (b *BASE) loadWorldState(stub shim.ChaincodeStubInterface, fileName string,
 			return nil, baselogger.ErrorECM(errcm.Code, "setWorldState: dateConvertWhenUpdate failed.  error=(%s)", errcm)
 		}
 
-		err = stateCache.PutState_Ex(stub, newKey, newValB)
+		err = StateCache.PutState_Ex(stub, newKey, newValB)
 		if err != nil {
 			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setWorldState: PutState_Ex failed. key=%s error=(%s)", key, err)
 		}
@@ -2890,7 +4064,7 @@ func (b *BASE) loadAfter(stub shim.ChaincodeStubInterface, srcCcid string) *Erro
 	return nil
 }
 
-func (b *BASE) setAccountLockAmountCfg(stub shim.ChaincodeStubInterface, accName, cfgStr string, overwriteOld bool) (int64, int64, *ErrorCodeMsg) {
+func (b *BASE) setAccountLockAmountCfg(stub shim.ChaincodeStubInterface, accName, assetId, cfgStr string, overwriteOld bool) (int64, int64, *ErrorCodeMsg) {
 	//配置格式如下 "2000:1518407999000;3000:1518407999000..."，防止输入错误，先去除两边的空格，然后再去除两边的';'（防止split出来空字符串）
 	var newCfg = strings.Trim(strings.TrimSpace(cfgStr), ";")
 
@@ -2928,12 +4102,12 @@ func (b *BASE) setAccountLockAmountCfg(stub shim.ChaincodeStubInterface, accName
 	}
 
 	var acli AccountCoinLockInfo
-	var lockinfoKey = b.getAccountLockInfoKey(accName)
+	var lockinfoKey = b.getAccountLockInfoKey(accName, assetId)
 
 	if overwriteOld {
 		acli.AccName = accName
 	} else {
-		acliB, err := stateCache.GetState_Ex(stub, lockinfoKey)
+		acliB, err := StateCache.GetState_Ex(stub, lockinfoKey)
 		if err != nil {
 			return 0, 0, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setAccountLockAmountCfg: GetState  failed. error=(%s)", err)
 		}
@@ -2957,7 +4131,7 @@ func (b *BASE) setAccountLockAmountCfg(stub shim.ChaincodeStubInterface, accName
 	if err != nil {
 		return 0, 0, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setAccountLockAmountCfg: Marshal  failed. error=(%s)", err)
 	}
-	err = stateCache.PutState_Ex(stub, lockinfoKey, acliB)
+	err = StateCache.PutState_Ex(stub, lockinfoKey, acliB)
 	if err != nil {
 		return 0, 0, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setAccountLockAmountCfg: putState_Ex  failed. error=(%s)", err)
 	}
@@ -2976,7 +4150,7 @@ func (b *BASE) getUserEntity(stub shim.ChaincodeStubInterface, userName string)
 	var ue UserEntity
 	var err error
 
-	entB, err = stateCache.GetState_Ex(stub, b.getUserEntityKey(userName))
+	entB, err = StateCache.GetState_Ex(stub, b.getUserEntityKey(userName))
 	if err != nil {
 		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getUserEntity GetState failed. error=(%s)", err)
 	}
@@ -2998,32 +4172,84 @@ func (b *BASE) setUserEntity(stub shim.ChaincodeStubInterface, ue *UserEntity) *
 		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setUserEntity: Marshal failed. error=(%s)", err)
 	}
 
-	err = stateCache.PutState_Ex(stub, b.getUserEntityKey(ue.EntID), jsons)
+	err = StateCache.PutState_Ex(stub, b.getUserEntityKey(ue.EntID), jsons)
 	if err != nil {
 		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setUserEntity: PutState cb failed. error=(%s)", err)
 	}
 	return nil
 }
 
-func (b *BASE) getSignAndMsg(function string, args []string, signIdx int) ([]byte, []byte, *ErrorCodeMsg) {
-	var err error
-
-	var signBase64 = args[signIdx]
+//签名参数有两种格式：旧格式为base64(签名)，签名消息为函数名和参数用","拼接后的sha256；
+//v1格式带过期时间，签名消息中包含链id、合约名，参数按长度前缀编码，见llcrypto/signmsg.go。返回的isV1用于检查账户要求的签名格式
+func (b *BASE) getSignAndMsg(stub shim.ChaincodeStubInterface, function string, args []string, signIdx int, invokeTime int64) ([]byte, []byte, bool, *ErrorCodeMsg) {
+	isV1, expiry, signBase64, err := llcrypto.ParseSignEnvelope(args[signIdx])
+	if err != nil {
+		return nil, nil, false, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getSignAndMsg: parse sign failed. error=(%s)", err)
+	}
 
 	var sign []byte
 	sign, err = base64.StdEncoding.DecodeString(signBase64)
 	if err != nil {
-		return nil, nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getSignAndMsg: convert sign(%s) failed. error=(%s)", signBase64, err)
+		return nil, nil, false, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getSignAndMsg: convert sign(%s) failed. error=(%s)", signBase64, err)
+	}
+
+	if !isV1 {
+		//客户端签名的生成： 把函数名和输入的参数用","拼接为字符串，然后计算其Sha256作为msg，然后用私钥对msg做签名。所以这里用同样的方法生成msg
+		var allArgsString = function + "," + strings.Join(args[:signIdx], ",") //不包括签名本身，对签名参数以前的参数做签名
+		msg := util.ComputeSHA256([]byte(allArgsString))
+
+		baselogger.Debug("allArgsString =%s", allArgsString)
+		baselogger.Debug("sign-msg =%v", msg)
+
+		return sign, msg, false, nil
+	}
+
+	if expiry <= invokeTime {
+		return nil, nil, true, baselogger.ErrorECM(ERRCODE_COMMON_IDENTITY_VERIFY_FAILED, "getSignAndMsg: sign expired(%d,%d).", expiry, invokeTime)
+	}
+	if expiry > invokeTime+SIGN_MAX_VALID_PERIOD {
+		return nil, nil, true, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getSignAndMsg: sign expiry too far(%d,%d).", expiry, invokeTime)
+	}
+
+	ccName, errcm := b.getInvokedChaincodeName(stub)
+	if errcm != nil {
+		return nil, nil, true, baselogger.ErrorECM(errcm.Code, "getSignAndMsg: getInvokedChaincodeName failed. error=(%s)", errcm)
+	}
+
+	msg := llcrypto.CanonicalSignMsg(stub.GetChannelID(), ccName, function, args[:signIdx], expiry)
+
+	baselogger.Debug("sign-msg(v1) =%v, channel=%s, chaincode=%s, expiry=%d", msg, stub.GetChannelID(), ccName, expiry)
+
+	return sign, msg, true, nil
+}
+
+//客户端提交交易时调用的合约名，从交易提案中解析
+func (b *BASE) getInvokedChaincodeName(stub shim.ChaincodeStubInterface) (string, *ErrorCodeMsg) {
+	sp, err := stub.GetSignedProposal()
+	if err != nil || sp == nil {
+		return "", baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getInvokedChaincodeName: GetSignedProposal failed. error=(%v)", err)
+	}
+
+	var prop pb.Proposal
+	if err = proto.Unmarshal(sp.ProposalBytes, &prop); err != nil {
+		return "", baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getInvokedChaincodeName: Unmarshal proposal failed. error=(%s)", err)
+	}
+
+	var cpp pb.ChaincodeProposalPayload
+	if err = proto.Unmarshal(prop.Payload, &cpp); err != nil {
+		return "", baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getInvokedChaincodeName: Unmarshal payload failed. error=(%s)", err)
 	}
 
-	//客户端签名的生成： 把函数名和输入的参数用","拼接为字符串，然后计算其Sha256作为msg，然后用私钥对msg做签名。所以这里用同样的方法生成msg
-	var allArgsString = function + "," + strings.Join(args[:signIdx], ",") //不包括签名本身，对签名参数以前的参数做签名
-	msg := util.ComputeSHA256([]byte(allArgsString))
+	var cis pb.ChaincodeInvocationSpec
+	if err = proto.Unmarshal(cpp.Input, &cis); err != nil {
+		return "", baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getInvokedChaincodeName: Unmarshal invocation spec failed. error=(%s)", err)
+	}
 
-	baselogger.Debug("allArgsString =%s", allArgsString)
-	baselogger.Debug("sign-msg =%v", msg)
+	if cis.ChaincodeSpec == nil || cis.ChaincodeSpec.ChaincodeId == nil {
+		return "", baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getInvokedChaincodeName: no chaincode id in proposal.")
+	}
 
-	return sign, msg, nil
+	return cis.ChaincodeSpec.ChaincodeId.Name, nil
 }
 
 func (b *BASE) getAppRecdKey(appid string) string {
@@ -3037,7 +4263,7 @@ func (b *BASE) setAppInfo(stub shim.ChaincodeStubInterface, app *AppInfo) *Error
 		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "Marshal failed. error=(%s)", err)
 	}
 
-	err = stateCache.PutState_Ex(stub, b.getAppRecdKey(app.AppID), appB)
+	err = StateCache.PutState_Ex(stub, b.getAppRecdKey(app.AppID), appB)
 	if err != nil {
 		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "PutState failed. error=(%s)", err)
 	}
@@ -3046,7 +4272,7 @@ func (b *BASE) setAppInfo(stub shim.ChaincodeStubInterface, app *AppInfo) *Error
 }
 
 func (b *BASE) getAppInfo(stub shim.ChaincodeStubInterface, appid string) (*AppInfo, *ErrorCodeMsg) {
-	appB, err := stateCache.GetState_Ex(stub, b.getAppRecdKey(appid))
+	appB, err := StateCache.GetState_Ex(stub, b.getAppRecdKey(appid))
 	if err != nil {
 		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "GetState failed. error=(%s)", err)
 	}
@@ -3072,7 +4298,7 @@ func (b *BASE) getCrossChaincodeName(flag string) string {
 }
 
 func (b *BASE) isAppExists(stub shim.ChaincodeStubInterface, appid string) (bool, *ErrorCodeMsg) {
-	appB, err := stateCache.GetState_Ex(stub, b.getAppRecdKey(appid))
+	appB, err := StateCache.GetState_Ex(stub, b.getAppRecdKey(appid))
 	if err != nil {
 		return false, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "GetState failed. error=(%s)", err)
 	}
@@ -3085,10 +4311,10 @@ func (b *BASE) isAppExists(stub shim.ChaincodeStubInterface, appid string) (bool
 }
 
 func (b *BASE) isAccountSysFunc(function string) bool {
-	return strSliceContains(sysFunc, function)
+	return StrSliceContains(sysFunc, function)
 }
 
-func (b *BASE) corssChaincodeCall(stub shim.ChaincodeStubInterface, args [][]byte, chaincodeName, currUserName, currAccountName string, sign, signMsg []byte) ([]byte, *ErrorCodeMsg) {
+func (b *BASE) corssChaincodeCall(stub shim.ChaincodeStubInterface, args [][]byte, chaincodeName, currUserName, currAccountName string, sign, signMsg []byte, signIsV1 bool) ([]byte, *ErrorCodeMsg) {
 	baselogger.Debug("before invoke")
 	response := stub.InvokeChaincode(chaincodeName, args, "")
 	if response.Status != shim.OK {
@@ -3121,16 +4347,24 @@ func (b *BASE) corssChaincodeCall(stub shim.ChaincodeStubInterface, args [][]byt
 		}
 		//转出账户不是当前账户， 校验用户身份，看当前用户是否能操作该账户。  如果是当前账户，已经校验过了
 		for noCurrAcc, _ := range noCurrAccMap {
-			fromAcc, errcm := b.getAccountEntity(stub, noCurrAcc)
+			fromAcc, errcm := b.GetAccountEntity(stub, noCurrAcc)
 			if errcm != nil {
 				return nil, baselogger.ErrorECM(errcm.Code, "InvokeChaincode(%s) transfer failed, get transfer-from account failed, error=(%s).", chaincodeName, errcm)
 			}
-			if errcm := b.verifyIdentity(stub, currUserName, sign, signMsg, fromAcc, "", ""); errcm != nil {
+			if errcm := b.verifyIdentity(stub, currUserName, sign, signMsg, signIsV1, fromAcc, "", ""); errcm != nil {
 				return nil, baselogger.ErrorECM(errcm.Code, "InvokeChaincode(%s) transfer failed, verify user and transfer-from account failed, error=(%s).", chaincodeName, errcm)
 			}
 		}
 		for _, tx := range invokeRslt.TransInfos {
-			_, errcm := b.transferCoin(stub, tx.FromID, tx.ToID, tx.TransType, tx.Description, tx.Amount, tx.Time, true, tx.AppID)
+			var amount = tx.Amount
+			if tx.UpToBalance {
+				var errcm *ErrorCodeMsg
+				amount, errcm = b.capAmountByBalance(stub, &tx)
+				if errcm != nil {
+					return nil, baselogger.ErrorECM(errcm.Code, "InvokeChaincode(%s) capAmountByBalance error, error=(%s).", chaincodeName, errcm)
+				}
+			}
+			_, errcm := b.TransferCoin(stub, tx.FromID, tx.ToID, tx.TransType, tx.Description, amount, tx.Time, true, tx.AppID)
 			if errcm != nil {
 				return nil, baselogger.ErrorECM(errcm.Code, "InvokeChaincode(%s) transferCoin error, error=(%s).", chaincodeName, errcm)
 			}
@@ -3140,6 +4374,40 @@ func (b *BASE) corssChaincodeCall(stub shim.ChaincodeStubInterface, args [][]byt
 	return invokeRslt.Payload, nil
 }
 
+//跨合约调用返回的转账设置了UpToBalance时，转账金额不超过发送方的可用余额（扣除锁定金额和手续费之后）
+func (b *BASE) capAmountByBalance(stub shim.ChaincodeStubInterface, tx *TransferInfo) (int64, *ErrorCodeMsg) {
+	fromEnt, errcm := b.GetAccountEntity(stub, tx.FromID)
+	if errcm != nil {
+		return 0, baselogger.ErrorECM(errcm.Code, "capAmountByBalance: GetAccountEntity(%s) failed. error=(%s)", tx.FromID, errcm)
+	}
+	lockAmt, _, errcm := b.getAccountLockedAmount(stub, tx.FromID, DEFAULT_ASSET_ID, tx.Time)
+	if errcm != nil {
+		return 0, baselogger.ErrorECM(errcm.Code, "capAmountByBalance: getAccountLockedAmount(%s) failed. error=(%s)", tx.FromID, errcm)
+	}
+
+	_, restAmount := fromEnt.GetAssetAmount(DEFAULT_ASSET_ID)
+	var avail = restAmount - lockAmt
+	var amount = tx.Amount
+	if amount <= avail {
+		return amount, nil
+	}
+
+	amount = avail
+	if tx.FromID != tx.ToID {
+		fee, _, errcm := b.calcTransferFee(stub, tx.AppID, tx.TransType, DEFAULT_ASSET_ID, tx.FromID, amount)
+		if errcm != nil {
+			return 0, baselogger.ErrorECM(errcm.Code, "capAmountByBalance: calcTransferFee failed. error=(%s)", errcm)
+		}
+		amount -= fee
+	}
+	if amount < 0 {
+		amount = 0
+	}
+
+	baselogger.Info("capAmountByBalance: %s->%s %d capped to %d.", tx.FromID, tx.ToID, tx.Amount, amount)
+	return amount, nil
+}
+
 type AccountAmount struct {
 	UserName   string `json:"user"`
 	AccoutName string `json:"acc"`
@@ -3171,13 +4439,13 @@ func (b *BASE) getAccoutAmountRankingOrTopN(stub shim.ChaincodeStubInterface, us
 	var errcm *ErrorCodeMsg
 	var accEnt *AccountEntity = nil
 	if len(accName) > 0 {
-		accEnt, errcm = b.getAccountEntity(stub, accName)
+		accEnt, errcm = b.GetAccountEntity(stub, accName)
 		if errcm != nil {
-			return nil, baselogger.ErrorECM(errcm.Code, "getAccountEntity(%s) failed. error=(%s)", accName, errcm)
+			return nil, baselogger.ErrorECM(errcm.Code, "GetAccountEntity(%s) failed. error=(%s)", accName, errcm)
 		}
 	}
 
-	accsB, err := stateCache.GetState_Ex(stub, ALL_ACC_INFO_KEY)
+	accsB, err := StateCache.GetState_Ex(stub, ALL_ACC_INFO_KEY)
 	if err != nil {
 		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "GetState failed. error=(%s)", err)
 	}
@@ -3201,13 +4469,13 @@ func (b *BASE) getAccoutAmountRankingOrTopN(stub shim.ChaincodeStubInterface, us
 			}
 			acc = acc[:len(acc)-1] //去掉末尾的分隔符
 			accS = string(acc)
-			if strSliceContains(excludeAcc, accS) {
+			if StrSliceContains(excludeAcc, accS) {
 				continue
 			}
 
-			tmpEnt, errcm = b.getAccountEntity(stub, accS)
+			tmpEnt, errcm = b.GetAccountEntity(stub, accS)
 			if errcm != nil {
-				baselogger.Error("getAccountEntity(%s) failed. error=(%s)", string(acc), errcm)
+				baselogger.Error("GetAccountEntity(%s) failed. error=(%s)", string(acc), errcm)
 				continue
 			}
 			if accEnt != nil && tmpEnt.RestAmount > accEnt.RestAmount {
@@ -3258,7 +4526,7 @@ func (b *BASE) setUserInfo(stub shim.ChaincodeStubInterface, user *UserInfo) *Er
 		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "Marshal failed. error=(%s)", err)
 	}
 
-	err = stateCache.PutState_Ex(stub, b.getUserInfoKey(user.EntID), userB)
+	err = StateCache.PutState_Ex(stub, b.getUserInfoKey(user.EntID), userB)
 	if err != nil {
 		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "PutState failed. error=(%s)", err)
 	}
@@ -3267,7 +4535,7 @@ func (b *BASE) setUserInfo(stub shim.ChaincodeStubInterface, user *UserInfo) *Er
 }
 
 func (b *BASE) getUserInfo(stub shim.ChaincodeStubInterface, userName string) (*UserInfo, *ErrorCodeMsg) {
-	userB, err := stateCache.GetState_Ex(stub, b.getUserInfoKey(userName))
+	userB, err := StateCache.GetState_Ex(stub, b.getUserInfoKey(userName))
 	if err != nil {
 		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "GetState failed. error=(%s)", err)
 	}
@@ -3310,9 +4578,9 @@ func (b *BASE) updateUserInfo(stub shim.ChaincodeStubInterface, userName, pictur
 	return nil
 }
 
-func (b *BASE) isAdmin(stub shim.ChaincodeStubInterface, accName string) bool {
+func (b *BASE) IsAdmin(stub shim.ChaincodeStubInterface, accName string) bool {
 	//获取管理员帐号(央行账户作为管理员帐户)
-	tmpByte, err := b.getCenterBankAcc(stub)
+	tmpByte, err := b.GetCenterBankAcc(stub)
 	if err != nil {
 		baselogger.Error("Query getCenterBankAcc failed. error=(%s)", err)
 		return false
@@ -3325,15 +4593,3 @@ func (b *BASE) isAdmin(stub shim.ChaincodeStubInterface, accName string) bool {
 
 	return string(tmpByte) == accName
 }
-
-func main() {
-	// for debug
-	baselogger.SetDefaultLvl(shim.LogDebug)
-
-	//primitives.SetSecurityLevel("SHA3", 256)
-
-	err := shim.Start(new(BASE))
-	if err != nil {
-		baselogger.Error("Error starting EventSender chaincode: %s", err)
-	}
-}
@@ -0,0 +1,1472 @@
+package acbase
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"loulan/llcrypto"
+
+	. "loulan/ccutil"
+	. "loulan/errcode"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+func TestTransfer(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, map[string]int64{"alice": 1000, "bob": 500})
+
+	c.run([]testStep{
+		{
+			name: "transfer", user: "alice_user", function: "transefer",
+			args:  []string{"alice", "bob", "300", c.appid, "pay for milk", "shop"},
+			check: wantBalances(map[string]int64{"alice": 700, "bob": 800}),
+		},
+		{
+			name: "balance not enough", user: "alice_user", function: "transefer",
+			args:     []string{"alice", "bob", "701", c.appid},
+			wantCode: ERRCODE_TRANS_BALANCE_NOT_ENOUGH,
+			check:    wantBalances(map[string]int64{"alice": 700, "bob": 800}),
+		},
+		{
+			name: "payee not exists", user: "alice_user", function: "transefer",
+			args:     []string{"alice", "nobody", "10", c.appid},
+			wantCode: ERRCODE_TRANS_PAYEE_ACCOUNT_NOT_EXIST,
+		},
+		{
+			name: "negative amount", user: "alice_user", function: "transefer",
+			args:     []string{"alice", "bob", "-5", c.appid},
+			wantCode: ERRCODE_TRANS_AMOUNT_INVALID,
+		},
+		{
+			name: "appid not registered", user: "alice_user", function: "transefer",
+			args:     []string{"alice", "bob", "5", "noapp"},
+			wantCode: ERRCODE_COMMON_PARAM_INVALID,
+		},
+		{
+			name: "not owner", user: "bob_user", function: "transefer",
+			args:     []string{"alice", "bob", "5", c.appid},
+			wantCode: ERRCODE_COMMON_IDENTITY_VERIFY_FAILED,
+			check:    wantBalances(map[string]int64{"alice": 700, "bob": 800}),
+		},
+		{
+			name: "getBalance", user: "alice_user", function: "getBalance",
+			args: []string{"alice"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				if string(payload) != "700" {
+					t.Errorf("getBalance: want 700, got %s", string(payload))
+				}
+			},
+		},
+	})
+}
+
+func TestTransferSignature(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, map[string]int64{"alice": 1000, "bob": 500})
+
+	var alice = c.user("alice_user")
+	var bob = c.user("bob_user")
+
+	//身份正确，但签名不是账户所有者的私钥
+	resp := c.invokeAs(alice, bob, "transefer", []string{"alice", "bob", "100", c.appid}, "")
+	if code := respErrCode(resp); code != ERRCODE_COMMON_IDENTITY_VERIFY_FAILED {
+		t.Fatalf("wrong signer: want code %d, got %d, resp=%s", ERRCODE_COMMON_IDENTITY_VERIFY_FAILED, code, resp.Message)
+	}
+	c.checkInvariants("wrong signer")
+	wantBalances(map[string]int64{"alice": 1000, "bob": 500})(t, c, nil)
+
+	//控制合约关闭签名校验后，不再校验签名
+	c.ctrl.paras[CTRL_PARA_NEED_CHECK_SIGN] = "false"
+	c.mustOK(c.invokeAs(alice, bob, "transefer", []string{"alice", "bob", "100", c.appid}, ""), "transefer without sign check")
+	c.checkInvariants("sign check off")
+	wantBalances(map[string]int64{"alice": 900, "bob": 600})(t, c, nil)
+}
+
+func TestSignEnvelope(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, map[string]int64{"alice": 1000, "bob": 500})
+
+	var alice = c.user("alice_user")
+	var channel, ccName = c.stub.ChannelID, c.stub.Name
+
+	//用v1格式对signFunc和args签名，然后以transefer和submitArgs提交
+	var invokeV1 = func(signFunc string, args, submitArgs []string, channel, ccName string, expiry int64) int32 {
+		var signArgs = append([]string{alice.Name}, args...)
+		var allArgs = append([]string{alice.Name}, submitArgs...)
+		allArgs = append(allArgs, alice.signV1(t, channel, ccName, signFunc, signArgs, expiry))
+		resp := c.invokeRaw(alice, "transefer", allArgs)
+		c.checkInvariants(signFunc)
+		return respErrCode(resp)
+	}
+	var expect = func(what string, code, want int32) {
+		if code != want {
+			t.Fatalf("%s: want code %d, got %d", what, want, code)
+		}
+	}
+
+	var tr = []string{"alice", "bob", "1", c.appid, "a,b"}
+	var later = c.stub.nowMs() + 60*1000
+
+	//旧格式的账户也接受v1签名
+	expect("v1 on legacy account", invokeV1("transefer", tr, tr, channel, ccName, later), 0)
+	expect("expired", invokeV1("transefer", tr, tr, channel, ccName, c.stub.nowMs()), ERRCODE_COMMON_IDENTITY_VERIFY_FAILED)
+	expect("expiry too far", invokeV1("transefer", tr, tr, channel, ccName, c.stub.nowMs()+SIGN_MAX_VALID_PERIOD+1), ERRCODE_COMMON_PARAM_INVALID)
+	expect("other channel", invokeV1("transefer", tr, tr, "otherchannel", ccName, later), ERRCODE_COMMON_IDENTITY_VERIFY_FAILED)
+	expect("other chaincode", invokeV1("transefer", tr, tr, channel, "smk", later), ERRCODE_COMMON_IDENTITY_VERIFY_FAILED)
+	expect("other function", invokeV1("transefer3", tr, tr, channel, ccName, later), ERRCODE_COMMON_IDENTITY_VERIFY_FAILED)
+	//旧格式下这两组参数的签名消息相同
+	expect("comma in args", invokeV1("transefer", tr, []string{"alice", "bob", "1", c.appid, "a", "b"}, channel, ccName, later), ERRCODE_COMMON_IDENTITY_VERIFY_FAILED)
+	wantBalances(map[string]int64{"alice": 999, "bob": 501})(t, c, nil)
+
+	c.run([]testStep{
+		{
+			name: "invalid scheme", user: "alice_user", function: "setSignScheme",
+			args:     []string{"alice", "2"},
+			wantCode: ERRCODE_COMMON_PARAM_INVALID,
+		},
+		{
+			name: "set v1", user: "alice_user", function: "setSignScheme",
+			args: []string{"alice", fmt.Sprintf("%d", SIGN_SCHEME_CANONICAL_V1)},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				if ent := c.accountEntity("alice"); ent.SignScheme != SIGN_SCHEME_CANONICAL_V1 {
+					t.Errorf("SignScheme: want %d, got %d", SIGN_SCHEME_CANONICAL_V1, ent.SignScheme)
+				}
+			},
+		},
+		{
+			name: "legacy sign rejected", user: "alice_user", function: "transefer",
+			args:     []string{"alice", "bob", "1", c.appid},
+			wantCode: ERRCODE_COMMON_IDENTITY_VERIFY_FAILED,
+		},
+		{
+			name: "legacy sign can not switch back", user: "alice_user", function: "setSignScheme",
+			args:     []string{"alice", fmt.Sprintf("%d", SIGN_SCHEME_LEGACY)},
+			wantCode: ERRCODE_COMMON_IDENTITY_VERIFY_FAILED,
+		},
+	})
+
+	alice.SignV1 = true
+	c.mustOK(c.invoke("alice_user", "transefer", "alice", "bob", "1", c.appid), "v1 transefer")
+	c.mustOK(c.invoke("alice_user", "setSignScheme", "alice", fmt.Sprintf("%d", SIGN_SCHEME_LEGACY)), "switch back to legacy")
+
+	alice.SignV1 = false
+	c.mustOK(c.invoke("alice_user", "transefer", "alice", "bob", "1", c.appid), "legacy transefer")
+	wantBalances(map[string]int64{"alice": 997, "bob": 503})(t, c, nil)
+}
+
+func TestLockAmount(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, map[string]int64{"alice": 1000, "bob": 0})
+
+	var lockEnd = c.stub.nowMs() + int64(time.Hour/time.Millisecond)
+	var wantLocked = func(acc string, amt int64) func(t *testing.T, c *testChain, payload []byte) {
+		return func(t *testing.T, c *testChain, payload []byte) {
+			resp := c.invoke(acc+"_user", "getBalanceAndLocked", acc)
+			var qbal QueryBalanceAndLocked
+			if err := json.Unmarshal(c.mustOK(resp, "getBalanceAndLocked"), &qbal); err != nil {
+				t.Fatalf("Unmarshal QueryBalanceAndLocked failed, err=%s", err)
+			}
+			if qbal.LockedAmount != amt {
+				t.Errorf("locked amount of %s: want %d, got %d", acc, amt, qbal.LockedAmount)
+			}
+		}
+	}
+
+	c.run([]testStep{
+		{
+			name: "lock by non admin", user: "alice_user", function: "lockAccAmt",
+			args:     []string{"alice", "alice", fmt.Sprintf("600:%d", lockEnd), "0", "0"},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "lock more than rest", user: "cbuser", function: "lockAccAmt",
+			args:     []string{"cb", "alice", fmt.Sprintf("1001:%d", lockEnd), "0", "0"},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+			check:    wantLocked("alice", 0),
+		},
+		{
+			name: "lock", user: "cbuser", function: "lockAccAmt",
+			args:  []string{"cb", "alice", fmt.Sprintf("600:%d", lockEnd), "0", "0"},
+			check: wantLocked("alice", 600),
+		},
+		{
+			name: "transfer locked part", user: "alice_user", function: "transefer",
+			args:     []string{"alice", "bob", "500", c.appid},
+			wantCode: ERRCODE_TRANS_BALANCE_NOT_ENOUGH_BYLOCK,
+		},
+		{
+			name: "transfer unlocked part", user: "alice_user", function: "transefer",
+			args:  []string{"alice", "bob", "400", c.appid},
+			check: wantBalances(map[string]int64{"alice": 600, "bob": 400}),
+		},
+		{
+			name: "transfer with lock", user: "cbuser", function: "transefer3",
+			args:  []string{"cb", "bob", "300", fmt.Sprintf("200:%d", lockEnd), c.appid},
+			check: wantLocked("bob", 200),
+		},
+		{
+			name: "lock more than transfer", user: "cbuser", function: "transefer3",
+			args:     []string{"cb", "bob", "100", fmt.Sprintf("200:%d", lockEnd), c.appid},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+			check:    wantLocked("bob", 200),
+		},
+		{
+			name: "bob transfer locked part", user: "bob_user", function: "transefer",
+			args:     []string{"bob", "alice", "501", c.appid},
+			wantCode: ERRCODE_TRANS_BALANCE_NOT_ENOUGH_BYLOCK,
+		},
+		{
+			name: "lock expired", user: "alice_user", function: "transefer",
+			args:    []string{"alice", "bob", "600", c.appid},
+			advance: time.Hour,
+			check:   wantBalances(map[string]int64{"alice": 0, "bob": 1300}),
+		},
+		{
+			name: "bob lock expired", user: "bob_user", function: "transefer",
+			args:  []string{"bob", "alice", "1300", c.appid},
+			check: wantLocked("bob", 0),
+		},
+	})
+}
+
+func TestCrossChaincodePayout(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, map[string]int64{"alice": 1000, "bob": 100, "carol": 100})
+
+	var payout = c.addPayoutCC("payoutcc")
+	var setPayout = func(errcm *ErrorCodeMsg, txs ...TransferInfo) func(c *testChain) {
+		return func(c *testChain) {
+			for i := range txs {
+				txs[i].AppID = c.appid
+				txs[i].Time = c.stub.nowMs()
+			}
+			payout.transInfos = txs
+			payout.payload = []byte("settled")
+			payout.errcm = errcm
+		}
+	}
+
+	c.run([]testStep{
+		{
+			name: "payout", user: "alice_user", function: "settle", cc: "payoutcc",
+			args: []string{"alice", "order1"},
+			prepare: setPayout(nil,
+				TransferInfo{FromID: "alice", ToID: "bob", Amount: 300, TransType: "settle"},
+				TransferInfo{FromID: "alice", ToID: "carol", Amount: 200, TransType: "settle"}),
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantBalances(map[string]int64{"alice": 500, "bob": 400, "carol": 300})(t, c, payload)
+				if string(payload) != "settled" {
+					t.Errorf("payload: want settled, got %s", string(payload))
+				}
+				//被调用的合约收到的参数不包含签名和跨合约调用标志
+				var wantArgs = []string{"settle", "alice_user", "alice", "order1"}
+				if !reflect.DeepEqual(payout.calledArgs, wantArgs) {
+					t.Errorf("called args: want %v, got %v", wantArgs, payout.calledArgs)
+				}
+			},
+		},
+		{
+			name: "payout balance not enough", user: "alice_user", function: "settle", cc: "payoutcc",
+			args: []string{"alice", "order2"},
+			prepare: setPayout(nil,
+				TransferInfo{FromID: "alice", ToID: "bob", Amount: 400},
+				TransferInfo{FromID: "alice", ToID: "carol", Amount: 200}),
+			wantCode: ERRCODE_TRANS_BALANCE_NOT_ENOUGH,
+			//第一笔转账也要回滚
+			check: wantBalances(map[string]int64{"alice": 500, "bob": 400, "carol": 300}),
+		},
+		{
+			//UpToBalance的转账最多转出可用余额
+			name: "payout up to balance", user: "carol_user", function: "settle", cc: "payoutcc",
+			args: []string{"carol", "order2b"},
+			prepare: setPayout(nil,
+				TransferInfo{FromID: "carol", ToID: "bob", Amount: 250, UpToBalance: true},
+				TransferInfo{FromID: "carol", ToID: "alice", Amount: 100, UpToBalance: true}),
+			check: wantBalances(map[string]int64{"alice": 550, "bob": 650, "carol": 0}),
+		},
+		{
+			name: "payout from other account", user: "alice_user", function: "settle", cc: "payoutcc",
+			args:     []string{"alice", "order3"},
+			prepare:  setPayout(nil, TransferInfo{FromID: "bob", ToID: "alice", Amount: 50}),
+			wantCode: ERRCODE_COMMON_IDENTITY_VERIFY_FAILED,
+			check:    wantBalances(map[string]int64{"alice": 550, "bob": 650}),
+		},
+		{
+			name: "callee error", user: "alice_user", function: "settle", cc: "payoutcc",
+			args:     []string{"alice", "order4"},
+			prepare:  setPayout(NewErrorCodeMsg(ERRCODE_COMMON_CHECK_FAILED, "order closed")),
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "payout nothing", user: "bob_user", function: "query", cc: "payoutcc",
+			args:    []string{"bob"},
+			prepare: setPayout(nil),
+			check:   wantBalances(map[string]int64{"alice": 550, "bob": 650, "carol": 0}),
+		},
+	})
+}
+
+func TestInvokeFilterHook(t *testing.T) {
+	defer func() { InvokeFilterHook = nil }()
+
+	var c = newTestChain(t)
+	c.setup(1000, map[string]int64{"alice": 100, "bob": 100})
+
+	InvokeFilterHook = func(stub shim.ChaincodeStubInterface, function string, ifas *BaseInvokeArgs) *ErrorCodeMsg {
+		if function == "transefer" {
+			return NewErrorCodeMsg(ERRCODE_COMMON_CHECK_FAILED, "transefer disabled.")
+		}
+		return nil
+	}
+
+	c.run([]testStep{
+		{
+			name: "filtered", user: "alice_user", function: "transefer",
+			args:     []string{"alice", "bob", "1", c.appid},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "not filtered", user: "alice_user", function: "getBalance",
+			args: []string{"alice"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				if string(payload) != "100" {
+					t.Errorf("getBalance: want 100, got %s", string(payload))
+				}
+			},
+		},
+	})
+}
+
+func TestMultiAsset(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, map[string]int64{"alice": 1000, "bob": 500, "shop": 0})
+
+	var lockEnd = c.stub.nowMs() + int64(time.Hour/time.Millisecond)
+	var wantAsset = func(assetId string, want map[string]int64) func(t *testing.T, c *testChain, payload []byte) {
+		return func(t *testing.T, c *testChain, payload []byte) {
+			for acc, amt := range want {
+				if got := c.assetBalance(acc, assetId); got != amt {
+					t.Errorf("%s balance of %s: want %d, got %d", assetId, acc, amt, got)
+				}
+			}
+		}
+	}
+
+	c.run([]testStep{
+		{
+			name: "register by non admin", user: "shop_user", function: "registerAsset",
+			args:     []string{"shop", "pts", "PTS", "shop", "10000", "2"},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "register default asset", user: "cbuser", function: "registerAsset",
+			args:     []string{"cb", DEFAULT_ASSET_ID, "PTS", "shop", "10000", "2"},
+			wantCode: ERRCODE_COMMON_PARAM_INVALID,
+		},
+		{
+			name: "register asset", user: "cbuser", function: "registerAsset",
+			args: []string{"cb", "pts", "PTS", "shop", "10000", "2"},
+		},
+		{
+			name: "register asset again", user: "cbuser", function: "registerAsset",
+			args:     []string{"cb", "pts", "PTS", "shop", "10000", "2"},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "issue by other account", user: "cbuser", function: "issue",
+			args:     []string{"cb", "100", "pts"},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "issue over cap", user: "shop_user", function: "issue",
+			args:     []string{"shop", "10001", "pts"},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "issue", user: "shop_user", function: "issue",
+			args:  []string{"shop", "6000", "pts"},
+			check: wantAsset("pts", map[string]int64{"shop": 6000}),
+		},
+		{
+			name: "issue rest of cap", user: "shop_user", function: "issue",
+			args:     []string{"shop", "4001", "pts"},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "transfer asset", user: "shop_user", function: "transefer",
+			args: []string{"shop", "alice", "800", c.appid, "", "", "", "pts"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantAsset("pts", map[string]int64{"shop": 5200, "alice": 800})(t, c, payload)
+				//默认资产不受影响
+				wantBalances(map[string]int64{"alice": 1000, "shop": 0})(t, c, payload)
+			},
+		},
+		{
+			name: "asset not exists", user: "alice_user", function: "transefer",
+			args:     []string{"alice", "bob", "1", c.appid, "", "", "", "nopts"},
+			wantCode: ERRCODE_COMMON_PARAM_INVALID,
+		},
+		{
+			name: "asset balance not enough", user: "alice_user", function: "transefer",
+			args:     []string{"alice", "bob", "801", c.appid, "", "", "", "pts"},
+			wantCode: ERRCODE_TRANS_BALANCE_NOT_ENOUGH,
+		},
+		{
+			name: "default asset by id", user: "alice_user", function: "transefer",
+			args:  []string{"alice", "bob", "100", c.appid, "", "", "", DEFAULT_ASSET_ID},
+			check: wantBalances(map[string]int64{"alice": 900, "bob": 600}),
+		},
+		{
+			name: "lock asset", user: "cbuser", function: "lockAccAmt",
+			args: []string{"cb", "alice", fmt.Sprintf("500:%d", lockEnd), "0", "0", "pts"},
+		},
+		{
+			name: "transfer locked asset", user: "alice_user", function: "transefer",
+			args:     []string{"alice", "bob", "301", c.appid, "", "", "", "pts"},
+			wantCode: ERRCODE_TRANS_BALANCE_NOT_ENOUGH_BYLOCK,
+		},
+		{
+			//锁定的是pts，默认资产可以全部转出
+			name: "default asset not locked", user: "alice_user", function: "transefer",
+			args:  []string{"alice", "bob", "900", c.appid},
+			check: wantBalances(map[string]int64{"alice": 0, "bob": 1500}),
+		},
+		{
+			name: "transfer unlocked asset", user: "alice_user", function: "transefer",
+			args:  []string{"alice", "bob", "300", c.appid, "", "", "", "pts"},
+			check: wantAsset("pts", map[string]int64{"alice": 500, "bob": 300}),
+		},
+		{
+			name: "getBalance asset", user: "bob_user", function: "getBalance",
+			args: []string{"bob", "pts"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				if string(payload) != "300" {
+					t.Errorf("getBalance: want 300, got %s", string(payload))
+				}
+			},
+		},
+		{
+			name: "getBalanceAndLocked asset", user: "alice_user", function: "getBalanceAndLocked",
+			args: []string{"alice", "pts"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				var qbal QueryBalanceAndLocked
+				if err := json.Unmarshal(payload, &qbal); err != nil {
+					t.Fatalf("Unmarshal QueryBalanceAndLocked failed, err=%s", err)
+				}
+				if qbal.AssetID != "pts" || qbal.Balance != 500 || qbal.LockedAmount != 500 {
+					t.Errorf("getBalanceAndLocked: got %+v", qbal)
+				}
+			},
+		},
+		{
+			name: "getTransInfo by asset", user: "bob_user", function: "getTransInfo",
+			args: []string{"bob", "0", "-1", "", "", "2", "0", "-1", "asc", "-1", "pts"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				var qr QueryTransResult
+				if err := json.Unmarshal(payload, &qr); err != nil {
+					t.Fatalf("Unmarshal QueryTransResult failed, err=%s", err)
+				}
+				if len(qr.TransRecords) != 1 || qr.TransRecords[0].AssetID != "pts" || qr.TransRecords[0].Amount != 300 {
+					t.Errorf("getTransInfo: got %+v", qr.TransRecords)
+				}
+			},
+		},
+		{
+			name: "getAssetInfo", user: "bob_user", function: "getAssetInfo",
+			args: []string{"bob", ""},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				var assets []AssetInfo
+				if err := json.Unmarshal(payload, &assets); err != nil {
+					t.Fatalf("Unmarshal AssetInfo failed, err=%s", err)
+				}
+				if len(assets) != 1 || assets[0].Symbol != "PTS" || assets[0].IssuedAmount != 6000 || assets[0].Decimals != 2 {
+					t.Errorf("getAssetInfo: got %+v", assets)
+				}
+			},
+		},
+	})
+}
+
+func TestAllowance(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, map[string]int64{"alice": 1000, "shop": 0, "carol": 0})
+
+	var expiry = c.stub.nowMs() + int64(time.Hour/time.Millisecond)
+	var wantAllowance = func(acc, role string, want map[string]int64) func(t *testing.T, c *testChain, payload []byte) {
+		return func(t *testing.T, c *testChain, payload []byte) {
+			resp := c.invoke(acc+"_user", "getAllowance", acc, role)
+			var allowList []QueryAllowance
+			if err := json.Unmarshal(c.mustOK(resp, "getAllowance"), &allowList); err != nil {
+				t.Fatalf("Unmarshal QueryAllowance failed, err=%s", err)
+			}
+			var got = make(map[string]int64)
+			for _, al := range allowList {
+				got[al.Owner+">"+al.Spender] = al.Amount
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("getAllowance(%s,%s): want %v, got %v", acc, role, want, got)
+			}
+		}
+	}
+
+	c.run([]testStep{
+		{
+			name: "approve", user: "alice_user", function: "approve",
+			args:  []string{"alice", "shop", "300", fmt.Sprintf("%d", expiry), c.appid},
+			check: wantAllowance("alice", "owner", map[string]int64{"alice>shop": 300}),
+		},
+		{
+			name: "approve unknown spender", user: "alice_user", function: "approve",
+			args:     []string{"alice", "nobody", "300", "0", c.appid},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "transferFrom", user: "shop_user", function: "transferFrom",
+			args: []string{"shop", "alice", "carol", "100"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantBalances(map[string]int64{"alice": 900, "carol": 100, "shop": 0})(t, c, payload)
+				wantAllowance("shop", "spender", map[string]int64{"alice>shop": 200})(t, c, payload)
+			},
+		},
+		{
+			name: "transferFrom over allowance", user: "shop_user", function: "transferFrom",
+			args:     []string{"shop", "alice", "shop", "201"},
+			wantCode: ERRCODE_TRANS_ALLOWANCE_NOT_ENOUGH,
+			check:    wantBalances(map[string]int64{"alice": 900, "shop": 0}),
+		},
+		{
+			name: "transferFrom without allowance", user: "carol_user", function: "transferFrom",
+			args:     []string{"carol", "alice", "carol", "1"},
+			wantCode: ERRCODE_TRANS_ALLOWANCE_NOT_ENOUGH,
+		},
+		{
+			name: "transferFrom signed by other", user: "carol_user", function: "transferFrom",
+			args:     []string{"shop", "alice", "carol", "1"},
+			wantCode: ERRCODE_COMMON_IDENTITY_VERIFY_FAILED,
+		},
+		{
+			name: "transferFrom use up", user: "shop_user", function: "transferFrom",
+			args: []string{"shop", "alice", "shop", "200"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantBalances(map[string]int64{"alice": 700, "shop": 200})(t, c, payload)
+				wantAllowance("alice", "owner", map[string]int64{})(t, c, payload)
+				wantAllowance("shop", "spender", map[string]int64{})(t, c, payload)
+			},
+		},
+		{
+			name: "approve again", user: "alice_user", function: "approve",
+			args: []string{"alice", "shop", "50", fmt.Sprintf("%d", expiry), c.appid},
+		},
+		{
+			name: "revoke", user: "alice_user", function: "revokeAllowance",
+			args:  []string{"alice", "shop"},
+			check: wantAllowance("shop", "spender", map[string]int64{}),
+		},
+		{
+			name: "transferFrom after revoke", user: "shop_user", function: "transferFrom",
+			args:     []string{"shop", "alice", "shop", "10"},
+			wantCode: ERRCODE_TRANS_ALLOWANCE_NOT_ENOUGH,
+		},
+		{
+			name: "approve with expiry", user: "alice_user", function: "approve",
+			args: []string{"alice", "carol", "50", fmt.Sprintf("%d", expiry), c.appid},
+		},
+		{
+			name: "transferFrom expired", user: "carol_user", function: "transferFrom",
+			args:     []string{"carol", "alice", "carol", "10"},
+			advance:  time.Hour,
+			wantCode: ERRCODE_TRANS_ALLOWANCE_NOT_ENOUGH,
+			check:    wantAllowance("alice", "owner", map[string]int64{}),
+		},
+	})
+}
+
+func TestEncryptedMemo(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, map[string]int64{"alice": 1000, "bob": 0})
+
+	var alice = c.user("alice_user")
+	var bob = c.user("bob_user")
+	var pubKey = func(u *testUser) string { return base64.StdEncoding.EncodeToString(u.PubKey) }
+
+	var memo = "order 0001, tel 138****0000"
+	var encMemo string
+
+	c.run([]testStep{
+		{
+			name: "set other's pubkey", user: "alice_user", function: "setPubKey",
+			args:     []string{"alice", pubKey(bob)},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "set pubkey", user: "alice_user", function: "setPubKey",
+			args: []string{"alice", pubKey(alice)},
+		},
+		{
+			name: "bob set pubkey", user: "bob_user", function: "setPubKey",
+			args: []string{"bob", pubKey(bob)},
+		},
+		{
+			//付款方从链上查询收款方的公钥，加密备注
+			name: "getPubKey", user: "alice_user", function: "getPubKey",
+			args: []string{"alice", "bob"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				bobPub, err := base64.StdEncoding.DecodeString(string(payload))
+				if err != nil {
+					t.Fatalf("decode pubkey failed, err=%s", err)
+				}
+				encMemo, err = llcrypto.EncryptMemo([]byte(memo), alice.PubKey, bobPub)
+				if err != nil {
+					t.Fatalf("EncryptMemo failed, err=%s", err)
+				}
+			},
+		},
+		{
+			name: "memo too long", user: "alice_user", function: "transefer",
+			args:     []string{"alice", "bob", "100", c.appid, "pay", "", "", "", strings.Repeat("x", ENC_MEMO_MAX_LEN+1)},
+			wantCode: ERRCODE_COMMON_PARAM_INVALID,
+		},
+	})
+
+	//encMemo在上一步的check中才生成，所以这里单独执行
+	c.mustOK(c.invoke("alice_user", "transefer", "alice", "bob", "50", c.appid, "pay", "", "", "", encMemo), "transefer with memo")
+
+	for _, u := range []*testUser{alice, bob} {
+		var acc = strings.TrimSuffix(u.Name, "_user")
+		resp := c.invoke(u.Name, "getTransInfo", acc, "0", "1", "")
+		var qr QueryTransResult
+		if err := json.Unmarshal(c.mustOK(resp, "getTransInfo"), &qr); err != nil {
+			t.Fatalf("Unmarshal QueryTransResult failed, err=%s", err)
+		}
+		if len(qr.TransRecords) != 1 || qr.TransRecords[0].EncMemo != encMemo {
+			t.Fatalf("getTransInfo(%s): encrypted memo not returned, %+v", acc, qr.TransRecords)
+		}
+		got, err := llcrypto.DecryptMemo(qr.TransRecords[0].EncMemo, u.SecKey)
+		if err != nil || string(got) != memo {
+			t.Errorf("DecryptMemo by %s: got %s, err=%v", acc, got, err)
+		}
+	}
+}
+
+func TestTransferFee(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, map[string]int64{"alice": 10000, "bob": 0, "feeacc": 0})
+
+	var preCheck = func(amount, transType string, want TransPreCheckResult) func(t *testing.T, c *testChain, payload []byte) {
+		return func(t *testing.T, c *testChain, payload []byte) {
+			resp := c.invoke("alice_user", "transPreCheck", "alice", "bob", "", amount, "", c.appid, transType)
+			var got TransPreCheckResult
+			if err := json.Unmarshal(c.mustOK(resp, "transPreCheck"), &got); err != nil {
+				t.Fatalf("Unmarshal TransPreCheckResult failed, err=%s", err)
+			}
+			if got != want {
+				t.Errorf("transPreCheck(%s): want %+v, got %+v", amount, want, got)
+			}
+		}
+	}
+
+	c.run([]testStep{
+		{
+			name: "not admin", user: "alice_user", function: "setFeeRule",
+			args:     []string{"alice", c.appid, "", "feeacc", "1", "100", "2", "50", "10"},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "invalid rate", user: "cbuser", function: "setFeeRule",
+			args:     []string{"cb", c.appid, "", "feeacc", "1", "10001", "2", "50", "10"},
+			wantCode: ERRCODE_COMMON_PARAM_INVALID,
+		},
+		{
+			name: "fee account not exists", user: "cbuser", function: "setFeeRule",
+			args:     []string{"cb", c.appid, "", "nobody", "1", "100", "2", "50", "10"},
+			wantCode: ERRCODE_COMMON_PARAM_INVALID,
+		},
+		{
+			//固定1，比例1%，比例部分最少2最多50，10以内免费
+			name: "set default rule", user: "cbuser", function: "setFeeRule",
+			args: []string{"cb", c.appid, "", "feeacc", "1", "100", "2", "50", "10"},
+		},
+		{
+			name: "free tier", user: "alice_user", function: "transefer",
+			args:  []string{"alice", "bob", "10", c.appid},
+			check: wantBalances(map[string]int64{"alice": 9990, "bob": 10, "feeacc": 0}),
+		},
+		{
+			name: "min fee", user: "alice_user", function: "transefer",
+			args:  []string{"alice", "bob", "100", c.appid},
+			check: wantBalances(map[string]int64{"alice": 9887, "bob": 110, "feeacc": 3}),
+		},
+		{
+			name: "rate fee", user: "alice_user", function: "transefer",
+			args:  []string{"alice", "bob", "1000", c.appid},
+			check: wantBalances(map[string]int64{"alice": 8876, "bob": 1110, "feeacc": 14}),
+		},
+		{
+			name: "precheck", user: "alice_user", function: "transPreCheck",
+			args: []string{"alice", "bob", "", "8000"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				//不传应用id时和以前一样只返回错误码
+				if string(payload) != "0" {
+					t.Errorf("transPreCheck: want 0, got %s", string(payload))
+				}
+				preCheck("8000", "", TransPreCheckResult{Code: 0, Fee: 51})(t, c, payload)
+				preCheck("8826", "", TransPreCheckResult{Code: ERRCODE_TRANS_BALANCE_NOT_ENOUGH, Fee: 51})(t, c, payload)
+			},
+		},
+		{
+			name: "fee makes balance not enough", user: "alice_user", function: "transefer",
+			args:     []string{"alice", "bob", "8826", c.appid},
+			wantCode: ERRCODE_TRANS_BALANCE_NOT_ENOUGH,
+		},
+		{
+			name: "set type rule", user: "cbuser", function: "setFeeRule",
+			args: []string{"cb", c.appid, "vip", "feeacc", "0", "0", "0", "0", "0"},
+		},
+		{
+			name: "type rule overrides default", user: "alice_user", function: "transefer",
+			args:  []string{"alice", "bob", "1000", c.appid, "", "vip"},
+			check: wantBalances(map[string]int64{"alice": 7876, "bob": 2110, "feeacc": 14}),
+		},
+		{
+			name: "fee account pays no fee", user: "feeacc_user", function: "transefer",
+			args:  []string{"feeacc", "bob", "14", c.appid},
+			check: wantBalances(map[string]int64{"bob": 2124, "feeacc": 0}),
+		},
+		{
+			name: "delete default rule", user: "cbuser", function: "setFeeRule",
+			args: []string{"cb", c.appid, "", "", "0", "0", "0", "0", "0"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				var fs FeeSchedule
+				json.Unmarshal(c.mustOK(c.invoke("alice_user", "getFeeSchedule", "alice", c.appid), "getFeeSchedule"), &fs)
+				if len(fs.Rules) != 1 || fs.Rules[0].TransType != "vip" {
+					t.Errorf("getFeeSchedule: want only vip rule, got %+v", fs)
+				}
+			},
+		},
+		{
+			name: "no fee after delete", user: "alice_user", function: "transefer",
+			args:  []string{"alice", "bob", "100", c.appid},
+			check: wantBalances(map[string]int64{"alice": 7776, "bob": 2224, "feeacc": 0}),
+		},
+	})
+
+	//手续费记录在转账交易上，且另有手续费交易记录
+	var feeOnTrans, feeRecords int64
+	for k, v := range c.stub.State {
+		if !strings.HasPrefix(k, TRANSINFO_PREFIX) {
+			continue
+		}
+		var tx Transaction
+		if err := json.Unmarshal(v, &tx); err != nil {
+			t.Fatalf("Unmarshal Transaction failed, err=%s", err)
+		}
+		if tx.TransFlag != TRANS_PAY {
+			continue
+		}
+		if tx.TransType == FEE_TRANS_TYPE {
+			feeRecords += tx.Amount
+		} else {
+			feeOnTrans += tx.Fee
+		}
+	}
+	if feeOnTrans != 14 || feeRecords != 14 {
+		t.Errorf("fee records: want 14/14, got %d/%d", feeOnTrans, feeRecords)
+	}
+}
+
+func TestRefund(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, map[string]int64{"alice": 1000, "bob": 0, "carol": 0})
+
+	c.mustOK(c.invoke("alice_user", "transefer", "alice", "bob", "300", c.appid), "transefer")
+	var txid = fmt.Sprintf("tx%d", c.txSeq)
+
+	var serials []int64
+	json.Unmarshal(c.stub.State[TXID_SERIAL_PREFIX+txid], &serials)
+	if len(serials) != 1 {
+		t.Fatalf("tx serial index: want 1 serial, got %v", serials)
+	}
+	var origSerial = serials[0]
+	var incomeSerial = fmt.Sprintf("%d", origSerial+1)
+
+	var wantRefunded = func(amount int64, count int) func(t *testing.T, c *testChain, payload []byte) {
+		return func(t *testing.T, c *testChain, payload []byte) {
+			var ri RefundInfo
+			if err := json.Unmarshal(c.mustOK(c.invoke("alice_user", "getRefundInfo", "alice", txid), "getRefundInfo"), &ri); err != nil {
+				t.Fatalf("Unmarshal RefundInfo failed, err=%s", err)
+			}
+			if ri.OrigSerial != origSerial || ri.Amount != 300 || ri.RefundedAmount != amount || len(ri.Refunds) != count {
+				t.Fatalf("getRefundInfo: want %d/%d refunded, got %+v", amount, count, ri)
+			}
+			//退款记录指向原交易
+			for _, ser := range ri.Refunds {
+				var tx Transaction
+				json.Unmarshal(c.stub.State[TRANSINFO_PREFIX+fmt.Sprintf("%d", ser)], &tx)
+				if tx.RefundOf != origSerial || tx.TransFlag != TRANS_PAY || tx.FromID != "bob" || tx.ToID != "alice" {
+					t.Errorf("refund record %d: %+v", ser, tx)
+				}
+			}
+		}
+	}
+
+	c.run([]testStep{
+		{
+			name: "payer can't refund", user: "alice_user", function: "refund",
+			args:     []string{"alice", txid, "100"},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "partial refund by payee", user: "bob_user", function: "refund",
+			args: []string{"bob", txid, "100"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantBalances(map[string]int64{"alice": 800, "bob": 200})(t, c, payload)
+				wantRefunded(100, 1)(t, c, payload)
+			},
+		},
+		{
+			name: "refund exceeded", user: "bob_user", function: "refund",
+			args:     []string{"bob", incomeSerial, "201"},
+			wantCode: ERRCODE_TRANS_REFUND_EXCEEDED,
+		},
+		{
+			name: "admin refund by income serial", user: "cbuser", function: "refund",
+			args: []string{"cb", incomeSerial, "200", "dispute"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantBalances(map[string]int64{"alice": 1000, "bob": 0})(t, c, payload)
+				wantRefunded(300, 2)(t, c, payload)
+			},
+		},
+		{
+			name: "fully refunded", user: "cbuser", function: "refund",
+			args:     []string{"cb", txid, "1"},
+			wantCode: ERRCODE_TRANS_REFUND_EXCEEDED,
+		},
+		{
+			name: "refund of refund", user: "cbuser", function: "refund",
+			args:     []string{"cb", fmt.Sprintf("%d", origSerial+2), "1"},
+			wantCode: ERRCODE_COMMON_PARAM_INVALID,
+		},
+		{
+			name: "trans not exists", user: "bob_user", function: "refund",
+			args:     []string{"bob", "no-such-tx", "1"},
+			wantCode: ERRCODE_COMMON_PARAM_INVALID,
+		},
+		{
+			name: "others can't query", user: "carol_user", function: "getRefundInfo",
+			args:     []string{"carol", txid},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+	})
+}
+
+//退款按手续费规则收取手续费：没有refund规则时使用应用的默认规则
+func TestRefundFee(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, map[string]int64{"alice": 1000, "bob": 0, "feeacc": 0})
+
+	c.mustOK(c.invoke("cbuser", "setFeeRule", "cb", c.appid, "", "feeacc", "1", "0", "0", "0", "0"), "setFeeRule")
+	c.mustOK(c.invoke("alice_user", "transefer", "alice", "bob", "300", c.appid), "transefer")
+	var txid = fmt.Sprintf("tx%d", c.txSeq)
+
+	var wantLastRefund = func(amount, fee int64) func(t *testing.T, c *testChain, payload []byte) {
+		return func(t *testing.T, c *testChain, payload []byte) {
+			var ri RefundInfo
+			json.Unmarshal(payload, &ri)
+			if len(ri.Refunds) == 0 {
+				t.Fatalf("refund: no refund serial, %+v", ri)
+			}
+			//手续费记录在退款记录之后，返回的序列号仍是退款的付款记录
+			var tx Transaction
+			json.Unmarshal(c.stub.State[TRANSINFO_PREFIX+fmt.Sprintf("%d", ri.Refunds[len(ri.Refunds)-1])], &tx)
+			if tx.TransFlag != TRANS_PAY || tx.TransType != REFUND_TRANS_TYPE || tx.Amount != amount || tx.Fee != fee {
+				t.Errorf("refund record: want pay %d fee %d, got %+v", amount, fee, tx)
+			}
+		}
+	}
+
+	c.run([]testStep{
+		{
+			name: "default rule applies to refund", user: "bob_user", function: "refund",
+			args: []string{"bob", txid, "100"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantBalances(map[string]int64{"alice": 799, "bob": 199, "feeacc": 2})(t, c, payload)
+				wantLastRefund(100, 1)(t, c, payload)
+			},
+		},
+		{
+			name: "free refund rule", user: "cbuser", function: "setFeeRule",
+			args: []string{"cb", c.appid, REFUND_TRANS_TYPE, "feeacc", "0", "0", "0", "0", "0"},
+		},
+		{
+			name: "refund rule overrides default", user: "bob_user", function: "refund",
+			args: []string{"bob", txid, "100"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantBalances(map[string]int64{"alice": 899, "bob": 99, "feeacc": 2})(t, c, payload)
+				wantLastRefund(100, 0)(t, c, payload)
+			},
+		},
+	})
+}
+
+func TestHistoryBalance(t *testing.T) {
+	var c = newTestChain(t)
+	c.ctrl.paras[CTRL_PARA_BALANCE_CKPT_INTERVAL] = "2"
+	c.setup(100000, map[string]int64{"alice": 1000, "bob": 500})
+
+	var times []int64
+	var transfer = func(from, to, amount string) {
+		c.stub.advance(time.Hour)
+		times = append(times, c.stub.nowMs())
+		c.mustOK(c.invoke(from+"_user", "transefer", from, to, amount, c.appid), "transefer")
+	}
+	transfer("alice", "bob", "100")
+	transfer("alice", "bob", "200")
+	transfer("bob", "alice", "50")
+	c.stub.advance(time.Hour)
+
+	var ms = func(v int64) string { return fmt.Sprintf("%d", v) }
+
+	var wantBalanceAt = func(acc string, at int64, want string) func(t *testing.T, c *testChain, payload []byte) {
+		return func(t *testing.T, c *testChain, payload []byte) {
+			got := c.mustOK(c.invoke(acc+"_user", "getHistoryBalance", acc, ms(at)), "getHistoryBalance")
+			if string(got) != want {
+				t.Errorf("getHistoryBalance(%s,%d): want %s, got %s", acc, at, want, got)
+			}
+		}
+	}
+
+	var checkAll = func(t *testing.T, c *testChain, payload []byte) {
+		wantBalanceAt("alice", times[0]-1, "1000")(t, c, payload)
+		wantBalanceAt("alice", times[0], "900")(t, c, payload)
+		wantBalanceAt("alice", times[1], "700")(t, c, payload)
+		wantBalanceAt("alice", times[2], "750")(t, c, payload)
+		wantBalanceAt("bob", times[1]+1, "800")(t, c, payload)
+		wantBalanceAt("bob", c.stub.nowMs(), "750")(t, c, payload)
+
+		var st AccountStatement
+		stB := c.mustOK(c.invoke("alice_user", "getStatement", "alice", ms(times[0]), ms(times[2])), "getStatement")
+		if err := json.Unmarshal(stB, &st); err != nil {
+			t.Fatalf("Unmarshal AccountStatement failed, err=%s", err)
+		}
+		if st.Opening != 1000 || st.Credits != 50 || st.Debits != 300 || st.Closing != 750 || st.TransCount != 3 {
+			t.Errorf("getStatement: got %+v", st)
+		}
+	}
+
+	c.run([]testStep{
+		{
+			name: "checkpoints written", user: "alice_user", function: "getBalance",
+			args: []string{"alice"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				if _, ok := c.stub.State[BALANCE_CKPT_PREFIX+"alice_1"]; !ok {
+					t.Errorf("no balance checkpoint for alice")
+				}
+				checkAll(t, c, payload)
+			},
+		},
+		{
+			//没有快照时从第一条记录开始重放，结果相同
+			name: "without checkpoints", user: "alice_user", function: "getBalance",
+			args: []string{"alice"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				for k := range c.stub.State {
+					if strings.HasPrefix(k, BALANCE_CKPT_PREFIX) {
+						c.stub.MockStub.DelState(k)
+					}
+				}
+				checkAll(t, c, payload)
+			},
+		},
+		{
+			name: "others can't query", user: "bob_user", function: "getHistoryBalance",
+			args:     []string{"bob", ms(times[0]), "", "alice"},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "admin query other account", user: "cbuser", function: "getHistoryBalance",
+			args: []string{"cb", ms(times[1]), "", "bob"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				if string(payload) != "800" {
+					t.Errorf("getHistoryBalance: want 800, got %s", payload)
+				}
+			},
+		},
+		{
+			name: "invalid period", user: "alice_user", function: "getStatement",
+			args:     []string{"alice", ms(times[2]), ms(times[0])},
+			wantCode: ERRCODE_COMMON_PARAM_INVALID,
+		},
+	})
+}
+
+//账户有不是由交易记录产生的余额（如迁移的数据）时，历史余额仍然正确
+func TestHistoryBalancePreExisting(t *testing.T) {
+	var c = newTestChain(t)
+	c.ctrl.paras[CTRL_PARA_BALANCE_CKPT_INTERVAL] = "2"
+	c.setup(100000, map[string]int64{"bob": 0})
+	c.openAccount("dave_user", "dave", false)
+
+	//从央行账户直接移500给dave，不记录交易
+	var move = c.accountEntity(c.cbAcc)
+	var dave = c.accountEntity("dave")
+	move.RestAmount -= 500
+	dave.RestAmount += 500
+	dave.TotalAmount += 500
+	c.stub.MockTransactionStart("migrate")
+	for _, ent := range []*AccountEntity{move, dave} {
+		entB, _ := json.Marshal(ent)
+		c.stub.MockStub.PutState(ACC_ENTITY_PREFIX+ent.EntID, entB)
+	}
+	c.stub.MockTransactionEnd("migrate")
+
+	var times []int64
+	for i := 0; i < 3; i++ {
+		c.stub.advance(time.Hour)
+		times = append(times, c.stub.nowMs())
+		c.mustOK(c.invoke("dave_user", "transefer", "dave", "bob", "100", c.appid), "transefer")
+	}
+	c.stub.advance(time.Hour)
+
+	var ms = func(v int64) string { return fmt.Sprintf("%d", v) }
+	var checkAll = func(t *testing.T, c *testChain, payload []byte) {
+		for i, want := range []string{"500", "400", "300", "200"} {
+			var at = c.stub.nowMs()
+			if i < len(times) {
+				at = times[i] - 1
+			}
+			got := c.mustOK(c.invoke("dave_user", "getHistoryBalance", "dave", ms(at)), "getHistoryBalance")
+			if string(got) != want {
+				t.Errorf("getHistoryBalance(dave,%d): want %s, got %s", at, want, got)
+			}
+		}
+
+		var st AccountStatement
+		json.Unmarshal(c.mustOK(c.invoke("dave_user", "getStatement", "dave", ms(times[0]), ms(times[1])), "getStatement"), &st)
+		if st.Opening != 500 || st.Credits != 0 || st.Debits != 200 || st.Closing != 300 || st.TransCount != 2 {
+			t.Errorf("getStatement: got %+v", st)
+		}
+	}
+
+	c.run([]testStep{
+		{
+			//第一个快照在第二笔转账之后，之前的余额从快照往前倒推
+			name: "with checkpoint", user: "dave_user", function: "getBalance",
+			args: []string{"dave"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				if _, ok := c.stub.State[BALANCE_CKPT_PREFIX+"dave_1"]; !ok {
+					t.Errorf("no balance checkpoint for dave")
+				}
+				checkAll(t, c, payload)
+			},
+		},
+		{
+			//没有快照时从当前余额往前倒推
+			name: "without checkpoints", user: "dave_user", function: "getBalance",
+			args: []string{"dave"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				for k := range c.stub.State {
+					if strings.HasPrefix(k, BALANCE_CKPT_PREFIX) {
+						c.stub.MockStub.DelState(k)
+					}
+				}
+				checkAll(t, c, payload)
+			},
+		},
+	})
+}
+
+func TestBurnAndIssueCap(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, map[string]int64{"alice": 1000, "bob": 500})
+
+	c.mustOK(c.invoke("alice_user", "burn", "alice", "300", "redeem for goods"), "burn")
+	var burnTxid = fmt.Sprintf("tx%d", c.txSeq)
+	c.checkInvariants("burn")
+
+	var wantSupply = func(cap, issued, burned int64, capChanges int) func(t *testing.T, c *testChain, payload []byte) {
+		return func(t *testing.T, c *testChain, payload []byte) {
+			var si SupplyInfo
+			if err := json.Unmarshal(c.mustOK(c.invoke("cbuser", "getSupplyInfo", "cb"), "getSupplyInfo"), &si); err != nil {
+				t.Fatalf("Unmarshal SupplyInfo failed, err=%s", err)
+			}
+			if si.IssueCap != cap || si.IssuedAmount != issued || si.BurnedAmount != burned || len(si.CapChanges) != capChanges {
+				t.Errorf("getSupplyInfo: want %d/%d/%d/%d, got %+v", cap, issued, burned, capChanges, si)
+			}
+		}
+	}
+
+	c.run([]testStep{
+		{
+			name: "burned", user: "cbuser", function: "getStatisticInfo",
+			args: []string{"cb", ""},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantBalances(map[string]int64{"alice": 700, "bob": 500})(t, c, payload)
+				wantSupply(testIssueAmountTotal, 100000-300, 300, 0)(t, c, payload)
+
+				var info struct {
+					IssueAmount     int64 `json:"issueamt"`
+					CirculateAmount int64 `json:"circulateamt"`
+					BurnedAmount    int64 `json:"burnedamt"`
+				}
+				json.Unmarshal(payload, &info)
+				if info.IssueAmount != 100000-300 || info.CirculateAmount != 1500-300 || info.BurnedAmount != 300 {
+					t.Errorf("getStatisticInfo: got %+v", info)
+				}
+			},
+		},
+		{
+			name: "balance not enough", user: "bob_user", function: "burn",
+			args:     []string{"bob", "501", "too much"},
+			wantCode: ERRCODE_TRANS_BALANCE_NOT_ENOUGH,
+		},
+		{
+			name: "reason required", user: "bob_user", function: "burn",
+			args:     []string{"bob", "1", ""},
+			wantCode: ERRCODE_COMMON_PARAM_INVALID,
+		},
+		{
+			name: "burn can't be refunded", user: "cbuser", function: "refund",
+			args:     []string{"cb", burnTxid, "100"},
+			wantCode: ERRCODE_COMMON_PARAM_INVALID,
+		},
+		{
+			name: "center bank burns", user: "cbuser", function: "burn",
+			args:  []string{"cb", "1000", "unused budget"},
+			check: wantSupply(testIssueAmountTotal, 100000-1300, 1300, 0),
+		},
+		{
+			name: "not admin", user: "alice_user", function: "setIssueCap",
+			args:     []string{"alice", "200000", "shrink"},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "cap below issued", user: "cbuser", function: "setIssueCap",
+			args:     []string{"cb", "98699", "shrink"},
+			wantCode: ERRCODE_COMMON_PARAM_INVALID,
+		},
+		{
+			name: "lower cap", user: "cbuser", function: "setIssueCap",
+			args:    []string{"cb", "200000", "shrink"},
+			prepare: func(c *testChain) { c.issueTotal = 200000 },
+			check:   wantSupply(200000, 98700, 1300, 1),
+		},
+		{
+			name: "issue over cap", user: "cbuser", function: "issue",
+			args:     []string{"cb", "101301"},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			//销毁的货币可以再次发行
+			name: "issue up to cap", user: "cbuser", function: "issue",
+			args:  []string{"cb", "101300"},
+			check: wantSupply(200000, 200000, 1300, 1),
+		},
+	})
+}
+
+func TestInvoice(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, map[string]int64{"alice": 1000, "bob": 0, "carol": 0})
+
+	var ms = func(v int64) string { return fmt.Sprintf("%d", v) }
+	var now = c.stub.nowMs()
+	c.mustOK(c.invoke("bob_user", "createInvoice", "bob", "inv-1", "300", ms(now+3600*1000), c.appid, "hash1"), "createInvoice")
+	c.mustOK(c.invoke("bob_user", "createInvoice", "bob", "inv-2", "100", ms(now+60*1000), c.appid, "hash2"), "createInvoice")
+	c.mustOK(c.invoke("bob_user", "createInvoice", "bob", "inv-3", "50", "0", c.appid, "hash3"), "createInvoice")
+
+	var wantInvoices = func(status string, ids ...string) func(t *testing.T, c *testChain, payload []byte) {
+		return func(t *testing.T, c *testChain, payload []byte) {
+			var invList []Invoice
+			json.Unmarshal(c.mustOK(c.invoke("bob_user", "getInvoicesByPayee", "bob", status), "getInvoicesByPayee"), &invList)
+			var got []string
+			for _, inv := range invList {
+				got = append(got, inv.InvoiceID)
+			}
+			if !reflect.DeepEqual(got, ids) {
+				t.Errorf("getInvoicesByPayee(%s): want %v, got %v", status, ids, got)
+			}
+		}
+	}
+
+	c.run([]testStep{
+		{
+			name: "same id of other payee", user: "carol_user", function: "createInvoice",
+			args: []string{"carol", "inv-1", "10", "0", c.appid, ""},
+		},
+		{
+			name: "duplicate id", user: "bob_user", function: "createInvoice",
+			args:     []string{"bob", "inv-1", "10", "0", c.appid, ""},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "payer queries invoice", user: "alice_user", function: "getInvoice",
+			args: []string{"alice", "bob", "inv-1"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				var inv Invoice
+				json.Unmarshal(payload, &inv)
+				if inv.Payee != "bob" || inv.Amount != 300 || inv.Status != INVOICE_STATUS_OPEN || inv.MemoHash != "hash1" {
+					t.Errorf("getInvoice: got %+v", inv)
+				}
+			},
+		},
+		{
+			name: "pay wrong amount", user: "alice_user", function: "payInvoice",
+			args:     []string{"alice", "bob", "inv-1", "30"},
+			wantCode: ERRCODE_COMMON_PARAM_INVALID,
+			check:    wantBalances(map[string]int64{"alice": 1000, "bob": 0, "carol": 0}),
+		},
+		{
+			name: "pay wrong payee", user: "alice_user", function: "payInvoice",
+			args:     []string{"alice", "carol", "inv-1", "300"},
+			wantCode: ERRCODE_COMMON_PARAM_INVALID,
+			check:    wantBalances(map[string]int64{"alice": 1000, "bob": 0, "carol": 0}),
+		},
+		{
+			name: "pay", user: "alice_user", function: "payInvoice",
+			args: []string{"alice", "bob", "inv-1", "300"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantBalances(map[string]int64{"alice": 700, "bob": 300})(t, c, payload)
+
+				var inv Invoice
+				json.Unmarshal(payload, &inv)
+				var tx Transaction
+				json.Unmarshal(c.stub.State[TRANSINFO_PREFIX+ms(inv.PaySerial)], &tx)
+				if inv.Status != INVOICE_STATUS_PAID || inv.Payer != "alice" || tx.TransFlag != TRANS_PAY || tx.FromID != "alice" || tx.ToID != "bob" || tx.TransType != INVOICE_TRANS_TYPE {
+					t.Errorf("payInvoice: invoice %+v, record %+v", inv, tx)
+				}
+			},
+		},
+		{
+			name: "pay twice", user: "alice_user", function: "payInvoice",
+			args:     []string{"alice", "bob", "inv-1", "300"},
+			wantCode: ERRCODE_TRANS_INVOICE_NOT_PAYABLE,
+			check:    wantBalances(map[string]int64{"alice": 700, "bob": 300}),
+		},
+		{
+			name: "payee pays own invoice", user: "bob_user", function: "payInvoice",
+			args:     []string{"bob", "bob", "inv-3", "50"},
+			wantCode: ERRCODE_COMMON_PARAM_INVALID,
+		},
+		{
+			name: "others can't cancel", user: "carol_user", function: "cancelInvoice",
+			args:     []string{"carol", "inv-3", "bob"},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "cancel", user: "bob_user", function: "cancelInvoice",
+			args: []string{"bob", "inv-3"},
+		},
+		{
+			name: "pay cancelled", user: "alice_user", function: "payInvoice",
+			args:     []string{"alice", "bob", "inv-3", "50"},
+			wantCode: ERRCODE_TRANS_INVOICE_NOT_PAYABLE,
+		},
+		{
+			name: "pay expired", user: "alice_user", function: "payInvoice",
+			args:     []string{"alice", "bob", "inv-2", "100"},
+			advance:  2 * time.Minute,
+			wantCode: ERRCODE_TRANS_INVOICE_NOT_PAYABLE,
+		},
+		{
+			name: "query by status", user: "bob_user", function: "getInvoicesByPayee",
+			args: []string{"bob"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantInvoices("", "inv-1", "inv-2", "inv-3")(t, c, payload)
+				wantInvoices(INVOICE_STATUS_PAID, "inv-1")(t, c, payload)
+				wantInvoices(INVOICE_STATUS_EXPIRED, "inv-2")(t, c, payload)
+				wantInvoices(INVOICE_STATUS_CANCELLED, "inv-3")(t, c, payload)
+			},
+		},
+		{
+			name: "other payee's invoice untouched", user: "carol_user", function: "getInvoice",
+			args: []string{"carol", "carol", "inv-1"},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				var inv Invoice
+				json.Unmarshal(payload, &inv)
+				if inv.Payee != "carol" || inv.Amount != 10 || inv.Status != INVOICE_STATUS_OPEN {
+					t.Errorf("getInvoice: got %+v", inv)
+				}
+			},
+		},
+		{
+			name: "others can't query payee", user: "carol_user", function: "getInvoicesByPayee",
+			args:     []string{"carol", "", "bob"},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+	})
+}
+
+func TestHtlc(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, map[string]int64{"alice": 1000, "bob": 0})
+
+	var preimage = hex.EncodeToString([]byte("swap secret"))
+	var sum = sha256.Sum256([]byte("swap secret"))
+	var hashlock = hex.EncodeToString(sum[:])
+
+	var ms = func(v int64) string { return fmt.Sprintf("%d", v) }
+	var lockHtlc = func(amount string, timeout time.Duration) string {
+		var lock HtlcLock
+		payload := c.mustOK(c.invoke("alice_user", "lockHtlc", "alice", "bob", amount, hashlock, ms(c.stub.nowMs()+int64(timeout/time.Millisecond)), c.appid), "lockHtlc")
+		json.Unmarshal(payload, &lock)
+		return lock.LockID
+	}
+	var lock1 = lockHtlc("300", time.Hour)
+	var lock2 = lockHtlc("200", time.Minute)
+
+	var wantLocked = func(balance, locked int64) func(t *testing.T, c *testChain, payload []byte) {
+		return func(t *testing.T, c *testChain, payload []byte) {
+			var qbal QueryBalanceAndLocked
+			json.Unmarshal(c.mustOK(c.invoke("alice_user", "getBalanceAndLocked", "alice"), "getBalanceAndLocked"), &qbal)
+			if qbal.Balance != balance || qbal.LockedAmount != locked || qbal.HtlcAmount != locked {
+				t.Errorf("getBalanceAndLocked: want %d/%d, got %+v", balance, locked, qbal)
+			}
+		}
+	}
+	var wantStatus = func(lockId, status string) func(t *testing.T, c *testChain, payload []byte) {
+		return func(t *testing.T, c *testChain, payload []byte) {
+			var lock HtlcLock
+			json.Unmarshal(c.mustOK(c.invoke("bob_user", "getHtlc", "bob", lockId), "getHtlc"), &lock)
+			if lock.Status != status {
+				t.Errorf("getHtlc(%s): want %s, got %+v", lockId, status, lock)
+			}
+			if status == HTLC_STATUS_CLAIMED && lock.Preimage != preimage {
+				t.Errorf("getHtlc(%s): preimage not revealed, got %+v", lockId, lock)
+			}
+		}
+	}
+
+	c.run([]testStep{
+		{
+			name: "locked", user: "alice_user", function: "getBalance",
+			args:  []string{"alice"},
+			check: wantLocked(1000, 500),
+		},
+		{
+			name: "transfer locked amount", user: "alice_user", function: "transefer",
+			args:     []string{"alice", "bob", "501", c.appid},
+			wantCode: ERRCODE_TRANS_BALANCE_NOT_ENOUGH_BYLOCK,
+		},
+		{
+			name: "invalid hashlock", user: "alice_user", function: "lockHtlc",
+			args:     []string{"alice", "bob", "10", "abcd", ms(c.stub.nowMs() + 3600*1000), c.appid},
+			wantCode: ERRCODE_COMMON_PARAM_INVALID,
+		},
+		{
+			name: "payer can't claim", user: "alice_user", function: "claimHtlc",
+			args:     []string{"alice", lock1, preimage},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "wrong preimage", user: "bob_user", function: "claimHtlc",
+			args:     []string{"bob", lock1, hex.EncodeToString([]byte("guess"))},
+			wantCode: ERRCODE_COMMON_IDENTITY_VERIFY_FAILED,
+		},
+		{
+			name: "refund before timeout", user: "alice_user", function: "refundHtlc",
+			args:     []string{"alice", lock1},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "claim", user: "bob_user", function: "claimHtlc",
+			args: []string{"bob", lock1, preimage},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantBalances(map[string]int64{"alice": 700, "bob": 300})(t, c, payload)
+				wantLocked(700, 200)(t, c, payload)
+				wantStatus(lock1, HTLC_STATUS_CLAIMED)(t, c, payload)
+			},
+		},
+		{
+			name: "claim twice", user: "bob_user", function: "claimHtlc",
+			args:     []string{"bob", lock1, preimage},
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "claim after timeout", user: "bob_user", function: "claimHtlc",
+			args:     []string{"bob", lock2, preimage},
+			advance:  2 * time.Minute,
+			wantCode: ERRCODE_COMMON_CHECK_FAILED,
+		},
+		{
+			name: "refund after timeout", user: "alice_user", function: "refundHtlc",
+			args: []string{"alice", lock2},
+			check: func(t *testing.T, c *testChain, payload []byte) {
+				wantLocked(700, 0)(t, c, payload)
+				wantStatus(lock2, HTLC_STATUS_REFUNDED)(t, c, payload)
+			},
+		},
+	})
+}
+
+//领取时收取的手续费不超过创建时锁定的手续费
+func TestHtlcFee(t *testing.T) {
+	var c = newTestChain(t)
+	c.setup(100000, map[string]int64{"alice": 1000, "bob": 0, "feeacc": 0})
+
+	var preimage = hex.EncodeToString([]byte("swap secret"))
+	var sum = sha256.Sum256([]byte("swap secret"))
+	var hashlock = hex.EncodeToString(sum[:])
+
+	var ms = func(v int64) string { return fmt.Sprintf("%d", v) }
+	var setFlatFee = func(feeAcc, fee string) {
+		c.mustOK(c.invoke("cbuser", "setFeeRule", "cb", c.appid, "", feeAcc, fee, "0", "0", "0", "0"), "setFeeRule")
+	}
+	var lockHtlc = func(amount string) string {
+		var lock HtlcLock
+		payload := c.mustOK(c.invoke("alice_user", "lockHtlc", "alice", "bob", amount, hashlock, ms(c.stub.nowMs()+3600*1000), c.appid), "lockHtlc")
+		json.Unmarshal(payload, &lock)
+		if lock.Fee != 5 {
+			t.Fatalf("lockHtlc: want fee 5, got %+v", lock)
+		}
+		return lock.LockID
+	}
+
+	setFlatFee("feeacc", "5")
+	var lock1 = lockHtlc("300")
+	var lock2 = lockHtlc("200")
+
+	c.run([]testStep{
+		{
+			name: "fee raised", user: "cbuser", function: "setFeeRule",
+			args: []string{"cb", c.appid, "", "feeacc", "500", "0", "0", "0", "0"},
+		},
+		{
+			name: "claim charges locked fee", user: "bob_user", function: "claimHtlc",
+			args:  []string{"bob", lock1, preimage},
+			check: wantBalances(map[string]int64{"alice": 695, "bob": 300, "feeacc": 5}),
+		},
+		{
+			name: "fee lowered", user: "cbuser", function: "setFeeRule",
+			args: []string{"cb", c.appid, "", "feeacc", "1", "0", "0", "0", "0"},
+		},
+		{
+			name: "claim charges current fee", user: "bob_user", function: "claimHtlc",
+			args:  []string{"bob", lock2, preimage},
+			check: wantBalances(map[string]int64{"alice": 494, "bob": 500, "feeacc": 6}),
+		},
+	})
+}
+
+func TestSetModuleName(t *testing.T) {
+	defer SetModuleName("acsys")
+
+	SetModuleName("smk")
+	if ACC_ENTITY_PREFIX != "!smk@accEntPre~" || CENTERBANK_ACC_KEY != "!smk@centerBankAccKey@!" {
+		t.Errorf("key prefix not changed: %s %s", ACC_ENTITY_PREFIX, CENTERBANK_ACC_KEY)
+	}
+
+	//扩展合约的账户数据以自己的模块名为前缀保存
+	var c = newTestChain(t)
+	c.setup(1000, map[string]int64{"alice": 100})
+	if _, ok := c.stub.State["!smk@accEntPre~alice"]; !ok {
+		t.Errorf("account of alice not saved with module prefix")
+	}
+}
@@ -0,0 +1,20 @@
+package acbase
+
+import (
+	. "loulan/mylog"
+)
+
+//流程控制。运行时从控制合约sysctrlcc读取，控制合约未部署或者没有该参数时使用下面的默认值
+const (
+	Ctrl_isTestChain         = false //是否是测试链。
+	Ctrl_needCheckSign       = true  //是否检查签名。
+	Ctrl_needCheckIndentity  = true  //是否检查用户身份。
+	Ctrl_balanceCkptInterval = 100   //每隔多少条交易记录保存一次账户余额快照，0表示不保存。
+)
+
+func LogCtrlParameter(logger *MYLOG) {
+	logger.Info("default Ctrl_isTestChain        = %v", Ctrl_isTestChain)
+	logger.Info("default Ctrl_needCheckSign      = %v", Ctrl_needCheckSign)
+	logger.Info("default Ctrl_needCheckIndentity = %v", Ctrl_needCheckIndentity)
+	logger.Info("default Ctrl_balanceCkptInterval = %v", Ctrl_balanceCkptInterval)
+}
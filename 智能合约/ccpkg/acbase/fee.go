@@ -0,0 +1,214 @@
+package acbase
+
+import (
+	"encoding/json"
+
+	. "loulan/errcode"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	FEE_RATE_BP_DENOM = 10000 //比例手续费的单位为万分之一
+	FEE_TRANS_TYPE    = "fee" //手续费交易记录的交易类型
+)
+
+//手续费规则，由管理员按应用和交易类型配置。转账时付款方在转账金额之外另付手续费，记入手续费账户。
+//目前只对默认资产的转账收取手续费
+type FeeRule struct {
+	TransType  string `json:"tstp"` //交易类型，空表示该应用下没有单独配置规则的交易类型都使用此规则
+	FeeAccount string `json:"facc"` //手续费收款账户
+	FlatFee    int64  `json:"flat"` //固定手续费
+	RateBP     int64  `json:"bp"`   //比例手续费，单位万分之一
+	MinFee     int64  `json:"min"`  //比例手续费的下限
+	MaxFee     int64  `json:"max"`  //比例手续费的上限，0表示不限
+	FreeAmount int64  `json:"free"` //免费档，转账金额不超过此值时不收手续费
+	Time       int64  `json:"time"` //设置时间
+}
+
+//一个应用的所有手续费规则
+type FeeSchedule struct {
+	AppID string    `json:"app"`
+	Rules []FeeRule `json:"rules"`
+}
+
+//transPreCheck传入应用id时的返回结果
+type TransPreCheckResult struct {
+	Code int32 `json:"code"` //0表示检查通过，否则为错误码
+	Fee  int64 `json:"fee"`  //需要另付的手续费
+}
+
+//计算手续费
+func (r *FeeRule) CalcFee(amount int64) int64 {
+	if amount <= r.FreeAmount {
+		return 0
+	}
+
+	var rateFee int64
+	if r.RateBP > 0 {
+		//先除后乘，避免溢出
+		rateFee = amount/FEE_RATE_BP_DENOM*r.RateBP + amount%FEE_RATE_BP_DENOM*r.RateBP/FEE_RATE_BP_DENOM
+		if rateFee < r.MinFee {
+			rateFee = r.MinFee
+		}
+		if r.MaxFee > 0 && rateFee > r.MaxFee {
+			rateFee = r.MaxFee
+		}
+	}
+
+	return r.FlatFee + rateFee
+}
+
+func (b *BASE) getFeeScheduleKey(appid string) string {
+	return FEE_SCHEDULE_PREFIX + appid
+}
+
+func (b *BASE) getFeeSchedule(stub shim.ChaincodeStubInterface, appid string) (*FeeSchedule, *ErrorCodeMsg) {
+	var fs FeeSchedule
+	fs.AppID = appid
+	fs.Rules = []FeeRule{}
+
+	fsB, err := StateCache.GetState_Ex(stub, b.getFeeScheduleKey(appid))
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getFeeSchedule: GetState failed. error=(%s)", err)
+	}
+	if fsB == nil {
+		return &fs, nil
+	}
+
+	err = json.Unmarshal(fsB, &fs)
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getFeeSchedule: Unmarshal failed. error=(%s)", err)
+	}
+
+	return &fs, nil
+}
+
+func (b *BASE) setFeeSchedule(stub shim.ChaincodeStubInterface, fs *FeeSchedule) *ErrorCodeMsg {
+	fsB, err := json.Marshal(fs)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setFeeSchedule: Marshal failed. error=(%s)", err)
+	}
+
+	err = StateCache.PutState_Ex(stub, b.getFeeScheduleKey(fs.AppID), fsB)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setFeeSchedule: PutState failed. error=(%s)", err)
+	}
+
+	return nil
+}
+
+//设置应用的一条手续费规则，同一交易类型的规则会被覆盖。手续费账户为空时删除该规则
+func (b *BASE) setFeeRule(stub shim.ChaincodeStubInterface, appid string, rule *FeeRule) *ErrorCodeMsg {
+	if rule.FlatFee < 0 || rule.MinFee < 0 || rule.MaxFee < 0 || rule.FreeAmount < 0 {
+		return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "setFeeRule: negative value in rule(%+v).", *rule)
+	}
+	if rule.RateBP < 0 || rule.RateBP > FEE_RATE_BP_DENOM {
+		return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "setFeeRule: invalid rate(%d).", rule.RateBP)
+	}
+	if rule.MaxFee > 0 && rule.MaxFee < rule.MinFee {
+		return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "setFeeRule: max fee(%d) less than min fee(%d).", rule.MaxFee, rule.MinFee)
+	}
+
+	var isDelete = len(rule.FeeAccount) == 0
+
+	if !isDelete {
+		exists, errcm := b.IsAccEntityExists(stub, rule.FeeAccount)
+		if errcm != nil {
+			return baselogger.ErrorECM(errcm.Code, "setFeeRule: IsAccEntityExists failed. error=(%s)", errcm)
+		}
+		if !exists {
+			return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "setFeeRule: fee account(%s) not exists.", rule.FeeAccount)
+		}
+	}
+
+	fs, errcm := b.getFeeSchedule(stub, appid)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "setFeeRule: getFeeSchedule failed. error=(%s)", errcm)
+	}
+
+	var newRules = []FeeRule{}
+	for _, r := range fs.Rules {
+		if r.TransType != rule.TransType {
+			newRules = append(newRules, r)
+		}
+	}
+	if !isDelete {
+		newRules = append(newRules, *rule)
+	}
+	fs.Rules = newRules
+
+	errcm = b.setFeeSchedule(stub, fs)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "setFeeRule: setFeeSchedule failed. error=(%s)", errcm)
+	}
+
+	return nil
+}
+
+//查找适用的手续费规则，优先使用交易类型完全匹配的规则，其次使用交易类型为空的规则。没有规则时返回nil
+func (b *BASE) getFeeRule(stub shim.ChaincodeStubInterface, appid, transType string) (*FeeRule, *ErrorCodeMsg) {
+	fs, errcm := b.getFeeSchedule(stub, appid)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "getFeeRule: getFeeSchedule failed. error=(%s)", errcm)
+	}
+
+	var defRule *FeeRule
+	for i := range fs.Rules {
+		if fs.Rules[i].TransType == transType {
+			return &fs.Rules[i], nil
+		}
+		if len(fs.Rules[i].TransType) == 0 {
+			defRule = &fs.Rules[i]
+		}
+	}
+
+	return defRule, nil
+}
+
+//计算一笔转账的手续费，返回手续费和手续费账户。手续费账户自己转出时不收
+func (b *BASE) calcTransferFee(stub shim.ChaincodeStubInterface, appid, transType, assetId, from string, amount int64) (int64, string, *ErrorCodeMsg) {
+	if !IsDefaultAsset(assetId) || len(appid) == 0 {
+		return 0, "", nil
+	}
+
+	rule, errcm := b.getFeeRule(stub, appid, transType)
+	if errcm != nil {
+		return 0, "", baselogger.ErrorECM(errcm.Code, "calcTransferFee: getFeeRule failed. error=(%s)", errcm)
+	}
+	if rule == nil || rule.FeeAccount == from {
+		return 0, "", nil
+	}
+
+	return rule.CalcFee(amount), rule.FeeAccount, nil
+}
+
+//把付款方已扣除的手续费记入手续费账户，并记录交易。fromEnt的余额在转账时已经扣除了手续费
+func (b *BASE) chargeTransferFee(stub shim.ChaincodeStubInterface, fromEnt *AccountEntity, feeAcc, assetId, to string, fee, transTime int64, appid string) *ErrorCodeMsg {
+	//手续费账户可能就是收款账户，所以重新获取一次
+	feeEnt, errcm := b.GetAccountEntity(stub, feeAcc)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "chargeTransferFee: GetAccountEntity(%s) failed. error=(%s)", feeAcc, errcm)
+	}
+
+	feeEnt.AddAssetAmount(assetId, fee)
+
+	errcm = b.setAccountEntity(stub, feeEnt)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "chargeTransferFee: setAccountEntity(%s) failed. error=(%s)", feeAcc, errcm)
+	}
+
+	var description = "fee for transfer to " + to
+
+	_, errcm = b.recordTranse(stub, fromEnt, feeEnt, TRANS_PAY, FEE_TRANS_TYPE, description, "", fee, transTime, appid, assetId, 0, 0)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "chargeTransferFee: recordTranse failed. error=(%s)", errcm)
+	}
+
+	_, errcm = b.recordTranse(stub, feeEnt, fromEnt, TRANS_INCOME, FEE_TRANS_TYPE, description, "", fee, transTime, appid, assetId, 0, 0)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "chargeTransferFee: recordTranse failed. error=(%s)", errcm)
+	}
+
+	return nil
+}
@@ -0,0 +1,337 @@
+package acbase
+
+import (
+	"encoding/json"
+	"strconv"
+
+	. "loulan/errcode"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//历史余额和对账单都是按账户的交易记录（见setOneAccTransInfo）从某个已知余额开始重放计算的，这里假设账户交易记录的时间随序列号递增。
+//为了不每次都重放所有记录，转账时每隔一定数量的记录保存一次账户余额快照，间隔由控制合约配置，0表示不保存
+
+//账户余额快照
+type BalanceCheckpoint struct {
+	Seq      int64            `json:"seq"`  //快照时账户交易记录的序列号，快照包含这条记录
+	Time     int64            `json:"time"` //快照时间
+	Balances map[string]int64 `json:"bal"`  //各资产的余额，默认资产的key为DEFAULT_ASSET_ID
+}
+
+//账户的快照数量和最后一个快照的序列号
+type BalanceCheckpointMeta struct {
+	Count   int64 `json:"cnt"`
+	LastSeq int64 `json:"lseq"`
+}
+
+//对账单
+type AccountStatement struct {
+	AccName    string `json:"acc"`
+	AssetID    string `json:"asset"`
+	BeginTime  int64  `json:"beg"`
+	EndTime    int64  `json:"end"`
+	Opening    int64  `json:"open"`  //期初余额，即BeginTime之前的余额
+	Credits    int64  `json:"cr"`    //期间收入合计
+	Debits     int64  `json:"dr"`    //期间支出合计，包括手续费
+	Closing    int64  `json:"close"` //期末余额
+	TransCount int64  `json:"cnt"`   //期间的交易记录数
+}
+
+func (b *BASE) getBalanceCkptMetaKey(accName string) string {
+	return BALANCE_CKPT_PREFIX + accName
+}
+
+func (b *BASE) getBalanceCkptKey(accName string, idx int64) string {
+	return BALANCE_CKPT_PREFIX + accName + "_" + strconv.FormatInt(idx, 10)
+}
+
+func (b *BASE) getBalanceCkptMeta(stub shim.ChaincodeStubInterface, accName string) (*BalanceCheckpointMeta, *ErrorCodeMsg) {
+	var meta BalanceCheckpointMeta
+
+	metaB, err := StateCache.GetState_Ex(stub, b.getBalanceCkptMetaKey(accName))
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getBalanceCkptMeta: GetState failed. error=(%s)", err)
+	}
+	if metaB == nil {
+		return &meta, nil
+	}
+
+	err = json.Unmarshal(metaB, &meta)
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getBalanceCkptMeta: Unmarshal failed. error=(%s)", err)
+	}
+
+	return &meta, nil
+}
+
+func (b *BASE) getBalanceCkpt(stub shim.ChaincodeStubInterface, accName string, idx int64) (*BalanceCheckpoint, *ErrorCodeMsg) {
+	ckptB, err := StateCache.GetState_Ex(stub, b.getBalanceCkptKey(accName, idx))
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getBalanceCkpt: GetState failed. error=(%s)", err)
+	}
+	if ckptB == nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_INNER_ERROR, "getBalanceCkpt: checkpoint %s_%d not exists.", accName, idx)
+	}
+
+	var ckpt BalanceCheckpoint
+	err = json.Unmarshal(ckptB, &ckpt)
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getBalanceCkpt: Unmarshal failed. error=(%s)", err)
+	}
+
+	return &ckpt, nil
+}
+
+//转账结束后调用，距上次快照的交易记录数达到间隔时保存一次快照。此时账户的余额和交易记录是一致的
+func (b *BASE) updateBalanceCheckpoint(stub shim.ChaincodeStubInterface, accName string, currTime int64) *ErrorCodeMsg {
+	var interval = b.balanceCkptInterval(stub)
+	if interval <= 0 {
+		return nil
+	}
+
+	meta, errcm := b.getBalanceCkptMeta(stub, accName)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "updateBalanceCheckpoint: getBalanceCkptMeta failed. error=(%s)", errcm)
+	}
+
+	seq, errcm := b.GetTransSeq(stub, b.getAccTransSeqKey(accName))
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "updateBalanceCheckpoint: GetTransSeq failed. error=(%s)", errcm)
+	}
+	if seq-meta.LastSeq < interval {
+		return nil
+	}
+
+	ent, errcm := b.GetAccountEntity(stub, accName)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "updateBalanceCheckpoint: GetAccountEntity(%s) failed. error=(%s)", accName, errcm)
+	}
+
+	var ckpt BalanceCheckpoint
+	ckpt.Seq = seq
+	ckpt.Time = currTime
+	ckpt.Balances = map[string]int64{DEFAULT_ASSET_ID: ent.RestAmount}
+	for assetId, aa := range ent.AssetAmounts {
+		ckpt.Balances[assetId] = aa.RestAmount
+	}
+
+	meta.Count++
+	meta.LastSeq = seq
+
+	ckptB, err := json.Marshal(ckpt)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "updateBalanceCheckpoint: Marshal failed. error=(%s)", err)
+	}
+	err = StateCache.PutState_Ex(stub, b.getBalanceCkptKey(accName, meta.Count), ckptB)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "updateBalanceCheckpoint: PutState failed. error=(%s)", err)
+	}
+
+	metaB, err := json.Marshal(meta)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "updateBalanceCheckpoint: Marshal failed. error=(%s)", err)
+	}
+	err = StateCache.PutState_Ex(stub, b.getBalanceCkptMetaKey(accName), metaB)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "updateBalanceCheckpoint: PutState failed. error=(%s)", err)
+	}
+
+	return nil
+}
+
+//二分查找时间早于queryTime的最后一个快照，没有时返回nil
+func (b *BASE) findBalanceCheckpoint(stub shim.ChaincodeStubInterface, accName string, queryTime int64) (*BalanceCheckpoint, *ErrorCodeMsg) {
+	meta, errcm := b.getBalanceCkptMeta(stub, accName)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "findBalanceCheckpoint: getBalanceCkptMeta failed. error=(%s)", errcm)
+	}
+
+	var found *BalanceCheckpoint
+	var low, high = int64(1), meta.Count
+	for low <= high {
+		var mid = (low + high) / 2
+		ckpt, errcm := b.getBalanceCkpt(stub, accName, mid)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "findBalanceCheckpoint: getBalanceCkpt failed. error=(%s)", errcm)
+		}
+		if ckpt.Time < queryTime {
+			found = ckpt
+			low = mid + 1
+		} else {
+			high = mid - 1
+		}
+	}
+
+	return found, nil
+}
+
+//对账单最多读取的交易记录数，超过时需要缩短查询区间
+const STATEMENT_MAX_SCAN_COUNT = 10000
+
+//计算begTime之前的余额，以及[begTime, endTime]内的收入和支出。
+//begTime之前有快照时，从该快照开始往后重放交易记录；否则从begTime之后的第一个快照（没有快照时为账户当前余额）往前倒推。
+//账户可能有不是由交易记录产生的余额（如迁移的数据、测试链的recharge），所以没有快照时不能从0开始往后重放
+func (b *BASE) getAccountStatement(stub shim.ChaincodeStubInterface, accName, assetId string, begTime, endTime int64) (*AccountStatement, *ErrorCodeMsg) {
+	var st AccountStatement
+	st.AccName = accName
+	st.AssetID = assetId
+	if IsDefaultAsset(assetId) {
+		st.AssetID = DEFAULT_ASSET_ID
+	}
+	st.BeginTime = begTime
+	st.EndTime = endTime
+
+	maxSeq, errcm := b.GetTransSeq(stub, b.getAccTransSeqKey(accName))
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "getAccountStatement: GetTransSeq failed. error=(%s)", errcm)
+	}
+
+	//读取账户的第seq条交易记录，不存在或者不是要查询的资产时返回nil
+	var scanCount int64
+	var getTrans = func(seq int64) (*Transaction, *ErrorCodeMsg) {
+		scanCount++
+		if scanCount > STATEMENT_MAX_SCAN_COUNT {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "too many transactions(>%d) to scan, please narrow the period.", STATEMENT_MAX_SCAN_COUNT)
+		}
+
+		globalKeyB, err := StateCache.GetState_Ex(stub, b.getOneAccTransInfoKey(accName, seq))
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "GetState failed. error=(%s)", err)
+		}
+		if globalKeyB == nil {
+			return nil, nil
+		}
+
+		trans, errcm := b.getOnceTransInfo(stub, string(globalKeyB))
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "getOnceTransInfo failed. error=(%s)", errcm)
+		}
+		if !b.isSameAsset(trans.AssetID, assetId) {
+			return nil, nil
+		}
+		return trans, nil
+	}
+	var signedAmount = func(trans *Transaction) int64 {
+		if trans.TransFlag == TRANS_PAY {
+			return -trans.Amount
+		}
+		return trans.Amount
+	}
+	var addPeriod = func(amount int64) {
+		st.TransCount++
+		if amount > 0 {
+			st.Credits += amount
+		} else {
+			st.Debits -= amount
+		}
+	}
+
+	ckpt, errcm := b.findBalanceCheckpoint(stub, accName, begTime)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "getAccountStatement: findBalanceCheckpoint failed. error=(%s)", errcm)
+	}
+
+	var anchorSeq int64 //从anchorSeq+1开始往后累计区间内的收支
+	if ckpt != nil {
+		anchorSeq = ckpt.Seq
+		st.Opening = ckpt.Balances[st.AssetID]
+	} else {
+		//begTime之前没有快照，第一个快照（如果有）一定在begTime之后
+		meta, errcm := b.getBalanceCkptMeta(stub, accName)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "getAccountStatement: getBalanceCkptMeta failed. error=(%s)", errcm)
+		}
+
+		var anchorBalance int64
+		if meta.Count > 0 {
+			first, errcm := b.getBalanceCkpt(stub, accName, 1)
+			if errcm != nil {
+				return nil, baselogger.ErrorECM(errcm.Code, "getAccountStatement: getBalanceCkpt failed. error=(%s)", errcm)
+			}
+			anchorSeq = first.Seq
+			anchorBalance = first.Balances[st.AssetID]
+		} else {
+			ent, errcm := b.GetAccountEntity(stub, accName)
+			if errcm != nil {
+				return nil, baselogger.ErrorECM(errcm.Code, "getAccountStatement: GetAccountEntity(%s) failed. error=(%s)", accName, errcm)
+			}
+			anchorSeq = maxSeq
+			_, anchorBalance = ent.GetAssetAmount(st.AssetID)
+		}
+
+		//往前倒推到begTime之前
+		st.Opening = anchorBalance
+		for seq := anchorSeq; seq > 0; seq-- {
+			trans, errcm := getTrans(seq)
+			if errcm != nil {
+				return nil, baselogger.ErrorECM(errcm.Code, "getAccountStatement: getTrans failed. error=(%s)", errcm)
+			}
+			if trans == nil {
+				continue
+			}
+			if trans.Time < begTime {
+				break
+			}
+
+			var amount = signedAmount(trans)
+			st.Opening -= amount
+			if trans.Time <= endTime {
+				addPeriod(amount)
+			}
+		}
+	}
+
+	for seq := anchorSeq + 1; seq <= maxSeq; seq++ {
+		trans, errcm := getTrans(seq)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "getAccountStatement: getTrans failed. error=(%s)", errcm)
+		}
+		if trans == nil {
+			continue
+		}
+		if trans.Time > endTime {
+			break
+		}
+
+		var amount = signedAmount(trans)
+		if trans.Time < begTime {
+			st.Opening += amount
+			continue
+		}
+		addPeriod(amount)
+	}
+
+	st.Closing = st.Opening + st.Credits - st.Debits
+
+	return &st, nil
+}
+
+//账户在queryTime（包括该时刻）的余额
+func (b *BASE) getHistoryBalance(stub shim.ChaincodeStubInterface, accName, assetId string, queryTime int64) (int64, *ErrorCodeMsg) {
+	//区间为空，期初余额即queryTime+1之前的余额
+	st, errcm := b.getAccountStatement(stub, accName, assetId, queryTime+1, queryTime)
+	if errcm != nil {
+		return 0, baselogger.ErrorECM(errcm.Code, "getHistoryBalance: getAccountStatement failed. error=(%s)", errcm)
+	}
+
+	return st.Opening, nil
+}
+
+//转账后更新相关账户的余额快照，空账户名和重复的账户忽略
+func (b *BASE) updateBalanceCheckpoints(stub shim.ChaincodeStubInterface, currTime int64, accNames ...string) *ErrorCodeMsg {
+	var done = make(map[string]bool)
+	for _, accName := range accNames {
+		if len(accName) == 0 || done[accName] {
+			continue
+		}
+		done[accName] = true
+
+		errcm := b.updateBalanceCheckpoint(stub, accName, currTime)
+		if errcm != nil {
+			return baselogger.ErrorECM(errcm.Code, "updateBalanceCheckpoints: updateBalanceCheckpoint(%s) failed. error=(%s)", accName, errcm)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,326 @@
+package acbase
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	. "loulan/errcode"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	HTLC_TRANS_TYPE = "htlc" //哈希时间锁转账的交易记录的交易类型
+
+	HTLC_STATUS_LOCKED   = "locked"
+	HTLC_STATUS_CLAIMED  = "claimed"
+	HTLC_STATUS_REFUNDED = "refunded"
+)
+
+//哈希时间锁，用于和其它链原子交换。付款方锁定一笔金额给收款方，收款方在超时之前出示哈希原像即可领取，
+//超时之后付款方可以取回。锁定期间金额仍在付款方账户中，和CoinLockCfg一样计入锁定金额，领取时才转账
+type HtlcLock struct {
+	LockID      string `json:"id"`             //锁id，即创建锁的交易id
+	Payer       string `json:"payer"`          //付款账户
+	Payee       string `json:"payee"`          //收款账户
+	AssetID     string `json:"asid,omitempty"` //锁定的资产，默认资产为空
+	Amount      int64  `json:"amt"`            //锁定金额
+	Fee         int64  `json:"fee"`            //创建时按手续费规则算出的手续费，和金额一起锁定。领取时按当时的规则收取，但不超过该值
+	HashLock    string `json:"hash"`           //原像的sha256，hex小写
+	Timeout     int64  `json:"tmo"`            //超时时间，单位毫秒，和交易时间比较
+	AppID       string `json:"app"`            //领取时交易记录在该应用下
+	Status      string `json:"st"`             //见HTLC_STATUS_*
+	Preimage    string `json:"pre,omitempty"`  //领取时出示的原像，hex。另一条链上的对手方据此领取
+	ClaimSerial int64  `json:"cser,omitempty"` //领取转账付款记录的全局序列号
+	CreateTime  int64  `json:"ctime"`
+	UpdateTime  int64  `json:"utime,omitempty"` //领取或取回的时间
+}
+
+func (b *BASE) getHtlcKey(lockId string) string {
+	return HTLC_PREFIX + lockId
+}
+
+//付款方尚未领取或取回的锁id列表，计算锁定金额用
+func (b *BASE) getPayerHtlcsKey(payer string) string {
+	return HTLC_PAYER_PREFIX + payer
+}
+
+//锁不存在时返回nil
+func (b *BASE) getHtlc(stub shim.ChaincodeStubInterface, lockId string) (*HtlcLock, *ErrorCodeMsg) {
+	lockB, err := StateCache.GetState_Ex(stub, b.getHtlcKey(lockId))
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getHtlc: GetState failed. error=(%s)", err)
+	}
+	if lockB == nil {
+		return nil, nil
+	}
+
+	var lock HtlcLock
+	err = json.Unmarshal(lockB, &lock)
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getHtlc: Unmarshal failed. error=(%s)", err)
+	}
+
+	return &lock, nil
+}
+
+func (b *BASE) setHtlc(stub shim.ChaincodeStubInterface, lock *HtlcLock) *ErrorCodeMsg {
+	lockB, err := json.Marshal(lock)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setHtlc: Marshal failed. error=(%s)", err)
+	}
+
+	err = StateCache.PutState_Ex(stub, b.getHtlcKey(lock.LockID), lockB)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setHtlc: PutState failed. error=(%s)", err)
+	}
+
+	return nil
+}
+
+func (b *BASE) getPayerHtlcs(stub shim.ChaincodeStubInterface, payer string) ([]string, *ErrorCodeMsg) {
+	var ids []string
+
+	idsB, err := StateCache.GetState_Ex(stub, b.getPayerHtlcsKey(payer))
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getPayerHtlcs: GetState failed. error=(%s)", err)
+	}
+	if idsB == nil {
+		return ids, nil
+	}
+
+	err = json.Unmarshal(idsB, &ids)
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getPayerHtlcs: Unmarshal failed. error=(%s)", err)
+	}
+
+	return ids, nil
+}
+
+//更新付款方的锁id列表。isActive表示该锁是否仍在锁定中
+func (b *BASE) updatePayerHtlcs(stub shim.ChaincodeStubInterface, payer, lockId string, isActive bool) *ErrorCodeMsg {
+	ids, errcm := b.getPayerHtlcs(stub, payer)
+	if errcm != nil {
+		return errcm
+	}
+
+	var newIds = []string{}
+	for _, id := range ids {
+		if id != lockId {
+			newIds = append(newIds, id)
+		}
+	}
+	if isActive {
+		newIds = append(newIds, lockId)
+	}
+
+	idsB, err := json.Marshal(newIds)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "updatePayerHtlcs: Marshal failed. error=(%s)", err)
+	}
+
+	err = StateCache.PutState_Ex(stub, b.getPayerHtlcsKey(payer), idsB)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "updatePayerHtlcs: PutState failed. error=(%s)", err)
+	}
+
+	return nil
+}
+
+//付款方某种资产在哈希时间锁中锁定的金额（包括预留的手续费）
+func (b *BASE) getHtlcLockedAmount(stub shim.ChaincodeStubInterface, payer, assetId string) (int64, *ErrorCodeMsg) {
+	ids, errcm := b.getPayerHtlcs(stub, payer)
+	if errcm != nil {
+		return 0, baselogger.ErrorECM(errcm.Code, "getHtlcLockedAmount: getPayerHtlcs failed. error=(%s)", errcm)
+	}
+
+	var lockAmt int64
+	for _, id := range ids {
+		lock, errcm := b.getHtlc(stub, id)
+		if errcm != nil {
+			return 0, baselogger.ErrorECM(errcm.Code, "getHtlcLockedAmount: getHtlc(%s) failed. error=(%s)", id, errcm)
+		}
+		if lock == nil || lock.Status != HTLC_STATUS_LOCKED || !b.isSameAsset(lock.AssetID, assetId) {
+			continue
+		}
+		lockAmt += lock.Amount + lock.Fee
+	}
+
+	return lockAmt, nil
+}
+
+//原像为hex编码，返回sha256的hex
+func htlcHash(preimageHex string) (string, error) {
+	preimage, err := hex.DecodeString(preimageHex)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(preimage)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+//创建哈希时间锁，lock中的LockID、Payer、Status等由这里设置
+func (b *BASE) createHtlc(stub shim.ChaincodeStubInterface, lock *HtlcLock) *ErrorCodeMsg {
+	if lock.Amount <= 0 {
+		return baselogger.ErrorECM(ERRCODE_TRANS_AMOUNT_INVALID, "createHtlc: invalid amount(%d).", lock.Amount)
+	}
+	if lock.Timeout <= lock.CreateTime {
+		return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "createHtlc: timeout(%d) is earlier than now(%d).", lock.Timeout, lock.CreateTime)
+	}
+	if lock.Payer == lock.Payee {
+		return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "createHtlc: can not lock to self.")
+	}
+
+	lock.HashLock = strings.ToLower(lock.HashLock)
+	hashB, err := hex.DecodeString(lock.HashLock)
+	if err != nil || len(hashB) != sha256.Size {
+		return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "createHtlc: invalid hashlock(%s).", lock.HashLock)
+	}
+
+	if IsDefaultAsset(lock.AssetID) {
+		lock.AssetID = ""
+	}
+	errcm := b.checkAssetExists(stub, lock.AssetID)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "createHtlc: checkAssetExists failed. error=(%s)", errcm)
+	}
+
+	exists, errcm := b.IsAccEntityExists(stub, lock.Payee)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "createHtlc: IsAccEntityExists(%s) failed. error=(%s)", lock.Payee, errcm)
+	}
+	if !exists {
+		return baselogger.ErrorECM(ERRCODE_TRANS_PAYEE_ACCOUNT_NOT_EXIST, "createHtlc: payee(%s) not exists.", lock.Payee)
+	}
+
+	lock.LockID = stub.GetTxID()
+	lock.Status = HTLC_STATUS_LOCKED
+
+	old, errcm := b.getHtlc(stub, lock.LockID)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "createHtlc: getHtlc failed. error=(%s)", errcm)
+	}
+	if old != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "createHtlc: only one lock in a transaction.")
+	}
+
+	//领取时按同样的规则收手续费，这里一起锁定
+	lock.Fee, _, errcm = b.calcTransferFee(stub, lock.AppID, HTLC_TRANS_TYPE, lock.AssetID, lock.Payer, lock.Amount)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "createHtlc: calcTransferFee failed. error=(%s)", errcm)
+	}
+
+	payerEnt, errcm := b.GetAccountEntity(stub, lock.Payer)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "createHtlc: GetAccountEntity(%s) failed. error=(%s)", lock.Payer, errcm)
+	}
+	lockAmt, _, errcm := b.getAccountLockedAmount(stub, lock.Payer, lock.AssetID, lock.CreateTime)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "createHtlc: getAccountLockedAmount(%s) failed. error=(%s)", lock.Payer, errcm)
+	}
+	_, restAmount := payerEnt.GetAssetAmount(lock.AssetID)
+	if restAmount-lockAmt < lock.Amount+lock.Fee {
+		var errCode int32 = ERRCODE_TRANS_BALANCE_NOT_ENOUGH
+		if lockAmt > 0 {
+			errCode = ERRCODE_TRANS_BALANCE_NOT_ENOUGH_BYLOCK
+		}
+		return baselogger.ErrorECM(errCode, "createHtlc: %s restAmount not enough(%d,%d,%d,%d).", lock.Payer, restAmount, lockAmt, lock.Amount, lock.Fee)
+	}
+
+	errcm = b.setHtlc(stub, lock)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "createHtlc: setHtlc failed. error=(%s)", errcm)
+	}
+
+	errcm = b.updatePayerHtlcs(stub, lock.Payer, lock.LockID, true)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "createHtlc: updatePayerHtlcs failed. error=(%s)", errcm)
+	}
+
+	return nil
+}
+
+//收款方出示原像领取，必须在超时之前
+func (b *BASE) claimHtlc(stub shim.ChaincodeStubInterface, operator, lockId, preimageHex string, claimTime int64) (*HtlcLock, *ErrorCodeMsg) {
+	lock, errcm := b.getHtlc(stub, lockId)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "claimHtlc: getHtlc failed. error=(%s)", errcm)
+	}
+	if lock == nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "claimHtlc: lock(%s) not exists.", lockId)
+	}
+	if operator != lock.Payee {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "claimHtlc: %s is not payee of lock(%s).", operator, lockId)
+	}
+	if lock.Status != HTLC_STATUS_LOCKED {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "claimHtlc: lock(%s) is %s.", lockId, lock.Status)
+	}
+	if claimTime >= lock.Timeout {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "claimHtlc: lock(%s) timeout(%d,%d).", lockId, lock.Timeout, claimTime)
+	}
+
+	hash, err := htlcHash(preimageHex)
+	if err != nil || hash != lock.HashLock {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_IDENTITY_VERIFY_FAILED, "claimHtlc: preimage not match lock(%s).", lockId)
+	}
+
+	//先解除锁定，否则转账时锁定金额不能转出
+	lock.Status = HTLC_STATUS_CLAIMED
+	lock.Preimage = strings.ToLower(preimageHex)
+	lock.UpdateTime = claimTime
+
+	errcm = b.updatePayerHtlcs(stub, lock.Payer, lockId, false)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "claimHtlc: updatePayerHtlcs failed. error=(%s)", errcm)
+	}
+
+	//锁定期间手续费规则可能调高，付款方只锁定了lock.Fee，所以最多收取lock.Fee
+	lock.ClaimSerial, errcm = b.transferAsset(stub, lock.AssetID, lock.Payer, lock.Payee, HTLC_TRANS_TYPE, "htlc claim "+lockId, "", lock.Amount, claimTime, false, lock.AppID, 0, lock.Fee)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "claimHtlc: transferAsset failed. error=(%s)", errcm)
+	}
+
+	errcm = b.setHtlc(stub, lock)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "claimHtlc: setHtlc failed. error=(%s)", errcm)
+	}
+
+	return lock, nil
+}
+
+//超时之后付款方取回，即解除锁定，金额一直在付款方账户中，不需要转账
+func (b *BASE) refundHtlc(stub shim.ChaincodeStubInterface, operator, lockId string, refundTime int64) (*HtlcLock, *ErrorCodeMsg) {
+	lock, errcm := b.getHtlc(stub, lockId)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "refundHtlc: getHtlc failed. error=(%s)", errcm)
+	}
+	if lock == nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "refundHtlc: lock(%s) not exists.", lockId)
+	}
+	if operator != lock.Payer && !b.IsAdmin(stub, operator) {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "refundHtlc: %s can't refund lock(%s).", operator, lockId)
+	}
+	if lock.Status != HTLC_STATUS_LOCKED {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "refundHtlc: lock(%s) is %s.", lockId, lock.Status)
+	}
+	if refundTime < lock.Timeout {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "refundHtlc: lock(%s) not timeout(%d,%d).", lockId, lock.Timeout, refundTime)
+	}
+
+	lock.Status = HTLC_STATUS_REFUNDED
+	lock.UpdateTime = refundTime
+
+	errcm = b.updatePayerHtlcs(stub, lock.Payer, lockId, false)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "refundHtlc: updatePayerHtlcs failed. error=(%s)", errcm)
+	}
+
+	errcm = b.setHtlc(stub, lock)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "refundHtlc: setHtlc failed. error=(%s)", errcm)
+	}
+
+	return lock, nil
+}
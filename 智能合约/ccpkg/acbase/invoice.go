@@ -0,0 +1,247 @@
+package acbase
+
+import (
+	"encoding/json"
+
+	. "loulan/errcode"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	INVOICE_TRANS_TYPE = "invoice" //支付账单的交易记录的交易类型
+
+	INVOICE_STATUS_OPEN      = "open"
+	INVOICE_STATUS_PAID      = "paid"
+	INVOICE_STATUS_CANCELLED = "cancelled"
+	INVOICE_STATUS_EXPIRED   = "expired" //不保存，查询时对已过期的open账单返回此状态
+)
+
+//收款账单。商户（收款方）创建账单，付款方支付一次后账单关闭，不用再靠交易描述在链下匹配
+type Invoice struct {
+	InvoiceID  string `json:"id"`              //账单id，同一收款方下唯一，不同收款方可以相同
+	Payee      string `json:"payee"`           //收款账户，即创建账单的账户
+	Amount     int64  `json:"amt"`             //金额（默认资产）
+	Expiry     int64  `json:"exp"`             //过期时间，单位毫秒，0表示不过期
+	AppID      string `json:"app"`             //支付时交易记录在该应用下
+	MemoHash   string `json:"mhash"`           //账单备注的hash，备注本身不上链
+	Status     string `json:"st"`              //见INVOICE_STATUS_*
+	Payer      string `json:"payer,omitempty"` //付款账户
+	PaySerial  int64  `json:"pser,omitempty"`  //支付交易付款记录的全局序列号
+	CreateTime int64  `json:"ctime"`
+	UpdateTime int64  `json:"utime,omitempty"` //支付或取消的时间
+}
+
+//账户名中不能有':'，所以不同收款方的账单key不会冲突
+func (b *BASE) getInvoiceKey(payee, invoiceId string) string {
+	return INVOICE_PREFIX + payee + ":" + invoiceId
+}
+
+//收款方创建的所有账单id，查询用
+func (b *BASE) getPayeeInvoicesKey(payee string) string {
+	return INVOICE_PAYEE_PREFIX + payee
+}
+
+//账单不存在时返回nil
+func (b *BASE) getInvoice(stub shim.ChaincodeStubInterface, payee, invoiceId string) (*Invoice, *ErrorCodeMsg) {
+	invB, err := StateCache.GetState_Ex(stub, b.getInvoiceKey(payee, invoiceId))
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getInvoice: GetState failed. error=(%s)", err)
+	}
+	if invB == nil {
+		return nil, nil
+	}
+
+	var inv Invoice
+	err = json.Unmarshal(invB, &inv)
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getInvoice: Unmarshal failed. error=(%s)", err)
+	}
+
+	return &inv, nil
+}
+
+func (b *BASE) setInvoice(stub shim.ChaincodeStubInterface, inv *Invoice) *ErrorCodeMsg {
+	invB, err := json.Marshal(inv)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setInvoice: Marshal failed. error=(%s)", err)
+	}
+
+	err = StateCache.PutState_Ex(stub, b.getInvoiceKey(inv.Payee, inv.InvoiceID), invB)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setInvoice: PutState failed. error=(%s)", err)
+	}
+
+	return nil
+}
+
+func (b *BASE) getPayeeInvoices(stub shim.ChaincodeStubInterface, payee string) ([]string, *ErrorCodeMsg) {
+	var ids []string
+
+	idsB, err := StateCache.GetState_Ex(stub, b.getPayeeInvoicesKey(payee))
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getPayeeInvoices: GetState failed. error=(%s)", err)
+	}
+	if idsB == nil {
+		return ids, nil
+	}
+
+	err = json.Unmarshal(idsB, &ids)
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getPayeeInvoices: Unmarshal failed. error=(%s)", err)
+	}
+
+	return ids, nil
+}
+
+func (b *BASE) addPayeeInvoice(stub shim.ChaincodeStubInterface, payee, invoiceId string) *ErrorCodeMsg {
+	ids, errcm := b.getPayeeInvoices(stub, payee)
+	if errcm != nil {
+		return errcm
+	}
+	ids = append(ids, invoiceId)
+
+	idsB, err := json.Marshal(ids)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "addPayeeInvoice: Marshal failed. error=(%s)", err)
+	}
+
+	err = StateCache.PutState_Ex(stub, b.getPayeeInvoicesKey(payee), idsB)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "addPayeeInvoice: PutState failed. error=(%s)", err)
+	}
+
+	return nil
+}
+
+//查询时的状态，过期的open账单返回expired
+func (inv *Invoice) currentStatus(currTime int64) string {
+	if inv.Status == INVOICE_STATUS_OPEN && inv.Expiry != 0 && inv.Expiry <= currTime {
+		return INVOICE_STATUS_EXPIRED
+	}
+	return inv.Status
+}
+
+func (b *BASE) createInvoice(stub shim.ChaincodeStubInterface, inv *Invoice) *ErrorCodeMsg {
+	if len(inv.InvoiceID) == 0 {
+		return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "createInvoice: invoice id is empty.")
+	}
+	if inv.Amount <= 0 {
+		return baselogger.ErrorECM(ERRCODE_TRANS_AMOUNT_INVALID, "createInvoice: invalid amount(%d).", inv.Amount)
+	}
+	if inv.Expiry != 0 && inv.Expiry <= inv.CreateTime {
+		return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "createInvoice: expiry(%d) is earlier than now(%d).", inv.Expiry, inv.CreateTime)
+	}
+
+	old, errcm := b.getInvoice(stub, inv.Payee, inv.InvoiceID)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "createInvoice: getInvoice failed. error=(%s)", errcm)
+	}
+	if old != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "createInvoice: invoice(%s) exists.", inv.InvoiceID)
+	}
+
+	inv.Status = INVOICE_STATUS_OPEN
+
+	errcm = b.setInvoice(stub, inv)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "createInvoice: setInvoice failed. error=(%s)", errcm)
+	}
+
+	errcm = b.addPayeeInvoice(stub, inv.Payee, inv.InvoiceID)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "createInvoice: addPayeeInvoice failed. error=(%s)", errcm)
+	}
+
+	return nil
+}
+
+//取消账单，只有收款方或管理员可以取消，已支付的账单不能取消
+func (b *BASE) cancelInvoice(stub shim.ChaincodeStubInterface, operator, payee, invoiceId string, cancelTime int64) *ErrorCodeMsg {
+	inv, errcm := b.getInvoice(stub, payee, invoiceId)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "cancelInvoice: getInvoice failed. error=(%s)", errcm)
+	}
+	if inv == nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "cancelInvoice: invoice(%s,%s) not exists.", payee, invoiceId)
+	}
+	if operator != inv.Payee && !b.IsAdmin(stub, operator) {
+		return baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "cancelInvoice: %s can't cancel invoice(%s).", operator, invoiceId)
+	}
+	if inv.Status != INVOICE_STATUS_OPEN {
+		return baselogger.ErrorECM(ERRCODE_TRANS_INVOICE_NOT_PAYABLE, "cancelInvoice: invoice(%s) is %s.", invoiceId, inv.Status)
+	}
+
+	inv.Status = INVOICE_STATUS_CANCELLED
+	inv.UpdateTime = cancelTime
+
+	errcm = b.setInvoice(stub, inv)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "cancelInvoice: setInvoice failed. error=(%s)", errcm)
+	}
+
+	return nil
+}
+
+//支付账单，从payer转账给收款方，账单只能支付一次。付款方传入支付前查询到的收款方和金额，和账单不一致时不支付
+func (b *BASE) payInvoice(stub shim.ChaincodeStubInterface, payer, payee, invoiceId string, amount, payTime int64) (*Invoice, *ErrorCodeMsg) {
+	inv, errcm := b.getInvoice(stub, payee, invoiceId)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "payInvoice: getInvoice failed. error=(%s)", errcm)
+	}
+	if inv == nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "payInvoice: invoice(%s,%s) not exists.", payee, invoiceId)
+	}
+	if amount != inv.Amount {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "payInvoice: amount(%d) not match invoice(%s,%s) amount(%d).", amount, payee, invoiceId, inv.Amount)
+	}
+	if status := inv.currentStatus(payTime); status != INVOICE_STATUS_OPEN {
+		return nil, baselogger.ErrorECM(ERRCODE_TRANS_INVOICE_NOT_PAYABLE, "payInvoice: invoice(%s) is %s.", invoiceId, status)
+	}
+	if payer == inv.Payee {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "payInvoice: payee can't pay own invoice(%s).", invoiceId)
+	}
+
+	paySerial, errcm := b.transferAsset(stub, DEFAULT_ASSET_ID, payer, inv.Payee, INVOICE_TRANS_TYPE, "pay invoice "+invoiceId, "", inv.Amount, payTime, false, inv.AppID, 0, -1)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "payInvoice: transferAsset failed. error=(%s)", errcm)
+	}
+
+	inv.Status = INVOICE_STATUS_PAID
+	inv.Payer = payer
+	inv.PaySerial = paySerial
+	inv.UpdateTime = payTime
+
+	errcm = b.setInvoice(stub, inv)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "payInvoice: setInvoice failed. error=(%s)", errcm)
+	}
+
+	return inv, nil
+}
+
+//查询收款方的账单，status为空时返回所有账单
+func (b *BASE) queryPayeeInvoices(stub shim.ChaincodeStubInterface, payee, status string, queryTime int64) ([]Invoice, *ErrorCodeMsg) {
+	ids, errcm := b.getPayeeInvoices(stub, payee)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "queryPayeeInvoices: getPayeeInvoices failed. error=(%s)", errcm)
+	}
+
+	var invList = []Invoice{}
+	for _, id := range ids {
+		inv, errcm := b.getInvoice(stub, payee, id)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "queryPayeeInvoices: getInvoice(%s) failed. error=(%s)", id, errcm)
+		}
+		if inv == nil {
+			continue
+		}
+
+		inv.Status = inv.currentStatus(queryTime)
+		if len(status) == 0 || inv.Status == status {
+			invList = append(invList, *inv)
+		}
+	}
+
+	return invList, nil
+}
@@ -0,0 +1,554 @@
+package acbase
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"loulan/llcrypto"
+
+	. "loulan/ccutil"
+	. "loulan/errcode"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+//init时设置的货币发行总额，见__Init中SetIssueAmountTotal
+const testIssueAmountTotal = 10000000000
+
+//测试用户。secp256k1密钥用于交易签名，证书用于身份校验(GetCreator)
+type testUser struct {
+	Name       string
+	PubKey     []byte
+	SecKey     []byte
+	PubKeyHash string //base64
+	Cert       []byte //pem格式
+	CertHash   string //base64
+	SignV1     bool   //用v1格式签名，见llcrypto/signmsg.go
+}
+
+func newTestUser(t *testing.T, name string) *testUser {
+	var u = &testUser{Name: name}
+	var err error
+
+	u.PubKey, u.SecKey, err = secp256k1.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("newTestUser(%s): GenerateKeyPair failed, err=%s", name, err)
+	}
+	hash, err := llcrypto.RipemdHash160(u.PubKey)
+	if err != nil {
+		t.Fatalf("newTestUser(%s): RipemdHash160(pubkey) failed, err=%s", name, err)
+	}
+	u.PubKeyHash = base64.StdEncoding.EncodeToString(hash)
+
+	//自签名证书，CommonName为用户名，verifyIdentity中会校验
+	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("newTestUser(%s): GenerateKey failed, err=%s", name, err)
+	}
+	var tmpl = x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &certKey.PublicKey, certKey)
+	if err != nil {
+		t.Fatalf("newTestUser(%s): CreateCertificate failed, err=%s", name, err)
+	}
+	u.Cert = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	hash, err = llcrypto.RipemdHash160(u.Cert)
+	if err != nil {
+		t.Fatalf("newTestUser(%s): RipemdHash160(cert) failed, err=%s", name, err)
+	}
+	u.CertHash = base64.StdEncoding.EncodeToString(hash)
+
+	return u
+}
+
+//按客户端的方式签名：函数名和参数用","拼接后计算sha256，再用私钥签名。和getSignAndMsg对应
+func (u *testUser) sign(t *testing.T, function string, args []string) string {
+	var msg = util.ComputeSHA256([]byte(function + "," + strings.Join(args, ",")))
+	sig, err := secp256k1.Sign(msg, u.SecKey)
+	if err != nil {
+		t.Fatalf("sign(%s): Sign failed, err=%s", u.Name, err)
+	}
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+//按v1格式签名，返回的是签名参数(带过期时间)
+func (u *testUser) signV1(t *testing.T, channelId, chaincodeName, function string, args []string, expiry int64) string {
+	var msg = llcrypto.CanonicalSignMsg(channelId, chaincodeName, function, args, expiry)
+	sig, err := secp256k1.Sign(msg, u.SecKey)
+	if err != nil {
+		t.Fatalf("signV1(%s): Sign failed, err=%s", u.Name, err)
+	}
+	return llcrypto.MakeSignEnvelope(expiry, base64.StdEncoding.EncodeToString(sig))
+}
+
+//MockStub的交易时间为当前时间，GetCreator返回nil，测试中需要可控的时间和身份，所以包装一下
+type testStub struct {
+	*shim.MockStub
+	args    [][]byte
+	creator []byte
+	now     time.Time
+}
+
+func (s *testStub) GetArgs() [][]byte {
+	return s.args
+}
+
+func (s *testStub) GetStringArgs() []string {
+	var strs = make([]string, 0, len(s.args))
+	for _, arg := range s.args {
+		strs = append(strs, string(arg))
+	}
+	return strs
+}
+
+func (s *testStub) GetFunctionAndParameters() (string, []string) {
+	var strs = s.GetStringArgs()
+	if len(strs) == 0 {
+		return "", []string{}
+	}
+	return strs[0], strs[1:]
+}
+
+func (s *testStub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+//MockStub默认没有交易提案，这里构造一个只带合约名的提案，v1签名校验时会从中解析合约名
+func (s *testStub) GetSignedProposal() (*pb.SignedProposal, error) {
+	cisB, err := proto.Marshal(&pb.ChaincodeInvocationSpec{ChaincodeSpec: &pb.ChaincodeSpec{ChaincodeId: &pb.ChaincodeID{Name: s.Name}}})
+	if err != nil {
+		return nil, err
+	}
+	cppB, err := proto.Marshal(&pb.ChaincodeProposalPayload{Input: cisB})
+	if err != nil {
+		return nil, err
+	}
+	propB, err := proto.Marshal(&pb.Proposal{Payload: cppB})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SignedProposal{ProposalBytes: propB}, nil
+}
+
+func (s *testStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return &timestamp.Timestamp{Seconds: s.now.Unix(), Nanos: int32(s.now.Nanosecond())}, nil
+}
+
+//当前交易时间，单位毫秒，和合约中invokeTime的计算方式一致
+func (s *testStub) nowMs() int64 {
+	return s.now.Unix()*1000 + int64(s.now.Nanosecond()/1000000)
+}
+
+func (s *testStub) advance(d time.Duration) {
+	s.now = s.now.Add(d)
+}
+
+//模拟控制合约，返回配置的参数值。没有配置的参数返回错误，账户系统会使用默认值
+type testCtrlCC struct {
+	paras map[string]string
+}
+
+func (c *testCtrlCC) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success(nil)
+}
+
+func (c *testCtrlCC) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	function, args := stub.GetFunctionAndParameters()
+	if function != CONTROL_CC_GETPARA_FUNC_NAME || len(args) < 1 {
+		return shim.Error(fmt.Sprintf("testCtrlCC: unknown function %s%v", function, args))
+	}
+	value, ok := c.paras[args[0]]
+	if !ok {
+		return shim.Error(fmt.Sprintf("testCtrlCC: parameter %s not exists", args[0]))
+	}
+	return shim.Success([]byte(value))
+}
+
+//模拟被账户系统跨合约调用的业务合约，返回预先设置好的转账，由账户系统执行
+type testPayoutCC struct {
+	transInfos []TransferInfo
+	payload    []byte
+	errcm      *ErrorCodeMsg
+	calledArgs []string //最近一次被调用时收到的参数
+}
+
+func (c *testPayoutCC) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success(nil)
+}
+
+func (c *testPayoutCC) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	c.calledArgs = stub.GetStringArgs()
+	if c.errcm != nil {
+		return shim.Error(c.errcm.ToJson())
+	}
+
+	rsltB, err := json.Marshal(InvokeResult{TransInfos: c.transInfos, Payload: c.payload})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(rsltB)
+}
+
+//一条测试链：账户系统 + 控制合约 + 可选的业务合约
+type testChain struct {
+	t       *testing.T
+	stub    *testStub
+	ctrl    *testCtrlCC
+	users   map[string]*testUser
+	txSeq   int
+	cbUser  string
+	cbAcc   string
+	appid   string
+	payouts map[string]*testPayoutCC
+
+	issueTotal int64 //当前的货币发行上限，修改上限的测试需要同步修改
+}
+
+func newTestChain(t *testing.T) *testChain {
+	//包级别的缓存会跨测试保留，每条链重新开始
+	centerBankAccCache = nil
+
+	var c = &testChain{t: t, users: make(map[string]*testUser), payouts: make(map[string]*testPayoutCC), issueTotal: testIssueAmountTotal}
+
+	c.ctrl = &testCtrlCC{paras: map[string]string{
+		CTRL_PARA_IS_TEST_CHAIN:       "false",
+		CTRL_PARA_NEED_CHECK_SIGN:     "true",
+		CTRL_PARA_NEED_CHECK_IDENTITY: "true",
+	}}
+
+	var mock = shim.NewMockStub("accountsys", &Base)
+	mock.ChannelID = "testchannel"
+	mock.MockPeerChaincode(CONTROL_CC_NAME, shim.NewMockStub(CONTROL_CC_NAME, c.ctrl))
+
+	c.stub = &testStub{MockStub: mock, now: time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)}
+
+	c.txSeq++
+	var txid = fmt.Sprintf("tx%d", c.txSeq)
+	c.stub.args = util.ToChaincodeArgs("init")
+	c.stub.MockTransactionStart(txid)
+	resp := Base.Init(c.stub)
+	c.stub.MockTransactionEnd(txid)
+	if resp.Status != shim.OK {
+		t.Fatalf("newTestChain: init failed, %s", resp.Message)
+	}
+
+	return c
+}
+
+func (c *testChain) user(name string) *testUser {
+	u, ok := c.users[name]
+	if !ok {
+		u = newTestUser(c.t, name)
+		c.users[name] = u
+	}
+	return u
+}
+
+//注册一个业务合约，供跨合约调用
+func (c *testChain) addPayoutCC(name string) *testPayoutCC {
+	var cc = &testPayoutCC{}
+	c.payouts[name] = cc
+	c.stub.MockPeerChaincode(name, shim.NewMockStub(name, cc))
+	return cc
+}
+
+//执行一个交易。creator为发起交易的身份，signer为签名的用户（一般和creator相同），
+//args为用户名之后、签名之前的参数。失败时和fabric一样丢弃本交易的所有写操作
+func (c *testChain) invokeAs(creator, signer *testUser, function string, args []string, crossCC string) pb.Response {
+	var allArgs = append([]string{creator.Name}, args...)
+	if signer.SignV1 {
+		allArgs = append(allArgs, signer.signV1(c.t, c.stub.ChannelID, c.stub.Name, function, allArgs, c.stub.nowMs()+60*1000))
+	} else {
+		allArgs = append(allArgs, signer.sign(c.t, function, allArgs))
+	}
+	if len(crossCC) > 0 {
+		allArgs = append(allArgs, CROSSCCCALL_PREFIX+crossCC)
+	}
+
+	return c.invokeRaw(creator, function, allArgs)
+}
+
+//用已经带签名的完整参数执行交易，用于构造各种签名
+func (c *testChain) invokeRaw(creator *testUser, function string, allArgs []string) pb.Response {
+	c.txSeq++
+	var txid = fmt.Sprintf("tx%d", c.txSeq)
+
+	var snapshot = make(map[string][]byte, len(c.stub.State))
+	for k, v := range c.stub.State {
+		snapshot[k] = v
+	}
+
+	c.stub.args = util.ToChaincodeArgs(append([]string{function}, allArgs...)...)
+	c.stub.creator = creator.Cert
+	c.stub.MockTransactionStart(txid)
+
+	resp := Base.Invoke(c.stub)
+	if resp.Status != shim.OK {
+		c.rollback(snapshot)
+	}
+
+	c.stub.MockTransactionEnd(txid)
+
+	return resp
+}
+
+func (c *testChain) invoke(user, function string, args ...string) pb.Response {
+	var u = c.user(user)
+	return c.invokeAs(u, u, function, args, "")
+}
+
+func (c *testChain) crossInvoke(user, ccName, function string, args ...string) pb.Response {
+	var u = c.user(user)
+	return c.invokeAs(u, u, function, args, ccName)
+}
+
+func (c *testChain) rollback(snapshot map[string][]byte) {
+	var keys []string
+	for k := range c.stub.State {
+		keys = append(keys, k)
+	}
+	for _, k := range keys {
+		old, ok := snapshot[k]
+		if !ok {
+			c.stub.MockStub.DelState(k)
+		} else if !bytes.Equal(old, c.stub.State[k]) {
+			c.stub.MockStub.PutState(k, old)
+		}
+	}
+	for k, v := range snapshot {
+		if _, ok := c.stub.State[k]; !ok {
+			c.stub.MockStub.PutState(k, v)
+		}
+	}
+}
+
+//开户。userIdentity由平台追加在签名之后，所以不能用invoke
+func (c *testChain) openAccount(user, acc string, isCB bool) {
+	var u = c.user(user)
+	var function = "account"
+	if isCB {
+		function = "accountCB"
+	}
+
+	var args = []string{u.Name, acc, u.PubKeyHash}
+	args = append(args, u.sign(c.t, function, args), u.CertHash)
+
+	c.txSeq++
+	var txid = fmt.Sprintf("tx%d", c.txSeq)
+	c.stub.args = util.ToChaincodeArgs(append([]string{function}, args...)...)
+	c.stub.creator = u.Cert
+	c.stub.MockTransactionStart(txid)
+	resp := Base.Invoke(c.stub)
+	c.stub.MockTransactionEnd(txid)
+
+	if resp.Status != shim.OK {
+		c.t.Fatalf("openAccount(%s,%s) failed, %s", user, acc, resp.Message)
+	}
+	if isCB {
+		c.cbUser = user
+		c.cbAcc = acc
+	}
+	c.checkInvariants("openAccount " + acc)
+}
+
+//搭建一个常用的环境：央行发行货币，注册应用，给每个账户转入初始余额
+func (c *testChain) setup(issue int64, balances map[string]int64) {
+	c.openAccount("cbuser", "cb", true)
+	c.mustOK(c.invoke("cbuser", "issue", "cb", fmt.Sprintf("%d", issue)), "issue")
+
+	c.appid = "testapp"
+	c.mustOK(c.invoke("cbuser", "registerApp", "cb", c.appid, "test app", "test corp"), "registerApp")
+
+	for acc, amt := range balances {
+		c.openAccount(acc+"_user", acc, false)
+		c.mustOK(c.invoke("cbuser", "transefer", "cb", acc, fmt.Sprintf("%d", amt), c.appid), "transefer to "+acc)
+	}
+	c.checkInvariants("setup")
+}
+
+func (c *testChain) mustOK(resp pb.Response, what string) []byte {
+	if resp.Status != shim.OK {
+		c.t.Fatalf("%s failed, %s", what, resp.Message)
+	}
+	return resp.Payload
+}
+
+//失败时返回的错误码。无法解析时返回-1
+func respErrCode(resp pb.Response) int32 {
+	if resp.Status == shim.OK {
+		return 0
+	}
+	errcm, err := NewErrorCodeMsgFromString(resp.Message)
+	if err != nil {
+		return -1
+	}
+	return errcm.Code
+}
+
+func (c *testChain) accountEntity(acc string) *AccountEntity {
+	entB := c.stub.State[ACC_ENTITY_PREFIX+acc]
+	if entB == nil {
+		return nil
+	}
+	var ent AccountEntity
+	if err := json.Unmarshal(entB, &ent); err != nil {
+		c.t.Fatalf("accountEntity(%s): Unmarshal failed, err=%s", acc, err)
+	}
+	return &ent
+}
+
+func (c *testChain) balance(acc string) int64 {
+	ent := c.accountEntity(acc)
+	if ent == nil {
+		c.t.Fatalf("balance: account %s not exists", acc)
+	}
+	return ent.RestAmount
+}
+
+func (c *testChain) assetBalance(acc, assetId string) int64 {
+	ent := c.accountEntity(acc)
+	if ent == nil {
+		c.t.Fatalf("assetBalance: account %s not exists", acc)
+	}
+	_, rest := ent.GetAssetAmount(assetId)
+	return rest
+}
+
+//每一步之后检查的不变量：
+//1. 所有账户（包括虚拟的发行账户）余额之和等于发行总额，即转账不会凭空产生货币，销毁的货币回到发行账户
+//2. 账户余额不为负，且不超过累计收入
+//3. getAllAccAmt对账结果和直接统计的结果一致
+//4. 其它资产的账户余额之和等于该资产的已发行数量
+func (c *testChain) checkInvariants(step string) {
+	var sum, userSum int64
+	var assetSum = make(map[string]int64)
+	for k, v := range c.stub.State {
+		if !strings.HasPrefix(k, ACC_ENTITY_PREFIX) {
+			continue
+		}
+		var ent AccountEntity
+		if err := json.Unmarshal(v, &ent); err != nil {
+			c.t.Fatalf("[%s] invariant: Unmarshal %s failed, err=%s", step, k, err)
+		}
+		if ent.RestAmount < 0 {
+			c.t.Errorf("[%s] invariant: %s rest amount %d < 0", step, ent.EntID, ent.RestAmount)
+		}
+		if ent.RestAmount > ent.TotalAmount {
+			c.t.Errorf("[%s] invariant: %s rest amount %d > total amount %d", step, ent.EntID, ent.RestAmount, ent.TotalAmount)
+		}
+		sum += ent.RestAmount
+		if ent.EntID != COIN_ISSUE_ACC_ENTID && ent.EntID != c.cbAcc {
+			userSum += ent.RestAmount
+		}
+		for assetId, aa := range ent.AssetAmounts {
+			if aa.RestAmount < 0 || aa.RestAmount > aa.TotalAmount {
+				c.t.Errorf("[%s] invariant: %s asset %s amount invalid %+v", step, ent.EntID, assetId, *aa)
+			}
+			assetSum[assetId] += aa.RestAmount
+		}
+	}
+	if sum != c.issueTotal {
+		c.t.Errorf("[%s] invariant: sum of balances %d != issue total %d", step, sum, c.issueTotal)
+	}
+	for k, v := range c.stub.State {
+		if !strings.HasPrefix(k, ASSET_INFO_PREFIX) {
+			continue
+		}
+		var ai AssetInfo
+		if err := json.Unmarshal(v, &ai); err != nil {
+			c.t.Fatalf("[%s] invariant: Unmarshal %s failed, err=%s", step, k, err)
+		}
+		if assetSum[ai.AssetID] != ai.IssuedAmount {
+			c.t.Errorf("[%s] invariant: sum of asset %s %d != issued %d", step, ai.AssetID, assetSum[ai.AssetID], ai.IssuedAmount)
+		}
+	}
+
+	if len(c.cbAcc) == 0 {
+		return
+	}
+
+	//查询不推进时间，避免影响锁定期
+	var cb = c.user(c.cbUser)
+	resp := c.invokeAs(cb, cb, "getAllAccAmt", []string{c.cbAcc}, "")
+	if resp.Status != shim.OK {
+		c.t.Fatalf("[%s] invariant: getAllAccAmt failed, %s", step, resp.Message)
+	}
+	var qb QueryBalance
+	if err := json.Unmarshal(resp.Payload, &qb); err != nil {
+		c.t.Fatalf("[%s] invariant: Unmarshal QueryBalance failed, err=%s", step, err)
+	}
+	if qb.AccSumAmount != userSum {
+		c.t.Errorf("[%s] invariant: getAllAccAmt sum %d != %d", step, qb.AccSumAmount, userSum)
+	}
+	if qb.AccSumAmount != qb.IssueAmount {
+		c.t.Errorf("[%s] invariant: getAllAccAmt sum %d != issued %d", step, qb.AccSumAmount, qb.IssueAmount)
+	}
+}
+
+//表驱动测试的一步
+type testStep struct {
+	name     string
+	user     string        //发起交易并签名的用户
+	function string        //
+	args     []string      //用户名之后、签名之前的参数，第一个为账户名
+	cc       string        //非空时为跨合约调用的合约名
+	advance  time.Duration //执行前推进的时间
+	prepare  func(c *testChain)
+	wantCode int32 //0表示期望成功，否则为期望的错误码
+	check    func(t *testing.T, c *testChain, payload []byte)
+}
+
+func (c *testChain) run(steps []testStep) {
+	for _, st := range steps {
+		c.stub.advance(st.advance)
+		if st.prepare != nil {
+			st.prepare(c)
+		}
+
+		var u = c.user(st.user)
+		resp := c.invokeAs(u, u, st.function, st.args, st.cc)
+		c.stub.advance(time.Second)
+
+		if code := respErrCode(resp); code != st.wantCode {
+			c.t.Fatalf("[%s] want code %d, got %d, resp=%s", st.name, st.wantCode, code, resp.Message)
+		}
+
+		c.checkInvariants(st.name)
+
+		if st.check != nil {
+			st.check(c.t, c, resp.Payload)
+		}
+	}
+}
+
+//检查账户余额的check函数
+func wantBalances(want map[string]int64) func(t *testing.T, c *testChain, payload []byte) {
+	return func(t *testing.T, c *testChain, payload []byte) {
+		for acc, amt := range want {
+			if got := c.balance(acc); got != amt {
+				t.Errorf("balance of %s: want %d, got %d", acc, amt, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package acbase
+
+import (
+	"encoding/json"
+	"strconv"
+
+	. "loulan/errcode"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//退款交易记录的交易类型。退款和普通转账一样由付款方(原交易的收款方)另付手续费，没有配置该类型的规则时
+//使用应用的默认规则，不收退款手续费需要为该类型配置费用为0的规则
+const REFUND_TRANS_TYPE = "refund"
+
+//一笔交易的退款信息。退款的交易记录中RefundOf指向原交易，这里记录原交易对应的所有退款
+type RefundInfo struct {
+	OrigSerial     int64   `json:"oser"` //原交易付款记录的全局序列号
+	OrigTxID       string  `json:"otx"`  //原交易ID
+	Amount         int64   `json:"amt"`  //原交易金额
+	RefundedAmount int64   `json:"rfda"` //已退款金额
+	Refunds        []int64 `json:"rfds"` //退款交易付款记录的全局序列号
+}
+
+func (b *BASE) getRefundInfoKey(origSerial int64) string {
+	return REFUND_INFO_PREFIX + strconv.FormatInt(origSerial, 10)
+}
+
+func (b *BASE) getTxSerialsKey(txid string) string {
+	return TXID_SERIAL_PREFIX + txid
+}
+
+//交易id对应的转账付款记录的全局序列号，一个交易中可能有多笔转账
+func (b *BASE) getTxSerials(stub shim.ChaincodeStubInterface, txid string) ([]int64, *ErrorCodeMsg) {
+	var serials []int64
+
+	serB, err := StateCache.GetState_Ex(stub, b.getTxSerialsKey(txid))
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getTxSerials: GetState failed. error=(%s)", err)
+	}
+	if serB == nil {
+		return serials, nil
+	}
+
+	err = json.Unmarshal(serB, &serials)
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getTxSerials: Unmarshal failed. error=(%s)", err)
+	}
+
+	return serials, nil
+}
+
+func (b *BASE) addTxSerial(stub shim.ChaincodeStubInterface, txid string, serial int64) *ErrorCodeMsg {
+	serials, errcm := b.getTxSerials(stub, txid)
+	if errcm != nil {
+		return errcm
+	}
+	serials = append(serials, serial)
+
+	serB, err := json.Marshal(serials)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "addTxSerial: Marshal failed. error=(%s)", err)
+	}
+
+	err = StateCache.PutState_Ex(stub, b.getTxSerialsKey(txid), serB)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "addTxSerial: PutState failed. error=(%s)", err)
+	}
+
+	return nil
+}
+
+func (b *BASE) getRefundInfo(stub shim.ChaincodeStubInterface, orig *Transaction) (*RefundInfo, *ErrorCodeMsg) {
+	var ri RefundInfo
+	ri.OrigSerial = orig.GlobalSerial
+	ri.OrigTxID = orig.TxID
+	ri.Amount = orig.Amount
+	ri.Refunds = []int64{}
+
+	riB, err := StateCache.GetState_Ex(stub, b.getRefundInfoKey(orig.GlobalSerial))
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getRefundInfo: GetState failed. error=(%s)", err)
+	}
+	if riB == nil {
+		return &ri, nil
+	}
+
+	err = json.Unmarshal(riB, &ri)
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getRefundInfo: Unmarshal failed. error=(%s)", err)
+	}
+
+	return &ri, nil
+}
+
+func (b *BASE) setRefundInfo(stub shim.ChaincodeStubInterface, ri *RefundInfo) *ErrorCodeMsg {
+	riB, err := json.Marshal(ri)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setRefundInfo: Marshal failed. error=(%s)", err)
+	}
+
+	err = StateCache.PutState_Ex(stub, b.getRefundInfoKey(ri.OrigSerial), riB)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setRefundInfo: PutState failed. error=(%s)", err)
+	}
+
+	return nil
+}
+
+//根据交易id或全局序列号找到原交易的付款记录。origRef为数字时是全局序列号（付款或收款记录都可以），否则为交易id，
+//交易id只能找到有索引的交易，且交易中只有一笔转账时才能确定是哪一笔
+func (b *BASE) getRefundableTrans(stub shim.ChaincodeStubInterface, origRef string) (*Transaction, *ErrorCodeMsg) {
+	serial, err := strconv.ParseInt(origRef, 10, 64)
+	if err != nil {
+		serials, errcm := b.getTxSerials(stub, origRef)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "getRefundableTrans: getTxSerials failed. error=(%s)", errcm)
+		}
+		if len(serials) != 1 {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getRefundableTrans: tx(%s) has %d transfers, use global serial instead.", origRef, len(serials))
+		}
+		serial = serials[0]
+	}
+
+	trans, errcm := b.getTransBySerial(stub, serial)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "getRefundableTrans: getTransBySerial(%d) failed. error=(%s)", serial, errcm)
+	}
+
+	//收款记录紧跟在付款记录之后，见TransferAsset
+	if trans.TransFlag == TRANS_INCOME {
+		pay, errcm := b.getTransBySerial(stub, serial-1)
+		if errcm != nil {
+			return nil, baselogger.ErrorECM(errcm.Code, "getRefundableTrans: getTransBySerial(%d) failed. error=(%s)", serial-1, errcm)
+		}
+		if pay.TransFlag != TRANS_PAY || pay.TxID != trans.TxID || pay.FromID != trans.ToID || pay.ToID != trans.FromID || pay.Amount != trans.Amount {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getRefundableTrans: no pay record for trans(%d).", serial)
+		}
+		trans = pay
+	}
+
+	if trans.FromID == trans.ToID {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getRefundableTrans: trans(%d) is not a transfer.", serial)
+	}
+	if trans.TransType == FEE_TRANS_TYPE || trans.TransType == BURN_TRANS_TYPE || trans.RefundOf > 0 {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getRefundableTrans: fee, burn or refund trans(%d) can't be refunded.", serial)
+	}
+
+	return trans, nil
+}
+
+func (b *BASE) getTransBySerial(stub shim.ChaincodeStubInterface, serial int64) (*Transaction, *ErrorCodeMsg) {
+	if serial <= 0 {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getTransBySerial: invalid serial(%d).", serial)
+	}
+
+	transB, err := StateCache.GetState_Ex(stub, b.getTransInfoKey(stub, serial))
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getTransBySerial: GetState failed. error=(%s)", err)
+	}
+	if transB == nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "getTransBySerial: trans(%d) not exists.", serial)
+	}
+
+	var trans Transaction
+	err = json.Unmarshal(transB, &trans)
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getTransBySerial: Unmarshal failed. error=(%s)", err)
+	}
+
+	return &trans, nil
+}
+
+//退款，由原交易的收款方退给付款方，可以部分退款，累计不超过原交易金额。operator为发起退款的账户，只能是原收款方或管理员
+func (b *BASE) refund(stub shim.ChaincodeStubInterface, operator, origRef, description string, amount, transTime int64) (*RefundInfo, *ErrorCodeMsg) {
+	if amount <= 0 {
+		return nil, baselogger.ErrorECM(ERRCODE_TRANS_AMOUNT_INVALID, "refund: invalid amount(%d).", amount)
+	}
+
+	orig, errcm := b.getRefundableTrans(stub, origRef)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "refund: getRefundableTrans failed. error=(%s)", errcm)
+	}
+
+	if operator != orig.ToID && !b.IsAdmin(stub, operator) {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_CHECK_FAILED, "refund: %s can't refund trans(%d).", operator, orig.GlobalSerial)
+	}
+
+	ri, errcm := b.getRefundInfo(stub, orig)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "refund: getRefundInfo failed. error=(%s)", errcm)
+	}
+	if ri.RefundedAmount+amount > ri.Amount {
+		return nil, baselogger.ErrorECM(ERRCODE_TRANS_REFUND_EXCEEDED, "refund: refund amount exceeded(%d,%d,%d).", ri.Amount, ri.RefundedAmount, amount)
+	}
+
+	if len(description) == 0 {
+		description = "refund of " + strconv.FormatInt(orig.GlobalSerial, 10)
+	}
+
+	paySerial, errcm := b.transferAsset(stub, orig.AssetID, orig.ToID, orig.FromID, REFUND_TRANS_TYPE, description, "", amount, transTime, false, orig.AppID, orig.GlobalSerial, -1)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "refund: transferAsset failed. error=(%s)", errcm)
+	}
+
+	ri.RefundedAmount += amount
+	ri.Refunds = append(ri.Refunds, paySerial)
+
+	errcm = b.setRefundInfo(stub, ri)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "refund: setRefundInfo failed. error=(%s)", errcm)
+	}
+
+	return ri, nil
+}
@@ -0,0 +1,186 @@
+package acbase
+
+import (
+	"encoding/json"
+
+	. "loulan/errcode"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const BURN_TRANS_TYPE = "burn" //销毁交易记录的交易类型
+
+//货币发行上限的一次修改记录
+type IssueCapChange struct {
+	OldCap   int64  `json:"old"`
+	NewCap   int64  `json:"new"`
+	Operator string `json:"op"`
+	Reason   string `json:"rsn"`
+	Time     int64  `json:"time"`
+	TxID     string `json:"txid"`
+}
+
+//货币供应信息。发行上限和已发行数量保存在发行货币的虚拟账户中（TotalAmount为上限，RestAmount为未发行的数量），
+//销毁的货币回到虚拟账户，可以再次发行。这里记录累计销毁数量和发行上限的修改记录
+type SupplyInfo struct {
+	IssueCap     int64            `json:"cap"`    //发行上限，查询时从虚拟账户获取
+	IssuedAmount int64            `json:"issued"` //已发行且未销毁的数量，查询时从虚拟账户获取
+	BurnedAmount int64            `json:"burned"` //累计销毁数量
+	BurnCount    int64            `json:"bcnt"`   //销毁次数
+	CapChanges   []IssueCapChange `json:"capchg"` //发行上限的修改记录
+}
+
+func (b *BASE) getSupplyInfo(stub shim.ChaincodeStubInterface) (*SupplyInfo, *ErrorCodeMsg) {
+	var si SupplyInfo
+	si.CapChanges = []IssueCapChange{}
+
+	siB, err := StateCache.GetState_Ex(stub, SUPPLY_INFO_KEY)
+	if err != nil {
+		return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getSupplyInfo: GetState failed. error=(%s)", err)
+	}
+	if siB != nil {
+		err = json.Unmarshal(siB, &si)
+		if err != nil {
+			return nil, baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "getSupplyInfo: Unmarshal failed. error=(%s)", err)
+		}
+	}
+
+	issueEntity, errcm := b.GetAccountEntity(stub, COIN_ISSUE_ACC_ENTID)
+	if errcm != nil {
+		return nil, baselogger.ErrorECM(errcm.Code, "getSupplyInfo: getIssueEntity failed. error=(%s)", errcm)
+	}
+	si.IssueCap = issueEntity.TotalAmount
+	si.IssuedAmount = issueEntity.TotalAmount - issueEntity.RestAmount
+
+	return &si, nil
+}
+
+func (b *BASE) setSupplyInfo(stub shim.ChaincodeStubInterface, si *SupplyInfo) *ErrorCodeMsg {
+	siB, err := json.Marshal(si)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setSupplyInfo: Marshal failed. error=(%s)", err)
+	}
+
+	err = StateCache.PutState_Ex(stub, SUPPLY_INFO_KEY, siB)
+	if err != nil {
+		return baselogger.ErrorECM(ERRCODE_COMMON_SYS_ERROR, "setSupplyInfo: PutState failed. error=(%s)", err)
+	}
+
+	return nil
+}
+
+//销毁账户中的货币（默认资产），货币回到发行货币的虚拟账户。锁定的金额不能销毁
+func (b *BASE) burnCoin(stub shim.ChaincodeStubInterface, accName, reason string, amount, burnTime int64) *ErrorCodeMsg {
+	if amount <= 0 {
+		return baselogger.ErrorECM(ERRCODE_TRANS_AMOUNT_INVALID, "burnCoin: invalid amount(%d).", amount)
+	}
+	if len(reason) == 0 {
+		return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "burnCoin: reason is empty.")
+	}
+
+	ent, errcm := b.GetAccountEntity(stub, accName)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "burnCoin: GetAccountEntity(%s) failed. error=(%s)", accName, errcm)
+	}
+
+	lockAmt, _, errcm := b.getAccountLockedAmount(stub, accName, DEFAULT_ASSET_ID, burnTime)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "burnCoin: getAccountLockedAmount(%s) failed. error=(%s)", accName, errcm)
+	}
+	if ent.RestAmount-lockAmt < amount {
+		var errCode int32 = ERRCODE_TRANS_BALANCE_NOT_ENOUGH
+		if lockAmt > 0 {
+			errCode = ERRCODE_TRANS_BALANCE_NOT_ENOUGH_BYLOCK
+		}
+		return baselogger.ErrorECM(errCode, "burnCoin: %s restAmount not enough(%d,%d,%d).", accName, ent.RestAmount, lockAmt, amount)
+	}
+
+	issueEntity, errcm := b.GetAccountEntity(stub, COIN_ISSUE_ACC_ENTID)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "burnCoin: getIssueEntity failed. error=(%s)", errcm)
+	}
+
+	ent.RestAmount -= amount
+	issueEntity.RestAmount += amount
+
+	errcm = b.setAccountEntity(stub, ent)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "burnCoin: setAccountEntity(%s) failed. error=(%s)", accName, errcm)
+	}
+
+	errcm = b.setAccountEntity(stub, issueEntity)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "burnCoin: setIssueEntity failed. error=(%s)", errcm)
+	}
+
+	//和发行一样，只记录账户一方的支出
+	_, errcm = b.recordTranse(stub, ent, issueEntity, TRANS_PAY, BURN_TRANS_TYPE, reason, "", amount, burnTime, "", DEFAULT_ASSET_ID, 0, 0)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "burnCoin: recordTranse failed. error=(%s)", errcm)
+	}
+
+	si, errcm := b.getSupplyInfo(stub)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "burnCoin: getSupplyInfo failed. error=(%s)", errcm)
+	}
+	si.BurnedAmount += amount
+	si.BurnCount++
+
+	errcm = b.setSupplyInfo(stub, si)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "burnCoin: setSupplyInfo failed. error=(%s)", errcm)
+	}
+
+	errcm = b.updateBalanceCheckpoints(stub, burnTime, accName)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "burnCoin: updateBalanceCheckpoints failed. error=(%s)", errcm)
+	}
+
+	return nil
+}
+
+//修改货币发行上限，不能低于已发行的数量。每次修改都记录操作人和原因
+func (b *BASE) setIssueCap(stub shim.ChaincodeStubInterface, operator, reason string, newCap, setTime int64) *ErrorCodeMsg {
+	if len(reason) == 0 {
+		return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "setIssueCap: reason is empty.")
+	}
+
+	issueEntity, errcm := b.GetAccountEntity(stub, COIN_ISSUE_ACC_ENTID)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "setIssueCap: getIssueEntity failed. error=(%s)", errcm)
+	}
+
+	var issued = issueEntity.TotalAmount - issueEntity.RestAmount
+	if newCap < issued {
+		return baselogger.ErrorECM(ERRCODE_COMMON_PARAM_INVALID, "setIssueCap: new cap(%d) less than issued amount(%d).", newCap, issued)
+	}
+
+	var chg IssueCapChange
+	chg.OldCap = issueEntity.TotalAmount
+	chg.NewCap = newCap
+	chg.Operator = operator
+	chg.Reason = reason
+	chg.Time = setTime
+	chg.TxID = stub.GetTxID()
+
+	issueEntity.TotalAmount = newCap
+	issueEntity.RestAmount = newCap - issued
+
+	errcm = b.setAccountEntity(stub, issueEntity)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "setIssueCap: setIssueEntity failed. error=(%s)", errcm)
+	}
+
+	si, errcm := b.getSupplyInfo(stub)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "setIssueCap: getSupplyInfo failed. error=(%s)", errcm)
+	}
+	si.CapChanges = append(si.CapChanges, chg)
+
+	errcm = b.setSupplyInfo(stub, si)
+	if errcm != nil {
+		return baselogger.ErrorECM(errcm.Code, "setIssueCap: setSupplyInfo failed. error=(%s)", errcm)
+	}
+
+	return nil
+}
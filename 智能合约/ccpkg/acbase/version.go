@@ -0,0 +1,5 @@
+//账户系统。accountsys直接使用，扩展合约（smk、frt等）通过钩子函数在账户系统基础上扩展
+package acbase
+
+//包版本号。接口有不兼容的修改时增加主版本号，并同步修改ccmanifest.json中引用此包的合约
+const ACBASE_VERSION = "1.10.0"
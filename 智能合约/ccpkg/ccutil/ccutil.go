@@ -0,0 +1,81 @@
+package ccutil
+
+import (
+	"sync"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+func StrSliceContains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func StrSliceDelete(list []string, value string) []string {
+	var newList []string
+
+	for _, v := range list {
+		if v != value {
+			newList = append(newList, v)
+		}
+	}
+
+	return newList
+}
+
+type StateWorldCache struct {
+	stateCache map[string]map[string][]byte
+	lock       sync.RWMutex //这里的map类似于全局变量，访问需要加锁
+}
+
+func (t *StateWorldCache) Create(stub shim.ChaincodeStubInterface) {
+	t.lock.Lock()
+
+	if t.stateCache == nil {
+		t.stateCache = make(map[string]map[string][]byte)
+	}
+	t.stateCache[stub.GetTxID()] = make(map[string][]byte)
+
+	t.lock.Unlock()
+}
+
+func (t *StateWorldCache) Destroy(stub shim.ChaincodeStubInterface) {
+	t.lock.Lock()
+	delete(t.stateCache, stub.GetTxID())
+	t.lock.Unlock()
+}
+
+func (t *StateWorldCache) GetState_Ex(stub shim.ChaincodeStubInterface, key string) ([]byte, error) {
+	t.lock.RLock() //读锁
+	if len(t.stateCache[stub.GetTxID()]) > 0 {
+		if value, ok := t.stateCache[stub.GetTxID()][key]; ok {
+			t.lock.RUnlock()
+			return value, nil
+		}
+	}
+	t.lock.RUnlock()
+
+	value, err := stub.GetState(key)
+	if err == nil {
+		t.lock.Lock() //写锁
+		t.stateCache[stub.GetTxID()][key] = value
+		t.lock.Unlock()
+	}
+
+	return value, err
+}
+
+func (t *StateWorldCache) PutState_Ex(stub shim.ChaincodeStubInterface, key string, value []byte) error {
+	err := stub.PutState(key, value)
+	if err == nil {
+		t.lock.Lock()
+		t.stateCache[stub.GetTxID()][key] = value
+		t.lock.Unlock()
+	}
+	return err
+}
@@ -1,4 +1,4 @@
-package main
+package ccutil
 
 import (
 	"sync"
@@ -7,13 +7,14 @@ import (
 )
 
 type TransferInfo struct {
-	FromID      string `json:"fid"`  //发送方ID
-	ToID        string `json:"tid"`  //接收方ID
-	Amount      int64  `json:"amt"`  //交易数额
-	Description string `json:"desc"` //交易描述
-	TransType   string `json:"tstp"` //交易类型，前端传入，透传
-	Time        int64  `json:"time"` //交易时间
-	AppID       string `json:"app"`  //应用ID  目前一条链一个账户体系，但是可能会有多种应用，所以交易信息记录一下应用id，可以按应用来过滤交易信息
+	FromID      string `json:"fid"`           //发送方ID
+	ToID        string `json:"tid"`           //接收方ID
+	Amount      int64  `json:"amt"`           //交易数额
+	Description string `json:"desc"`          //交易描述
+	TransType   string `json:"tstp"`          //交易类型，前端传入，透传
+	Time        int64  `json:"time"`          //交易时间
+	AppID       string `json:"app"`           //应用ID  目前一条链一个账户体系，但是可能会有多种应用，所以交易信息记录一下应用id，可以按应用来过滤交易信息
+	UpToBalance bool   `json:"utb,omitempty"` //为true时，发送方可用余额不足的话只转出可用余额。被调用的合约看不到余额，需要按余额扣款时使用
 }
 type InvokeResult struct {
 	TransInfos []TransferInfo `json:"transinfos"`
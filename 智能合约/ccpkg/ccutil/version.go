@@ -0,0 +1,5 @@
+//合约公共工具：交易内的worldState缓存、跨合约调用的转账信息等
+package ccutil
+
+//包版本号。接口有不兼容的修改时增加主版本号，并同步修改ccmanifest.json中引用此包的合约
+const CCUTIL_VERSION = "1.0.0"
@@ -0,0 +1,5 @@
+//错误码和ErrorCodeMsg。错误码会返回给前端，各合约共用同一套定义
+package errcode
+
+//包版本号。接口有不兼容的修改时增加主版本号，并同步修改ccmanifest.json中引用此包的合约
+const ERRCODE_VERSION = "1.3.0"
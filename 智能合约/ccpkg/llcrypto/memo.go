@@ -0,0 +1,152 @@
+package llcrypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+)
+
+//加密的转账备注。合约中只保存和返回，不解析；加解密都在客户端完成
+//
+//加密方式：随机生成内容密钥，用AES-256(CFB)加密备注；再生成一个临时密钥对，和每个接收者的公钥做ECDH，
+//用sha256(共享点)加密内容密钥。每个接收者用自己的私钥和临时公钥做ECDH即可解出内容密钥
+type EncryptedMemo struct {
+	Version   int               `json:"v"`
+	EphPubKey string            `json:"epk"`  //临时公钥，base64
+	IV        string            `json:"iv"`   //base64
+	Keys      map[string]string `json:"keys"` //接收者公钥hash(base64，和AccountEntity.OwnerPubKeyHash相同) => 加密后的内容密钥(base64)
+	Data      string            `json:"data"` //加密后的备注，base64
+	Mac       string            `json:"mac"`  //hmac-sha256(内容密钥, iv+data)，base64
+}
+
+const ENC_MEMO_VERSION = 1
+
+var memoSecp = NewSecp256k1()
+var memoCrypto = MyCryptoNew()
+
+func memoPubKeyHash(pubkey []byte) (string, error) {
+	hash, err := RipemdHash160(pubkey)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(hash), nil
+}
+
+func memoMac(key, iv, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(iv)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+//为多个接收者（一般是付款方和收款方）加密备注，pubkeys为33字节的压缩公钥。返回的字符串可以直接作为转账的加密备注参数
+func EncryptMemo(memo []byte, pubkeys ...[]byte) (string, error) {
+	if len(pubkeys) == 0 {
+		return "", commlogger.Errorf("EncryptMemo: no receiver.")
+	}
+
+	var em EncryptedMemo
+	em.Version = ENC_MEMO_VERSION
+	em.Keys = make(map[string]string)
+
+	var contentKey = memoSecp.RandByte(32)
+	var iv = memoSecp.RandByte(16)
+
+	ephPub, ephSec, err := memoSecp.GenerateKeyPair()
+	if err != nil {
+		return "", commlogger.Errorf("EncryptMemo: GenerateKeyPair failed, err=%s", err)
+	}
+
+	for _, pub := range pubkeys {
+		shared, err := memoSecp.ECDH(pub, ephSec)
+		if err != nil {
+			return "", commlogger.Errorf("EncryptMemo: ECDH failed, err=%s", err)
+		}
+
+		wrapped, err := memoCrypto.AESEncrypt(256, memoSecp.SumSHA256(shared), iv, contentKey)
+		if err != nil {
+			return "", commlogger.Errorf("EncryptMemo: wrap key failed, err=%s", err)
+		}
+
+		pubHash, err := memoPubKeyHash(pub)
+		if err != nil {
+			return "", commlogger.Errorf("EncryptMemo: hash pubkey failed, err=%s", err)
+		}
+		em.Keys[pubHash] = base64.StdEncoding.EncodeToString(wrapped)
+	}
+
+	data, err := memoCrypto.AESEncrypt(256, contentKey, iv, memo)
+	if err != nil {
+		return "", commlogger.Errorf("EncryptMemo: encrypt failed, err=%s", err)
+	}
+
+	em.EphPubKey = base64.StdEncoding.EncodeToString(ephPub)
+	em.IV = base64.StdEncoding.EncodeToString(iv)
+	em.Data = base64.StdEncoding.EncodeToString(data)
+	em.Mac = base64.StdEncoding.EncodeToString(memoMac(contentKey, iv, data))
+
+	emB, err := json.Marshal(em)
+	if err != nil {
+		return "", commlogger.Errorf("EncryptMemo: Marshal failed, err=%s", err)
+	}
+
+	return string(emB), nil
+}
+
+//用接收者的私钥解密备注
+func DecryptMemo(encMemo string, seckey []byte) ([]byte, error) {
+	var em EncryptedMemo
+	err := json.Unmarshal([]byte(encMemo), &em)
+	if err != nil {
+		return nil, commlogger.Errorf("DecryptMemo: Unmarshal failed, err=%s", err)
+	}
+	if em.Version != ENC_MEMO_VERSION {
+		return nil, commlogger.Errorf("DecryptMemo: unsupported version %d.", em.Version)
+	}
+
+	pub, err := memoSecp.PubkeyFromSeckey(seckey)
+	if err != nil {
+		return nil, commlogger.Errorf("DecryptMemo: PubkeyFromSeckey failed, err=%s", err)
+	}
+	pubHash, err := memoPubKeyHash(pub)
+	if err != nil {
+		return nil, commlogger.Errorf("DecryptMemo: hash pubkey failed, err=%s", err)
+	}
+
+	wrappedB64, ok := em.Keys[pubHash]
+	if !ok {
+		return nil, commlogger.Errorf("DecryptMemo: memo is not encrypted for this key.")
+	}
+
+	var decoded = make([][]byte, 4)
+	for i, s := range []string{em.EphPubKey, em.IV, wrappedB64, em.Data} {
+		decoded[i], err = base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, commlogger.Errorf("DecryptMemo: base64 decode failed, err=%s", err)
+		}
+	}
+	var ephPub, iv, wrapped, data = decoded[0], decoded[1], decoded[2], decoded[3]
+
+	shared, err := memoSecp.ECDH(ephPub, seckey)
+	if err != nil {
+		return nil, commlogger.Errorf("DecryptMemo: ECDH failed, err=%s", err)
+	}
+
+	contentKey, err := memoCrypto.AESDecrypt(256, memoSecp.SumSHA256(shared), iv, wrapped)
+	if err != nil {
+		return nil, commlogger.Errorf("DecryptMemo: unwrap key failed, err=%s", err)
+	}
+
+	mac, err := base64.StdEncoding.DecodeString(em.Mac)
+	if err != nil || !hmac.Equal(mac, memoMac(contentKey, iv, data)) {
+		return nil, commlogger.Errorf("DecryptMemo: mac not match.")
+	}
+
+	memo, err := memoCrypto.AESDecrypt(256, contentKey, iv, data)
+	if err != nil {
+		return nil, commlogger.Errorf("DecryptMemo: decrypt failed, err=%s", err)
+	}
+
+	return memo, nil
+}
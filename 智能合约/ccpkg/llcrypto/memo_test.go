@@ -0,0 +1,43 @@
+package llcrypto
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEncryptMemo(t *testing.T) {
+	var secp = NewSecp256k1()
+	payerPub, payerSec, _ := secp.GenerateKeyPair()
+	payeePub, payeeSec, _ := secp.GenerateKeyPair()
+	_, otherSec, _ := secp.GenerateKeyPair()
+
+	var memo = []byte("order 20180601-0001, 张三 138****0000")
+
+	encMemo, err := EncryptMemo(memo, payerPub, payeePub)
+	if err != nil {
+		t.Fatalf("EncryptMemo failed, err=%s", err)
+	}
+
+	for _, sec := range [][]byte{payerSec, payeeSec} {
+		got, err := DecryptMemo(encMemo, sec)
+		if err != nil {
+			t.Fatalf("DecryptMemo failed, err=%s", err)
+		}
+		if string(got) != string(memo) {
+			t.Errorf("DecryptMemo: want %s, got %s", memo, got)
+		}
+	}
+
+	if _, err = DecryptMemo(encMemo, otherSec); err == nil {
+		t.Errorf("DecryptMemo by other key should fail")
+	}
+
+	//篡改密文
+	var em EncryptedMemo
+	json.Unmarshal([]byte(encMemo), &em)
+	em.Data = "AAAA" + em.Data[4:]
+	tampered, _ := json.Marshal(em)
+	if _, err = DecryptMemo(string(tampered), payeeSec); err == nil {
+		t.Errorf("DecryptMemo of tampered memo should fail")
+	}
+}
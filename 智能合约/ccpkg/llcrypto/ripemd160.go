@@ -2,8 +2,9 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Package ripemd160 implements the RIPEMD-160 hash algorithm.
-package main
+// ripemd160 implements the RIPEMD-160 hash algorithm.
+
+package llcrypto
 
 // RIPEMD-160 is designed by by Hans Dobbertin, Antoon Bosselaers, and Bart
 // Preneel with specifications available at:
@@ -1,4 +1,4 @@
-package main
+package llcrypto
 
 import (
 	"bytes"
@@ -48,7 +48,7 @@ func (secp *SECP256K1) pubkeyFromSeckey(seckey []byte) ([]byte, error) {
 	}
 
 	if ret := __secp256k1_ec.PubkeyIsValid(pubkey); ret != 1 {
-		return nil, fmt.Errorf("ERROR: pubkey invald, ret=%s", ret)
+		return nil, fmt.Errorf("ERROR: pubkey invald, ret=%d", ret)
 	}
 
 	if ret := secp.VerifyPubkey(pubkey); ret != 1 {
@@ -262,7 +262,7 @@ func (secp *SECP256K1) Sign(msg []byte, seckey []byte) ([]byte, error) {
 		sig[i] = sig_bytes[i]
 	}
 	if len(sig_bytes) != 64 {
-		return nil, fmt.Errorf("Sign, Invalid signature byte count: %s", len(sig_bytes))
+		return nil, fmt.Errorf("Sign, Invalid signature byte count: %d", len(sig_bytes))
 	}
 	sig[64] = byte(int(recid))
 
@@ -303,7 +303,7 @@ func (secp *SECP256K1) SignDeterministic(msg []byte, seckey []byte, nonce_seed [
 	sig[64] = byte(recid)
 
 	if len(sig_bytes) != 64 {
-		return nil, fmt.Errorf("SignDeterministic, Invalid signature byte count: %s", len(sig_bytes))
+		return nil, fmt.Errorf("SignDeterministic, Invalid signature byte count: %d", len(sig_bytes))
 	}
 
 	if int(recid) > 4 {
@@ -500,7 +500,7 @@ func (secp *SECP256K1) ECDH(pub []byte, sec []byte) ([]byte, error) {
 	}
 
 	if ret := secp.VerifyPubkey(pub); ret != 1 {
-		return nil, fmt.Errorf("ECDH: VerifyPubkey, failed, err=.", ret)
+		return nil, fmt.Errorf("ECDH: VerifyPubkey, failed, ret=%d.", ret)
 	}
 
 	pubkey_out := __secp256k1_ec.Multiply(pub, sec)
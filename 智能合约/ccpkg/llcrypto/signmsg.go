@@ -0,0 +1,75 @@
+package llcrypto
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"strconv"
+	"strings"
+)
+
+//规范的签名消息格式(v1)。旧格式是把函数名和参数用","拼接后做sha256，参数中含有","时不同的参数列表会得到相同的消息，
+//且签名和链、合约无关，可以在别的链或合约上重放。v1格式为：
+//sha256( lp(SIGN_MSG_DOMAIN) | lp("v1") | lp(channelId) | lp(chaincodeName) | lp(function) | uint32(参数个数) | lp(arg)... | int64(过期时间) )
+//其中lp(x)为4字节大端长度加上x本身，整数都是大端编码。过期时间单位为毫秒，合约中和交易时间比较
+//
+//客户端传给合约的签名参数为 SIGN_ENVELOPE_V1_PREFIX + 过期时间 + ":" + base64(签名)，见MakeSignEnvelope
+const (
+	SIGN_MSG_DOMAIN         = "loulan-chaincode-sign"
+	SIGN_ENVELOPE_V1_TAG    = "v1"
+	SIGN_ENVELOPE_V1_PREFIX = SIGN_ENVELOPE_V1_TAG + ":"
+)
+
+func appendLenPrefixed(buf []byte, s string) []byte {
+	var l [4]byte
+	binary.BigEndian.PutUint32(l[:], uint32(len(s)))
+	buf = append(buf, l[:]...)
+	return append(buf, s...)
+}
+
+//计算v1格式的签名消息，返回sha256结果，直接用于签名和验签
+func CanonicalSignMsg(channelId, chaincodeName, function string, args []string, expiry int64) []byte {
+	var buf []byte
+	buf = appendLenPrefixed(buf, SIGN_MSG_DOMAIN)
+	buf = appendLenPrefixed(buf, SIGN_ENVELOPE_V1_TAG)
+	buf = appendLenPrefixed(buf, channelId)
+	buf = appendLenPrefixed(buf, chaincodeName)
+	buf = appendLenPrefixed(buf, function)
+
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(args)))
+	buf = append(buf, n[:]...)
+	for _, arg := range args {
+		buf = appendLenPrefixed(buf, arg)
+	}
+
+	var exp [8]byte
+	binary.BigEndian.PutUint64(exp[:], uint64(expiry))
+	buf = append(buf, exp[:]...)
+
+	sum := sha256.Sum256(buf)
+	return sum[:]
+}
+
+//生成v1格式的签名参数
+func MakeSignEnvelope(expiry int64, signBase64 string) string {
+	return SIGN_ENVELOPE_V1_PREFIX + strconv.FormatInt(expiry, 10) + ":" + signBase64
+}
+
+//解析签名参数。isV1为false时表示旧格式，signBase64即为整个参数
+func ParseSignEnvelope(signArg string) (isV1 bool, expiry int64, signBase64 string, err error) {
+	if !strings.HasPrefix(signArg, SIGN_ENVELOPE_V1_PREFIX) {
+		return false, 0, signArg, nil
+	}
+
+	var parts = strings.SplitN(signArg[len(SIGN_ENVELOPE_V1_PREFIX):], ":", 2)
+	if len(parts) != 2 {
+		return true, 0, "", commlogger.Errorf("ParseSignEnvelope: invalid envelope(%s).", signArg)
+	}
+
+	expiry, err = strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return true, 0, "", commlogger.Errorf("ParseSignEnvelope: invalid expiry(%s), err=%s", parts[0], err)
+	}
+
+	return true, expiry, parts[1], nil
+}
@@ -0,0 +1,39 @@
+package llcrypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCanonicalSignMsg(t *testing.T) {
+	var base = CanonicalSignMsg("ch1", "acsys", "transefer", []string{"u", "a,b", "c"}, 1000)
+
+	//旧格式下这几组参数的消息都相同
+	var diffs = [][]byte{
+		CanonicalSignMsg("ch1", "acsys", "transefer", []string{"u", "a", "b,c"}, 1000),
+		CanonicalSignMsg("ch1", "acsys", "transefer", []string{"u", "a", "b", "c"}, 1000),
+		CanonicalSignMsg("ch2", "acsys", "transefer", []string{"u", "a,b", "c"}, 1000),
+		CanonicalSignMsg("ch1", "smk", "transefer", []string{"u", "a,b", "c"}, 1000),
+		CanonicalSignMsg("ch1", "acsys", "transefer3", []string{"u", "a,b", "c"}, 1000),
+		CanonicalSignMsg("ch1", "acsys", "transefer", []string{"u", "a,b", "c"}, 1001),
+	}
+	for i, d := range diffs {
+		if bytes.Equal(base, d) {
+			t.Errorf("case %d: msg should differ", i)
+		}
+	}
+
+	isV1, expiry, sig, err := ParseSignEnvelope(MakeSignEnvelope(1000, "c2lnbg=="))
+	if err != nil || !isV1 || expiry != 1000 || sig != "c2lnbg==" {
+		t.Errorf("ParseSignEnvelope: got %v %d %s %v", isV1, expiry, sig, err)
+	}
+
+	isV1, _, sig, err = ParseSignEnvelope("c2lnbg==")
+	if err != nil || isV1 || sig != "c2lnbg==" {
+		t.Errorf("ParseSignEnvelope(legacy): got %v %s %v", isV1, sig, err)
+	}
+
+	if _, _, _, err = ParseSignEnvelope("v1:abc:c2lnbg=="); err == nil {
+		t.Errorf("ParseSignEnvelope should fail on bad expiry")
+	}
+}
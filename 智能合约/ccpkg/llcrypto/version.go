@@ -0,0 +1,5 @@
+//合约中使用的密码学算法：secp256k1签名验证、ripemd160、AES等
+package llcrypto
+
+//包版本号。接口有不兼容的修改时增加主版本号，并同步修改ccmanifest.json中引用此包的合约
+const LLCRYPTO_VERSION = "1.2.0"
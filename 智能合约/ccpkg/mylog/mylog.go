@@ -0,0 +1,94 @@
+package mylog
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+
+	. "loulan/errcode"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+type MYLOG struct {
+	logger *shim.ChaincodeLogger
+}
+
+var __myLoggerSet []*MYLOG
+
+func NewMylogger(module string) *MYLOG {
+	var logger = shim.NewLogger(module)
+	logger.SetLevel(shim.LogInfo)
+	var mylogger = MYLOG{logger}
+	__myLoggerSet = append(__myLoggerSet, &mylogger)
+	return &mylogger
+}
+
+//修改日志的模块名，修改后需要重新设置日志级别
+func (m *MYLOG) SetModule(module string) {
+	m.logger = shim.NewLogger(module)
+	m.logger.SetLevel(shim.LogInfo)
+}
+
+// debug=5, info=4, notice=3, warning=2, error=1, critical=0
+func (m *MYLOG) SetDefaultLvl(lvl shim.LoggingLevel) {
+	m.logger.SetLevel(lvl)
+}
+
+func (m *MYLOG) _addStackInfo(format string) string {
+	pc, file, line, ok := runtime.Caller(2)
+	if !ok {
+		return format
+	}
+
+	return fmt.Sprintf("%s:%d[%s] %s", path.Base(file), line, runtime.FuncForPC(pc).Name(), format)
+}
+
+func (m *MYLOG) Debug(format string, args ...interface{}) {
+	m.logger.Debugf(m._addStackInfo(format), args...)
+}
+func (m *MYLOG) Info(format string, args ...interface{}) {
+	m.logger.Infof(m._addStackInfo(format), args...)
+}
+func (m *MYLOG) Notice(format string, args ...interface{}) {
+	m.logger.Noticef(m._addStackInfo(format), args...)
+}
+
+func (m *MYLOG) Warn(format string, args ...interface{}) {
+	m.logger.Warningf(m._addStackInfo(format), args...)
+}
+func (m *MYLOG) Error(format string, args ...interface{}) {
+	m.logger.Errorf(m._addStackInfo(format), args...)
+}
+
+//输出错误信息，并返回错误对象
+func (m *MYLOG) Errorf(format string, args ...interface{}) error {
+	//日志追加stack信息
+	m.logger.Errorf(m._addStackInfo(format), args...)
+	//返回的信息不追加stack信息
+	return fmt.Errorf(format, args...)
+}
+
+//输出错误信息，并返回错误信息
+func (m *MYLOG) SError(format string, args ...interface{}) string {
+	//日志追加stack信息
+	m.logger.Errorf(m._addStackInfo(format), args...)
+	//返回的信息不追加stack信息
+	return fmt.Sprintf(format, args...)
+}
+
+//输出错误信息，并返回 ErrorCodeMsg 对象
+func (m *MYLOG) ErrorECM(code int32, format string, args ...interface{}) *ErrorCodeMsg {
+	//日志追加stack信息
+	m.logger.Errorf(m._addStackInfo(format), args...)
+	//返回的信息不追加stack信息
+	return NewErrorCodeMsg(code, fmt.Sprintf(format, args...))
+}
+
+func (m *MYLOG) Critical(format string, args ...interface{}) {
+	m.logger.Criticalf(m._addStackInfo(format), args...)
+}
+
+func (m *MYLOG) GetLoggers() []*MYLOG {
+	return __myLoggerSet
+}
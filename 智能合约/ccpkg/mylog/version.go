@@ -0,0 +1,5 @@
+//合约日志，在shim.ChaincodeLogger基础上增加调用位置信息
+package mylog
+
+//包版本号。接口有不兼容的修改时增加主版本号，并同步修改ccmanifest.json中引用此包的合约
+const MYLOG_VERSION = "1.0.0"
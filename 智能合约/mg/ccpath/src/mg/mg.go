@@ -14,6 +14,11 @@ import (
 	"strconv"
 	"strings"
 
+	"loulan/llcrypto"
+
+	. "loulan/ccutil"
+	. "loulan/mylog"
+
 	//"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	//"github.com/hyperledger/fabric/core/crypto/primitives"
@@ -267,7 +272,7 @@ type InvokeArgs struct {
 }
 
 var mglogger = NewMylogger("mg")
-var mgCrypto = MyCryptoNew()
+var mgCrypto = llcrypto.MyCryptoNew()
 
 var stateCache StateWorldCache
 
@@ -1003,7 +1008,7 @@ func (t *MG) getRackAllocCfg(stub shim.ChaincodeStubInterface, rackid string, pe
 	if peap != nil {
 		err = json.Unmarshal(eapB, peap)
 		if err != nil {
-			return nil, mglogger.Errorf("getRackAllocCfg Unmarshal failed. err=%s", rackid, err)
+			return nil, mglogger.Errorf("getRackAllocCfg Unmarshal(rackid=%s) failed. err=%s", rackid, err)
 		}
 	}
 
@@ -1540,13 +1545,13 @@ func (t *MG) userBuyFinance(stub shim.ChaincodeStubInterface, accName, rackid, f
 		_, ok := rfi.UserAmountMap[accName]
 		if ok {
 			//如果用户已提取了，又来买，那么从新记录投资额，不能累计，否则会把前一次的累计进来。
-			if strSliceContains(rfi.PayFinanceUserList, accName) {
+			if StrSliceContains(rfi.PayFinanceUserList, accName) {
 				rfi.AmountFinca -= rfi.UserAmountMap[accName] //实际投资额度要减去上一次的
 				rfi.UserAmountMap[accName] = amount
 				if isRenewal {
 					rfi.UserRenewalMap[accName] = amount
 				}
-				rfi.PayFinanceUserList = strSliceDelete(rfi.PayFinanceUserList, accName)
+				rfi.PayFinanceUserList = StrSliceDelete(rfi.PayFinanceUserList, accName)
 			} else {
 				rfi.UserAmountMap[accName] += amount
 				if isRenewal {
@@ -1630,7 +1635,7 @@ func (t *MG) userBuyFinance(stub shim.ChaincodeStubInterface, accName, rackid, f
 	}
 	mglogger.Debug("userBuyFinance: ent=%+v", arfi)
 
-	if !strSliceContains(fi.RackList, ri.RackID) {
+	if !StrSliceContains(fi.RackList, ri.RackID) {
 		fi.RackList = append(fi.RackList, ri.RackID)
 	}
 	fiJson, err := json.Marshal(fi)
@@ -1638,7 +1643,7 @@ func (t *MG) userBuyFinance(stub shim.ChaincodeStubInterface, accName, rackid, f
 		return nil, mglogger.Errorf("userBuyFinance:  Marshal failed. err=%s.", err)
 	}
 
-	if !strSliceContains(ri.FinacList, fi.FID) {
+	if !StrSliceContains(ri.FinacList, fi.FID) {
 		ri.FinacList = append(ri.FinacList, fi.FID)
 	}
 
@@ -1962,7 +1967,7 @@ func (t *MG) getRackFinanceAmount(stub shim.ChaincodeStubInterface, rackid, fid
 	}
 	var totalAmt int64 = 0
 	for acc, amt := range rfi.UserAmountMap {
-		if !strSliceContains(rfi.PayFinanceUserList, acc) {
+		if !StrSliceContains(rfi.PayFinanceUserList, acc) {
 			totalAmt += amt
 		}
 	}
@@ -2099,7 +2104,7 @@ func (t *MG) financeRenewalPreviousFinance(stub shim.ChaincodeStubInterface, cur
 
 		for acc, amt := range rfi.UserAmountMap {
 			//已赎回的用户不在续期
-			if strSliceContains(rfi.PayFinanceUserList, acc) {
+			if StrSliceContains(rfi.PayFinanceUserList, acc) {
 				continue
 			}
 
@@ -2164,7 +2169,7 @@ func (t *MG) payUserFinance(stub shim.ChaincodeStubInterface, accName, reacc, ra
 		}
 
 		//如果已提取过，则不能再提取。这里不报错，不实际执行转账即可
-		if strSliceContains(rfi.PayFinanceUserList, reacc) {
+		if StrSliceContains(rfi.PayFinanceUserList, reacc) {
 			mglogger.Warn("payUserFinance: %s has paid already, do nothing.", reacc)
 			continue
 		}
@@ -2202,7 +2207,7 @@ func (t *MG) payUserFinance(stub shim.ChaincodeStubInterface, accName, reacc, ra
 
 	//将赎回的理财期号写入已赎回列表
 	for _, fid := range paidFidList {
-		if !strSliceContains(reaccEnt.PaidFidList, fid) {
+		if !StrSliceContains(reaccEnt.PaidFidList, fid) {
 			reaccEnt.PaidFidList = append(reaccEnt.PaidFidList, fid)
 		}
 	}